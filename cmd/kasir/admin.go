@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+
+	"github.com/spf13/cobra"
+)
+
+func newCreateAdminCmd() *cobra.Command {
+	var email, role string
+
+	cmd := &cobra.Command{
+		Use:   "create-admin <username>",
+		Short: "Create a back-office admin account",
+		Long: "Create a back-office admin account. The password is read from the first line of\n" +
+			"standard input, so it doesn't end up in the shell history:\n\n" +
+			"  kasir create-admin budi\n" +
+			"  echo \"$ADMIN_PASSWORD\" | kasir create-admin budi --email budi@example.com\n\n" +
+			"With --email, a link to confirm the address is mailed there through the SMTP\n" +
+			"server the API uses, signed with AUTH_TOKEN_SECRET.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := openPostgres()
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			fmt.Fprint(cmd.ErrOrStderr(), "Password: ")
+			password, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+			if err != nil && password == "" {
+				return errors.New("no password given on standard input")
+			}
+			password = strings.TrimRight(password, "\r\n")
+
+			admin, err := newAuthService(db).Register(models.RegisterAdminRequest{Username: args[0], Email: email, Password: password, Role: role})
+			if err != nil && !errors.Is(err, services.ErrVerificationNotSent) {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Created admin %s (id %d, role %s)\n", admin.Username, admin.ID, admin.Role)
+			if err != nil {
+				return err
+			}
+			if admin.Email != "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "Sent a verification link to %s\n", admin.Email)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&email, "email", "", "email address to verify, where password reset links are sent")
+	cmd.Flags().StringVar(&role, "role", "admin", "role whose permissions the account gets")
+	return cmd
+}
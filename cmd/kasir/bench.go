@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"kasir-api/database"
+	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/repositories"
+	"kasir-api/repositories/sqlite"
+	"kasir-api/sequence"
+	"kasir-api/services"
+
+	"github.com/spf13/cobra"
+)
+
+func newBenchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Measure the throughput of the sales flow",
+	}
+	cmd.AddCommand(newBenchCheckoutCmd())
+	return cmd
+}
+
+func newBenchCheckoutCmd() *cobra.Command {
+	var transactions, concurrency, items, products int
+
+	cmd := &cobra.Command{
+		Use:   "checkout",
+		Short: "Measure transaction creation against an in-memory kiosk database",
+		Long: "Run checkouts through the transaction service against a fresh in-memory\n" +
+			"kiosk database and print the throughput and latency. The database and its\n" +
+			"configuration are left alone. The last line is in Go benchmark format, so\n" +
+			"runs can be compared with benchstat. Pricing, recording a checkout and the\n" +
+			"report aggregation have benchmarks of their own under go test -bench, in the\n" +
+			"services and repositories packages.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if transactions < 1 || concurrency < 1 || items < 1 || products < items {
+				return fmt.Errorf("transactions, concurrency and items must be at least 1, products at least items")
+			}
+
+			db, err := database.OpenSQLiteMemory()
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			if err := database.MigrateSQLite(db); err != nil {
+				return fmt.Errorf("error running migrations: %v", err)
+			}
+
+			productStore := sqlite.NewProductRepository(db)
+			productIDs := make([]int, products)
+			for i := range productIDs {
+				p, err := productStore.Create(models.Product{
+					Name:      fmt.Sprintf("Bench product %d", i+1),
+					SKU:       fmt.Sprintf("BENCH-%06d", i+1),
+					Price:     money.Amount(5000 + 500*(i%20)),
+					CostPrice: money.Amount(4000 + 400*(i%20)),
+					Stock:     transactions * 3,
+				})
+				if err != nil {
+					return fmt.Errorf("error creating products: %v", err)
+				}
+				productIDs[i] = p.ID
+			}
+
+			format, err := sequence.ParseFormat("BENCH/{SEQ:8}", sequence.ResetNever)
+			if err != nil {
+				return err
+			}
+			numbering, err := services.NewReceiptNumbering(repositories.NewSequenceRepository(db), format, "")
+			if err != nil {
+				return err
+			}
+			transactionService := services.NewTransactionService(
+				repositories.NewTransactionRepository(db),
+				productStore,
+				sqlite.NewPricingRuleRepository(db),
+				numbering,
+				nil,
+				nil,
+				nil,
+				nil,
+				services.OpenItemPolicy{},
+				money.Rounding{},
+			)
+
+			// baskets are drawn up front so the timing covers checkouts only
+			rng := rand.New(rand.NewPCG(1, 1))
+			baskets := make([][]models.CheckoutItem, transactions)
+			for i := range baskets {
+				basket := make([]models.CheckoutItem, items)
+				for j, k := range rng.Perm(products)[:items] {
+					basket[j] = models.CheckoutItem{ProductID: productIDs[k], Quantity: 1 + rng.IntN(3)}
+				}
+				baskets[i] = basket
+			}
+
+			latencies := make([]time.Duration, transactions)
+			failures := make([]error, transactions)
+			next := make(chan int)
+			var wg sync.WaitGroup
+
+			start := time.Now()
+			for range concurrency {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range next {
+						t := time.Now()
+						_, failures[i] = transactionService.Checkout(context.Background(), models.CheckoutRequest{Items: baskets[i]}, false)
+						latencies[i] = time.Since(t)
+					}
+				}()
+			}
+			for i := range baskets {
+				next <- i
+			}
+			close(next)
+			wg.Wait()
+			elapsed := time.Since(start)
+
+			failed := 0
+			var firstErr error
+			for _, err := range failures {
+				if err != nil {
+					failed++
+					if firstErr == nil {
+						firstErr = err
+					}
+				}
+			}
+			slices.Sort(latencies)
+			percentile := func(p float64) time.Duration {
+				return latencies[int(p*float64(len(latencies)-1))]
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "%d checkouts of %d items by %d workers in %s\n", transactions, items, concurrency, elapsed.Round(time.Millisecond))
+			fmt.Fprintf(out, "Throughput  %.1f transactions/s\n", float64(transactions)/elapsed.Seconds())
+			fmt.Fprintf(out, "Latency     p50 %s  p95 %s  p99 %s  max %s\n",
+				percentile(0.50).Round(time.Microsecond), percentile(0.95).Round(time.Microsecond),
+				percentile(0.99).Round(time.Microsecond), latencies[len(latencies)-1].Round(time.Microsecond))
+			if failed > 0 {
+				fmt.Fprintf(out, "Failed      %d, first error: %v\n", failed, firstErr)
+			}
+			fmt.Fprintf(out, "BenchmarkCheckout/items=%d/concurrency=%d-%d\t%d\t%d ns/op\n",
+				items, concurrency, runtime.GOMAXPROCS(0), transactions, elapsed.Nanoseconds()/int64(transactions))
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d checkouts failed", failed, transactions)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&transactions, "transactions", 2000, "number of checkouts")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 4, "number of checkouts running at once")
+	cmd.Flags().IntVar(&items, "items", 3, "products in each checkout")
+	cmd.Flags().IntVar(&products, "products", 200, "products in the catalog")
+	return cmd
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"kasir-api/money"
+	"kasir-api/repositories"
+	"kasir-api/spreadsheet"
+
+	"github.com/spf13/cobra"
+)
+
+func newExportProductsCmd() *cobra.Command {
+	var format, output string
+
+	cmd := &cobra.Command{
+		Use:   "export-products",
+		Short: "Export the active products as CSV or XLSX",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "csv" && format != "xlsx" {
+				return fmt.Errorf("format must be csv or xlsx")
+			}
+			currency, err := storeCurrency()
+			if err != nil {
+				return err
+			}
+
+			db, err := openDB()
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			_, productStore := catalogStores(db)
+			productService, err := newProductService(db, productStore)
+			if err != nil {
+				return err
+			}
+			products, err := productService.GetAll("", "", "", repositories.ExpandCategory, time.Time{})
+			if err != nil {
+				return err
+			}
+
+			var out io.Writer = cmd.OutOrStdout()
+			if output != "" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				out = f
+			}
+
+			sheet, err := spreadsheet.NewWriter(format, out)
+			if err != nil {
+				return err
+			}
+			if err := sheet.WriteRow("id", "sku", "barcode", "name", "categories", "price", "cost_price", "stock", "reorder_point", "is_bundle"); err != nil {
+				return err
+			}
+			for _, p := range products {
+				names := make([]string, len(p.Categories))
+				for i, c := range p.Categories {
+					names[i] = c.Name
+				}
+				err := sheet.WriteRow(p.ID, p.SKU, p.Barcode, p.Name, strings.Join(names, ", "),
+					money.New(int64(p.Price), currency), money.New(int64(p.CostPrice), currency), p.Stock, p.ReorderPoint, p.IsBundle)
+				if err != nil {
+					return err
+				}
+			}
+			if err := sheet.Close(); err != nil {
+				return err
+			}
+
+			if output != "" {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d products to %s\n", len(products), output)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "csv", "file format, csv or xlsx")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "file to write, standard output when empty")
+	return cmd
+}
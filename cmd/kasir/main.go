@@ -0,0 +1,202 @@
+// Command kasir runs the maintenance tasks of a Kasir install against the
+// database configured in .env or the environment, the same settings the
+// server reads, so ops work doesn't need raw SQL:
+//
+//	kasir migrate
+//	kasir seed
+//	kasir create-admin <username> [--email address] [--role name]
+//	kasir export-products [--format csv|xlsx] [--output file]
+//	kasir report daily [--date YYYY-MM-DD]
+//	kasir bench checkout [--transactions n] [--concurrency n]
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"kasir-api/database"
+	"kasir-api/mailer"
+	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/repositories"
+	"kasir-api/repositories/sqlite"
+	"kasir-api/sequence"
+	"kasir-api/services"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var errKioskUnsupported = errors.New("this command needs the PostgreSQL server database, kiosk installs (DB_DRIVER=sqlite) don't have it")
+
+func main() {
+	log.SetFlags(0)
+
+	root := &cobra.Command{
+		Use:           "kasir",
+		Short:         "Maintenance tasks for a Kasir install",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			loadConfig()
+		},
+	}
+	root.AddCommand(
+		newMigrateCmd(),
+		newSeedCmd(),
+		newCreateAdminCmd(),
+		newExportProductsCmd(),
+		newReportCmd(),
+		newBenchCmd(),
+	)
+
+	if err := root.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// loadConfig reads .env like the server does, with the environment taking precedence
+func loadConfig() {
+	viper.SetConfigFile(".env")
+	viper.AutomaticEnv()
+	_ = viper.ReadInConfig()
+}
+
+func isKiosk() bool {
+	return viper.GetString("DB_DRIVER") == "sqlite"
+}
+
+// openDB connects to DATABASE_URL, the SQLite file of a kiosk install when DB_DRIVER=sqlite
+func openDB() (*sql.DB, error) {
+	if isKiosk() {
+		return database.OpenSQLite(viper.GetString("DATABASE_URL"))
+	}
+	return database.Connect(viper.GetString("DATABASE_URL"))
+}
+
+// openPostgres connects to the server database, refusing kiosk installs
+func openPostgres() (*sql.DB, error) {
+	if isKiosk() {
+		return nil, errKioskUnsupported
+	}
+	return database.Connect(viper.GetString("DATABASE_URL"))
+}
+
+// catalogStores returns the category and product stores of the configured driver
+func catalogStores(db *sql.DB) (repositories.CategoryStore, repositories.ProductStore) {
+	if isKiosk() {
+		return sqlite.NewCategoryRepository(db), sqlite.NewProductRepository(db)
+	}
+	return repositories.NewCategoryRepository(db), repositories.NewProductRepository(db)
+}
+
+// storeCurrency is the STORE_CURRENCY the server keeps amounts in, IDR unless set
+func storeCurrency() (money.Currency, error) {
+	code := viper.GetString("STORE_CURRENCY")
+	if code == "" {
+		code = money.Default
+	}
+	return money.Lookup(code)
+}
+
+// storeTimezone is the STORE_TIMEZONE the server reports in, Asia/Jakarta unless set
+func storeTimezone() (string, error) {
+	timezone := viper.GetString("STORE_TIMEZONE")
+	if timezone == "" {
+		timezone = "Asia/Jakarta"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "Local" {
+		return "", fmt.Errorf("STORE_TIMEZONE must be an IANA time zone, not %s", timezone)
+	}
+	return timezone, nil
+}
+
+// taxRate returns TAX_RATE, the PPN percentage prices include, 11 when unset
+func taxRate() int {
+	if rate := viper.GetInt("TAX_RATE"); rate > 0 {
+		return rate
+	}
+	return 11
+}
+
+// storeSettings reads the store profile the server keeps in store_settings,
+// the environment filling in a profile never saved
+func storeSettings(db *sql.DB) (models.StoreSettings, *services.SettingsService, error) {
+	currency, err := storeCurrency()
+	if err != nil {
+		return models.StoreSettings{}, nil, err
+	}
+	timezone, err := storeTimezone()
+	if err != nil {
+		return models.StoreSettings{}, nil, err
+	}
+
+	settingsService := services.NewSettingsService(repositories.NewSettingsRepository(db), models.StoreSettings{
+		Currency: currency.Code,
+		TaxRate:  taxRate(),
+		Timezone: timezone,
+	})
+	settings, err := settingsService.Load()
+	if err != nil {
+		return models.StoreSettings{}, nil, fmt.Errorf("error reading store settings: %v", err)
+	}
+	return settings, settingsService, nil
+}
+
+// newMailer sends through the SMTP server the API uses, or only logs
+// messages when none is configured
+func newMailer() mailer.Mailer {
+	smtpHost := viper.GetString("SMTP_HOST")
+	if smtpHost == "" {
+		return mailer.LogMailer{}
+	}
+	smtpPort := viper.GetString("SMTP_PORT")
+	if smtpPort == "" {
+		smtpPort = "587"
+	}
+	return mailer.NewSMTPMailer(smtpHost, smtpPort, viper.GetString("SMTP_USERNAME"), viper.GetString("SMTP_PASSWORD"), viper.GetString("SMTP_FROM"))
+}
+
+// newAuthService issues email links the way the server does, so they work there
+func newAuthService(db *sql.DB) *services.AuthService {
+	appURL := viper.GetString("APP_URL")
+	if appURL == "" {
+		port := viper.GetString("PORT")
+		if port == "" {
+			port = "8080"
+		}
+		appURL = "http://localhost:" + port
+	}
+	verifyTTL := viper.GetDuration("AUTH_VERIFY_TOKEN_TTL")
+	if verifyTTL <= 0 {
+		verifyTTL = 48 * time.Hour
+	}
+	resetTTL := viper.GetDuration("AUTH_RESET_TOKEN_TTL")
+	if resetTTL <= 0 {
+		resetTTL = time.Hour
+	}
+	return services.NewAuthService(repositories.NewAdminUserRepository(db), repositories.NewAuthTokenRepository(db), newMailer(), viper.GetString("AUTH_TOKEN_SECRET"), appURL, verifyTTL, resetTTL)
+}
+
+// newProductService numbers generated SKUs with SKU_FORMAT, as the server does
+func newProductService(db *sql.DB, products repositories.ProductStore) (*services.ProductService, error) {
+	skuFormat := viper.GetString("SKU_FORMAT")
+	if skuFormat == "" {
+		skuFormat = "SKU-{SEQ:6}"
+	}
+
+	format, err := sequence.ParseFormat(skuFormat, sequence.ResetNever)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing SKU format: %v", err)
+	}
+	skus, err := services.NewSKUNumbering(repositories.NewSequenceRepository(db), format, viper.GetString("STORE_CODE"))
+	if err != nil {
+		return nil, fmt.Errorf("error configuring SKUs: %v", err)
+	}
+	return services.NewProductService(products, skus), nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+
+	"kasir-api/database"
+
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply pending database migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := openDB()
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			if isKiosk() {
+				err = database.MigrateSQLite(db)
+			} else {
+				err = database.Migrate(db)
+			}
+			if err != nil {
+				return fmt.Errorf("error running migrations: %v", err)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Database is up to date")
+			return nil
+		},
+	}
+}
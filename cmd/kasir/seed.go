@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"kasir-api/repositories"
+	"kasir-api/repositories/sqlite"
+	"kasir-api/services"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newSeedCmd() *cobra.Command {
+	var opts services.SeedOptions
+
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Fill the database with realistic demo data",
+		Long: "Fill the database with realistic demo data for demos and load tests: a grocery\n" +
+			"catalog, customers and a history of sales over the past days. Reruns only add\n" +
+			"what the requested volume still lacks, so raising a flag tops the data up.\n" +
+			"Kiosk installs (DB_DRIVER=sqlite) get no customers.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := openDB()
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			categoryStore, productStore := catalogStores(db)
+			productService, err := newProductService(db, productStore)
+			if err != nil {
+				return err
+			}
+
+			var customerService *services.CustomerService
+			var pricingStore repositories.PricingRuleStore
+			if isKiosk() {
+				pricingStore = sqlite.NewPricingRuleRepository(db)
+			} else {
+				pricingStore = repositories.NewPricingRuleRepository(db)
+				phoneCountryCode := strings.TrimPrefix(viper.GetString("PHONE_COUNTRY_CODE"), "+")
+				if phoneCountryCode == "" {
+					phoneCountryCode = "62"
+				}
+				customerService = services.NewCustomerService(repositories.NewCustomerRepository(db), phoneCountryCode)
+			}
+
+			seedService := services.NewSeedService(
+				services.NewCategoryService(categoryStore, repositories.CategoryDeleteBlock),
+				productService,
+				customerService,
+				repositories.NewTransactionRepository(db),
+				pricingStore,
+				repositories.NewSeedRepository(db),
+			)
+			result, err := seedService.Seed(opts)
+			if err != nil {
+				return err
+			}
+
+			// terminals holding the catalog in cache pick the new products up
+			if result.Categories > 0 || result.Products > 0 {
+				if _, err := services.NewCatalogVersion(repositories.NewSequenceRepository(db)).Bump(); err != nil {
+					return err
+				}
+			}
+
+			// past days are read from the daily summaries, which the new sales are missing
+			if !isKiosk() {
+				_, settingsService, err := storeSettings(db)
+				if err != nil {
+					return err
+				}
+				reportService := services.NewReportService(repositories.NewReportRepository(db, settingsService), settingsService)
+				for _, day := range result.SeededDays {
+					if err := reportService.AggregateDay(day); err != nil {
+						return fmt.Errorf("error aggregating %s: %v", day, err)
+					}
+				}
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Added %d categories, %d products, %d customers and %d transactions\n",
+				result.Categories, result.Products, result.Customers, result.Transactions)
+			if result.Skipped > 0 {
+				fmt.Fprintf(out, "Skipped %d transactions for lack of stock\n", result.Skipped)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&opts.Products, "products", 30, "number of products in the catalog")
+	cmd.Flags().IntVar(&opts.Customers, "customers", 50, "number of customers")
+	cmd.Flags().IntVar(&opts.Days, "days", 30, "number of past days with sales, today excluded")
+	cmd.Flags().IntVar(&opts.TransactionsPerDay, "transactions-per-day", 40, "average number of sales per day")
+	return cmd
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// the SQLite schema of kiosk installs only has the tables of the sales flow
+//
+//go:embed migrations_sqlite/*.sql
+var sqliteMigrationFiles embed.FS
+
+// Migrate applies all pending SQL migrations in filename order
+func Migrate(db *sql.DB) error {
+	return migrate(db, migrationFiles, "migrations", "TIMESTAMPTZ NOT NULL DEFAULT NOW()")
+}
+
+// MigrateSQLite applies all pending SQLite migrations in filename order
+func MigrateSQLite(db *sql.DB) error {
+	return migrate(db, sqliteMigrationFiles, "migrations_sqlite", "TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP")
+}
+
+func migrate(db *sql.DB, files embed.FS, dir, appliedAt string) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    VARCHAR(255) PRIMARY KEY,
+		applied_at ` + appliedAt + `
+	)`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations table: %v", err)
+	}
+
+	versions, err := migrationVersions(files, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, version := range versions {
+		name := version + ".sql"
+
+		var exists bool
+		err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", version).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		content, err := files.ReadFile(dir + "/" + name)
+		if err != nil {
+			return err
+		}
+
+		if err := applyMigration(db, version, string(content)); err != nil {
+			return fmt.Errorf("error applying migration %s: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// applyMigration runs a single migration and records it in one transaction
+func applyMigration(db *sql.DB, version, content string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(content); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// migrationVersions lists the migrations in dir of files in the order they apply
+func migrationVersions(files embed.FS, dir string) ([]string, error) {
+	entries, err := files.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".sql") {
+			versions = append(versions, strings.TrimSuffix(entry.Name(), ".sql"))
+		}
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
+
+// CheckSchema reports whether the database has exactly the migrations built
+// into this binary, e.g. not one written by a newer build after a rollback
+func CheckSchema(db *sql.DB) error {
+	return checkSchema(db, migrationFiles, "migrations")
+}
+
+// CheckSQLiteSchema is CheckSchema for kiosk installs
+func CheckSQLiteSchema(db *sql.DB) error {
+	return checkSchema(db, sqliteMigrationFiles, "migrations_sqlite")
+}
+
+func checkSchema(db *sql.DB, files embed.FS, dir string) error {
+	versions, err := migrationVersions(files, dir)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.Query("SELECT version FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return err
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, version := range versions {
+		if !applied[version] {
+			return fmt.Errorf("migration %s is not applied", version)
+		}
+		delete(applied, version)
+	}
+	if len(applied) > 0 {
+		var unknown []string
+		for version := range applied {
+			unknown = append(unknown, version)
+		}
+		sort.Strings(unknown)
+		return fmt.Errorf("database has migration %s, which this build doesn't know; it was migrated by a newer version", unknown[len(unknown)-1])
+	}
+	return nil
+}
@@ -0,0 +1,4 @@
+ALTER TABLE product ADD COLUMN IF NOT EXISTS cost_price INTEGER NOT NULL DEFAULT 0;
+
+-- cost is snapshotted per line so later cost changes don't rewrite history
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS cost_price INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,53 @@
+CREATE TABLE IF NOT EXISTS customer (
+    id               SERIAL PRIMARY KEY,
+    name             VARCHAR(255) NOT NULL,
+    phone            VARCHAR(50) NOT NULL DEFAULT '',
+    email            VARCHAR(255) NOT NULL DEFAULT '',
+    reminder_channel VARCHAR(20) NOT NULL DEFAULT 'whatsapp',
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at       TIMESTAMPTZ
+);
+
+-- kasbon is store credit given to a customer, repaid through kasbon_payment
+CREATE TABLE IF NOT EXISTS kasbon (
+    id          SERIAL PRIMARY KEY,
+    customer_id INTEGER NOT NULL REFERENCES customer(id),
+    amount      INTEGER NOT NULL,
+    due_date    DATE NOT NULL,
+    note        TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS kasbon_payment (
+    id          SERIAL PRIMARY KEY,
+    customer_id INTEGER NOT NULL REFERENCES customer(id),
+    amount      INTEGER NOT NULL,
+    note        TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS reminder_template (
+    channel VARCHAR(20) PRIMARY KEY,
+    body    TEXT NOT NULL
+);
+
+INSERT INTO reminder_template (channel, body) VALUES
+    ('whatsapp', 'Halo {{.Name}}, kasbon Anda sebesar Rp{{.Balance}} sudah jatuh tempo sejak {{.DueDate}}. Mohon segera melakukan pembayaran. Terima kasih.'),
+    ('sms', 'Halo {{.Name}}, kasbon Rp{{.Balance}} jatuh tempo sejak {{.DueDate}}. Mohon segera dibayar.'),
+    ('email', 'Halo {{.Name}},\n\nKasbon Anda sebesar Rp{{.Balance}} sudah jatuh tempo sejak {{.DueDate}}. Mohon segera melakukan pembayaran.\n\nTerima kasih.')
+ON CONFLICT (channel) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS reminder_history (
+    id          SERIAL PRIMARY KEY,
+    customer_id INTEGER NOT NULL REFERENCES customer(id),
+    channel     VARCHAR(20) NOT NULL,
+    recipient   VARCHAR(255) NOT NULL,
+    message     TEXT NOT NULL,
+    status      VARCHAR(20) NOT NULL,
+    error       TEXT NOT NULL DEFAULT '',
+    sent_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_kasbon_customer_id ON kasbon(customer_id);
+CREATE INDEX IF NOT EXISTS idx_kasbon_payment_customer_id ON kasbon_payment(customer_id);
+CREATE INDEX IF NOT EXISTS idx_reminder_history_customer_id ON reminder_history(customer_id);
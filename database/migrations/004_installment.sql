@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS installment_plan (
+    id                SERIAL PRIMARY KEY,
+    transaction_id    INTEGER NOT NULL UNIQUE REFERENCES transactions(id),
+    customer_id       INTEGER REFERENCES customer(id),
+    total_amount      INTEGER NOT NULL,
+    down_payment      INTEGER NOT NULL DEFAULT 0,
+    installment_count INTEGER NOT NULL,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS installment (
+    id          SERIAL PRIMARY KEY,
+    plan_id     INTEGER NOT NULL REFERENCES installment_plan(id),
+    sequence    INTEGER NOT NULL,
+    due_date    DATE NOT NULL,
+    amount      INTEGER NOT NULL,
+    paid_amount INTEGER NOT NULL DEFAULT 0,
+    paid_at     TIMESTAMPTZ,
+    UNIQUE (plan_id, sequence)
+);
+
+CREATE TABLE IF NOT EXISTS installment_payment (
+    id             SERIAL PRIMARY KEY,
+    plan_id        INTEGER NOT NULL REFERENCES installment_plan(id),
+    installment_id INTEGER NOT NULL REFERENCES installment(id),
+    amount         INTEGER NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_installment_plan_customer_id ON installment_plan(customer_id);
+CREATE INDEX IF NOT EXISTS idx_installment_due_date ON installment(due_date) WHERE paid_amount < amount;
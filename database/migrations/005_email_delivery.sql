@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS email_delivery (
+    id             SERIAL PRIMARY KEY,
+    transaction_id INTEGER NOT NULL REFERENCES transactions(id),
+    recipient      VARCHAR(255) NOT NULL,
+    subject        VARCHAR(255) NOT NULL,
+    status         VARCHAR(20) NOT NULL DEFAULT 'queued',
+    attempts       INTEGER NOT NULL DEFAULT 0,
+    error          TEXT NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at        TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_delivery_transaction_id ON email_delivery(transaction_id);
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS supplier (
+    id         SERIAL PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    phone      VARCHAR(50) NOT NULL DEFAULT '',
+    email      VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+
+-- current price offered by a supplier for a product
+CREATE TABLE IF NOT EXISTS supplier_price (
+    id          SERIAL PRIMARY KEY,
+    supplier_id INTEGER NOT NULL REFERENCES supplier(id),
+    product_id  INTEGER NOT NULL REFERENCES product(id),
+    price       INTEGER NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (supplier_id, product_id)
+);
+
+-- every price ever recorded, used for price trends
+CREATE TABLE IF NOT EXISTS supplier_price_history (
+    id          SERIAL PRIMARY KEY,
+    supplier_id INTEGER NOT NULL REFERENCES supplier(id),
+    product_id  INTEGER NOT NULL REFERENCES product(id),
+    price       INTEGER NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_supplier_price_product_id ON supplier_price(product_id);
+CREATE INDEX IF NOT EXISTS idx_supplier_price_history_lookup ON supplier_price_history(supplier_id, product_id, recorded_at);
+
+ALTER TABLE product ADD COLUMN IF NOT EXISTS reorder_point INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE product ADD COLUMN IF NOT EXISTS reorder_qty INTEGER NOT NULL DEFAULT 0;
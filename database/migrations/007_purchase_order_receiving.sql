@@ -0,0 +1,53 @@
+ALTER TABLE product ADD COLUMN IF NOT EXISTS barcode VARCHAR(64) NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_barcode ON product(barcode) WHERE barcode <> '' AND deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS purchase_order (
+    id          SERIAL PRIMARY KEY,
+    supplier_id INTEGER NOT NULL REFERENCES supplier(id),
+    status      VARCHAR(20) NOT NULL DEFAULT 'open',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS purchase_order_item (
+    id                SERIAL PRIMARY KEY,
+    purchase_order_id INTEGER NOT NULL REFERENCES purchase_order(id),
+    product_id        INTEGER NOT NULL REFERENCES product(id),
+    quantity_ordered  INTEGER NOT NULL,
+    quantity_received INTEGER NOT NULL DEFAULT 0,
+    unit_cost         INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (purchase_order_id, product_id)
+);
+
+CREATE TABLE IF NOT EXISTS receiving_session (
+    id                SERIAL PRIMARY KEY,
+    purchase_order_id INTEGER NOT NULL REFERENCES purchase_order(id),
+    status            VARCHAR(20) NOT NULL DEFAULT 'open',
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    committed_at      TIMESTAMPTZ
+);
+
+-- at most one open session per purchase order
+CREATE UNIQUE INDEX IF NOT EXISTS idx_receiving_session_open ON receiving_session(purchase_order_id) WHERE status = 'open';
+
+CREATE TABLE IF NOT EXISTS receiving_session_item (
+    session_id  INTEGER NOT NULL REFERENCES receiving_session(id),
+    product_id  INTEGER NOT NULL REFERENCES product(id),
+    scanned_qty INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (session_id, product_id)
+);
+
+-- stock actually received, written when a receiving session is committed
+CREATE TABLE IF NOT EXISTS goods_receipt (
+    id                SERIAL PRIMARY KEY,
+    purchase_order_id INTEGER NOT NULL REFERENCES purchase_order(id),
+    session_id        INTEGER REFERENCES receiving_session(id),
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS goods_receipt_item (
+    id         SERIAL PRIMARY KEY,
+    receipt_id INTEGER NOT NULL REFERENCES goods_receipt(id),
+    product_id INTEGER NOT NULL REFERENCES product(id),
+    quantity   INTEGER NOT NULL,
+    unit_cost  INTEGER NOT NULL DEFAULT 0
+);
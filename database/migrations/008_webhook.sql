@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS webhook (
+    id         SERIAL PRIMARY KEY,
+    url        TEXT NOT NULL,
+    events     TEXT[] NOT NULL,
+    secret     VARCHAR(64) NOT NULL,
+    active     BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id            SERIAL PRIMARY KEY,
+    webhook_id    INTEGER NOT NULL REFERENCES webhook(id),
+    event         VARCHAR(50) NOT NULL,
+    payload       JSONB NOT NULL,
+    status        VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts      INTEGER NOT NULL DEFAULT 0,
+    response_code INTEGER NOT NULL DEFAULT 0,
+    error         TEXT NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_webhook_id ON webhook_delivery(webhook_id);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS job (
+    id           SERIAL PRIMARY KEY,
+    type         VARCHAR(100) NOT NULL,
+    payload      JSONB NOT NULL DEFAULT '{}',
+    status       VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts     INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 5,
+    last_error   TEXT NOT NULL DEFAULT '',
+    unique_key   VARCHAR(255) UNIQUE,
+    run_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_at    TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_pending ON job(run_at) WHERE status = 'pending';
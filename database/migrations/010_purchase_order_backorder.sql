@@ -0,0 +1,5 @@
+-- purchase_order.status: open -> partially_received -> received, or short_closed
+ALTER TABLE purchase_order ADD COLUMN IF NOT EXISTS closed_at TIMESTAMPTZ;
+ALTER TABLE purchase_order ADD COLUMN IF NOT EXISTS close_reason TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_goods_receipt_purchase_order_id ON goods_receipt(purchase_order_id);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS cycle_count_task (
+    id            SERIAL PRIMARY KEY,
+    product_id    INTEGER NOT NULL REFERENCES product(id),
+    abc_class     CHAR(1) NOT NULL,
+    scheduled_for DATE NOT NULL,
+    status        VARCHAR(20) NOT NULL DEFAULT 'pending',
+    expected_qty  INTEGER,
+    counted_qty   INTEGER,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    counted_at    TIMESTAMPTZ,
+    UNIQUE (product_id, scheduled_for)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cycle_count_task_scheduled_for ON cycle_count_task(scheduled_for);
@@ -0,0 +1,16 @@
+-- rolled up nightly from transactions, reports read these for past days
+CREATE TABLE IF NOT EXISTS daily_sales_summary (
+    date               DATE PRIMARY KEY,
+    total_revenue      BIGINT NOT NULL DEFAULT 0,
+    total_transactions INTEGER NOT NULL DEFAULT 0,
+    aggregated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS daily_product_sales (
+    date       DATE NOT NULL REFERENCES daily_sales_summary(date) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES product(id),
+    qty_sold   INTEGER NOT NULL DEFAULT 0,
+    revenue    BIGINT NOT NULL DEFAULT 0,
+    cost       BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (date, product_id)
+);
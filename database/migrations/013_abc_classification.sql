@@ -0,0 +1,4 @@
+ALTER TABLE product ADD COLUMN IF NOT EXISTS abc_class CHAR(1) NOT NULL DEFAULT 'C';
+ALTER TABLE product ADD COLUMN IF NOT EXISTS abc_classified_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_product_abc_class ON product(abc_class) WHERE deleted_at IS NULL;
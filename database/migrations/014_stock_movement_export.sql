@@ -0,0 +1,30 @@
+-- every change to product stock, balance_after is the stock right after the movement
+CREATE TABLE IF NOT EXISTS stock_movement (
+    id            SERIAL PRIMARY KEY,
+    product_id    INTEGER NOT NULL REFERENCES product(id),
+    quantity      INTEGER NOT NULL,
+    balance_after INTEGER NOT NULL,
+    reason        VARCHAR(20) NOT NULL,
+    reference_id  INTEGER,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movement_product_created_at ON stock_movement(product_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_stock_movement_created_at ON stock_movement(created_at);
+
+-- history starts from the stock on hand when the ledger was introduced
+INSERT INTO stock_movement (product_id, quantity, balance_after, reason)
+SELECT id, stock, stock, 'opening' FROM product;
+
+CREATE TABLE IF NOT EXISTS data_export (
+    id           SERIAL PRIMARY KEY,
+    type         VARCHAR(50) NOT NULL,
+    format       VARCHAR(10) NOT NULL,
+    date_from    DATE NOT NULL,
+    date_to      DATE NOT NULL,
+    status       VARCHAR(20) NOT NULL DEFAULT 'pending',
+    file_name    TEXT NOT NULL DEFAULT '',
+    error        TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
@@ -0,0 +1,14 @@
+-- petty cash paid out of the register, optionally with a photo of the receipt
+CREATE TABLE IF NOT EXISTS expense (
+    id              SERIAL PRIMARY KEY,
+    amount          INTEGER NOT NULL,
+    category        VARCHAR(50) NOT NULL DEFAULT '',
+    description     TEXT NOT NULL DEFAULT '',
+    spent_on        DATE NOT NULL DEFAULT CURRENT_DATE,
+    attachment_name TEXT NOT NULL DEFAULT '',
+    attachment_type VARCHAR(50) NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at      TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_expense_spent_on ON expense(spent_on) WHERE deleted_at IS NULL;
@@ -0,0 +1,9 @@
+-- one counter per store and reset period, e.g. "|2024-06" for a monthly reset
+CREATE TABLE IF NOT EXISTS receipt_sequence (
+    scope      VARCHAR(100) PRIMARY KEY,
+    last_value BIGINT NOT NULL
+);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS receipt_number VARCHAR(50);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_receipt_number ON transactions(receipt_number);
@@ -0,0 +1,24 @@
+-- a physical count of the whole store (or one category); the variances
+-- are only booked into stock once the count is approved
+CREATE TABLE IF NOT EXISTS stock_opname (
+    id           SERIAL PRIMARY KEY,
+    category_id  INTEGER REFERENCES category(id),
+    status       VARCHAR(20) NOT NULL DEFAULT 'open',
+    note         TEXT NOT NULL DEFAULT '',
+    review_note  TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    submitted_at TIMESTAMPTZ,
+    reviewed_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_opname_status ON stock_opname(status);
+
+-- expected_qty is the recorded stock at the moment the product was counted
+CREATE TABLE IF NOT EXISTS stock_opname_line (
+    opname_id    INTEGER NOT NULL REFERENCES stock_opname(id) ON DELETE CASCADE,
+    product_id   INTEGER NOT NULL REFERENCES product(id),
+    expected_qty INTEGER NOT NULL,
+    counted_qty  INTEGER NOT NULL,
+    counted_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (opname_id, product_id)
+);
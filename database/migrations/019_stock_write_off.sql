@@ -0,0 +1,15 @@
+-- stock taken out of sellable inventory other than by a sale: damaged or
+-- expired goods, and goods sent back to their supplier
+CREATE TABLE IF NOT EXISTS stock_write_off (
+    id          SERIAL PRIMARY KEY,
+    product_id  INTEGER NOT NULL REFERENCES product(id),
+    supplier_id INTEGER REFERENCES supplier(id),
+    reason      VARCHAR(20) NOT NULL,
+    quantity    INTEGER NOT NULL,
+    -- cost price at the time of the write-off, used to value shrinkage
+    unit_cost   INTEGER NOT NULL DEFAULT 0,
+    note        TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_write_off_created_at ON stock_write_off(created_at);
@@ -0,0 +1,35 @@
+-- a bundle is a product with its own price that is made of other products;
+-- it holds no stock of its own, selling it takes stock from its components
+ALTER TABLE product ADD COLUMN IF NOT EXISTS is_bundle BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS product_bundle_item (
+    bundle_id    INTEGER NOT NULL REFERENCES product(id),
+    component_id INTEGER NOT NULL REFERENCES product(id),
+    quantity     INTEGER NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (bundle_id, component_id)
+);
+
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS is_bundle BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- the components sold with a bundle line, with the line's revenue allocated
+-- in proportion to the components' own prices
+CREATE TABLE IF NOT EXISTS transaction_bundle_component (
+    detail_id      INTEGER NOT NULL REFERENCES transaction_details(id),
+    transaction_id INTEGER NOT NULL REFERENCES transactions(id),
+    product_id     INTEGER NOT NULL REFERENCES product(id),
+    quantity       INTEGER NOT NULL,
+    revenue        INTEGER NOT NULL,
+    cost           INTEGER NOT NULL,
+    PRIMARY KEY (detail_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_bundle_component_transaction_id ON transaction_bundle_component(transaction_id);
+
+-- sales per product as reported: bundle lines are replaced by their components
+CREATE OR REPLACE VIEW product_sales_line AS
+    SELECT td.transaction_id, td.product_id, td.quantity, td.subtotal, td.cost_price * td.quantity AS cost
+    FROM transaction_details td
+    WHERE NOT td.is_bundle
+    UNION ALL
+    SELECT c.transaction_id, c.product_id, c.quantity, c.revenue, c.cost
+    FROM transaction_bundle_component c;
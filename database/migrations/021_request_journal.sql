@@ -0,0 +1,17 @@
+-- anonymized request/response pairs captured for debugging terminal
+-- specific bugs; capped to the most recent REQUEST_JOURNAL_LIMIT entries
+CREATE TABLE IF NOT EXISTS request_journal (
+    id               BIGSERIAL PRIMARY KEY,
+    method           VARCHAR(10) NOT NULL,
+    path             TEXT NOT NULL,
+    query            TEXT NOT NULL DEFAULT '',
+    terminal_id      VARCHAR(100) NOT NULL DEFAULT '',
+    request_headers  JSONB NOT NULL DEFAULT '{}',
+    request_body     TEXT NOT NULL DEFAULT '',
+    response_status  INTEGER NOT NULL,
+    response_body    TEXT NOT NULL DEFAULT '',
+    duration_ms      INTEGER NOT NULL DEFAULT 0,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_request_journal_terminal_id ON request_journal(terminal_id);
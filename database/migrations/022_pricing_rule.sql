@@ -0,0 +1,18 @@
+-- quantity price breaks such as "3 for 25,000": every full group of
+-- min_quantity units sells for price, the rest at the product's own price
+CREATE TABLE IF NOT EXISTS pricing_rule (
+    id           SERIAL PRIMARY KEY,
+    product_id   INTEGER NOT NULL REFERENCES product(id),
+    name         VARCHAR(100) NOT NULL DEFAULT '',
+    min_quantity INTEGER NOT NULL CHECK (min_quantity > 1),
+    price        INTEGER NOT NULL CHECK (price > 0),
+    active       BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at   TIMESTAMP DEFAULT NOW(),
+    deleted_at   TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_pricing_rule_product_id ON pricing_rule(product_id);
+
+-- the rule a checkout line was priced with and what it took off the line
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS pricing_rule_id INTEGER REFERENCES pricing_rule(id);
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS discount INTEGER NOT NULL DEFAULT 0;
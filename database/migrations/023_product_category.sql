@@ -0,0 +1,19 @@
+-- a product can be in several categories, e.g. "Drinks" and "Promo"
+CREATE TABLE IF NOT EXISTS product_category (
+    product_id  INTEGER NOT NULL REFERENCES product(id),
+    category_id INTEGER NOT NULL REFERENCES category(id),
+    PRIMARY KEY (product_id, category_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_category_category_id ON product_category(category_id);
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'product' AND column_name = 'category_id') THEN
+        INSERT INTO product_category (product_id, category_id)
+        SELECT id, category_id FROM product WHERE category_id IS NOT NULL
+        ON CONFLICT DO NOTHING;
+    END IF;
+END $$;
+
+ALTER TABLE product DROP COLUMN IF EXISTS category_id;
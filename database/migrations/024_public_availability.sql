@@ -0,0 +1,2 @@
+-- products the store has opted in to showing on its website's availability widget
+ALTER TABLE product ADD COLUMN IF NOT EXISTS public_availability BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+-- API requests and server errors per day, the denominator of the error budget
+CREATE TABLE IF NOT EXISTS http_daily_stats (
+    day           DATE PRIMARY KEY,
+    requests      BIGINT NOT NULL DEFAULT 0,
+    server_errors BIGINT NOT NULL DEFAULT 0
+);
@@ -0,0 +1,7 @@
+-- back-office admins; passwords are stored as PBKDF2 hashes, see services/admin_user_service.go
+CREATE TABLE IF NOT EXISTS admin_user (
+    id            SERIAL PRIMARY KEY,
+    username      VARCHAR(32) NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
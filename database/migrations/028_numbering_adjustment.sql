@@ -0,0 +1,10 @@
+-- audit trail of next numbers set by hand through /api/admin/numbering
+CREATE TABLE IF NOT EXISTS numbering_adjustment (
+    id            SERIAL PRIMARY KEY,
+    sequence      VARCHAR(32) NOT NULL,
+    scope         VARCHAR(100) NOT NULL,
+    previous_next BIGINT NOT NULL,
+    next_value    BIGINT NOT NULL,
+    reason        TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
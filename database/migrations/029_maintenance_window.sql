@@ -0,0 +1,10 @@
+-- times of the week heavy scheduled jobs may run, see /api/admin/maintenance-windows;
+-- a window ending before it starts runs past midnight
+CREATE TABLE IF NOT EXISTS maintenance_window (
+    id         SERIAL PRIMARY KEY,
+    name       VARCHAR(100) NOT NULL,
+    days       INTEGER[] NOT NULL DEFAULT '{}',
+    start_time TIME NOT NULL,
+    end_time   TIME NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
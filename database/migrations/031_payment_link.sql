@@ -0,0 +1,20 @@
+-- remote orders paid through a gateway link, see /api/payment-links; items are
+-- checked out into a transaction once the gateway reports the link paid
+CREATE TABLE IF NOT EXISTS payment_link (
+    id             SERIAL PRIMARY KEY,
+    reference      VARCHAR(32) NOT NULL UNIQUE,
+    customer_name  VARCHAR(100) NOT NULL DEFAULT '',
+    customer_phone VARCHAR(20) NOT NULL DEFAULT '',
+    note           TEXT NOT NULL DEFAULT '',
+    items          JSONB NOT NULL,
+    amount         INTEGER NOT NULL,
+    url            TEXT NOT NULL DEFAULT '',
+    status         VARCHAR(16) NOT NULL DEFAULT 'pending',
+    paid_amount    INTEGER NOT NULL DEFAULT 0,
+    transaction_id INTEGER REFERENCES transactions(id),
+    expires_at     TIMESTAMPTZ NOT NULL,
+    paid_at        TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_link_status ON payment_link(status, created_at);
@@ -0,0 +1,16 @@
+-- advertising playlist of the customer-facing display, see /api/admin/display/media;
+-- an item without start and end time plays all day, one ending before it starts
+-- plays past midnight
+CREATE TABLE IF NOT EXISTS display_media (
+    id               SERIAL PRIMARY KEY,
+    title            VARCHAR(100) NOT NULL DEFAULT '',
+    file_name        TEXT NOT NULL,
+    content_type     VARCHAR(50) NOT NULL,
+    size             BIGINT NOT NULL,
+    duration_seconds INTEGER NOT NULL DEFAULT 0,
+    position         INTEGER NOT NULL DEFAULT 0,
+    start_time       TIME,
+    end_time         TIME,
+    active           BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
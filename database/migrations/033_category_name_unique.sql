@@ -0,0 +1,9 @@
+-- category names are unique among active categories, ignoring case; existing
+-- duplicates keep their products and get the id appended to tell them apart
+UPDATE category c SET name = c.name || ' (' || c.id || ')'
+WHERE c.deleted_at IS NULL AND EXISTS (
+    SELECT 1 FROM category o
+    WHERE o.deleted_at IS NULL AND LOWER(o.name) = LOWER(c.name) AND o.id < c.id
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_category_name ON category (LOWER(name)) WHERE deleted_at IS NULL;
@@ -0,0 +1,16 @@
+-- months closed by the accountant through /api/admin/periods; expenses, sales
+-- and receipt costs dated in a closed month can't change until it is reopened
+CREATE TABLE IF NOT EXISTS accounting_period (
+    month     DATE PRIMARY KEY,
+    note      TEXT NOT NULL DEFAULT '',
+    closed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- audit trail of every close and reopen
+CREATE TABLE IF NOT EXISTS accounting_period_event (
+    id         SERIAL PRIMARY KEY,
+    month      DATE NOT NULL,
+    action     VARCHAR(16) NOT NULL,
+    reason     TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,46 @@
+-- amounts are integers in the minor unit of STORE_CURRENCY; BIGINT leaves room
+-- for currencies with cents and for large totals. product_sales_line reads
+-- the sale amounts, so it is recreated around the change.
+DROP VIEW IF EXISTS product_sales_line;
+
+ALTER TABLE product ALTER COLUMN price TYPE BIGINT, ALTER COLUMN cost_price TYPE BIGINT;
+ALTER TABLE transactions ALTER COLUMN total_amount TYPE BIGINT;
+ALTER TABLE transaction_details
+    ALTER COLUMN subtotal TYPE BIGINT,
+    ALTER COLUMN cost_price TYPE BIGINT,
+    ALTER COLUMN discount TYPE BIGINT;
+ALTER TABLE transaction_bundle_component ALTER COLUMN revenue TYPE BIGINT, ALTER COLUMN cost TYPE BIGINT;
+ALTER TABLE pricing_rule ALTER COLUMN price TYPE BIGINT;
+ALTER TABLE kasbon ALTER COLUMN amount TYPE BIGINT;
+ALTER TABLE kasbon_payment ALTER COLUMN amount TYPE BIGINT;
+ALTER TABLE installment_plan ALTER COLUMN total_amount TYPE BIGINT, ALTER COLUMN down_payment TYPE BIGINT;
+ALTER TABLE installment ALTER COLUMN amount TYPE BIGINT, ALTER COLUMN paid_amount TYPE BIGINT;
+ALTER TABLE installment_payment ALTER COLUMN amount TYPE BIGINT;
+ALTER TABLE supplier_price ALTER COLUMN price TYPE BIGINT;
+ALTER TABLE supplier_price_history ALTER COLUMN price TYPE BIGINT;
+ALTER TABLE purchase_order_item ALTER COLUMN unit_cost TYPE BIGINT;
+ALTER TABLE goods_receipt_item ALTER COLUMN unit_cost TYPE BIGINT;
+ALTER TABLE stock_write_off ALTER COLUMN unit_cost TYPE BIGINT;
+ALTER TABLE expense ALTER COLUMN amount TYPE BIGINT;
+ALTER TABLE payment_link ALTER COLUMN amount TYPE BIGINT, ALTER COLUMN paid_amount TYPE BIGINT;
+
+CREATE VIEW product_sales_line AS
+    SELECT td.transaction_id, td.product_id, td.quantity, td.subtotal, td.cost_price * td.quantity AS cost
+    FROM transaction_details td
+    WHERE NOT td.is_bundle
+    UNION ALL
+    SELECT c.transaction_id, c.product_id, c.quantity, c.revenue, c.cost
+    FROM transaction_bundle_component c;
+
+-- prices, costs and sale amounts can't be negative; rows written before are
+-- left as they are
+ALTER TABLE product
+    ADD CONSTRAINT product_price_not_negative CHECK (price >= 0 AND cost_price >= 0) NOT VALID;
+ALTER TABLE transactions
+    ADD CONSTRAINT transactions_total_not_negative CHECK (total_amount >= 0) NOT VALID;
+ALTER TABLE transaction_details
+    ADD CONSTRAINT transaction_details_amount_not_negative CHECK (subtotal >= 0 AND cost_price >= 0 AND discount >= 0) NOT VALID;
+ALTER TABLE purchase_order_item
+    ADD CONSTRAINT purchase_order_item_cost_not_negative CHECK (unit_cost >= 0) NOT VALID;
+ALTER TABLE goods_receipt_item
+    ADD CONSTRAINT goods_receipt_item_cost_not_negative CHECK (unit_cost >= 0) NOT VALID;
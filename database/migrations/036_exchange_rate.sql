@@ -0,0 +1,21 @@
+-- foreign currencies the store accepts and how many whole units of the store
+-- currency one whole unit is worth; fetched rates are refreshed on a schedule
+CREATE TABLE IF NOT EXISTS exchange_rate (
+    currency   VARCHAR(3) PRIMARY KEY,
+    rate       NUMERIC(24, 12) NOT NULL CHECK (rate > 0),
+    fetched    BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- what a sale was paid with: the amount tendered in its currency and, at the
+-- rate of the moment, its worth in the store currency with the change given
+CREATE TABLE IF NOT EXISTS transaction_payment (
+    transaction_id INTEGER PRIMARY KEY REFERENCES transactions(id),
+    currency       VARCHAR(3) NOT NULL,
+    amount         BIGINT NOT NULL CHECK (amount >= 0),
+    rate           NUMERIC(24, 12) NOT NULL,
+    base_amount    BIGINT NOT NULL,
+    change         BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_payment_currency ON transaction_payment(currency);
@@ -0,0 +1,28 @@
+-- prices negotiated with a wholesale customer, e.g. a warung buying by the
+-- carton; while valid they replace the product's price and pricing rules at
+-- checkout for that customer
+CREATE TABLE IF NOT EXISTS price_contract (
+    id          SERIAL PRIMARY KEY,
+    customer_id INTEGER NOT NULL REFERENCES customer(id),
+    name        VARCHAR(100) NOT NULL DEFAULT '',
+    valid_from  DATE NOT NULL,
+    valid_until DATE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at  TIMESTAMPTZ,
+    CHECK (valid_until IS NULL OR valid_until >= valid_from)
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_contract_customer_id ON price_contract(customer_id) WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS price_contract_item (
+    contract_id INTEGER NOT NULL REFERENCES price_contract(id),
+    product_id  INTEGER NOT NULL REFERENCES product(id),
+    price       BIGINT NOT NULL CHECK (price >= 0),
+    PRIMARY KEY (contract_id, product_id)
+);
+
+-- the customer a sale was made to, and the contract a line was priced with
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS customer_id INTEGER REFERENCES customer(id);
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS price_contract_id INTEGER REFERENCES price_contract(id);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_details_price_contract_id ON transaction_details(price_contract_id) WHERE price_contract_id IS NOT NULL;
@@ -0,0 +1,38 @@
+-- couriers who take orders out to customers, e.g. the neighbourhood ojek driver
+CREATE TABLE IF NOT EXISTS courier (
+    id         SERIAL PRIMARY KEY,
+    name       VARCHAR(100) NOT NULL,
+    phone      VARCHAR(20) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+
+-- a sale to be delivered: pending, packed, out_for_delivery, then delivered
+-- with a photo as proof, or cancelled
+CREATE TABLE IF NOT EXISTS delivery (
+    id             SERIAL PRIMARY KEY,
+    transaction_id INTEGER NOT NULL UNIQUE REFERENCES transactions(id),
+    customer_name  VARCHAR(100) NOT NULL DEFAULT '',
+    customer_phone VARCHAR(20) NOT NULL DEFAULT '',
+    address        TEXT NOT NULL,
+    note           TEXT NOT NULL DEFAULT '',
+    courier_id     INTEGER REFERENCES courier(id),
+    status         VARCHAR(20) NOT NULL DEFAULT 'pending',
+    proof_name     TEXT NOT NULL DEFAULT '',
+    proof_type     VARCHAR(50) NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_status ON delivery(status, created_at);
+
+-- every status a delivery went through, for tracking
+CREATE TABLE IF NOT EXISTS delivery_event (
+    id          SERIAL PRIMARY KEY,
+    delivery_id INTEGER NOT NULL REFERENCES delivery(id),
+    status      VARCHAR(20) NOT NULL,
+    courier_id  INTEGER REFERENCES courier(id),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_event_delivery_id ON delivery_event(delivery_id);
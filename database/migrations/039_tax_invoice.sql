@@ -0,0 +1,18 @@
+-- tax invoices (faktur pajak) issued to business buyers, one per sale. The
+-- number is drawn from the tax_invoice receipt_sequence scope of its year in
+-- the same transaction, so issued numbers have no gaps. Amounts are kept as
+-- issued, whatever the tax rate is later.
+CREATE TABLE IF NOT EXISTS tax_invoice (
+    id             SERIAL PRIMARY KEY,
+    number         VARCHAR(50) NOT NULL UNIQUE,
+    transaction_id INTEGER NOT NULL UNIQUE REFERENCES transactions(id),
+    buyer_npwp     VARCHAR(16) NOT NULL,
+    buyer_name     VARCHAR(150) NOT NULL,
+    buyer_address  TEXT NOT NULL,
+    tax_rate       INTEGER NOT NULL CHECK (tax_rate >= 0),
+    tax_base       BIGINT NOT NULL,
+    tax_amount     BIGINT NOT NULL,
+    issued_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tax_invoice_issued_at ON tax_invoice(issued_at);
@@ -0,0 +1,16 @@
+-- sales move draft -> pending_payment -> paid -> completed, or end voided or
+-- refunded; a sale rung up and paid at the counter starts completed. Voided and
+-- refunded sales are soft deleted, so they drop out of the reports.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'completed';
+
+-- every status a sale went through, with the reason given for it
+CREATE TABLE IF NOT EXISTS transaction_status_event (
+    id             SERIAL PRIMARY KEY,
+    transaction_id INTEGER NOT NULL REFERENCES transactions(id),
+    from_status    VARCHAR(20) NOT NULL DEFAULT '',
+    status         VARCHAR(20) NOT NULL,
+    reason         TEXT NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_status_event_transaction_id ON transaction_status_event(transaction_id);
@@ -0,0 +1,23 @@
+-- every product and category row remembers the transaction that last changed
+-- it, so terminals can pull what changed since their cursor. Stock, prices and
+-- soft deletes all count, whichever code path made the change.
+ALTER TABLE product ADD COLUMN IF NOT EXISTS change_txid BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE category ADD COLUMN IF NOT EXISTS change_txid BIGINT NOT NULL DEFAULT 0;
+
+CREATE OR REPLACE FUNCTION record_change_txid() RETURNS trigger AS $$
+BEGIN
+    NEW.change_txid := txid_current();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS product_change_txid ON product;
+CREATE TRIGGER product_change_txid BEFORE INSERT OR UPDATE ON product
+    FOR EACH ROW EXECUTE PROCEDURE record_change_txid();
+
+DROP TRIGGER IF EXISTS category_change_txid ON category;
+CREATE TRIGGER category_change_txid BEFORE INSERT OR UPDATE ON category
+    FOR EACH ROW EXECUTE PROCEDURE record_change_txid();
+
+CREATE INDEX IF NOT EXISTS idx_product_change_txid ON product(change_txid);
+CREATE INDEX IF NOT EXISTS idx_category_change_txid ON category(change_txid);
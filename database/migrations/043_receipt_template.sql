@@ -0,0 +1,17 @@
+-- versions of the store's receipt branding: logo, header and footer text, the
+-- promo message under the footer and, optionally, a layout of its own. Every
+-- save is a new version; the active one is used, and an older one can be made
+-- active again.
+CREATE TABLE IF NOT EXISTS receipt_template (
+    id         SERIAL PRIMARY KEY,
+    logo_url   TEXT NOT NULL DEFAULT '',
+    header     TEXT NOT NULL DEFAULT '',
+    footer     TEXT NOT NULL DEFAULT '',
+    promo      TEXT NOT NULL DEFAULT '',
+    body       TEXT NOT NULL DEFAULT '',
+    note       TEXT NOT NULL DEFAULT '',
+    active     BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_receipt_template_active ON receipt_template(active) WHERE active;
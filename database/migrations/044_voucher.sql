@@ -0,0 +1,24 @@
+-- gift cards and vouchers: a code with a balance in the store currency,
+-- spent at checkout in one or more sales until it runs out or expires at the
+-- end of expires_on
+CREATE TABLE IF NOT EXISTS voucher (
+    id             SERIAL PRIMARY KEY,
+    code           VARCHAR(32) NOT NULL UNIQUE,
+    initial_amount BIGINT NOT NULL CHECK (initial_amount > 0),
+    balance        BIGINT NOT NULL CHECK (balance >= 0),
+    expires_on     DATE,
+    note           TEXT NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- what each sale took from a voucher; a voided or refunded sale gives it back
+CREATE TABLE IF NOT EXISTS voucher_redemption (
+    id             SERIAL PRIMARY KEY,
+    voucher_id     INTEGER NOT NULL REFERENCES voucher(id),
+    transaction_id INTEGER NOT NULL UNIQUE REFERENCES transactions(id),
+    amount         BIGINT NOT NULL CHECK (amount > 0),
+    reversed_at    TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_voucher_redemption_voucher_id ON voucher_redemption(voucher_id);
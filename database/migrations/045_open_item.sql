@@ -0,0 +1,17 @@
+-- open items: sale lines with no product, sold under a description at a
+-- price the cashier enters, e.g. a delivery fee
+ALTER TABLE transaction_details ALTER COLUMN product_id DROP NOT NULL;
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE transaction_details
+    ADD CONSTRAINT transaction_details_product_or_description CHECK (product_id IS NOT NULL OR description <> '') NOT VALID;
+
+CREATE INDEX IF NOT EXISTS idx_transaction_details_open_item ON transaction_details(transaction_id) WHERE product_id IS NULL;
+
+-- open items are reported on their own, not as product sales
+CREATE OR REPLACE VIEW product_sales_line AS
+    SELECT td.transaction_id, td.product_id, td.quantity, td.subtotal, td.cost_price * td.quantity AS cost
+    FROM transaction_details td
+    WHERE NOT td.is_bundle AND td.product_id IS NOT NULL
+    UNION ALL
+    SELECT c.transaction_id, c.product_id, c.quantity, c.revenue, c.cost
+    FROM transaction_bundle_component c;
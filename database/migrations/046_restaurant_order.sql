@@ -0,0 +1,20 @@
+-- restaurant mode: an order is a draft sale served at a table or, taken away,
+-- called out by its number in the queue of the day, and open to more items
+-- until it is billed
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS table_number VARCHAR(16),
+    ADD COLUMN IF NOT EXISTS queue_number INTEGER;
+
+-- a table has at most one open order
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_open_table ON transactions(table_number)
+    WHERE status IN ('draft', 'pending_payment') AND deleted_at IS NULL;
+
+-- the kitchen display reads the open orders
+CREATE INDEX IF NOT EXISTS idx_transactions_open_order ON transactions(id)
+    WHERE status IN ('draft', 'pending_payment') AND deleted_at IS NULL
+        AND (table_number IS NOT NULL OR queue_number IS NOT NULL);
+
+-- when each line was ordered, so the kitchen sees what was added to an order;
+-- lines sold before are left without
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS ordered_at TIMESTAMPTZ;
+ALTER TABLE transaction_details ALTER COLUMN ordered_at SET DEFAULT NOW();
@@ -0,0 +1,40 @@
+-- kitchen printers: each station (kitchen, bar) prints the lines of restaurant
+-- orders in the categories routed to it, the default printer what no station
+-- takes, open items included
+CREATE TABLE IF NOT EXISTS printer (
+    id           SERIAL PRIMARY KEY,
+    name         VARCHAR(100) NOT NULL,
+    address      VARCHAR(255) NOT NULL,
+    category_ids INTEGER[] NOT NULL DEFAULT '{}',
+    is_default   BOOLEAN NOT NULL DEFAULT FALSE,
+    active       BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at   TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_printer_default ON printer((TRUE)) WHERE is_default AND deleted_at IS NULL;
+
+-- a print job is the ticket of one station for the lines an order took at
+-- once, sent by the job runner
+CREATE TABLE IF NOT EXISTS print_job (
+    id             SERIAL PRIMARY KEY,
+    printer_id     INTEGER NOT NULL REFERENCES printer(id),
+    transaction_id INTEGER NOT NULL REFERENCES transactions(id),
+    content        TEXT NOT NULL,
+    status         VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts       INTEGER NOT NULL DEFAULT 0,
+    error          TEXT NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    printed_at     TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_print_job_status ON print_job(status, id);
+CREATE INDEX IF NOT EXISTS idx_print_job_transaction_id ON print_job(transaction_id);
+
+-- when a line was sent to the printers; the lines of orders open now are
+-- taken as sent, so they aren't printed when the order takes more items
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS routed_at TIMESTAMPTZ;
+UPDATE transaction_details SET routed_at = NOW()
+WHERE routed_at IS NULL AND transaction_id IN (
+    SELECT id FROM transactions WHERE status IN ('draft', 'pending_payment') AND deleted_at IS NULL
+);
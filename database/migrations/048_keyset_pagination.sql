@@ -0,0 +1,5 @@
+-- the transaction and stock movement histories are paged by (created_at, id),
+-- newest first, from the last row of the page before
+CREATE INDEX IF NOT EXISTS idx_transactions_created_at_id ON transactions(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_stock_movement_created_at_id ON stock_movement(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_stock_movement_product_created_at_id ON stock_movement(product_id, created_at, id);
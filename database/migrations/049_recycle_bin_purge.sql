@@ -0,0 +1,15 @@
+-- soft deleted rows past the recycle bin retention are purged by a nightly
+-- job; each row purged, or kept because other records still refer to it, is
+-- logged here, dry runs included
+CREATE TABLE IF NOT EXISTS purge_log (
+    id         SERIAL PRIMARY KEY,
+    dry_run    BOOLEAN NOT NULL DEFAULT FALSE,
+    table_name VARCHAR(50) NOT NULL,
+    record_id  INTEGER NOT NULL,
+    deleted_at TIMESTAMPTZ NOT NULL,
+    outcome    VARCHAR(10) NOT NULL,
+    reason     TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_purge_log_table_record ON purge_log(table_name, record_id);
@@ -0,0 +1,24 @@
+-- perishable stock is tracked in batches by expiry date, received with an
+-- expires_on at goods receiving. Sales and write-offs take the batch expiring
+-- first; stock received without a date is taken after every batch. The batches
+-- of a product are only changed with its product row locked.
+CREATE TABLE IF NOT EXISTS stock_batch (
+    id         SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES product(id),
+    quantity   INTEGER NOT NULL CHECK (quantity >= 0),
+    expires_on DATE NOT NULL,
+    receipt_id INTEGER REFERENCES goods_receipt(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_batch_product_expires_on ON stock_batch(product_id, expires_on, id) WHERE quantity > 0;
+CREATE INDEX IF NOT EXISTS idx_stock_batch_expires_on ON stock_batch(expires_on) WHERE quantity > 0;
+
+-- the dated part of what a receiving session scanned, by expiry date
+CREATE TABLE IF NOT EXISTS receiving_session_batch (
+    session_id INTEGER NOT NULL REFERENCES receiving_session(id),
+    product_id INTEGER NOT NULL REFERENCES product(id),
+    expires_on DATE NOT NULL,
+    quantity   INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (session_id, product_id, expires_on)
+);
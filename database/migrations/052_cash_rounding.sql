@@ -0,0 +1,4 @@
+-- totals rounded for cash, e.g. to the nearest 100 rupiah: total_amount is
+-- what the customer paid, rounding_adjustment what rounding added to the lines,
+-- negative when it took off
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS rounding_adjustment BIGINT NOT NULL DEFAULT 0;
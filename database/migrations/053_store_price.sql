@@ -0,0 +1,20 @@
+-- multi-store installs sell from several outlets sharing the catalog; an
+-- outlet may override the price of a product, which checkouts at the outlet
+-- sell it at instead
+CREATE TABLE IF NOT EXISTS store (
+    id         SERIAL PRIMARY KEY,
+    name       VARCHAR(100) NOT NULL,
+    address    TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS store_price (
+    store_id   INTEGER NOT NULL REFERENCES store(id),
+    product_id INTEGER NOT NULL REFERENCES product(id),
+    price      BIGINT NOT NULL CHECK (price >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (store_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_store_price_product ON store_price(product_id);
@@ -0,0 +1,12 @@
+-- managers subscribed to a sales summary by email, daily of yesterday or
+-- weekly of last Monday to Sunday; last_sent_on is the last day of the last
+-- period sent, so a period is never sent twice
+CREATE TABLE IF NOT EXISTS report_subscription (
+    id           SERIAL PRIMARY KEY,
+    email        VARCHAR(255) NOT NULL,
+    frequency    VARCHAR(10) NOT NULL,
+    format       VARCHAR(10) NOT NULL DEFAULT 'html',
+    last_sent_on DATE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (email, frequency)
+);
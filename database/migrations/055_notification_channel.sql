@@ -0,0 +1,11 @@
+-- chats the store's alerts are sent to: a Telegram chat through the store's
+-- bot, or a WhatsApp number through the WhatsApp Business API
+CREATE TABLE IF NOT EXISTS notification_channel (
+    id         SERIAL PRIMARY KEY,
+    provider   VARCHAR(20) NOT NULL,
+    recipient  VARCHAR(100) NOT NULL,
+    events     TEXT[] NOT NULL,
+    active     BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, recipient)
+);
@@ -0,0 +1,13 @@
+-- the store profile edited through /api/settings, a single row; until it is
+-- first saved the settings from the environment stand in for it
+CREATE TABLE IF NOT EXISTS store_settings (
+    id             SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    name           VARCHAR(255) NOT NULL,
+    address        TEXT NOT NULL DEFAULT '',
+    npwp           VARCHAR(16) NOT NULL DEFAULT '',
+    receipt_footer TEXT NOT NULL DEFAULT '',
+    currency       VARCHAR(3) NOT NULL,
+    tax_rate       INTEGER NOT NULL,
+    timezone       VARCHAR(64) NOT NULL,
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
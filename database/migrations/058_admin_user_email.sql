@@ -0,0 +1,16 @@
+-- admins confirm their email address, and reset a forgotten password through
+-- it, with single-use tokens; only a hash of a token's secret part is kept
+ALTER TABLE admin_user ADD COLUMN IF NOT EXISTS email VARCHAR(254) UNIQUE;
+ALTER TABLE admin_user ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS admin_user_token (
+    id            SERIAL PRIMARY KEY,
+    admin_user_id INTEGER NOT NULL REFERENCES admin_user(id) ON DELETE CASCADE,
+    purpose       VARCHAR(20) NOT NULL CHECK (purpose IN ('verify_email', 'reset_password')),
+    token_hash    VARCHAR(64) NOT NULL,
+    expires_at    TIMESTAMPTZ NOT NULL,
+    used_at       TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_user_token_user ON admin_user_token(admin_user_id, purpose, created_at);
@@ -0,0 +1,3 @@
+-- the PIN a cashier switches into a terminal session with, hashed like the
+-- password; admins without one can't use the quick switch
+ALTER TABLE admin_user ADD COLUMN IF NOT EXISTS pin_hash TEXT;
@@ -0,0 +1,45 @@
+-- what each role may do, checked per request when ENFORCE_PERMISSIONS is on;
+-- the admin role holds every permission and isn't listed here
+CREATE TABLE IF NOT EXISTS permission (
+    name        VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS role (
+    name        VARCHAR(32) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS role_permission (
+    role       VARCHAR(32) NOT NULL REFERENCES role(name) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL REFERENCES permission(name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO permission (name, description) VALUES
+    ('product.view', 'View products, categories and their stock'),
+    ('product.write', 'Create, edit and delete products, categories and pricing rules'),
+    ('inventory.manage', 'Receive goods, order from suppliers, count stock and write it off'),
+    ('transaction.create', 'Ring up sales, open orders, hold carts and send payment links'),
+    ('transaction.view', 'View sales and their receipts'),
+    ('transaction.refund', 'Void and refund sales, and move them to another status'),
+    ('customer.manage', 'Manage customers, their installments, price contracts and dunning'),
+    ('report.view', 'View reports, statistics and exports'),
+    ('settings.manage', 'Edit the store settings, stores, printers, receipt templates, webhooks and notification channels'),
+    ('user.manage', 'Register accounts, set their PIN and role, and grant permissions to roles'),
+    ('system.admin', 'Run maintenance: jobs, the request journal, numbering, periods, the recycle bin and diagnostics')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role (name, description) VALUES
+    ('admin', 'Back office, every permission'),
+    ('cashier', 'Point of sale')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permission (role, permission) VALUES
+    ('cashier', 'product.view'),
+    ('cashier', 'transaction.create'),
+    ('cashier', 'transaction.view')
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE admin_user ADD COLUMN IF NOT EXISTS role VARCHAR(32) NOT NULL DEFAULT 'admin' REFERENCES role(name);
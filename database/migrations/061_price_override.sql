@@ -0,0 +1,37 @@
+-- a price or extra discount a cashier gave a sale line, with the manager who
+-- approved it with their PIN, named as they were then. The override replaces the pricing rule or
+-- contract that priced the line; original_subtotal is what it came to then.
+INSERT INTO permission (name, description) VALUES
+    ('transaction.override_price', 'Approve a price override or extra discount on a sale line with a PIN')
+ON CONFLICT (name) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS price_override (
+    detail_id         INTEGER PRIMARY KEY REFERENCES transaction_details(id) ON DELETE CASCADE,
+    transaction_id    INTEGER NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    original_subtotal BIGINT NOT NULL,
+    price             BIGINT CHECK (price > 0),
+    extra_discount    BIGINT NOT NULL DEFAULT 0 CHECK (extra_discount >= 0),
+    reason            TEXT NOT NULL,
+    approved_by       INTEGER NOT NULL REFERENCES admin_user(id),
+    approver          VARCHAR(32) NOT NULL,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_override_transaction_id ON price_override(transaction_id);
+
+-- new_value reads on from the subtotal, e.g. "45000, approved by budi: box damaged"
+CREATE OR REPLACE FUNCTION log_price_override_activity() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO activity_log (entity, entity_id, name, action, old_value, new_value)
+    SELECT 'transaction', t.id, COALESCE(t.receipt_number, ''), 'price_overridden', NEW.original_subtotal::text,
+           format('%s, approved by %s: %s', td.subtotal, NEW.approver, NEW.reason)
+    FROM transactions t
+    INNER JOIN transaction_details td ON td.id = NEW.detail_id
+    WHERE t.id = NEW.transaction_id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS price_override_activity ON price_override;
+CREATE TRIGGER price_override_activity AFTER INSERT ON price_override
+    FOR EACH ROW EXECUTE PROCEDURE log_price_override_activity();
@@ -0,0 +1,5 @@
+-- expenses were open to every signed in cashier; recording them and reading
+-- their receipts now needs a permission of its own, not granted to cashiers
+INSERT INTO permission (name, description) VALUES
+    ('expense.manage', 'Record expenses and read and attach their receipts')
+ON CONFLICT (name) DO NOTHING;
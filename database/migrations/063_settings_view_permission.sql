@@ -0,0 +1,16 @@
+-- terminals read the store currency, PPN rate and time zone from the
+-- settings, which needed transaction.create; reading them is a permission of
+-- its own now, granted to cashiers
+INSERT INTO permission (name, description) VALUES
+    ('settings.view', 'View the store settings: the currency, PPN rate, time zone and receipt details')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permission (role, permission) VALUES
+    ('cashier', 'settings.view')
+ON CONFLICT DO NOTHING;
+
+-- billing and completing a sale needs transaction.create, only voiding and
+-- refunding it transaction.refund
+UPDATE permission SET description = 'Ring up sales and move them on to paid and completed, open orders, hold carts and send payment links'
+WHERE name = 'transaction.create';
+UPDATE permission SET description = 'Void and refund sales' WHERE name = 'transaction.refund';
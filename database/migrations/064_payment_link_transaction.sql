@@ -0,0 +1,6 @@
+-- payment links are for a draft transaction, whose items they no longer
+-- carry; the links made before keep theirs. A link paid short is underpaid,
+-- its sale stays pending_payment.
+ALTER TABLE payment_link ALTER COLUMN items DROP NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_payment_link_transaction ON payment_link(transaction_id);
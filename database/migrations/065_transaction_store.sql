@@ -0,0 +1,3 @@
+-- the outlet a sale was made at, so items added to an open order later are
+-- priced at its prices too; NULL for sales of the catalog's own prices
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS store_id INTEGER REFERENCES store(id);
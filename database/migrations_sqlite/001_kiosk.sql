@@ -0,0 +1,96 @@
+-- the sales flow of a single-terminal kiosk install: catalog, pricing rules,
+-- checkout and the stock ledger. Timestamps are local time, as on PostgreSQL.
+CREATE TABLE IF NOT EXISTS category (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    name        VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    deleted_at  TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS product (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    name          VARCHAR(255) NOT NULL,
+    barcode       VARCHAR(64) NOT NULL DEFAULT '',
+    price         INTEGER NOT NULL DEFAULT 0,
+    cost_price    INTEGER NOT NULL DEFAULT 0,
+    stock         INTEGER NOT NULL DEFAULT 0,
+    reorder_point INTEGER NOT NULL DEFAULT 0,
+    reorder_qty   INTEGER NOT NULL DEFAULT 0,
+    abc_class     CHAR(1) NOT NULL DEFAULT 'C',
+    is_bundle     BOOLEAN NOT NULL DEFAULT FALSE,
+    category_id   INTEGER REFERENCES category(id),
+    deleted_at    TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_barcode ON product(barcode) WHERE barcode <> '' AND deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS product_bundle_item (
+    bundle_id    INTEGER NOT NULL REFERENCES product(id),
+    component_id INTEGER NOT NULL REFERENCES product(id),
+    quantity     INTEGER NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (bundle_id, component_id)
+);
+
+CREATE TABLE IF NOT EXISTS pricing_rule (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id   INTEGER NOT NULL REFERENCES product(id),
+    name         VARCHAR(100) NOT NULL DEFAULT '',
+    min_quantity INTEGER NOT NULL CHECK (min_quantity > 1),
+    price        INTEGER NOT NULL CHECK (price > 0),
+    active       BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at   TIMESTAMP DEFAULT (datetime('now', 'localtime')),
+    deleted_at   TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_pricing_rule_product_id ON pricing_rule(product_id);
+
+CREATE TABLE IF NOT EXISTS receipt_sequence (
+    scope      VARCHAR(100) PRIMARY KEY,
+    last_value BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    receipt_number VARCHAR(50),
+    total_amount   INTEGER NOT NULL DEFAULT 0,
+    created_at     TIMESTAMP NOT NULL DEFAULT (datetime('now', 'localtime')),
+    deleted_at     TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_receipt_number ON transactions(receipt_number);
+
+CREATE TABLE IF NOT EXISTS transaction_details (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    transaction_id  INTEGER NOT NULL REFERENCES transactions(id),
+    product_id      INTEGER NOT NULL REFERENCES product(id),
+    quantity        INTEGER NOT NULL,
+    subtotal        INTEGER NOT NULL,
+    cost_price      INTEGER NOT NULL DEFAULT 0,
+    is_bundle       BOOLEAN NOT NULL DEFAULT FALSE,
+    pricing_rule_id INTEGER REFERENCES pricing_rule(id),
+    discount        INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_details_transaction_id ON transaction_details(transaction_id);
+
+CREATE TABLE IF NOT EXISTS transaction_bundle_component (
+    detail_id      INTEGER NOT NULL REFERENCES transaction_details(id),
+    transaction_id INTEGER NOT NULL REFERENCES transactions(id),
+    product_id     INTEGER NOT NULL REFERENCES product(id),
+    quantity       INTEGER NOT NULL,
+    revenue        INTEGER NOT NULL,
+    cost           INTEGER NOT NULL,
+    PRIMARY KEY (detail_id, product_id)
+);
+
+CREATE TABLE IF NOT EXISTS stock_movement (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id    INTEGER NOT NULL REFERENCES product(id),
+    quantity      INTEGER NOT NULL,
+    balance_after INTEGER NOT NULL,
+    reason        VARCHAR(20) NOT NULL,
+    reference_id  INTEGER,
+    created_at    TIMESTAMP NOT NULL DEFAULT (datetime('now', 'localtime'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movement_product_created_at ON stock_movement(product_id, created_at);
@@ -0,0 +1,5 @@
+-- sales made offline are pushed to the central server; synced_at is set once
+-- the central server has them
+ALTER TABLE transactions ADD COLUMN synced_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_synced_at ON transactions(synced_at);
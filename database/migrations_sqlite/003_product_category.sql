@@ -0,0 +1,15 @@
+-- a product can be in several categories, e.g. "Drinks" and "Promo".
+-- product.category_id stays behind unused: SQLite can't drop a column with a
+-- foreign key.
+CREATE TABLE IF NOT EXISTS product_category (
+    product_id  INTEGER NOT NULL REFERENCES product(id),
+    category_id INTEGER NOT NULL REFERENCES category(id),
+    PRIMARY KEY (product_id, category_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_category_category_id ON product_category(category_id);
+
+INSERT OR IGNORE INTO product_category (product_id, category_id)
+SELECT id, category_id FROM product WHERE category_id IS NOT NULL;
+
+UPDATE product SET category_id = NULL;
@@ -0,0 +1,12 @@
+-- stock keeping unit, the store's own product code; generated when left out
+ALTER TABLE product ADD COLUMN sku VARCHAR(64) NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_sku ON product(sku) WHERE sku <> '' AND deleted_at IS NULL;
+
+-- existing products get SKUs in the default format, and the counter carries on
+-- after them; SQLite needs the WHERE to tell ON CONFLICT from a join constraint
+UPDATE product SET sku = printf('SKU-%06d', id) WHERE sku = '';
+
+INSERT INTO receipt_sequence (scope, last_value)
+SELECT 'sku', COALESCE(MAX(id), 0) FROM product
+WHERE true
+ON CONFLICT (scope) DO NOTHING;
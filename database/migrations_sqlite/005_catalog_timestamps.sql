@@ -0,0 +1,10 @@
+-- when products and categories were created and their details last changed;
+-- SQLite only takes constant column defaults, so existing rows are stamped here
+-- and the repositories set both on every write
+ALTER TABLE category ADD COLUMN created_at TIMESTAMP;
+ALTER TABLE category ADD COLUMN updated_at TIMESTAMP;
+ALTER TABLE product ADD COLUMN created_at TIMESTAMP;
+ALTER TABLE product ADD COLUMN updated_at TIMESTAMP;
+
+UPDATE category SET created_at = datetime('now', 'localtime'), updated_at = datetime('now', 'localtime');
+UPDATE product SET created_at = datetime('now', 'localtime'), updated_at = datetime('now', 'localtime');
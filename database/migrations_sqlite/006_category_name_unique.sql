@@ -0,0 +1,9 @@
+-- category names are unique among active categories, ignoring case (ASCII
+-- only, as SQLite's LOWER); existing duplicates get the id appended
+UPDATE category SET name = name || ' (' || id || ')'
+WHERE deleted_at IS NULL AND EXISTS (
+    SELECT 1 FROM category o
+    WHERE o.deleted_at IS NULL AND LOWER(o.name) = LOWER(category.name) AND o.id < category.id
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_category_name ON category (LOWER(name)) WHERE deleted_at IS NULL;
@@ -0,0 +1,4 @@
+-- kiosk sales carry no customer, the columns keep checkout's statements the
+-- same as on the central server
+ALTER TABLE transactions ADD COLUMN customer_id INTEGER;
+ALTER TABLE transaction_details ADD COLUMN price_contract_id INTEGER;
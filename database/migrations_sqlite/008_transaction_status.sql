@@ -0,0 +1,12 @@
+-- kiosk sales are completed at the counter; the status and its history keep
+-- checkout's statements the same as on the central server
+ALTER TABLE transactions ADD COLUMN status TEXT NOT NULL DEFAULT 'completed';
+
+CREATE TABLE IF NOT EXISTS transaction_status_event (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    transaction_id INTEGER NOT NULL REFERENCES transactions(id),
+    from_status    TEXT NOT NULL DEFAULT '',
+    status         TEXT NOT NULL,
+    reason         TEXT NOT NULL DEFAULT '',
+    created_at     TIMESTAMP DEFAULT (datetime('now', 'localtime'))
+);
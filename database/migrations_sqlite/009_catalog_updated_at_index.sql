@@ -0,0 +1,3 @@
+-- products and categories are polled for changes by updated_at
+CREATE INDEX IF NOT EXISTS idx_product_updated_at ON product(updated_at);
+CREATE INDEX IF NOT EXISTS idx_category_updated_at ON category(updated_at);
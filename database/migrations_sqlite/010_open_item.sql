@@ -0,0 +1,3 @@
+-- kiosks sell no open items, the column keeps checkout's statements the same
+-- as on the central server
+ALTER TABLE transaction_details ADD COLUMN description TEXT NOT NULL DEFAULT '';
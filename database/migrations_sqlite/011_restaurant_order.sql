@@ -0,0 +1,4 @@
+-- kiosks take no restaurant orders, the columns keep checkout's statements
+-- the same as on the central server
+ALTER TABLE transactions ADD COLUMN table_number TEXT;
+ALTER TABLE transactions ADD COLUMN queue_number INTEGER;
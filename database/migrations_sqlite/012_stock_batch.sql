@@ -0,0 +1,12 @@
+-- kiosks receive no goods, so they hold no batches; the table keeps
+-- checkout's statements the same as on the central server
+CREATE TABLE IF NOT EXISTS stock_batch (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL REFERENCES product(id),
+    quantity   INTEGER NOT NULL CHECK (quantity >= 0),
+    expires_on TEXT NOT NULL,
+    receipt_id INTEGER,
+    created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_batch_product_expires_on ON stock_batch(product_id, expires_on, id) WHERE quantity > 0;
@@ -0,0 +1,2 @@
+-- totals rounded for cash, as on the central server
+ALTER TABLE transactions ADD COLUMN rounding_adjustment INTEGER NOT NULL DEFAULT 0;
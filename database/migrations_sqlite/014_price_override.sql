@@ -0,0 +1,13 @@
+-- kiosks take no price overrides, the table keeps checkout's statements the
+-- same as on the central server
+CREATE TABLE IF NOT EXISTS price_override (
+    detail_id         INTEGER PRIMARY KEY REFERENCES transaction_details(id) ON DELETE CASCADE,
+    transaction_id    INTEGER NOT NULL,
+    original_subtotal INTEGER NOT NULL,
+    price             INTEGER,
+    extra_discount    INTEGER NOT NULL DEFAULT 0,
+    reason            TEXT NOT NULL,
+    approved_by       INTEGER NOT NULL,
+    approver          TEXT NOT NULL,
+    created_at        TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
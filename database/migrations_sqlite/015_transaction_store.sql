@@ -0,0 +1,3 @@
+-- the outlet a sale was made at, as on the central server; kiosks sell at
+-- the catalog's own prices, so it stays NULL
+ALTER TABLE transactions ADD COLUMN store_id INTEGER;
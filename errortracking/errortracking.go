@@ -0,0 +1,49 @@
+// Package errortracking reports what went wrong with nobody waiting on it,
+// panics of handlers and failures of background work, to an error tracker
+// such as Sentry. Without a tracker set, reporting only costs a nil check;
+// the errors are logged where they happen either way.
+package errortracking
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Tracker is an error tracker
+type Tracker interface {
+	// ReportError reports err, tagged with what it happened to, e.g. the
+	// job or payment link
+	ReportError(err error, tags map[string]string)
+	// ReportPanic reports the panic a handler raised serving r
+	ReportPanic(r *http.Request, value interface{}, stack []byte)
+}
+
+// holder lets the tracker be swapped atomically while it is an interface
+type holder struct {
+	tracker Tracker
+}
+
+var current atomic.Pointer[holder]
+
+// SetTracker has errors reported from then on sent to t; nil stops reporting
+func SetTracker(t Tracker) {
+	current.Store(&holder{tracker: t})
+}
+
+// ReportError reports err to the tracker set, unless err or the tracker is nil
+func ReportError(err error, tags map[string]string) {
+	h := current.Load()
+	if err == nil || h == nil || h.tracker == nil {
+		return
+	}
+	h.tracker.ReportError(err, tags)
+}
+
+// ReportPanic reports the panic a handler raised serving r to the tracker set
+func ReportPanic(r *http.Request, value interface{}, stack []byte) {
+	h := current.Load()
+	if h == nil || h.tracker == nil {
+		return
+	}
+	h.tracker.ReportPanic(r, value, stack)
+}
@@ -0,0 +1,157 @@
+package errortracking
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"kasir-api/utils"
+)
+
+// Sentry is a Tracker sending events to a Sentry project, or a compatible
+// tracker, over its HTTP store endpoint
+type Sentry struct {
+	storeURL    string
+	auth        string
+	environment string
+	release     string
+	Client      *http.Client
+}
+
+// NewSentry reports to the project of dsn, as shown in the project's
+// settings, e.g. https://<key>@o123.ingest.sentry.io/456. Events are tagged
+// with environment and release when they are set.
+func NewSentry(dsn, environment, release string) (*Sentry, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Sentry DSN: %v", err)
+	}
+	key := u.User.Username()
+	projectID := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" || projectID == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN: want scheme://key@host/project")
+	}
+
+	// a DSN may sit under a path prefix, e.g. a self-hosted install behind a proxy
+	prefix := ""
+	if i := strings.LastIndex(projectID, "/"); i >= 0 {
+		prefix, projectID = "/"+projectID[:i], projectID[i+1:]
+	}
+
+	return &Sentry{
+		storeURL:    fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, projectID),
+		auth:        "Sentry sentry_version=7, sentry_client=kasir-api/1.0, sentry_key=" + key,
+		environment: environment,
+		release:     release,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// event is the part of Sentry's event payload filled in here
+type event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Transaction string            `json:"transaction,omitempty"`
+	Exception   exceptions        `json:"exception"`
+	Request     *request          `json:"request,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]string `json:"extra"`
+}
+
+type exceptions struct {
+	Values []exception `json:"values"`
+}
+
+type exception struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// request leaves out headers and body, which carry customers' details
+type request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+// ReportError sends err, with the stack it was reported from
+func (s *Sentry) ReportError(err error, tags map[string]string) {
+	ev := s.event("error", fmt.Sprintf("%T", err), err.Error(), debug.Stack())
+	ev.Tags = tags
+	s.sendAsync(ev)
+}
+
+// ReportPanic sends the panic a handler raised serving r, with its stack
+func (s *Sentry) ReportPanic(r *http.Request, value interface{}, stack []byte) {
+	ev := s.event("fatal", fmt.Sprintf("%T", value), fmt.Sprint(value), stack)
+	ev.Transaction = r.Method + " " + r.URL.Path
+	ev.Request = &request{Method: r.Method, URL: r.URL.Path}
+	if requestID := r.Header.Get(utils.RequestIDHeader); requestID != "" {
+		ev.Tags = map[string]string{"request_id": requestID}
+	}
+	s.sendAsync(ev)
+}
+
+func (s *Sentry) event(level, typ, value string, stack []byte) event {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return event{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Level:       level,
+		Platform:    "go",
+		Logger:      "kasir-api",
+		Environment: s.environment,
+		Release:     s.release,
+		Exception:   exceptions{Values: []exception{{Type: typ, Value: value}}},
+		Extra:       map[string]string{"stack": string(stack)},
+	}
+}
+
+// sendAsync sends ev in the background, so neither a response nor the work
+// that failed is held up by the tracker
+func (s *Sentry) sendAsync(ev event) {
+	go func() {
+		if err := s.send(ev); err != nil {
+			log.Println("Error reporting to Sentry:", err)
+		}
+	}()
+}
+
+func (s *Sentry) send(ev event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.storeURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sentry responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package events
+
+import "sync"
+
+// listenerBuffer is how many events a slow listener may fall behind before
+// the ones after are dropped for it
+const listenerBuffer = 32
+
+// Broadcaster fans the events it handles out to the listeners connected at
+// the time, e.g. the dashboards streaming them; a listener that falls
+// behind misses events rather than holding up the publisher
+type Broadcaster struct {
+	mu        sync.Mutex
+	listeners map[chan Event]struct{}
+}
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{listeners: make(map[chan Event]struct{})}
+}
+
+// Listen returns the channel the events handled from now on arrive on, and
+// the function to stop listening with
+func (b *Broadcaster) Listen() (<-chan Event, func()) {
+	ch := make(chan Event, listenerBuffer)
+	b.mu.Lock()
+	b.listeners[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.listeners, ch)
+		b.mu.Unlock()
+	}
+}
+
+// Handle is the bus Handler of the broadcaster
+func (b *Broadcaster) Handle(event Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.listeners {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+// Package events is the in-process bus domain events are published on, so
+// what follows a sale or a catalog change (webhooks, chat alerts, cached
+// numbers, live dashboards) subscribes to it instead of being called by the
+// service that made the change. The events of business writes reach it
+// through the outbox, once the writes are committed.
+package events
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"kasir-api/errortracking"
+)
+
+// domain events, named after what happened
+const (
+	ProductCreated       = "product.created"
+	ProductLowStock      = "product.low_stock"
+	TransactionCompleted = "transaction.completed"
+	RefundIssued         = "refund.issued"
+	PaymentLinkPaid      = "payment_link.paid"
+)
+
+// Event is something that happened; Payload is what it happened to, e.g. the
+// *models.Product of a ProductCreated with its categories, the
+// *models.Transaction of a TransactionCompleted and the []models.Product that
+// just reached their reorder point of a ProductLowStock
+type Event struct {
+	Name       string
+	Payload    interface{}
+	OccurredAt time.Time
+}
+
+// Handler handles an event for a subscriber; it runs on the publisher's
+// goroutine, so anything slow belongs in a job
+type Handler func(Event) error
+
+type subscription struct {
+	subscriber string
+	names      map[string]bool
+	handle     Handler
+}
+
+// Bus delivers every event published on it to the subscribers of its name,
+// in the order they subscribed. A nil Bus drops every event, as kiosk
+// installs and the CLI have nothing subscribing.
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions []subscription
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe has handle called with the events of names, or with every event
+// when none are given; subscriber names it in the log when it fails
+func (b *Bus) Subscribe(subscriber string, handle Handler, names ...string) {
+	sub := subscription{subscriber: subscriber, handle: handle}
+	if len(names) > 0 {
+		sub.names = make(map[string]bool, len(names))
+		for _, name := range names {
+			sub.names[name] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions = append(b.subscriptions, sub)
+}
+
+// Publish hands the event to its subscribers. Their failures are logged,
+// not returned: what happened already has, whatever a subscriber makes of it.
+func (b *Bus) Publish(name string, payload interface{}) {
+	b.Deliver(Event{Name: name, Payload: payload, OccurredAt: time.Now()})
+}
+
+// Deliver hands an event that happened earlier to its subscribers, as
+// Publish does, e.g. one relayed from the outbox
+func (b *Bus) Deliver(event Event) {
+	for _, sub := range b.subscribers(event.Name) {
+		deliver(sub, event)
+	}
+}
+
+func (b *Bus) subscribers(name string) []subscription {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var subs []subscription
+	for _, sub := range b.subscriptions {
+		if sub.names == nil || sub.names[name] {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// deliver calls one subscriber, so its panic doesn't keep the event from the
+// others or fail the request that published it
+func deliver(sub subscription, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Panic handling", event.Name, "in", sub.subscriber+":", r)
+			errortracking.ReportError(fmt.Errorf("panic: %v", r), map[string]string{"event": event.Name, "subscriber": sub.subscriber})
+		}
+	}()
+	if err := sub.handle(event); err != nil {
+		log.Println("Error handling", event.Name, "in", sub.subscriber+":", err)
+		errortracking.ReportError(err, map[string]string{"event": event.Name, "subscriber": sub.subscriber})
+	}
+}
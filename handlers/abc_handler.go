@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type ABCHandler struct {
+	service *services.ABCService
+}
+
+func NewABCHandler(service *services.ABCService) *ABCHandler {
+	return &ABCHandler{service: service}
+}
+
+// GetABCSummary godoc
+// @Summary      Get ABC classification summary
+// @Description  Get the number of products and the revenue share of each ABC class
+// @Tags         abc-classification
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /abc-classification [get]
+func (h *ABCHandler) GetABCSummary(w http.ResponseWriter, r *http.Request) {
+	summary, err := h.service.GetSummary()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch ABC classification: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "ABC classification retrieved successfully",
+		Data:    summary,
+	})
+}
+
+// RunABCClassification godoc
+// @Summary      Run ABC classification
+// @Description  Reclassify every product by its share of revenue over the last 90 days: A items make up the first 80% of revenue, B the next 15% and C the rest. Runs nightly on the ABC schedule.
+// @Tags         abc-classification
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /abc-classification/run [post]
+func (h *ABCHandler) RunABCClassification(w http.ResponseWriter, r *http.Request) {
+	classified, err := h.service.Classify()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to run ABC classification: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "ABC classification completed successfully",
+		Data:    map[string]int{"classified": classified},
+	})
+}
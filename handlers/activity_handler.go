@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type ActivityHandler struct {
+	service *services.ActivityService
+}
+
+func NewActivityHandler(service *services.ActivityService) *ActivityHandler {
+	return &ActivityHandler{service: service}
+}
+
+// GetActivity godoc
+// @Summary      Get the activity feed
+// @Description  Get what happened to the catalog and to sales, newest first, a page at a time: products and categories created, renamed, repriced or deleted, sales voided or refunded, and lines of sales repriced at a price override a manager approved. Send the next_cursor of a page as cursor to get the page after it; the last page has none.
+// @Tags         report
+// @Accept       json
+// @Produce      json
+// @Param        entity  query     string  false  "Only the activity of this kind of record"  Enums(product, category, transaction)
+// @Param        cursor  query     string  false  "next_cursor of the page before"
+// @Param        limit   query     int     false  "Maximum number of entries (default 50, at most 200)"
+// @Success      200     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /activity [get]
+func (h *ActivityHandler) GetActivity(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid limit",
+			})
+			return
+		}
+		limit = l
+	}
+
+	page, err := h.service.GetPage(r.URL.Query().Get("entity"), r.URL.Query().Get("cursor"), limit)
+	if err == services.ErrInvalidActivityEntity || err == services.ErrInvalidCursor {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch activity: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Activity retrieved successfully",
+		Data:    page,
+	})
+}
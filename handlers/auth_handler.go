@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type AuthHandler struct {
+	service     *services.AuthService
+	permissions *services.PermissionService
+}
+
+func NewAuthHandler(service *services.AuthService, permissions *services.PermissionService) *AuthHandler {
+	return &AuthHandler{service: service, permissions: permissions}
+}
+
+// writeAuthError answers a failed auth request with the status of its cause
+func writeAuthError(w http.ResponseWriter, err error, action string) {
+	status := http.StatusInternalServerError
+	message := "Failed to " + action + ": " + err.Error()
+	switch {
+	case err == services.ErrInvalidUsername || err == services.ErrInvalidEmail || err == services.ErrWeakPassword || err == repositories.ErrInvalidAuthToken ||
+		err == services.ErrRoleRequired || errors.Is(err, repositories.ErrRoleNotFound):
+		status, message = http.StatusBadRequest, err.Error()
+	case errors.Is(err, services.ErrNotPermitted):
+		status, message = http.StatusForbidden, err.Error()
+	case err == repositories.ErrDuplicateAdminUser:
+		status, message = http.StatusConflict, err.Error()
+	case err == services.ErrAuthNotConfigured:
+		status, message = http.StatusServiceUnavailable, err.Error()
+	}
+	utils.WriteJSON(w, status, utils.Response{
+		Status:  "failed",
+		Message: message,
+	})
+}
+
+// RegisterAdmin godoc
+// @Summary      Register an admin
+// @Description  Create a back-office account in a role (400 for a missing or unknown one). Only a cashier signed in with system.admin can create one in the admin role, or another role granting system.admin (403). With an email, a link to confirm the address is mailed there (503 when AUTH_TOKEN_SECRET isn't set); if that email fails the admin is still created, and the link can be sent again.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        X-Terminal-Session  header    string                      false  "Terminal session token"
+// @Param        admin               body      models.RegisterAdminRequest  true   "Admin"
+// @Success      201                 {object}  utils.Response
+// @Failure      400                 {object}  utils.Response
+// @Failure      403                 {object}  utils.Response
+// @Failure      409                 {object}  utils.Response
+// @Failure      503                 {object}  utils.Response
+// @Failure      500                 {object}  utils.Response
+// @Router       /admin/users [post]
+func (h *AuthHandler) RegisterAdmin(w http.ResponseWriter, r *http.Request) {
+	var req models.RegisterAdminRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	if err := h.permissions.AuthorizeRole(r.Context(), strings.TrimSpace(r.Header.Get("X-Terminal-Session")), req.Role); err != nil {
+		writeAuthError(w, err, "register admin")
+		return
+	}
+
+	admin, err := h.service.Register(req)
+	if errors.Is(err, services.ErrVerificationNotSent) {
+		utils.WriteJSON(w, http.StatusCreated, utils.Response{
+			Status:  "success",
+			Message: "Admin created, but the verification email could not be sent; send it again from /api/auth/verify-email/resend",
+			Data:    admin,
+		})
+		return
+	}
+	if err != nil {
+		writeAuthError(w, err, "register admin")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Admin registered successfully",
+		Data:    admin,
+	})
+}
+
+// VerifyEmail godoc
+// @Summary      Verify an admin's email
+// @Description  Confirm an admin's email address with the token of the link mailed there. A token works once, until it expires (AUTH_VERIFY_TOKEN_TTL). Rate limited per client (AUTH_RATE_LIMIT).
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        token  body      models.VerifyEmailRequest  true  "Verification token"
+// @Success      200    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      429    {object}  utils.Response
+// @Failure      503    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	if err := h.service.VerifyEmail(req.Token); err != nil {
+		writeAuthError(w, err, "verify email")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Email verified successfully",
+	})
+}
+
+// ResendVerification godoc
+// @Summary      Resend an email verification link
+// @Description  Mail a new link to confirm an unverified admin address. The answer is the same whether or not the address belongs to an admin; at most 3 links an hour are sent to one. Rate limited per client (AUTH_RATE_LIMIT).
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        email  body      models.AuthEmailRequest  true  "Email"
+// @Success      202    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      429    {object}  utils.Response
+// @Failure      503    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /auth/verify-email/resend [post]
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req models.AuthEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	if err := h.service.ResendVerification(req.Email); err != nil {
+		writeAuthError(w, err, "resend verification")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, utils.Response{
+		Status:  "success",
+		Message: "If the address belongs to an unverified admin, a verification link is on its way",
+	})
+}
+
+// ForgotPassword godoc
+// @Summary      Request a password reset link
+// @Description  Mail a link to reset the password to an admin's address. The answer is the same whether or not the address belongs to an admin; at most 3 links an hour are sent to one, each working once until it expires (AUTH_RESET_TOKEN_TTL). Rate limited per client (AUTH_RATE_LIMIT).
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        email  body      models.AuthEmailRequest  true  "Email"
+// @Success      202    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      429    {object}  utils.Response
+// @Failure      503    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /auth/forgot-password [post]
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.AuthEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	if err := h.service.ForgotPassword(req.Email); err != nil {
+		writeAuthError(w, err, "request password reset")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, utils.Response{
+		Status:  "success",
+		Message: "If the address belongs to an admin, a password reset link is on its way",
+	})
+}
+
+// ResetPassword godoc
+// @Summary      Reset a password
+// @Description  Set a new password with the token of a password reset link. The admin's other reset links stop working, and the address counts as verified. Rate limited per client (AUTH_RATE_LIMIT).
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        reset  body      models.ResetPasswordRequest  true  "Token and new password"
+// @Success      200    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      429    {object}  utils.Response
+// @Failure      503    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	if err := h.service.ResetPassword(req.Token, req.Password); err != nil {
+		writeAuthError(w, err, "reset password")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Password reset successfully",
+	})
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+// availabilityWidget is the script stores embed on their own website
+//
+//go:embed availability_widget.js
+var availabilityWidget []byte
+
+type AvailabilityHandler struct {
+	service *services.AvailabilityService
+}
+
+func NewAvailabilityHandler(service *services.AvailabilityService) *AvailabilityHandler {
+	return &AvailabilityHandler{service: service}
+}
+
+// GetPublicAvailability godoc
+// @Summary      Get public product availability
+// @Description  Get whether the listed products are in stock, for the store's website. Only products opted in with PUT /product/{id}/public are returned, without quantities or prices. Open to anyone, from any origin, and rate limited per client.
+// @Tags         public
+// @Accept       json
+// @Produce      json
+// @Param        ids  query     string  false  "Comma-separated product IDs, at most 100; every published product when empty"  example(1,2,3)
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      429  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /public/availability [get]
+func (h *AvailabilityHandler) GetPublicAvailability(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	products, err := h.service.GetPublic(r.URL.Query().Get("ids"))
+	if err == services.ErrInvalidProductIDs || err == services.ErrTooManyProductIDs {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch availability",
+		})
+		return
+	}
+
+	// a minute of staleness keeps most page views off the database
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Availability retrieved successfully",
+		Data:    products,
+	})
+}
+
+// GetAvailabilityWidget godoc
+// @Summary      Get the availability widget script
+// @Description  Get the script to embed on the store's website. It fills every element with a data-kasir-product="{id}" attribute with "In stock" or "Out of stock" and sets data-kasir-in-stock to true or false for styling.
+// @Tags         public
+// @Produce      application/javascript
+// @Success      200  {string}  string
+// @Failure      429  {object}  utils.Response
+// @Router       /public/availability.js [get]
+func (h *AvailabilityHandler) GetAvailabilityWidget(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Write(availabilityWidget)
+}
+
+// SetPublicAvailability godoc
+// @Summary      Publish a product's availability
+// @Description  Opt a product in to or out of the public availability widget. Products are private until published.
+// @Tags         product
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                               true  "Product ID"
+// @Param        request  body      models.PublicAvailabilityRequest  true  "Whether the product is shown on the widget"
+// @Success      200      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      404      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /product/{id}/public [put]
+func (h *AvailabilityHandler) SetPublicAvailability(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/product/")
+	idStr = strings.TrimSuffix(idStr, "/public")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Product ID",
+		})
+		return
+	}
+
+	var req models.PublicAvailabilityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	err = h.service.SetPublic(id, req.Public)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Product not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to update product: " + err.Error(),
+		})
+		return
+	}
+
+	message := "Product availability is no longer public"
+	if req.Public {
+		message = "Product availability is now public"
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: message,
+		Data:    req,
+	})
+}
@@ -0,0 +1,59 @@
+// Kasir product availability widget.
+//
+// Include it on any page with
+//
+//   <script src="https://your-store/api/public/availability.js" defer></script>
+//
+// and mark up each product with its ID:
+//
+//   <span data-kasir-product="42"></span>
+//
+// The element's text becomes "In stock" or "Out of stock" (override with
+// data-in-stock-text and data-out-of-stock-text) and its data-kasir-in-stock
+// attribute is set to "true" or "false" for styling. Products the store has
+// not published are left untouched.
+(function () {
+  var script = document.currentScript;
+  var origin = new URL(script.src).origin;
+  var batch = 100;
+
+  function render(elements, products) {
+    var byID = {};
+    products.forEach(function (p) { byID[p.id] = p; });
+
+    elements.forEach(function (el) {
+      var p = byID[el.getAttribute("data-kasir-product")];
+      if (!p) {
+        return;
+      }
+      el.setAttribute("data-kasir-in-stock", p.in_stock ? "true" : "false");
+      el.textContent = p.in_stock
+        ? el.getAttribute("data-in-stock-text") || "In stock"
+        : el.getAttribute("data-out-of-stock-text") || "Out of stock";
+    });
+  }
+
+  function load() {
+    var elements = Array.prototype.slice.call(document.querySelectorAll("[data-kasir-product]"));
+    var ids = [];
+    elements.forEach(function (el) {
+      var id = el.getAttribute("data-kasir-product");
+      if (/^\d+$/.test(id) && ids.indexOf(id) < 0) {
+        ids.push(id);
+      }
+    });
+
+    for (var i = 0; i < ids.length; i += batch) {
+      fetch(origin + "/api/public/availability?ids=" + ids.slice(i, i + batch).join(","))
+        .then(function (res) { return res.ok ? res.json() : { data: [] }; })
+        .then(function (body) { render(elements, body.data || []); })
+        .catch(function () {});
+    }
+  }
+
+  if (document.readyState === "loading") {
+    document.addEventListener("DOMContentLoaded", load);
+  } else {
+    load();
+  }
+})();
@@ -3,11 +3,14 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"kasir-api/models"
+	"kasir-api/repositories"
 	"kasir-api/services"
 	"kasir-api/utils"
 )
@@ -22,12 +25,14 @@ func NewCategoryHandler(service *services.CategoryService) *CategoryHandler {
 
 // GetCategoryByID godoc
 // @Summary      Get a category by ID
-// @Description  Get a category by its ID
+// @Description  Get a category by its ID. Send the ETag of the response back in If-None-Match to get a 304 without a body while it is unchanged.
 // @Tags         category
 // @Accept       json
 // @Produce      json
-// @Param        id   path      int  true  "Category ID"
-// @Success      200  {object}  utils.Response
+// @Param        id                 path      int     true   "Category ID"
+// @Param        If-None-Match      header    string  false  "ETag of the cached category"
+// @Success      200                {object}  utils.Response
+// @Success      304                "Not Modified"
 // @Failure      400  {object}  utils.Response
 // @Failure      404  {object}  utils.Response
 // @Failure      500  {object}  utils.Response
@@ -71,15 +76,17 @@ func (h *CategoryHandler) GetCategoryByID(w http.ResponseWriter, r *http.Request
 
 // DeleteCategory godoc
 // @Summary      Delete a category
-// @Description  Soft delete a category by ID
+// @Description  Soft delete a category by ID. What happens to its active products depends on policy, CATEGORY_DELETE_POLICY (block unless set) when left out: block refuses while it has any (409 with their count), cascade soft deletes those it leaves without a category, reassign moves those to the Uncategorized category, created when missing. Products in another category only leave this one.
 // @Tags         category
 // @Accept       json
 // @Produce      json
-// @Param        id   path      int  true  "Category ID"
-// @Success      200  {object}  utils.Response
-// @Failure      400  {object}  utils.Response
-// @Failure      404  {object}  utils.Response
-// @Failure      500  {object}  utils.Response
+// @Param        id      path      int     true   "Category ID"
+// @Param        policy  query     string  false  "What to do with its products"  Enums(block, cascade, reassign)
+// @Success      200     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      404     {object}  utils.Response
+// @Failure      409     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
 // @Router       /category/{id} [delete]
 func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
 	// get id
@@ -95,7 +102,15 @@ func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = h.Service.Delete(id)
+	deletion, err := h.Service.Delete(id, r.URL.Query().Get("policy"))
+	if err == services.ErrInvalidDeletePolicy {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
 	if err == sql.ErrNoRows {
 		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
 			Status:  "failed",
@@ -104,6 +119,25 @@ func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	var inUse *repositories.CategoryHasProductsError
+	if errors.As(err, &inUse) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:    "failed",
+			Message:   err.Error() + ", delete it with policy cascade or reassign",
+			ErrorCode: "category_has_products",
+			Data:      map[string]int{"products": inUse.Products},
+		})
+		return
+	}
+
+	if err == repositories.ErrDeleteUncategorized {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
 	if err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
 			Status:  "failed",
@@ -115,6 +149,7 @@ func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request)
 	utils.WriteJSON(w, http.StatusOK, utils.Response{
 		Status:  "success",
 		Message: "Category deleted successfully",
+		Data:    deletion,
 	})
 }
 
@@ -129,6 +164,7 @@ func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request)
 // @Success      200       {object}  utils.Response
 // @Failure      400       {object}  utils.Response
 // @Failure      404       {object}  utils.Response
+// @Failure      409       {object}  utils.Response
 // @Failure      500       {object}  utils.Response
 // @Router       /category/{id} [put]
 func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
@@ -184,6 +220,14 @@ func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request)
 
 	// Update category di database
 	updatedCategory, err := h.Service.Update(existingCategory)
+	if err == repositories.ErrDuplicateCategoryName {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: "A category named \"" + strings.TrimSpace(existingCategory.Name) + "\" already exists",
+		})
+		return
+	}
+
 	if err == sql.ErrNoRows {
 		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
 			Status:  "failed",
@@ -207,17 +251,125 @@ func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// CreateCategoryBatch godoc
+// @Summary      Create categories in a batch
+// @Description  Create up to 500 categories in one database transaction, e.g. to set up a catalog, each as a create of it would. The result of each is at its index in the request: created with the category, or failed with why, e.g. a name already taken. Categories that fail are left out and the others are saved; with atomic none is saved unless all can be (422 otherwise), those that could have been are skipped.
+// @Tags         category
+// @Accept       json
+// @Produce      json
+// @Param        categories  body      []models.Category  true   "Categories"
+// @Param        atomic      query     boolean            false  "Save all categories or none"
+// @Success      200         {object}  utils.Response
+// @Failure      400         {object}  utils.Response
+// @Failure      422         {object}  utils.Response
+// @Failure      500         {object}  utils.Response
+// @Router       /category/batch [post]
+func (h *CategoryHandler) CreateCategoryBatch(w http.ResponseWriter, r *http.Request) {
+	h.saveCategoryBatch(w, r, "created", h.Service.CreateBatch)
+}
+
+// UpdateCategoryBatch godoc
+// @Summary      Update categories in a batch
+// @Description  Update up to 500 categories in one database transaction, each as an update of it would: id names the category and fields left out are kept. The result of each is at its index in the request: updated with the category, or failed with why. Categories that fail are left out and the others are saved; with atomic none is saved unless all can be (422 otherwise), those that could have been are skipped.
+// @Tags         category
+// @Accept       json
+// @Produce      json
+// @Param        categories  body      []models.Category  true   "Category changes"
+// @Param        atomic      query     boolean            false  "Save all categories or none"
+// @Success      200         {object}  utils.Response
+// @Failure      400         {object}  utils.Response
+// @Failure      422         {object}  utils.Response
+// @Failure      500         {object}  utils.Response
+// @Router       /category/batch [put]
+func (h *CategoryHandler) UpdateCategoryBatch(w http.ResponseWriter, r *http.Request) {
+	h.saveCategoryBatch(w, r, "updated", h.Service.UpdateBatch)
+}
+
+func (h *CategoryHandler) saveCategoryBatch(w http.ResponseWriter, r *http.Request, saved string, save func([]models.Category, bool) ([]models.CategoryBatchResult, error)) {
+	atomic, _ := strconv.ParseBool(r.URL.Query().Get("atomic"))
+
+	var categories []models.Category
+	if err := json.NewDecoder(r.Body).Decode(&categories); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	results, err := save(categories, atomic)
+	if err == services.ErrInvalidCategoryBatch {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to save categories: " + err.Error(),
+		})
+		return
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Status == services.BatchFailed {
+			failed++
+		}
+	}
+
+	if atomic && failed > 0 {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Response{
+			Status:    "failed",
+			Message:   fmt.Sprintf("%d of %d categories failed, none was saved", failed, len(results)),
+			ErrorCode: "batch_failed",
+			Data:      results,
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: fmt.Sprintf("%d of %d categories %s", len(results)-failed, len(results), saved),
+		Data:    results,
+	})
+}
+
 // GetCategories godoc
 // @Summary      Get all categories
-// @Description  Get a list of all active categories
+// @Description  Get a list of all active categories. To poll for changes, pass the latest updated_at seen as updated_since: categories changed since are returned, deleted ones with deleted_at set. The response carries an ETag: send it back in If-None-Match to get a 304 without a body while the list is unchanged.
 // @Tags         category
 // @Accept       json
 // @Produce      json
-// @Success      200  {object}  utils.Response
+// @Param        sort               query     string  false  "Sort by name, created_at or updated_at, prefixed with - for descending (default id)"
+// @Param        updated_since      query     string  false  "Only categories updated at or after this time, deleted ones included (RFC 3339 or YYYY-MM-DD HH:MM:SS)"
+// @Param        If-None-Match      header    string  false  "ETag of the cached list"
+// @Success      200                {object}  utils.Response
+// @Success      304                "Not Modified"
+// @Failure      400  {object}  utils.Response
 // @Failure      500  {object}  utils.Response
 // @Router       /category [get]
 func (h *CategoryHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
-	categories, err := h.Service.GetAll()
+	updatedSince, err := updatedSinceFromQuery(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid updated_since",
+		})
+		return
+	}
+
+	categories, err := h.Service.GetAll(r.URL.Query().Get("sort"), updatedSince)
+	if err == services.ErrInvalidSort {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
 	if err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
 			Status:  "failed",
@@ -235,13 +387,16 @@ func (h *CategoryHandler) GetCategories(w http.ResponseWriter, r *http.Request)
 
 // CreateCategory godoc
 // @Summary      Create a new category
-// @Description  Create a new category with the provided details
+// @Description  Create a new category with the provided details. Names are unique among active categories, ignoring case; a taken name is refused with 409, or with upsert=true the existing category is returned with 200, its description updated when one is given.
 // @Tags         category
 // @Accept       json
 // @Produce      json
-// @Param        category  body      models.Category  true  "Category Data"
+// @Param        category  body      models.Category  true   "Category Data"
+// @Param        upsert    query     bool             false  "Return the existing category of the same name instead of refusing it"
+// @Success      200       {object}  utils.Response
 // @Success      201       {object}  utils.Response
 // @Failure      400       {object}  utils.Response
+// @Failure      409       {object}  utils.Response
 // @Failure      500       {object}  utils.Response
 // @Router       /category [post]
 func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
@@ -256,7 +411,42 @@ func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if r.URL.Query().Get("upsert") == "true" {
+		category, created, err := h.Service.Upsert(categoryBaru)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+				Status:  "failed",
+				Message: "Failed to save category: " + err.Error(),
+			})
+			return
+		}
+
+		if !created {
+			utils.WriteJSON(w, http.StatusOK, utils.Response{
+				Status:  "success",
+				Message: "Category already exists",
+				Data:    category,
+			})
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusCreated, utils.Response{
+			Status:  "success",
+			Message: "Category created successfully",
+			Data:    category,
+		})
+		return
+	}
+
 	category, err := h.Service.Create(categoryBaru)
+	if err == repositories.ErrDuplicateCategoryName {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: "A category named \"" + strings.TrimSpace(categoryBaru.Name) + "\" already exists",
+		})
+		return
+	}
+
 	if err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
 			Status:  "failed",
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type CostHandler struct {
+	service *services.CostService
+}
+
+func NewCostHandler(service *services.CostService) *CostHandler {
+	return &CostHandler{service: service}
+}
+
+// RecalculateCosts godoc
+// @Summary      Recalculate product costs
+// @Description  Rebuild the cost price of every product ever received as the moving average of its goods receipts, e.g. after correcting a receipt's unit cost. Lists the products whose cost changes with the impact on the stock value and on shrinkage valued at the cost price; profit of past sales keeps the cost recorded at checkout. Set dry_run to see the changes without applying them.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        dry_run  query     boolean  false  "Only report what would change"
+// @Success      200      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /admin/costs/recalculate [post]
+func (h *CostHandler) RecalculateCosts(w http.ResponseWriter, r *http.Request) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	result, err := h.service.Recalculate(dryRun)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to recalculate costs: " + err.Error(),
+		})
+		return
+	}
+
+	message := "Costs recalculated successfully"
+	if dryRun {
+		message = "Dry run, nothing was saved"
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: message,
+		Data:    result,
+	})
+}
+
+// CorrectReceiptCost godoc
+// @Summary      Correct a goods receipt cost
+// @Description  Fix the unit cost recorded for a product on a goods receipt. Cost prices change only on the next recalculation. Receipts of a closed accounting period can't be corrected.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id          path      int                           true  "Goods receipt ID"
+// @Param        correction  body      models.ReceiptCostCorrection  true  "Product and unit cost"
+// @Success      200         {object}  utils.Response
+// @Failure      400         {object}  utils.Response
+// @Failure      404         {object}  utils.Response
+// @Failure      409         {object}  utils.Response
+// @Failure      500         {object}  utils.Response
+// @Router       /admin/costs/receipts/{id} [put]
+func (h *CostHandler) CorrectReceiptCost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/costs/receipts/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Goods receipt ID",
+		})
+		return
+	}
+
+	var correction models.ReceiptCostCorrection
+	if err := json.NewDecoder(r.Body).Decode(&correction); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	err = h.service.CorrectReceiptCost(id, correction)
+	if err == services.ErrInvalidCostCorrection {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == repositories.ErrReceiptItemNotFound {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if errors.Is(err, repositories.ErrPeriodClosed) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to correct receipt cost: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Receipt cost corrected successfully",
+		Data:    correction,
+	})
+}
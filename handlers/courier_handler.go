@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type CourierHandler struct {
+	service *services.DeliveryService
+}
+
+func NewCourierHandler(service *services.DeliveryService) *CourierHandler {
+	return &CourierHandler{service: service}
+}
+
+// GetCouriers godoc
+// @Summary      Get couriers
+// @Description  Get the couriers deliveries can be given to
+// @Tags         deliveries
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /couriers [get]
+func (h *CourierHandler) GetCouriers(w http.ResponseWriter, r *http.Request) {
+	couriers, err := h.service.GetCouriers()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch couriers: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Couriers retrieved successfully",
+		Data:    couriers,
+	})
+}
+
+// CreateCourier godoc
+// @Summary      Create a courier
+// @Description  Add a courier; the phone is written in international form
+// @Tags         deliveries
+// @Accept       json
+// @Produce      json
+// @Param        courier  body      models.Courier  true  "Courier"
+// @Success      201      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /couriers [post]
+func (h *CourierHandler) CreateCourier(w http.ResponseWriter, r *http.Request) {
+	var courier models.Courier
+	if err := json.NewDecoder(r.Body).Decode(&courier); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	courier, err := h.service.CreateCourier(courier)
+	if err == services.ErrCourierNameRequired || err == services.ErrInvalidPhone {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to create courier: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Courier created successfully",
+		Data:    courier,
+	})
+}
+
+// UpdateCourier godoc
+// @Summary      Update a courier
+// @Description  Update a courier's name and phone
+// @Tags         deliveries
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int             true  "Courier ID"
+// @Param        courier  body      models.Courier  true  "Courier"
+// @Success      200      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      404      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /couriers/{id} [put]
+func (h *CourierHandler) UpdateCourier(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/couriers/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Courier ID",
+		})
+		return
+	}
+
+	var courier models.Courier
+	if err := json.NewDecoder(r.Body).Decode(&courier); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+	courier.ID = id
+
+	courier, err = h.service.UpdateCourier(courier)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Courier not found",
+		})
+		return
+	}
+
+	if err == services.ErrCourierNameRequired || err == services.ErrInvalidPhone {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to update courier: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Courier updated successfully",
+		Data:    courier,
+	})
+}
+
+// DeleteCourier godoc
+// @Summary      Delete a courier
+// @Description  Soft delete a courier; the deliveries they made keep them
+// @Tags         deliveries
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Courier ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /couriers/{id} [delete]
+func (h *CourierHandler) DeleteCourier(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/couriers/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Courier ID",
+		})
+		return
+	}
+
+	err = h.service.DeleteCourier(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Courier not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to delete courier: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Courier deleted successfully",
+	})
+}
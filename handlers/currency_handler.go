@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type CurrencyHandler struct {
+	service *services.CurrencyService
+}
+
+func NewCurrencyHandler(service *services.CurrencyService) *CurrencyHandler {
+	return &CurrencyHandler{service: service}
+}
+
+// GetCurrencies godoc
+// @Summary      Get accepted currencies
+// @Description  Get the store currency and the foreign currencies accepted as payment, with how many units of the store currency one unit of each is worth
+// @Tags         currency
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /currencies [get]
+func (h *CurrencyHandler) GetCurrencies(w http.ResponseWriter, r *http.Request) {
+	currencies, err := h.service.GetAccepted()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch currencies: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Currencies retrieved successfully",
+		Data:    currencies,
+	})
+}
+
+// SetCurrency godoc
+// @Summary      Accept a foreign currency
+// @Description  Accept payment in a currency at a rate set by hand, or at the rate fetched from the exchange rate API when fetched is set; fetched rates are then refreshed on a schedule. Setting an accepted currency again replaces its rate.
+// @Tags         currency
+// @Accept       json
+// @Produce      json
+// @Param        code  path      string                      true  "ISO 4217 currency code"
+// @Param        rate  body      models.ExchangeRateRequest  true  "Exchange rate"
+// @Success      200   {object}  utils.Response
+// @Failure      400   {object}  utils.Response
+// @Failure      500   {object}  utils.Response
+// @Router       /currencies/{code} [put]
+func (h *CurrencyHandler) SetCurrency(w http.ResponseWriter, r *http.Request) {
+	var req models.ExchangeRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	rate, err := h.service.Set(strings.TrimPrefix(r.URL.Path, "/api/currencies/"), req)
+	if err == services.ErrUnknownCurrency || err == services.ErrBaseCurrencyRate || err == services.ErrInvalidRate ||
+		err == services.ErrNoRateSource || err == services.ErrRateNotQuoted {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to set exchange rate: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Exchange rate set successfully",
+		Data:    rate,
+	})
+}
+
+// DeleteCurrency godoc
+// @Summary      Stop accepting a foreign currency
+// @Description  Stop accepting payment in a currency; sales already paid in it keep their payment
+// @Tags         currency
+// @Accept       json
+// @Produce      json
+// @Param        code  path      string  true  "ISO 4217 currency code"
+// @Success      200   {object}  utils.Response
+// @Failure      404   {object}  utils.Response
+// @Failure      500   {object}  utils.Response
+// @Router       /currencies/{code} [delete]
+func (h *CurrencyHandler) DeleteCurrency(w http.ResponseWriter, r *http.Request) {
+	err := h.service.Delete(strings.TrimPrefix(r.URL.Path, "/api/currencies/"))
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Currency is not accepted",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to delete currency: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Currency deleted successfully",
+	})
+}
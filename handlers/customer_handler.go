@@ -0,0 +1,600 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type CustomerHandler struct {
+	Service *services.CustomerService
+}
+
+func NewCustomerHandler(service *services.CustomerService) *CustomerHandler {
+	return &CustomerHandler{Service: service}
+}
+
+// customerIDFromPath parses the ID out of /api/customer/{id}[suffix]
+func customerIDFromPath(path, suffix string) (int, error) {
+	idStr := strings.TrimPrefix(path, "/api/customer/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	return strconv.Atoi(idStr)
+}
+
+// GetCustomers godoc
+// @Summary      Get all customers
+// @Description  Get a list of all active customers
+// @Tags         customer
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /customer [get]
+func (h *CustomerHandler) GetCustomers(w http.ResponseWriter, r *http.Request) {
+	customers, err := h.Service.GetAll()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch customers: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Customers retrieved successfully",
+		Data:    customers,
+	})
+}
+
+// GetCustomerByID godoc
+// @Summary      Get a customer by ID
+// @Description  Get a customer by its ID
+// @Tags         customer
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Customer ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /customer/{id} [get]
+func (h *CustomerHandler) GetCustomerByID(w http.ResponseWriter, r *http.Request) {
+	id, err := customerIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Customer ID",
+		})
+		return
+	}
+
+	customer, err := h.Service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Customer not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch customer: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Customer retrieved successfully",
+		Data:    customer,
+	})
+}
+
+// CreateCustomer godoc
+// @Summary      Create a new customer
+// @Description  Create a new customer with the provided details. The phone number is stored in international form, e.g. +6281234567890.
+// @Tags         customer
+// @Accept       json
+// @Produce      json
+// @Param        customer  body      models.Customer  true  "Customer Data"
+// @Success      201       {object}  utils.Response
+// @Failure      400       {object}  utils.Response
+// @Failure      500       {object}  utils.Response
+// @Router       /customer [post]
+func (h *CustomerHandler) CreateCustomer(w http.ResponseWriter, r *http.Request) {
+	var customerReq models.Customer
+	err := json.NewDecoder(r.Body).Decode(&customerReq)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	customer, err := h.Service.Create(customerReq)
+	if err == services.ErrInvalidChannel || err == services.ErrInvalidPhone || err == services.ErrInvalidEmail {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to save customer: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Customer created successfully",
+		Data:    customer,
+	})
+}
+
+// UpdateCustomer godoc
+// @Summary      Update a customer
+// @Description  Update a customer by ID
+// @Tags         customer
+// @Accept       json
+// @Produce      json
+// @Param        id        path      int              true  "Customer ID"
+// @Param        customer  body      models.Customer  true  "Customer Data"
+// @Success      200       {object}  utils.Response
+// @Failure      400       {object}  utils.Response
+// @Failure      404       {object}  utils.Response
+// @Failure      500       {object}  utils.Response
+// @Router       /customer/{id} [put]
+func (h *CustomerHandler) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	id, err := customerIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Customer ID",
+		})
+		return
+	}
+
+	var updateReq models.Customer
+	err = json.NewDecoder(r.Body).Decode(&updateReq)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	existingCustomer, err := h.Service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Customer not found",
+		})
+		return
+	}
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch customer: " + err.Error(),
+		})
+		return
+	}
+
+	if updateReq.Name != "" {
+		existingCustomer.Name = updateReq.Name
+	}
+	if updateReq.Phone != "" {
+		existingCustomer.Phone = updateReq.Phone
+	}
+	if updateReq.Email != "" {
+		existingCustomer.Email = updateReq.Email
+	}
+	if updateReq.ReminderChannel != "" {
+		existingCustomer.ReminderChannel = updateReq.ReminderChannel
+	}
+
+	updatedCustomer, err := h.Service.Update(existingCustomer)
+	if err == services.ErrInvalidChannel || err == services.ErrInvalidPhone || err == services.ErrInvalidEmail {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to update customer: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Customer updated successfully",
+		Data:    updatedCustomer,
+	})
+}
+
+// DeleteCustomer godoc
+// @Summary      Delete a customer
+// @Description  Soft delete a customer by ID
+// @Tags         customer
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Customer ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /customer/{id} [delete]
+func (h *CustomerHandler) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
+	id, err := customerIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Customer ID",
+		})
+		return
+	}
+
+	err = h.Service.Delete(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Customer not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to delete customer: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Customer deleted successfully",
+	})
+}
+
+// GetCustomerKasbon godoc
+// @Summary      Get customer kasbon
+// @Description  Get kasbon entries, payments, outstanding and overdue balance of a customer
+// @Tags         customer
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Customer ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /customer/{id}/kasbon [get]
+func (h *CustomerHandler) GetCustomerKasbon(w http.ResponseWriter, r *http.Request) {
+	id, err := customerIDFromPath(r.URL.Path, "/kasbon")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Customer ID",
+		})
+		return
+	}
+
+	if _, err := h.Service.GetByID(id); err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Customer not found",
+		})
+		return
+	}
+
+	credit, err := h.Service.GetCredit(id)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch kasbon: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Kasbon retrieved successfully",
+		Data:    credit,
+	})
+}
+
+// CreateKasbon godoc
+// @Summary      Record kasbon
+// @Description  Record new store credit (kasbon) for a customer with a due date
+// @Tags         customer
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int            true  "Customer ID"
+// @Param        kasbon  body      models.Kasbon  true  "Kasbon Data"
+// @Success      201     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      404     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /customer/{id}/kasbon [post]
+func (h *CustomerHandler) CreateKasbon(w http.ResponseWriter, r *http.Request) {
+	id, err := customerIDFromPath(r.URL.Path, "/kasbon")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Customer ID",
+		})
+		return
+	}
+
+	var kasbonReq models.Kasbon
+	if err := json.NewDecoder(r.Body).Decode(&kasbonReq); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	if _, err := h.Service.GetByID(id); err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Customer not found",
+		})
+		return
+	}
+
+	kasbonReq.CustomerID = id
+	kasbon, err := h.Service.CreateKasbon(kasbonReq)
+	if errors.Is(err, services.ErrInvalidAmount) || errors.Is(err, services.ErrInvalidDueDate) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to save kasbon: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Kasbon recorded successfully",
+		Data:    kasbon,
+	})
+}
+
+// CreateKasbonPayment godoc
+// @Summary      Record kasbon payment
+// @Description  Record a kasbon repayment for a customer; reminders stop once the overdue balance is settled
+// @Tags         customer
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                   true  "Customer ID"
+// @Param        payment  body      models.KasbonPayment  true  "Payment Data"
+// @Success      201      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      404      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /customer/{id}/payment [post]
+func (h *CustomerHandler) CreateKasbonPayment(w http.ResponseWriter, r *http.Request) {
+	id, err := customerIDFromPath(r.URL.Path, "/payment")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Customer ID",
+		})
+		return
+	}
+
+	var paymentReq models.KasbonPayment
+	if err := json.NewDecoder(r.Body).Decode(&paymentReq); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	if _, err := h.Service.GetByID(id); err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Customer not found",
+		})
+		return
+	}
+
+	paymentReq.CustomerID = id
+	payment, err := h.Service.CreatePayment(paymentReq)
+	if err == services.ErrInvalidAmount {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to save payment: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Payment recorded successfully",
+		Data:    payment,
+	})
+}
+
+// ImportCustomers godoc
+// @Summary      Import customers from CSV
+// @Description  Import customers from a CSV file of at most 5 MB with a header row. Recognized columns are name (or nama), phone (telepon, hp, no_hp, whatsapp), email and reminder_channel; other columns are ignored. Phone numbers are normalized to international form. Rows whose phone or email is already known are skipped and invalid rows are reported; the valid rows are saved together. Set dry_run to check a file without saving it.
+// @Tags         customer
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file     formData  file     true   "CSV file"
+// @Param        dry_run  query     boolean  false  "Only report what would be imported"
+// @Success      200      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /customer/import [post]
+func (h *CustomerHandler) ImportCustomers(w http.ResponseWriter, r *http.Request) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	// leaves room for the multipart framing around the file
+	r.Body = http.MaxBytesReader(w, r.Body, 6<<20)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Missing or too large file",
+		})
+		return
+	}
+	defer file.Close()
+
+	result, err := h.Service.Import(file, dryRun)
+	if errors.Is(err, services.ErrInvalidImportCSV) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to import customers: " + err.Error(),
+		})
+		return
+	}
+
+	message := "Customers imported successfully"
+	if dryRun {
+		message = "Dry run, nothing was saved"
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: message,
+		Data:    result,
+	})
+}
+
+// GetDuplicateCustomers godoc
+// @Summary      Find duplicate customers
+// @Description  Group active customers that share a phone number or email, directly or through another customer in the group, as candidates for a merge
+// @Tags         customer
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /customer/duplicates [get]
+func (h *CustomerHandler) GetDuplicateCustomers(w http.ResponseWriter, r *http.Request) {
+	duplicates, err := h.Service.FindDuplicates()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to find duplicate customers: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Duplicate customers retrieved successfully",
+		Data:    duplicates,
+	})
+}
+
+// MergeCustomers godoc
+// @Summary      Merge duplicate customers
+// @Description  Merge the customers in customer_ids into this one: their kasbon, payments, reminder history, installment plans, price contracts and sales move over, a missing phone or email is filled in from them, and they are deleted
+// @Tags         customer
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                          true  "Customer ID to keep"
+// @Param        request  body      models.CustomerMergeRequest  true  "Customers to merge"
+// @Success      200      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      404      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /customer/{id}/merge [post]
+func (h *CustomerHandler) MergeCustomers(w http.ResponseWriter, r *http.Request) {
+	id, err := customerIDFromPath(r.URL.Path, "/merge")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Customer ID",
+		})
+		return
+	}
+
+	var req models.CustomerMergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	customer, err := h.Service.Merge(id, req.CustomerIDs)
+	if err == services.ErrInvalidMerge {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == repositories.ErrCustomerNotFound {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to merge customers: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Customers merged successfully",
+		Data:    customer,
+	})
+}
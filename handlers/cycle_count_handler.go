@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type CycleCountHandler struct {
+	service *services.CycleCountService
+}
+
+func NewCycleCountHandler(service *services.CycleCountService) *CycleCountHandler {
+	return &CycleCountHandler{service: service}
+}
+
+// GetCycleCountTasks godoc
+// @Summary      Get cycle count tasks
+// @Description  Get the products scheduled for counting, optionally filtered by date and status
+// @Tags         cycle-count
+// @Accept       json
+// @Produce      json
+// @Param        date    query     string  false  "Scheduled date (YYYY-MM-DD)"
+// @Param        status  query     string  false  "Filter by status"  Enums(pending, counted)
+// @Success      200     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /cycle-count/tasks [get]
+func (h *CycleCountHandler) GetCycleCountTasks(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	if date != "" {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid date format, use YYYY-MM-DD",
+			})
+			return
+		}
+	}
+
+	tasks, err := h.service.GetTasks(date, r.URL.Query().Get("status"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch cycle count tasks: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Cycle count tasks retrieved successfully",
+		Data:    tasks,
+	})
+}
+
+// GenerateCycleCountTasks godoc
+// @Summary      Generate cycle count tasks
+// @Description  Pick today's rotating, ABC-weighted subset of products to count. Runs daily on the cycle count schedule.
+// @Tags         cycle-count
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /cycle-count/generate [post]
+func (h *CycleCountHandler) GenerateCycleCountTasks(w http.ResponseWriter, r *http.Request) {
+	created, err := h.service.Generate(time.Now().Format("2006-01-02"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to generate cycle count tasks: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Cycle count tasks generated successfully",
+		Data:    map[string]int{"created": created},
+	})
+}
+
+// RecordCycleCount godoc
+// @Summary      Record a cycle count
+// @Description  Record the counted quantity of a task; the product stock is corrected to the counted quantity
+// @Tags         cycle-count
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                       true  "Cycle Count Task ID"
+// @Param        count  body      models.CycleCountRequest  true  "Count Data"
+// @Success      200    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      404    {object}  utils.Response
+// @Failure      409    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /cycle-count/tasks/{id}/count [post]
+func (h *CycleCountHandler) RecordCycleCount(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/cycle-count/tasks/")
+	idStr = strings.TrimSuffix(idStr, "/count")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Cycle Count Task ID",
+		})
+		return
+	}
+
+	var req models.CycleCountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	task, err := h.service.RecordCount(id, req.CountedQty)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Cycle count task not found",
+		})
+		return
+	}
+
+	if err == services.ErrInvalidAmount {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == repositories.ErrTaskAlreadyCounted {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to record cycle count: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Cycle count recorded successfully",
+		Data:    task,
+	})
+}
+
+// GetCountAccuracy godoc
+// @Summary      Get count accuracy trends
+// @Description  Get the accuracy of recorded stock against cycle counts over time, per product
+// @Tags         cycle-count
+// @Accept       json
+// @Produce      json
+// @Param        product_id  query     int  false  "Product ID"
+// @Success      200         {object}  utils.Response
+// @Failure      400         {object}  utils.Response
+// @Failure      500         {object}  utils.Response
+// @Router       /cycle-count/accuracy [get]
+func (h *CycleCountHandler) GetCountAccuracy(w http.ResponseWriter, r *http.Request) {
+	productID := 0
+	if productIDStr := r.URL.Query().Get("product_id"); productIDStr != "" {
+		id, err := strconv.Atoi(productIDStr)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid Product ID",
+			})
+			return
+		}
+		productID = id
+	}
+
+	accuracy, err := h.service.GetAccuracy(productID)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch count accuracy: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Count accuracy retrieved successfully",
+		Data:    accuracy,
+	})
+}
@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/storage"
+	"kasir-api/utils"
+)
+
+type DeliveryHandler struct {
+	service *services.DeliveryService
+}
+
+func NewDeliveryHandler(service *services.DeliveryService) *DeliveryHandler {
+	return &DeliveryHandler{service: service}
+}
+
+// deliveryIDFromPath parses the ID out of /api/deliveries/{id}[suffix]
+func deliveryIDFromPath(path, suffix string) (int, error) {
+	idStr := strings.TrimPrefix(path, "/api/deliveries/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	return strconv.Atoi(idStr)
+}
+
+// writeDeliveryError writes the response for an error of a delivery change
+func writeDeliveryError(w http.ResponseWriter, err error, failure string) {
+	switch err {
+	case sql.ErrNoRows:
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Delivery not found",
+		})
+	case services.ErrAddressRequired, services.ErrInvalidPhone, services.ErrInvalidPhoto,
+		repositories.ErrTransactionNotFound, repositories.ErrCourierNotFound:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+	case services.ErrDeliveryTransition, services.ErrCourierRequired, services.ErrDeliveryClosed,
+		repositories.ErrDeliveryExists, repositories.ErrDeliveryChanged:
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+	default:
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: failure + ": " + err.Error(),
+		})
+	}
+}
+
+// GetDeliveries godoc
+// @Summary      Get deliveries
+// @Description  Get the deliveries, newest first, optionally only those in a status or of a courier
+// @Tags         deliveries
+// @Accept       json
+// @Produce      json
+// @Param        status      query     string  false  "Status"  Enums(pending, packed, out_for_delivery, delivered, cancelled)
+// @Param        courier_id  query     int     false  "Courier ID"
+// @Success      200         {object}  utils.Response
+// @Failure      400         {object}  utils.Response
+// @Failure      500         {object}  utils.Response
+// @Router       /deliveries [get]
+func (h *DeliveryHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	courierID := 0
+	if courierStr := r.URL.Query().Get("courier_id"); courierStr != "" {
+		id, err := strconv.Atoi(courierStr)
+		if err != nil || id <= 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid courier_id",
+			})
+			return
+		}
+		courierID = id
+	}
+
+	deliveries, err := h.service.GetAll(r.URL.Query().Get("status"), courierID)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch deliveries: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Deliveries retrieved successfully",
+		Data:    deliveries,
+	})
+}
+
+// CreateDelivery godoc
+// @Summary      Mark a transaction for delivery
+// @Description  Mark a sale to be delivered to the customer's address, optionally with its courier. It starts pending; the customer's phone is written in international form and gets a WhatsApp message when the order goes out, arrives or is cancelled.
+// @Tags         deliveries
+// @Accept       json
+// @Produce      json
+// @Param        delivery  body      models.DeliveryRequest  true  "Delivery"
+// @Success      201       {object}  utils.Response
+// @Failure      400       {object}  utils.Response
+// @Failure      409       {object}  utils.Response
+// @Failure      500       {object}  utils.Response
+// @Router       /deliveries [post]
+func (h *DeliveryHandler) CreateDelivery(w http.ResponseWriter, r *http.Request) {
+	var req models.DeliveryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	delivery, err := h.service.Create(req)
+	if err != nil {
+		writeDeliveryError(w, err, "Failed to create delivery")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Delivery created successfully",
+		Data:    delivery,
+	})
+}
+
+// GetDeliveryByID godoc
+// @Summary      Get a delivery
+// @Description  Get a delivery with its courier and every status it went through
+// @Tags         deliveries
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Delivery ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /deliveries/{id} [get]
+func (h *DeliveryHandler) GetDeliveryByID(w http.ResponseWriter, r *http.Request) {
+	id, err := deliveryIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Delivery ID",
+		})
+		return
+	}
+
+	delivery, err := h.service.GetByID(id)
+	if err != nil {
+		writeDeliveryError(w, err, "Failed to fetch delivery")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Delivery retrieved successfully",
+		Data:    delivery,
+	})
+}
+
+// AssignDeliveryCourier godoc
+// @Summary      Assign a courier
+// @Description  Give a delivery to a courier, or to another one, until it is delivered or cancelled
+// @Tags         deliveries
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                            true  "Delivery ID"
+// @Param        courier  body      models.DeliveryCourierRequest  true  "Courier"
+// @Success      200      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      404      {object}  utils.Response
+// @Failure      409      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /deliveries/{id}/courier [put]
+func (h *DeliveryHandler) AssignDeliveryCourier(w http.ResponseWriter, r *http.Request) {
+	id, err := deliveryIDFromPath(r.URL.Path, "/courier")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Delivery ID",
+		})
+		return
+	}
+
+	var req models.DeliveryCourierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	delivery, err := h.service.AssignCourier(id, req)
+	if err != nil {
+		writeDeliveryError(w, err, "Failed to assign courier")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Courier assigned successfully",
+		Data:    delivery,
+	})
+}
+
+// UpdateDeliveryStatus godoc
+// @Summary      Update a delivery's status
+// @Description  Move a delivery on: pending to packed, packed to out_for_delivery once it has a courier, out_for_delivery back to packed, or any of them to cancelled. It becomes delivered by uploading its photo proof.
+// @Tags         deliveries
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                           true  "Delivery ID"
+// @Param        status  body      models.DeliveryStatusRequest  true  "Status"
+// @Success      200     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      404     {object}  utils.Response
+// @Failure      409     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /deliveries/{id}/status [put]
+func (h *DeliveryHandler) UpdateDeliveryStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := deliveryIDFromPath(r.URL.Path, "/status")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Delivery ID",
+		})
+		return
+	}
+
+	var req models.DeliveryStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	delivery, err := h.service.UpdateStatus(id, req)
+	if err != nil {
+		writeDeliveryError(w, err, "Failed to update delivery status")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Delivery status updated successfully",
+		Data:    delivery,
+	})
+}
+
+// UploadDeliveryProof godoc
+// @Summary      Upload the proof of delivery
+// @Description  Upload a JPEG, PNG or WebP photo taken at the door, at most 5 MB, marking a delivery that is out for delivery as delivered
+// @Tags         deliveries
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        id     path      int   true  "Delivery ID"
+// @Param        photo  formData  file  true  "Proof photo"
+// @Success      200    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      404    {object}  utils.Response
+// @Failure      409    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /deliveries/{id}/proof [put]
+func (h *DeliveryHandler) UploadDeliveryProof(w http.ResponseWriter, r *http.Request) {
+	id, err := deliveryIDFromPath(r.URL.Path, "/proof")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Delivery ID",
+		})
+		return
+	}
+
+	// leaves room for the multipart framing around the photo
+	r.Body = http.MaxBytesReader(w, r.Body, 6<<20)
+	photo, _, err := r.FormFile("photo")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Missing or too large photo",
+		})
+		return
+	}
+	defer photo.Close()
+
+	delivery, err := h.service.SetProof(id, photo)
+	if err != nil {
+		writeDeliveryError(w, err, "Failed to upload proof")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Delivery marked delivered successfully",
+		Data:    delivery,
+	})
+}
+
+// GetDeliveryProof godoc
+// @Summary      Get the proof of delivery
+// @Description  Download the photo taken when a delivery arrived
+// @Tags         deliveries
+// @Produce      image/jpeg,image/png,image/webp
+// @Param        id   path      int  true  "Delivery ID"
+// @Success      200  {file}    file
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /deliveries/{id}/proof [get]
+func (h *DeliveryHandler) GetDeliveryProof(w http.ResponseWriter, r *http.Request) {
+	id, err := deliveryIDFromPath(r.URL.Path, "/proof")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Delivery ID",
+		})
+		return
+	}
+
+	delivery, f, err := h.service.OpenProof(id)
+	if err == sql.ErrNoRows || err == storage.ErrNotFound {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Proof not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to open proof: " + err.Error(),
+		})
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", delivery.ProofType)
+	w.Header().Set("Content-Disposition", `inline; filename="`+path.Base(delivery.ProofName)+`"`)
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, f)
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type DiagnosticsHandler struct {
+	service *services.DiagnosticsService
+}
+
+func NewDiagnosticsHandler(service *services.DiagnosticsService) *DiagnosticsHandler {
+	return &DiagnosticsHandler{service: service}
+}
+
+// GetVars answers /debug/vars with the build, goroutine count, memory and
+// database pool of the running process. It sits next to the pprof profiles
+// on the loopback listener served when DIAGNOSTICS is on, never on the API's.
+func (h *DiagnosticsHandler) GetVars(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Diagnostics retrieved successfully",
+		Data:    h.service.Snapshot(),
+	})
+}
@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/storage"
+	"kasir-api/utils"
+)
+
+type DisplayHandler struct {
+	service *services.DisplayService
+}
+
+func NewDisplayHandler(service *services.DisplayService) *DisplayHandler {
+	return &DisplayHandler{service: service}
+}
+
+// GetDisplayPlaylist godoc
+// @Summary      Get the customer display playlist
+// @Description  Get the images and videos the customer-facing display shows while idle at this time of day, in playing order. The response carries the playlist version as its ETag; poll with If-None-Match to get 304 Not Modified until the playlist or the schedule changes.
+// @Tags         display
+// @Accept       json
+// @Produce      json
+// @Param        If-None-Match  header    string  false  "ETag of the playlist the display has"
+// @Success      200            {object}  utils.Response
+// @Success      304            "Playlist unchanged"
+// @Failure      500            {object}  utils.Response
+// @Router       /display/playlist [get]
+func (h *DisplayHandler) GetDisplayPlaylist(w http.ResponseWriter, r *http.Request) {
+	playlist, err := h.service.Playlist(time.Now())
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch playlist: " + err.Error(),
+		})
+		return
+	}
+
+	etag := `"` + playlist.Version + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Playlist retrieved successfully",
+		Data:    playlist,
+	})
+}
+
+// GetDisplayMediaFile godoc
+// @Summary      Get a playlist file
+// @Description  Download the image or video of a playlist item. A file never changes once uploaded, so displays may cache it for a day; range requests are supported for video.
+// @Tags         display
+// @Produce      image/jpeg,image/png,image/webp,image/gif,video/mp4,video/webm
+// @Param        id   path      int  true  "Playlist item ID"
+// @Success      200  {file}    file
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /display/media/{id} [get]
+func (h *DisplayHandler) GetDisplayMediaFile(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/display/media/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid playlist item ID",
+		})
+		return
+	}
+
+	media, f, err := h.service.OpenMedia(id)
+	if err == sql.ErrNoRows || err == storage.ErrNotFound {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Playlist item not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to open playlist item: " + err.Error(),
+		})
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", media.ContentType)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("ETag", `"`+path.Base(media.FileName)+`"`)
+
+	// local files can seek, which ServeContent needs for range requests and If-None-Match
+	if rs, ok := f.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, path.Base(media.FileName), time.Time{}, rs)
+		return
+	}
+	if r.Header.Get("If-None-Match") == w.Header().Get("ETag") {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, f)
+}
+
+// GetDisplayMedia godoc
+// @Summary      Get all playlist items
+// @Description  Get every item of the customer display playlist, inactive and not scheduled now ones too
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /admin/display/media [get]
+func (h *DisplayHandler) GetDisplayMedia(w http.ResponseWriter, r *http.Request) {
+	media, err := h.service.GetAll()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch playlist items: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Playlist items retrieved successfully",
+		Data:    media,
+	})
+}
+
+// UploadDisplayMedia godoc
+// @Summary      Add a playlist item
+// @Description  Upload a JPEG, PNG, WebP or GIF image of at most 5 MB or an MP4 or WebM video of at most 50 MB to the customer display playlist. Without start and end time it plays all day; an item ending before it starts plays past midnight.
+// @Tags         admin
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file              formData  file     true   "Image or video"
+// @Param        title             formData  string   false  "Title"
+// @Param        duration_seconds  formData  int      false  "Seconds an image stays on screen; 0 plays a video to its end"
+// @Param        position          formData  int      false  "Place in the playlist, lowest first"
+// @Param        start_time        formData  string   false  "Time of day it starts playing, HH:MM"
+// @Param        end_time          formData  string   false  "Time of day it stops playing, HH:MM"
+// @Param        active            formData  boolean  false  "Whether it plays (default true)"
+// @Success      201               {object}  utils.Response
+// @Failure      400               {object}  utils.Response
+// @Failure      500               {object}  utils.Response
+// @Router       /admin/display/media [post]
+func (h *DisplayHandler) UploadDisplayMedia(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxDisplayUpload)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Missing or too large file",
+		})
+		return
+	}
+	defer file.Close()
+
+	req := models.DisplayMediaRequest{
+		Title:     r.FormValue("title"),
+		StartTime: r.FormValue("start_time"),
+		EndTime:   r.FormValue("end_time"),
+		Active:    r.FormValue("active") != "false",
+	}
+	for name, value := range map[string]*int{"duration_seconds": &req.DurationSeconds, "position": &req.Position} {
+		if s := r.FormValue(name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+					Status:  "failed",
+					Message: "Invalid " + name,
+				})
+				return
+			}
+			*value = n
+		}
+	}
+
+	media, err := h.service.Upload(req, file)
+	if err == services.ErrInvalidDisplayMedia || err == services.ErrInvalidDisplaySchedule {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to upload playlist item: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Playlist item added successfully",
+		Data:    media,
+	})
+}
+
+// UpdateDisplayMedia godoc
+// @Summary      Update a playlist item
+// @Description  Change the title, playing order and schedule of a playlist item; the file stays
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int                         true  "Playlist item ID"
+// @Param        item  body      models.DisplayMediaRequest  true  "Playlist item"
+// @Success      200   {object}  utils.Response
+// @Failure      400   {object}  utils.Response
+// @Failure      404   {object}  utils.Response
+// @Failure      500   {object}  utils.Response
+// @Router       /admin/display/media/{id} [put]
+func (h *DisplayHandler) UpdateDisplayMedia(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/display/media/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid playlist item ID",
+		})
+		return
+	}
+
+	var req models.DisplayMediaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	media, err := h.service.Update(id, req)
+	if err == services.ErrInvalidDisplaySchedule {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Playlist item not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to update playlist item: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Playlist item updated successfully",
+		Data:    media,
+	})
+}
+
+// DeleteDisplayMedia godoc
+// @Summary      Delete a playlist item
+// @Description  Remove an item from the customer display playlist along with its file
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Playlist item ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /admin/display/media/{id} [delete]
+func (h *DisplayHandler) DeleteDisplayMedia(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/display/media/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid playlist item ID",
+		})
+		return
+	}
+
+	err = h.service.Delete(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Playlist item not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to delete playlist item: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Playlist item deleted successfully",
+	})
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type DunningHandler struct {
+	service *services.DunningService
+}
+
+func NewDunningHandler(service *services.DunningService) *DunningHandler {
+	return &DunningHandler{service: service}
+}
+
+// GetReminderHistory godoc
+// @Summary      Get customer reminder history
+// @Description  Get all kasbon reminders sent (or attempted) to a customer, newest first
+// @Tags         dunning
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Customer ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /customer/{id}/reminders [get]
+func (h *DunningHandler) GetReminderHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := customerIDFromPath(r.URL.Path, "/reminders")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Customer ID",
+		})
+		return
+	}
+
+	history, err := h.service.GetHistory(id)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch reminder history: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Reminder history retrieved successfully",
+		Data:    history,
+	})
+}
+
+// GetTemplates godoc
+// @Summary      Get reminder templates
+// @Description  Get the kasbon reminder template of every channel
+// @Tags         dunning
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /dunning/templates [get]
+func (h *DunningHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.service.GetTemplates()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch reminder templates: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Reminder templates retrieved successfully",
+		Data:    templates,
+	})
+}
+
+// UpdateTemplate godoc
+// @Summary      Update a reminder template
+// @Description  Replace the reminder template of a channel (Go text/template with fields Name, Balance and DueDate)
+// @Tags         dunning
+// @Accept       json
+// @Produce      json
+// @Param        channel   path      string                   true  "Channel (whatsapp, sms, email)"
+// @Param        template  body      models.ReminderTemplate  true  "Template Data"
+// @Success      200       {object}  utils.Response
+// @Failure      400       {object}  utils.Response
+// @Failure      500       {object}  utils.Response
+// @Router       /dunning/templates/{channel} [put]
+func (h *DunningHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	channel := strings.TrimPrefix(r.URL.Path, "/api/dunning/templates/")
+
+	var tmplReq models.ReminderTemplate
+	if err := json.NewDecoder(r.Body).Decode(&tmplReq); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+	tmplReq.Channel = channel
+
+	tmpl, err := h.service.SaveTemplate(tmplReq)
+	if err == services.ErrInvalidChannel || errors.Is(err, services.ErrInvalidTemplate) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to save reminder template: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Reminder template updated successfully",
+		Data:    tmpl,
+	})
+}
+
+// RunReminders godoc
+// @Summary      Send due reminders now
+// @Description  Send kasbon reminders to every overdue customer whose cadence has elapsed, without waiting for the scheduler
+// @Tags         dunning
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /dunning/run [post]
+func (h *DunningHandler) RunReminders(w http.ResponseWriter, r *http.Request) {
+	sent, err := h.service.SendReminders()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to send reminders: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Reminders sent successfully",
+		Data:    map[string]int{"sent": sent},
+	})
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"kasir-api/events"
+	"kasir-api/models"
+)
+
+// eventKeepAlive is how long an event stream may go quiet before it's sent a
+// comment, so proxies keep it open
+const eventKeepAlive = 15 * time.Second
+
+type EventHandler struct {
+	broadcaster *events.Broadcaster
+}
+
+func NewEventHandler(broadcaster *events.Broadcaster) *EventHandler {
+	return &EventHandler{broadcaster: broadcaster}
+}
+
+// StreamEvents godoc
+// @Summary      Stream store events
+// @Description  Server-sent events for live dashboards: each domain event as it happens, named after it (transaction.completed, product.created, product.low_stock, refund.issued, payment_link.paid), its data the envelope webhooks get. Events happening while a client falls behind are dropped for it.
+// @Tags         events
+// @Produce      text/event-stream
+// @Success      200  {object}  models.WebhookEvent
+// @Router       /events [get]
+func (h *EventHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	listener, stop := h.broadcaster.Listen()
+	defer stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case e := <-listener:
+			data, err := json.Marshal(models.WebhookEvent{
+				Event:      e.Name,
+				OccurredAt: e.OccurredAt.Format(time.RFC3339),
+				Data:       e.Payload,
+			})
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Name, data); err != nil {
+				return
+			}
+			keepAlive.Reset(eventKeepAlive)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/storage"
+	"kasir-api/utils"
+)
+
+type ExpenseHandler struct {
+	service *services.ExpenseService
+}
+
+func NewExpenseHandler(service *services.ExpenseService) *ExpenseHandler {
+	return &ExpenseHandler{service: service}
+}
+
+// expenseIDFromPath parses the ID out of /api/expenses/{id}[suffix]
+func expenseIDFromPath(path, suffix string) (int, error) {
+	idStr := strings.TrimPrefix(path, "/api/expenses/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	return strconv.Atoi(idStr)
+}
+
+// GetExpenses godoc
+// @Summary      Get expenses
+// @Description  Get the petty cash expenses, optionally within a date range
+// @Tags         expenses
+// @Accept       json
+// @Produce      json
+// @Param        date_from  query     string  false  "From date (YYYY-MM-DD)"
+// @Param        date_to    query     string  false  "To date (YYYY-MM-DD)"
+// @Success      200        {object}  utils.Response
+// @Failure      400        {object}  utils.Response
+// @Failure      500        {object}  utils.Response
+// @Router       /expenses [get]
+func (h *ExpenseHandler) GetExpenses(w http.ResponseWriter, r *http.Request) {
+	dateFrom := r.URL.Query().Get("date_from")
+	dateTo := r.URL.Query().Get("date_to")
+	for _, date := range []string{dateFrom, dateTo} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid date format, use YYYY-MM-DD",
+			})
+			return
+		}
+	}
+
+	expenses, err := h.service.GetAll(dateFrom, dateTo)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch expenses: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Expenses retrieved successfully",
+		Data:    expenses,
+	})
+}
+
+// CreateExpense godoc
+// @Summary      Create an expense
+// @Description  Record petty cash paid out of the register; spent_on defaults to today and can't be in a closed accounting period
+// @Tags         expenses
+// @Accept       json
+// @Produce      json
+// @Param        expense  body      models.Expense  true  "Expense Data"
+// @Success      201      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      409      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /expenses [post]
+func (h *ExpenseHandler) CreateExpense(w http.ResponseWriter, r *http.Request) {
+	var expense models.Expense
+	if err := json.NewDecoder(r.Body).Decode(&expense); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	created, err := h.service.Create(expense)
+	if err == services.ErrInvalidAmount || err == services.ErrInvalidSpentOn {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if errors.Is(err, repositories.ErrPeriodClosed) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to create expense: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Expense created successfully",
+		Data:    created,
+	})
+}
+
+// GetExpenseByID godoc
+// @Summary      Get expense by ID
+// @Description  Get a petty cash expense
+// @Tags         expenses
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Expense ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /expenses/{id} [get]
+func (h *ExpenseHandler) GetExpenseByID(w http.ResponseWriter, r *http.Request) {
+	id, err := expenseIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Expense ID",
+		})
+		return
+	}
+
+	expense, err := h.service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Expense not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch expense: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Expense retrieved successfully",
+		Data:    expense,
+	})
+}
+
+// DeleteExpense godoc
+// @Summary      Delete an expense
+// @Description  Soft delete a petty cash expense, unless it was spent in a closed accounting period
+// @Tags         expenses
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Expense ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      409  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /expenses/{id} [delete]
+func (h *ExpenseHandler) DeleteExpense(w http.ResponseWriter, r *http.Request) {
+	id, err := expenseIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Expense ID",
+		})
+		return
+	}
+
+	err = h.service.Delete(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Expense not found",
+		})
+		return
+	}
+
+	if errors.Is(err, repositories.ErrPeriodClosed) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to delete expense: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Expense deleted successfully",
+	})
+}
+
+// UploadExpenseAttachment godoc
+// @Summary      Attach a receipt photo
+// @Description  Upload a JPEG, PNG or WebP photo of the receipt, at most 5 MB, replacing any earlier photo
+// @Tags         expenses
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        id     path      int   true  "Expense ID"
+// @Param        photo  formData  file  true  "Receipt photo"
+// @Success      200    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      404    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /expenses/{id}/attachment [put]
+func (h *ExpenseHandler) UploadExpenseAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := expenseIDFromPath(r.URL.Path, "/attachment")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Expense ID",
+		})
+		return
+	}
+
+	// leaves room for the multipart framing around the photo
+	r.Body = http.MaxBytesReader(w, r.Body, 6<<20)
+	photo, _, err := r.FormFile("photo")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Missing or too large photo",
+		})
+		return
+	}
+	defer photo.Close()
+
+	expense, err := h.service.AttachPhoto(id, photo)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Expense not found",
+		})
+		return
+	}
+
+	if err == services.ErrInvalidPhoto {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to attach photo: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Photo attached successfully",
+		Data:    expense,
+	})
+}
+
+// GetExpenseAttachment godoc
+// @Summary      Get the receipt photo
+// @Description  Download the receipt photo of an expense
+// @Tags         expenses
+// @Produce      image/jpeg,image/png,image/webp
+// @Param        id   path      int  true  "Expense ID"
+// @Success      200  {file}    file
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /expenses/{id}/attachment [get]
+func (h *ExpenseHandler) GetExpenseAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := expenseIDFromPath(r.URL.Path, "/attachment")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Expense ID",
+		})
+		return
+	}
+
+	expense, f, err := h.service.OpenAttachment(id)
+	if err == sql.ErrNoRows || err == storage.ErrNotFound {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Attachment not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to open attachment: " + err.Error(),
+		})
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", expense.AttachmentType)
+	w.Header().Set("Content-Disposition", `inline; filename="`+path.Base(expense.AttachmentName)+`"`)
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, f)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type ExpiryHandler struct {
+	service *services.ExpiryService
+}
+
+func NewExpiryHandler(service *services.ExpiryService) *ExpiryHandler {
+	return &ExpiryHandler{service: service}
+}
+
+// GetExpiringProducts godoc
+// @Summary      Get expiring stock
+// @Description  Get the stock batches that expire within days, expiring first first, with what they are worth at cost. Batches past their date are listed until the nightly job writes them off as expired.
+// @Tags         product
+// @Accept       json
+// @Produce      json
+// @Param        days  query     int  false  "Days ahead, 0 for today (default 7, at most 365)"
+// @Success      200   {object}  utils.Response
+// @Failure      400   {object}  utils.Response
+// @Failure      500   {object}  utils.Response
+// @Router       /product/expiring [get]
+func (h *ExpiryHandler) GetExpiringProducts(w http.ResponseWriter, r *http.Request) {
+	days := 7
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid days",
+			})
+			return
+		}
+		days = d
+	}
+
+	batches, err := h.service.GetExpiring(days)
+	if err == services.ErrInvalidExpiryDays {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch expiring stock: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Expiring stock retrieved successfully",
+		Data:    batches,
+	})
+}
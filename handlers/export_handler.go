@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/spreadsheet"
+	"kasir-api/storage"
+	"kasir-api/utils"
+)
+
+type ExportHandler struct {
+	service *services.ExportService
+}
+
+func NewExportHandler(service *services.ExportService) *ExportHandler {
+	return &ExportHandler{service: service}
+}
+
+// exportIDFromPath parses the ID out of /api/exports/{id}[suffix]
+func exportIDFromPath(path, suffix string) (int, error) {
+	idStr := strings.TrimPrefix(path, "/api/exports/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	return strconv.Atoi(idStr)
+}
+
+// GetExports godoc
+// @Summary      Get exports
+// @Description  Get the most recently requested exports
+// @Tags         exports
+// @Accept       json
+// @Produce      json
+// @Param        limit  query     int  false  "Maximum number of exports (default 50)"
+// @Success      200    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /exports [get]
+func (h *ExportHandler) GetExports(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid limit",
+			})
+			return
+		}
+		limit = l
+	}
+
+	exports, err := h.service.GetAll(limit)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch exports: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Exports retrieved successfully",
+		Data:    exports,
+	})
+}
+
+// ExportStockMovements godoc
+// @Summary      Export stock movements
+// @Description  Queue an export of every stock movement between two dates, inclusive, with the opening and closing balance of each product. Poll the export and download it once its status is done.
+// @Tags         exports
+// @Accept       json
+// @Produce      json
+// @Param        export  body      models.ExportRequest  true  "Export Data"
+// @Success      202     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /exports/stock-movements [post]
+func (h *ExportHandler) ExportStockMovements(w http.ResponseWriter, r *http.Request) {
+	var req models.ExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	export, err := h.service.RequestStockMovements(req)
+	if err == services.ErrInvalidExportFormat || err == services.ErrInvalidExportRange {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to queue export: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, utils.Response{
+		Status:  "success",
+		Message: "Export queued successfully",
+		Data:    export,
+	})
+}
+
+// ExportExpenses godoc
+// @Summary      Export expenses
+// @Description  Queue an export of the petty cash expenses between two dates, inclusive, with links to their receipt photos. Poll the export and download it once its status is done.
+// @Tags         exports
+// @Accept       json
+// @Produce      json
+// @Param        export  body      models.ExportRequest  true  "Export Data"
+// @Success      202     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /exports/expenses [post]
+func (h *ExportHandler) ExportExpenses(w http.ResponseWriter, r *http.Request) {
+	var req models.ExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	export, err := h.service.RequestExpenses(req)
+	if err == services.ErrInvalidExportFormat || err == services.ErrInvalidExportRange {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to queue export: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, utils.Response{
+		Status:  "success",
+		Message: "Export queued successfully",
+		Data:    export,
+	})
+}
+
+// ExportJournal godoc
+// @Summary      Export sales as journal entries
+// @Description  Queue an export of the sales between two dates, inclusive, as journal entries to import into accounting software such as Accurate, Jurnal or Xero: an entry per day, debiting the accounts of how the sales were paid (ACCOUNT_CASH, ACCOUNT_VOUCHER, ACCOUNT_PAYMENT_LINK, ACCOUNT_INSTALLMENT) and crediting revenue (ACCOUNT_SALES) and the PPN in it at TAX_RATE (ACCOUNT_TAX). Poll the export and download it once its status is done.
+// @Tags         exports
+// @Accept       json
+// @Produce      json
+// @Param        export  body      models.ExportRequest  true  "Export Data"
+// @Success      202     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /exports/journal [post]
+func (h *ExportHandler) ExportJournal(w http.ResponseWriter, r *http.Request) {
+	var req models.ExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	export, err := h.service.RequestJournal(req)
+	if err == services.ErrInvalidExportFormat || err == services.ErrInvalidExportRange {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to queue export: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, utils.Response{
+		Status:  "success",
+		Message: "Export queued successfully",
+		Data:    export,
+	})
+}
+
+// GetExportByID godoc
+// @Summary      Get export by ID
+// @Description  Get the status of an export
+// @Tags         exports
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Export ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /exports/{id} [get]
+func (h *ExportHandler) GetExportByID(w http.ResponseWriter, r *http.Request) {
+	id, err := exportIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Export ID",
+		})
+		return
+	}
+
+	export, err := h.service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Export not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch export: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Export retrieved successfully",
+		Data:    export,
+	})
+}
+
+// DownloadExport godoc
+// @Summary      Download an export
+// @Description  Download the file of a finished export
+// @Tags         exports
+// @Produce      octet-stream
+// @Param        id   path      int  true  "Export ID"
+// @Success      200  {file}    file
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      409  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /exports/{id}/download [get]
+func (h *ExportHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	id, err := exportIDFromPath(r.URL.Path, "/download")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Export ID",
+		})
+		return
+	}
+
+	export, f, err := h.service.Open(id)
+	if err == sql.ErrNoRows || err == storage.ErrNotFound {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Export not found",
+		})
+		return
+	}
+
+	if err == services.ErrExportNotReady {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to open export: " + err.Error(),
+		})
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", spreadsheet.ContentType(export.Format))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+path.Base(export.FileName)+`"`)
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, f)
+}
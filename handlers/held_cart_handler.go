@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/session"
+	"kasir-api/utils"
+)
+
+type HeldCartHandler struct {
+	service *services.HeldCartService
+}
+
+func NewHeldCartHandler(service *services.HeldCartService) *HeldCartHandler {
+	return &HeldCartHandler{service: service}
+}
+
+// heldCartIDFromPath parses the ID out of /api/held-carts/{id}[suffix]
+func heldCartIDFromPath(path, suffix string) string {
+	id := strings.TrimPrefix(path, "/api/held-carts/")
+	return strings.TrimSuffix(id, suffix)
+}
+
+// GetHeldCarts godoc
+// @Summary      Get held carts
+// @Description  Get the carts parked by cashier terminals, oldest first
+// @Tags         held-carts
+// @Accept       json
+// @Produce      json
+// @Param        terminal_id  query     string  false  "Filter by terminal"
+// @Success      200          {object}  utils.Response
+// @Failure      500          {object}  utils.Response
+// @Router       /held-carts [get]
+func (h *HeldCartHandler) GetHeldCarts(w http.ResponseWriter, r *http.Request) {
+	carts, err := h.service.GetAll(r.Context(), r.URL.Query().Get("terminal_id"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch held carts: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Held carts retrieved successfully",
+		Data:    carts,
+	})
+}
+
+// HoldCart godoc
+// @Summary      Hold a cart
+// @Description  Park a cart so it can be resumed later from any terminal. Held carts expire after the session TTL.
+// @Tags         held-carts
+// @Accept       json
+// @Produce      json
+// @Param        cart  body      models.HoldCartRequest  true  "Cart Data"
+// @Success      201   {object}  utils.Response
+// @Failure      400   {object}  utils.Response
+// @Failure      500   {object}  utils.Response
+// @Router       /held-carts [post]
+func (h *HeldCartHandler) HoldCart(w http.ResponseWriter, r *http.Request) {
+	var req models.HoldCartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	cart, err := h.service.Hold(r.Context(), req)
+	if err == services.ErrTerminalRequired || err == services.ErrEmptyCart || err == services.ErrInvalidAmount {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to hold cart: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Cart held successfully",
+		Data:    cart,
+	})
+}
+
+// GetHeldCartByID godoc
+// @Summary      Get held cart by ID
+// @Description  Get a held cart without resuming it
+// @Tags         held-carts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Held Cart ID"
+// @Success      200  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /held-carts/{id} [get]
+func (h *HeldCartHandler) GetHeldCartByID(w http.ResponseWriter, r *http.Request) {
+	cart, err := h.service.GetByID(r.Context(), heldCartIDFromPath(r.URL.Path, ""))
+	if err == session.ErrNotFound {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Held cart not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch held cart: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Held cart retrieved successfully",
+		Data:    cart,
+	})
+}
+
+// ResumeHeldCart godoc
+// @Summary      Resume a held cart
+// @Description  Take a held cart back to the register. The cart is removed, so it can only be resumed once.
+// @Tags         held-carts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Held Cart ID"
+// @Success      200  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /held-carts/{id}/resume [post]
+func (h *HeldCartHandler) ResumeHeldCart(w http.ResponseWriter, r *http.Request) {
+	cart, err := h.service.Resume(r.Context(), heldCartIDFromPath(r.URL.Path, "/resume"))
+	if err == session.ErrNotFound {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Held cart not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to resume held cart: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Held cart resumed successfully",
+		Data:    cart,
+	})
+}
+
+// DeleteHeldCart godoc
+// @Summary      Discard a held cart
+// @Description  Discard a held cart without checking it out
+// @Tags         held-carts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Held Cart ID"
+// @Success      200  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /held-carts/{id} [delete]
+func (h *HeldCartHandler) DeleteHeldCart(w http.ResponseWriter, r *http.Request) {
+	err := h.service.Delete(r.Context(), heldCartIDFromPath(r.URL.Path, ""))
+	if err == session.ErrNotFound {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Held cart not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to discard held cart: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Held cart discarded successfully",
+	})
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type HistoryHandler struct {
+	service *services.HistoryService
+}
+
+func NewHistoryHandler(service *services.HistoryService) *HistoryHandler {
+	return &HistoryHandler{service: service}
+}
+
+// GetTransactions godoc
+// @Summary      Get the transaction history
+// @Description  Get the sales, newest first, voided and refunded ones included, a page at a time. Send the next_cursor of a page as cursor to get the page after it; the last page has none. A cursor keeps its place while sales are rung up, so no sale is skipped or repeated.
+// @Tags         transaction
+// @Accept       json
+// @Produce      json
+// @Param        cursor  query     string  false  "next_cursor of the page before"
+// @Param        limit   query     int     false  "Maximum number of sales (default 50, at most 200)"
+// @Success      200     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /transactions [get]
+func (h *HistoryHandler) GetTransactions(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid limit",
+			})
+			return
+		}
+		limit = l
+	}
+
+	page, err := h.service.GetTransactions(r.URL.Query().Get("cursor"), limit)
+	if err == services.ErrInvalidCursor {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch transactions: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Transactions retrieved successfully",
+		Data:    page,
+	})
+}
+
+// GetStockMovements godoc
+// @Summary      Get the stock movement history
+// @Description  Get every change of stock, newest first, with its reason and the stock right after it, a page at a time. Send the next_cursor of a page as cursor to get the page after it; the last page has none.
+// @Tags         stock-movements
+// @Accept       json
+// @Produce      json
+// @Param        product_id  query     int     false  "Only the movements of this product"
+// @Param        cursor      query     string  false  "next_cursor of the page before"
+// @Param        limit       query     int     false  "Maximum number of movements (default 50, at most 200)"
+// @Success      200         {object}  utils.Response
+// @Failure      400         {object}  utils.Response
+// @Failure      500         {object}  utils.Response
+// @Router       /stock-movements [get]
+func (h *HistoryHandler) GetStockMovements(w http.ResponseWriter, r *http.Request) {
+	productID := 0
+	if productIDStr := r.URL.Query().Get("product_id"); productIDStr != "" {
+		id, err := strconv.Atoi(productIDStr)
+		if err != nil || id <= 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid product_id",
+			})
+			return
+		}
+		productID = id
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid limit",
+			})
+			return
+		}
+		limit = l
+	}
+
+	page, err := h.service.GetStockMovements(productID, r.URL.Query().Get("cursor"), limit)
+	if err == services.ErrInvalidCursor {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch stock movements: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Stock movements retrieved successfully",
+		Data:    page,
+	})
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type InstallmentHandler struct {
+	service *services.InstallmentService
+}
+
+func NewInstallmentHandler(service *services.InstallmentService) *InstallmentHandler {
+	return &InstallmentHandler{service: service}
+}
+
+// GetInstallmentPlans godoc
+// @Summary      Get installment plans
+// @Description  Get a list of installment plans with their payment status
+// @Tags         installment
+// @Accept       json
+// @Produce      json
+// @Param        customer_id  query     int     false  "Filter by customer ID"
+// @Param        status       query     string  false  "Filter by status (active, overdue, paid)"
+// @Success      200          {object}  utils.Response
+// @Failure      400          {object}  utils.Response
+// @Failure      500          {object}  utils.Response
+// @Router       /installment [get]
+func (h *InstallmentHandler) GetInstallmentPlans(w http.ResponseWriter, r *http.Request) {
+	customerID := 0
+	if customerIDStr := r.URL.Query().Get("customer_id"); customerIDStr != "" {
+		id, err := strconv.Atoi(customerIDStr)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid Customer ID",
+			})
+			return
+		}
+		customerID = id
+	}
+
+	plans, err := h.service.GetAll(customerID, r.URL.Query().Get("status"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch installment plans: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Installment plans retrieved successfully",
+		Data:    plans,
+	})
+}
+
+// CreateInstallmentPlan godoc
+// @Summary      Create an installment plan
+// @Description  Split a transaction into N monthly installments starting at first_due_date, after an optional down payment
+// @Tags         installment
+// @Accept       json
+// @Produce      json
+// @Param        plan  body      models.CreateInstallmentPlanRequest  true  "Installment Plan Data"
+// @Success      201   {object}  utils.Response
+// @Failure      400   {object}  utils.Response
+// @Failure      404   {object}  utils.Response
+// @Failure      500   {object}  utils.Response
+// @Router       /installment [post]
+func (h *InstallmentHandler) CreateInstallmentPlan(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateInstallmentPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	plan, err := h.service.CreatePlan(req)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Transaction not found",
+		})
+		return
+	}
+
+	if err == services.ErrInvalidInstallmentCount || err == services.ErrInvalidDownPayment || err == services.ErrInvalidFirstDueDate {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to create installment plan: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Installment plan created successfully",
+		Data:    plan,
+	})
+}
+
+// GetInstallmentPlanByID godoc
+// @Summary      Get an installment plan by ID
+// @Description  Get an installment plan with its schedule and the status of every installment
+// @Tags         installment
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Installment Plan ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /installment/{id} [get]
+func (h *InstallmentHandler) GetInstallmentPlanByID(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/installment/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Installment Plan ID",
+		})
+		return
+	}
+
+	plan, err := h.service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Installment plan not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch installment plan: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Installment plan retrieved successfully",
+		Data:    plan,
+	})
+}
+
+// RecordInstallmentPayment godoc
+// @Summary      Record an installment payment
+// @Description  Record a payment against the plan; it is applied to the earliest unpaid installments first
+// @Tags         installment
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                               true  "Installment Plan ID"
+// @Param        payment  body      models.InstallmentPaymentRequest  true  "Payment Data"
+// @Success      201      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      404      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /installment/{id}/payment [post]
+func (h *InstallmentHandler) RecordInstallmentPayment(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/installment/")
+	idStr = strings.TrimSuffix(idStr, "/payment")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Installment Plan ID",
+		})
+		return
+	}
+
+	var req models.InstallmentPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	if _, err := h.service.GetByID(id); err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Installment plan not found",
+		})
+		return
+	}
+
+	payments, err := h.service.RecordPayment(id, req.Amount)
+	if err == services.ErrInvalidAmount || err == repositories.ErrPaymentExceedsBalance {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to record payment: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Payment recorded successfully",
+		Data:    payments,
+	})
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type JobHandler struct {
+	service *services.JobService
+}
+
+func NewJobHandler(service *services.JobService) *JobHandler {
+	return &JobHandler{service: service}
+}
+
+// jobIDFromPath parses the ID out of /api/jobs/{id}[suffix]
+func jobIDFromPath(path, suffix string) (int, error) {
+	idStr := strings.TrimPrefix(path, "/api/jobs/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	return strconv.Atoi(idStr)
+}
+
+// GetJobs godoc
+// @Summary      Get background jobs
+// @Description  Get the most recent background jobs, optionally filtered by status
+// @Tags         jobs
+// @Accept       json
+// @Produce      json
+// @Param        status  query     string  false  "Filter by status"  Enums(pending, running, done, failed)
+// @Param        limit   query     int     false  "Maximum number of jobs (default 50)"
+// @Success      200     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /jobs [get]
+func (h *JobHandler) GetJobs(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid limit",
+			})
+			return
+		}
+		limit = l
+	}
+
+	jobs, err := h.service.GetAll(r.URL.Query().Get("status"), limit)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch jobs: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Jobs retrieved successfully",
+		Data:    jobs,
+	})
+}
+
+// GetJobByID godoc
+// @Summary      Get a background job by ID
+// @Description  Get the status, attempts and last error of a background job
+// @Tags         jobs
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Job ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /jobs/{id} [get]
+func (h *JobHandler) GetJobByID(w http.ResponseWriter, r *http.Request) {
+	id, err := jobIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Job ID",
+		})
+		return
+	}
+
+	job, err := h.service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Job not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch job: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Job retrieved successfully",
+		Data:    job,
+	})
+}
+
+// RetryJob godoc
+// @Summary      Retry a failed job
+// @Description  Queue a failed background job again with a fresh set of attempts
+// @Tags         jobs
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Job ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      409  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /jobs/{id}/retry [post]
+func (h *JobHandler) RetryJob(w http.ResponseWriter, r *http.Request) {
+	id, err := jobIDFromPath(r.URL.Path, "/retry")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Job ID",
+		})
+		return
+	}
+
+	job, err := h.service.Retry(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Job not found",
+		})
+		return
+	}
+
+	if err == repositories.ErrJobNotFailed {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to retry job: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Job queued for retry",
+		Data:    job,
+	})
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type LoadTestHandler struct {
+	service *services.LoadTestService
+}
+
+func NewLoadTestHandler(service *services.LoadTestService) *LoadTestHandler {
+	return &LoadTestHandler{service: service}
+}
+
+// GetCheckoutScenario godoc
+// @Summary      Generate a checkout load test scenario
+// @Description  Generate sample checkout payloads of products in stock for a load test. With format json the payloads are returned as data; vegeta returns newline delimited targets for `vegeta attack -format=json`, k6 a script for `k6 run`. Every checkout takes stock, so a long run sells out and starts failing; run it against a seeded test database, see `kasir seed`.
+// @Tags         admin
+// @Produce      json
+// @Produce      plain
+// @Param        format     query     string  false  "Output format (default json)"  Enums(json, vegeta, k6)
+// @Param        count      query     int     false  "Number of payloads, at most 10000 (default 100)"
+// @Param        max_items  query     int     false  "Most products in one checkout, at most 20 (default 5)"
+// @Param        seed       query     int     false  "Random seed, the same seed gives the same payloads (default 1)"
+// @Param        target     query     string  false  "Base URL the scenario calls (default this server)"
+// @Success      200        {object}  utils.Response
+// @Failure      400        {object}  utils.Response
+// @Failure      500        {object}  utils.Response
+// @Router       /admin/load-test/checkout [get]
+func (h *LoadTestHandler) GetCheckoutScenario(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "vegeta" && format != "k6" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid format, use json, vegeta or k6",
+		})
+		return
+	}
+
+	params := map[string]int{"count": 100, "max_items": 5, "seed": 1}
+	for name := range params {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid " + name,
+			})
+			return
+		}
+		params[name] = n
+	}
+
+	payloads, err := h.service.CheckoutPayloads(params["count"], params["max_items"], uint64(params["seed"]))
+	if err == services.ErrInvalidLoadTestSize || err == services.ErrNoStockForLoadTest {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to generate load test: " + err.Error(),
+		})
+		return
+	}
+
+	if format == "json" {
+		utils.WriteJSON(w, http.StatusOK, utils.Response{
+			Status:  "success",
+			Message: "Load test payloads generated successfully",
+			Data:    payloads,
+		})
+		return
+	}
+
+	target := strings.TrimSuffix(r.URL.Query().Get("target"), "/")
+	if target == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		target = scheme + "://" + r.Host
+	}
+
+	var body []byte
+	fileName := "checkout.js"
+	if format == "vegeta" {
+		body, err = services.VegetaTargets(payloads, target)
+		fileName = "checkout-targets.json"
+	} else {
+		body, err = services.K6Script(payloads, target)
+	}
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to generate load test: " + err.Error(),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
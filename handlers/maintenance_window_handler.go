@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type MaintenanceWindowHandler struct {
+	service *services.MaintenanceWindowService
+}
+
+func NewMaintenanceWindowHandler(service *services.MaintenanceWindowService) *MaintenanceWindowHandler {
+	return &MaintenanceWindowHandler{service: service}
+}
+
+// GetMaintenanceWindows godoc
+// @Summary      Get maintenance windows
+// @Description  Get the times of the week heavy scheduled jobs (daily report rollup, ABC classification) may run in. A scheduled run outside of every window waits for the next one; without any window they run whenever they are scheduled.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /admin/maintenance-windows [get]
+func (h *MaintenanceWindowHandler) GetMaintenanceWindows(w http.ResponseWriter, r *http.Request) {
+	windows, err := h.service.GetAll()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch maintenance windows: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Maintenance windows retrieved successfully",
+		Data:    windows,
+	})
+}
+
+// CreateMaintenanceWindow godoc
+// @Summary      Create a maintenance window
+// @Description  Add a time of the week heavy scheduled jobs may run in, in the server's time zone. Days are 0 (Sunday) to 6 (Saturday), none meaning every day; a window ending before it starts runs past midnight.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        window  body      models.MaintenanceWindowRequest  true  "Maintenance window"
+// @Success      201     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /admin/maintenance-windows [post]
+func (h *MaintenanceWindowHandler) CreateMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	var req models.MaintenanceWindowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	window, err := h.service.Create(req)
+	if err == services.ErrInvalidMaintenanceWindow {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to create maintenance window: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Maintenance window created successfully",
+		Data:    window,
+	})
+}
+
+// DeleteMaintenanceWindow godoc
+// @Summary      Delete a maintenance window
+// @Description  Delete a maintenance window by ID. Runs already deferred to it keep their time.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Maintenance window ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /admin/maintenance-windows/{id} [delete]
+func (h *MaintenanceWindowHandler) DeleteMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/maintenance-windows/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Maintenance window ID",
+		})
+		return
+	}
+
+	err = h.service.Delete(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Maintenance window not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to delete maintenance window: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Maintenance window deleted successfully",
+	})
+}
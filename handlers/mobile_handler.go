@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type MobileHandler struct {
+	service *services.MobileService
+}
+
+func NewMobileHandler(service *services.MobileService) *MobileHandler {
+	return &MobileHandler{service: service}
+}
+
+// GetMobileSummary godoc
+// @Summary      Get owner mobile summary
+// @Description  Get today's key numbers and alerts in a compact payload for the owner's phone app. Today is the store's day in its time zone; revenue, transactions and gross profit are of the sales paid or completed. The summary is recomputed at most once a minute; send the ETag back in If-None-Match to get a 304 without a body, and Accept-Encoding gzip to compress it.
+// @Tags         mobile
+// @Accept       json
+// @Produce      json
+// @Param        If-None-Match  header    string  false  "ETag of the cached summary"
+// @Success      200            {object}  utils.Response
+// @Success      304            "Not Modified"
+// @Failure      500            {object}  utils.Response
+// @Router       /mobile/summary [get]
+func (h *MobileHandler) GetMobileSummary(w http.ResponseWriter, r *http.Request) {
+	summary, err := h.service.GetSummary()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch summary: " + err.Error(),
+		})
+		return
+	}
+
+	// marshalled here rather than by utils.WriteJSON: the body is hashed into
+	// the ETag, so it leaves out the request ID, which is in the header
+	body, err := json.Marshal(utils.Response{
+		Status:  "success",
+		Message: "Summary retrieved successfully",
+		Data:    summary,
+	})
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to encode summary: " + err.Error(),
+		})
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, max-age=60, stale-while-revalidate=300")
+	w.Header().Set("Vary", "Accept-Encoding")
+	if match := r.Header.Get("If-None-Match"); match != "" && strings.Contains(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+		return
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	w.WriteHeader(http.StatusOK)
+	gz := gzip.NewWriter(w)
+	gz.Write(body)
+	gz.Close()
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type NotificationHandler struct {
+	service *services.NotificationService
+}
+
+func NewNotificationHandler(service *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{service: service}
+}
+
+// notificationChannelIDFromPath parses the ID out of /api/notification-channels/{id}[suffix]
+func notificationChannelIDFromPath(path, suffix string) (int, error) {
+	idStr := strings.TrimPrefix(path, "/api/notification-channels/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	return strconv.Atoi(idStr)
+}
+
+// writeNotificationError answers a failed channel request with the status of its cause
+func writeNotificationError(w http.ResponseWriter, err error, action string) {
+	status := http.StatusInternalServerError
+	message := "Failed to " + action + ": " + err.Error()
+	switch err {
+	case services.ErrInvalidProvider, services.ErrProviderNotConfigured, services.ErrInvalidChatID, services.ErrInvalidPhone, services.ErrInvalidNotifyEvent:
+		status, message = http.StatusBadRequest, err.Error()
+	case sql.ErrNoRows:
+		status, message = http.StatusNotFound, "Notification channel not found"
+	}
+	utils.WriteJSON(w, status, utils.Response{
+		Status:  "failed",
+		Message: message,
+	})
+}
+
+// GetNotificationChannels godoc
+// @Summary      Get notification channels
+// @Description  Get the Telegram and WhatsApp chats the store's alerts are sent to
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /notification-channels [get]
+func (h *NotificationHandler) GetNotificationChannels(w http.ResponseWriter, r *http.Request) {
+	channels, err := h.service.GetAll()
+	if err != nil {
+		writeNotificationError(w, err, "fetch notification channels")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Notification channels retrieved successfully",
+		Data:    channels,
+	})
+}
+
+// CreateNotificationChannel godoc
+// @Summary      Add a notification channel
+// @Description  Send the store's alerts to a chat: low_stock when a sale brings a product down to its reorder point, daily_summary with the day's sales on NOTIFICATION_SUMMARY_SCHEDULE. A telegram chat is reached through the bot of TELEGRAM_BOT_TOKEN by its chat ID; a whatsapp number through the WhatsApp Business API number WHATSAPP_PHONE_NUMBER_ID. Adding a chat again replaces its events.
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Param        channel  body      models.NotificationChannel  true  "Channel"
+// @Success      201      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /notification-channels [post]
+func (h *NotificationHandler) CreateNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	var channel models.NotificationChannel
+	if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	channel, err := h.service.Create(channel)
+	if err != nil {
+		writeNotificationError(w, err, "create notification channel")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Notification channel created successfully",
+		Data:    channel,
+	})
+}
+
+// UpdateNotificationChannel godoc
+// @Summary      Update a notification channel
+// @Description  Change the events a chat gets, or pause it with active false
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                         true  "Channel ID"
+// @Param        channel  body      models.NotificationChannel  true  "Channel"
+// @Success      200      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      404      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /notification-channels/{id} [put]
+func (h *NotificationHandler) UpdateNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	id, err := notificationChannelIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Channel ID",
+		})
+		return
+	}
+
+	var channel models.NotificationChannel
+	if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+	channel.ID = id
+
+	channel, err = h.service.Update(channel)
+	if err != nil {
+		writeNotificationError(w, err, "update notification channel")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Notification channel updated successfully",
+		Data:    channel,
+	})
+}
+
+// DeleteNotificationChannel godoc
+// @Summary      Delete a notification channel
+// @Description  Stop sending alerts to a chat
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Channel ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /notification-channels/{id} [delete]
+func (h *NotificationHandler) DeleteNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	id, err := notificationChannelIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Channel ID",
+		})
+		return
+	}
+
+	if err := h.service.Delete(id); err != nil {
+		writeNotificationError(w, err, "delete notification channel")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Notification channel deleted successfully",
+	})
+}
+
+// TestNotificationChannel godoc
+// @Summary      Send a test notification
+// @Description  Send a test message to a chat right away, so a wrong chat ID, number or token shows at once
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Channel ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /notification-channels/{id}/test [post]
+func (h *NotificationHandler) TestNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	id, err := notificationChannelIDFromPath(r.URL.Path, "/test")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Channel ID",
+		})
+		return
+	}
+
+	if err := h.service.Test(id); err != nil {
+		writeNotificationError(w, err, "send test notification")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Test notification sent successfully",
+	})
+}
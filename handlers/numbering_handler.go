@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type NumberingHandler struct {
+	service *services.NumberingService
+}
+
+func NewNumberingHandler(service *services.NumberingService) *NumberingHandler {
+	return &NumberingHandler{service: service}
+}
+
+// GetNumbering godoc
+// @Summary      Get numbering sequences
+// @Description  Get the format, reset period and next number of the receipt, SKU and tax invoice sequences in the current reset period
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /admin/numbering [get]
+func (h *NumberingHandler) GetNumbering(w http.ResponseWriter, r *http.Request) {
+	sequences, err := h.service.GetAll()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch numbering sequences: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Numbering sequences retrieved successfully",
+		Data:    sequences,
+	})
+}
+
+// SetNextNumber godoc
+// @Summary      Set the next number of a sequence
+// @Description  Set the next value of the receipt, SKU or tax invoice sequence in its current reset period, e.g. to continue from a paper receipt book or to the start of the tax invoice numbers allotted by the tax office. The value can only move past the numbers already issued. Every change is recorded with its reason.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        name        path      string                             true  "Sequence"  Enums(receipt, sku, tax_invoice)
+// @Param        adjustment  body      models.NumberingAdjustmentRequest  true  "Next value and reason"
+// @Success      200         {object}  utils.Response
+// @Failure      400         {object}  utils.Response
+// @Failure      404         {object}  utils.Response
+// @Failure      409         {object}  utils.Response
+// @Failure      500         {object}  utils.Response
+// @Router       /admin/numbering/{name} [put]
+func (h *NumberingHandler) SetNextNumber(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/admin/numbering/")
+
+	var req models.NumberingAdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	adjustment, err := h.service.SetNext(name, req)
+	if err == services.ErrUnknownSequence {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == services.ErrInvalidNextValue || err == services.ErrAdjustmentReason {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == repositories.ErrSequenceBehind {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to set next number: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Next number set successfully",
+		Data:    adjustment,
+	})
+}
+
+// GetNumberingAdjustments godoc
+// @Summary      Get numbering adjustments
+// @Description  Get the audit trail of next numbers set by hand, newest first
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        limit  query     int  false  "Maximum number of adjustments (default 50)"
+// @Success      200    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /admin/numbering/adjustments [get]
+func (h *NumberingHandler) GetNumberingAdjustments(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid limit",
+			})
+			return
+		}
+		limit = l
+	}
+
+	adjustments, err := h.service.GetAdjustments(limit)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch numbering adjustments: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Numbering adjustments retrieved successfully",
+		Data:    adjustments,
+	})
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+// maxCallbackBody bounds the gateway callbacks read for verification
+const maxCallbackBody = 64 << 10
+
+type PaymentLinkHandler struct {
+	service *services.PaymentLinkService
+}
+
+func NewPaymentLinkHandler(service *services.PaymentLinkService) *PaymentLinkHandler {
+	return &PaymentLinkHandler{service: service}
+}
+
+// GetPaymentLinks godoc
+// @Summary      Get payment links
+// @Description  Get the payment links of remote orders, newest first
+// @Tags         payment
+// @Accept       json
+// @Produce      json
+// @Param        status  query     string  false  "Filter by status"  Enums(pending, paid, underpaid, expired)
+// @Success      200     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /payment-links [get]
+func (h *PaymentLinkHandler) GetPaymentLinks(w http.ResponseWriter, r *http.Request) {
+	links, err := h.service.GetAll(r.URL.Query().Get("status"))
+	if err == services.ErrPaymentLinkStatus {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch payment links: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Payment links retrieved successfully",
+		Data:    links,
+	})
+}
+
+// GetPaymentLinkByID godoc
+// @Summary      Get a payment link
+// @Description  Get a payment link by its ID, with the transaction it is for
+// @Tags         payment
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Payment link ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /payment-links/{id} [get]
+func (h *PaymentLinkHandler) GetPaymentLinkByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/payment-links/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid payment link ID",
+		})
+		return
+	}
+
+	link, err := h.service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Payment link not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch payment link: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Payment link retrieved successfully",
+		Data:    link,
+	})
+}
+
+// CreatePaymentLink godoc
+// @Summary      Create a payment link
+// @Description  Create a link to pay the draft transaction of a remote order, e.g. taken over WhatsApp, through the payment gateway. A draft is billed first, moved to pending_payment with the cash rounding applied, and the link is for its total; a pending_payment sale gets a new link once the last one expired (409 while it is open). Share the returned url with the customer; once the gateway reports it paid in full, the sale is marked paid and the store is alerted.
+// @Tags         payment
+// @Accept       json
+// @Produce      json
+// @Param        link  body      models.PaymentLinkRequest  true  "Transaction to pay"
+// @Success      201   {object}  utils.Response
+// @Failure      400   {object}  utils.Response
+// @Failure      404   {object}  utils.Response
+// @Failure      409   {object}  utils.Response
+// @Failure      500   {object}  utils.Response
+// @Failure      502   {object}  utils.Response
+// @Failure      503   {object}  utils.Response
+// @Router       /payment-links [post]
+func (h *PaymentLinkHandler) CreatePaymentLink(w http.ResponseWriter, r *http.Request) {
+	var req models.PaymentLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	link, err := h.service.Create(req)
+	if err == services.ErrInvalidPhone {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Transaction not found",
+		})
+		return
+	}
+
+	if err == services.ErrPaymentLinkTransaction || err == services.ErrPaymentLinkOpen || err == repositories.ErrTransactionChanged || errors.Is(err, repositories.ErrPeriodClosed) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == services.ErrPaymentNotConfigured {
+		utils.WriteJSON(w, http.StatusServiceUnavailable, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if errors.Is(err, services.ErrPaymentGatewayUnavailable) {
+		utils.WriteJSON(w, http.StatusBadGateway, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to create payment link: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Payment link created successfully",
+		Data:    link,
+	})
+}
+
+// PaymentCallback godoc
+// @Summary      Payment gateway callback
+// @Description  Called by the payment gateway when a payment link is paid or expires. The body is signed with HMAC-SHA256 using PAYMENT_CALLBACK_SECRET in the X-Callback-Signature header ("sha256=<hex>"). Paid in full, the link's sale is marked paid; paid less than the link's amount, the link is underpaid and the sale stays pending_payment. A repeated callback for a paid link is acknowledged without marking the sale paid again.
+// @Tags         payment
+// @Accept       json
+// @Produce      json
+// @Param        X-Callback-Signature  header    string                  true  "sha256=<hex HMAC-SHA256 of the body>"
+// @Param        callback              body      models.PaymentCallback  true  "Payment status"
+// @Success      200                   {object}  utils.Response
+// @Failure      400                   {object}  utils.Response
+// @Failure      401                   {object}  utils.Response
+// @Failure      404                   {object}  utils.Response
+// @Failure      500                   {object}  utils.Response
+// @Router       /payment-links/callback [post]
+func (h *PaymentLinkHandler) PaymentCallback(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxCallbackBody))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	link, err := h.service.HandleCallback(body, r.Header.Get("X-Callback-Signature"))
+	if err == services.ErrInvalidCallbackSignature {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == services.ErrInvalidPaymentCallback {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Payment link not found",
+		})
+		return
+	}
+
+	if err == repositories.ErrPaymentLinkAlreadyPaid {
+		utils.WriteJSON(w, http.StatusOK, utils.Response{
+			Status:  "success",
+			Message: "Payment link was already paid",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to process payment callback: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Payment callback processed successfully",
+		Data:    link,
+	})
+}
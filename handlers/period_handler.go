@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type PeriodHandler struct {
+	service *services.PeriodService
+}
+
+func NewPeriodHandler(service *services.PeriodService) *PeriodHandler {
+	return &PeriodHandler{service: service}
+}
+
+// periodMonthFromPath parses the month out of /api/admin/periods/{month}{suffix}
+func periodMonthFromPath(path, suffix string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(path, "/api/admin/periods/"), suffix)
+}
+
+// GetPeriods godoc
+// @Summary      Get closed accounting periods
+// @Description  Get the closed accounting months, newest first
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /admin/periods [get]
+func (h *PeriodHandler) GetPeriods(w http.ResponseWriter, r *http.Request) {
+	periods, err := h.service.GetAll()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch accounting periods: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Accounting periods retrieved successfully",
+		Data:    periods,
+	})
+}
+
+// ClosePeriod godoc
+// @Summary      Close an accounting period
+// @Description  Lock a month that has ended once its numbers are confirmed. Expenses spent, offline sales rung up, goods receipt costs received and status changes and added items of sales made in a closed month are refused with 409 until it is reopened; a terminal keeps such sales queued meanwhile.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        month   path      string                     true   "Month (YYYY-MM)"
+// @Param        period  body      models.PeriodCloseRequest  false  "Note"
+// @Success      201     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      409     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /admin/periods/{month}/close [post]
+func (h *PeriodHandler) ClosePeriod(w http.ResponseWriter, r *http.Request) {
+	var req models.PeriodCloseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	period, err := h.service.Close(periodMonthFromPath(r.URL.Path, "/close"), req)
+	if err == services.ErrInvalidPeriod || err == services.ErrPeriodNotEnded {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == repositories.ErrPeriodAlreadyClosed {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to close accounting period: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Accounting period closed successfully",
+		Data:    period,
+	})
+}
+
+// ReopenPeriod godoc
+// @Summary      Reopen an accounting period
+// @Description  Unlock a closed month so its records can change again. The reason is recorded in the audit trail.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        month   path      string                      true  "Month (YYYY-MM)"
+// @Param        period  body      models.PeriodReopenRequest  true  "Reason"
+// @Success      200     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      409     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /admin/periods/{month}/reopen [post]
+func (h *PeriodHandler) ReopenPeriod(w http.ResponseWriter, r *http.Request) {
+	var req models.PeriodReopenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	err := h.service.Reopen(periodMonthFromPath(r.URL.Path, "/reopen"), req)
+	if err == services.ErrInvalidPeriod || err == services.ErrReopenReason {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == repositories.ErrPeriodNotClosed {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to reopen accounting period: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Accounting period reopened successfully",
+	})
+}
+
+// GetPeriodEvents godoc
+// @Summary      Get the accounting period audit trail
+// @Description  Get the closes and reopens of accounting periods with their notes and reasons, newest first
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        limit  query     int  false  "Maximum number of entries (default 50)"
+// @Success      200    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /admin/periods/events [get]
+func (h *PeriodHandler) GetPeriodEvents(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid limit",
+			})
+			return
+		}
+		limit = l
+	}
+
+	events, err := h.service.GetEvents(limit)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch accounting period audit trail: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Accounting period audit trail retrieved successfully",
+		Data:    events,
+	})
+}
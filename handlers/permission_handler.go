@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type PermissionHandler struct {
+	service *services.PermissionService
+}
+
+func NewPermissionHandler(service *services.PermissionService) *PermissionHandler {
+	return &PermissionHandler{service: service}
+}
+
+// writePermissionError answers a failed permission request with the status of its cause
+func writePermissionError(w http.ResponseWriter, err error, action string) {
+	status := http.StatusInternalServerError
+	message := "Failed to " + action + ": " + err.Error()
+	switch {
+	case err == services.ErrInvalidRoleName || err == services.ErrAdminRoleFixed || err == services.ErrTerminalSessionRequired || err == services.ErrRoleRequired ||
+		errors.Is(err, repositories.ErrPermissionNotFound) || errors.Is(err, repositories.ErrRoleNotFound):
+		status, message = http.StatusBadRequest, err.Error()
+	case err == services.ErrNoTerminalSession:
+		status, message = http.StatusUnauthorized, err.Error()
+	case errors.Is(err, services.ErrNotPermitted):
+		status, message = http.StatusForbidden, err.Error()
+	case err == sql.ErrNoRows:
+		status, message = http.StatusNotFound, "Admin not found"
+	}
+	utils.WriteJSON(w, status, utils.Response{
+		Status:  "failed",
+		Message: message,
+	})
+}
+
+// GetPermissions godoc
+// @Summary      Get permissions
+// @Description  Get every permission a role can be granted, the rules saying which routes need which, and the routes that need none. With ENFORCE_PERMISSIONS on, API routes no rule covers are refused.
+// @Tags         permissions
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /permissions [get]
+func (h *PermissionHandler) GetPermissions(w http.ResponseWriter, r *http.Request) {
+	permissions, err := h.service.GetPermissions()
+	if err != nil {
+		writePermissionError(w, err, "fetch permissions")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Permissions retrieved successfully",
+		Data: map[string]interface{}{
+			"permissions":   permissions,
+			"rules":         services.PermissionRules,
+			"public_routes": services.PublicRoutes,
+		},
+	})
+}
+
+// GetRoles godoc
+// @Summary      Get roles
+// @Description  Get the roles with the permissions granted to them; the admin role holds every permission
+// @Tags         permissions
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /roles [get]
+func (h *PermissionHandler) GetRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.service.GetRoles()
+	if err != nil {
+		writePermissionError(w, err, "fetch roles")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Roles retrieved successfully",
+		Data:    roles,
+	})
+}
+
+// SaveRole godoc
+// @Summary      Create or update a role
+// @Description  Create a role or replace the permissions granted to it, of those GET /permissions lists (400 for any other). Cashiers in the role signed in on a terminal get them within a minute. The admin role can't be changed, and a role granting system.admin is saved only by a holder of it (403).
+// @Tags         permissions
+// @Accept       json
+// @Produce      json
+// @Param        name                path      string              true   "Role name"
+// @Param        X-Terminal-Session  header    string              false  "Terminal session token"
+// @Param        role                body      models.RoleRequest  true   "Role"
+// @Success      200                 {object}  utils.Response
+// @Failure      400                 {object}  utils.Response
+// @Failure      403                 {object}  utils.Response
+// @Failure      500                 {object}  utils.Response
+// @Router       /roles/{name} [put]
+func (h *PermissionHandler) SaveRole(w http.ResponseWriter, r *http.Request) {
+	var req models.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	role, err := h.service.SaveRole(r.Context(), strings.TrimSpace(r.Header.Get("X-Terminal-Session")), strings.TrimPrefix(r.URL.Path, "/api/roles/"), req)
+	if err != nil {
+		writePermissionError(w, err, "save role")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Role saved successfully",
+		Data:    role,
+	})
+}
+
+// SetUserRole godoc
+// @Summary      Set an account's role
+// @Description  Move an account to another role, in force from the next time it switches into a terminal. Moving an account into or out of the admin role, or another role granting system.admin, needs the cashier signed in to hold system.admin (403).
+// @Tags         permissions
+// @Accept       json
+// @Produce      json
+// @Param        id                  path      int                    true   "Admin ID"
+// @Param        X-Terminal-Session  header    string                 false  "Terminal session token"
+// @Param        role                body      models.SetRoleRequest  true   "Role"
+// @Success      200                 {object}  utils.Response
+// @Failure      400                 {object}  utils.Response
+// @Failure      403                 {object}  utils.Response
+// @Failure      404                 {object}  utils.Response
+// @Failure      500                 {object}  utils.Response
+// @Router       /admin/users/{id}/role [put]
+func (h *PermissionHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/admin/users/")
+	id, err := strconv.Atoi(strings.TrimSuffix(idStr, "/role"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Admin ID",
+		})
+		return
+	}
+
+	var req models.SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	if err := h.service.SetUserRole(r.Context(), strings.TrimSpace(r.Header.Get("X-Terminal-Session")), id, req.Role); err != nil {
+		writePermissionError(w, err, "set role")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Role set successfully",
+	})
+}
+
+// GetMyPermissions godoc
+// @Summary      Get my permissions
+// @Description  Get what the cashier signed in with the session in X-Terminal-Session may do, so the frontend can hide what they can't. Requests are only refused for a missing permission with ENFORCE_PERMISSIONS on.
+// @Tags         permissions
+// @Accept       json
+// @Produce      json
+// @Param        X-Terminal-Session  header    string  true  "Terminal session token"
+// @Success      200                 {object}  utils.Response
+// @Failure      400                 {object}  utils.Response
+// @Failure      401                 {object}  utils.Response
+// @Failure      500                 {object}  utils.Response
+// @Router       /me/permissions [get]
+func (h *PermissionHandler) GetMyPermissions(w http.ResponseWriter, r *http.Request) {
+	effective, err := h.service.Effective(r.Context(), strings.TrimSpace(r.Header.Get("X-Terminal-Session")))
+	if err != nil {
+		writePermissionError(w, err, "fetch permissions")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Permissions retrieved successfully",
+		Data:    effective,
+	})
+}
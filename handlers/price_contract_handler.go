@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type PriceContractHandler struct {
+	service *services.PriceContractService
+}
+
+func NewPriceContractHandler(service *services.PriceContractService) *PriceContractHandler {
+	return &PriceContractHandler{service: service}
+}
+
+// writePriceContractError answers the validation and lookup failures shared by
+// creating and updating a contract; it reports whether err was one of them
+func writePriceContractError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == services.ErrInvalidContractDates || err == services.ErrContractItemsRequired ||
+		err == services.ErrDuplicateContractItem || err == services.ErrNegativeAmount:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+	case errors.Is(err, repositories.ErrCustomerNotFound) || errors.Is(err, repositories.ErrProductNotFound):
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+	default:
+		return false
+	}
+	return true
+}
+
+// GetPriceContracts godoc
+// @Summary      Get price contracts
+// @Description  Get the price lists negotiated with wholesale customers, with their items
+// @Tags         price-contracts
+// @Accept       json
+// @Produce      json
+// @Param        customer_id  query     int  false  "Filter by customer"
+// @Success      200          {object}  utils.Response
+// @Failure      400          {object}  utils.Response
+// @Failure      500          {object}  utils.Response
+// @Router       /price-contracts [get]
+func (h *PriceContractHandler) GetPriceContracts(w http.ResponseWriter, r *http.Request) {
+	customerID := 0
+	if customerIDStr := r.URL.Query().Get("customer_id"); customerIDStr != "" {
+		id, err := strconv.Atoi(customerIDStr)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid Customer ID",
+			})
+			return
+		}
+		customerID = id
+	}
+
+	contracts, err := h.service.GetAll(customerID)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch price contracts: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Price contracts retrieved successfully",
+		Data:    contracts,
+	})
+}
+
+// CreatePriceContract godoc
+// @Summary      Create a price contract
+// @Description  Negotiate prices with a customer. From valid_from through valid_until, or with no end when it is left out, checkouts the customer is attached to sell the items at their contract price, in place of the product price and pricing rules. Of overlapping contracts the cheapest price wins.
+// @Tags         price-contracts
+// @Accept       json
+// @Produce      json
+// @Param        contract  body      models.PriceContract  true  "Price Contract Data"
+// @Success      201       {object}  utils.Response
+// @Failure      400       {object}  utils.Response
+// @Failure      404       {object}  utils.Response
+// @Failure      500       {object}  utils.Response
+// @Router       /price-contracts [post]
+func (h *PriceContractHandler) CreatePriceContract(w http.ResponseWriter, r *http.Request) {
+	var contract models.PriceContract
+	if err := json.NewDecoder(r.Body).Decode(&contract); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	created, err := h.service.Create(contract)
+	if writePriceContractError(w, err) {
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to save price contract: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Price contract created successfully",
+		Data:    created,
+	})
+}
+
+// GetPriceContractByID godoc
+// @Summary      Get a price contract
+// @Description  Get a price contract by ID
+// @Tags         price-contracts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Price Contract ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /price-contracts/{id} [get]
+func (h *PriceContractHandler) GetPriceContractByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/price-contracts/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Price Contract ID",
+		})
+		return
+	}
+
+	contract, err := h.service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Price contract not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch price contract: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Price contract retrieved successfully",
+		Data:    contract,
+	})
+}
+
+// UpdatePriceContract godoc
+// @Summary      Update a price contract
+// @Description  Replace the name, validity and items of a price contract; it stays with its customer. Lines already sold keep the price they were sold at.
+// @Tags         price-contracts
+// @Accept       json
+// @Produce      json
+// @Param        id        path      int                   true  "Price Contract ID"
+// @Param        contract  body      models.PriceContract  true  "Price Contract Data"
+// @Success      200       {object}  utils.Response
+// @Failure      400       {object}  utils.Response
+// @Failure      404       {object}  utils.Response
+// @Failure      500       {object}  utils.Response
+// @Router       /price-contracts/{id} [put]
+func (h *PriceContractHandler) UpdatePriceContract(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/price-contracts/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Price Contract ID",
+		})
+		return
+	}
+
+	var contract models.PriceContract
+	if err := json.NewDecoder(r.Body).Decode(&contract); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+	contract.ID = id
+
+	updated, err := h.service.Update(contract)
+	if writePriceContractError(w, err) {
+		return
+	}
+
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Price contract not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to update price contract: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Price contract updated successfully",
+		Data:    updated,
+	})
+}
+
+// DeletePriceContract godoc
+// @Summary      Delete a price contract
+// @Description  End a price contract; lines already sold under it keep their prices and still count in the contract sales report
+// @Tags         price-contracts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Price Contract ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /price-contracts/{id} [delete]
+func (h *PriceContractHandler) DeletePriceContract(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/price-contracts/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Price Contract ID",
+		})
+		return
+	}
+
+	err = h.service.Delete(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Price contract not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to delete price contract: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Price contract deleted successfully",
+	})
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type PricingRuleHandler struct {
+	service *services.PricingRuleService
+}
+
+func NewPricingRuleHandler(service *services.PricingRuleService) *PricingRuleHandler {
+	return &PricingRuleHandler{service: service}
+}
+
+// GetPricingRules godoc
+// @Summary      Get pricing rules
+// @Description  Get quantity price breaks, e.g. 3 for 25,000
+// @Tags         pricing-rules
+// @Accept       json
+// @Produce      json
+// @Param        product_id  query     int  false  "Filter by product"
+// @Success      200         {object}  utils.Response
+// @Failure      400         {object}  utils.Response
+// @Failure      500         {object}  utils.Response
+// @Router       /pricing-rules [get]
+func (h *PricingRuleHandler) GetPricingRules(w http.ResponseWriter, r *http.Request) {
+	productID := 0
+	if productIDStr := r.URL.Query().Get("product_id"); productIDStr != "" {
+		id, err := strconv.Atoi(productIDStr)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid Product ID",
+			})
+			return
+		}
+		productID = id
+	}
+
+	rules, err := h.service.GetAll(productID)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch pricing rules: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Pricing rules retrieved successfully",
+		Data:    rules,
+	})
+}
+
+// CreatePricingRule godoc
+// @Summary      Create a pricing rule
+// @Description  Add a price break to a product: every full group of min_quantity units in a checkout line sells for price, the rest at the product price. When several rules apply the cheapest one is used.
+// @Tags         pricing-rules
+// @Accept       json
+// @Produce      json
+// @Param        rule  body      models.PricingRule  true  "Pricing Rule Data"
+// @Success      201   {object}  utils.Response
+// @Failure      400   {object}  utils.Response
+// @Failure      404   {object}  utils.Response
+// @Failure      500   {object}  utils.Response
+// @Router       /pricing-rules [post]
+func (h *PricingRuleHandler) CreatePricingRule(w http.ResponseWriter, r *http.Request) {
+	var rule models.PricingRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	created, err := h.service.Create(rule)
+	if err == services.ErrInvalidMinQuantity || err == services.ErrInvalidAmount {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == repositories.ErrProductNotFound {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to save pricing rule: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Pricing rule created successfully",
+		Data:    created,
+	})
+}
+
+// GetPricingRuleByID godoc
+// @Summary      Get a pricing rule
+// @Description  Get a pricing rule by ID
+// @Tags         pricing-rules
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Pricing Rule ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /pricing-rules/{id} [get]
+func (h *PricingRuleHandler) GetPricingRuleByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/pricing-rules/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Pricing Rule ID",
+		})
+		return
+	}
+
+	rule, err := h.service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Pricing rule not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch pricing rule: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Pricing rule retrieved successfully",
+		Data:    rule,
+	})
+}
+
+// UpdatePricingRule godoc
+// @Summary      Update a pricing rule
+// @Description  Change the name, quantity, price or active flag of a pricing rule. Lines already sold keep the price they were sold at.
+// @Tags         pricing-rules
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int                 true  "Pricing Rule ID"
+// @Param        rule  body      models.PricingRule  true  "Pricing Rule Data"
+// @Success      200   {object}  utils.Response
+// @Failure      400   {object}  utils.Response
+// @Failure      404   {object}  utils.Response
+// @Failure      500   {object}  utils.Response
+// @Router       /pricing-rules/{id} [put]
+func (h *PricingRuleHandler) UpdatePricingRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/pricing-rules/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Pricing Rule ID",
+		})
+		return
+	}
+
+	var rule models.PricingRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+	rule.ID = id
+
+	updated, err := h.service.Update(rule)
+	if err == services.ErrInvalidMinQuantity || err == services.ErrInvalidAmount {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Pricing rule not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to update pricing rule: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Pricing rule updated successfully",
+		Data:    updated,
+	})
+}
+
+// DeletePricingRule godoc
+// @Summary      Delete a pricing rule
+// @Description  Delete a pricing rule by ID
+// @Tags         pricing-rules
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Pricing Rule ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /pricing-rules/{id} [delete]
+func (h *PricingRuleHandler) DeletePricingRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/pricing-rules/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Pricing Rule ID",
+		})
+		return
+	}
+
+	err = h.service.Delete(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Pricing rule not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to delete pricing rule: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Pricing rule deleted successfully",
+	})
+}
@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type PrinterHandler struct {
+	service *services.PrinterService
+}
+
+func NewPrinterHandler(service *services.PrinterService) *PrinterHandler {
+	return &PrinterHandler{service: service}
+}
+
+func isInvalidPrinter(err error) bool {
+	return err == services.ErrInvalidPrinterName || err == services.ErrInvalidPrinterAddress || err == services.ErrInvalidCategoryIDs
+}
+
+// GetPrinters godoc
+// @Summary      Get kitchen printers
+// @Description  Get the kitchen and bar printers with the categories each prints
+// @Tags         printers
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /printers [get]
+func (h *PrinterHandler) GetPrinters(w http.ResponseWriter, r *http.Request) {
+	printers, err := h.service.GetAll()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch printers: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Printers retrieved successfully",
+		Data:    printers,
+	})
+}
+
+// CreatePrinter godoc
+// @Summary      Add a kitchen printer
+// @Description  Add an ESC/POS printer reached over TCP at address, e.g. a bar printer for the drinks categories. Restaurant order lines are printed at every printer of one of their categories; the default printer prints the lines no other printer takes, open items included. A new default printer takes over from the one before.
+// @Tags         printers
+// @Accept       json
+// @Produce      json
+// @Param        printer  body      models.Printer  true  "Printer"
+// @Success      201      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /printers [post]
+func (h *PrinterHandler) CreatePrinter(w http.ResponseWriter, r *http.Request) {
+	var p models.Printer
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	created, err := h.service.Create(p)
+	if isInvalidPrinter(err) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to create printer: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Printer created successfully",
+		Data:    created,
+	})
+}
+
+// GetPrinterByID godoc
+// @Summary      Get a kitchen printer by ID
+// @Description  Get a kitchen printer with the categories it prints
+// @Tags         printers
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Printer ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /printers/{id} [get]
+func (h *PrinterHandler) GetPrinterByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/printers/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Printer ID",
+		})
+		return
+	}
+
+	p, err := h.service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Printer not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch printer: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Printer retrieved successfully",
+		Data:    p,
+	})
+}
+
+// UpdatePrinter godoc
+// @Summary      Update a kitchen printer
+// @Description  Update a kitchen printer; fields left out are kept, category_ids is replaced when given. An inactive printer is sent nothing, its lines go to the default printer.
+// @Tags         printers
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                          true  "Printer ID"
+// @Param        printer  body      models.UpdatePrinterRequest  true  "Printer"
+// @Success      200      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      404      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /printers/{id} [put]
+func (h *PrinterHandler) UpdatePrinter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/printers/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Printer ID",
+		})
+		return
+	}
+
+	var req models.UpdatePrinterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	p, err := h.service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Printer not found",
+		})
+		return
+	}
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch printer: " + err.Error(),
+		})
+		return
+	}
+
+	if req.Name != "" {
+		p.Name = req.Name
+	}
+	if req.Address != "" {
+		p.Address = req.Address
+	}
+	if req.CategoryIDs != nil {
+		p.CategoryIDs = req.CategoryIDs
+	}
+	if req.IsDefault != nil {
+		p.IsDefault = *req.IsDefault
+	}
+	if req.Active != nil {
+		p.Active = *req.Active
+	}
+
+	updated, err := h.service.Update(p)
+	if isInvalidPrinter(err) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Printer not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to update printer: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Printer updated successfully",
+		Data:    updated,
+	})
+}
+
+// DeletePrinter godoc
+// @Summary      Delete a kitchen printer
+// @Description  Soft delete a kitchen printer, its print jobs are kept
+// @Tags         printers
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Printer ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /printers/{id} [delete]
+func (h *PrinterHandler) DeletePrinter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/printers/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Printer ID",
+		})
+		return
+	}
+
+	err = h.service.Delete(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Printer not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to delete printer: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Printer deleted successfully",
+	})
+}
+
+// GetPrintJobs godoc
+// @Summary      Get print jobs
+// @Description  Get the most recent kitchen tickets, newest first, with whether they were printed. A pending job is waiting for its printer or retried after failing to reach it; a failed one gave up.
+// @Tags         printers
+// @Accept       json
+// @Produce      json
+// @Param        status  query     string  false  "Only jobs of this status"  Enums(pending, printed, failed)
+// @Param        limit   query     int     false  "Maximum number of jobs (default 50)"
+// @Success      200     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /print-jobs [get]
+func (h *PrinterHandler) GetPrintJobs(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid limit",
+			})
+			return
+		}
+		limit = l
+	}
+
+	jobs, err := h.service.GetJobs(r.URL.Query().Get("status"), limit)
+	if err == services.ErrInvalidPrintStatus {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch print jobs: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Print jobs retrieved successfully",
+		Data:    jobs,
+	})
+}
+
+// RetryPrintJob godoc
+// @Summary      Print a job again
+// @Description  Send a failed kitchen ticket to its printer again, or reprint a printed one, e.g. after a paper jam. A job still pending can't be retried (409).
+// @Tags         printers
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Print job ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      409  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /print-jobs/{id}/retry [post]
+func (h *PrinterHandler) RetryPrintJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/print-jobs/"), "/retry"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid print job ID",
+		})
+		return
+	}
+
+	job, err := h.service.Retry(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Print job not found",
+		})
+		return
+	}
+
+	if err == services.ErrPrintJobPending {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to retry print job: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Print job queued successfully",
+		Data:    job,
+	})
+}
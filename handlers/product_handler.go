@@ -3,11 +3,15 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"kasir-api/models"
+	"kasir-api/repositories"
 	"kasir-api/services"
 	"kasir-api/utils"
 )
@@ -20,19 +24,58 @@ func NewProductHandler(service *services.ProductService) *ProductHandler {
 	return &ProductHandler{Service: service}
 }
 
+// updatedSinceFromQuery parses the updated_since query parameter, an RFC 3339
+// timestamp as the catalog returns them or YYYY-MM-DD HH:MM:SS in local time;
+// the zero time when it is left out
+func updatedSinceFromQuery(r *http.Request) (time.Time, error) {
+	s := r.URL.Query().Get("updated_since")
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
+}
+
 // GetProducts godoc
 // @Summary      Get all products
-// @Description  Get a list of all active products
+// @Description  Get a list of all active products with the IDs of their categories; pass expand=category to have the categories themselves in each product, fetched together for the whole list. To poll for changes instead of downloading the catalog again, pass the latest updated_at seen as updated_since: products changed since are returned, deleted ones with deleted_at set. Stock taken by sales doesn't count as a change; the sync changes feed has it. The response carries an ETag: send it back in If-None-Match to get a 304 without a body while the list, stock included, is unchanged.
 // @Tags         product
 // @Accept       json
 // @Produce      json
-// @Param        name  query     string  false  "Filter products by name (case-insensitive)"
-// @Success      200  {object}  utils.Response
+// @Param        name               query     string  false  "Filter products by name (case-insensitive)"
+// @Param        abc_class          query     string  false  "Filter products by ABC class"  Enums(A, B, C)
+// @Param        sort               query     string  false  "Sort by name, created_at or updated_at, prefixed with - for descending (default id)"
+// @Param        updated_since      query     string  false  "Only products updated at or after this time, deleted ones included (RFC 3339 or YYYY-MM-DD HH:MM:SS)"
+// @Param        expand             query     string  false  "Relations to embed in each product, separated by commas: category embeds its categories next to category_ids"
+// @Param        If-None-Match      header    string  false  "ETag of the cached list"
+// @Success      200                {object}  utils.Response
+// @Success      304                "Not Modified"
+// @Failure      400                {object}  utils.Response
 // @Failure      500  {object}  utils.Response
 // @Router       /product [get]
 func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
+	updatedSince, err := updatedSinceFromQuery(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid updated_since",
+		})
+		return
+	}
+
 	name := r.URL.Query().Get("name")
-	products, err := h.Service.GetAll(name)
+	abcClass := r.URL.Query().Get("abc_class")
+	products, err := h.Service.GetAll(name, abcClass, r.URL.Query().Get("sort"), r.URL.Query().Get("expand"), updatedSince)
+	if err == services.ErrInvalidSort || err == services.ErrInvalidExpand {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
 	if err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
 			Status:  "failed",
@@ -50,12 +93,14 @@ func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 
 // GetProductByID godoc
 // @Summary      Get a product by ID
-// @Description  Get a product by its ID
+// @Description  Get a product by its ID. Send the ETag of the response back in If-None-Match to get a 304 without a body while it is unchanged.
 // @Tags         product
 // @Accept       json
 // @Produce      json
-// @Param        id   path      int  true  "Product ID"
-// @Success      200  {object}  utils.Response
+// @Param        id                 path      int     true   "Product ID"
+// @Param        If-None-Match      header    string  false  "ETag of the cached product"
+// @Success      200                {object}  utils.Response
+// @Success      304                "Not Modified"
 // @Failure      400  {object}  utils.Response
 // @Failure      404  {object}  utils.Response
 // @Failure      500  {object}  utils.Response
@@ -95,15 +140,57 @@ func (h *ProductHandler) GetProductByID(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// GetProductBySKU godoc
+// @Summary      Get a product by SKU
+// @Description  Get the active product with the given SKU. Send the ETag of the response back in If-None-Match to get a 304 without a body while it is unchanged.
+// @Tags         product
+// @Accept       json
+// @Produce      json
+// @Param        sku                path      string  true   "Product SKU"
+// @Param        If-None-Match      header    string  false  "ETag of the cached product"
+// @Success      200                {object}  utils.Response
+// @Success      304                "Not Modified"
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /product/sku/{sku} [get]
+func (h *ProductHandler) GetProductBySKU(w http.ResponseWriter, r *http.Request) {
+	sku := strings.TrimPrefix(r.URL.Path, "/api/product/sku/")
+
+	product, err := h.Service.GetBySKU(sku)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Product not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch product: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Product retrieved successfully",
+		Data:    product,
+	})
+}
+
 // CreateProduct godoc
 // @Summary      Create a new product
-// @Description  Create a new product with the provided details
+// @Description  Create a new product with the provided details, in the categories listed in category_ids. Leave sku out to have one generated.
 // @Tags         product
 // @Accept       json
 // @Produce      json
 // @Param        product  body      models.Product  true  "Product Data"
 // @Success      201      {object}  utils.Response
 // @Failure      400      {object}  utils.Response
+// @Failure      404      {object}  utils.Response
+// @Failure      409      {object}  utils.Response
 // @Failure      500      {object}  utils.Response
 // @Router       /product [post]
 func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
@@ -118,6 +205,28 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	product, err := h.Service.Create(productReq)
+	if err == repositories.ErrCategoryNotFound {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if err == services.ErrInvalidSKU || err == services.ErrNegativeAmount {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if err == repositories.ErrDuplicateSKU {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:    "failed",
+			Message:   err.Error(),
+			ErrorCode: "duplicate_sku",
+		})
+		return
+	}
 	if err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
 			Status:  "failed",
@@ -135,7 +244,7 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 
 // UpdateProduct godoc
 // @Summary      Update a product
-// @Description  Update a product by ID
+// @Description  Update a product by ID. category_ids replaces its categories when present.
 // @Tags         product
 // @Accept       json
 // @Produce      json
@@ -144,6 +253,7 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 // @Success      200      {object}  utils.Response
 // @Failure      400      {object}  utils.Response
 // @Failure      404      {object}  utils.Response
+// @Failure      409      {object}  utils.Response
 // @Failure      500      {object}  utils.Response
 // @Router       /product/{id} [put]
 func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
@@ -183,20 +293,30 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if updateReq.Name != "" {
-		existingProduct.Name = updateReq.Name
-	}
-	if updateReq.Price != 0 {
-		existingProduct.Price = updateReq.Price
+	// category_ids replaces the product's categories; leave it out to keep them, send [] to clear them
+	updatedProduct, err := h.Service.Update(services.MergeProduct(existingProduct, updateReq))
+	if err == repositories.ErrCategoryNotFound {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
 	}
-	if updateReq.Stock != 0 {
-		existingProduct.Stock = updateReq.Stock
+	if err == services.ErrInvalidSKU || err == services.ErrNegativeAmount {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
 	}
-	if updateReq.CategoryID != 0 {
-		existingProduct.CategoryID = updateReq.CategoryID
+	if err == repositories.ErrDuplicateSKU {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:    "failed",
+			Message:   err.Error(),
+			ErrorCode: "duplicate_sku",
+		})
+		return
 	}
-
-	updatedProduct, err := h.Service.Update(existingProduct)
 	if err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
 			Status:  "failed",
@@ -212,6 +332,92 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// CreateProductBatch godoc
+// @Summary      Create products in a batch
+// @Description  Create up to 500 products in one database transaction, e.g. to set up a catalog, each as a create of it would. The result of each is at its index in the request: created with the product, or failed with why. Products that fail are left out and the others are saved; with atomic none is saved unless all can be (422 otherwise), those that could have been are skipped.
+// @Tags         product
+// @Accept       json
+// @Produce      json
+// @Param        products  body      []models.Product  true   "Products"
+// @Param        atomic    query     boolean           false  "Save all products or none"
+// @Success      200       {object}  utils.Response
+// @Failure      400       {object}  utils.Response
+// @Failure      422       {object}  utils.Response
+// @Failure      500       {object}  utils.Response
+// @Router       /product/batch [post]
+func (h *ProductHandler) CreateProductBatch(w http.ResponseWriter, r *http.Request) {
+	h.saveProductBatch(w, r, "created", h.Service.CreateBatch)
+}
+
+// UpdateProductBatch godoc
+// @Summary      Update products in a batch
+// @Description  Update up to 500 products in one database transaction, each as an update of it would: id names the product, fields left out are kept and category_ids replaces its categories when present. The result of each is at its index in the request: updated with the product, or failed with why. Products that fail are left out and the others are saved; with atomic none is saved unless all can be (422 otherwise), those that could have been are skipped.
+// @Tags         product
+// @Accept       json
+// @Produce      json
+// @Param        products  body      []models.Product  true   "Product changes"
+// @Param        atomic    query     boolean           false  "Save all products or none"
+// @Success      200       {object}  utils.Response
+// @Failure      400       {object}  utils.Response
+// @Failure      422       {object}  utils.Response
+// @Failure      500       {object}  utils.Response
+// @Router       /product/batch [put]
+func (h *ProductHandler) UpdateProductBatch(w http.ResponseWriter, r *http.Request) {
+	h.saveProductBatch(w, r, "updated", h.Service.UpdateBatch)
+}
+
+func (h *ProductHandler) saveProductBatch(w http.ResponseWriter, r *http.Request, saved string, save func([]models.Product, bool) ([]models.ProductBatchResult, error)) {
+	atomic, _ := strconv.ParseBool(r.URL.Query().Get("atomic"))
+
+	var products []models.Product
+	if err := json.NewDecoder(r.Body).Decode(&products); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	results, err := save(products, atomic)
+	if err == services.ErrInvalidBatch {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to save products: " + err.Error(),
+		})
+		return
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Status == services.BatchFailed {
+			failed++
+		}
+	}
+
+	if atomic && failed > 0 {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Response{
+			Status:    "failed",
+			Message:   fmt.Sprintf("%d of %d products failed, none was saved", failed, len(results)),
+			ErrorCode: "batch_failed",
+			Data:      results,
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: fmt.Sprintf("%d of %d products %s", len(results)-failed, len(results), saved),
+		Data:    results,
+	})
+}
+
 // DeleteProduct godoc
 // @Summary      Delete a product
 // @Description  Soft delete a product by ID
@@ -221,6 +427,7 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 // @Param        id   path      int  true  "Product ID"
 // @Success      200  {object}  utils.Response
 // @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
 // @Failure      500  {object}  utils.Response
 // @Router       /product/{id} [delete]
 func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
@@ -235,6 +442,14 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err = h.Service.Delete(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Product not found",
+		})
+		return
+	}
+
 	if err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
 			Status:  "failed",
@@ -248,3 +463,196 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 		Message: "Product deleted successfully",
 	})
 }
+
+// AdjustStock godoc
+// @Summary      Adjust product stock
+// @Description  Add quantity to the stock of a product, negative to take stock, e.g. for goods found or damaged outside a count; it is logged in the stock movements as an adjustment. Stock taken comes out of the batches expiring first and can't go below zero (409). A bundle has no stock of its own (409).
+// @Tags         product
+// @Accept       json
+// @Produce      json
+// @Param        id          path      int                            true  "Product ID"
+// @Param        adjustment  body      models.StockAdjustmentRequest  true  "Stock adjustment"
+// @Success      200         {object}  utils.Response
+// @Failure      400         {object}  utils.Response
+// @Failure      404         {object}  utils.Response
+// @Failure      409         {object}  utils.Response
+// @Failure      500         {object}  utils.Response
+// @Router       /product/{id}/stock [post]
+func (h *ProductHandler) AdjustStock(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/product/"), "/stock")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Product ID",
+		})
+		return
+	}
+
+	var req models.StockAdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	product, err := h.Service.AdjustStock(id, req.Quantity)
+	var stockErr *repositories.InsufficientStockError
+	switch {
+	case err == services.ErrZeroAdjustment:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	case err == sql.ErrNoRows:
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Product not found",
+		})
+		return
+	case err == repositories.ErrBundleStock || errors.As(err, &stockErr):
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	case err != nil:
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to adjust stock: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Stock adjusted successfully",
+		Data:    product,
+	})
+}
+
+// SetBundleComponents godoc
+// @Summary      Make a product a bundle
+// @Description  Set the products a bundle is made of and how many of each. The bundle keeps its own price; its stock is what its components allow, and selling it takes stock from the components.
+// @Tags         product
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                   true  "Product ID"
+// @Param        bundle  body      models.BundleRequest  true  "Bundle Components"
+// @Success      200     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      404     {object}  utils.Response
+// @Failure      409     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /product/{id}/components [put]
+func (h *ProductHandler) SetBundleComponents(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/product/")
+	idStr = strings.TrimSuffix(idStr, "/components")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Product ID",
+		})
+		return
+	}
+
+	var req models.BundleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	product, err := h.Service.SetComponents(id, req)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Product not found",
+		})
+		return
+	}
+
+	if err == services.ErrEmptyBundle || err == services.ErrInvalidAmount || err == repositories.ErrInvalidBundleComponent {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == repositories.ErrBundleHasStock {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to set bundle components: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Bundle components set successfully",
+		Data:    product,
+	})
+}
+
+// RemoveBundleComponents godoc
+// @Summary      Turn a bundle back into a product
+// @Description  Remove all components of a bundle; it is then sold from its own stock, which starts at zero
+// @Tags         product
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Product ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /product/{id}/components [delete]
+func (h *ProductHandler) RemoveBundleComponents(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/product/")
+	idStr = strings.TrimSuffix(idStr, "/components")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Product ID",
+		})
+		return
+	}
+
+	product, err := h.Service.RemoveComponents(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Product not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to remove bundle components: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Bundle components removed successfully",
+		Data:    product,
+	})
+}
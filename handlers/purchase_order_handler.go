@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type PurchaseOrderHandler struct {
+	service *services.PurchaseOrderService
+}
+
+func NewPurchaseOrderHandler(service *services.PurchaseOrderService) *PurchaseOrderHandler {
+	return &PurchaseOrderHandler{service: service}
+}
+
+// purchaseOrderIDFromPath parses the ID out of /api/purchase-order/{id}[suffix]
+func purchaseOrderIDFromPath(path, suffix string) (int, error) {
+	idStr := strings.TrimPrefix(path, "/api/purchase-order/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	return strconv.Atoi(idStr)
+}
+
+// GetPurchaseOrders godoc
+// @Summary      Get purchase orders
+// @Description  Get a list of purchase orders, optionally filtered by status
+// @Tags         purchase-order
+// @Accept       json
+// @Produce      json
+// @Param        status  query     string  false  "Filter by status"  Enums(open, partially_received, received, short_closed)
+// @Success      200     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /purchase-order [get]
+func (h *PurchaseOrderHandler) GetPurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	orders, err := h.service.GetAll(r.URL.Query().Get("status"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch purchase orders: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Purchase orders retrieved successfully",
+		Data:    orders,
+	})
+}
+
+// CreatePurchaseOrder godoc
+// @Summary      Create a purchase order
+// @Description  Create a purchase order to a supplier with the ordered products
+// @Tags         purchase-order
+// @Accept       json
+// @Produce      json
+// @Param        order  body      models.CreatePurchaseOrderRequest  true  "Purchase Order Data"
+// @Success      201    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /purchase-order [post]
+func (h *PurchaseOrderHandler) CreatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	var req models.CreatePurchaseOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	po, err := h.service.Create(req)
+	if err == services.ErrEmptyPurchaseOrder || err == services.ErrInvalidAmount || err == services.ErrNegativeAmount {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to create purchase order: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Purchase order created successfully",
+		Data:    po,
+	})
+}
+
+// GetPurchaseOrderByID godoc
+// @Summary      Get a purchase order by ID
+// @Description  Get a purchase order with ordered, received and remaining (back-ordered) quantity per item
+// @Tags         purchase-order
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Purchase Order ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /purchase-order/{id} [get]
+func (h *PurchaseOrderHandler) GetPurchaseOrderByID(w http.ResponseWriter, r *http.Request) {
+	id, err := purchaseOrderIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Purchase Order ID",
+		})
+		return
+	}
+
+	po, err := h.service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Purchase order not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch purchase order: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Purchase order retrieved successfully",
+		Data:    po,
+	})
+}
+
+// ClosePurchaseOrder godoc
+// @Summary      Short-close a purchase order
+// @Description  Close an open or partially received purchase order so its remaining quantities are no longer expected
+// @Tags         purchase-order
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                               true   "Purchase Order ID"
+// @Param        close  body      models.ClosePurchaseOrderRequest  false  "Close Reason"
+// @Success      200    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      404    {object}  utils.Response
+// @Failure      409    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /purchase-order/{id}/close [post]
+func (h *PurchaseOrderHandler) ClosePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := purchaseOrderIDFromPath(r.URL.Path, "/close")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Purchase Order ID",
+		})
+		return
+	}
+
+	var req models.ClosePurchaseOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	po, err := h.service.ShortClose(id, req.Reason)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Purchase order not found",
+		})
+		return
+	}
+
+	if err == services.ErrPurchaseOrderNotOpen || err == services.ErrReceivingInProgress {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to close purchase order: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Purchase order closed successfully",
+		Data:    po,
+	})
+}
+
+// GetPurchaseOrderReceipts godoc
+// @Summary      Get goods receipts of a purchase order
+// @Description  Get every partial delivery booked against a purchase order
+// @Tags         purchase-order
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Purchase Order ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /purchase-order/{id}/receipts [get]
+func (h *PurchaseOrderHandler) GetPurchaseOrderReceipts(w http.ResponseWriter, r *http.Request) {
+	id, err := purchaseOrderIDFromPath(r.URL.Path, "/receipts")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Purchase Order ID",
+		})
+		return
+	}
+
+	receipts, err := h.service.GetReceipts(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Purchase order not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch goods receipts: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Goods receipts retrieved successfully",
+		Data:    receipts,
+	})
+}
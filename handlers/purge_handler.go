@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type PurgeHandler struct {
+	service *services.PurgeService
+}
+
+func NewPurgeHandler(service *services.PurgeService) *PurgeHandler {
+	return &PurgeHandler{service: service}
+}
+
+// Purge godoc
+// @Summary      Purge the recycle bin
+// @Description  Hard delete the products, categories, customers and other records soft deleted longer ago than the retention (RECYCLE_BIN_RETENTION_DAYS). A record sales or other history still refer to is kept. Every record is written to the purge log; a dry run only reports and logs what would be purged.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        dry_run  query     bool  false  "Only report what would be purged (default RECYCLE_BIN_PURGE_DRY_RUN)"
+// @Success      200      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /admin/recycle-bin/purge [post]
+func (h *PurgeHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	dryRun := h.service.DryRun()
+	if dryRunStr := r.URL.Query().Get("dry_run"); dryRunStr != "" {
+		d, err := strconv.ParseBool(dryRunStr)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid dry_run",
+			})
+			return
+		}
+		dryRun = d
+	}
+
+	result, err := h.service.Purge(dryRun)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to purge recycle bin: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Recycle bin purged successfully",
+		Data:    result,
+	})
+}
+
+// GetLog godoc
+// @Summary      Get the purge log
+// @Description  Get the records the recycle bin purge removed or kept, newest first, dry runs included
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        limit  query     int  false  "Maximum number of entries (default 50)"
+// @Success      200    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /admin/recycle-bin/log [get]
+func (h *PurgeHandler) GetLog(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid limit",
+			})
+			return
+		}
+		limit = l
+	}
+
+	entries, err := h.service.GetLog(limit)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch purge log: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Purge log retrieved successfully",
+		Data:    entries,
+	})
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type PushHandler struct {
+	service *services.PushService
+}
+
+func NewPushHandler(service *services.PushService) *PushHandler {
+	return &PushHandler{service: service}
+}
+
+// GetVAPIDPublicKey godoc
+// @Summary      Get the VAPID public key
+// @Description  Get the applicationServerKey to pass to PushManager.subscribe() in the admin UI
+// @Tags         push
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      503  {object}  utils.Response
+// @Router       /push/vapid-public-key [get]
+func (h *PushHandler) GetVAPIDPublicKey(w http.ResponseWriter, r *http.Request) {
+	key, err := h.service.VAPIDPublicKey()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusServiceUnavailable, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "VAPID public key retrieved successfully",
+		Data:    map[string]string{"public_key": key},
+	})
+}
+
+// GetPushSubscriptions godoc
+// @Summary      Get push subscriptions
+// @Description  Get the browsers subscribed to critical alerts
+// @Tags         push
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /push/subscriptions [get]
+func (h *PushHandler) GetPushSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := h.service.GetSubscriptions()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch push subscriptions: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Push subscriptions retrieved successfully",
+		Data:    subscriptions,
+	})
+}
+
+// Subscribe godoc
+// @Summary      Subscribe to push alerts
+// @Description  Register the browser's PushSubscription for critical alerts such as a payment gateway outage, a failed backup or a large refund
+// @Tags         push
+// @Accept       json
+// @Produce      json
+// @Param        subscription  body      models.PushSubscription  true  "PushSubscription.toJSON()"
+// @Success      201           {object}  utils.Response
+// @Failure      400           {object}  utils.Response
+// @Failure      500           {object}  utils.Response
+// @Router       /push/subscriptions [post]
+func (h *PushHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	var sub models.PushSubscription
+	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+	sub.UserAgent = r.UserAgent()
+
+	saved, err := h.service.Subscribe(sub)
+	if errors.Is(err, services.ErrInvalidSubscription) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to save push subscription: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Subscribed to push alerts successfully",
+		Data:    saved,
+	})
+}
+
+// Unsubscribe godoc
+// @Summary      Unsubscribe from push alerts
+// @Description  Remove a browser's subscription by its endpoint
+// @Tags         push
+// @Accept       json
+// @Produce      json
+// @Param        subscription  body      models.UnsubscribeRequest  true  "Subscription endpoint"
+// @Success      200           {object}  utils.Response
+// @Failure      400           {object}  utils.Response
+// @Failure      404           {object}  utils.Response
+// @Failure      500           {object}  utils.Response
+// @Router       /push/subscriptions [delete]
+func (h *PushHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	var req models.UnsubscribeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	err := h.service.Unsubscribe(req.Endpoint)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Push subscription not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to delete push subscription: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Unsubscribed from push alerts successfully",
+	})
+}
+
+// SendTestPush godoc
+// @Summary      Send a test alert
+// @Description  Push a test alert to every subscribed browser
+// @Tags         push
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      503  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /push/test [post]
+func (h *PushHandler) SendTestPush(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.service.VAPIDPublicKey(); err != nil {
+		utils.WriteJSON(w, http.StatusServiceUnavailable, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.service.Alert(services.AlertTest, "Test alert", "Push alerts are working"); err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to send test alert: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Test alert queued successfully",
+	})
+}
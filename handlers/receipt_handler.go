@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type ReceiptHandler struct {
+	service *services.ReceiptService
+}
+
+func NewReceiptHandler(service *services.ReceiptService) *ReceiptHandler {
+	return &ReceiptHandler{service: service}
+}
+
+// EmailReceipt godoc
+// @Summary      Email a transaction receipt
+// @Description  Render the receipt of a transaction and queue it for delivery to the given email address
+// @Tags         transaction
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                         true  "Transaction ID"
+// @Param        request  body      models.EmailReceiptRequest  true  "Recipient"
+// @Success      202      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      404      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /transactions/{id}/email-receipt [post]
+func (h *ReceiptHandler) EmailReceipt(w http.ResponseWriter, r *http.Request) {
+	id, err := transactionIDFromPath(r.URL.Path, "/email-receipt")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Transaction ID",
+		})
+		return
+	}
+
+	var req models.EmailReceiptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	delivery, err := h.service.EmailReceipt(id, req.Email)
+	if err == services.ErrInvalidEmail {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Transaction not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to send receipt: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, utils.Response{
+		Status:  "success",
+		Message: "Receipt queued for delivery",
+		Data:    delivery,
+	})
+}
+
+// GetReceiptDeliveries godoc
+// @Summary      Get receipt email deliveries
+// @Description  Get the delivery status of every receipt email sent for a transaction
+// @Tags         transaction
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Transaction ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /transactions/{id}/email-receipt [get]
+func (h *ReceiptHandler) GetReceiptDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := transactionIDFromPath(r.URL.Path, "/email-receipt")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Transaction ID",
+		})
+		return
+	}
+
+	deliveries, err := h.service.GetDeliveries(id)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch receipt deliveries: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Receipt deliveries retrieved successfully",
+		Data:    deliveries,
+	})
+}
+
+// transactionIDFromPath parses the ID out of /api/transactions/{id}[suffix]
+func transactionIDFromPath(path, suffix string) (int, error) {
+	idStr := strings.TrimPrefix(path, "/api/transactions/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	return strconv.Atoi(idStr)
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type ReceiptTemplateHandler struct {
+	service *services.ReceiptTemplateService
+}
+
+func NewReceiptTemplateHandler(service *services.ReceiptTemplateService) *ReceiptTemplateHandler {
+	return &ReceiptTemplateHandler{service: service}
+}
+
+// GetReceiptTemplates godoc
+// @Summary      Get receipt template versions
+// @Description  Get every saved version of the receipt branding, newest first; the active one is used for receipts
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /admin/receipt-templates [get]
+func (h *ReceiptTemplateHandler) GetReceiptTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.service.GetAll()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch receipt templates: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Receipt templates retrieved successfully",
+		Data:    templates,
+	})
+}
+
+// SaveReceiptTemplate godoc
+// @Summary      Save a receipt template version
+// @Description  Save the logo, header, footer and promo message of receipts as a new version and make it active. Body, when given, replaces the built-in layout: an html/template executed with the transaction's fields, Branding (LogoURL, Header, Footer, Promo, and StoreName, StoreAddress and NPWP of the store settings) and the functions money, moneyIn, number, date, datetime, neg and lines. Without a footer, the receipt footer of the settings is printed. It must render a sample sale.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        template  body      models.ReceiptTemplateRequest  true  "Receipt template"
+// @Success      201       {object}  utils.Response
+// @Failure      400       {object}  utils.Response
+// @Failure      500       {object}  utils.Response
+// @Router       /admin/receipt-templates [post]
+func (h *ReceiptTemplateHandler) SaveReceiptTemplate(w http.ResponseWriter, r *http.Request) {
+	var req models.ReceiptTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	tmpl, err := h.service.Save(req)
+	if err == services.ErrInvalidLogoURL || errors.Is(err, services.ErrInvalidTemplate) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to save receipt template: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Receipt template saved successfully",
+		Data:    tmpl,
+	})
+}
+
+// ActivateReceiptTemplate godoc
+// @Summary      Activate a receipt template version
+// @Description  Use an earlier version of the receipt branding again
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Version"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /admin/receipt-templates/{id}/activate [post]
+func (h *ReceiptTemplateHandler) ActivateReceiptTemplate(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/admin/receipt-templates/"), "/activate")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid receipt template version",
+		})
+		return
+	}
+
+	tmpl, err := h.service.Activate(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Receipt template not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to activate receipt template: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Receipt template activated successfully",
+		Data:    tmpl,
+	})
+}
+
+// PreviewReceiptTemplate godoc
+// @Summary      Preview a receipt template
+// @Description  Render a receipt template as it would be saved, with the receipt of a transaction or, without one, a sample sale. Nothing is saved.
+// @Tags         admin
+// @Accept       json
+// @Produce      html
+// @Param        preview  body      models.ReceiptPreviewRequest  true  "Template and transaction"
+// @Success      200      {string}  string  "Rendered receipt"
+// @Failure      400      {object}  utils.Response
+// @Failure      404      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /admin/receipt-templates/preview [post]
+func (h *ReceiptTemplateHandler) PreviewReceiptTemplate(w http.ResponseWriter, r *http.Request) {
+	var req models.ReceiptPreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	body, err := h.service.Preview(req)
+	if err == services.ErrInvalidLogoURL || errors.Is(err, services.ErrInvalidTemplate) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Transaction not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to preview receipt template: " + err.Error(),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(body))
+}
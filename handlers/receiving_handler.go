@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type ReceivingHandler struct {
+	service *services.ReceivingService
+}
+
+func NewReceivingHandler(service *services.ReceivingService) *ReceivingHandler {
+	return &ReceivingHandler{service: service}
+}
+
+// receivingIDFromPath parses the ID out of /api/receiving/{id}[suffix]
+func receivingIDFromPath(path, suffix string) (int, error) {
+	idStr := strings.TrimPrefix(path, "/api/receiving/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	return strconv.Atoi(idStr)
+}
+
+// OpenReceivingSession godoc
+// @Summary      Open a receiving session
+// @Description  Start receiving goods against an open purchase order
+// @Tags         receiving
+// @Accept       json
+// @Produce      json
+// @Param        session  body      models.OpenReceivingRequest  true  "Purchase Order"
+// @Success      201      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      404      {object}  utils.Response
+// @Failure      409      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /receiving [post]
+func (h *ReceivingHandler) OpenReceivingSession(w http.ResponseWriter, r *http.Request) {
+	var req models.OpenReceivingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	session, err := h.service.Open(req.PurchaseOrderID)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Purchase order not found",
+		})
+		return
+	}
+
+	if err == services.ErrPurchaseOrderNotOpen || err == services.ErrSessionAlreadyOpen {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to open receiving session: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Receiving session opened successfully",
+		Data:    session,
+	})
+}
+
+// GetReceivingSession godoc
+// @Summary      Get a receiving session
+// @Description  Get scanned versus expected quantity per product with overage/shortage flags
+// @Tags         receiving
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Receiving Session ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /receiving/{id} [get]
+func (h *ReceivingHandler) GetReceivingSession(w http.ResponseWriter, r *http.Request) {
+	id, err := receivingIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Receiving Session ID",
+		})
+		return
+	}
+
+	session, err := h.service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Receiving session not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch receiving session: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Receiving session retrieved successfully",
+		Data:    session,
+	})
+}
+
+// ScanReceivingItem godoc
+// @Summary      Scan an item
+// @Description  Scan a product barcode, incrementing its received count in the session (quantity defaults to 1). Units scanned with expires_on are stocked as a batch of that date when the session is committed.
+// @Tags         receiving
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int                 true  "Receiving Session ID"
+// @Param        scan  body      models.ScanRequest  true  "Scan Data"
+// @Success      200   {object}  utils.Response
+// @Failure      400   {object}  utils.Response
+// @Failure      404   {object}  utils.Response
+// @Failure      409   {object}  utils.Response
+// @Failure      500   {object}  utils.Response
+// @Router       /receiving/{id}/scan [post]
+func (h *ReceivingHandler) ScanReceivingItem(w http.ResponseWriter, r *http.Request) {
+	id, err := receivingIDFromPath(r.URL.Path, "/scan")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Receiving Session ID",
+		})
+		return
+	}
+
+	var req models.ScanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	session, err := h.service.Scan(id, req)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Receiving session not found",
+		})
+		return
+	}
+
+	if err == repositories.ErrProductNotOnOrder || err == services.ErrInvalidAmount || err == services.ErrInvalidExpiryDate {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == repositories.ErrSessionNotOpen {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to scan item: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Item scanned successfully",
+		Data:    session,
+	})
+}
+
+// CommitReceivingSession godoc
+// @Summary      Commit a receiving session
+// @Description  Add all scanned quantities to stock and to the purchase order, record a goods receipt and close the session
+// @Tags         receiving
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Receiving Session ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      409  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /receiving/{id}/commit [post]
+func (h *ReceivingHandler) CommitReceivingSession(w http.ResponseWriter, r *http.Request) {
+	id, err := receivingIDFromPath(r.URL.Path, "/commit")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Receiving Session ID",
+		})
+		return
+	}
+
+	session, err := h.service.Commit(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Receiving session not found",
+		})
+		return
+	}
+
+	if err == repositories.ErrSessionNotOpen {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to commit receiving session: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Receiving session committed successfully",
+		Data:    session,
+	})
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type ReliabilityHandler struct {
+	service *services.ReliabilityService
+}
+
+func NewReliabilityHandler(service *services.ReliabilityService) *ReliabilityHandler {
+	return &ReliabilityHandler{service: service}
+}
+
+// GetReliability godoc
+// @Summary      Get the error budget report
+// @Description  Get failed webhook deliveries, failed background jobs, reminders refused by the WhatsApp, SMS or email gateway and the API's 5xx rate, per day and in total. healthy is false once the 5xx rate is over the error budget or anything failed today.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        days  query     int  false  "Number of days, counting today"  default(7) minimum(1) maximum(90)
+// @Success      200   {object}  utils.Response
+// @Failure      400   {object}  utils.Response
+// @Failure      500   {object}  utils.Response
+// @Router       /admin/reliability [get]
+func (h *ReliabilityHandler) GetReliability(w http.ResponseWriter, r *http.Request) {
+	days := 7
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		var err error
+		days, err = strconv.Atoi(daysStr)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: services.ErrInvalidReliabilityDays.Error(),
+			})
+			return
+		}
+	}
+
+	report, err := h.service.Report(days)
+	if err == services.ErrInvalidReliabilityDays {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to build reliability report: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Reliability report retrieved successfully",
+		Data:    report,
+	})
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type ReorderHandler struct {
+	service *services.ReorderService
+}
+
+func NewReorderHandler(service *services.ReorderService) *ReorderHandler {
+	return &ReorderHandler{service: service}
+}
+
+// GetReorderSuggestions godoc
+// @Summary      Get reorder suggestions
+// @Description  Get products at or below their reorder point with a suggested quantity and the cheapest current supplier
+// @Tags         reorder
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /reorder/suggestions [get]
+func (h *ReorderHandler) GetReorderSuggestions(w http.ResponseWriter, r *http.Request) {
+	suggestions, err := h.service.GetSuggestions()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch reorder suggestions: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Reorder suggestions retrieved successfully",
+		Data:    suggestions,
+	})
+}
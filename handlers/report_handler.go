@@ -17,15 +17,25 @@ func NewReportHandler(service *services.ReportService) *ReportHandler {
 
 // GetDailySalesReport godoc
 // @Summary      Get today's sales report
-// @Description  Get sales report for today including total revenue, transaction count, and top-selling product
+// @Description  Get sales report for today including total revenue, transaction count, and top-selling product. Revenue is in the store currency; payments breaks it down by the currency it was paid in.
 // @Tags         report
 // @Accept       json
 // @Produce      json
+// @Param        tz   query     string  false  "IANA time zone of the day, e.g. Asia/Makassar (default STORE_TIMEZONE)"
 // @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
 // @Failure      500  {object}  utils.Response
 // @Router       /report/hari-ini [get]
 func (h *ReportHandler) GetDailySalesReport(w http.ResponseWriter, r *http.Request) {
-	report, err := h.service.GetDailySalesReport()
+	report, err := h.service.GetDailySalesReport(r.URL.Query().Get("tz"))
+	if err == services.ErrInvalidTimezone {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
 	if err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
 			Status:  "failed",
@@ -43,12 +53,13 @@ func (h *ReportHandler) GetDailySalesReport(w http.ResponseWriter, r *http.Reque
 
 // GetSalesReportByDateRange godoc
 // @Summary      Get sales report by date range
-// @Description  Get sales report for a specific date range including total revenue, transaction count, and top-selling product
+// @Description  Get sales report for a specific date range including total revenue, transaction count, and top-selling product. Revenue is in the store currency; payments breaks it down by the currency it was paid in.
 // @Tags         report
 // @Accept       json
 // @Produce      json
 // @Param        start_date  query     string  true  "Start date (YYYY-MM-DD)"
 // @Param        end_date    query     string  true  "End date (YYYY-MM-DD)"
+// @Param        tz          query     string  false  "IANA time zone the dates are days of, e.g. Asia/Makassar (default STORE_TIMEZONE)"
 // @Success      200         {object}  utils.Response
 // @Failure      400         {object}  utils.Response
 // @Failure      500         {object}  utils.Response
@@ -65,11 +76,15 @@ func (h *ReportHandler) GetSalesReportByDateRange(w http.ResponseWriter, r *http
 		return
 	}
 
-	// Add time component to dates
-	startDateTime := startDate + " 00:00:00"
-	endDateTime := endDate + " 23:59:59"
+	report, err := h.service.GetSalesReportByDateRange(startDate, endDate, r.URL.Query().Get("tz"))
+	if err == services.ErrInvalidTimezone || err == services.ErrInvalidDateRange {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
 
-	report, err := h.service.GetSalesReportByDateRange(startDateTime, endDateTime)
 	if err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
 			Status:  "failed",
@@ -84,3 +99,310 @@ func (h *ReportHandler) GetSalesReportByDateRange(w http.ResponseWriter, r *http
 		Data:    report,
 	})
 }
+
+// GetProfitReport godoc
+// @Summary      Get profit report by date range
+// @Description  Get gross profit and margin per product and per day for a specific date range, based on cost prices recorded at checkout
+// @Tags         report
+// @Accept       json
+// @Produce      json
+// @Param        start_date  query     string  true  "Start date (YYYY-MM-DD)"
+// @Param        end_date    query     string  true  "End date (YYYY-MM-DD)"
+// @Param        tz          query     string  false  "IANA time zone the dates are days of, e.g. Asia/Makassar (default STORE_TIMEZONE)"
+// @Success      200         {object}  utils.Response
+// @Failure      400         {object}  utils.Response
+// @Failure      500         {object}  utils.Response
+// @Router       /report/profit [get]
+func (h *ReportHandler) GetProfitReport(w http.ResponseWriter, r *http.Request) {
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
+
+	if startDate == "" || endDate == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "start_date and end_date query parameters are required",
+		})
+		return
+	}
+
+	report, err := h.service.GetProfitReport(startDate, endDate, r.URL.Query().Get("tz"))
+	if err == services.ErrInvalidTimezone || err == services.ErrInvalidDateRange {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch profit report: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Profit report retrieved successfully",
+		Data:    report,
+	})
+}
+
+// GetComparisonReport godoc
+// @Summary      Compare sales of two months or years
+// @Description  Get the revenue, transactions and best sellers of a month or year side by side with those of another, with the growth from it as a percentage (null when it sold nothing)
+// @Tags         report
+// @Accept       json
+// @Produce      json
+// @Param        period    query     string  true   "Period compared"  Enums(month, year)
+// @Param        current   query     string  true   "Current period (YYYY-MM for a month, YYYY for a year)"
+// @Param        previous  query     string  false  "Period compared against, in the format of current (default the one before current)"
+// @Param        tz        query     string  false  "IANA time zone the periods are of, e.g. Asia/Makassar (default STORE_TIMEZONE)"
+// @Success      200       {object}  utils.Response
+// @Failure      400       {object}  utils.Response
+// @Failure      500       {object}  utils.Response
+// @Router       /report/compare [get]
+func (h *ReportHandler) GetComparisonReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("period") == "" || query.Get("current") == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "period and current query parameters are required",
+		})
+		return
+	}
+
+	report, err := h.service.GetComparisonReport(query.Get("period"), query.Get("current"), query.Get("previous"), query.Get("tz"))
+	if err == services.ErrInvalidComparePeriod || err == services.ErrInvalidComparison || err == services.ErrInvalidTimezone {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch comparison report: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Comparison report retrieved successfully",
+		Data:    report,
+	})
+}
+
+// GetProfitLossReport godoc
+// @Summary      Get profit and loss summary by date range
+// @Description  Sum up the sales of a date range down to net profit: gross sales, discounts, cash rounding and refunds of sales refunded since make the net sales; the PPN they include at TAX_RATE is the tax collected, and the rest less the cost of goods sold and the petty cash expenses is the net profit
+// @Tags         report
+// @Accept       json
+// @Produce      json
+// @Param        start_date  query     string  true  "Start date (YYYY-MM-DD)"
+// @Param        end_date    query     string  true  "End date (YYYY-MM-DD)"
+// @Param        tz          query     string  false  "IANA time zone the dates are days of, e.g. Asia/Makassar (default STORE_TIMEZONE)"
+// @Success      200         {object}  utils.Response
+// @Failure      400         {object}  utils.Response
+// @Failure      500         {object}  utils.Response
+// @Router       /report/profit-loss [get]
+func (h *ReportHandler) GetProfitLossReport(w http.ResponseWriter, r *http.Request) {
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
+
+	if startDate == "" || endDate == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "start_date and end_date query parameters are required",
+		})
+		return
+	}
+
+	report, err := h.service.GetProfitLossReport(startDate, endDate, r.URL.Query().Get("tz"))
+	if err == services.ErrInvalidTimezone || err == services.ErrInvalidDateRange {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch profit and loss report: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Profit and loss report retrieved successfully",
+		Data:    report,
+	})
+}
+
+// GetPriceContractSalesReport godoc
+// @Summary      Get price contract sales report by date range
+// @Description  Get the sales made under each wholesale price contract in a specific date range: transactions, quantity, revenue and the discount the contract gave off the product prices
+// @Tags         report
+// @Accept       json
+// @Produce      json
+// @Param        start_date  query     string  true  "Start date (YYYY-MM-DD)"
+// @Param        end_date    query     string  true  "End date (YYYY-MM-DD)"
+// @Param        tz          query     string  false  "IANA time zone the dates are days of, e.g. Asia/Makassar (default STORE_TIMEZONE)"
+// @Success      200         {object}  utils.Response
+// @Failure      400         {object}  utils.Response
+// @Failure      500         {object}  utils.Response
+// @Router       /report/price-contracts [get]
+func (h *ReportHandler) GetPriceContractSalesReport(w http.ResponseWriter, r *http.Request) {
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
+
+	if startDate == "" || endDate == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "start_date and end_date query parameters are required",
+		})
+		return
+	}
+
+	report, err := h.service.GetPriceContractSalesReport(startDate, endDate, r.URL.Query().Get("tz"))
+	if err == services.ErrInvalidTimezone || err == services.ErrInvalidDateRange {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch price contract sales report: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Price contract sales report retrieved successfully",
+		Data:    report,
+	})
+}
+
+// GetShrinkageReport godoc
+// @Summary      Get shrinkage report by date range
+// @Description  Get stock lost to damage, expiry and count shortages per reason and per product, valued at cost, with the shrinkage rate against cost of goods sold. Returns to suppliers are reported separately.
+// @Tags         report
+// @Accept       json
+// @Produce      json
+// @Param        start_date  query     string  true  "Start date (YYYY-MM-DD)"
+// @Param        end_date    query     string  true  "End date (YYYY-MM-DD)"
+// @Param        tz          query     string  false  "IANA time zone the dates are days of, e.g. Asia/Makassar (default STORE_TIMEZONE)"
+// @Success      200         {object}  utils.Response
+// @Failure      400         {object}  utils.Response
+// @Failure      500         {object}  utils.Response
+// @Router       /report/shrinkage [get]
+func (h *ReportHandler) GetShrinkageReport(w http.ResponseWriter, r *http.Request) {
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
+
+	if startDate == "" || endDate == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "start_date and end_date query parameters are required",
+		})
+		return
+	}
+
+	report, err := h.service.GetShrinkageReport(startDate, endDate, r.URL.Query().Get("tz"))
+	if err == services.ErrInvalidTimezone || err == services.ErrInvalidDateRange {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch shrinkage report: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Shrinkage report retrieved successfully",
+		Data:    report,
+	})
+}
+
+// GetReceivablesAging godoc
+// @Summary      Get receivables aging report
+// @Description  Get outstanding installment balances grouped by days overdue (current, 1-30, 31-60, 61-90, over 90), in total and per customer
+// @Tags         report
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /report/receivables-aging [get]
+func (h *ReportHandler) GetReceivablesAging(w http.ResponseWriter, r *http.Request) {
+	report, err := h.service.GetReceivablesAging()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch receivables aging report: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Receivables aging report retrieved successfully",
+		Data:    report,
+	})
+}
+
+// AggregateDailyReport godoc
+// @Summary      Rebuild a daily report summary
+// @Description  Re-aggregate the pre-computed sales summary of a past day. Days are aggregated nightly; today is always read live.
+// @Tags         report
+// @Accept       json
+// @Produce      json
+// @Param        date  query     string  true  "Date (YYYY-MM-DD)"
+// @Success      200   {object}  utils.Response
+// @Failure      400   {object}  utils.Response
+// @Failure      500   {object}  utils.Response
+// @Router       /report/aggregate [post]
+func (h *ReportHandler) AggregateDailyReport(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+
+	err := h.service.AggregateDay(date)
+	if err == services.ErrInvalidAggregationDate {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to aggregate daily report: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Daily report aggregated successfully",
+	})
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type ReportSubscriptionHandler struct {
+	service *services.ReportSubscriptionService
+}
+
+func NewReportSubscriptionHandler(service *services.ReportSubscriptionService) *ReportSubscriptionHandler {
+	return &ReportSubscriptionHandler{service: service}
+}
+
+// GetReportSubscriptions godoc
+// @Summary      Get report subscriptions
+// @Description  Get the addresses the sales summary is emailed to, with the last day of the last period sent to each
+// @Tags         report
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /report-subscriptions [get]
+func (h *ReportSubscriptionHandler) GetReportSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := h.service.GetAll()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch report subscriptions: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Report subscriptions retrieved successfully",
+		Data:    subscriptions,
+	})
+}
+
+// SubscribeReport godoc
+// @Summary      Subscribe to the sales summary
+// @Description  Email an address the sales summary on REPORT_EMAIL_SCHEDULE: daily of yesterday, or weekly of last Monday to Sunday. A pdf subscription gets it attached as PDF too. Subscribing an address again at the same frequency changes its format.
+// @Tags         report
+// @Accept       json
+// @Produce      json
+// @Param        subscription  body      models.ReportSubscription  true  "Subscription"
+// @Success      201           {object}  utils.Response
+// @Failure      400           {object}  utils.Response
+// @Failure      500           {object}  utils.Response
+// @Router       /report-subscriptions [post]
+func (h *ReportSubscriptionHandler) SubscribeReport(w http.ResponseWriter, r *http.Request) {
+	var sub models.ReportSubscription
+	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	saved, err := h.service.Subscribe(sub)
+	if err == services.ErrInvalidEmail || err == services.ErrInvalidReportFrequency || err == services.ErrInvalidReportFormat {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to save report subscription: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Subscribed to the sales summary successfully",
+		Data:    saved,
+	})
+}
+
+// UnsubscribeReport godoc
+// @Summary      Unsubscribe from the sales summary
+// @Description  Stop emailing the sales summary to a subscription
+// @Tags         report
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Subscription ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /report-subscriptions/{id} [delete]
+func (h *ReportSubscriptionHandler) UnsubscribeReport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/report-subscriptions/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Subscription ID",
+		})
+		return
+	}
+
+	err = h.service.Unsubscribe(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Report subscription not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to delete report subscription: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Unsubscribed from the sales summary successfully",
+	})
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type RequestJournalHandler struct {
+	service *services.RequestJournalService
+}
+
+func NewRequestJournalHandler(service *services.RequestJournalService) *RequestJournalHandler {
+	return &RequestJournalHandler{service: service}
+}
+
+func journalIDFromPath(path, suffix string) (int64, error) {
+	idStr := strings.TrimSuffix(strings.TrimPrefix(path, "/api/request-journal/"), suffix)
+	return strconv.ParseInt(idStr, 10, 64)
+}
+
+// GetJournalEntries godoc
+// @Summary      Get request journal entries
+// @Description  Get the latest anonymized request/response pairs captured from the journaled endpoints, newest first. Capture is enabled with REQUEST_JOURNAL.
+// @Tags         request-journal
+// @Accept       json
+// @Produce      json
+// @Param        terminal_id  query     string  false  "Filter by terminal"
+// @Param        path         query     string  false  "Filter by request path"
+// @Success      200          {object}  utils.Response
+// @Failure      500          {object}  utils.Response
+// @Router       /request-journal [get]
+func (h *RequestJournalHandler) GetJournalEntries(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.service.GetAll(r.URL.Query().Get("terminal_id"), r.URL.Query().Get("path"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch request journal: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Request journal retrieved successfully",
+		Data:    entries,
+	})
+}
+
+// GetJournalEntryByID godoc
+// @Summary      Get a request journal entry
+// @Description  Get a captured request/response pair by ID
+// @Tags         request-journal
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Journal entry ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /request-journal/{id} [get]
+func (h *RequestJournalHandler) GetJournalEntryByID(w http.ResponseWriter, r *http.Request) {
+	id, err := journalIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid journal entry ID",
+		})
+		return
+	}
+
+	entry, err := h.service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Journal entry not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch journal entry: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Journal entry retrieved successfully",
+		Data:    entry,
+	})
+}
+
+// ReplayJournalEntry godoc
+// @Summary      Replay a captured request
+// @Description  Send a captured request again, with its anonymized body and kept headers, to the staging instance set in REQUEST_JOURNAL_REPLAY_URL and compare the responses
+// @Tags         request-journal
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Journal entry ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      502  {object}  utils.Response
+// @Failure      503  {object}  utils.Response
+// @Router       /request-journal/{id}/replay [post]
+func (h *RequestJournalHandler) ReplayJournalEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := journalIDFromPath(r.URL.Path, "/replay")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid journal entry ID",
+		})
+		return
+	}
+
+	result, err := h.service.Replay(id)
+	if err == services.ErrReplayNotConfigured {
+		utils.WriteJSON(w, http.StatusServiceUnavailable, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Journal entry not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadGateway, utils.Response{
+			Status:  "failed",
+			Message: "Failed to replay request: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Request replayed successfully",
+		Data:    result,
+	})
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+// the kitchen display is sent the open orders when they change, checked
+// every kitchenPollInterval, and a comment when nothing changed for
+// kitchenKeepAlive so proxies keep the stream open
+const (
+	kitchenPollInterval = 2 * time.Second
+	kitchenKeepAlive    = 15 * time.Second
+)
+
+type RestaurantHandler struct {
+	service *services.RestaurantService
+}
+
+func NewRestaurantHandler(service *services.RestaurantService) *RestaurantHandler {
+	return &RestaurantHandler{service: service}
+}
+
+// writeOrderError answers a failed order with the status and error code of
+// its cause; what checkout refuses is answered as checkout does
+func writeOrderError(w http.ResponseWriter, err error, action string) {
+	code := checkoutErrorCode(err)
+	status := checkoutErrorStatus(code)
+	switch {
+	case err == services.ErrRestaurantModeOff:
+		code, status = "restaurant_mode_off", http.StatusServiceUnavailable
+	case err == services.ErrEmptyCart || err == services.ErrInvalidAmount || err == services.ErrInvalidTableNumber:
+		code, status = "invalid_request", http.StatusBadRequest
+	case err == repositories.ErrTableOccupied:
+		code, status = "table_occupied", http.StatusConflict
+	case err == repositories.ErrOrderClosed:
+		code, status = "order_closed", http.StatusConflict
+	case errors.Is(err, repositories.ErrPeriodClosed):
+		code, status = "period_closed", http.StatusConflict
+	case err == sql.ErrNoRows:
+		code, status = "order_not_found", http.StatusNotFound
+	}
+
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		message = "Failed to " + action + ": " + message
+	}
+	res := utils.Response{
+		Status:    "failed",
+		Message:   message,
+		ErrorCode: code,
+	}
+	res.Errors, res.Data = priceViolations(err)
+	utils.WriteJSON(w, status, res)
+}
+
+// OpenOrder godoc
+// @Summary      Open a restaurant order
+// @Description  Open an order served at table_number or, without one, taken away under the next queue number of the day, at the prices of store_id when given (multi-store). The order is a draft sale: its stock is taken now, more items can be added until it is billed by moving it to pending_payment or paid, which rounds its total for cash, and the kitchen display shows it while it is open. A table takes one open order at a time (409). Price overrides and lines priced past the price guardrails need an approval, as at checkout (403, 422 without one; 423 while the approver is locked out). Needs RESTAURANT_MODE (503 otherwise).
+// @Tags         restaurant
+// @Accept       json
+// @Produce      json
+// @Param        order  body      models.OrderRequest  true  "Order"
+// @Success      201    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      403    {object}  utils.Response
+// @Failure      409    {object}  utils.Response
+// @Failure      422    {object}  utils.Response
+// @Failure      423    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Failure      503    {object}  utils.Response
+// @Router       /orders [post]
+func (h *RestaurantHandler) OpenOrder(w http.ResponseWriter, r *http.Request) {
+	var req models.OrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:    "failed",
+			Message:   "Invalid request body",
+			ErrorCode: "invalid_request",
+		})
+		return
+	}
+
+	order, err := h.service.OpenOrder(r.Context(), req)
+	if err != nil {
+		writeOrderError(w, err, "open order")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Order opened successfully",
+		Data:    order,
+	})
+}
+
+// AddOrderItems godoc
+// @Summary      Add items to an order
+// @Description  Add items to an open order, priced as a checkout of them would be now at the store the order was opened at, and take their stock. Only a draft takes more items, and none made in a closed accounting period (409 otherwise). Price overrides and lines priced past the price guardrails need an approval, as at checkout (403, 422 without one; 423 while the approver is locked out). Needs RESTAURANT_MODE (503 otherwise).
+// @Tags         restaurant
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                       true  "Transaction ID"
+// @Param        items  body      models.OrderItemsRequest  true  "Items"
+// @Success      200    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      403    {object}  utils.Response
+// @Failure      404    {object}  utils.Response
+// @Failure      409    {object}  utils.Response
+// @Failure      422    {object}  utils.Response
+// @Failure      423    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Failure      503    {object}  utils.Response
+// @Router       /orders/{id}/items [post]
+func (h *RestaurantHandler) AddOrderItems(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/orders/"), "/items")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:    "failed",
+			Message:   "Invalid order ID",
+			ErrorCode: "invalid_request",
+		})
+		return
+	}
+
+	var req models.OrderItemsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:    "failed",
+			Message:   "Invalid request body",
+			ErrorCode: "invalid_request",
+		})
+		return
+	}
+
+	order, err := h.service.AddItems(r.Context(), id, req.Items, req.Approval)
+	if err != nil {
+		writeOrderError(w, err, "add items")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Items added successfully",
+		Data:    order,
+	})
+}
+
+// StreamKitchenOrders godoc
+// @Summary      Stream open orders to the kitchen
+// @Description  Server-sent events for a kitchen display: an orders event with every open order, oldest first, sent on connecting and whenever an order is opened, added to or billed. Lines carry when they were ordered, so what was added to an order can be told apart. Needs RESTAURANT_MODE (503 otherwise).
+// @Tags         restaurant
+// @Produce      text/event-stream
+// @Success      200  {array}   models.KitchenOrder
+// @Failure      500  {object}  utils.Response
+// @Failure      503  {object}  utils.Response
+// @Router       /kitchen/orders [get]
+func (h *RestaurantHandler) StreamKitchenOrders(w http.ResponseWriter, r *http.Request) {
+	orders, err := h.service.KitchenOrders()
+	if err != nil {
+		writeOrderError(w, err, "fetch open orders")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+
+	last := ""
+	lastSent := time.Now()
+	send := func(orders []models.KitchenOrder) error {
+		data, err := json.Marshal(orders)
+		if err != nil {
+			return err
+		}
+		if string(data) == last {
+			if time.Since(lastSent) < kitchenKeepAlive {
+				return nil
+			}
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		} else {
+			last = string(data)
+			_, err = fmt.Fprintf(w, "event: orders\ndata: %s\n\n", data)
+		}
+		if err != nil {
+			return err
+		}
+		lastSent = time.Now()
+		return rc.Flush()
+	}
+
+	if err := send(orders); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(kitchenPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			orders, err := h.service.KitchenOrders()
+			if err != nil {
+				fmt.Fprintf(w, "event: error\ndata: %q\n\n", err.Error())
+				rc.Flush()
+				return
+			}
+			if err := send(orders); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type SelfTestHandler struct {
+	service *services.SelfTestService
+}
+
+func NewSelfTestHandler(service *services.SelfTestService) *SelfTestHandler {
+	return &SelfTestHandler{service: service}
+}
+
+// GetSelfTest godoc
+// @Summary      Get the startup self-test report
+// @Description  Get the result of the checks run on boot: database schema version, required settings, storage and, when configured, the receipt printer. While a critical check fails, requests that change data are refused with 503.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      503  {object}  utils.Response
+// @Router       /admin/selftest [get]
+func (h *SelfTestHandler) GetSelfTest(w http.ResponseWriter, r *http.Request) {
+	writeSelfTestReport(w, h.service.Report())
+}
+
+// RunSelfTest godoc
+// @Summary      Run the self-test again
+// @Description  Run the startup checks again, e.g. after fixing a failing one; changes are accepted again once every critical check passes
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      503  {object}  utils.Response
+// @Router       /admin/selftest [post]
+func (h *SelfTestHandler) RunSelfTest(w http.ResponseWriter, r *http.Request) {
+	writeSelfTestReport(w, h.service.Run(r.Context()))
+}
+
+func writeSelfTestReport(w http.ResponseWriter, report models.SelfTestReport) {
+	if !report.OK {
+		utils.WriteJSON(w, http.StatusServiceUnavailable, utils.Response{
+			Status:    "failed",
+			Message:   "Self-test failed, changes are refused until every critical check passes",
+			ErrorCode: "self_test_failed",
+			Data:      report,
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Self-test passed",
+		Data:    report,
+	})
+}
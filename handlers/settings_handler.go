@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type SettingsHandler struct {
+	service *services.SettingsService
+}
+
+func NewSettingsHandler(service *services.SettingsService) *SettingsHandler {
+	return &SettingsHandler{service: service}
+}
+
+// GetSettings godoc
+// @Summary      Get the store settings
+// @Description  Get the store profile: name, address and NPWP on receipts and tax invoices, the receipt footer, the currency amounts are in, the PPN rate prices include and the time zone the store's days start in. Before they are first saved, the settings from the environment (TAX_SELLER_NAME, TAX_SELLER_ADDRESS, TAX_SELLER_NPWP, STORE_CURRENCY, TAX_RATE, STORE_TIMEZONE) are returned.
+// @Tags         settings
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Router       /settings [get]
+func (h *SettingsHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Settings retrieved successfully",
+		Data:    h.service.Settings(),
+	})
+}
+
+// UpdateSettings godoc
+// @Summary      Update the store settings
+// @Description  Replace the store profile. Receipts, reports, journal exports and tax invoices use it from then on; other instances pick it up within a minute. A new time zone drops the daily summaries, which are rolled up again in its days. The currency can't change once sales are recorded (409), and is taken on at the next start.
+// @Tags         settings
+// @Accept       json
+// @Produce      json
+// @Param        settings  body      models.StoreSettings  true  "Store settings"
+// @Success      200       {object}  utils.Response
+// @Failure      400       {object}  utils.Response
+// @Failure      409       {object}  utils.Response
+// @Failure      500       {object}  utils.Response
+// @Router       /settings [put]
+func (h *SettingsHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var req models.StoreSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	settings, err := h.service.Update(req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to update settings: " + err.Error()
+		switch err {
+		case services.ErrStoreNameRequired, services.ErrInvalidNPWP, services.ErrUnknownCurrency, services.ErrInvalidTaxRate, services.ErrInvalidStoreTimezone:
+			status, message = http.StatusBadRequest, err.Error()
+		case services.ErrCurrencyInUse:
+			status, message = http.StatusConflict, err.Error()
+		}
+		utils.WriteJSON(w, status, utils.Response{
+			Status:  "failed",
+			Message: message,
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Settings updated successfully",
+		Data:    settings,
+	})
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type StatsHandler struct {
+	service *services.StatsService
+}
+
+func NewStatsHandler(service *services.StatsService) *StatsHandler {
+	return &StatsHandler{service: service}
+}
+
+// GetStats godoc
+// @Summary      Get store stats
+// @Description  Get the catalog size, the products sold in the last 30 days, the number of customers and the revenue of the last 7 and 30 full days against the periods before them, read from the nightly summaries. The stats are recomputed at most once a minute and may be cached for as long.
+// @Tags         report
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /stats [get]
+func (h *StatsHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.GetStats()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch stats: " + err.Error(),
+		})
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, max-age=60")
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Stats retrieved successfully",
+		Data:    stats,
+	})
+}
@@ -0,0 +1,415 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type StockOpnameHandler struct {
+	service *services.StockOpnameService
+}
+
+func NewStockOpnameHandler(service *services.StockOpnameService) *StockOpnameHandler {
+	return &StockOpnameHandler{service: service}
+}
+
+// stockOpnameIDFromPath parses the ID out of /api/stock-opname/{id}[suffix]
+func stockOpnameIDFromPath(path, suffix string) (int, error) {
+	idStr := strings.TrimPrefix(path, "/api/stock-opname/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	return strconv.Atoi(idStr)
+}
+
+// GetStockOpnames godoc
+// @Summary      Get stock opnames
+// @Description  Get the physical inventory count sessions with their variance totals, newest first
+// @Tags         stock-opname
+// @Accept       json
+// @Produce      json
+// @Param        status  query     string  false  "Filter by status"  Enums(open, submitted, approved, rejected)
+// @Success      200     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /stock-opname [get]
+func (h *StockOpnameHandler) GetStockOpnames(w http.ResponseWriter, r *http.Request) {
+	opnames, err := h.service.GetAll(r.URL.Query().Get("status"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch stock opnames: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Stock opnames retrieved successfully",
+		Data:    opnames,
+	})
+}
+
+// StartStockOpname godoc
+// @Summary      Start a stock opname
+// @Description  Open a physical inventory count for the whole store, or for one category. Only one count can be in progress at a time.
+// @Tags         stock-opname
+// @Accept       json
+// @Produce      json
+// @Param        opname  body      models.StartOpnameRequest  false  "Stock Opname Data"
+// @Success      201     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      409     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /stock-opname [post]
+func (h *StockOpnameHandler) StartStockOpname(w http.ResponseWriter, r *http.Request) {
+	var req models.StartOpnameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	opname, err := h.service.Start(req)
+	if err == services.ErrInvalidAmount {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == services.ErrOpnameInProgress {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to start stock opname: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Stock opname started successfully",
+		Data:    opname,
+	})
+}
+
+// GetStockOpname godoc
+// @Summary      Get a stock opname
+// @Description  Get counted versus recorded quantity per product with variance, its value at cost price and overage/shortage flags
+// @Tags         stock-opname
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Stock Opname ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /stock-opname/{id} [get]
+func (h *StockOpnameHandler) GetStockOpname(w http.ResponseWriter, r *http.Request) {
+	id, err := stockOpnameIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Stock Opname ID",
+		})
+		return
+	}
+
+	opname, err := h.service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Stock opname not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch stock opname: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Stock opname retrieved successfully",
+		Data:    opname,
+	})
+}
+
+// RecordStockOpnameCounts godoc
+// @Summary      Record counted quantities
+// @Description  Submit counted quantities per product; the recorded stock at that moment is kept as the expected quantity. Counting a product again replaces its earlier count.
+// @Tags         stock-opname
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                        true  "Stock Opname ID"
+// @Param        counts  body      models.OpnameCountRequest  true  "Counted Quantities"
+// @Success      200     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      404     {object}  utils.Response
+// @Failure      409     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /stock-opname/{id}/counts [post]
+func (h *StockOpnameHandler) RecordStockOpnameCounts(w http.ResponseWriter, r *http.Request) {
+	id, err := stockOpnameIDFromPath(r.URL.Path, "/counts")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Stock Opname ID",
+		})
+		return
+	}
+
+	var req models.OpnameCountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	opname, err := h.service.RecordCounts(id, req)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Stock opname not found",
+		})
+		return
+	}
+
+	if err == services.ErrInvalidAmount || err == services.ErrEmptyOpnameCount || err == repositories.ErrProductNotInOpname {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == repositories.ErrOpnameNotOpen {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to record counts: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Counts recorded successfully",
+		Data:    opname,
+	})
+}
+
+// SubmitStockOpname godoc
+// @Summary      Submit a stock opname
+// @Description  Close counting and send the variances for approval
+// @Tags         stock-opname
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Stock Opname ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      409  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /stock-opname/{id}/submit [post]
+func (h *StockOpnameHandler) SubmitStockOpname(w http.ResponseWriter, r *http.Request) {
+	id, err := stockOpnameIDFromPath(r.URL.Path, "/submit")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Stock Opname ID",
+		})
+		return
+	}
+
+	opname, err := h.service.Submit(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Stock opname not found",
+		})
+		return
+	}
+
+	if err == repositories.ErrOpnameNotOpen || err == repositories.ErrOpnameNotCounted {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to submit stock opname: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Stock opname submitted for approval",
+		Data:    opname,
+	})
+}
+
+// ApproveStockOpname godoc
+// @Summary      Approve a stock opname
+// @Description  Apply every variance to product stock as a stock opname movement in the stock ledger. Variances are added to the current stock, so sales made after counting are kept.
+// @Tags         stock-opname
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                         true   "Stock Opname ID"
+// @Param        review  body      models.ReviewOpnameRequest  false  "Review Note"
+// @Success      200     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      404     {object}  utils.Response
+// @Failure      409     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /stock-opname/{id}/approve [post]
+func (h *StockOpnameHandler) ApproveStockOpname(w http.ResponseWriter, r *http.Request) {
+	id, err := stockOpnameIDFromPath(r.URL.Path, "/approve")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Stock Opname ID",
+		})
+		return
+	}
+
+	var req models.ReviewOpnameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	opname, err := h.service.Approve(id, req)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Stock opname not found",
+		})
+		return
+	}
+
+	if err == repositories.ErrOpnameNotSubmitted {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to approve stock opname: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Stock opname approved and stock adjusted",
+		Data:    opname,
+	})
+}
+
+// RejectStockOpname godoc
+// @Summary      Reject a stock opname
+// @Description  Close a submitted count without changing stock
+// @Tags         stock-opname
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                         true   "Stock Opname ID"
+// @Param        review  body      models.ReviewOpnameRequest  false  "Review Note"
+// @Success      200     {object}  utils.Response
+// @Failure      400     {object}  utils.Response
+// @Failure      404     {object}  utils.Response
+// @Failure      409     {object}  utils.Response
+// @Failure      500     {object}  utils.Response
+// @Router       /stock-opname/{id}/reject [post]
+func (h *StockOpnameHandler) RejectStockOpname(w http.ResponseWriter, r *http.Request) {
+	id, err := stockOpnameIDFromPath(r.URL.Path, "/reject")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Stock Opname ID",
+		})
+		return
+	}
+
+	var req models.ReviewOpnameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	opname, err := h.service.Reject(id, req)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Stock opname not found",
+		})
+		return
+	}
+
+	if err == repositories.ErrOpnameNotSubmitted {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to reject stock opname: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Stock opname rejected",
+		Data:    opname,
+	})
+}
@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type StoreHandler struct {
+	service *services.StoreService
+}
+
+func NewStoreHandler(service *services.StoreService) *StoreHandler {
+	return &StoreHandler{service: service}
+}
+
+// storeIDFromPath parses the ID out of /api/stores/{id}[suffix]
+func storeIDFromPath(path, suffix string) (int, error) {
+	idStr := strings.TrimPrefix(path, "/api/stores/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	return strconv.Atoi(idStr)
+}
+
+// writeStoreError answers a failed store request with the status of its
+// cause; notFound is the message of a missing store or price
+func writeStoreError(w http.ResponseWriter, err error, action, notFound string) {
+	status := http.StatusInternalServerError
+	message := "Failed to " + action + ": " + err.Error()
+	switch {
+	case err == services.ErrMultiStoreOff:
+		status, message = http.StatusServiceUnavailable, err.Error()
+	case err == services.ErrStoreNameRequired || err == services.ErrInvalidStorePrice:
+		status, message = http.StatusBadRequest, err.Error()
+	case errors.Is(err, repositories.ErrStoreNotFound) || errors.Is(err, repositories.ErrProductNotFound):
+		status, message = http.StatusNotFound, err.Error()
+	case err == sql.ErrNoRows:
+		status, message = http.StatusNotFound, notFound
+	}
+	utils.WriteJSON(w, status, utils.Response{
+		Status:  "failed",
+		Message: message,
+	})
+}
+
+// GetStores godoc
+// @Summary      Get stores
+// @Description  Get the stores of a multi-store install. Needs MULTI_STORE (503 otherwise).
+// @Tags         stores
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      503  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /stores [get]
+func (h *StoreHandler) GetStores(w http.ResponseWriter, r *http.Request) {
+	stores, err := h.service.GetAll()
+	if err != nil {
+		writeStoreError(w, err, "fetch stores", "")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Stores retrieved successfully",
+		Data:    stores,
+	})
+}
+
+// CreateStore godoc
+// @Summary      Create a store
+// @Description  Add a store; it sells the catalog at the products' own prices until it overrides them. Needs MULTI_STORE (503 otherwise).
+// @Tags         stores
+// @Accept       json
+// @Produce      json
+// @Param        store  body      models.Store  true  "Store"
+// @Success      201    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      503    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /stores [post]
+func (h *StoreHandler) CreateStore(w http.ResponseWriter, r *http.Request) {
+	var store models.Store
+	if err := json.NewDecoder(r.Body).Decode(&store); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	store, err := h.service.Create(store)
+	if err != nil {
+		writeStoreError(w, err, "create store", "")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Store created successfully",
+		Data:    store,
+	})
+}
+
+// UpdateStore godoc
+// @Summary      Update a store
+// @Description  Update a store's name and address. Needs MULTI_STORE (503 otherwise).
+// @Tags         stores
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int           true  "Store ID"
+// @Param        store  body      models.Store  true  "Store"
+// @Success      200    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      404    {object}  utils.Response
+// @Failure      503    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /stores/{id} [put]
+func (h *StoreHandler) UpdateStore(w http.ResponseWriter, r *http.Request) {
+	id, err := storeIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Store ID",
+		})
+		return
+	}
+
+	var store models.Store
+	if err := json.NewDecoder(r.Body).Decode(&store); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+	store.ID = id
+
+	store, err = h.service.Update(store)
+	if err != nil {
+		writeStoreError(w, err, "update store", "Store not found")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Store updated successfully",
+		Data:    store,
+	})
+}
+
+// DeleteStore godoc
+// @Summary      Delete a store
+// @Description  Soft delete a store; checkouts at it are refused from then on. Needs MULTI_STORE (503 otherwise).
+// @Tags         stores
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Store ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      503  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /stores/{id} [delete]
+func (h *StoreHandler) DeleteStore(w http.ResponseWriter, r *http.Request) {
+	id, err := storeIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Store ID",
+		})
+		return
+	}
+
+	if err := h.service.Delete(id); err != nil {
+		writeStoreError(w, err, "delete store", "Store not found")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Store deleted successfully",
+	})
+}
+
+// GetStorePrices godoc
+// @Summary      Get store prices
+// @Description  Get the prices a store sells products at instead of their own, next to the product price. Needs MULTI_STORE (503 otherwise).
+// @Tags         stores
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Store ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      503  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /stores/{id}/prices [get]
+func (h *StoreHandler) GetStorePrices(w http.ResponseWriter, r *http.Request) {
+	id, err := storeIDFromPath(r.URL.Path, "/prices")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Store ID",
+		})
+		return
+	}
+
+	prices, err := h.service.GetPrices(id)
+	if err != nil {
+		writeStoreError(w, err, "fetch store prices", "")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Store prices retrieved successfully",
+		Data:    prices,
+	})
+}
+
+// SetStorePrice godoc
+// @Summary      Set a store price
+// @Description  Set the price a store sells a product at instead of its own. Checkouts at the store start from it: pricing rules apply to it, and a customer's contract price still replaces it. Needs MULTI_STORE (503 otherwise).
+// @Tags         stores
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                true  "Store ID"
+// @Param        price  body      models.StorePrice  true  "Store price"
+// @Success      200    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      404    {object}  utils.Response
+// @Failure      503    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /stores/{id}/prices [put]
+func (h *StoreHandler) SetStorePrice(w http.ResponseWriter, r *http.Request) {
+	id, err := storeIDFromPath(r.URL.Path, "/prices")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Store ID",
+		})
+		return
+	}
+
+	var price models.StorePrice
+	if err := json.NewDecoder(r.Body).Decode(&price); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	price, err = h.service.SetPrice(id, price)
+	if err != nil {
+		writeStoreError(w, err, "set store price", "")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Store price set successfully",
+		Data:    price,
+	})
+}
+
+// DeleteStorePrice godoc
+// @Summary      Delete a store price
+// @Description  Drop the price a store overrides, so it sells the product at its own price again. Needs MULTI_STORE (503 otherwise).
+// @Tags         stores
+// @Accept       json
+// @Produce      json
+// @Param        id          path      int  true  "Store ID"
+// @Param        product_id  query     int  true  "Product ID"
+// @Success      200         {object}  utils.Response
+// @Failure      400         {object}  utils.Response
+// @Failure      404         {object}  utils.Response
+// @Failure      503         {object}  utils.Response
+// @Failure      500         {object}  utils.Response
+// @Router       /stores/{id}/prices [delete]
+func (h *StoreHandler) DeleteStorePrice(w http.ResponseWriter, r *http.Request) {
+	id, err := storeIDFromPath(r.URL.Path, "/prices")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Store ID",
+		})
+		return
+	}
+
+	productID, err := strconv.Atoi(r.URL.Query().Get("product_id"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid product_id",
+		})
+		return
+	}
+
+	if err := h.service.DeletePrice(id, productID); err != nil {
+		writeStoreError(w, err, "delete store price", "Store price not found")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Store price deleted successfully",
+	})
+}
@@ -0,0 +1,446 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type SupplierHandler struct {
+	Service *services.SupplierService
+}
+
+func NewSupplierHandler(service *services.SupplierService) *SupplierHandler {
+	return &SupplierHandler{Service: service}
+}
+
+// supplierIDFromPath parses the ID out of /api/supplier/{id}[suffix]
+func supplierIDFromPath(path, suffix string) (int, error) {
+	idStr := strings.TrimPrefix(path, "/api/supplier/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	return strconv.Atoi(idStr)
+}
+
+// GetSuppliers godoc
+// @Summary      Get all suppliers
+// @Description  Get a list of all active suppliers
+// @Tags         supplier
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /supplier [get]
+func (h *SupplierHandler) GetSuppliers(w http.ResponseWriter, r *http.Request) {
+	suppliers, err := h.Service.GetAll()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch suppliers: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Suppliers retrieved successfully",
+		Data:    suppliers,
+	})
+}
+
+// GetSupplierByID godoc
+// @Summary      Get a supplier by ID
+// @Description  Get a supplier by its ID
+// @Tags         supplier
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Supplier ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /supplier/{id} [get]
+func (h *SupplierHandler) GetSupplierByID(w http.ResponseWriter, r *http.Request) {
+	id, err := supplierIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Supplier ID",
+		})
+		return
+	}
+
+	supplier, err := h.Service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Supplier not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch supplier: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Supplier retrieved successfully",
+		Data:    supplier,
+	})
+}
+
+// CreateSupplier godoc
+// @Summary      Create a new supplier
+// @Description  Create a new supplier with the provided details
+// @Tags         supplier
+// @Accept       json
+// @Produce      json
+// @Param        supplier  body      models.Supplier  true  "Supplier Data"
+// @Success      201       {object}  utils.Response
+// @Failure      400       {object}  utils.Response
+// @Failure      500       {object}  utils.Response
+// @Router       /supplier [post]
+func (h *SupplierHandler) CreateSupplier(w http.ResponseWriter, r *http.Request) {
+	var supplierReq models.Supplier
+	err := json.NewDecoder(r.Body).Decode(&supplierReq)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	supplier, err := h.Service.Create(supplierReq)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to save supplier: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Supplier created successfully",
+		Data:    supplier,
+	})
+}
+
+// UpdateSupplier godoc
+// @Summary      Update a supplier
+// @Description  Update a supplier by ID
+// @Tags         supplier
+// @Accept       json
+// @Produce      json
+// @Param        id        path      int              true  "Supplier ID"
+// @Param        supplier  body      models.Supplier  true  "Supplier Data"
+// @Success      200       {object}  utils.Response
+// @Failure      400       {object}  utils.Response
+// @Failure      404       {object}  utils.Response
+// @Failure      500       {object}  utils.Response
+// @Router       /supplier/{id} [put]
+func (h *SupplierHandler) UpdateSupplier(w http.ResponseWriter, r *http.Request) {
+	id, err := supplierIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Supplier ID",
+		})
+		return
+	}
+
+	var updateReq models.Supplier
+	err = json.NewDecoder(r.Body).Decode(&updateReq)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	existingSupplier, err := h.Service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Supplier not found",
+		})
+		return
+	}
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch supplier: " + err.Error(),
+		})
+		return
+	}
+
+	if updateReq.Name != "" {
+		existingSupplier.Name = updateReq.Name
+	}
+	if updateReq.Phone != "" {
+		existingSupplier.Phone = updateReq.Phone
+	}
+	if updateReq.Email != "" {
+		existingSupplier.Email = updateReq.Email
+	}
+
+	updatedSupplier, err := h.Service.Update(existingSupplier)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to update supplier: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Supplier updated successfully",
+		Data:    updatedSupplier,
+	})
+}
+
+// DeleteSupplier godoc
+// @Summary      Delete a supplier
+// @Description  Soft delete a supplier by ID
+// @Tags         supplier
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Supplier ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /supplier/{id} [delete]
+func (h *SupplierHandler) DeleteSupplier(w http.ResponseWriter, r *http.Request) {
+	id, err := supplierIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Supplier ID",
+		})
+		return
+	}
+
+	err = h.Service.Delete(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Supplier not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to delete supplier: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Supplier deleted successfully",
+	})
+}
+
+// GetSupplierPrices godoc
+// @Summary      Get supplier catalog
+// @Description  Get the current price of every product offered by a supplier
+// @Tags         supplier
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Supplier ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /supplier/{id}/prices [get]
+func (h *SupplierHandler) GetSupplierPrices(w http.ResponseWriter, r *http.Request) {
+	id, err := supplierIDFromPath(r.URL.Path, "/prices")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Supplier ID",
+		})
+		return
+	}
+
+	prices, err := h.Service.GetPricesBySupplier(id)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch supplier prices: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Supplier prices retrieved successfully",
+		Data:    prices,
+	})
+}
+
+// SaveSupplierPrice godoc
+// @Summary      Set a supplier price
+// @Description  Set the current price of a product at a supplier; every change is kept in the price history
+// @Tags         supplier
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                   true  "Supplier ID"
+// @Param        price  body      models.SupplierPrice  true  "Supplier Price Data"
+// @Success      200    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      404    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /supplier/{id}/prices [put]
+func (h *SupplierHandler) SaveSupplierPrice(w http.ResponseWriter, r *http.Request) {
+	id, err := supplierIDFromPath(r.URL.Path, "/prices")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Supplier ID",
+		})
+		return
+	}
+
+	var priceReq models.SupplierPrice
+	if err := json.NewDecoder(r.Body).Decode(&priceReq); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	if _, err := h.Service.GetByID(id); err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Supplier not found",
+		})
+		return
+	}
+
+	priceReq.SupplierID = id
+	price, err := h.Service.SavePrice(priceReq)
+	if err == services.ErrInvalidAmount {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "price must be greater than zero",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to save supplier price: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Supplier price saved successfully",
+		Data:    price,
+	})
+}
+
+// GetPriceTrend godoc
+// @Summary      Get supplier price trend
+// @Description  Get the price history of a product at a supplier with first, latest, lowest and highest price
+// @Tags         supplier
+// @Accept       json
+// @Produce      json
+// @Param        id          path      int  true  "Supplier ID"
+// @Param        product_id  query     int  true  "Product ID"
+// @Success      200         {object}  utils.Response
+// @Failure      400         {object}  utils.Response
+// @Failure      500         {object}  utils.Response
+// @Router       /supplier/{id}/price-trend [get]
+func (h *SupplierHandler) GetPriceTrend(w http.ResponseWriter, r *http.Request) {
+	id, err := supplierIDFromPath(r.URL.Path, "/price-trend")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Supplier ID",
+		})
+		return
+	}
+
+	productID, err := strconv.Atoi(r.URL.Query().Get("product_id"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Product ID",
+		})
+		return
+	}
+
+	trend, err := h.Service.GetPriceTrend(id, productID)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch price trend: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Price trend retrieved successfully",
+		Data:    trend,
+	})
+}
+
+// GetProductSupplierPrices godoc
+// @Summary      Compare supplier prices for a product
+// @Description  Get the current price of a product at every supplier, cheapest first
+// @Tags         product
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Product ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /product/{id}/suppliers [get]
+func (h *SupplierHandler) GetProductSupplierPrices(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/product/")
+	idStr = strings.TrimSuffix(idStr, "/suppliers")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Product ID",
+		})
+		return
+	}
+
+	prices, err := h.Service.GetPricesByProduct(id)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch supplier prices: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Supplier prices retrieved successfully",
+		Data:    prices,
+	})
+}
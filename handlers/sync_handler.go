@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type SyncHandler struct {
+	service *services.TerminalSyncService
+}
+
+func NewSyncHandler(service *services.TerminalSyncService) *SyncHandler {
+	return &SyncHandler{service: service}
+}
+
+// GetChanges godoc
+// @Summary      Pull catalog changes
+// @Description  Get the products and categories changed since the cursor of the terminal's last pull, deleted ones with deleted_at set; leave out since for the whole active catalog. Send the returned cursor on the next pull. A product may come again on the next pull, apply it over the one held.
+// @Tags         sync
+// @Accept       json
+// @Produce      json
+// @Param        since  query     int  false  "Cursor returned by the last pull"
+// @Success      200    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /sync/changes [get]
+func (h *SyncHandler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	var since int64
+	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+		var err error
+		since, err = strconv.ParseInt(sinceStr, 10, 64)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: services.ErrInvalidSyncCursor.Error(),
+			})
+			return
+		}
+	}
+
+	changes, err := h.service.GetChanges(since)
+	if err == services.ErrInvalidSyncCursor {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch catalog changes: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Catalog changes retrieved successfully",
+		Data:    changes,
+	})
+}
+
+// PushTransactions godoc
+// @Summary      Push offline sales
+// @Description  Record up to 500 sales a terminal rang up offline, as they were priced there, and get what became of each in order: created; duplicate when it was pushed before, so a batch can be pushed again safely; conflict when its receipt number belongs to a different sale; deferred while its accounting period is closed, push it again once reopened; or rejected with the reason. Stock is taken out as of now and never goes below zero. A sale of a past day is in that day's reports right away, the day is rolled up again with it.
+// @Tags         sync
+// @Accept       json
+// @Produce      json
+// @Param        batch  body      models.SyncPushRequest  true  "Offline sales"
+// @Success      200    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /sync/transactions [post]
+func (h *SyncHandler) PushTransactions(w http.ResponseWriter, r *http.Request) {
+	var req models.SyncPushRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	results, err := h.service.Push(req)
+	if err == services.ErrSyncBatchSize {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to record offline sales: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Offline sales processed",
+		Data:    results,
+	})
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type TaxInvoiceHandler struct {
+	service *services.TaxInvoiceService
+}
+
+func NewTaxInvoiceHandler(service *services.TaxInvoiceService) *TaxInvoiceHandler {
+	return &TaxInvoiceHandler{service: service}
+}
+
+// taxInvoiceIDFromPath parses the ID out of /api/tax-invoices/{id}[suffix]
+func taxInvoiceIDFromPath(path, suffix string) (int, error) {
+	idStr := strings.TrimPrefix(path, "/api/tax-invoices/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	return strconv.Atoi(idStr)
+}
+
+// GetTaxInvoices godoc
+// @Summary      Get tax invoices
+// @Description  Get the tax invoices issued, in number order, optionally within a date range
+// @Tags         tax-invoices
+// @Accept       json
+// @Produce      json
+// @Param        date_from  query     string  false  "From date (YYYY-MM-DD)"
+// @Param        date_to    query     string  false  "To date (YYYY-MM-DD)"
+// @Success      200        {object}  utils.Response
+// @Failure      400        {object}  utils.Response
+// @Failure      500        {object}  utils.Response
+// @Router       /tax-invoices [get]
+func (h *TaxInvoiceHandler) GetTaxInvoices(w http.ResponseWriter, r *http.Request) {
+	dateFrom := r.URL.Query().Get("date_from")
+	dateTo := r.URL.Query().Get("date_to")
+	for _, date := range []string{dateFrom, dateTo} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid date format, use YYYY-MM-DD",
+			})
+			return
+		}
+	}
+
+	invoices, err := h.service.GetAll(dateFrom, dateTo)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch tax invoices: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Tax invoices retrieved successfully",
+		Data:    invoices,
+	})
+}
+
+// IssueTaxInvoice godoc
+// @Summary      Issue a tax invoice
+// @Description  Issue the faktur pajak of a sale to a business buyer, numbered next in the tax_invoice sequence. Prices include PPN; the tax base and PPN are taken out of the total at TAX_RATE and kept as issued. A sale gets one tax invoice.
+// @Tags         tax-invoices
+// @Accept       json
+// @Produce      json
+// @Param        invoice  body      models.TaxInvoiceRequest  true  "Transaction and buyer"
+// @Success      201      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      409      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /tax-invoices [post]
+func (h *TaxInvoiceHandler) IssueTaxInvoice(w http.ResponseWriter, r *http.Request) {
+	var req models.TaxInvoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	invoice, err := h.service.Issue(req)
+	if err == services.ErrInvalidNPWP || err == services.ErrBuyerRequired || err == services.ErrTaxNotConfigured ||
+		err == repositories.ErrTransactionNotFound {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == repositories.ErrTaxInvoiceExists {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to issue tax invoice: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Tax invoice issued successfully",
+		Data:    invoice,
+	})
+}
+
+// GetTaxInvoiceByID godoc
+// @Summary      Get a tax invoice
+// @Description  Get a tax invoice by ID
+// @Tags         tax-invoices
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Tax invoice ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /tax-invoices/{id} [get]
+func (h *TaxInvoiceHandler) GetTaxInvoiceByID(w http.ResponseWriter, r *http.Request) {
+	id, err := taxInvoiceIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Tax Invoice ID",
+		})
+		return
+	}
+
+	invoice, err := h.service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Tax invoice not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch tax invoice: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Tax invoice retrieved successfully",
+		Data:    invoice,
+	})
+}
+
+// GetTaxInvoicePDF godoc
+// @Summary      Download a tax invoice
+// @Description  Render a tax invoice as a printable PDF with the seller, the buyer, the lines without PPN, the tax base and the PPN
+// @Tags         tax-invoices
+// @Produce      application/pdf
+// @Param        id   path      int  true  "Tax invoice ID"
+// @Success      200  {file}    file
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /tax-invoices/{id}/pdf [get]
+func (h *TaxInvoiceHandler) GetTaxInvoicePDF(w http.ResponseWriter, r *http.Request) {
+	id, err := taxInvoiceIDFromPath(r.URL.Path, "/pdf")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Tax Invoice ID",
+		})
+		return
+	}
+
+	invoice, body, err := h.service.RenderPDF(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Tax invoice not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to render tax invoice: " + err.Error(),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="faktur-pajak-`+strings.NewReplacer("/", "-", ".", "-").Replace(invoice.Number)+`.pdf"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
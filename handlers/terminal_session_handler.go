@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type TerminalSessionHandler struct {
+	service *services.TerminalSessionService
+}
+
+func NewTerminalSessionHandler(service *services.TerminalSessionService) *TerminalSessionHandler {
+	return &TerminalSessionHandler{service: service}
+}
+
+// writeTerminalSessionError answers a failed terminal session request with
+// the status of its cause
+func writeTerminalSessionError(w http.ResponseWriter, err error, action string) {
+	status := http.StatusInternalServerError
+	message := "Failed to " + action + ": " + err.Error()
+	var locked *services.PINLockedError
+	switch {
+	case errors.As(err, &locked):
+		status, message = http.StatusLocked, err.Error()
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(locked.Until).Seconds()))))
+	case err == services.ErrTerminalRequired || err == services.ErrInvalidPIN || err == services.ErrTerminalSessionRequired:
+		status, message = http.StatusBadRequest, err.Error()
+	case err == services.ErrWrongPIN || err == services.ErrWrongPassword || err == services.ErrNoTerminalSession:
+		status, message = http.StatusUnauthorized, err.Error()
+	case err == services.ErrEmailNotVerified:
+		status, message = http.StatusForbidden, err.Error()
+	case err == sql.ErrNoRows:
+		status, message = http.StatusNotFound, "Admin not found"
+	}
+	utils.WriteJSON(w, status, utils.Response{
+		Status:  "failed",
+		Message: message,
+	})
+}
+
+// StartTerminalSession godoc
+// @Summary      Switch a terminal's cashier
+// @Description  Sign a cashier in on a terminal with the 4 to 6 digit PIN of their account, ending the session the terminal was in. The session lasts TERMINAL_SESSION_TTL; its token goes with the terminal's requests in X-Terminal-Session. PIN_MAX_ATTEMPTS wrong PINs in a row lock the account out of every terminal for PIN_LOCKOUT (423, with Retry-After).
+// @Tags         terminal-sessions
+// @Accept       json
+// @Produce      json
+// @Param        session  body      models.StartTerminalSessionRequest  true  "Terminal, username and PIN"
+// @Success      201      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      401      {object}  utils.Response
+// @Failure      423      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /terminal-sessions [post]
+func (h *TerminalSessionHandler) StartTerminalSession(w http.ResponseWriter, r *http.Request) {
+	var req models.StartTerminalSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	ts, err := h.service.Start(r.Context(), req)
+	if err != nil {
+		writeTerminalSessionError(w, err, "start terminal session")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Terminal session started successfully",
+		Data:    ts,
+	})
+}
+
+// SignIn godoc
+// @Summary      Sign in with a password
+// @Description  Sign an admin in on a terminal with the password of their account, set at registration or through a reset link, ending the session the terminal was in. The session is the one a PIN starts, its token goes in X-Terminal-Session. An account with an email signs in once the address is confirmed (403). Wrong passwords count towards the same lockout as wrong PINs (423, with Retry-After). Rate limited per client (AUTH_RATE_LIMIT).
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        credentials  body      models.PasswordSignInRequest  true  "Terminal, username and password"
+// @Success      201          {object}  utils.Response
+// @Failure      400          {object}  utils.Response
+// @Failure      401          {object}  utils.Response
+// @Failure      403          {object}  utils.Response
+// @Failure      423          {object}  utils.Response
+// @Failure      429          {object}  utils.Response
+// @Failure      500          {object}  utils.Response
+// @Router       /auth/login [post]
+func (h *TerminalSessionHandler) SignIn(w http.ResponseWriter, r *http.Request) {
+	var req models.PasswordSignInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	ts, err := h.service.SignIn(r.Context(), req)
+	if err != nil {
+		writeTerminalSessionError(w, err, "sign in")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Signed in successfully",
+		Data:    ts,
+	})
+}
+
+// GetCurrentTerminalSession godoc
+// @Summary      Get the current terminal session
+// @Description  Get the cashier signed in with the session in X-Terminal-Session; 401 once it expired or another cashier switched in
+// @Tags         terminal-sessions
+// @Accept       json
+// @Produce      json
+// @Param        X-Terminal-Session  header    string  true  "Terminal session token"
+// @Success      200                 {object}  utils.Response
+// @Failure      400                 {object}  utils.Response
+// @Failure      401                 {object}  utils.Response
+// @Failure      500                 {object}  utils.Response
+// @Router       /terminal-sessions/current [get]
+func (h *TerminalSessionHandler) GetCurrentTerminalSession(w http.ResponseWriter, r *http.Request) {
+	ts, err := h.service.Current(r.Context(), strings.TrimSpace(r.Header.Get("X-Terminal-Session")))
+	if err != nil {
+		writeTerminalSessionError(w, err, "fetch terminal session")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Terminal session retrieved successfully",
+		Data:    ts,
+	})
+}
+
+// EndTerminalSession godoc
+// @Summary      End the current terminal session
+// @Description  Sign the cashier of the session in X-Terminal-Session out of the terminal, locking it until the next PIN
+// @Tags         terminal-sessions
+// @Accept       json
+// @Produce      json
+// @Param        X-Terminal-Session  header    string  true  "Terminal session token"
+// @Success      200                 {object}  utils.Response
+// @Failure      400                 {object}  utils.Response
+// @Failure      401                 {object}  utils.Response
+// @Failure      500                 {object}  utils.Response
+// @Router       /terminal-sessions/current [delete]
+func (h *TerminalSessionHandler) EndTerminalSession(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.End(r.Context(), strings.TrimSpace(r.Header.Get("X-Terminal-Session"))); err != nil {
+		writeTerminalSessionError(w, err, "end terminal session")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Terminal session ended successfully",
+	})
+}
+
+// SetPIN godoc
+// @Summary      Set an account's PIN
+// @Description  Set the 4 to 6 digit PIN an account switches into terminals with, replacing the one it had
+// @Tags         terminal-sessions
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int                   true  "Admin ID"
+// @Param        pin  body      models.SetPINRequest  true  "PIN"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /admin/users/{id}/pin [put]
+func (h *TerminalSessionHandler) SetPIN(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/admin/users/")
+	id, err := strconv.Atoi(strings.TrimSuffix(idStr, "/pin"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Admin ID",
+		})
+		return
+	}
+
+	var req models.SetPINRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	if err := h.service.SetPIN(id, req.PIN); err != nil {
+		writeTerminalSessionError(w, err, "set PIN")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "PIN set successfully",
+	})
+}
@@ -2,13 +2,98 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 
+	"kasir-api/metrics"
 	"kasir-api/models"
+	"kasir-api/repositories"
 	"kasir-api/services"
 	"kasir-api/utils"
 )
 
+// business outcomes of checkout, exposed on /metrics next to the HTTP metrics
+var (
+	checkoutTotal = metrics.NewCounter("kasir_checkout_total", "Checkouts by outcome and error code", "outcome", "code")
+	basketLines   = metrics.NewSummary("kasir_checkout_basket_lines", "Lines per successful checkout basket")
+	basketUnits   = metrics.NewSummary("kasir_checkout_basket_units", "Units sold per successful checkout basket")
+	stockOuts     = metrics.NewCounter("kasir_checkout_stockout_total", "Checkout lines rejected because the product was out of stock", "product_id")
+)
+
+// checkoutErrorCode classifies a checkout failure for the checkout metrics and
+// the error code of the response
+func checkoutErrorCode(err error) string {
+	var stockErr *repositories.InsufficientStockError
+	var lockedErr *services.PINLockedError
+	var guardrailErr *repositories.PriceGuardrailError
+	switch {
+	case errors.As(err, &stockErr):
+		return "insufficient_stock"
+	case errors.Is(err, repositories.ErrProductNotFound):
+		return "product_not_found"
+	case errors.Is(err, repositories.ErrCustomerNotFound):
+		return "customer_not_found"
+	case errors.Is(err, repositories.ErrStoreNotFound):
+		return "store_not_found"
+	case errors.Is(err, services.ErrMultiStoreOff):
+		return "multi_store_off"
+	case errors.Is(err, services.ErrUnknownCurrency), errors.Is(err, services.ErrCurrencyNotAccepted):
+		return "currency_not_accepted"
+	case errors.Is(err, services.ErrInsufficientPayment):
+		return "insufficient_payment"
+	case errors.Is(err, repositories.ErrVoucherNotFound), errors.Is(err, repositories.ErrVoucherExpired), errors.Is(err, repositories.ErrVoucherUsedUp):
+		return "voucher_invalid"
+	case errors.Is(err, services.ErrInvalidTransactionStatus), errors.Is(err, services.ErrPaymentNotDue):
+		return "invalid_status"
+	case errors.Is(err, services.ErrInvalidOpenItem):
+		return "invalid_open_item"
+	case errors.Is(err, services.ErrOpenItemNotAllowed), errors.Is(err, services.ErrOpenItemOverLimit):
+		return "open_item_not_allowed"
+	case errors.Is(err, services.ErrInvalidOverride):
+		return "invalid_override"
+	case errors.Is(err, services.ErrOverrideApproval), errors.Is(err, services.ErrWrongPIN), errors.Is(err, services.ErrInvalidPIN), errors.Is(err, services.ErrNotPermitted):
+		return "override_not_approved"
+	case errors.As(err, &lockedErr):
+		return "approver_locked"
+	case errors.As(err, &guardrailErr):
+		return "price_guardrail"
+	default:
+		return "internal"
+	}
+}
+
+// checkoutErrorStatus is the HTTP status of a checkout failure classified as code
+func checkoutErrorStatus(code string) int {
+	switch code {
+	case "customer_not_found", "store_not_found", "currency_not_accepted", "insufficient_payment", "voucher_invalid", "invalid_status", "invalid_open_item", "invalid_override":
+		return http.StatusBadRequest
+	case "open_item_not_allowed", "override_not_approved":
+		return http.StatusForbidden
+	case "approver_locked":
+		return http.StatusLocked
+	case "price_guardrail":
+		return http.StatusUnprocessableEntity
+	case "multi_store_off":
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// priceViolations returns the lines of a checkout refused by the price
+// guardrails as field errors, and as refused, nil for other failures
+func priceViolations(err error) ([]utils.FieldError, interface{}) {
+	var guardrailErr *repositories.PriceGuardrailError
+	if !errors.As(err, &guardrailErr) {
+		return nil, nil
+	}
+	errs := make([]utils.FieldError, len(guardrailErr.Violations))
+	for i, v := range guardrailErr.Violations {
+		errs[i] = utils.FieldError{Field: v.Field, Message: v.Message}
+	}
+	return errs, guardrailErr.Violations
+}
+
 type TransactionHandler struct {
 	service *services.TransactionService
 }
@@ -23,41 +108,67 @@ func (h *TransactionHandler) HandleCheckout(w http.ResponseWriter, r *http.Reque
 	case http.MethodPost:
 		h.Checkout(w, r)
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+			Status:  "failed",
+			Message: "Method not allowed",
+		})
 	}
 }
 
 // Checkout godoc
 // @Summary      Process checkout
-// @Description  Create a new transaction by processing checkout items
+// @Description  Create a new transaction by processing checkout items. A line without product_id is an open item, e.g. a delivery fee, sold under its description at its price; open items must be allowed by OPEN_ITEMS and priced at most OPEN_ITEM_MAX_PRICE when set (403 otherwise). Attaching a customer prices the products of their valid price contracts at the contract price instead of the product price and pricing rules. With MULTI_STORE on, a store_id sells at the prices that store overrides, which the pricing rules apply to and contracts replace; an unknown store is refused (400), and a store_id with MULTI_STORE off too (503). The cash tendered may be given as payment, in the store currency or an accepted foreign one converted at the current rate; it must cover the total (400 otherwise) and the change, in the store currency, is recorded on the transaction. A voucher_code pays first, as much as the voucher's balance allows, and the payment covers the rest; an unknown, expired or used up voucher is refused (400). A product line may carry an override, a unit price replacing its pricing rule or contract price and an extra discount off the line, with a reason; the checkout then needs the approval of the username and PIN of an account holding transaction.override_price (403 otherwise, 423 once wrong PINs locked it out). The override, the line's original subtotal and the approver are recorded on the line and in the activity log. Lines discounted, by pricing rules, contracts or overrides, by more than MAX_DISCOUNT_PERCENT of their price, or sold under their cost with BLOCK_BELOW_COST on, are refused (422, each one listed in errors and data) unless the approval is given. The sale is completed unless status starts it as a draft or pending_payment, both paid later and so without payment or voucher, or as paid but not yet handed over.
 // @Tags         transaction
 // @Accept       json
 // @Produce      json
 // @Param        checkout  body      models.CheckoutRequest  true  "Checkout Data"
 // @Success      200       {object}  utils.Response
 // @Failure      400       {object}  utils.Response
+// @Failure      403       {object}  utils.Response
+// @Failure      422       {object}  utils.Response
+// @Failure      423       {object}  utils.Response
+// @Failure      503       {object}  utils.Response
 // @Failure      500       {object}  utils.Response
 // @Router       /checkout [post]
 func (h *TransactionHandler) Checkout(w http.ResponseWriter, r *http.Request) {
 	var req models.CheckoutRequest
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
+		checkoutTotal.Inc("failure", "invalid_request")
 		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
-			Status:  "failed",
-			Message: "Invalid request body",
+			Status:    "failed",
+			Message:   "Invalid request body",
+			ErrorCode: "invalid_request",
 		})
 		return
 	}
 
-	transaction, err := h.service.Checkout(req.Items, false)
+	transaction, err := h.service.Checkout(r.Context(), req, false)
 	if err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
-			Status:  "failed",
-			Message: "Failed to process checkout: " + err.Error(),
-		})
+		code := checkoutErrorCode(err)
+		checkoutTotal.Inc("failure", code)
+		var stockErr *repositories.InsufficientStockError
+		if errors.As(err, &stockErr) {
+			stockOuts.Inc(strconv.Itoa(stockErr.ProductID))
+		}
+		res := utils.Response{
+			Status:    "failed",
+			Message:   "Failed to process checkout: " + err.Error(),
+			ErrorCode: code,
+		}
+		res.Errors, res.Data = priceViolations(err)
+		utils.WriteJSON(w, checkoutErrorStatus(code), res)
 		return
 	}
 
+	units := 0
+	for _, d := range transaction.Details {
+		units += d.Quantity
+	}
+	checkoutTotal.Inc("success", "")
+	basketLines.Observe(float64(len(transaction.Details)))
+	basketUnits.Observe(float64(units))
+
 	utils.WriteJSON(w, http.StatusOK, utils.Response{
 		Status:  "success",
 		Message: "Transaction created successfully",
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type TransactionStatusHandler struct {
+	service     *services.TransactionStatusService
+	permissions *services.PermissionService
+}
+
+func NewTransactionStatusHandler(service *services.TransactionStatusService, permissions *services.PermissionService) *TransactionStatusHandler {
+	return &TransactionStatusHandler{service: service, permissions: permissions}
+}
+
+// UpdateTransactionStatus godoc
+// @Summary      Update a transaction's status
+// @Description  Move a sale on: draft to pending_payment, paid or voided; pending_payment to paid or voided; paid to completed or refunded; completed to refunded. A draft moved to pending_payment or paid is billed: its total is rounded for cash then, per CASH_ROUNDING. Voiding or refunding needs a reason and the transaction.refund permission (403), puts the stock back and takes the sale out of the reports. A sale made in a closed accounting period can't change status (409), as that would change the month's revenue. A refund is published as refund.issued.
+// @Tags         transaction
+// @Accept       json
+// @Produce      json
+// @Param        id                  path      int                              true   "Transaction ID"
+// @Param        X-Terminal-Session  header    string                           false  "Terminal session token"
+// @Param        status              body      models.TransactionStatusRequest  true   "Status"
+// @Success      200                 {object}  utils.Response
+// @Failure      400                 {object}  utils.Response
+// @Failure      403                 {object}  utils.Response
+// @Failure      404                 {object}  utils.Response
+// @Failure      409                 {object}  utils.Response
+// @Failure      500                 {object}  utils.Response
+// @Router       /transactions/{id}/status [put]
+func (h *TransactionStatusHandler) UpdateTransactionStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := transactionIDFromPath(r.URL.Path, "/status")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Transaction ID",
+		})
+		return
+	}
+
+	var req models.TransactionStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	if req.Status == repositories.TransactionVoided || req.Status == repositories.TransactionRefunded {
+		err := h.permissions.Require(r.Context(), strings.TrimSpace(r.Header.Get("X-Terminal-Session")), services.PermTransactionRefund)
+		if errors.Is(err, services.ErrNotPermitted) {
+			utils.WriteJSON(w, http.StatusForbidden, utils.Response{
+				Status:  "failed",
+				Message: err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+				Status:  "failed",
+				Message: "Failed to check permissions: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	event, err := h.service.UpdateStatus(id, req)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Transaction not found",
+		})
+		return
+	}
+
+	if err == services.ErrStatusReasonRequired {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == services.ErrTransactionTransition || err == repositories.ErrTransactionChanged || errors.Is(err, repositories.ErrPeriodClosed) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to update transaction status: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Transaction status updated successfully",
+		Data:    event,
+	})
+}
+
+// GetTransactionStatusHistory godoc
+// @Summary      Get a transaction's status history
+// @Description  Get every status a sale went through, oldest first, with the reasons given, voided and refunded sales included
+// @Tags         transaction
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Transaction ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /transactions/{id}/status-history [get]
+func (h *TransactionStatusHandler) GetTransactionStatusHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := transactionIDFromPath(r.URL.Path, "/status-history")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Transaction ID",
+		})
+		return
+	}
+
+	events, err := h.service.GetHistory(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Transaction not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch transaction status history: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Transaction status history retrieved successfully",
+		Data:    events,
+	})
+}
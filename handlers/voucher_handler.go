@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type VoucherHandler struct {
+	service *services.VoucherService
+}
+
+func NewVoucherHandler(service *services.VoucherService) *VoucherHandler {
+	return &VoucherHandler{service: service}
+}
+
+// GetVouchers godoc
+// @Summary      Get vouchers
+// @Description  Get every gift card and voucher issued with its balance, newest first
+// @Tags         vouchers
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /vouchers [get]
+func (h *VoucherHandler) GetVouchers(w http.ResponseWriter, r *http.Request) {
+	vouchers, err := h.service.GetAll()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch vouchers: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Vouchers retrieved successfully",
+		Data:    vouchers,
+	})
+}
+
+// IssueVoucher godoc
+// @Summary      Issue a voucher
+// @Description  Issue a gift card or voucher worth amount in the store currency, spent at checkout with voucher_code until its balance runs out. A code such as GV-7K3M-Q9XP is generated unless one is given; without expires_on it never expires.
+// @Tags         vouchers
+// @Accept       json
+// @Produce      json
+// @Param        voucher  body      models.VoucherRequest  true  "Voucher"
+// @Success      201      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      409      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /vouchers [post]
+func (h *VoucherHandler) IssueVoucher(w http.ResponseWriter, r *http.Request) {
+	var req models.VoucherRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	voucher, err := h.service.Issue(req)
+	if err == services.ErrInvalidVoucherAmount || err == services.ErrInvalidVoucherCode || err == services.ErrInvalidVoucherExpiry {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == repositories.ErrDuplicateVoucherCode {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to issue voucher: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Voucher issued successfully",
+		Data:    voucher,
+	})
+}
+
+// GetVoucher godoc
+// @Summary      Get a voucher by code
+// @Description  Get the balance and expiry of a voucher, with what each sale took from it, e.g. to tell a customer before checkout
+// @Tags         vouchers
+// @Accept       json
+// @Produce      json
+// @Param        code  path      string  true  "Voucher code"
+// @Success      200   {object}  utils.Response
+// @Failure      404   {object}  utils.Response
+// @Failure      500   {object}  utils.Response
+// @Router       /vouchers/{code} [get]
+func (h *VoucherHandler) GetVoucher(w http.ResponseWriter, r *http.Request) {
+	code := strings.TrimPrefix(r.URL.Path, "/api/vouchers/")
+	voucher, err := h.service.GetByCode(code)
+	if err == repositories.ErrVoucherNotFound {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Voucher not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch voucher: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Voucher retrieved successfully",
+		Data:    voucher,
+	})
+}
@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type WebhookHandler struct {
+	service *services.WebhookService
+}
+
+func NewWebhookHandler(service *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// webhookIDFromPath parses the ID out of /api/webhook/{id}[suffix]
+func webhookIDFromPath(path, suffix string) (int, error) {
+	idStr := strings.TrimPrefix(path, "/api/webhook/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	return strconv.Atoi(idStr)
+}
+
+// GetWebhooks godoc
+// @Summary      Get all webhooks
+// @Description  Get a list of all registered webhooks
+// @Tags         webhook
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /webhook [get]
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.service.GetAll()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch webhooks: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Webhooks retrieved successfully",
+		Data:    webhooks,
+	})
+}
+
+// CreateWebhook godoc
+// @Summary      Register a webhook
+// @Description  Register a URL to receive events (transaction.created, product.low_stock, refund.issued, payment_link.paid). Payloads are signed with HMAC-SHA256 of the body in the X-Webhook-Signature header; a secret is generated when none is given.
+// @Tags         webhook
+// @Accept       json
+// @Produce      json
+// @Param        webhook  body      models.Webhook  true  "Webhook Data"
+// @Success      201      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /webhook [post]
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var wh models.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&wh); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	createdWebhook, err := h.service.Create(wh)
+	if err == services.ErrInvalidWebhookURL || err == services.ErrInvalidEvent {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to create webhook: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Webhook created successfully",
+		Data:    createdWebhook,
+	})
+}
+
+// GetWebhookByID godoc
+// @Summary      Get a webhook by ID
+// @Description  Get details of a specific webhook
+// @Tags         webhook
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Webhook ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /webhook/{id} [get]
+func (h *WebhookHandler) GetWebhookByID(w http.ResponseWriter, r *http.Request) {
+	id, err := webhookIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Webhook ID",
+		})
+		return
+	}
+
+	wh, err := h.service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Webhook not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch webhook: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Webhook retrieved successfully",
+		Data:    wh,
+	})
+}
+
+// UpdateWebhook godoc
+// @Summary      Update a webhook
+// @Description  Update the URL, subscribed events or active flag of a webhook
+// @Tags         webhook
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                          true  "Webhook ID"
+// @Param        webhook  body      models.UpdateWebhookRequest  true  "Webhook Data"
+// @Success      200      {object}  utils.Response
+// @Failure      400      {object}  utils.Response
+// @Failure      404      {object}  utils.Response
+// @Failure      500      {object}  utils.Response
+// @Router       /webhook/{id} [put]
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := webhookIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Webhook ID",
+		})
+		return
+	}
+
+	var updateReq models.UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&updateReq); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	existingWebhook, err := h.service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Webhook not found",
+		})
+		return
+	}
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch webhook: " + err.Error(),
+		})
+		return
+	}
+
+	if updateReq.URL != "" {
+		existingWebhook.URL = updateReq.URL
+	}
+	if len(updateReq.Events) > 0 {
+		existingWebhook.Events = updateReq.Events
+	}
+	if updateReq.Active != nil {
+		existingWebhook.Active = *updateReq.Active
+	}
+
+	updatedWebhook, err := h.service.Update(existingWebhook)
+	if err == services.ErrInvalidWebhookURL || err == services.ErrInvalidEvent {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to update webhook: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Webhook updated successfully",
+		Data:    updatedWebhook,
+	})
+}
+
+// DeleteWebhook godoc
+// @Summary      Delete a webhook
+// @Description  Soft delete a webhook by ID, its delivery log is kept
+// @Tags         webhook
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Webhook ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /webhook/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := webhookIDFromPath(r.URL.Path, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Webhook ID",
+		})
+		return
+	}
+
+	err = h.service.Delete(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Webhook not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to delete webhook: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Webhook deleted successfully",
+	})
+}
+
+// GetWebhookDeliveries godoc
+// @Summary      Get webhook delivery log
+// @Description  Get the most recent deliveries of a webhook with status, attempts and last response code
+// @Tags         webhook
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int  true   "Webhook ID"
+// @Param        limit  query     int  false  "Maximum number of deliveries (default 50)"
+// @Success      200    {object}  utils.Response
+// @Failure      400    {object}  utils.Response
+// @Failure      404    {object}  utils.Response
+// @Failure      500    {object}  utils.Response
+// @Router       /webhook/{id}/deliveries [get]
+func (h *WebhookHandler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := webhookIDFromPath(r.URL.Path, "/deliveries")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Webhook ID",
+		})
+		return
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid limit",
+			})
+			return
+		}
+		limit = l
+	}
+
+	deliveries, err := h.service.GetDeliveries(id, limit)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Webhook not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch webhook deliveries: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Webhook deliveries retrieved successfully",
+		Data:    deliveries,
+	})
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/utils"
+)
+
+type WriteOffHandler struct {
+	service *services.WriteOffService
+}
+
+func NewWriteOffHandler(service *services.WriteOffService) *WriteOffHandler {
+	return &WriteOffHandler{service: service}
+}
+
+// GetWriteOffs godoc
+// @Summary      Get write-offs
+// @Description  Get stock written off as damaged or expired and stock returned to suppliers, newest first
+// @Tags         write-offs
+// @Accept       json
+// @Produce      json
+// @Param        reason     query     string  false  "Filter by reason"  Enums(damaged, expired, supplier_return)
+// @Param        date_from  query     string  false  "From date (YYYY-MM-DD)"
+// @Param        date_to    query     string  false  "To date (YYYY-MM-DD)"
+// @Success      200        {object}  utils.Response
+// @Failure      400        {object}  utils.Response
+// @Failure      500        {object}  utils.Response
+// @Router       /write-offs [get]
+func (h *WriteOffHandler) GetWriteOffs(w http.ResponseWriter, r *http.Request) {
+	dateFrom := r.URL.Query().Get("date_from")
+	dateTo := r.URL.Query().Get("date_to")
+	for _, date := range []string{dateFrom, dateTo} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Invalid date format, use YYYY-MM-DD",
+			})
+			return
+		}
+	}
+
+	writeOffs, err := h.service.GetAll(r.URL.Query().Get("reason"), dateFrom, dateTo)
+	if err == services.ErrInvalidWriteOffReason {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch write-offs: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Write-offs retrieved successfully",
+		Data:    writeOffs,
+	})
+}
+
+// CreateWriteOff godoc
+// @Summary      Record a write-off
+// @Description  Take stock out of inventory as damaged, expired or returned to a supplier (supplier_id required). The quantity is logged in the stock ledger under the reason and valued at the current cost price.
+// @Tags         write-offs
+// @Accept       json
+// @Produce      json
+// @Param        write_off  body      models.WriteOffRequest  true  "Write-off Data"
+// @Success      201        {object}  utils.Response
+// @Failure      400        {object}  utils.Response
+// @Failure      404        {object}  utils.Response
+// @Failure      409        {object}  utils.Response
+// @Failure      500        {object}  utils.Response
+// @Router       /write-offs [post]
+func (h *WriteOffHandler) CreateWriteOff(w http.ResponseWriter, r *http.Request) {
+	var req models.WriteOffRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	writeOff, err := h.service.Create(req)
+	if err == services.ErrInvalidWriteOffReason || err == services.ErrInvalidAmount || err == services.ErrSupplierRequired {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err == repositories.ErrProductNotFound || err == repositories.ErrSupplierNotFound {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var stockErr *repositories.InsufficientStockError
+	if errors.As(err, &stockErr) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Response{
+			Status:    "failed",
+			Message:   err.Error(),
+			ErrorCode: "insufficient_stock",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to record write-off: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Response{
+		Status:  "success",
+		Message: "Write-off recorded successfully",
+		Data:    writeOff,
+	})
+}
+
+// GetWriteOffByID godoc
+// @Summary      Get a write-off
+// @Description  Get a write-off by ID
+// @Tags         write-offs
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Write-off ID"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /write-offs/{id} [get]
+func (h *WriteOffHandler) GetWriteOffByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/write-offs/"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+			Status:  "failed",
+			Message: "Invalid Write-off ID",
+		})
+		return
+	}
+
+	writeOff, err := h.service.GetByID(id)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+			Status:  "failed",
+			Message: "Write-off not found",
+		})
+		return
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+			Status:  "failed",
+			Message: "Failed to fetch write-off: " + err.Error(),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Response{
+		Status:  "success",
+		Message: "Write-off retrieved successfully",
+		Data:    writeOff,
+	})
+}
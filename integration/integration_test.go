@@ -0,0 +1,402 @@
+//go:build integration
+
+package integration
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"kasir-api/database"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/session"
+)
+
+// step is one request of a scenario. Path, Body and the Expect values may
+// refer to saved values as $name.
+type step struct {
+	Method string
+	Path   string
+	// Body is sent as JSON; File, when set, is sent as a multipart form instead
+	Body string
+	File *formFile
+	// Header is sent along, e.g. "If-None-Match": "$etag"
+	Header map[string]string
+	// Signed sends the HMAC of Body with PAYMENT_CALLBACK_SECRET in
+	// X-Callback-Signature, as the payment gateway signs its callbacks
+	Signed bool
+	// Stream reads a response of server-sent events up to its first event,
+	// whose data is taken as the body
+	Stream bool
+	Want   int
+	// Save keeps values of the response under a name, e.g. "product":
+	// "data.id"; "header.ETag" is a response header
+	Save map[string]string
+	// Expect compares fields of the response, e.g. "data.stock": 7
+	Expect map[string]interface{}
+	// Retry repeats the request for up to this long until it gets Want and
+	// Expect, for results of background jobs
+	Retry time.Duration
+	// Session names the saved terminal session token sent in
+	// X-Terminal-Session, the admin's unless set; "none" sends no session
+	Session string
+}
+
+type formFile struct {
+	Field   string
+	Name    string
+	Content string
+}
+
+type scenario struct {
+	Name  string
+	Steps []step
+}
+
+var placeholder = regexp.MustCompile(`\$([a-z_]+)`)
+
+// the accounts the scenarios sign in with: an admin, a cashier and a cashier
+// whose PIN the terminal sessions scenario gets wrong until it is locked out
+var accounts = []struct {
+	Name string
+	Role string
+	PIN  string
+}{
+	{Name: "admin", Role: services.RoleAdmin, PIN: "2468"},
+	{Name: "cashier", Role: "cashier", PIN: "1357"},
+	{Name: "locked", Role: "cashier", PIN: "9753"},
+}
+
+var (
+	client  = &http.Client{Timeout: 30 * time.Second}
+	baseURL string
+	// callbackSecret signs the payment callbacks of Signed steps; the server
+	// has to verify them with the same PAYMENT_CALLBACK_SECRET
+	callbackSecret string
+	// vars are the values every scenario starts from: the run and dates, the
+	// accounts and their terminal sessions
+	vars map[string]string
+)
+
+func TestMain(m *testing.M) {
+	baseURL = strings.TrimSuffix(os.Getenv("BASE_URL"), "/")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+
+	callbackSecret = os.Getenv("PAYMENT_CALLBACK_SECRET")
+
+	if err := startStubs(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error starting the stubs:", err)
+		os.Exit(1)
+	}
+	if err := waitForServer(client, baseURL, time.Minute); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	vars = newVars(time.Now())
+	if err := signIn(vars); err != nil {
+		fmt.Fprintln(os.Stderr, "Error signing in:", err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// TestScenarios runs the scenarios in order, as later ones count on what
+// earlier ones left behind; -run TestScenarios/<name> runs one
+func TestScenarios(t *testing.T) {
+	for _, sc := range scenarios() {
+		t.Run(sc.Name, func(t *testing.T) {
+			if err := run(client, baseURL, sc, maps.Clone(vars)); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// signIn creates the accounts straight in the database at DATABASE_URL, as
+// no route creates the first one, and signs the admin and the cashier in on
+// terminals of their own
+func signIn(vars map[string]string) error {
+	db, err := database.Connect(os.Getenv("DATABASE_URL"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	users := repositories.NewAdminUserRepository(db)
+	admins := services.NewAdminUserService(users)
+	pins := services.NewTerminalSessionService(users, session.NewMemoryStore(), time.Hour, 1, time.Minute)
+	for _, account := range accounts {
+		username := "it-" + account.Name + "-" + vars["run"]
+		admin, err := admins.Create(username, "", account.Role, "integration-"+vars["run"])
+		if err != nil {
+			return fmt.Errorf("creating %s: %v", username, err)
+		}
+		if err := pins.SetPIN(admin.ID, account.PIN); err != nil {
+			return fmt.Errorf("setting the PIN of %s: %v", username, err)
+		}
+		vars[account.Name+"_user"] = username
+		vars[account.Name+"_pin"] = account.PIN
+		vars[account.Name+"_id"] = strconv.Itoa(admin.ID)
+	}
+
+	return run(client, baseURL, scenario{Name: "sign in", Steps: []step{
+		{Method: "POST", Path: "/api/terminal-sessions", Session: "none", Want: 201,
+			Body: `{"terminal_id": "it-admin-$run", "username": "$admin_user", "pin": "$admin_pin"}`,
+			Save: map[string]string{"admin_session": "data.token"}},
+		{Method: "POST", Path: "/api/terminal-sessions", Session: "none", Want: 201,
+			Body: `{"terminal_id": "it-cashier-$run", "username": "$cashier_user", "pin": "$cashier_pin"}`,
+			Save: map[string]string{"cashier_session": "data.token"}},
+	}}, vars)
+}
+
+// waitForServer polls /health until the server answers, as it may still be
+// migrating the database
+func waitForServer(client *http.Client, baseURL string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, err := client.Get(baseURL + "/health")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("server at %s isn't healthy after %s", baseURL, timeout)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// newVars returns the values of a run started at now. Names and codes are
+// made unique per run, so a rerun against the same database doesn't collide
+// with the data of the previous one.
+func newVars(now time.Time) map[string]string {
+	vars := map[string]string{
+		"run":        strconv.FormatInt(now.UnixNano()%1e8, 10),
+		"epoch":      strconv.FormatInt(now.Unix(), 10),
+		"today":      now.Format("2006-01-02"),
+		"yesterday":  now.AddDate(0, 0, -1).Format("2006-01-02"),
+		"tomorrow":   now.AddDate(0, 0, 1).Format("2006-01-02"),
+		"next_month": now.AddDate(0, 1, 0).Format("2006-01-02"),
+		"month":      now.Format("2006-01"),
+		"last_month": now.AddDate(0, -1, -now.Day()+1).Format("2006-01"),
+	}
+	vars["phone"] = fmt.Sprintf("%08s", vars["run"])
+	vars["stub_host"] = stubHost()
+	return vars
+}
+
+// run executes the steps of sc in order with vars and stops at the first
+// that fails
+func run(client *http.Client, baseURL string, sc scenario, vars map[string]string) error {
+	for i, st := range sc.Steps {
+		path := expand(st.Path, vars)
+		deadline := time.Now().Add(st.Retry)
+		for {
+			status, header, body, err := send(client, baseURL, st, path, vars)
+			if err != nil {
+				return fmt.Errorf("step %d %s %s: %v", i+1, st.Method, path, err)
+			}
+			if status != st.Want {
+				err = fmt.Errorf("want status %d, got %d", st.Want, status)
+			} else {
+				err = check(st, header, body, vars)
+			}
+			if err != nil && time.Now().Before(deadline) {
+				time.Sleep(500 * time.Millisecond)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("step %d %s %s: %v: %s", i+1, st.Method, path, err, truncate(body))
+			}
+			break
+		}
+	}
+	return nil
+}
+
+func send(client *http.Client, baseURL string, st step, path string, vars map[string]string) (int, http.Header, []byte, error) {
+	var body io.Reader
+	contentType := ""
+	switch {
+	case st.File != nil:
+		var buf bytes.Buffer
+		form := multipart.NewWriter(&buf)
+		part, err := form.CreateFormFile(st.File.Field, st.File.Name)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		if _, err := io.WriteString(part, expand(st.File.Content, vars)); err != nil {
+			return 0, nil, nil, err
+		}
+		if err := form.Close(); err != nil {
+			return 0, nil, nil, err
+		}
+		body, contentType = &buf, form.FormDataContentType()
+	case st.Body != "":
+		body, contentType = strings.NewReader(expand(st.Body, vars)), "application/json"
+	}
+
+	req, err := http.NewRequest(st.Method, baseURL+path, body)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for name, value := range st.Header {
+		req.Header.Set(name, expand(value, vars))
+	}
+	if st.Signed {
+		mac := hmac.New(sha256.New, []byte(callbackSecret))
+		mac.Write([]byte(expand(st.Body, vars)))
+		req.Header.Set("X-Callback-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	switch st.Session {
+	case "none":
+	case "":
+		req.Header.Set("X-Terminal-Session", vars["admin_session"])
+	default:
+		req.Header.Set("X-Terminal-Session", vars[st.Session])
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer resp.Body.Close()
+	if st.Stream && resp.StatusCode == http.StatusOK {
+		data, err := firstEvent(resp.Body, len(st.Expect) > 0 || len(st.Save) > 0)
+		return resp.StatusCode, resp.Header, data, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, resp.Header, respBody, err
+}
+
+// firstEvent returns the data of the first event of a server-sent events
+// stream, or nothing without reading when read is false, as a stream may not
+// send one until something happens
+func firstEvent(stream io.Reader, read bool) ([]byte, error) {
+	if !read {
+		return nil, nil
+	}
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			return []byte(data), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+// check compares the Expect fields of st and keeps its Save fields in vars
+func check(st step, header http.Header, body []byte, vars map[string]string) error {
+	if len(st.Expect) == 0 && len(st.Save) == 0 {
+		return nil
+	}
+
+	var doc interface{}
+	field := func(path string) (interface{}, error) {
+		if name, ok := strings.CutPrefix(path, "header."); ok {
+			if header.Get(name) == "" {
+				return nil, fmt.Errorf("response has no %s header", name)
+			}
+			return header.Get(name), nil
+		}
+		if doc == nil {
+			if err := json.Unmarshal(body, &doc); err != nil {
+				return nil, fmt.Errorf("response isn't JSON")
+			}
+		}
+		got, ok := lookup(doc, path)
+		if !ok {
+			return nil, fmt.Errorf("response has no %s", path)
+		}
+		return got, nil
+	}
+
+	for path, want := range st.Expect {
+		got, err := field(path)
+		if err != nil {
+			return err
+		}
+		wantStr := expand(fmt.Sprint(want), vars)
+		if fmt.Sprint(got) != wantStr {
+			return fmt.Errorf("want %s = %s, got %v", path, wantStr, got)
+		}
+	}
+
+	for name, path := range st.Save {
+		got, err := field(path)
+		if err != nil {
+			return err
+		}
+		vars[name] = fmt.Sprint(got)
+	}
+	return nil
+}
+
+// lookup follows a dotted path such as "data.items.0.id" through decoded JSON
+func lookup(doc interface{}, path string) (interface{}, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if doc, ok = v[key]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			doc = v[i]
+		default:
+			return nil, false
+		}
+	}
+
+	// JSON numbers decode as float64; whole ones are compared as integers
+	if f, ok := doc.(float64); ok && f == float64(int64(f)) {
+		return int64(f), true
+	}
+	return doc, true
+}
+
+func expand(s string, vars map[string]string) string {
+	return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+		if v, ok := vars[m[1:]]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+func truncate(body []byte) string {
+	if len(body) > 300 {
+		return string(body[:300]) + "..."
+	}
+	return string(body)
+}
@@ -0,0 +1,982 @@
+//go:build integration
+
+package integration
+
+import "time"
+
+// pngHeader is enough of a PNG for its type to be sniffed from the content
+const pngHeader = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+// pushKey and pushAuth are the keys of a browser push subscription
+const (
+	pushKey  = "BEQ49xjg7WKJnJbVjE8aPHZ7t2bvGjc1K_ehyAUifmf4A5025yh1F68i-8SR5MnFyRaDl6eIqS7PBmW8qFBT8zo"
+	pushAuth = "elEwjZt4ymdf3Xv8xcYIRw"
+)
+
+func scenarios() []scenario {
+	return []scenario{
+		terminalSessionScenario(),
+		authScenario(),
+		catalogScenario(),
+		batchScenario(),
+		salesScenario(),
+		voucherScenario(),
+		returnScenario(),
+		overrideScenario(),
+		restaurantScenario(),
+		reportScenario(),
+		customerScenario(),
+		purchasingScenario(),
+		inventoryScenario(),
+		deliveryScenario(),
+		taxInvoiceScenario(),
+		paymentLinkScenario(),
+		syncScenario(),
+		storeScenario(),
+		priceContractScenario(),
+		currencyScenario(),
+		printingScenario(),
+		displayScenario(),
+		notificationScenario(),
+		adminScenario(),
+		periodScenario(),
+	}
+}
+
+// terminalSessionScenario covers PIN sign-in, switching cashiers, the
+// lockout after wrong PINs, permissions and roles
+func terminalSessionScenario() scenario {
+	return scenario{Name: "terminal-sessions", Steps: []step{
+		{Method: "GET", Path: "/api/terminal-sessions/current", Session: "cashier_session", Want: 200,
+			Expect: map[string]interface{}{"data.username": "$cashier_user", "data.role": "cashier"}},
+		{Method: "GET", Path: "/api/me/permissions", Session: "cashier_session", Want: 200,
+			Expect: map[string]interface{}{"data.role": "cashier", "data.enforced": true}},
+		{Method: "GET", Path: "/api/me/permissions", Want: 200, Expect: map[string]interface{}{"data.role": "admin"}},
+		{Method: "GET", Path: "/api/product", Session: "none", Want: 401},
+		{Method: "GET", Path: "/api/product", Session: "cashier_session", Want: 200},
+		{Method: "GET", Path: "/api/roles", Session: "cashier_session", Want: 403},
+		{Method: "GET", Path: "/api/settings", Session: "cashier_session", Want: 200},
+		{Method: "PUT", Path: "/api/settings", Session: "cashier_session", Body: `{}`, Want: 403},
+
+		// switching in ends the session the terminal was in
+		{Method: "POST", Path: "/api/terminal-sessions", Session: "none", Want: 201,
+			Body: `{"terminal_id": "it-switch-$run", "username": "$cashier_user", "pin": "$cashier_pin"}`,
+			Save: map[string]string{"switched_out": "data.token"}},
+		{Method: "POST", Path: "/api/terminal-sessions", Session: "none", Want: 201,
+			Body: `{"terminal_id": "it-switch-$run", "username": "$admin_user", "pin": "$admin_pin"}`,
+			Save: map[string]string{"switched_in": "data.token"}},
+		{Method: "GET", Path: "/api/terminal-sessions/current", Session: "switched_out", Want: 401},
+		{Method: "GET", Path: "/api/terminal-sessions/current", Session: "switched_in", Want: 200,
+			Expect: map[string]interface{}{"data.username": "$admin_user", "data.terminal_id": "it-switch-$run"}},
+		{Method: "DELETE", Path: "/api/terminal-sessions/current", Session: "switched_in", Want: 200},
+		{Method: "GET", Path: "/api/terminal-sessions/current", Session: "switched_in", Want: 401},
+
+		// PIN_MAX_ATTEMPTS is 5: the fifth wrong PIN locks the account, after
+		// which the right one is refused too
+		{Method: "POST", Path: "/api/terminal-sessions", Session: "none", Body: `{"terminal_id": "it-locked-$run", "username": "$locked_user", "pin": "0000"}`, Want: 401},
+		{Method: "POST", Path: "/api/terminal-sessions", Session: "none", Body: `{"terminal_id": "it-locked-$run", "username": "$locked_user", "pin": "0000"}`, Want: 401},
+		{Method: "POST", Path: "/api/terminal-sessions", Session: "none", Body: `{"terminal_id": "it-locked-$run", "username": "$locked_user", "pin": "0000"}`, Want: 401},
+		{Method: "POST", Path: "/api/terminal-sessions", Session: "none", Body: `{"terminal_id": "it-locked-$run", "username": "$locked_user", "pin": "0000"}`, Want: 401},
+		{Method: "POST", Path: "/api/terminal-sessions", Session: "none", Body: `{"terminal_id": "it-locked-$run", "username": "$locked_user", "pin": "0000"}`, Want: 423},
+		{Method: "POST", Path: "/api/terminal-sessions", Session: "none", Body: `{"terminal_id": "it-locked-$run", "username": "$locked_user", "pin": "$locked_pin"}`, Want: 423},
+		{Method: "POST", Path: "/api/terminal-sessions", Session: "none", Body: `{"terminal_id": "it-locked-$run", "username": "$locked_user", "pin": "12"}`, Want: 400},
+
+		{Method: "GET", Path: "/api/permissions", Want: 200},
+		{Method: "PUT", Path: "/api/roles/it-auditor-$run", Body: `{"description": "integration", "permissions": ["report.view"]}`, Want: 200},
+		{Method: "GET", Path: "/api/roles", Want: 200},
+		{Method: "PUT", Path: "/api/roles/admin", Body: `{"permissions": ["report.view"]}`, Want: 400},
+		{Method: "PUT", Path: "/api/roles/it-bogus-$run", Body: `{"permissions": ["no.such"]}`, Want: 400},
+
+		// a manager holding user.manage can't make anyone an admin
+		{Method: "PUT", Path: "/api/roles/it-manager-$run", Body: `{"permissions": ["user.manage"]}`, Want: 200},
+		{Method: "POST", Path: "/api/admin/users", Body: `{"username": "it-manager-$run", "password": "integration-$run", "role": "it-manager-$run"}`, Want: 201,
+			Save: map[string]string{"manager_id": "data.id"}},
+		{Method: "PUT", Path: "/api/admin/users/$manager_id/pin", Body: `{"pin": "8642"}`, Want: 200},
+		{Method: "POST", Path: "/api/terminal-sessions", Session: "none", Want: 201,
+			Body: `{"terminal_id": "it-manager-$run", "username": "it-manager-$run", "pin": "8642"}`,
+			Save: map[string]string{"manager_session": "data.token"}},
+		{Method: "POST", Path: "/api/admin/users", Session: "manager_session", Body: `{"username": "it-rogue-$run", "password": "integration-$run", "role": "admin"}`, Want: 403},
+		{Method: "POST", Path: "/api/admin/users", Session: "manager_session", Body: `{"username": "it-rogue-$run", "password": "integration-$run"}`, Want: 400},
+		{Method: "POST", Path: "/api/admin/users", Session: "manager_session", Body: `{"username": "it-clerk-$run", "password": "integration-$run", "role": "cashier"}`, Want: 201},
+		{Method: "PUT", Path: "/api/admin/users/$manager_id/role", Session: "manager_session", Body: `{"role": "admin"}`, Want: 403},
+		{Method: "PUT", Path: "/api/admin/users/$admin_id/role", Session: "manager_session", Body: `{"role": "cashier"}`, Want: 403},
+		{Method: "PUT", Path: "/api/roles/it-manager-$run", Session: "manager_session", Body: `{"permissions": ["user.manage", "system.admin"]}`, Want: 403},
+	}}
+}
+
+// authScenario covers signing in with a password, which an account with an
+// email can only do once the address is confirmed, and the email links; the
+// mails are only logged, so no link is followed
+func authScenario() scenario {
+	return scenario{Name: "auth", Steps: []step{
+		{Method: "POST", Path: "/api/admin/users", Body: `{"username": "it-owner-$run", "password": "integration-$run", "role": "cashier"}`, Want: 201},
+		{Method: "POST", Path: "/api/auth/login", Session: "none", Body: `{"terminal_id": "it-office-$run", "username": "it-owner-$run", "password": "wrong-password"}`, Want: 401},
+		{Method: "POST", Path: "/api/auth/login", Session: "none", Body: `{"terminal_id": "it-office-$run", "username": "it-nobody-$run", "password": "integration-$run"}`, Want: 401},
+		{Method: "POST", Path: "/api/auth/login", Session: "none", Body: `{"username": "it-owner-$run", "password": "integration-$run"}`, Want: 400},
+		{Method: "POST", Path: "/api/auth/login", Session: "none", Want: 201,
+			Body:   `{"terminal_id": "it-office-$run", "username": "it-owner-$run", "password": "integration-$run"}`,
+			Expect: map[string]interface{}{"data.username": "it-owner-$run", "data.role": "cashier"},
+			Save:   map[string]string{"owner_session": "data.token"}},
+		{Method: "GET", Path: "/api/terminal-sessions/current", Session: "owner_session", Want: 200,
+			Expect: map[string]interface{}{"data.terminal_id": "it-office-$run"}},
+		{Method: "DELETE", Path: "/api/terminal-sessions/current", Session: "owner_session", Want: 200},
+
+		{Method: "POST", Path: "/api/admin/users", Want: 201,
+			Body:   `{"username": "it-mailed-$run", "email": "mailed$run@example.com", "password": "integration-$run", "role": "cashier"}`,
+			Expect: map[string]interface{}{"data.email_verified": false}},
+		{Method: "POST", Path: "/api/auth/login", Session: "none", Body: `{"terminal_id": "it-office-$run", "username": "it-mailed-$run", "password": "integration-$run"}`, Want: 403},
+		{Method: "POST", Path: "/api/auth/verify-email", Session: "none", Body: `{"token": "bogus"}`, Want: 400},
+		{Method: "POST", Path: "/api/auth/verify-email/resend", Session: "none", Body: `{"email": "mailed$run@example.com"}`, Want: 202},
+		{Method: "POST", Path: "/api/auth/forgot-password", Session: "none", Body: `{"email": "mailed$run@example.com"}`, Want: 202},
+		{Method: "POST", Path: "/api/auth/forgot-password", Session: "none", Body: `{"email": "nobody$run@example.com"}`, Want: 202},
+		{Method: "POST", Path: "/api/auth/reset-password", Session: "none", Body: `{"token": "bogus", "password": "short"}`, Want: 400},
+		{Method: "POST", Path: "/api/auth/reset-password", Session: "none", Body: `{"token": "bogus", "password": "integration-$run"}`, Want: 400},
+	}}
+}
+
+// catalogScenario covers categories, products, SKUs, bundles and public availability
+func catalogScenario() scenario {
+	return scenario{Name: "catalog", Steps: []step{
+		{Method: "POST", Path: "/api/category", Body: `{"name": "Snacks $run", "description": "integration"}`, Want: 201,
+			Save: map[string]string{"category": "data.id"}},
+		{Method: "GET", Path: "/api/category/$category", Want: 200, Expect: map[string]interface{}{"data.name": "Snacks $run"}},
+		{Method: "PUT", Path: "/api/category/$category", Body: `{"name": "Snacks $run", "description": "updated"}`, Want: 200},
+		{Method: "GET", Path: "/api/category", Want: 200},
+
+		{Method: "POST", Path: "/api/product", Want: 201,
+			Body: `{"name": "Chips $run", "barcode": "899$run", "price": 10000, "cost_price": 7000, "stock": 10, "category_ids": [$category]}`,
+			Save: map[string]string{"product": "data.id", "sku": "data.sku"}},
+		{Method: "GET", Path: "/api/product/$product", Want: 200, Expect: map[string]interface{}{"data.stock": 10, "data.categories.0.id": "$category"}},
+		{Method: "GET", Path: "/api/product/sku/$sku", Want: 200, Expect: map[string]interface{}{"data.id": "$product"}},
+		{Method: "GET", Path: "/api/product?name=Chips%20$run", Want: 200, Expect: map[string]interface{}{"data.0.id": "$product"}},
+		{Method: "PUT", Path: "/api/product/$product", Want: 200,
+			Body:   `{"name": "Chips $run", "barcode": "899$run", "price": 12000, "cost_price": 7000, "stock": 10, "category_ids": [$category]}`,
+			Expect: map[string]interface{}{"data.price": 12000}},
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Chips copy $run", "sku": "$sku", "price": 1000, "category_ids": []}`, Want: 409},
+
+		{Method: "PUT", Path: "/api/product/$product/public", Body: `{"public": true}`, Want: 200},
+		{Method: "GET", Path: "/api/public/availability?ids=$product", Want: 200, Expect: map[string]interface{}{"data.0.in_stock": true}},
+		{Method: "GET", Path: "/api/public/availability.js", Want: 200},
+
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Chips twin pack $run", "price": 20000, "category_ids": []}`, Want: 201,
+			Save: map[string]string{"bundle": "data.id"}},
+		{Method: "PUT", Path: "/api/product/$bundle/components", Body: `{"components": [{"product_id": $product, "quantity": 2}]}`, Want: 200,
+			Expect: map[string]interface{}{"data.is_bundle": true}},
+		{Method: "DELETE", Path: "/api/product/$bundle/components", Want: 200},
+		{Method: "DELETE", Path: "/api/product/$bundle", Want: 200},
+		{Method: "GET", Path: "/api/product/$bundle", Want: 404},
+
+		{Method: "POST", Path: "/api/category", Body: `{"name": "Temporary $run"}`, Want: 201, Save: map[string]string{"temporary": "data.id"}},
+		{Method: "DELETE", Path: "/api/category/$temporary", Want: 200},
+		{Method: "GET", Path: "/api/category/$temporary", Want: 404},
+	}}
+}
+
+// batchScenario covers creating and updating categories and products in
+// batches, with and without atomic
+func batchScenario() scenario {
+	return scenario{Name: "batch", Steps: []step{
+		{Method: "POST", Path: "/api/category/batch", Want: 200,
+			Body:   `[{"name": "Drinks $run"}, {"name": "Frozen $run"}, {"name": "drinks $run"}]`,
+			Expect: map[string]interface{}{"data.0.status": "created", "data.1.status": "created", "data.2.status": "failed"},
+			Save:   map[string]string{"drinks": "data.0.category.id", "frozen": "data.1.category.id"}},
+		{Method: "POST", Path: "/api/category/batch?atomic=true", Body: `[{"name": "Bakery $run"}, {"name": "Frozen $run"}]`, Want: 422,
+			Expect: map[string]interface{}{"data.0.status": "skipped", "data.1.status": "failed"}},
+		{Method: "PUT", Path: "/api/category/batch", Want: 200,
+			Body:   `[{"id": $frozen, "description": "integration"}, {"name": "No id $run"}]`,
+			Expect: map[string]interface{}{"data.0.category.description": "integration", "data.1.status": "failed"}},
+		{Method: "POST", Path: "/api/category/batch", Body: `[]`, Want: 400},
+
+		{Method: "POST", Path: "/api/product/batch", Want: 200,
+			Body: `[{"name": "Tea $run", "price": 5000, "cost_price": 3000, "stock": 5, "category_ids": [$drinks]},
+				{"name": "Ice cream $run", "price": 9000, "category_ids": [$frozen]}]`,
+			Expect: map[string]interface{}{"data.0.status": "created", "data.0.product.categories.0.id": "$drinks", "data.1.status": "created"},
+			Save:   map[string]string{"tea": "data.0.product.id"}},
+		{Method: "PUT", Path: "/api/product/batch", Body: `[{"id": $tea, "price": 5500}]`, Want: 200,
+			Expect: map[string]interface{}{"data.0.product.price": 5500, "data.0.product.stock": 5}},
+		{Method: "POST", Path: "/api/product/batch?atomic=true", Body: `[{"name": "Coffee $run", "price": 8000}, {"name": "Bad $run", "price": -1}]`, Want: 422,
+			Expect: map[string]interface{}{"data.0.status": "skipped", "data.1.status": "failed"}},
+	}}
+}
+
+// salesScenario covers checkout with pricing rules, receipts, held carts and sales reports
+func salesScenario() scenario {
+	return scenario{Name: "sales", Steps: []step{
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Soda $run", "price": 5000, "cost_price": 3000, "stock": 20, "category_ids": []}`, Want: 201,
+			Save: map[string]string{"product": "data.id"}},
+		{Method: "POST", Path: "/api/pricing-rules", Want: 201,
+			Body: `{"product_id": $product, "name": "3 for 12000", "min_quantity": 3, "price": 12000, "active": true}`,
+			Save: map[string]string{"rule": "data.id"}},
+		{Method: "GET", Path: "/api/pricing-rules/$rule", Want: 200},
+		{Method: "GET", Path: "/api/pricing-rules?product_id=$product", Want: 200},
+
+		// three at the rule price and one at the unit price
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 4}]}`, Want: 200,
+			Expect: map[string]interface{}{"data.total_amount": 17000},
+			Save:   map[string]string{"transaction": "data.id"}},
+		{Method: "GET", Path: "/api/product/$product", Want: 200, Expect: map[string]interface{}{"data.stock": 16}},
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 1000}]}`, Want: 500,
+			Expect: map[string]interface{}{"error_code": "insufficient_stock"}},
+		{Method: "GET", Path: "/api/product/$product", Want: 200, Expect: map[string]interface{}{"data.stock": 16}},
+
+		{Method: "POST", Path: "/api/transactions/$transaction/email-receipt", Body: `{"email": "buyer$run@example.com"}`, Want: 202},
+		{Method: "GET", Path: "/api/transactions/$transaction/email-receipt", Want: 200},
+
+		{Method: "POST", Path: "/api/held-carts", Want: 201,
+			Body: `{"terminal_id": "T-$run", "note": "integration", "items": [{"product_id": $product, "quantity": 1}]}`,
+			Save: map[string]string{"cart": "data.id"}},
+		{Method: "GET", Path: "/api/held-carts?terminal_id=T-$run", Want: 200, Expect: map[string]interface{}{"data.0.id": "$cart"}},
+		{Method: "GET", Path: "/api/held-carts/$cart", Want: 200},
+		{Method: "POST", Path: "/api/held-carts/$cart/resume", Want: 200},
+		{Method: "GET", Path: "/api/held-carts/$cart", Want: 404},
+		{Method: "POST", Path: "/api/held-carts", Body: `{"terminal_id": "T-$run", "items": [{"product_id": $product, "quantity": 2}]}`, Want: 201,
+			Save: map[string]string{"discarded": "data.id"}},
+		{Method: "DELETE", Path: "/api/held-carts/$discarded", Want: 200},
+		{Method: "GET", Path: "/api/held-carts/$discarded", Want: 404},
+
+		{Method: "GET", Path: "/api/report/hari-ini", Want: 200},
+		{Method: "GET", Path: "/api/report?start_date=$today&end_date=$today", Want: 200},
+		{Method: "GET", Path: "/api/report/profit?start_date=$today&end_date=$today", Want: 200},
+		{Method: "GET", Path: "/api/mobile/summary", Want: 200},
+
+		{Method: "DELETE", Path: "/api/pricing-rules/$rule", Want: 200},
+	}}
+}
+
+// voucherScenario covers issuing vouchers, paying with them and getting the
+// balance back on a refund
+func voucherScenario() scenario {
+	return scenario{Name: "vouchers", Steps: []step{
+		{Method: "POST", Path: "/api/vouchers", Body: `{"code": "IT-$run", "amount": 10000, "expires_on": "$next_month", "note": "integration"}`, Want: 201,
+			Expect: map[string]interface{}{"data.balance": 10000}},
+		{Method: "POST", Path: "/api/vouchers", Body: `{"code": "IT-$run", "amount": 5000}`, Want: 409},
+		{Method: "POST", Path: "/api/vouchers", Body: `{"amount": 0}`, Want: 400},
+		{Method: "GET", Path: "/api/vouchers", Want: 200},
+
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Tea $run", "price": 4000, "cost_price": 2500, "stock": 5, "category_ids": []}`, Want: 201,
+			Save: map[string]string{"product": "data.id"}},
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 2}], "voucher_code": "IT-$run"}`, Want: 200,
+			Expect: map[string]interface{}{"data.total_amount": 8000, "data.payment.voucher.amount": 8000, "data.payment.voucher.balance": 2000},
+			Save:   map[string]string{"transaction": "data.id"}},
+		{Method: "GET", Path: "/api/vouchers/IT-$run", Want: 200,
+			Expect: map[string]interface{}{"data.balance": 2000, "data.redemptions.0.transaction_id": "$transaction"}},
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 1}], "voucher_code": "NO-SUCH-$run"}`, Want: 400,
+			Expect: map[string]interface{}{"error_code": "voucher_invalid"}},
+
+		{Method: "PUT", Path: "/api/transactions/$transaction/status", Body: `{"status": "refunded", "reason": "integration"}`, Want: 200},
+		{Method: "GET", Path: "/api/vouchers/IT-$run", Want: 200, Expect: map[string]interface{}{"data.balance": 10000}},
+		{Method: "GET", Path: "/api/product/$product", Want: 200, Expect: map[string]interface{}{"data.stock": 5}},
+	}}
+}
+
+// returnScenario covers the transaction status flow: paying later, handing
+// over, refunding and voiding, with the stock put back
+func returnScenario() scenario {
+	return scenario{Name: "returns", Steps: []step{
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Kettle $run", "price": 6000, "cost_price": 4000, "stock": 10, "category_ids": []}`, Want: 201,
+			Save: map[string]string{"product": "data.id"}},
+
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 3}], "status": "paid"}`, Want: 200,
+			Expect: map[string]interface{}{"data.status": "paid"},
+			Save:   map[string]string{"transaction": "data.id"}},
+		{Method: "GET", Path: "/api/product/$product", Want: 200, Expect: map[string]interface{}{"data.stock": 7}},
+		// a cashier hands a sale over, but can't refund it
+		{Method: "PUT", Path: "/api/transactions/$transaction/status", Session: "cashier_session", Body: `{"status": "completed"}`, Want: 200},
+		{Method: "PUT", Path: "/api/transactions/$transaction/status", Session: "cashier_session", Body: `{"status": "refunded", "reason": "integration"}`, Want: 403},
+		{Method: "PUT", Path: "/api/transactions/$transaction/status", Body: `{"status": "paid"}`, Want: 409},
+		{Method: "PUT", Path: "/api/transactions/$transaction/status", Body: `{"status": "refunded"}`, Want: 400},
+		{Method: "PUT", Path: "/api/transactions/$transaction/status", Body: `{"status": "refunded", "reason": "Customer returned the goods"}`, Want: 200},
+		{Method: "PUT", Path: "/api/transactions/$transaction/status", Body: `{"status": "refunded", "reason": "twice"}`, Want: 409},
+		{Method: "GET", Path: "/api/product/$product", Want: 200, Expect: map[string]interface{}{"data.stock": 10}},
+		{Method: "GET", Path: "/api/transactions/$transaction/status-history", Want: 200},
+
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 2}], "status": "draft"}`, Want: 200,
+			Expect: map[string]interface{}{"data.status": "draft"},
+			Save:   map[string]string{"draft": "data.id"}},
+		{Method: "PUT", Path: "/api/transactions/$draft/status", Body: `{"status": "pending_payment"}`, Want: 200},
+		{Method: "PUT", Path: "/api/transactions/$draft/status", Body: `{"status": "voided", "reason": "integration"}`, Want: 200},
+		{Method: "GET", Path: "/api/product/$product", Want: 200, Expect: map[string]interface{}{"data.stock": 10}},
+		{Method: "GET", Path: "/api/transactions?limit=5", Want: 200},
+	}}
+}
+
+// overrideScenario covers price overrides approved with a manager's PIN and
+// the discount and margin guardrails; the server runs with
+// MAX_DISCOUNT_PERCENT=50 and BLOCK_BELOW_COST on
+func overrideScenario() scenario {
+	return scenario{Name: "overrides", Steps: []step{
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Blender $run", "price": 10000, "cost_price": 6000, "stock": 20, "category_ids": []}`, Want: 201,
+			Save: map[string]string{"product": "data.id"}},
+
+		{Method: "POST", Path: "/api/checkout", Session: "cashier_session", Want: 403,
+			Body:   `{"items": [{"product_id": $product, "quantity": 1, "override": {"price": 8000, "reason": "Kemasan penyok"}}]}`,
+			Expect: map[string]interface{}{"error_code": "override_not_approved"}},
+		{Method: "POST", Path: "/api/checkout", Session: "cashier_session", Want: 403,
+			Body:   `{"items": [{"product_id": $product, "quantity": 1, "override": {"price": 8000, "reason": "Kemasan penyok"}}], "approval": {"username": "$cashier_user", "pin": "$cashier_pin"}}`,
+			Expect: map[string]interface{}{"error_code": "override_not_approved"}},
+		{Method: "POST", Path: "/api/checkout", Session: "cashier_session", Want: 400,
+			Body:   `{"items": [{"product_id": $product, "quantity": 1, "override": {"price": 8000}}], "approval": {"username": "$admin_user", "pin": "$admin_pin"}}`,
+			Expect: map[string]interface{}{"error_code": "invalid_override"}},
+		{Method: "POST", Path: "/api/checkout", Session: "cashier_session", Want: 200,
+			Body: `{"items": [{"product_id": $product, "quantity": 1, "override": {"price": 8000, "reason": "Kemasan penyok"}}], "approval": {"username": "$admin_user", "pin": "$admin_pin"}}`,
+			Expect: map[string]interface{}{
+				"data.total_amount": 8000,
+				"data.details.0.price_override.original_subtotal": 10000,
+				"data.details.0.price_override.approved_by":       "$admin_user",
+				"data.details.0.price_override.reason":            "Kemasan penyok",
+			}},
+
+		// a pricing rule taking 70% off, past MAX_DISCOUNT_PERCENT and under cost
+		{Method: "POST", Path: "/api/pricing-rules", Want: 201,
+			Body: `{"product_id": $product, "name": "clearance", "min_quantity": 1, "price": 3000, "active": true}`,
+			Save: map[string]string{"rule": "data.id"}},
+		{Method: "POST", Path: "/api/checkout", Session: "cashier_session", Body: `{"items": [{"product_id": $product, "quantity": 1}]}`, Want: 422,
+			Expect: map[string]interface{}{"error_code": "price_guardrail", "errors.0.field": "items[0]"}},
+		{Method: "POST", Path: "/api/checkout", Session: "cashier_session", Want: 403,
+			Body: `{"items": [{"product_id": $product, "quantity": 1}], "approval": {"username": "$admin_user", "pin": "0000"}}`},
+		{Method: "POST", Path: "/api/checkout", Session: "cashier_session", Want: 200,
+			Body:   `{"items": [{"product_id": $product, "quantity": 1}], "approval": {"username": "$admin_user", "pin": "$admin_pin"}}`,
+			Expect: map[string]interface{}{"data.total_amount": 3000}},
+		{Method: "DELETE", Path: "/api/pricing-rules/$rule", Want: 200},
+
+		// an approval sent along with a sale needing none isn't checked
+		{Method: "POST", Path: "/api/checkout", Session: "cashier_session", Want: 200,
+			Body:   `{"items": [{"product_id": $product, "quantity": 1}], "approval": {"username": "$admin_user", "pin": "0000"}}`,
+			Expect: map[string]interface{}{"data.total_amount": 10000}},
+		{Method: "GET", Path: "/api/activity?entity=transaction", Want: 200},
+	}}
+}
+
+// restaurantScenario covers orders at a table and by queue number, items
+// added with and without approval, and billing them
+func restaurantScenario() scenario {
+	return scenario{Name: "restaurant", Steps: []step{
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Fried rice $run", "price": 15000, "cost_price": 9000, "stock": 10, "category_ids": []}`, Want: 201,
+			Save: map[string]string{"product": "data.id"}},
+
+		{Method: "POST", Path: "/api/orders", Body: `{"table_number": "IT-$run", "items": [{"product_id": $product, "quantity": 1}]}`, Want: 201,
+			Expect: map[string]interface{}{"data.status": "draft", "data.table_number": "IT-$run", "data.total_amount": 15000},
+			Save:   map[string]string{"order": "data.id"}},
+		{Method: "POST", Path: "/api/orders", Body: `{"table_number": "IT-$run", "items": [{"product_id": $product, "quantity": 1}]}`, Want: 409,
+			Expect: map[string]interface{}{"error_code": "table_occupied"}},
+		{Method: "POST", Path: "/api/orders/$order/items", Body: `{"items": [{"product_id": $product, "quantity": 2}]}`, Want: 200,
+			Expect: map[string]interface{}{"data.total_amount": 45000}},
+		{Method: "POST", Path: "/api/orders/$order/items", Want: 403,
+			Body: `{"items": [{"product_id": $product, "quantity": 1, "override": {"price": 10000, "reason": "Porsi kecil"}}]}`},
+		{Method: "POST", Path: "/api/orders/$order/items", Want: 200,
+			Body:   `{"items": [{"product_id": $product, "quantity": 1, "override": {"price": 10000, "reason": "Porsi kecil"}}], "approval": {"username": "$admin_user", "pin": "$admin_pin"}}`,
+			Expect: map[string]interface{}{"data.total_amount": 55000}},
+		{Method: "GET", Path: "/api/product/$product", Want: 200, Expect: map[string]interface{}{"data.stock": 6}},
+		{Method: "PUT", Path: "/api/transactions/$order/status", Session: "cashier_session", Body: `{"status": "paid"}`, Want: 200},
+		{Method: "POST", Path: "/api/orders/$order/items", Body: `{"items": [{"product_id": $product, "quantity": 1}]}`, Want: 409,
+			Expect: map[string]interface{}{"error_code": "order_closed"}},
+
+		{Method: "POST", Path: "/api/orders", Body: `{"items": [{"product_id": $product, "quantity": 1}]}`, Want: 201,
+			Save: map[string]string{"queued": "data.id", "queue": "data.queue_number"}},
+		{Method: "PUT", Path: "/api/transactions/$queued/status", Body: `{"status": "voided", "reason": "integration"}`, Want: 200},
+	}}
+}
+
+// reportScenario covers the sales, profit and loss and comparison reports,
+// which count paid and completed sales only
+func reportScenario() scenario {
+	return scenario{Name: "reports", Steps: []step{
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Umbrella $run", "price": 5000, "cost_price": 3000, "stock": 10, "category_ids": []}`, Want: 201,
+			Save: map[string]string{"product": "data.id"}},
+		{Method: "GET", Path: "/api/report?start_date=$today&end_date=$today", Want: 200,
+			Save: map[string]string{"count": "data.total_transaksi", "revenue": "data.total_revenue"}},
+
+		// drafts and sales waiting for payment aren't revenue yet
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 1}], "status": "draft"}`, Want: 200,
+			Save: map[string]string{"draft": "data.id"}},
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 1}], "status": "pending_payment"}`, Want: 200,
+			Save: map[string]string{"pending": "data.id"}},
+		{Method: "GET", Path: "/api/report?start_date=$today&end_date=$today", Want: 200,
+			Expect: map[string]interface{}{"data.total_transaksi": "$count", "data.total_revenue": "$revenue"}},
+		{Method: "PUT", Path: "/api/transactions/$draft/status", Body: `{"status": "voided", "reason": "integration"}`, Want: 200},
+		{Method: "PUT", Path: "/api/transactions/$pending/status", Body: `{"status": "voided", "reason": "integration"}`, Want: 200},
+		{Method: "GET", Path: "/api/report?start_date=$today&end_date=$today", Want: 200,
+			Expect: map[string]interface{}{"data.total_transaksi": "$count", "data.total_revenue": "$revenue"}},
+
+		{Method: "GET", Path: "/api/report?start_date=$today&end_date=$today&tz=Asia/Makassar", Want: 200},
+		{Method: "GET", Path: "/api/report?start_date=$today&end_date=$today&tz=Mars/Olympus", Want: 400},
+		{Method: "GET", Path: "/api/report?start_date=$tomorrow&end_date=$today", Want: 400},
+		{Method: "GET", Path: "/api/report/hari-ini?tz=Asia/Jakarta", Want: 200},
+		{Method: "GET", Path: "/api/report/profit-loss?start_date=$today&end_date=$today", Want: 200},
+		{Method: "GET", Path: "/api/report/price-contracts?start_date=$today&end_date=$today", Want: 200},
+		{Method: "GET", Path: "/api/report/compare?period=month&current=$month&previous=$last_month", Want: 200,
+			Expect: map[string]interface{}{"data.period": "month", "data.current.period": "$month", "data.previous.period": "$last_month"}},
+		{Method: "GET", Path: "/api/report/compare?period=week&current=$month", Want: 400},
+		{Method: "GET", Path: "/api/report", Session: "cashier_session", Want: 403},
+		{Method: "GET", Path: "/api/stats", Want: 200},
+	}}
+}
+
+// customerScenario covers customers, kasbon, CSV import, merging and installment plans
+func customerScenario() scenario {
+	return scenario{Name: "customers", Steps: []step{
+		{Method: "POST", Path: "/api/customer", Body: `{"name": "Rina $run", "phone": "0813$phone", "reminder_channel": "whatsapp"}`, Want: 201,
+			Save: map[string]string{"customer": "data.id"}},
+		{Method: "GET", Path: "/api/customer/$customer", Want: 200, Expect: map[string]interface{}{"data.phone": "+62813$phone"}},
+		{Method: "PUT", Path: "/api/customer/$customer", Body: `{"name": "Rina $run", "phone": "0813$phone", "email": "Rina$run@Example.com"}`, Want: 200,
+			Expect: map[string]interface{}{"data.email": "rina$run@example.com"}},
+		{Method: "GET", Path: "/api/customer", Want: 200},
+
+		{Method: "POST", Path: "/api/customer/$customer/kasbon", Body: `{"amount": 50000, "due_date": "$tomorrow", "note": "integration"}`, Want: 201},
+		{Method: "POST", Path: "/api/customer/$customer/payment", Body: `{"amount": 20000}`, Want: 201},
+		{Method: "GET", Path: "/api/customer/$customer/kasbon", Want: 200, Expect: map[string]interface{}{"data.balance": 30000}},
+		{Method: "GET", Path: "/api/customer/$customer/reminders", Want: 200},
+
+		// the first row is a known customer, only the second is imported
+		{Method: "POST", Path: "/api/customer/import", Want: 200,
+			File:   &formFile{Field: "file", Name: "customers.csv", Content: "name,phone\nRina again $run,0813$phone\nDodi $run,0814$phone\n"},
+			Expect: map[string]interface{}{"data.imported": 1, "data.skipped.0.row": 2}},
+		{Method: "POST", Path: "/api/customer", Body: `{"name": "Rina Lestari $run", "email": "rina$run@example.com"}`, Want: 201,
+			Save: map[string]string{"duplicate": "data.id"}},
+		{Method: "GET", Path: "/api/customer/duplicates", Want: 200},
+		{Method: "POST", Path: "/api/customer/$customer/merge", Body: `{"customer_ids": [$duplicate]}`, Want: 200},
+		{Method: "GET", Path: "/api/customer/$duplicate", Want: 404},
+
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Fridge $run", "price": 3000000, "cost_price": 2500000, "stock": 2, "category_ids": []}`, Want: 201,
+			Save: map[string]string{"product": "data.id"}},
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 1}]}`, Want: 200,
+			Save: map[string]string{"transaction": "data.id"}},
+		{Method: "POST", Path: "/api/installment", Want: 201,
+			Body: `{"transaction_id": $transaction, "customer_id": $customer, "down_payment": 600000, "installment_count": 3, "first_due_date": "$next_month"}`,
+			Save: map[string]string{"plan": "data.id"}},
+		{Method: "POST", Path: "/api/installment/$plan/payment", Body: `{"amount": 800000}`, Want: 201},
+		{Method: "GET", Path: "/api/installment/$plan", Want: 200, Expect: map[string]interface{}{"data.outstanding": 1600000}},
+		{Method: "GET", Path: "/api/installment", Want: 200},
+		{Method: "GET", Path: "/api/report/receivables-aging", Want: 200},
+
+		{Method: "GET", Path: "/api/dunning/templates", Want: 200},
+		{Method: "PUT", Path: "/api/dunning/templates/sms", Body: `{"body": "Halo, tagihan Anda di toko kami sudah jatuh tempo."}`, Want: 200},
+
+		{Method: "POST", Path: "/api/customer", Body: `{"name": "Temporary $run"}`, Want: 201, Save: map[string]string{"temporary": "data.id"}},
+		{Method: "DELETE", Path: "/api/customer/$temporary", Want: 200},
+		{Method: "GET", Path: "/api/customer/$temporary", Want: 404},
+	}}
+}
+
+// purchasingScenario covers suppliers and their prices, purchase orders and receiving
+func purchasingScenario() scenario {
+	return scenario{Name: "purchasing", Steps: []step{
+		{Method: "POST", Path: "/api/supplier", Body: `{"name": "PT Sumber $run", "phone": "021$phone", "email": "po$run@example.com"}`, Want: 201,
+			Save: map[string]string{"supplier": "data.id"}},
+		{Method: "GET", Path: "/api/supplier/$supplier", Want: 200},
+		{Method: "PUT", Path: "/api/supplier/$supplier", Body: `{"name": "PT Sumber Jaya $run", "phone": "021$phone", "email": "po$run@example.com"}`, Want: 200},
+		{Method: "GET", Path: "/api/supplier", Want: 200},
+
+		{Method: "POST", Path: "/api/product", Want: 201,
+			Body: `{"name": "Rice $run", "barcode": "77$run", "price": 15000, "cost_price": 12000, "stock": 5, "reorder_point": 10, "reorder_qty": 20, "category_ids": []}`,
+			Save: map[string]string{"product": "data.id"}},
+		{Method: "PUT", Path: "/api/supplier/$supplier/prices", Body: `{"product_id": $product, "price": 11500}`, Want: 200},
+		{Method: "GET", Path: "/api/supplier/$supplier/prices", Want: 200},
+		{Method: "GET", Path: "/api/supplier/$supplier/price-trend?product_id=$product", Want: 200},
+		{Method: "GET", Path: "/api/product/$product/suppliers", Want: 200},
+		{Method: "GET", Path: "/api/reorder/suggestions", Want: 200},
+
+		{Method: "POST", Path: "/api/purchase-order", Body: `{"supplier_id": $supplier, "items": [{"product_id": $product, "quantity": 20, "unit_cost": 11500}]}`, Want: 201,
+			Save: map[string]string{"order": "data.id"}},
+		{Method: "GET", Path: "/api/purchase-order/$order", Want: 200, Expect: map[string]interface{}{"data.status": "open"}},
+		{Method: "GET", Path: "/api/purchase-order", Want: 200},
+		{Method: "POST", Path: "/api/receiving", Body: `{"purchase_order_id": $order}`, Want: 201, Save: map[string]string{"session": "data.id"}},
+		{Method: "POST", Path: "/api/receiving/$session/scan", Body: `{"barcode": "77$run", "quantity": 20}`, Want: 200},
+		{Method: "GET", Path: "/api/receiving/$session", Want: 200},
+		{Method: "POST", Path: "/api/receiving/$session/commit", Want: 200},
+		{Method: "GET", Path: "/api/product/$product", Want: 200, Expect: map[string]interface{}{"data.stock": 25}},
+		{Method: "GET", Path: "/api/purchase-order/$order/receipts", Want: 200, Save: map[string]string{"receipt": "data.0.id"}},
+
+		// the receipt was booked at the wrong cost; the cost price only
+		// follows once recalculated
+		{Method: "PUT", Path: "/api/admin/costs/receipts/$receipt", Body: `{"product_id": $product, "unit_cost": -1}`, Want: 400},
+		{Method: "PUT", Path: "/api/admin/costs/receipts/$receipt", Body: `{"product_id": $product, "unit_cost": 11000}`, Want: 200},
+		{Method: "POST", Path: "/api/admin/costs/recalculate?dry_run=true", Want: 200, Expect: map[string]interface{}{"data.dry_run": true}},
+		{Method: "POST", Path: "/api/admin/costs/recalculate", Want: 200, Expect: map[string]interface{}{"data.dry_run": false}},
+		{Method: "GET", Path: "/api/product/$product", Want: 200, Expect: map[string]interface{}{"data.cost_price": 11000}},
+
+		{Method: "POST", Path: "/api/purchase-order", Body: `{"supplier_id": $supplier, "items": [{"product_id": $product, "quantity": 5, "unit_cost": 11500}]}`, Want: 201,
+			Save: map[string]string{"cancelled": "data.id"}},
+		{Method: "POST", Path: "/api/purchase-order/$cancelled/close", Body: `{"reason": "integration"}`, Want: 200},
+
+		{Method: "POST", Path: "/api/supplier", Body: `{"name": "CV Temporary $run"}`, Want: 201, Save: map[string]string{"temporary": "data.id"}},
+		{Method: "DELETE", Path: "/api/supplier/$temporary", Want: 200},
+		{Method: "GET", Path: "/api/supplier/$temporary", Want: 404},
+	}}
+}
+
+// inventoryScenario covers write-offs, stock opname, cycle counts, ABC
+// classification, expenses and exports
+func inventoryScenario() scenario {
+	return scenario{Name: "inventory", Steps: []step{
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Soap $run", "barcode": "55$run", "price": 5000, "cost_price": 3800, "stock": 30, "category_ids": []}`, Want: 201,
+			Save: map[string]string{"product": "data.id"}},
+
+		{Method: "POST", Path: "/api/write-offs", Body: `{"product_id": $product, "reason": "damaged", "quantity": 2, "note": "integration"}`, Want: 201,
+			Save: map[string]string{"write_off": "data.id"}},
+		{Method: "GET", Path: "/api/write-offs/$write_off", Want: 200},
+		{Method: "GET", Path: "/api/write-offs", Want: 200},
+		{Method: "GET", Path: "/api/product/$product", Want: 200, Expect: map[string]interface{}{"data.stock": 28}},
+		{Method: "POST", Path: "/api/product/$product/stock", Body: `{"quantity": 2}`, Want: 200, Expect: map[string]interface{}{"data.stock": 30}},
+		{Method: "POST", Path: "/api/product/$product/stock", Body: `{"quantity": -100}`, Want: 409},
+		{Method: "GET", Path: "/api/stock-movements?product_id=$product", Want: 200, Expect: map[string]interface{}{"data.movements.0.product_id": "$product"}},
+		{Method: "GET", Path: "/api/product/expiring?days=30", Want: 200},
+
+		{Method: "POST", Path: "/api/stock-opname", Body: `{"note": "integration $run"}`, Want: 201, Save: map[string]string{"opname": "data.id"}},
+		{Method: "POST", Path: "/api/stock-opname/$opname/counts", Body: `{"items": [{"product_id": $product, "counted_qty": 27}]}`, Want: 200},
+		{Method: "GET", Path: "/api/stock-opname/$opname", Want: 200},
+		{Method: "POST", Path: "/api/stock-opname/$opname/submit", Want: 200},
+		{Method: "POST", Path: "/api/stock-opname/$opname/approve", Body: `{"note": "integration"}`, Want: 200},
+		{Method: "GET", Path: "/api/product/$product", Want: 200, Expect: map[string]interface{}{"data.stock": 27}},
+		{Method: "GET", Path: "/api/stock-opname", Want: 200},
+		{Method: "GET", Path: "/api/report/shrinkage?start_date=$today&end_date=$today", Want: 200},
+
+		// any of today's tasks is counted at the stock it has, so nothing changes
+		{Method: "POST", Path: "/api/cycle-count/generate", Want: 200},
+		{Method: "GET", Path: "/api/cycle-count/tasks?date=$today&status=pending", Want: 200,
+			Save: map[string]string{"task": "data.0.id", "task_product": "data.0.product_id"}},
+		{Method: "GET", Path: "/api/product/$task_product", Want: 200, Save: map[string]string{"task_stock": "data.stock"}},
+		{Method: "POST", Path: "/api/cycle-count/tasks/$task/count", Body: `{"counted_qty": $task_stock}`, Want: 200,
+			Expect: map[string]interface{}{"data.status": "counted", "data.variance": 0}},
+		{Method: "POST", Path: "/api/cycle-count/tasks/$task/count", Body: `{"counted_qty": $task_stock}`, Want: 409},
+		{Method: "GET", Path: "/api/cycle-count/tasks", Want: 200},
+		{Method: "GET", Path: "/api/cycle-count/accuracy", Want: 200},
+		{Method: "POST", Path: "/api/abc-classification/run", Want: 200},
+		{Method: "GET", Path: "/api/abc-classification", Want: 200},
+
+		{Method: "POST", Path: "/api/expenses", Body: `{"amount": 25000, "category": "supplies", "description": "integration $run", "spent_on": "$today"}`, Want: 201,
+			Save: map[string]string{"expense": "data.id"}},
+		{Method: "GET", Path: "/api/expenses/$expense", Want: 200},
+		{Method: "GET", Path: "/api/expenses", Want: 200},
+
+		{Method: "POST", Path: "/api/exports/stock-movements", Body: `{"date_from": "$today", "date_to": "$today", "format": "csv"}`, Want: 202,
+			Save: map[string]string{"export": "data.id"}},
+		{Method: "GET", Path: "/api/exports/$export/download", Want: 200, Retry: 30 * time.Second},
+		{Method: "POST", Path: "/api/exports/expenses", Body: `{"date_from": "$today", "date_to": "$today", "format": "xlsx"}`, Want: 202},
+		{Method: "POST", Path: "/api/exports/journal", Body: `{"date_from": "$today", "date_to": "$today", "format": "csv"}`, Want: 202,
+			Save: map[string]string{"journal": "data.id"}},
+		{Method: "GET", Path: "/api/exports/$journal/download", Want: 200, Retry: 30 * time.Second},
+		{Method: "GET", Path: "/api/exports", Want: 200},
+
+		{Method: "DELETE", Path: "/api/expenses/$expense", Want: 200},
+		{Method: "GET", Path: "/api/expenses/$expense", Want: 404},
+	}}
+}
+
+// deliveryScenario covers couriers, and a delivery from packing to a photo
+// proving it arrived
+func deliveryScenario() scenario {
+	return scenario{Name: "deliveries", Steps: []step{
+		{Method: "POST", Path: "/api/couriers", Body: `{"name": " ", "phone": "0815$phone"}`, Want: 400},
+		{Method: "POST", Path: "/api/couriers", Body: `{"name": "Pak Budi $run", "phone": "0815$phone"}`, Want: 201,
+			Expect: map[string]interface{}{"data.phone": "+62815$phone"},
+			Save:   map[string]string{"courier": "data.id"}},
+		{Method: "PUT", Path: "/api/couriers/$courier", Body: `{"name": "Pak Budi Santoso $run", "phone": "0815$phone"}`, Want: 200},
+		{Method: "GET", Path: "/api/couriers", Want: 200},
+
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Gallon water $run", "price": 20000, "cost_price": 15000, "stock": 10, "category_ids": []}`, Want: 201,
+			Save: map[string]string{"product": "data.id"}},
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 1}]}`, Want: 200,
+			Save: map[string]string{"transaction": "data.id"}},
+		{Method: "POST", Path: "/api/deliveries", Body: `{"transaction_id": $transaction, "customer_name": "Bu Sri", "customer_phone": "0816$phone"}`, Want: 400},
+		{Method: "POST", Path: "/api/deliveries", Want: 201,
+			Body:   `{"transaction_id": $transaction, "customer_name": "Bu Sri", "customer_phone": "0816$phone", "address": "Jl. Melati 5, Jakarta"}`,
+			Expect: map[string]interface{}{"data.status": "pending"},
+			Save:   map[string]string{"delivery": "data.id"}},
+		{Method: "POST", Path: "/api/deliveries", Want: 409,
+			Body: `{"transaction_id": $transaction, "customer_name": "Bu Sri", "customer_phone": "0816$phone", "address": "Jl. Melati 5, Jakarta"}`},
+		{Method: "GET", Path: "/api/deliveries/$delivery", Want: 200},
+		{Method: "GET", Path: "/api/deliveries?status=pending", Want: 200},
+
+		// a delivery is packed before it goes out, and goes out with a courier
+		{Method: "PUT", Path: "/api/deliveries/$delivery/status", Body: `{"status": "out_for_delivery"}`, Want: 409},
+		{Method: "PUT", Path: "/api/deliveries/$delivery/status", Body: `{"status": "packed"}`, Want: 200},
+		{Method: "PUT", Path: "/api/deliveries/$delivery/status", Body: `{"status": "out_for_delivery"}`, Want: 409},
+		{Method: "PUT", Path: "/api/deliveries/$delivery/courier", Body: `{"courier_id": $courier}`, Want: 200},
+		{Method: "PUT", Path: "/api/deliveries/$delivery/status", Body: `{"status": "out_for_delivery"}`, Want: 200},
+		{Method: "GET", Path: "/api/deliveries?courier_id=$courier", Want: 200, Expect: map[string]interface{}{"data.0.id": "$delivery"}},
+
+		{Method: "PUT", Path: "/api/deliveries/$delivery/proof", File: &formFile{Field: "photo", Name: "proof.txt", Content: "not a photo"}, Want: 400},
+		{Method: "PUT", Path: "/api/deliveries/$delivery/proof", File: &formFile{Field: "photo", Name: "proof.png", Content: pngHeader}, Want: 200,
+			Expect: map[string]interface{}{"data.status": "delivered", "data.has_proof": true}},
+		{Method: "GET", Path: "/api/deliveries/$delivery/proof", Want: 200},
+		{Method: "PUT", Path: "/api/deliveries/$delivery/status", Body: `{"status": "cancelled"}`, Want: 409},
+
+		{Method: "DELETE", Path: "/api/couriers/$courier", Want: 200},
+		{Method: "DELETE", Path: "/api/couriers/$courier", Want: 404},
+	}}
+}
+
+// taxInvoiceScenario covers issuing a tax invoice for a sale, with the tax
+// taken out of a price that includes it
+func taxInvoiceScenario() scenario {
+	return scenario{Name: "tax-invoices", Steps: []step{
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Printer $run", "price": 1110000, "cost_price": 900000, "stock": 5, "category_ids": []}`, Want: 201,
+			Save: map[string]string{"product": "data.id"}},
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 1}]}`, Want: 200,
+			Save: map[string]string{"transaction": "data.id"}},
+
+		{Method: "POST", Path: "/api/tax-invoices", Want: 400,
+			Body: `{"transaction_id": $transaction, "buyer_npwp": "01.234.567.8-901", "buyer_name": "PT Sumber Rejeki", "buyer_address": "Jl. Sudirman 10, Jakarta"}`},
+		{Method: "POST", Path: "/api/tax-invoices", Want: 400,
+			Body: `{"transaction_id": $transaction, "buyer_npwp": "01.234.567.8-901.000", "buyer_name": "PT Sumber Rejeki"}`},
+		{Method: "POST", Path: "/api/tax-invoices", Want: 400,
+			Body: `{"transaction_id": 999999999, "buyer_npwp": "01.234.567.8-901.000", "buyer_name": "PT Sumber Rejeki", "buyer_address": "Jl. Sudirman 10, Jakarta"}`},
+		{Method: "POST", Path: "/api/tax-invoices", Want: 201,
+			Body: `{"transaction_id": $transaction, "buyer_npwp": "01.234.567.8-901.000", "buyer_name": "PT Sumber Rejeki", "buyer_address": "Jl. Sudirman 10, Jakarta"}`,
+			Expect: map[string]interface{}{
+				"data.buyer_npwp": "012345678901000",
+				"data.tax_rate":   11,
+				"data.tax_base":   1000000,
+				"data.tax_amount": 110000,
+			},
+			Save: map[string]string{"invoice": "data.id"}},
+		{Method: "POST", Path: "/api/tax-invoices", Want: 409,
+			Body: `{"transaction_id": $transaction, "buyer_npwp": "01.234.567.8-901.000", "buyer_name": "PT Sumber Rejeki", "buyer_address": "Jl. Sudirman 10, Jakarta"}`},
+
+		{Method: "GET", Path: "/api/tax-invoices/$invoice", Want: 200, Expect: map[string]interface{}{"data.transaction_id": "$transaction"}},
+		{Method: "GET", Path: "/api/tax-invoices/$invoice/pdf", Want: 200},
+		{Method: "GET", Path: "/api/tax-invoices?date_from=$today&date_to=$today", Want: 200},
+		{Method: "GET", Path: "/api/tax-invoices/999999999", Want: 404},
+	}}
+}
+
+// paymentLinkScenario covers paying a draft through a link from the gateway
+// stub, the signed callbacks it sends back, and a link paid short
+func paymentLinkScenario() scenario {
+	return scenario{Name: "payment-links", Steps: []step{
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Rice cooker $run", "price": 50000, "cost_price": 35000, "stock": 10, "category_ids": []}`, Want: 201,
+			Save: map[string]string{"product": "data.id"}},
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 1}], "status": "draft"}`, Want: 200,
+			Save: map[string]string{"transaction": "data.id", "total": "data.total_amount"}},
+		{Method: "POST", Path: "/api/payment-links", Body: `{"transaction_id": $transaction, "customer_name": "Bu Ani", "customer_phone": "0817$phone"}`, Want: 201,
+			Expect: map[string]interface{}{"data.status": "pending", "data.amount": "$total"},
+			Save:   map[string]string{"link": "data.id", "reference": "data.reference"}},
+		{Method: "GET", Path: "/api/payment-links/$link", Want: 200, Expect: map[string]interface{}{"data.url": "https://pay.example.com/$reference"}},
+		{Method: "POST", Path: "/api/payment-links", Body: `{"transaction_id": $transaction}`, Want: 409},
+
+		// callbacks are only taken when signed with PAYMENT_CALLBACK_SECRET
+		{Method: "POST", Path: "/api/payment-links/callback", Session: "none", Body: `{"reference": "$reference", "status": "paid", "amount": $total}`, Want: 401},
+		{Method: "POST", Path: "/api/payment-links/callback", Session: "none", Signed: true, Body: `{"reference": "$reference", "status": "refunded", "amount": $total}`, Want: 400},
+		{Method: "POST", Path: "/api/payment-links/callback", Session: "none", Signed: true, Body: `{"reference": "missing-$run", "status": "paid", "amount": $total}`, Want: 404},
+		{Method: "POST", Path: "/api/payment-links/callback", Session: "none", Signed: true, Body: `{"reference": "$reference", "status": "paid", "amount": $total}`, Want: 200,
+			Expect: map[string]interface{}{"data.status": "paid"}},
+		{Method: "GET", Path: "/api/transactions/$transaction/status-history", Want: 200,
+			Expect: map[string]interface{}{"data.1.status": "pending_payment", "data.2.status": "paid"}},
+		{Method: "POST", Path: "/api/payment-links/callback", Session: "none", Signed: true, Body: `{"reference": "$reference", "status": "paid", "amount": $total}`, Want: 200},
+		{Method: "POST", Path: "/api/payment-links", Body: `{"transaction_id": $transaction}`, Want: 409},
+
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 1}], "status": "draft"}`, Want: 200,
+			Save: map[string]string{"short": "data.id"}},
+		{Method: "POST", Path: "/api/payment-links", Body: `{"transaction_id": $short}`, Want: 201, Save: map[string]string{"short_reference": "data.reference"}},
+		{Method: "POST", Path: "/api/payment-links/callback", Session: "none", Signed: true, Body: `{"reference": "$short_reference", "status": "paid", "amount": 20000}`, Want: 200,
+			Expect: map[string]interface{}{"data.status": "underpaid", "data.paid_amount": 20000}},
+		{Method: "GET", Path: "/api/payment-links?status=underpaid", Want: 200},
+		{Method: "PUT", Path: "/api/transactions/$short/status", Body: `{"status": "voided", "reason": "integration"}`, Want: 200},
+	}}
+}
+
+// syncScenario covers an offline terminal pulling catalog changes and pushing
+// the sales it made while offline
+func syncScenario() scenario {
+	return scenario{Name: "sync", Steps: []step{
+		{Method: "GET", Path: "/api/sync/changes", Session: "cashier_session", Want: 200, Save: map[string]string{"cursor": "data.cursor"}},
+		{Method: "GET", Path: "/api/sync/changes?since=-1", Session: "cashier_session", Want: 400},
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Candle $run", "price": 8000, "cost_price": 5000, "stock": 10, "category_ids": []}`, Want: 201,
+			Save: map[string]string{"product": "data.id"}},
+		{Method: "GET", Path: "/api/sync/changes?since=$cursor", Session: "cashier_session", Want: 200,
+			Expect: map[string]interface{}{"data.products.0.id": "$product"}},
+
+		// a sale whose lines don't add up to its total is rejected
+		{Method: "POST", Path: "/api/sync/transactions", Session: "cashier_session", Want: 200,
+			Body: `{"transactions": [
+				{"receipt_number": "OFF-$run-1", "status": "completed", "total_amount": 16000, "created_at": "$yesterday 12:00:00", "items": [{"product_id": $product, "quantity": 2, "subtotal": 16000}]},
+				{"receipt_number": "OFF-$run-2", "status": "completed", "total_amount": 1000, "created_at": "$yesterday 12:05:00", "items": [{"product_id": $product, "quantity": 1, "subtotal": 8000}]}
+			]}`,
+			Expect: map[string]interface{}{"data.0.result": "created", "data.1.result": "rejected"}},
+		{Method: "GET", Path: "/api/product/$product", Want: 200, Expect: map[string]interface{}{"data.stock": 8}},
+		{Method: "POST", Path: "/api/sync/transactions", Session: "cashier_session", Want: 200,
+			Body:   `{"transactions": [{"receipt_number": "OFF-$run-1", "status": "completed", "total_amount": 16000, "created_at": "$yesterday 12:00:00", "items": [{"product_id": $product, "quantity": 2, "subtotal": 16000}]}]}`,
+			Expect: map[string]interface{}{"data.0.result": "duplicate"}},
+		{Method: "POST", Path: "/api/sync/transactions", Session: "cashier_session", Want: 200,
+			Body:   `{"transactions": [{"receipt_number": "OFF-$run-1", "status": "completed", "total_amount": 8000, "created_at": "$yesterday 12:00:00", "items": [{"product_id": $product, "quantity": 1, "subtotal": 8000}]}]}`,
+			Expect: map[string]interface{}{"data.0.result": "conflict"}},
+		{Method: "POST", Path: "/api/sync/transactions", Session: "cashier_session", Body: `{"transactions": []}`, Want: 400},
+	}}
+}
+
+// storeScenario covers branches, their own prices, and sales and orders
+// priced at the branch they are made at
+func storeScenario() scenario {
+	return scenario{Name: "stores", Steps: []step{
+		{Method: "POST", Path: "/api/stores", Body: `{"name": ""}`, Want: 400},
+		{Method: "POST", Path: "/api/stores", Body: `{"name": "Cabang $run", "address": "Jl. Braga 10, Bandung"}`, Want: 201,
+			Save: map[string]string{"store": "data.id"}},
+		{Method: "PUT", Path: "/api/stores/$store", Body: `{"name": "Cabang Braga $run", "address": "Jl. Braga 10, Bandung"}`, Want: 200},
+		{Method: "GET", Path: "/api/stores", Want: 200},
+
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Iced tea $run", "price": 10000, "cost_price": 4000, "stock": 20, "category_ids": []}`, Want: 201,
+			Save: map[string]string{"product": "data.id"}},
+		{Method: "PUT", Path: "/api/stores/$store/prices", Body: `{"product_id": $product, "price": 9000}`, Want: 200},
+		{Method: "GET", Path: "/api/stores/$store/prices", Want: 200, Expect: map[string]interface{}{"data.0.price": 9000}},
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 1}], "store_id": $store}`, Want: 200,
+			Expect: map[string]interface{}{"data.total_amount": 9000}},
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 1}]}`, Want: 200,
+			Expect: map[string]interface{}{"data.total_amount": 10000}},
+
+		// items added to an order later are priced at the order's branch too
+		{Method: "POST", Path: "/api/orders", Body: `{"table_number": "S-$run", "store_id": $store, "items": [{"product_id": $product, "quantity": 1}]}`, Want: 201,
+			Expect: map[string]interface{}{"data.total_amount": 9000},
+			Save:   map[string]string{"order": "data.id"}},
+		{Method: "POST", Path: "/api/orders/$order/items", Body: `{"items": [{"product_id": $product, "quantity": 1}]}`, Want: 200,
+			Expect: map[string]interface{}{"data.total_amount": 18000}},
+		{Method: "PUT", Path: "/api/transactions/$order/status", Body: `{"status": "voided", "reason": "integration"}`, Want: 200},
+
+		{Method: "DELETE", Path: "/api/stores/$store/prices?product_id=$product", Want: 200},
+		{Method: "DELETE", Path: "/api/stores/$store", Want: 200},
+	}}
+}
+
+// priceContractScenario covers the prices agreed with a customer and the
+// checkouts they apply to
+func priceContractScenario() scenario {
+	return scenario{Name: "price-contracts", Steps: []step{
+		{Method: "POST", Path: "/api/customer", Body: `{"name": "Warung Maju $run", "phone": "0818$phone"}`, Want: 201,
+			Save: map[string]string{"customer": "data.id"}},
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Cooking oil $run", "price": 10000, "cost_price": 6000, "stock": 20, "category_ids": []}`, Want: 201,
+			Save: map[string]string{"product": "data.id"}},
+
+		{Method: "POST", Path: "/api/price-contracts", Want: 400,
+			Body: `{"customer_id": $customer, "name": "Grosir $run", "valid_from": "$today", "valid_until": "$yesterday", "items": [{"product_id": $product, "price": 8000}]}`},
+		{Method: "POST", Path: "/api/price-contracts", Want: 400,
+			Body: `{"customer_id": $customer, "name": "Grosir $run", "valid_from": "$today", "items": [{"product_id": $product, "price": 8000}, {"product_id": $product, "price": 7000}]}`},
+		{Method: "POST", Path: "/api/price-contracts", Body: `{"customer_id": $customer, "name": "Grosir $run", "valid_from": "$today", "items": []}`, Want: 400},
+		{Method: "POST", Path: "/api/price-contracts", Want: 201,
+			Body: `{"customer_id": $customer, "name": "Grosir $run", "valid_from": "$today", "items": [{"product_id": $product, "price": 8000}]}`,
+			Save: map[string]string{"contract": "data.id"}},
+		{Method: "GET", Path: "/api/price-contracts/$contract", Want: 200},
+		{Method: "GET", Path: "/api/price-contracts?customer_id=$customer", Want: 200, Expect: map[string]interface{}{"data.0.id": "$contract"}},
+
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 2}], "customer_id": $customer}`, Want: 200,
+			Expect: map[string]interface{}{"data.total_amount": 16000, "data.details.0.price_contract.id": "$contract"}},
+		{Method: "PUT", Path: "/api/price-contracts/$contract", Want: 200,
+			Body: `{"customer_id": $customer, "name": "Grosir $run", "valid_from": "$today", "items": [{"product_id": $product, "price": 9000}]}`},
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 2}], "customer_id": $customer}`, Want: 200,
+			Expect: map[string]interface{}{"data.total_amount": 18000}},
+		{Method: "GET", Path: "/api/report/price-contracts?start_date=$today&end_date=$today", Want: 200},
+
+		{Method: "DELETE", Path: "/api/price-contracts/$contract", Want: 200},
+		{Method: "GET", Path: "/api/price-contracts/$contract", Want: 404},
+	}}
+}
+
+// currencyScenario covers the exchange rates of accepted currencies and
+// paying cash in one of them
+func currencyScenario() scenario {
+	return scenario{Name: "currencies", Steps: []step{
+		{Method: "PUT", Path: "/api/currencies/IDR", Body: `{"rate": 1}`, Want: 400},
+		{Method: "PUT", Path: "/api/currencies/XYZ", Body: `{"rate": 16000}`, Want: 400},
+		{Method: "PUT", Path: "/api/currencies/USD", Body: `{"rate": 0}`, Want: 400},
+		{Method: "PUT", Path: "/api/currencies/USD", Body: `{"fetched": true}`, Want: 400},
+		{Method: "PUT", Path: "/api/currencies/USD", Body: `{"rate": 16000}`, Want: 200},
+		{Method: "GET", Path: "/api/currencies", Session: "cashier_session", Want: 200},
+
+		// USD is in cents, so 100 is a dollar
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Postcard $run", "price": 10000, "cost_price": 4000, "stock": 10, "category_ids": []}`, Want: 201,
+			Save: map[string]string{"product": "data.id"}},
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 1}], "payment": {"currency": "USD", "amount": 100}}`, Want: 200,
+			Expect: map[string]interface{}{
+				"data.payment.base_amount":            16000,
+				"data.payment.change":                 6000,
+				"data.payment.tendered.currency.code": "USD",
+			}},
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 1}], "payment": {"currency": "USD", "amount": 50}}`, Want: 400,
+			Expect: map[string]interface{}{"error_code": "insufficient_payment"}},
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 1}], "payment": {"currency": "EUR", "amount": 100}}`, Want: 400,
+			Expect: map[string]interface{}{"error_code": "currency_not_accepted"}},
+
+		{Method: "DELETE", Path: "/api/currencies/USD", Want: 200},
+		{Method: "DELETE", Path: "/api/currencies/USD", Want: 404},
+	}}
+}
+
+// printingScenario covers kitchen printers, the tickets printed on the
+// printer stub for an order, and the kitchen display stream
+func printingScenario() scenario {
+	return scenario{Name: "printing", Steps: []step{
+		{Method: "POST", Path: "/api/category", Body: `{"name": "Kitchen $run"}`, Want: 201, Save: map[string]string{"category": "data.id"}},
+		{Method: "POST", Path: "/api/printers", Body: `{"name": "Dapur $run", "address": "nohost"}`, Want: 400},
+		{Method: "POST", Path: "/api/printers", Body: `{"name": "Dapur $run", "address": "$stub_host:9100", "category_ids": [$category], "active": true}`, Want: 201,
+			Save: map[string]string{"printer": "data.id"}},
+		{Method: "GET", Path: "/api/printers/$printer", Want: 200},
+		{Method: "GET", Path: "/api/printers", Want: 200},
+
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Noodles $run", "price": 12000, "cost_price": 7000, "stock": 10, "category_ids": [$category]}`, Want: 201,
+			Save: map[string]string{"product": "data.id"}},
+		{Method: "POST", Path: "/api/orders", Body: `{"table_number": "K-$run", "items": [{"product_id": $product, "quantity": 1}]}`, Want: 201,
+			Save: map[string]string{"order": "data.id"}},
+		{Method: "GET", Path: "/api/print-jobs?status=printed", Want: 200, Retry: 30 * time.Second,
+			Expect: map[string]interface{}{"data.0.printer_id": "$printer", "data.0.transaction_id": "$order"},
+			Save:   map[string]string{"job": "data.0.id"}},
+		{Method: "POST", Path: "/api/print-jobs/$job/retry", Want: 200},
+		{Method: "GET", Path: "/api/print-jobs?status=bogus", Want: 400},
+		{Method: "GET", Path: "/api/kitchen/orders", Stream: true, Want: 200},
+
+		{Method: "PUT", Path: "/api/printers/$printer", Body: `{"active": false}`, Want: 200},
+		{Method: "PUT", Path: "/api/transactions/$order/status", Body: `{"status": "voided", "reason": "integration"}`, Want: 200},
+		{Method: "DELETE", Path: "/api/printers/$printer", Want: 200},
+		{Method: "GET", Path: "/api/printers/$printer", Want: 404},
+	}}
+}
+
+// displayScenario covers the customer display playlist: uploads, schedules
+// and the ETag a display polls with
+func displayScenario() scenario {
+	return scenario{Name: "display", Steps: []step{
+		{Method: "POST", Path: "/api/admin/display/media", File: &formFile{Field: "file", Name: "promo.txt", Content: "not an image"}, Want: 400},
+		{Method: "POST", Path: "/api/admin/display/media", File: &formFile{Field: "file", Name: "promo.png", Content: pngHeader}, Want: 201,
+			Expect: map[string]interface{}{"data.media_type": "image"},
+			Save:   map[string]string{"media": "data.id"}},
+		{Method: "GET", Path: "/api/admin/display/media", Want: 200},
+		{Method: "GET", Path: "/api/display/media/$media", Session: "cashier_session", Want: 200},
+
+		{Method: "GET", Path: "/api/display/playlist", Session: "cashier_session", Want: 200, Save: map[string]string{"etag": "header.ETag"}},
+		{Method: "GET", Path: "/api/display/playlist", Session: "cashier_session", Header: map[string]string{"If-None-Match": "$etag"}, Want: 304},
+
+		{Method: "PUT", Path: "/api/admin/display/media/$media", Body: `{"title": "Promo $run", "start_time": "25:00", "end_time": "11:00", "active": true}`, Want: 400},
+		{Method: "PUT", Path: "/api/admin/display/media/$media", Body: `{"title": "Promo $run", "duration_seconds": 10, "active": false}`, Want: 200},
+		{Method: "GET", Path: "/api/display/playlist", Session: "cashier_session", Header: map[string]string{"If-None-Match": "$etag"}, Want: 200},
+
+		{Method: "DELETE", Path: "/api/admin/display/media/$media", Want: 200},
+		{Method: "GET", Path: "/api/display/media/$media", Session: "cashier_session", Want: 404},
+	}}
+}
+
+// notificationScenario covers Telegram channels, emailed report
+// subscriptions and web push, which has no VAPID keys here
+func notificationScenario() scenario {
+	return scenario{Name: "notifications", Steps: []step{
+		{Method: "POST", Path: "/api/notification-channels", Body: `{"provider": "whatsapp", "recipient": "0819$phone", "events": ["low_stock"]}`, Want: 400},
+		{Method: "POST", Path: "/api/notification-channels", Body: `{"provider": "telegram", "recipient": "not-a-chat", "events": ["low_stock"]}`, Want: 400},
+		{Method: "POST", Path: "/api/notification-channels", Body: `{"provider": "telegram", "recipient": "-100$run", "events": ["birthday"]}`, Want: 400},
+		{Method: "POST", Path: "/api/notification-channels", Body: `{"provider": "telegram", "recipient": "-100$run", "events": ["daily_summary"]}`, Want: 201,
+			Save: map[string]string{"channel": "data.id"}},
+		{Method: "GET", Path: "/api/notification-channels", Want: 200},
+		{Method: "PUT", Path: "/api/notification-channels/$channel", Body: `{"events": ["daily_summary"], "active": false}`, Want: 200},
+		{Method: "DELETE", Path: "/api/notification-channels/$channel", Want: 200},
+		{Method: "POST", Path: "/api/notification-channels/$channel/test", Want: 404},
+
+		{Method: "POST", Path: "/api/report-subscriptions", Body: `{"email": "owner$run@example.com", "frequency": "monthly", "format": "pdf"}`, Want: 400},
+		{Method: "POST", Path: "/api/report-subscriptions", Body: `{"email": "owner$run@example.com", "frequency": "weekly", "format": "pdf"}`, Want: 201,
+			Save: map[string]string{"subscription": "data.id"}},
+		{Method: "GET", Path: "/api/report-subscriptions", Want: 200},
+		{Method: "DELETE", Path: "/api/report-subscriptions/$subscription", Want: 200},
+
+		{Method: "GET", Path: "/api/push/vapid-public-key", Want: 503},
+		{Method: "POST", Path: "/api/push/subscriptions", Want: 400,
+			Body: `{"endpoint": "http://push.example.com/$run", "keys": {"p256dh": "` + pushKey + `", "auth": "` + pushAuth + `"}}`},
+		{Method: "POST", Path: "/api/push/subscriptions", Want: 201,
+			Body: `{"endpoint": "https://push.example.com/$run", "keys": {"p256dh": "` + pushKey + `", "auth": "` + pushAuth + `"}}`},
+		{Method: "GET", Path: "/api/push/subscriptions", Want: 200},
+		{Method: "DELETE", Path: "/api/push/subscriptions", Body: `{"endpoint": "https://push.example.com/$run"}`, Want: 200},
+		{Method: "POST", Path: "/api/push/test", Want: 503},
+	}}
+}
+
+// adminScenario covers webhooks, jobs, numbering, reliability and the other admin tools
+func adminScenario() scenario {
+	return scenario{Name: "admin", Steps: []step{
+		// a sale is delivered to the webhook receiver stub by a background job
+		{Method: "POST", Path: "/api/webhook", Body: `{"url": "ftp://$stub_host/hooks/$run", "events": ["transaction.created"]}`, Want: 400},
+		{Method: "POST", Path: "/api/webhook", Want: 201,
+			Body: `{"url": "http://$stub_host:8091/hooks/$run", "events": ["transaction.created"], "secret": "integration-secret", "active": true}`,
+			Save: map[string]string{"webhook": "data.id"}},
+		{Method: "GET", Path: "/api/webhook/$webhook", Want: 200},
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Kite $run", "price": 5000, "cost_price": 3000, "stock": 5, "category_ids": []}`, Want: 201,
+			Save: map[string]string{"product": "data.id"}},
+		{Method: "POST", Path: "/api/checkout", Body: `{"items": [{"product_id": $product, "quantity": 1}]}`, Want: 200},
+		{Method: "GET", Path: "/api/webhook/$webhook/deliveries", Want: 200, Retry: 30 * time.Second,
+			Expect: map[string]interface{}{"data.0.status": "delivered", "data.0.response_code": 204}},
+		{Method: "PUT", Path: "/api/webhook/$webhook", Body: `{"active": false}`, Want: 200},
+		{Method: "GET", Path: "/api/webhook", Want: 200},
+		{Method: "DELETE", Path: "/api/webhook/$webhook", Want: 200},
+		{Method: "GET", Path: "/api/webhook/$webhook", Want: 404},
+
+		// the delivery ran as a job, which can't be retried once done
+		{Method: "GET", Path: "/api/jobs?status=done", Want: 200, Retry: 30 * time.Second, Save: map[string]string{"job": "data.0.id"}},
+		{Method: "GET", Path: "/api/jobs/$job", Want: 200, Expect: map[string]interface{}{"data.status": "done"}},
+		{Method: "POST", Path: "/api/jobs/$job/retry", Want: 409},
+		{Method: "GET", Path: "/api/jobs/999999999", Want: 404},
+		{Method: "GET", Path: "/api/jobs", Want: 200},
+
+		// a receipt was issued in the sales scenario, so going back to 1 is refused
+		{Method: "GET", Path: "/api/admin/numbering", Want: 200, Expect: map[string]interface{}{"data.0.name": "receipt"}},
+		{Method: "PUT", Path: "/api/admin/numbering/receipt", Body: `{"next_value": 1, "reason": "integration"}`, Want: 409},
+		{Method: "PUT", Path: "/api/admin/numbering/sku", Body: `{"next_value": $epoch, "reason": "integration"}`, Want: 200},
+		{Method: "GET", Path: "/api/admin/numbering/adjustments", Want: 200, Expect: map[string]interface{}{"data.0.sequence": "sku"}},
+
+		{Method: "POST", Path: "/api/report/aggregate?date=$yesterday", Want: 200},
+		{Method: "GET", Path: "/api/admin/reliability?days=7", Want: 200},
+		{Method: "GET", Path: "/api/admin/selftest", Want: 200},
+		{Method: "GET", Path: "/api/admin/deprecations", Want: 200},
+		{Method: "POST", Path: "/api/dunning/run", Want: 200},
+
+		// REQUEST_JOURNAL is on, and with no REQUEST_JOURNAL_REPLAY_URL an
+		// entry can't be replayed
+		{Method: "GET", Path: "/api/request-journal?path=/api/checkout", Want: 200, Retry: 10 * time.Second,
+			Save: map[string]string{"entry": "data.0.id"}},
+		{Method: "GET", Path: "/api/request-journal/$entry", Want: 200, Expect: map[string]interface{}{"data.path": "/api/checkout"}},
+		{Method: "POST", Path: "/api/request-journal/$entry/replay", Want: 503},
+		{Method: "GET", Path: "/api/request-journal", Want: 200},
+
+		{Method: "GET", Path: "/api/admin/load-test/checkout?count=3&max_items=2", Want: 200},
+		{Method: "GET", Path: "/api/admin/load-test/checkout?count=3&format=k6", Want: 200},
+		{Method: "GET", Path: "/api/admin/load-test/checkout?count=20000", Want: 400},
+
+		{Method: "POST", Path: "/api/admin/maintenance-windows", Body: `{"name": "Backup $run", "days": [0], "start": "03:00", "end": "03:00"}`, Want: 400},
+		{Method: "POST", Path: "/api/admin/maintenance-windows", Body: `{"name": "Backup $run", "days": [0], "start": "03:00", "end": "03:30"}`, Want: 201,
+			Save: map[string]string{"window": "data.id"}},
+		{Method: "GET", Path: "/api/admin/maintenance-windows", Want: 200},
+		{Method: "DELETE", Path: "/api/admin/maintenance-windows/$window", Want: 200},
+		{Method: "DELETE", Path: "/api/admin/maintenance-windows/$window", Want: 404},
+
+		{Method: "POST", Path: "/api/admin/receipt-templates/preview", Body: `{"template": {"header": "Toko $run", "footer": "Terima kasih"}}`, Want: 200},
+		{Method: "POST", Path: "/api/admin/receipt-templates/preview", Body: `{"template": {"logo_url": "javascript:alert(1)"}}`, Want: 400},
+		{Method: "POST", Path: "/api/admin/receipt-templates", Body: `{"header": "Toko $run", "footer": "Terima kasih", "note": "integration"}`, Want: 201,
+			Save: map[string]string{"template": "data.id"}},
+		{Method: "GET", Path: "/api/admin/receipt-templates", Want: 200, Expect: map[string]interface{}{"data.0.id": "$template"}},
+		{Method: "POST", Path: "/api/admin/receipt-templates/$template/activate", Want: 200},
+		{Method: "POST", Path: "/api/admin/receipt-templates/999999999/activate", Want: 404},
+
+		{Method: "POST", Path: "/api/admin/recycle-bin/purge?dry_run=true", Want: 200},
+		{Method: "GET", Path: "/api/admin/recycle-bin/log", Want: 200},
+	}}
+}
+
+// periodScenario covers closing last month's books, the changes refused or
+// deferred while it is closed, and reopening it. It runs last, as the other
+// scenarios don't expect a closed month.
+func periodScenario() scenario {
+	return scenario{Name: "periods", Steps: []step{
+		{Method: "POST", Path: "/api/admin/periods/$month/close", Body: `{"note": "integration"}`, Want: 400},
+		{Method: "POST", Path: "/api/admin/periods/2026-13/close", Body: `{"note": "integration"}`, Want: 400},
+		{Method: "POST", Path: "/api/admin/periods/$last_month/close", Body: `{"note": "integration $run"}`, Want: 201},
+		{Method: "POST", Path: "/api/admin/periods/$last_month/close", Body: `{"note": "integration $run"}`, Want: 409},
+		{Method: "GET", Path: "/api/admin/periods", Want: 200, Expect: map[string]interface{}{"data.0.month": "$last_month"}},
+
+		{Method: "POST", Path: "/api/expenses", Body: `{"amount": 25000, "category": "supplies", "description": "late $run", "spent_on": "$last_month-15"}`, Want: 409},
+		{Method: "POST", Path: "/api/product", Body: `{"name": "Ledger $run", "price": 8000, "cost_price": 5000, "stock": 10, "category_ids": []}`, Want: 201,
+			Save: map[string]string{"product": "data.id"}},
+		{Method: "POST", Path: "/api/sync/transactions", Session: "cashier_session", Want: 200,
+			Body:   `{"transactions": [{"receipt_number": "LATE-$run", "status": "completed", "total_amount": 8000, "created_at": "$last_month-15 12:00:00", "items": [{"product_id": $product, "quantity": 1, "subtotal": 8000}]}]}`,
+			Expect: map[string]interface{}{"data.0.result": "deferred"}},
+
+		{Method: "POST", Path: "/api/admin/periods/$last_month/reopen", Body: `{}`, Want: 400},
+		{Method: "POST", Path: "/api/admin/periods/$last_month/reopen", Body: `{"reason": "late offline sale"}`, Want: 200},
+		{Method: "POST", Path: "/api/admin/periods/$last_month/reopen", Body: `{"reason": "late offline sale"}`, Want: 409},
+		{Method: "POST", Path: "/api/sync/transactions", Session: "cashier_session", Want: 200,
+			Body:   `{"transactions": [{"receipt_number": "LATE-$run", "status": "completed", "total_amount": 8000, "created_at": "$last_month-15 12:00:00", "items": [{"product_id": $product, "quantity": 1, "subtotal": 8000}]}]}`,
+			Expect: map[string]interface{}{"data.0.result": "created"}},
+		{Method: "GET", Path: "/api/admin/periods/events", Want: 200, Expect: map[string]interface{}{"data.0.action": "reopen"}},
+	}}
+}
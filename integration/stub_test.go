@@ -0,0 +1,76 @@
+//go:build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+
+	"kasir-api/payment"
+)
+
+// the ports of the stubs standing in for the services the server calls out
+// to: the payment gateway and webhook receivers over HTTP, and a kitchen
+// printer
+const (
+	stubHTTPPort    = "8091"
+	stubPrinterPort = "9100"
+)
+
+// stubHost is the host the server reaches the stubs on, STUB_HOST or
+// localhost when it runs on the same machine
+func stubHost() string {
+	if host := os.Getenv("STUB_HOST"); host != "" {
+		return host
+	}
+	return "localhost"
+}
+
+// startStubs serves the stubs for the rest of the run. The gateway answers
+// POST /payment-links with a payment page named after the reference, the
+// receivers take any POST to /hooks/, and the printer swallows whatever it is
+// sent.
+func startStubs() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /payment-links", func(w http.ResponseWriter, r *http.Request) {
+		var link payment.LinkRequest
+		if err := json.NewDecoder(r.Body).Decode(&link); err != nil || link.Reference == "" {
+			http.Error(w, "invalid link request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"url": "https://pay.example.com/" + link.Reference})
+	})
+	mux.HandleFunc("POST /hooks/", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	httpListener, err := net.Listen("tcp", ":"+stubHTTPPort)
+	if err != nil {
+		return err
+	}
+	printerListener, err := net.Listen("tcp", ":"+stubPrinterPort)
+	if err != nil {
+		httpListener.Close()
+		return err
+	}
+
+	go http.Serve(httpListener, mux)
+	go func() {
+		for {
+			conn, err := printerListener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(io.Discard, conn)
+			}()
+		}
+	}()
+	return nil
+}
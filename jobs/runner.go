@@ -0,0 +1,299 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"kasir-api/errortracking"
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// HandlerFunc processes one attempt of a job. Returning an error schedules a
+// retry with exponential backoff until the job runs out of attempts.
+type HandlerFunc func(ctx context.Context, job models.Job) error
+
+// Windows tells when heavy work may run
+type Windows interface {
+	// NextOpen returns t when work may run at t, else when it next may
+	NextOpen(t time.Time) (time.Time, error)
+}
+
+type scheduledJob struct {
+	name     string
+	schedule Schedule
+	jobType  string
+}
+
+// Runner executes jobs from the persistent job table with a fixed pool of
+// workers and enqueues cron-style scheduled jobs
+type Runner struct {
+	repo         *repositories.JobRepository
+	handlers     map[string]HandlerFunc
+	onFailure    []func(job models.Job, err error)
+	schedules    []scheduledJob
+	windows      Windows
+	windowed     map[string]bool
+	workers      int
+	maxAttempts  int
+	pollInterval time.Duration
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	staleAfter   time.Duration
+
+	wake   chan struct{}
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewRunner(repo *repositories.JobRepository, workers int) *Runner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runner{
+		repo:         repo,
+		handlers:     map[string]HandlerFunc{},
+		windowed:     map[string]bool{},
+		workers:      workers,
+		maxAttempts:  5,
+		pollInterval: 5 * time.Second,
+		backoff:      10 * time.Second,
+		maxBackoff:   time.Hour,
+		staleAfter:   15 * time.Minute,
+		wake:         make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// OnFailure adds a hook called when a job has failed its last attempt.
+// Hooks must be added before Start.
+func (r *Runner) OnFailure(hook func(job models.Job, err error)) {
+	r.onFailure = append(r.onFailure, hook)
+}
+
+// Register sets the handler of a job type. Handlers must be registered before Start.
+func (r *Runner) Register(jobType string, handler HandlerFunc) {
+	r.handlers[jobType] = handler
+}
+
+// Schedule enqueues a job of jobType every minute matching the cron expression spec
+func (r *Runner) Schedule(name, spec, jobType string) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	r.schedules = append(r.schedules, scheduledJob{name: name, schedule: schedule, jobType: jobType})
+	return nil
+}
+
+// DeferToWindows holds scheduled runs of jobTypes that fall outside of
+// windows until the next one opens. Jobs enqueued by hand still run right
+// away. Must be called before Start.
+func (r *Runner) DeferToWindows(windows Windows, jobTypes ...string) {
+	r.windows = windows
+	for _, jobType := range jobTypes {
+		r.windowed[jobType] = true
+	}
+}
+
+// Enqueue persists a job to be run as soon as a worker is free
+func (r *Runner) Enqueue(jobType string, payload interface{}) (models.Job, error) {
+	return r.enqueue(jobType, payload, "", time.Time{})
+}
+
+// enqueue persists a job to be run from runAt, right away when zero
+func (r *Runner) enqueue(jobType string, payload interface{}, uniqueKey string, runAt time.Time) (models.Job, error) {
+	if _, ok := r.handlers[jobType]; !ok {
+		return models.Job{}, fmt.Errorf("no handler registered for job type %q", jobType)
+	}
+
+	data := []byte("{}")
+	if payload != nil {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			return models.Job{}, err
+		}
+	}
+
+	job, err := r.repo.Create(jobType, data, r.maxAttempts, uniqueKey, runAt)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Start releases jobs orphaned by a previous crash and starts the workers and scheduler
+func (r *Runner) Start() {
+	if released, err := r.repo.ReleaseStale(time.Now().Add(-r.staleAfter)); err != nil {
+		log.Println("Error releasing stale jobs:", err)
+	} else if released > 0 {
+		log.Printf("Released %d stale jobs", released)
+	}
+
+	for i := 0; i < r.workers; i++ {
+		r.wg.Add(1)
+		go r.work()
+	}
+
+	r.wg.Add(1)
+	go r.runScheduler()
+}
+
+// Shutdown stops claiming new jobs and waits for running ones to finish. When
+// ctx expires first, running handlers are cancelled and their jobs retried later.
+func (r *Runner) Shutdown(ctx context.Context) error {
+	close(r.stop)
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		r.cancel()
+		return nil
+	case <-ctx.Done():
+		r.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (r *Runner) work() {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+
+		job, err := r.repo.Claim()
+		if err == sql.ErrNoRows {
+			select {
+			case <-r.stop:
+				return
+			case <-r.wake:
+			case <-time.After(r.pollInterval):
+			}
+			continue
+		}
+		if err != nil {
+			log.Println("Error claiming job:", err)
+			select {
+			case <-r.stop:
+				return
+			case <-time.After(r.pollInterval):
+			}
+			continue
+		}
+
+		r.execute(job)
+	}
+}
+
+func (r *Runner) execute(job models.Job) {
+	err := r.call(job)
+	if err == nil {
+		if err := r.repo.Complete(job.ID); err != nil {
+			log.Println("Error completing job:", err)
+		}
+		return
+	}
+
+	if job.Attempts >= job.MaxAttempts {
+		log.Printf("Job %d (%s) failed after %d attempts: %v", job.ID, job.Type, job.Attempts, err)
+		errortracking.ReportError(err, map[string]string{"job_type": job.Type, "job_id": strconv.Itoa(job.ID)})
+		if err := r.repo.Fail(job.ID, err.Error()); err != nil {
+			log.Println("Error failing job:", err)
+		}
+		for _, hook := range r.onFailure {
+			hook(job, err)
+		}
+		return
+	}
+
+	if err := r.repo.Reschedule(job.ID, err.Error(), time.Now().Add(r.retryDelay(job.Attempts))); err != nil {
+		log.Println("Error rescheduling job:", err)
+	}
+}
+
+func (r *Runner) call(job models.Job) (err error) {
+	handler, ok := r.handlers[job.Type]
+	if !ok {
+		return fmt.Errorf("no handler registered for job type %q", job.Type)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+	return handler(r.ctx, job)
+}
+
+// retryDelay doubles the backoff after every attempt: 10s, 20s, 40s, ... up to maxBackoff
+func (r *Runner) retryDelay(attempts int) time.Duration {
+	delay := r.backoff
+	for i := 1; i < attempts && delay < r.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.maxBackoff {
+		delay = r.maxBackoff
+	}
+	return delay
+}
+
+func (r *Runner) runScheduler() {
+	defer r.wg.Done()
+
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+
+		select {
+		case <-r.stop:
+			return
+		case <-time.After(next.Sub(now)):
+		}
+
+		for _, sj := range r.schedules {
+			if !sj.schedule.Matches(next) {
+				continue
+			}
+			// heavy work outside of a maintenance window waits for the next one
+			var runAt time.Time
+			if r.windowed[sj.jobType] {
+				open, err := r.windows.NextOpen(next)
+				if err != nil {
+					log.Printf("Error reading maintenance windows, running %s now: %v", sj.name, err)
+				} else if open.After(next) {
+					runAt = open
+					log.Printf("Scheduled job %s deferred to the maintenance window at %s", sj.name, open.Format("2006-01-02 15:04"))
+				}
+			}
+
+			// the key keeps several instances from enqueueing the same run twice
+			key := sj.name + "@" + next.Format(time.RFC3339)
+			if _, err := r.enqueue(sj.jobType, nil, key, runAt); err != nil && err != sql.ErrNoRows {
+				log.Printf("Error enqueueing scheduled job %s: %v", sj.name, err)
+			}
+		}
+	}
+}
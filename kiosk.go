@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"kasir-api/database"
+	"kasir-api/docs"
+	"kasir-api/handlers"
+	"kasir-api/middleware"
+	"kasir-api/repositories"
+	"kasir-api/repositories/sqlite"
+	"kasir-api/router"
+	"kasir-api/services"
+	"kasir-api/session"
+	"kasir-api/utils"
+
+	"github.com/spf13/viper"
+	httpSwagger "github.com/swaggo/http-swagger"
+)
+
+// runKiosk serves a single offline terminal from the SQLite file at path
+// (DB_DRIVER=sqlite). Only the sales flow is available: catalog, pricing
+// rules, held carts and checkout. It shares the services and handlers of the
+// full server; features that need PostgreSQL, background jobs or
+// integrations are not registered.
+//
+// With SYNC_DATABASE_URL set the terminal works offline against a central
+// PostgreSQL server: sales are pushed and the catalog pulled every
+// SYNC_INTERVAL while the server is reachable, and the catalog becomes read-only
+// locally. Each terminal needs its own STORE_CODE so receipt numbers stay unique.
+func runKiosk(path, portStr string) {
+	db, err := database.OpenSQLite(path)
+	if err != nil {
+		log.Fatal("Error opening database:", err)
+	}
+	defer db.Close()
+
+	if err := database.MigrateSQLite(db); err != nil {
+		log.Fatal("Error running migrations:", err)
+	}
+	log.Println("Kiosk mode, sales are stored in", path)
+
+	var (
+		categories   repositories.CategoryStore    = sqlite.NewCategoryRepository(db)
+		products     repositories.ProductStore     = sqlite.NewProductRepository(db)
+		pricingRules repositories.PricingRuleStore = sqlite.NewPricingRuleRepository(db)
+		transactions repositories.TransactionStore = repositories.NewTransactionRepository(db)
+	)
+
+	categoryHandler := handlers.NewCategoryHandler(services.NewCategoryService(categories, categoryDeletePolicy()))
+	productHandler := handlers.NewProductHandler(services.NewProductService(products, newSKUNumbering(repositories.NewSequenceRepository(db))))
+	pricingRuleHandler := handlers.NewPricingRuleHandler(services.NewPricingRuleService(pricingRules))
+	transactionService := services.NewTransactionService(transactions, products, pricingRules, newReceiptNumbering(repositories.NewSequenceRepository(db)), nil, nil, nil, nil, services.OpenItemPolicy{}, cashRounding())
+	transactionHandler := handlers.NewTransactionHandler(transactionService)
+
+	sessionTTL := viper.GetDuration("SESSION_TTL")
+	if sessionTTL <= 0 {
+		sessionTTL = 12 * time.Hour
+	}
+	heldCartHandler := handlers.NewHeldCartHandler(services.NewHeldCartService(session.NewMemoryStore(), sessionTTL))
+
+	selfTestChecks := []services.SelfTestCheck{
+		{Name: "database_schema", Critical: true, Run: func(ctx context.Context) error { return database.CheckSQLiteSchema(db) }},
+	}
+	if printerAddr := viper.GetString("RECEIPT_PRINTER_ADDR"); printerAddr != "" {
+		selfTestChecks = append(selfTestChecks, services.SelfTestCheck{Name: "printer", Run: services.ReachableCheck(printerAddr)})
+	}
+	selfTestService := services.NewSelfTestService(selfTestChecks)
+	if report := selfTestService.Run(context.Background()); !report.OK {
+		log.Println("Self-test failed, refusing changes until it passes")
+	}
+	selfTestHandler := handlers.NewSelfTestHandler(selfTestService)
+
+	var syncService *services.SyncService
+	if syncURL := viper.GetString("SYNC_DATABASE_URL"); syncURL != "" {
+		if viper.GetString("STORE_CODE") == "" {
+			log.Fatal("STORE_CODE is required when SYNC_DATABASE_URL is set")
+		}
+		syncInterval := viper.GetDuration("SYNC_INTERVAL")
+		if syncInterval <= 0 {
+			syncInterval = time.Minute
+		}
+
+		// the central server may well be unreachable at startup, so it is not pinged here
+		central, err := database.Open(syncURL)
+		if err != nil {
+			log.Fatal("Error opening central database:", err)
+		}
+		defer central.Close()
+
+		syncService = services.NewSyncService(sqlite.NewSyncRepository(db), repositories.NewSyncRepository(central), syncInterval)
+		syncService.Start()
+		log.Println("Syncing with the central server every", syncInterval)
+	}
+
+	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteJSON(w, http.StatusOK, utils.Response{
+			Status:  "success",
+			Message: "API Running (kiosk)",
+		})
+	})
+	http.HandleFunc("/", httpSwagger.WrapHandler)
+
+	api := router.NewRouter(http.DefaultServeMux)
+	cashier := []router.Role{router.RoleCashier, router.RoleAdmin}
+	admin := []router.Role{router.RoleAdmin}
+
+	diagnosticsHandler := handlers.NewDiagnosticsHandler(services.NewDiagnosticsService(db, time.Now()))
+
+	// with sync enabled the version is the central server's, as of the last pull
+	catalogVersion := middleware.NewCatalogVersion(services.NewCatalogVersion(repositories.NewSequenceRepository(db)), catalogSyncHint)
+	// catalog reads carry an ETag; polling terminals sending it back get 304
+	// Not Modified while nothing changed
+	catalogCache := middleware.NewConditionalGet()
+
+	api.HandleFunc("/api/category/", cashier, catalogVersion.Middleware(catalogCache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if syncService != nil && r.Method != "GET" {
+			utils.WriteJSON(w, http.StatusForbidden, utils.Response{
+				Status:  "failed",
+				Message: "Catalog is managed on the central server while sync is enabled",
+			})
+			return
+		}
+		switch {
+		case r.URL.Path == "/api/category/batch" && r.Method == "POST":
+			categoryHandler.CreateCategoryBatch(w, r)
+		case r.URL.Path == "/api/category/batch" && r.Method == "PUT":
+			categoryHandler.UpdateCategoryBatch(w, r)
+		case r.URL.Path == "/api/category/batch":
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		case r.Method == "GET":
+			categoryHandler.GetCategoryByID(w, r)
+		case r.Method == "PUT":
+			categoryHandler.UpdateCategory(w, r)
+		case r.Method == "DELETE":
+			categoryHandler.DeleteCategory(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	}))).ServeHTTP)
+
+	api.HandleFunc("/api/category", cashier, catalogVersion.Middleware(catalogCache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if syncService != nil && r.Method != "GET" {
+			utils.WriteJSON(w, http.StatusForbidden, utils.Response{
+				Status:  "failed",
+				Message: "Catalog is managed on the central server while sync is enabled",
+			})
+			return
+		}
+		switch r.Method {
+		case "GET":
+			categoryHandler.GetCategories(w, r)
+		case "POST":
+			categoryHandler.CreateCategory(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	}))).ServeHTTP)
+
+	api.HandleFunc("/api/product/", cashier, catalogVersion.Middleware(catalogCache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if syncService != nil && r.Method != "GET" {
+			utils.WriteJSON(w, http.StatusForbidden, utils.Response{
+				Status:  "failed",
+				Message: "Catalog is managed on the central server while sync is enabled",
+			})
+			return
+		}
+		switch {
+		case r.URL.Path == "/api/product/batch" && r.Method == "POST":
+			productHandler.CreateProductBatch(w, r)
+		case r.URL.Path == "/api/product/batch" && r.Method == "PUT":
+			productHandler.UpdateProductBatch(w, r)
+		case r.URL.Path == "/api/product/batch":
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		case strings.HasPrefix(r.URL.Path, "/api/product/sku/") && r.Method == "GET":
+			productHandler.GetProductBySKU(w, r)
+		case strings.HasPrefix(r.URL.Path, "/api/product/sku/"):
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		case strings.HasSuffix(r.URL.Path, "/stock") && r.Method == "POST":
+			productHandler.AdjustStock(w, r)
+		case strings.HasSuffix(r.URL.Path, "/stock"):
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		case strings.HasSuffix(r.URL.Path, "/components") && r.Method == "PUT":
+			productHandler.SetBundleComponents(w, r)
+		case strings.HasSuffix(r.URL.Path, "/components") && r.Method == "DELETE":
+			productHandler.RemoveBundleComponents(w, r)
+		case strings.HasSuffix(r.URL.Path, "/components"):
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		case r.Method == "GET":
+			productHandler.GetProductByID(w, r)
+		case r.Method == "PUT":
+			productHandler.UpdateProduct(w, r)
+		case r.Method == "DELETE":
+			productHandler.DeleteProduct(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	}))).ServeHTTP)
+
+	api.HandleFunc("/api/product", cashier, catalogVersion.Middleware(catalogCache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if syncService != nil && r.Method != "GET" {
+			utils.WriteJSON(w, http.StatusForbidden, utils.Response{
+				Status:  "failed",
+				Message: "Catalog is managed on the central server while sync is enabled",
+			})
+			return
+		}
+		switch r.Method {
+		case "GET":
+			productHandler.GetProducts(w, r)
+		case "POST":
+			productHandler.CreateProduct(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	}))).ServeHTTP)
+
+	api.HandleFunc("/api/pricing-rules", admin, catalogVersion.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if syncService != nil && r.Method != "GET" {
+			utils.WriteJSON(w, http.StatusForbidden, utils.Response{
+				Status:  "failed",
+				Message: "Catalog is managed on the central server while sync is enabled",
+			})
+			return
+		}
+		switch r.Method {
+		case "GET":
+			pricingRuleHandler.GetPricingRules(w, r)
+		case "POST":
+			pricingRuleHandler.CreatePricingRule(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})).ServeHTTP)
+
+	api.HandleFunc("/api/pricing-rules/", admin, catalogVersion.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if syncService != nil && r.Method != "GET" {
+			utils.WriteJSON(w, http.StatusForbidden, utils.Response{
+				Status:  "failed",
+				Message: "Catalog is managed on the central server while sync is enabled",
+			})
+			return
+		}
+		switch r.Method {
+		case "GET":
+			pricingRuleHandler.GetPricingRuleByID(w, r)
+		case "PUT":
+			pricingRuleHandler.UpdatePricingRule(w, r)
+		case "DELETE":
+			pricingRuleHandler.DeletePricingRule(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})).ServeHTTP)
+
+	api.HandleFunc("/api/checkout", cashier, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			transactionHandler.Checkout(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/held-carts", cashier, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			heldCartHandler.GetHeldCarts(w, r)
+		case "POST":
+			heldCartHandler.HoldCart(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/held-carts/", cashier, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/resume") && r.Method == "POST":
+			heldCartHandler.ResumeHeldCart(w, r)
+		case r.Method == "GET":
+			heldCartHandler.GetHeldCartByID(w, r)
+		case r.Method == "DELETE":
+			heldCartHandler.DeleteHeldCart(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/admin/selftest", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			selfTestHandler.GetSelfTest(w, r)
+		case "POST":
+			selfTestHandler.RunSelfTest(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/sync", admin, func(w http.ResponseWriter, r *http.Request) {
+		if syncService == nil {
+			utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+				Status:  "failed",
+				Message: "Sync is not enabled",
+			})
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+		case "POST":
+			if err := syncService.RunOnce(r.Context()); err != nil {
+				log.Println("Error syncing with central server:", err)
+			}
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+			return
+		}
+
+		status, err := syncService.Status()
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+				Status:  "failed",
+				Message: err.Error(),
+			})
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, utils.Response{
+			Status:  "success",
+			Message: "Sync status",
+			Data:    status,
+		})
+	})
+
+	var handler http.Handler = http.DefaultServeMux
+	if viper.GetBool("REQUEST_VALIDATION") {
+		validator, err := middleware.NewRequestValidator([]byte(docs.SwaggerInfo.ReadDoc()))
+		if err != nil {
+			log.Fatal("Error loading OpenAPI document:", err)
+		}
+		handler = validator.Middleware(handler)
+	}
+	handler = middleware.NewSelfTestGuard(selfTestService, "/api/admin/selftest").Middleware(handler)
+	if viper.GetBool("COMPRESSION") {
+		handler = middleware.NewCompression(compressionMinSize()).Middleware(handler)
+	}
+	handler = middleware.NewRecovery().Middleware(handler)
+	handler = middleware.NewDiagnosticsGuard("/debug/").Middleware(handler)
+	handler = middleware.NewRequestID().Middleware(handler)
+	var diagnosticsServer *http.Server
+	if viper.GetBool("DIAGNOSTICS") {
+		diagnosticsServer, err = newDiagnosticsServer(viper.GetString("DIAGNOSTICS_ADDR"), diagnosticsHandler)
+		if err != nil {
+			log.Fatal("Error configuring diagnostics:", err)
+		}
+	}
+
+	server := &http.Server{Addr: ":" + portStr, Handler: handler}
+
+	go func() {
+		fmt.Println("Server running on http://localhost:" + portStr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Error running server:", err)
+		}
+	}()
+	if diagnosticsServer != nil {
+		go func() {
+			log.Printf("Diagnostics: serving /debug/vars and /debug/pprof/ on %s", diagnosticsServer.Addr)
+			if err := diagnosticsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatal("Error running diagnostics server:", err)
+			}
+		}()
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	log.Println("Shutting down...")
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println("Error shutting down server:", err)
+	}
+	if diagnosticsServer != nil {
+		diagnosticsServer.Close()
+	}
+	if syncService != nil {
+		syncService.Stop()
+	}
+}
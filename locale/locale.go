@@ -0,0 +1,140 @@
+package locale
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"kasir-api/money"
+)
+
+// Locale formats amounts and dates the way a store's customers read them,
+// e.g. "Rp15.000" and "31/01/2026" rather than "15000" and "2026-01-31".
+// Amounts are in the minor unit of Currency, so 1550 is Rp1.550 but $15.50.
+type Locale struct {
+	// Currency is the store currency, set apart from the preset
+	Currency money.Currency
+	// ThousandsSeparator groups the digits of amounts, "." in id-ID
+	ThousandsSeparator string
+	// DecimalSeparator comes before the cents of currencies that have them,
+	// "," in id-ID
+	DecimalSeparator string
+	// CurrencyFormat places the amount next to the symbol, e.g.
+	// "{symbol}{amount}", "Rp{amount}" or "{amount} IDR"
+	CurrencyFormat string
+	// DateFormat and DateTimeFormat are Go time layouts
+	DateFormat     string
+	DateTimeFormat string
+}
+
+// Default is the locale used when a store sets none
+const Default = "id-ID"
+
+var presets = map[string]Locale{
+	"id-ID": {
+		ThousandsSeparator: ".",
+		DecimalSeparator:   ",",
+		CurrencyFormat:     "{symbol}{amount}",
+		DateFormat:         "02/01/2006",
+		DateTimeFormat:     "02/01/2006 15.04",
+	},
+	"en-US": {
+		ThousandsSeparator: ",",
+		DecimalSeparator:   ".",
+		CurrencyFormat:     "{symbol}{amount}",
+		DateFormat:         "01/02/2006",
+		DateTimeFormat:     "01/02/2006 3:04 PM",
+	},
+	"en-GB": {
+		ThousandsSeparator: ",",
+		DecimalSeparator:   ".",
+		CurrencyFormat:     "{symbol}{amount}",
+		DateFormat:         "02/01/2006",
+		DateTimeFormat:     "02/01/2006 15:04",
+	},
+}
+
+// Lookup returns the preset named name, such as "id-ID"
+func Lookup(name string) (Locale, error) {
+	l, ok := presets[name]
+	if !ok {
+		return Locale{}, fmt.Errorf("unknown locale %q", name)
+	}
+	return l, nil
+}
+
+// Validate checks that the currency format has a place for the amount
+func (l Locale) Validate() error {
+	if strings.Count(l.CurrencyFormat, "{amount}") != 1 {
+		return fmt.Errorf("currency format %q must contain {amount} exactly once", l.CurrencyFormat)
+	}
+	return nil
+}
+
+// Number groups the digits of n, e.g. 15000 as "15.000"
+func (l Locale) Number(n int) string {
+	digits := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(l.ThousandsSeparator)
+		}
+		b.WriteRune(d)
+	}
+	return sign + b.String()
+}
+
+// Money formats n minor units of the currency with its symbol, the sign in
+// front: "-Rp1.500", "$1,234.50"
+func (l Locale) Money(n money.Amount) string {
+	m := n.In(l.Currency)
+	whole, fraction := m.Whole()
+	amount := l.Number(int(whole))
+	if l.Currency.Digits > 0 {
+		amount += fmt.Sprintf("%s%0*d", l.DecimalSeparator, l.Currency.Digits, fraction)
+	}
+
+	sign := ""
+	if n < 0 {
+		sign = "-"
+	}
+	return sign + strings.NewReplacer("{amount}", amount, "{symbol}", l.Currency.Symbol).Replace(l.CurrencyFormat)
+}
+
+// MoneyIn formats n minor units of the currency with ISO 4217 code, as Money
+// for the store currency and as "USD 15.50" for any other
+func (l Locale) MoneyIn(code string, n money.Amount) string {
+	if code == l.Currency.Code {
+		return l.Money(n)
+	}
+	c, err := money.Lookup(code)
+	if err != nil {
+		return fmt.Sprintf("%s %d", code, n)
+	}
+	return n.In(c).String()
+}
+
+// Date reformats a "2006-01-02" date; anything else is returned unchanged
+func (l Locale) Date(s string) string {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return s
+	}
+	return t.Format(l.DateFormat)
+}
+
+// DateTime reformats a "2006-01-02 15:04:05" timestamp, as the repositories
+// render them; anything else is returned unchanged
+func (l Locale) DateTime(s string) string {
+	t, err := time.Parse("2006-01-02 15:04:05", s)
+	if err != nil {
+		return s
+	}
+	return t.Format(l.DateTimeFormat)
+}
@@ -0,0 +1,127 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+)
+
+// Message is a single email
+type Message struct {
+	To          string       `json:"to"`
+	Subject     string       `json:"subject"`
+	HTML        string       `json:"html"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Attachment is a file sent along with a message
+type Attachment struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Content     []byte `json:"content"`
+}
+
+// Mailer delivers email messages
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// SMTPMailer sends email through an SMTP server using PLAIN auth
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{Host: host, Port: port, Username: username, Password: password, From: from}
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	headers := []string{
+		"From: " + m.From,
+		"To: " + msg.To,
+		"Subject: " + msg.Subject,
+		"MIME-Version: 1.0",
+	}
+	contentType, content := "text/html; charset=UTF-8", msg.HTML
+	if len(msg.Attachments) > 0 {
+		var err error
+		if contentType, content, err = multipartBody(msg); err != nil {
+			return fmt.Errorf("error sending email: %v", err)
+		}
+	}
+	headers = append(headers, "Content-Type: "+contentType)
+	body := strings.Join(headers, "\r\n") + "\r\n\r\n" + content
+
+	if err := smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{msg.To}, []byte(body)); err != nil {
+		return fmt.Errorf("error sending email: %v", err)
+	}
+	return nil
+}
+
+// multipartBody returns the content type and body of a message with
+// attachments: the HTML first, then each file base64 encoded
+func multipartBody(msg Message) (string, string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/html; charset=UTF-8"}})
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := part.Write([]byte(msg.HTML)); err != nil {
+		return "", "", err
+	}
+
+	for _, a := range msg.Attachments {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", a.Name)},
+		})
+		if err != nil {
+			return "", "", err
+		}
+		// encoded lines are kept within the 76 characters MIME allows
+		encoded := base64.StdEncoding.EncodeToString(a.Content)
+		for len(encoded) > 76 {
+			if _, err := part.Write([]byte(encoded[:76] + "\r\n")); err != nil {
+				return "", "", err
+			}
+			encoded = encoded[76:]
+		}
+		if _, err := part.Write([]byte(encoded)); err != nil {
+			return "", "", err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return "", "", err
+	}
+	return "multipart/mixed; boundary=" + mw.Boundary(), buf.String(), nil
+}
+
+// LogMailer only logs messages, used when SMTP is not configured
+type LogMailer struct{}
+
+func (LogMailer) Send(msg Message) error {
+	if len(msg.Attachments) > 0 {
+		log.Printf("[email] to %s: %s (%d attachments)", msg.To, msg.Subject, len(msg.Attachments))
+		return nil
+	}
+	log.Printf("[email] to %s: %s", msg.To, msg.Subject)
+	return nil
+}
@@ -1,16 +1,41 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"kasir-api/database"
 	"kasir-api/docs"
+	"kasir-api/errortracking"
+	"kasir-api/events"
 	"kasir-api/handlers"
+	"kasir-api/jobs"
+	"kasir-api/locale"
+	"kasir-api/mailer"
+	"kasir-api/metrics"
+	"kasir-api/middleware"
+	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/notifier"
+	"kasir-api/payment"
 	"kasir-api/repositories"
+	"kasir-api/router"
+	"kasir-api/sequence"
 	"kasir-api/services"
+	"kasir-api/session"
+	"kasir-api/storage"
+	"kasir-api/tracing"
 	"kasir-api/utils"
+	"kasir-api/webpush"
 
 	_ "github.com/lib/pq"
 	"github.com/spf13/viper"
@@ -19,13 +44,28 @@ import (
 
 // @title           Kasir API
 // @version         1.0
-// @description     This is a sample server for a Cashier System.
+// @description     This is a sample server for a Cashier System. Amounts are integers in the minor unit of the store currency, STORE_CURRENCY (IDR unless set): whole rupiah for IDR, cents for USD.
 // @BasePath        /api
 
+//go:generate swag init -g main.go -o docs --parseDependency
+
 func main() {
 	// load .env using viper
 	viper.SetConfigFile(".env")
 	viper.AutomaticEnv() // read value from system env too
+	viper.SetDefault("REQUEST_VALIDATION", true)
+	viper.SetDefault("COMPRESSION", true)
+	viper.SetDefault("DB_PREPARED_STATEMENTS", true)
+	viper.SetDefault("CATEGORY_DELETE_POLICY", repositories.CategoryDeleteBlock)
+	viper.SetDefault("CASH_ROUNDING", money.RoundNone)
+	viper.SetDefault("CASH_ROUNDING_UNIT", 100)
+	viper.SetDefault("STORE_TIMEZONE", "Asia/Jakarta")
+	viper.SetDefault("ACCOUNT_SALES", "4-1000")
+	viper.SetDefault("ACCOUNT_TAX", "2-1300")
+	viper.SetDefault("ACCOUNT_CASH", "1-1100")
+	viper.SetDefault("ACCOUNT_VOUCHER", "2-1400")
+	viper.SetDefault("ACCOUNT_PAYMENT_LINK", "1-1200")
+	viper.SetDefault("ACCOUNT_INSTALLMENT", "1-1300")
 
 	if err := viper.ReadInConfig(); err != nil {
 		log.Println("No .env file found, using system environment variables")
@@ -49,6 +89,36 @@ func main() {
 	}
 	log.Println("Swagger Host set to:", docs.SwaggerInfo.Host)
 
+	// before anything can fail, kiosk installs included
+	setErrorTracker()
+
+	// hot queries are prepared once; turn off behind PgBouncer in transaction mode
+	repositories.UsePreparedStatements(viper.GetBool("DB_PREPARED_STATEMENTS"))
+
+	// kiosk installs keep the sales flow in a local SQLite file, see kiosk.go
+	if viper.GetString("DB_DRIVER") == "sqlite" {
+		runKiosk(viper.GetString("DATABASE_URL"), portStr)
+		return
+	}
+
+	// traces go to an OpenTelemetry collector or Jaeger, e.g. http://jaeger:4318;
+	// the tracer is set before connecting, so SQL statements are traced too
+	var traceExporter *tracing.OTLPExporter
+	if otlpEndpoint := viper.GetString("OTEL_EXPORTER_OTLP_ENDPOINT"); otlpEndpoint != "" {
+		serviceName := viper.GetString("OTEL_SERVICE_NAME")
+		if serviceName == "" {
+			serviceName = "kasir-api"
+		}
+		sampleRatio := 1.0
+		if viper.IsSet("OTEL_TRACES_SAMPLER_ARG") {
+			sampleRatio = viper.GetFloat64("OTEL_TRACES_SAMPLER_ARG")
+		}
+		traceExporter = tracing.NewOTLPExporter(otlpEndpoint, serviceName)
+		traceExporter.Start()
+		tracing.SetTracer(tracing.NewTracer(traceExporter, sampleRatio))
+		log.Println("Tracing to", otlpEndpoint, "sampling", sampleRatio)
+	}
+
 	// connect to DB
 	dbConnStr := viper.GetString("DATABASE_URL")
 	db, err := database.Connect(dbConnStr)
@@ -59,6 +129,344 @@ func main() {
 
 	fmt.Println("Successfully connected to database!")
 
+	if err := database.Migrate(db); err != nil {
+		log.Fatal("Error running migrations:", err)
+	}
+
+	// held carts live in the session store; use the redis driver when running more than one instance
+	sessionStore, err := session.Open(viper.GetString("SESSION_DRIVER"), viper.GetString("REDIS_URL"))
+	if err != nil {
+		log.Fatal("Error opening session store:", err)
+	}
+	defer sessionStore.Close()
+
+	sessionTTL := viper.GetDuration("SESSION_TTL")
+	if sessionTTL <= 0 {
+		sessionTTL = 12 * time.Hour
+	}
+	heldCartService := services.NewHeldCartService(sessionStore, sessionTTL)
+
+	// cashiers switch into a terminal with the PIN of their account; the
+	// sessions and the wrong PIN counts live in the session store
+	terminalSessionTTL := viper.GetDuration("TERMINAL_SESSION_TTL")
+	if terminalSessionTTL <= 0 {
+		terminalSessionTTL = 8 * time.Hour
+	}
+	pinMaxAttempts := viper.GetInt("PIN_MAX_ATTEMPTS")
+	if pinMaxAttempts <= 0 {
+		pinMaxAttempts = 5
+	}
+	pinLockout := viper.GetDuration("PIN_LOCKOUT")
+	if pinLockout <= 0 {
+		pinLockout = 15 * time.Minute
+	}
+	terminalSessionService := services.NewTerminalSessionService(repositories.NewAdminUserRepository(db), sessionStore, terminalSessionTTL, pinMaxAttempts, pinLockout)
+	// requests the signed in cashier's role isn't permitted are refused only
+	// with ENFORCE_PERMISSIONS on, so terminals can move to PIN sign-in first
+	enforcePermissions := viper.GetBool("ENFORCE_PERMISSIONS")
+	permissionService := services.NewPermissionService(repositories.NewPermissionRepository(db), repositories.NewAdminUserRepository(db), terminalSessionService, enforcePermissions)
+
+	// background jobs (emails, webhooks, scheduled work) are persisted in the job table
+	jobWorkers := viper.GetInt("JOB_WORKERS")
+	if jobWorkers <= 0 {
+		jobWorkers = 4
+	}
+	jobRunner := jobs.NewRunner(repositories.NewJobRepository(db), jobWorkers)
+
+	// critical alerts are pushed to subscribed admin browsers when VAPID keys are configured, otherwise only logged
+	var pushClient *webpush.Client
+	if vapidKey := viper.GetString("VAPID_PRIVATE_KEY"); vapidKey != "" {
+		vapidSubject := viper.GetString("VAPID_SUBJECT")
+		if vapidSubject == "" {
+			vapidSubject = "mailto:admin@localhost"
+		}
+		pushClient, err = webpush.NewClient(vapidKey, vapidSubject)
+		if err != nil {
+			log.Fatal("Error configuring web push:", err)
+		}
+	} else if privateKey, _, err := webpush.GenerateVAPIDKeys(); err == nil {
+		log.Println("Web push disabled, set VAPID_PRIVATE_KEY to enable it, e.g. to a newly generated key:", privateKey)
+	}
+	pushService := services.NewPushService(repositories.NewPushRepository(db), pushClient, jobRunner)
+
+	// kasbon reminders, each channel goes through its gateway or is only logged
+	reminderSenders := map[string]notifier.Sender{}
+	for _, channel := range []string{"whatsapp", "sms", "email"} {
+		prefix := strings.ToUpper(channel)
+		if gatewayURL := viper.GetString(prefix + "_GATEWAY_URL"); gatewayURL != "" {
+			reminderSenders[channel] = notifier.NewGatewaySender(gatewayURL, viper.GetString(prefix+"_GATEWAY_TOKEN"))
+		} else {
+			reminderSenders[channel] = notifier.LogSender{Channel: channel}
+		}
+	}
+
+	cadenceDays := viper.GetInt("DUNNING_CADENCE_DAYS")
+	if cadenceDays <= 0 {
+		cadenceDays = 3
+	}
+	dunningSchedule := viper.GetString("DUNNING_SCHEDULE")
+	if dunningSchedule == "" {
+		dunningSchedule = "0 * * * *"
+	}
+
+	dunningService := services.NewDunningService(repositories.NewDunningRepository(db), reminderSenders, cadenceDays, jobRunner)
+	if err := jobRunner.Schedule("dunning", dunningSchedule, services.JobDunningReminders); err != nil {
+		log.Fatal("Error scheduling kasbon reminders:", err)
+	}
+
+	sequenceRepo := repositories.NewSequenceRepository(db)
+	receiptNumbering := newReceiptNumbering(sequenceRepo)
+	skuNumbering := newSKUNumbering(sequenceRepo)
+	taxInvoiceNumbering := newTaxInvoiceNumbering()
+
+	// customer phone numbers written without a country code get this one
+	phoneCountryCode := strings.TrimPrefix(viper.GetString("PHONE_COUNTRY_CODE"), "+")
+	if phoneCountryCode == "" {
+		phoneCountryCode = "62"
+	}
+
+	// the store profile of /api/settings; until it is first saved the
+	// environment stands in for it
+	settingsService := services.NewSettingsService(repositories.NewSettingsRepository(db), defaultSettings())
+	settings, err := settingsService.Load()
+	if err != nil {
+		log.Fatal("Error reading store settings:", err)
+	}
+
+	// amounts and dates on receipts and in exports follow the store's locale,
+	// in the currency of the settings as the server starts
+	storeLocale := newStoreLocale(settings.Currency)
+
+	// foreign currencies are taken at rates set by hand, or fetched from
+	// EXCHANGE_RATE_URL on EXCHANGE_RATE_SCHEDULE when configured
+	var rateSource money.RateSource
+	if exchangeRateURL := viper.GetString("EXCHANGE_RATE_URL"); exchangeRateURL != "" {
+		rateSource = money.NewHTTPRateSource(exchangeRateURL)
+		exchangeRateSchedule := viper.GetString("EXCHANGE_RATE_SCHEDULE")
+		if exchangeRateSchedule == "" {
+			exchangeRateSchedule = "0 * * * *"
+		}
+		if err := jobRunner.Schedule("exchange_rate", exchangeRateSchedule, services.JobExchangeRateRefresh); err != nil {
+			log.Fatal("Error scheduling exchange rate refresh:", err)
+		}
+	}
+	currencyService := services.NewCurrencyService(repositories.NewCurrencyRepository(db), storeLocale.Currency, rateSource, jobRunner)
+
+	// receipt and report emails are sent through SMTP when configured, otherwise only logged
+	var receiptMailer mailer.Mailer = mailer.LogMailer{}
+	if smtpHost := viper.GetString("SMTP_HOST"); smtpHost != "" {
+		smtpPort := viper.GetString("SMTP_PORT")
+		if smtpPort == "" {
+			smtpPort = "587"
+		}
+		receiptMailer = mailer.NewSMTPMailer(smtpHost, smtpPort, viper.GetString("SMTP_USERNAME"), viper.GetString("SMTP_PASSWORD"), viper.GetString("SMTP_FROM"))
+	}
+
+	receiptTemplateService := services.NewReceiptTemplateService(repositories.NewReceiptTemplateRepository(db), repositories.NewTransactionRepository(db), settingsService, storeLocale)
+	receiptService := services.NewReceiptService(repositories.NewTransactionRepository(db), repositories.NewEmailRepository(db), receiptMailer, jobRunner, receiptTemplateService)
+	// what follows a sale or a catalog change subscribes to the events on the
+	// bus; dashboards stream them from /api/events. Business writes record
+	// their events in the outbox, relayed to the bus every
+	// OUTBOX_RELAY_INTERVAL once committed.
+	eventBus := events.NewBus()
+	eventBroadcaster := events.NewBroadcaster()
+	eventBus.Subscribe("event stream", eventBroadcaster.Handle)
+	outbox := repositories.NewOutboxRepository(db)
+	outboxRelay := services.NewOutboxRelay(outbox, eventBus)
+	outboxRelayInterval := viper.GetDuration("OUTBOX_RELAY_INTERVAL")
+	if outboxRelayInterval <= 0 {
+		outboxRelayInterval = time.Second
+	}
+	webhookService := services.NewWebhookService(repositories.NewWebhookRepository(db), jobRunner, eventBus)
+
+	// payment links for remote orders go through the gateway when configured; its
+	// callbacks are signed with PAYMENT_CALLBACK_SECRET
+	var paymentGateway payment.Gateway
+	if paymentGatewayURL := viper.GetString("PAYMENT_GATEWAY_URL"); paymentGatewayURL != "" {
+		paymentGateway = payment.NewHTTPGateway(paymentGatewayURL, viper.GetString("PAYMENT_GATEWAY_TOKEN"))
+	}
+	deletePolicy := categoryDeletePolicy()
+	openItemPolicy := services.OpenItemPolicy{Allowed: viper.GetBool("OPEN_ITEMS"), MaxPrice: money.Amount(viper.GetInt64("OPEN_ITEM_MAX_PRICE"))}
+	priceGuardrails := services.PriceGuardrails{MaxDiscountPercent: viper.GetInt("MAX_DISCOUNT_PERCENT"), BlockBelowCost: viper.GetBool("BLOCK_BELOW_COST")}
+	rounding := cashRounding()
+	// checkouts sell at a store's prices only with multi-store on
+	multiStore := viper.GetBool("MULTI_STORE")
+	var checkoutStores *repositories.StoreRepository
+	if multiStore {
+		checkoutStores = repositories.NewStoreRepository(db)
+	}
+
+	// low stock and end of day alerts go to the Telegram and WhatsApp chats
+	// registered for them, through the providers configured here
+	notificationProviders := map[string]notifier.Sender{}
+	if token := viper.GetString("TELEGRAM_BOT_TOKEN"); token != "" {
+		notificationProviders[services.ProviderTelegram] = notifier.NewTelegramSender(token)
+	}
+	if phoneNumberID := viper.GetString("WHATSAPP_PHONE_NUMBER_ID"); phoneNumberID != "" {
+		notificationProviders[services.ProviderWhatsApp] = notifier.NewWhatsAppSender(phoneNumberID, viper.GetString("WHATSAPP_ACCESS_TOKEN"))
+	}
+	notificationSummarySchedule := viper.GetString("NOTIFICATION_SUMMARY_SCHEDULE")
+	if notificationSummarySchedule == "" {
+		notificationSummarySchedule = "0 21 * * *"
+	}
+
+	notificationService := services.NewNotificationService(repositories.NewNotificationRepository(db), notificationProviders, services.NewReportService(repositories.NewReportRepository(db, settingsService), settingsService), jobRunner, eventBus, storeLocale, phoneCountryCode)
+	if err := jobRunner.Schedule("notification_summary", notificationSummarySchedule, services.JobNotificationDailySummary); err != nil {
+		log.Fatal("Error scheduling end of day notifications:", err)
+	}
+
+	paymentLinkTTL := viper.GetDuration("PAYMENT_LINK_TTL")
+	if paymentLinkTTL <= 0 {
+		paymentLinkTTL = 24 * time.Hour
+	}
+	transactionStatusService := services.NewTransactionStatusService(repositories.NewTransactionRepository(db).WithOutbox(outbox), rounding)
+	paymentLinkService := services.NewPaymentLinkService(
+		repositories.NewPaymentLinkRepository(db).WithOutbox(outbox),
+		repositories.NewTransactionRepository(db), transactionStatusService,
+		paymentGateway, viper.GetString("PAYMENT_CALLBACK_SECRET"), paymentLinkTTL,
+		pushService, storeLocale, phoneCountryCode,
+	)
+
+	cycleCountDaily := viper.GetInt("CYCLE_COUNT_DAILY")
+	if cycleCountDaily <= 0 {
+		cycleCountDaily = 10
+	}
+	cycleCountSchedule := viper.GetString("CYCLE_COUNT_SCHEDULE")
+	if cycleCountSchedule == "" {
+		cycleCountSchedule = "0 6 * * *"
+	}
+
+	cycleCountService := services.NewCycleCountService(repositories.NewCycleCountRepository(db), jobRunner, cycleCountDaily)
+	if err := jobRunner.Schedule("cycle_count", cycleCountSchedule, services.JobCycleCountGenerate); err != nil {
+		log.Fatal("Error scheduling cycle counts:", err)
+	}
+
+	// exports, receipt photos and other files are kept on local disk
+	storageDir := viper.GetString("STORAGE_DIR")
+	if storageDir == "" {
+		storageDir = "data"
+	}
+	fileStorage, err := storage.NewLocalStorage(storageDir)
+	if err != nil {
+		log.Fatal("Error opening file storage:", err)
+	}
+
+	// links in exports point at the public address of the API
+	publicURL := "http://localhost:" + portStr
+	if appHost != "" {
+		publicURL = "https://" + appHost
+	}
+
+	// boot self-test; while a critical check fails the API refuses changes, see /api/admin/selftest
+	selfTestChecks := []services.SelfTestCheck{
+		{Name: "database_schema", Critical: true, Run: func(ctx context.Context) error { return database.CheckSchema(db) }},
+		{Name: "settings", Critical: true, Run: checkSettings},
+		{Name: "storage", Critical: true, Run: services.StorageCheck(fileStorage)},
+	}
+	if printerAddr := viper.GetString("RECEIPT_PRINTER_ADDR"); printerAddr != "" {
+		selfTestChecks = append(selfTestChecks, services.SelfTestCheck{Name: "printer", Run: services.ReachableCheck(printerAddr)})
+	}
+	selfTestService := services.NewSelfTestService(selfTestChecks)
+	if report := selfTestService.Run(context.Background()); !report.OK {
+		log.Println("Self-test failed, refusing changes until it passes")
+	}
+	selfTestHandler := handlers.NewSelfTestHandler(selfTestService)
+
+	// error budget: the share of API requests allowed to fail with a 5xx
+	errorBudget := viper.GetFloat64("RELIABILITY_ERROR_BUDGET")
+	if errorBudget <= 0 {
+		errorBudget = 0.01
+	}
+	reliabilityService := services.NewReliabilityService(repositories.NewReliabilityRepository(db), errorBudget)
+	reliabilityService.Start(time.Minute)
+
+	periodService := services.NewPeriodService(repositories.NewPeriodRepository(db))
+	expenseRepo := repositories.NewExpenseRepository(db)
+	expenseService := services.NewExpenseService(expenseRepo, fileStorage, periodService)
+	deliveryService := services.NewDeliveryService(repositories.NewDeliveryRepository(db), fileStorage, reminderSenders["whatsapp"], jobRunner, phoneCountryCode)
+
+	// tax invoices are issued once the store's own NPWP is set
+	taxInvoiceService := services.NewTaxInvoiceService(repositories.NewTaxInvoiceRepository(db), repositories.NewTransactionRepository(db), taxInvoiceNumbering, settingsService, storeLocale)
+
+	// journal exports post sales to the ACCOUNT_* codes of the store's chart of accounts
+	journalAccounts := services.JournalAccounts{
+		Sales:       viper.GetString("ACCOUNT_SALES"),
+		Tax:         viper.GetString("ACCOUNT_TAX"),
+		Cash:        viper.GetString("ACCOUNT_CASH"),
+		Voucher:     viper.GetString("ACCOUNT_VOUCHER"),
+		PaymentLink: viper.GetString("ACCOUNT_PAYMENT_LINK"),
+		Installment: viper.GetString("ACCOUNT_INSTALLMENT"),
+	}
+	exportService := services.NewExportService(repositories.NewExportRepository(db), repositories.NewStockMovementRepository(db), expenseRepo, repositories.NewReportRepository(db, settingsService), fileStorage, jobRunner, publicURL, storeLocale, journalAccounts, settingsService)
+
+	// products are reclassified nightly; cycle counts and reorder suggestions use the stored class
+	abcSchedule := viper.GetString("ABC_SCHEDULE")
+	if abcSchedule == "" {
+		abcSchedule = "30 0 * * *"
+	}
+
+	abcService := services.NewABCService(repositories.NewABCRepository(db), jobRunner)
+	if err := jobRunner.Schedule("abc_classification", abcSchedule, services.JobABCClassification); err != nil {
+		log.Fatal("Error scheduling ABC classification:", err)
+	}
+
+	// batches past their expiry date are written off every night, before the store opens
+	expiredWriteOffSchedule := viper.GetString("EXPIRED_WRITE_OFF_SCHEDULE")
+	if expiredWriteOffSchedule == "" {
+		expiredWriteOffSchedule = "15 0 * * *"
+	}
+
+	expiryHandler := handlers.NewExpiryHandler(services.NewExpiryService(repositories.NewStockBatchRepository(db), jobRunner))
+	if err := jobRunner.Schedule("expired_write_off", expiredWriteOffSchedule, services.JobExpiredWriteOff); err != nil {
+		log.Fatal("Error scheduling expired stock write-off:", err)
+	}
+
+	// reports read past days from summaries rolled up by this nightly job
+	reportAggregationSchedule := viper.GetString("REPORT_AGGREGATION_SCHEDULE")
+	if reportAggregationSchedule == "" {
+		reportAggregationSchedule = "5 0 * * *"
+	}
+
+	jobRunner.Register(services.JobReportAggregation, services.NewReportService(repositories.NewReportRepository(db, settingsService), settingsService).AggregateDailyJob)
+	if err := jobRunner.Schedule("report_aggregation", reportAggregationSchedule, services.JobReportAggregation); err != nil {
+		log.Fatal("Error scheduling report aggregation:", err)
+	}
+
+	// subscribers get the sales summary by email every morning, of yesterday
+	// or, once a week has passed, of last week
+	reportEmailSchedule := viper.GetString("REPORT_EMAIL_SCHEDULE")
+	if reportEmailSchedule == "" {
+		reportEmailSchedule = "0 7 * * *"
+	}
+
+	reportSubscriptionService := services.NewReportSubscriptionService(repositories.NewReportSubscriptionRepository(db), services.NewReportService(repositories.NewReportRepository(db, settingsService), settingsService), receiptMailer, jobRunner, storeLocale)
+	if err := jobRunner.Schedule("report_email", reportEmailSchedule, services.JobReportEmailDigest); err != nil {
+		log.Fatal("Error scheduling report emails:", err)
+	}
+
+	// soft deleted records are kept in the recycle bin for RECYCLE_BIN_RETENTION_DAYS,
+	// then purged nightly; with RECYCLE_BIN_PURGE_DRY_RUN the job only logs them
+	retentionDays := viper.GetInt("RECYCLE_BIN_RETENTION_DAYS")
+	if retentionDays <= 0 {
+		retentionDays = 90
+	}
+	purgeSchedule := viper.GetString("RECYCLE_BIN_PURGE_SCHEDULE")
+	if purgeSchedule == "" {
+		purgeSchedule = "45 3 * * *"
+	}
+
+	purgeService := services.NewPurgeService(repositories.NewPurgeRepository(db), jobRunner, retentionDays, viper.GetBool("RECYCLE_BIN_PURGE_DRY_RUN"))
+	if err := jobRunner.Schedule("recycle_bin_purge", purgeSchedule, services.JobRecycleBinPurge); err != nil {
+		log.Fatal("Error scheduling recycle bin purge:", err)
+	}
+	purgeHandler := handlers.NewPurgeHandler(purgeService)
+
+	// the nightly rollups and purge wait for a maintenance window when any is defined
+	maintenanceWindowService := services.NewMaintenanceWindowService(repositories.NewMaintenanceWindowRepository(db))
+	jobRunner.DeferToWindows(maintenanceWindowService, services.JobReportAggregation, services.JobABCClassification, services.JobRecycleBinPurge)
+
+	jobRunner.Start()
+
 	// {{host}}/health
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		utils.WriteJSON(w, http.StatusOK, utils.Response{
@@ -67,15 +475,84 @@ func main() {
 		})
 	})
 
-	// Swagger
-	http.HandleFunc("/", httpSwagger.WrapHandler)
+	// {{host}}/readyz, ready while the database answers; a session store
+	// running on its in-process fallback is reported as degraded
+	http.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		if err := db.PingContext(ctx); err != nil {
+			utils.WriteJSON(w, http.StatusServiceUnavailable, utils.Response{
+				Status:  "failed",
+				Message: "Database unreachable: " + err.Error(),
+			})
+			return
+		}
+
+		status := sessionStore.Status()
+		message := "API Ready"
+		if status.Degraded {
+			message = "API Ready (degraded: session store unreachable, held carts are kept per instance)"
+		}
+		utils.WriteJSON(w, http.StatusOK, utils.Response{
+			Status:  "success",
+			Message: message,
+			Data: map[string]interface{}{
+				"degraded": status.Degraded,
+				"session":  status,
+			},
+		})
+	})
+
+	// {{host}}/metrics, Prometheus text format
+	http.HandleFunc("/metrics", metrics.Default.Handler())
+
+	// routes are tagged with the personas they serve so each gets its own API docs
+	api := router.NewRouter(http.DefaultServeMux)
+	cashier := []router.Role{router.RoleCashier, router.RoleAdmin}
+	admin := []router.Role{router.RoleAdmin}
+	public := []router.Role{router.RolePublic, router.RoleAdmin}
+
+	// /debug/vars and the pprof profiles under /debug/pprof/ are served on
+	// their own loopback listener when DIAGNOSTICS is on, see below
+	diagnosticsHandler := handlers.NewDiagnosticsHandler(services.NewDiagnosticsService(db, time.Now()))
+	// deprecated routes are declared with api.Deprecate(pattern, router.Deprecation{...})
+	// and answer with Deprecation and Sunset headers until they are removed
+
+	// catalog responses carry X-Catalog-Version; terminals sending an older
+	// If-Catalog-Version are told to fetch the catalog again
+	catalogVersion := middleware.NewCatalogVersion(services.NewCatalogVersion(sequenceRepo), catalogSyncHint)
+	// catalog reads carry an ETag; polling terminals sending it back get 304
+	// Not Modified while nothing changed
+	catalogCache := middleware.NewConditionalGet()
+
+	// Swagger: the full document plus one filtered document per role
+	// {{host}}/docs/cashier.json, {{host}}/docs/admin.json, {{host}}/docs/public.json
+	http.HandleFunc("/docs/", api.DocHandler(docs.SwaggerInfo.ReadDoc))
+	http.HandleFunc("/", httpSwagger.Handler(httpSwagger.UIConfig(map[string]string{
+		"urls": `[{url: "/doc.json", name: "all"}, {url: "/docs/cashier.json", name: "cashier"}, {url: "/docs/admin.json", name: "admin"}, {url: "/docs/public.json", name: "public"}]`,
+	})))
 
 	// Routes
-	http.HandleFunc("/api/category/", func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/api/category/", cashier, catalogVersion.Middleware(catalogCache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		categoryRepo := repositories.NewCategoryRepository(db)
-		categoryService := services.NewCategoryService(categoryRepo)
+		categoryService := services.NewCategoryService(categoryRepo, deletePolicy)
 		categoryHandler := handlers.NewCategoryHandler(categoryService)
 
+		if r.URL.Path == "/api/category/batch" {
+			switch r.Method {
+			case "POST":
+				categoryHandler.CreateCategoryBatch(w, r)
+			case "PUT":
+				categoryHandler.UpdateCategoryBatch(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+			return
+		}
+
 		switch r.Method {
 		case "GET":
 			categoryHandler.GetCategoryByID(w, r)
@@ -89,11 +566,11 @@ func main() {
 				Message: "Method not allowed",
 			})
 		}
-	})
+	}))).ServeHTTP)
 
-	http.HandleFunc("/api/category", func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/api/category", cashier, catalogVersion.Middleware(catalogCache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		categoryRepo := repositories.NewCategoryRepository(db)
-		categoryService := services.NewCategoryService(categoryRepo)
+		categoryService := services.NewCategoryService(categoryRepo, deletePolicy)
 		categoryHandler := handlers.NewCategoryHandler(categoryService)
 
 		switch r.Method {
@@ -107,13 +584,112 @@ func main() {
 				Message: "Method not allowed",
 			})
 		}
-	})
+	}))).ServeHTTP)
 
-	http.HandleFunc("/api/product/", func(w http.ResponseWriter, r *http.Request) {
-		productRepo := repositories.NewProductRepository(db)
-		productService := services.NewProductService(productRepo)
+	api.HandleFunc("/api/product/", cashier, catalogVersion.Middleware(catalogCache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		productRepo := repositories.NewProductRepository(db).WithOutbox(outbox)
+		productService := services.NewProductService(productRepo, skuNumbering)
 		productHandler := handlers.NewProductHandler(productService)
 
+		if r.URL.Path == "/api/product/expiring" {
+			switch r.Method {
+			case "GET":
+				expiryHandler.GetExpiringProducts(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+			return
+		}
+
+		if r.URL.Path == "/api/product/batch" {
+			switch r.Method {
+			case "POST":
+				productHandler.CreateProductBatch(w, r)
+			case "PUT":
+				productHandler.UpdateProductBatch(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+			return
+		}
+
+		if strings.HasPrefix(r.URL.Path, "/api/product/sku/") {
+			switch r.Method {
+			case "GET":
+				productHandler.GetProductBySKU(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/suppliers") {
+			supplierHandler := handlers.NewSupplierHandler(services.NewSupplierService(repositories.NewSupplierRepository(db)))
+
+			switch r.Method {
+			case "GET":
+				supplierHandler.GetProductSupplierPrices(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/public") {
+			availabilityHandler := handlers.NewAvailabilityHandler(services.NewAvailabilityService(repositories.NewAvailabilityRepository(db)))
+
+			switch r.Method {
+			case "PUT":
+				availabilityHandler.SetPublicAvailability(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/stock") {
+			switch r.Method {
+			case "POST":
+				productHandler.AdjustStock(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/components") {
+			switch r.Method {
+			case "PUT":
+				productHandler.SetBundleComponents(w, r)
+			case "DELETE":
+				productHandler.RemoveBundleComponents(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+			return
+		}
+
 		switch r.Method {
 		case "GET":
 			productHandler.GetProductByID(w, r)
@@ -127,11 +703,11 @@ func main() {
 				Message: "Method not allowed",
 			})
 		}
-	})
+	}))).ServeHTTP)
 
-	http.HandleFunc("/api/product", func(w http.ResponseWriter, r *http.Request) {
-		productRepo := repositories.NewProductRepository(db)
-		productService := services.NewProductService(productRepo)
+	api.HandleFunc("/api/product", cashier, catalogVersion.Middleware(catalogCache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		productRepo := repositories.NewProductRepository(db).WithOutbox(outbox)
+		productService := services.NewProductService(productRepo, skuNumbering)
 		productHandler := handlers.NewProductHandler(productService)
 
 		switch r.Method {
@@ -145,11 +721,11 @@ func main() {
 				Message: "Method not allowed",
 			})
 		}
-	})
+	}))).ServeHTTP)
 
-	http.HandleFunc("/api/checkout", func(w http.ResponseWriter, r *http.Request) {
-		transactionRepo := repositories.NewTransactionRepository(db)
-		transactionService := services.NewTransactionService(transactionRepo)
+	api.HandleFunc("/api/checkout", cashier, func(w http.ResponseWriter, r *http.Request) {
+		transactionRepo := repositories.NewTransactionRepository(db).WithOutbox(outbox)
+		transactionService := services.NewTransactionService(transactionRepo, repositories.NewProductRepository(db), repositories.NewPricingRuleRepository(db), receiptNumbering, currencyService, repositories.NewPriceContractRepository(db), repositories.NewVoucherRepository(db), checkoutStores, openItemPolicy, rounding).WithApprovals(permissionService).WithGuardrails(priceGuardrails)
 		transactionHandler := handlers.NewTransactionHandler(transactionService)
 
 		switch r.Method {
@@ -163,15 +739,47 @@ func main() {
 		}
 	})
 
-	// sales summary
-	http.HandleFunc("/api/report/hari-ini", func(w http.ResponseWriter, r *http.Request) {
-		reportRepo := repositories.NewReportRepository(db)
-		reportService := services.NewReportService(reportRepo)
-		reportHandler := handlers.NewReportHandler(reportService)
+	printerService := services.NewPrinterService(repositories.NewPrinterRepository(db), jobRunner)
+	restaurantHandler := handlers.NewRestaurantHandler(services.NewRestaurantService(
+		viper.GetBool("RESTAURANT_MODE"),
+		services.NewTransactionService(repositories.NewTransactionRepository(db).WithOutbox(outbox), repositories.NewProductRepository(db), repositories.NewPricingRuleRepository(db), receiptNumbering, currencyService, repositories.NewPriceContractRepository(db), repositories.NewVoucherRepository(db), checkoutStores, openItemPolicy, rounding).WithApprovals(permissionService).WithGuardrails(priceGuardrails),
+		repositories.NewSequenceRepository(db),
+		repositories.NewRestaurantRepository(db),
+		printerService,
+	))
+
+	api.HandleFunc("/api/orders", cashier, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			restaurantHandler.OpenOrder(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/orders/", cashier, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/items") && r.Method == "POST":
+			restaurantHandler.AddOrderItems(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
 
+	printerHandler := handlers.NewPrinterHandler(printerService)
+
+	api.HandleFunc("/api/printers", admin, func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case "GET":
-			reportHandler.GetDailySalesReport(w, r)
+			printerHandler.GetPrinters(w, r)
+		case "POST":
+			printerHandler.CreatePrinter(w, r)
 		default:
 			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
 				Status:  "failed",
@@ -180,14 +788,26 @@ func main() {
 		}
 	})
 
-	http.HandleFunc("/api/report", func(w http.ResponseWriter, r *http.Request) {
-		reportRepo := repositories.NewReportRepository(db)
-		reportService := services.NewReportService(reportRepo)
-		reportHandler := handlers.NewReportHandler(reportService)
+	api.HandleFunc("/api/printers/", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			printerHandler.GetPrinterByID(w, r)
+		case "PUT":
+			printerHandler.UpdatePrinter(w, r)
+		case "DELETE":
+			printerHandler.DeletePrinter(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
 
+	api.HandleFunc("/api/print-jobs", cashier, func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case "GET":
-			reportHandler.GetSalesReportByDateRange(w, r)
+			printerHandler.GetPrintJobs(w, r)
 		default:
 			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
 				Status:  "failed",
@@ -196,9 +816,2376 @@ func main() {
 		}
 	})
 
-	fmt.Println("Server running on http://localhost:" + portStr)
-	err = http.ListenAndServe(":"+portStr, nil)
-	if err != nil {
-		fmt.Println("Error running server:", err)
+	api.HandleFunc("/api/print-jobs/", cashier, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/retry") && r.Method == "POST":
+			printerHandler.RetryPrintJob(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/kitchen/orders", cashier, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			restaurantHandler.StreamKitchenOrders(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	historyHandler := handlers.NewHistoryHandler(services.NewHistoryService(repositories.NewTransactionRepository(db), repositories.NewStockMovementRepository(db)))
+
+	api.HandleFunc("/api/transactions", cashier, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			historyHandler.GetTransactions(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/stock-movements", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			historyHandler.GetStockMovements(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	activityHandler := handlers.NewActivityHandler(services.NewActivityService(repositories.NewActivityRepository(db)))
+
+	api.HandleFunc("/api/activity", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			activityHandler.GetActivity(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/transactions/", cashier, func(w http.ResponseWriter, r *http.Request) {
+		receiptHandler := handlers.NewReceiptHandler(receiptService)
+		statusHandler := handlers.NewTransactionStatusHandler(transactionStatusService, permissionService)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/status") && r.Method == "PUT":
+			statusHandler.UpdateTransactionStatus(w, r)
+			return
+		case strings.HasSuffix(r.URL.Path, "/status-history") && r.Method == "GET":
+			statusHandler.GetTransactionStatusHistory(w, r)
+			return
+		}
+
+		if !strings.HasSuffix(r.URL.Path, "/email-receipt") {
+			utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+				Status:  "failed",
+				Message: "Not found",
+			})
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			receiptHandler.GetReceiptDeliveries(w, r)
+		case "POST":
+			receiptHandler.EmailReceipt(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	// sales summary
+	api.HandleFunc("/api/report/hari-ini", cashier, func(w http.ResponseWriter, r *http.Request) {
+		reportRepo := repositories.NewReportRepository(db, settingsService)
+		reportService := services.NewReportService(reportRepo, settingsService)
+		reportHandler := handlers.NewReportHandler(reportService)
+
+		switch r.Method {
+		case "GET":
+			reportHandler.GetDailySalesReport(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/report/profit", admin, func(w http.ResponseWriter, r *http.Request) {
+		reportRepo := repositories.NewReportRepository(db, settingsService)
+		reportService := services.NewReportService(reportRepo, settingsService)
+		reportHandler := handlers.NewReportHandler(reportService)
+
+		switch r.Method {
+		case "GET":
+			reportHandler.GetProfitReport(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/report/profit-loss", admin, func(w http.ResponseWriter, r *http.Request) {
+		reportRepo := repositories.NewReportRepository(db, settingsService)
+		reportService := services.NewReportService(reportRepo, settingsService)
+		reportHandler := handlers.NewReportHandler(reportService)
+
+		switch r.Method {
+		case "GET":
+			reportHandler.GetProfitLossReport(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/report/compare", admin, func(w http.ResponseWriter, r *http.Request) {
+		reportRepo := repositories.NewReportRepository(db, settingsService)
+		reportService := services.NewReportService(reportRepo, settingsService)
+		reportHandler := handlers.NewReportHandler(reportService)
+
+		switch r.Method {
+		case "GET":
+			reportHandler.GetComparisonReport(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/report/price-contracts", admin, func(w http.ResponseWriter, r *http.Request) {
+		reportRepo := repositories.NewReportRepository(db, settingsService)
+		reportService := services.NewReportService(reportRepo, settingsService)
+		reportHandler := handlers.NewReportHandler(reportService)
+
+		switch r.Method {
+		case "GET":
+			reportHandler.GetPriceContractSalesReport(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/report/shrinkage", admin, func(w http.ResponseWriter, r *http.Request) {
+		reportRepo := repositories.NewReportRepository(db, settingsService)
+		reportService := services.NewReportService(reportRepo, settingsService)
+		reportHandler := handlers.NewReportHandler(reportService)
+
+		switch r.Method {
+		case "GET":
+			reportHandler.GetShrinkageReport(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/report/receivables-aging", admin, func(w http.ResponseWriter, r *http.Request) {
+		reportRepo := repositories.NewReportRepository(db, settingsService)
+		reportService := services.NewReportService(reportRepo, settingsService)
+		reportHandler := handlers.NewReportHandler(reportService)
+
+		switch r.Method {
+		case "GET":
+			reportHandler.GetReceivablesAging(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/report/aggregate", admin, func(w http.ResponseWriter, r *http.Request) {
+		reportRepo := repositories.NewReportRepository(db, settingsService)
+		reportService := services.NewReportService(reportRepo, settingsService)
+		reportHandler := handlers.NewReportHandler(reportService)
+
+		switch r.Method {
+		case "POST":
+			reportHandler.AggregateDailyReport(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/report", admin, func(w http.ResponseWriter, r *http.Request) {
+		reportRepo := repositories.NewReportRepository(db, settingsService)
+		reportService := services.NewReportService(reportRepo, settingsService)
+		reportHandler := handlers.NewReportHandler(reportService)
+
+		switch r.Method {
+		case "GET":
+			reportHandler.GetSalesReportByDateRange(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/report-subscriptions", admin, func(w http.ResponseWriter, r *http.Request) {
+		reportSubscriptionHandler := handlers.NewReportSubscriptionHandler(reportSubscriptionService)
+
+		switch r.Method {
+		case "GET":
+			reportSubscriptionHandler.GetReportSubscriptions(w, r)
+		case "POST":
+			reportSubscriptionHandler.SubscribeReport(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/report-subscriptions/", admin, func(w http.ResponseWriter, r *http.Request) {
+		reportSubscriptionHandler := handlers.NewReportSubscriptionHandler(reportSubscriptionService)
+
+		switch r.Method {
+		case "DELETE":
+			reportSubscriptionHandler.UnsubscribeReport(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/customer/", cashier, func(w http.ResponseWriter, r *http.Request) {
+		customerRepo := repositories.NewCustomerRepository(db)
+		customerService := services.NewCustomerService(customerRepo, phoneCountryCode)
+		customerHandler := handlers.NewCustomerHandler(customerService)
+		dunningHandler := handlers.NewDunningHandler(dunningService)
+
+		switch {
+		case r.URL.Path == "/api/customer/import":
+			switch r.Method {
+			case "POST":
+				customerHandler.ImportCustomers(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+		case r.URL.Path == "/api/customer/duplicates":
+			switch r.Method {
+			case "GET":
+				customerHandler.GetDuplicateCustomers(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+		case strings.HasSuffix(r.URL.Path, "/merge"):
+			switch r.Method {
+			case "POST":
+				customerHandler.MergeCustomers(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+		case strings.HasSuffix(r.URL.Path, "/kasbon"):
+			switch r.Method {
+			case "GET":
+				customerHandler.GetCustomerKasbon(w, r)
+			case "POST":
+				customerHandler.CreateKasbon(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+		case strings.HasSuffix(r.URL.Path, "/payment"):
+			switch r.Method {
+			case "POST":
+				customerHandler.CreateKasbonPayment(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+		case strings.HasSuffix(r.URL.Path, "/reminders"):
+			switch r.Method {
+			case "GET":
+				dunningHandler.GetReminderHistory(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+		default:
+			switch r.Method {
+			case "GET":
+				customerHandler.GetCustomerByID(w, r)
+			case "PUT":
+				customerHandler.UpdateCustomer(w, r)
+			case "DELETE":
+				customerHandler.DeleteCustomer(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+		}
+	})
+
+	api.HandleFunc("/api/customer", cashier, func(w http.ResponseWriter, r *http.Request) {
+		customerRepo := repositories.NewCustomerRepository(db)
+		customerService := services.NewCustomerService(customerRepo, phoneCountryCode)
+		customerHandler := handlers.NewCustomerHandler(customerService)
+
+		switch r.Method {
+		case "GET":
+			customerHandler.GetCustomers(w, r)
+		case "POST":
+			customerHandler.CreateCustomer(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/dunning/templates", admin, func(w http.ResponseWriter, r *http.Request) {
+		dunningHandler := handlers.NewDunningHandler(dunningService)
+
+		switch r.Method {
+		case "GET":
+			dunningHandler.GetTemplates(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/dunning/templates/", admin, func(w http.ResponseWriter, r *http.Request) {
+		dunningHandler := handlers.NewDunningHandler(dunningService)
+
+		switch r.Method {
+		case "PUT":
+			dunningHandler.UpdateTemplate(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/dunning/run", admin, func(w http.ResponseWriter, r *http.Request) {
+		dunningHandler := handlers.NewDunningHandler(dunningService)
+
+		switch r.Method {
+		case "POST":
+			dunningHandler.RunReminders(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/installment/", cashier, func(w http.ResponseWriter, r *http.Request) {
+		installmentRepo := repositories.NewInstallmentRepository(db)
+		installmentService := services.NewInstallmentService(installmentRepo)
+		installmentHandler := handlers.NewInstallmentHandler(installmentService)
+
+		if strings.HasSuffix(r.URL.Path, "/payment") {
+			switch r.Method {
+			case "POST":
+				installmentHandler.RecordInstallmentPayment(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			installmentHandler.GetInstallmentPlanByID(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/installment", cashier, func(w http.ResponseWriter, r *http.Request) {
+		installmentRepo := repositories.NewInstallmentRepository(db)
+		installmentService := services.NewInstallmentService(installmentRepo)
+		installmentHandler := handlers.NewInstallmentHandler(installmentService)
+
+		switch r.Method {
+		case "GET":
+			installmentHandler.GetInstallmentPlans(w, r)
+		case "POST":
+			installmentHandler.CreateInstallmentPlan(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	var handler http.Handler = http.DefaultServeMux
+
+	// fault injection for resilience testing of the terminals; never enabled in production
+	if chaosRules := viper.GetString("CHAOS_RULES"); chaosRules != "" {
+		appEnv := viper.GetString("APP_ENV")
+		if appEnv != "development" && appEnv != "staging" {
+			log.Fatal("CHAOS_RULES is only allowed when APP_ENV is development or staging")
+		}
+		rules, err := middleware.ParseChaosRules(chaosRules)
+		if err != nil {
+			log.Fatal("Error parsing CHAOS_RULES:", err)
+		}
+		log.Printf("Chaos: injecting faults on %d rule(s)", len(rules))
+		handler = middleware.NewChaos(rules).Middleware(handler)
+	}
+
+	// reject requests that don't match the OpenAPI document generated from the handler annotations
+	if viper.GetBool("REQUEST_VALIDATION") {
+		validator, err := middleware.NewRequestValidator([]byte(docs.SwaggerInfo.ReadDoc()))
+		if err != nil {
+			log.Fatal("Error loading OpenAPI document:", err)
+		}
+		handler = validator.Middleware(handler)
+	}
+
+	if enforcePermissions {
+		handler = middleware.NewPolicy(permissionService, services.PermissionRules, services.PublicRoutes).Middleware(handler)
+	}
+
+	handler = middleware.NewSelfTestGuard(selfTestService, "/api/admin/selftest").Middleware(handler)
+
+	// anonymized request/response pairs of the journaled endpoints can be replayed against a staging instance
+	journalLimit := viper.GetInt("REQUEST_JOURNAL_LIMIT")
+	if journalLimit <= 0 {
+		journalLimit = 1000
+	}
+	requestJournalService := services.NewRequestJournalService(repositories.NewRequestJournalRepository(db), journalLimit, viper.GetString("REQUEST_JOURNAL_REPLAY_URL"))
+	if viper.GetBool("REQUEST_JOURNAL") {
+		journalPaths := viper.GetString("REQUEST_JOURNAL_PATHS")
+		if journalPaths == "" {
+			journalPaths = "/api/checkout,/api/held-carts"
+		}
+		handler = middleware.NewRequestJournal(requestJournalService, strings.Split(journalPaths, ",")).Middleware(handler)
+	}
+
+	// the journal above keeps responses as the handlers wrote them
+	if viper.GetBool("COMPRESSION") {
+		handler = middleware.NewCompression(compressionMinSize()).Middleware(handler)
+	}
+
+	// a panic anywhere above is answered with a 500 instead of a dropped connection
+	handler = middleware.NewRecovery().Middleware(handler)
+
+	// counts every API response, including those refused by the middleware above
+	handler = middleware.NewResponseCount(reliabilityService, "/api/").Middleware(handler)
+
+	// profiles and runtime stats tell more about the install than the API does,
+	// so they are never served to the network; turn them on at DIAGNOSTICS_ADDR
+	// while diagnosing a slowdown and reach them through an SSH tunnel
+	handler = middleware.NewDiagnosticsGuard("/debug/").Middleware(handler)
+	var diagnosticsServer *http.Server
+	if viper.GetBool("DIAGNOSTICS") {
+		diagnosticsServer, err = newDiagnosticsServer(viper.GetString("DIAGNOSTICS_ADDR"), diagnosticsHandler)
+		if err != nil {
+			log.Fatal("Error configuring diagnostics:", err)
+		}
+	}
+
+	// outermost, so responses of the middleware above carry the request ID too
+	handler = middleware.NewRequestID().Middleware(handler)
+
+	api.HandleFunc("/api/supplier/", admin, func(w http.ResponseWriter, r *http.Request) {
+		supplierRepo := repositories.NewSupplierRepository(db)
+		supplierService := services.NewSupplierService(supplierRepo)
+		supplierHandler := handlers.NewSupplierHandler(supplierService)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/prices"):
+			switch r.Method {
+			case "GET":
+				supplierHandler.GetSupplierPrices(w, r)
+			case "PUT":
+				supplierHandler.SaveSupplierPrice(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+		case strings.HasSuffix(r.URL.Path, "/price-trend"):
+			switch r.Method {
+			case "GET":
+				supplierHandler.GetPriceTrend(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+		default:
+			switch r.Method {
+			case "GET":
+				supplierHandler.GetSupplierByID(w, r)
+			case "PUT":
+				supplierHandler.UpdateSupplier(w, r)
+			case "DELETE":
+				supplierHandler.DeleteSupplier(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+		}
+	})
+
+	api.HandleFunc("/api/supplier", admin, func(w http.ResponseWriter, r *http.Request) {
+		supplierRepo := repositories.NewSupplierRepository(db)
+		supplierService := services.NewSupplierService(supplierRepo)
+		supplierHandler := handlers.NewSupplierHandler(supplierService)
+
+		switch r.Method {
+		case "GET":
+			supplierHandler.GetSuppliers(w, r)
+		case "POST":
+			supplierHandler.CreateSupplier(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/reorder/suggestions", admin, func(w http.ResponseWriter, r *http.Request) {
+		reorderRepo := repositories.NewReorderRepository(db)
+		reorderService := services.NewReorderService(reorderRepo)
+		reorderHandler := handlers.NewReorderHandler(reorderService)
+
+		switch r.Method {
+		case "GET":
+			reorderHandler.GetReorderSuggestions(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/purchase-order/", admin, func(w http.ResponseWriter, r *http.Request) {
+		purchaseOrderRepo := repositories.NewPurchaseOrderRepository(db)
+		purchaseOrderService := services.NewPurchaseOrderService(purchaseOrderRepo)
+		purchaseOrderHandler := handlers.NewPurchaseOrderHandler(purchaseOrderService)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/close") && r.Method == "POST":
+			purchaseOrderHandler.ClosePurchaseOrder(w, r)
+		case strings.HasSuffix(r.URL.Path, "/receipts") && r.Method == "GET":
+			purchaseOrderHandler.GetPurchaseOrderReceipts(w, r)
+		case r.Method == "GET":
+			purchaseOrderHandler.GetPurchaseOrderByID(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/purchase-order", admin, func(w http.ResponseWriter, r *http.Request) {
+		purchaseOrderRepo := repositories.NewPurchaseOrderRepository(db)
+		purchaseOrderService := services.NewPurchaseOrderService(purchaseOrderRepo)
+		purchaseOrderHandler := handlers.NewPurchaseOrderHandler(purchaseOrderService)
+
+		switch r.Method {
+		case "GET":
+			purchaseOrderHandler.GetPurchaseOrders(w, r)
+		case "POST":
+			purchaseOrderHandler.CreatePurchaseOrder(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/receiving/", cashier, func(w http.ResponseWriter, r *http.Request) {
+		receivingRepo := repositories.NewReceivingRepository(db)
+		receivingService := services.NewReceivingService(receivingRepo, repositories.NewPurchaseOrderRepository(db))
+		receivingHandler := handlers.NewReceivingHandler(receivingService)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/scan") && r.Method == "POST":
+			receivingHandler.ScanReceivingItem(w, r)
+		case strings.HasSuffix(r.URL.Path, "/commit") && r.Method == "POST":
+			receivingHandler.CommitReceivingSession(w, r)
+		case r.Method == "GET":
+			receivingHandler.GetReceivingSession(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/receiving", cashier, func(w http.ResponseWriter, r *http.Request) {
+		receivingRepo := repositories.NewReceivingRepository(db)
+		receivingService := services.NewReceivingService(receivingRepo, repositories.NewPurchaseOrderRepository(db))
+		receivingHandler := handlers.NewReceivingHandler(receivingService)
+
+		switch r.Method {
+		case "POST":
+			receivingHandler.OpenReceivingSession(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/webhook/", admin, func(w http.ResponseWriter, r *http.Request) {
+		webhookHandler := handlers.NewWebhookHandler(webhookService)
+
+		if strings.HasSuffix(r.URL.Path, "/deliveries") {
+			switch r.Method {
+			case "GET":
+				webhookHandler.GetWebhookDeliveries(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			webhookHandler.GetWebhookByID(w, r)
+		case "PUT":
+			webhookHandler.UpdateWebhook(w, r)
+		case "DELETE":
+			webhookHandler.DeleteWebhook(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/webhook", admin, func(w http.ResponseWriter, r *http.Request) {
+		webhookHandler := handlers.NewWebhookHandler(webhookService)
+
+		switch r.Method {
+		case "GET":
+			webhookHandler.GetWebhooks(w, r)
+		case "POST":
+			webhookHandler.CreateWebhook(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/jobs/", admin, func(w http.ResponseWriter, r *http.Request) {
+		jobHandler := handlers.NewJobHandler(services.NewJobService(repositories.NewJobRepository(db)))
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/retry") && r.Method == "POST":
+			jobHandler.RetryJob(w, r)
+		case r.Method == "GET":
+			jobHandler.GetJobByID(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/jobs", admin, func(w http.ResponseWriter, r *http.Request) {
+		jobHandler := handlers.NewJobHandler(services.NewJobService(repositories.NewJobRepository(db)))
+
+		switch r.Method {
+		case "GET":
+			jobHandler.GetJobs(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/cycle-count/tasks", cashier, func(w http.ResponseWriter, r *http.Request) {
+		cycleCountHandler := handlers.NewCycleCountHandler(cycleCountService)
+
+		switch r.Method {
+		case "GET":
+			cycleCountHandler.GetCycleCountTasks(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/cycle-count/tasks/", cashier, func(w http.ResponseWriter, r *http.Request) {
+		cycleCountHandler := handlers.NewCycleCountHandler(cycleCountService)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/count") && r.Method == "POST":
+			cycleCountHandler.RecordCycleCount(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/cycle-count/generate", admin, func(w http.ResponseWriter, r *http.Request) {
+		cycleCountHandler := handlers.NewCycleCountHandler(cycleCountService)
+
+		switch r.Method {
+		case "POST":
+			cycleCountHandler.GenerateCycleCountTasks(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/cycle-count/accuracy", admin, func(w http.ResponseWriter, r *http.Request) {
+		cycleCountHandler := handlers.NewCycleCountHandler(cycleCountService)
+
+		switch r.Method {
+		case "GET":
+			cycleCountHandler.GetCountAccuracy(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/write-offs", admin, func(w http.ResponseWriter, r *http.Request) {
+		writeOffHandler := handlers.NewWriteOffHandler(services.NewWriteOffService(repositories.NewWriteOffRepository(db)))
+
+		switch r.Method {
+		case "GET":
+			writeOffHandler.GetWriteOffs(w, r)
+		case "POST":
+			writeOffHandler.CreateWriteOff(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/write-offs/", admin, func(w http.ResponseWriter, r *http.Request) {
+		writeOffHandler := handlers.NewWriteOffHandler(services.NewWriteOffService(repositories.NewWriteOffRepository(db)))
+
+		switch r.Method {
+		case "GET":
+			writeOffHandler.GetWriteOffByID(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/pricing-rules", admin, catalogVersion.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pricingRuleHandler := handlers.NewPricingRuleHandler(services.NewPricingRuleService(repositories.NewPricingRuleRepository(db)))
+
+		switch r.Method {
+		case "GET":
+			pricingRuleHandler.GetPricingRules(w, r)
+		case "POST":
+			pricingRuleHandler.CreatePricingRule(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})).ServeHTTP)
+
+	api.HandleFunc("/api/pricing-rules/", admin, catalogVersion.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pricingRuleHandler := handlers.NewPricingRuleHandler(services.NewPricingRuleService(repositories.NewPricingRuleRepository(db)))
+
+		switch r.Method {
+		case "GET":
+			pricingRuleHandler.GetPricingRuleByID(w, r)
+		case "PUT":
+			pricingRuleHandler.UpdatePricingRule(w, r)
+		case "DELETE":
+			pricingRuleHandler.DeletePricingRule(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})).ServeHTTP)
+
+	priceContractHandler := handlers.NewPriceContractHandler(services.NewPriceContractService(repositories.NewPriceContractRepository(db)))
+
+	api.HandleFunc("/api/price-contracts", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			priceContractHandler.GetPriceContracts(w, r)
+		case "POST":
+			priceContractHandler.CreatePriceContract(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/price-contracts/", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			priceContractHandler.GetPriceContractByID(w, r)
+		case "PUT":
+			priceContractHandler.UpdatePriceContract(w, r)
+		case "DELETE":
+			priceContractHandler.DeletePriceContract(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/admin/selftest", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			selfTestHandler.GetSelfTest(w, r)
+		case "POST":
+			selfTestHandler.RunSelfTest(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/admin/recycle-bin/purge", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			purgeHandler.Purge(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/admin/recycle-bin/log", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			purgeHandler.GetLog(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	// the availability widget is open to the internet, so each client gets a budget
+	publicRateLimit := viper.GetInt("PUBLIC_RATE_LIMIT")
+	if publicRateLimit <= 0 {
+		publicRateLimit = 60
+	}
+	publicLimiter := middleware.NewRateLimit(publicRateLimit, time.Minute)
+	availabilityHandler := handlers.NewAvailabilityHandler(services.NewAvailabilityService(repositories.NewAvailabilityRepository(db)))
+
+	api.HandleFunc("/api/public/availability", public, publicLimiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			availabilityHandler.GetPublicAvailability(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})).ServeHTTP)
+
+	api.HandleFunc("/api/public/availability.js", public, publicLimiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			availabilityHandler.GetAvailabilityWidget(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})).ServeHTTP)
+
+	// admins sign in with their password, and confirm their address and
+	// reset a forgotten password through links signed with AUTH_TOKEN_SECRET,
+	// pointing at APP_URL's pages; the public endpoints get a tighter budget
+	// per client
+	authTokenSecret := viper.GetString("AUTH_TOKEN_SECRET")
+	if authTokenSecret != "" && len(authTokenSecret) < 32 {
+		log.Fatal("AUTH_TOKEN_SECRET must be at least 32 characters")
+	}
+	appURL := viper.GetString("APP_URL")
+	if appURL == "" {
+		appURL = "http://localhost:" + portStr
+	}
+	authVerifyTTL := viper.GetDuration("AUTH_VERIFY_TOKEN_TTL")
+	if authVerifyTTL <= 0 {
+		authVerifyTTL = 48 * time.Hour
+	}
+	authResetTTL := viper.GetDuration("AUTH_RESET_TOKEN_TTL")
+	if authResetTTL <= 0 {
+		authResetTTL = time.Hour
+	}
+	authRateLimit := viper.GetInt("AUTH_RATE_LIMIT")
+	if authRateLimit <= 0 {
+		authRateLimit = 5
+	}
+	authLimiter := middleware.NewRateLimit(authRateLimit, time.Minute)
+	authHandler := handlers.NewAuthHandler(services.NewAuthService(repositories.NewAdminUserRepository(db), repositories.NewAuthTokenRepository(db), receiptMailer, authTokenSecret, appURL, authVerifyTTL, authResetTTL), permissionService)
+
+	api.HandleFunc("/api/admin/users", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			authHandler.RegisterAdmin(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/auth/login", public, authLimiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		terminalSessionHandler := handlers.NewTerminalSessionHandler(terminalSessionService)
+
+		switch r.Method {
+		case "POST":
+			terminalSessionHandler.SignIn(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})).ServeHTTP)
+
+	api.HandleFunc("/api/auth/verify-email", public, authLimiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			authHandler.VerifyEmail(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})).ServeHTTP)
+
+	api.HandleFunc("/api/auth/verify-email/resend", public, authLimiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			authHandler.ResendVerification(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})).ServeHTTP)
+
+	api.HandleFunc("/api/auth/forgot-password", public, authLimiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			authHandler.ForgotPassword(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})).ServeHTTP)
+
+	api.HandleFunc("/api/auth/reset-password", public, authLimiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			authHandler.ResetPassword(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})).ServeHTTP)
+
+	// routes on their way out are listed with the callers still using them
+	api.HandleFunc("/api/admin/deprecations", admin, api.DeprecationHandler())
+
+	api.HandleFunc("/api/admin/reliability", admin, func(w http.ResponseWriter, r *http.Request) {
+		reliabilityHandler := handlers.NewReliabilityHandler(reliabilityService)
+
+		switch r.Method {
+		case "GET":
+			reliabilityHandler.GetReliability(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	numberingHandler := handlers.NewNumberingHandler(services.NewNumberingService(sequenceRepo, receiptNumbering, skuNumbering, taxInvoiceNumbering))
+
+	api.HandleFunc("/api/admin/numbering", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			numberingHandler.GetNumbering(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/admin/numbering/", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/admin/numbering/adjustments" && r.Method == "GET":
+			numberingHandler.GetNumberingAdjustments(w, r)
+		case r.URL.Path != "/api/admin/numbering/adjustments" && r.Method == "PUT":
+			numberingHandler.SetNextNumber(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	periodHandler := handlers.NewPeriodHandler(periodService)
+
+	api.HandleFunc("/api/admin/periods", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			periodHandler.GetPeriods(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/admin/periods/", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/admin/periods/events" && r.Method == "GET":
+			periodHandler.GetPeriodEvents(w, r)
+		case strings.HasSuffix(r.URL.Path, "/close") && r.Method == "POST":
+			periodHandler.ClosePeriod(w, r)
+		case strings.HasSuffix(r.URL.Path, "/reopen") && r.Method == "POST":
+			periodHandler.ReopenPeriod(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	currencyHandler := handlers.NewCurrencyHandler(currencyService)
+
+	api.HandleFunc("/api/currencies", cashier, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			currencyHandler.GetCurrencies(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/currencies/", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PUT":
+			currencyHandler.SetCurrency(w, r)
+		case "DELETE":
+			currencyHandler.DeleteCurrency(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	costHandler := handlers.NewCostHandler(services.NewCostService(repositories.NewCostRepository(db), periodService))
+
+	api.HandleFunc("/api/admin/costs/recalculate", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			costHandler.RecalculateCosts(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/admin/costs/receipts/", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PUT":
+			costHandler.CorrectReceiptCost(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/admin/load-test/checkout", admin, func(w http.ResponseWriter, r *http.Request) {
+		productService := services.NewProductService(repositories.NewProductRepository(db), skuNumbering)
+		loadTestHandler := handlers.NewLoadTestHandler(services.NewLoadTestService(productService))
+
+		switch r.Method {
+		case "GET":
+			loadTestHandler.GetCheckoutScenario(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	maintenanceWindowHandler := handlers.NewMaintenanceWindowHandler(maintenanceWindowService)
+
+	api.HandleFunc("/api/admin/maintenance-windows", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			maintenanceWindowHandler.GetMaintenanceWindows(w, r)
+		case "POST":
+			maintenanceWindowHandler.CreateMaintenanceWindow(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/admin/maintenance-windows/", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "DELETE":
+			maintenanceWindowHandler.DeleteMaintenanceWindow(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	receiptTemplateHandler := handlers.NewReceiptTemplateHandler(receiptTemplateService)
+
+	api.HandleFunc("/api/admin/receipt-templates", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			receiptTemplateHandler.GetReceiptTemplates(w, r)
+		case "POST":
+			receiptTemplateHandler.SaveReceiptTemplate(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/admin/receipt-templates/", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/admin/receipt-templates/preview" && r.Method == "POST":
+			receiptTemplateHandler.PreviewReceiptTemplate(w, r)
+		case strings.HasSuffix(r.URL.Path, "/activate") && r.Method == "POST":
+			receiptTemplateHandler.ActivateReceiptTemplate(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	voucherHandler := handlers.NewVoucherHandler(services.NewVoucherService(repositories.NewVoucherRepository(db)))
+
+	api.HandleFunc("/api/vouchers", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			voucherHandler.GetVouchers(w, r)
+		case "POST":
+			voucherHandler.IssueVoucher(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/vouchers/", cashier, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			voucherHandler.GetVoucher(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	displayService := services.NewDisplayService(repositories.NewDisplayRepository(db), fileStorage, publicURL)
+	api.HandleFunc("/api/display/playlist", cashier, func(w http.ResponseWriter, r *http.Request) {
+		displayHandler := handlers.NewDisplayHandler(displayService)
+
+		switch r.Method {
+		case "GET":
+			displayHandler.GetDisplayPlaylist(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/display/media/", cashier, func(w http.ResponseWriter, r *http.Request) {
+		displayHandler := handlers.NewDisplayHandler(displayService)
+
+		switch r.Method {
+		case "GET":
+			displayHandler.GetDisplayMediaFile(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/admin/display/media", admin, func(w http.ResponseWriter, r *http.Request) {
+		displayHandler := handlers.NewDisplayHandler(displayService)
+
+		switch r.Method {
+		case "GET":
+			displayHandler.GetDisplayMedia(w, r)
+		case "POST":
+			displayHandler.UploadDisplayMedia(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/admin/display/media/", admin, func(w http.ResponseWriter, r *http.Request) {
+		displayHandler := handlers.NewDisplayHandler(displayService)
+
+		switch r.Method {
+		case "PUT":
+			displayHandler.UpdateDisplayMedia(w, r)
+		case "DELETE":
+			displayHandler.DeleteDisplayMedia(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/request-journal", admin, func(w http.ResponseWriter, r *http.Request) {
+		requestJournalHandler := handlers.NewRequestJournalHandler(requestJournalService)
+
+		switch r.Method {
+		case "GET":
+			requestJournalHandler.GetJournalEntries(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/request-journal/", admin, func(w http.ResponseWriter, r *http.Request) {
+		requestJournalHandler := handlers.NewRequestJournalHandler(requestJournalService)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/replay") && r.Method == "POST":
+			requestJournalHandler.ReplayJournalEntry(w, r)
+		case r.Method == "GET":
+			requestJournalHandler.GetJournalEntryByID(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/stock-opname", cashier, func(w http.ResponseWriter, r *http.Request) {
+		stockOpnameService := services.NewStockOpnameService(repositories.NewStockOpnameRepository(db))
+		stockOpnameHandler := handlers.NewStockOpnameHandler(stockOpnameService)
+
+		switch r.Method {
+		case "GET":
+			stockOpnameHandler.GetStockOpnames(w, r)
+		case "POST":
+			stockOpnameHandler.StartStockOpname(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/stock-opname/", cashier, func(w http.ResponseWriter, r *http.Request) {
+		stockOpnameService := services.NewStockOpnameService(repositories.NewStockOpnameRepository(db))
+		stockOpnameHandler := handlers.NewStockOpnameHandler(stockOpnameService)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/counts") && r.Method == "POST":
+			stockOpnameHandler.RecordStockOpnameCounts(w, r)
+		case strings.HasSuffix(r.URL.Path, "/submit") && r.Method == "POST":
+			stockOpnameHandler.SubmitStockOpname(w, r)
+		case strings.HasSuffix(r.URL.Path, "/approve") && r.Method == "POST":
+			stockOpnameHandler.ApproveStockOpname(w, r)
+		case strings.HasSuffix(r.URL.Path, "/reject") && r.Method == "POST":
+			stockOpnameHandler.RejectStockOpname(w, r)
+		case r.Method == "GET":
+			stockOpnameHandler.GetStockOpname(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/abc-classification", admin, func(w http.ResponseWriter, r *http.Request) {
+		abcHandler := handlers.NewABCHandler(abcService)
+
+		switch r.Method {
+		case "GET":
+			abcHandler.GetABCSummary(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/abc-classification/run", admin, func(w http.ResponseWriter, r *http.Request) {
+		abcHandler := handlers.NewABCHandler(abcService)
+
+		switch r.Method {
+		case "POST":
+			abcHandler.RunABCClassification(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/payment-links", cashier, func(w http.ResponseWriter, r *http.Request) {
+		paymentLinkHandler := handlers.NewPaymentLinkHandler(paymentLinkService)
+
+		switch r.Method {
+		case "GET":
+			paymentLinkHandler.GetPaymentLinks(w, r)
+		case "POST":
+			paymentLinkHandler.CreatePaymentLink(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/payment-links/", cashier, func(w http.ResponseWriter, r *http.Request) {
+		paymentLinkHandler := handlers.NewPaymentLinkHandler(paymentLinkService)
+
+		switch r.Method {
+		case "GET":
+			paymentLinkHandler.GetPaymentLinkByID(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	// called by the payment gateway, authenticated by the callback signature
+	api.HandleFunc("/api/payment-links/callback", public, func(w http.ResponseWriter, r *http.Request) {
+		paymentLinkHandler := handlers.NewPaymentLinkHandler(paymentLinkService)
+
+		switch r.Method {
+		case "POST":
+			paymentLinkHandler.PaymentCallback(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/held-carts", cashier, func(w http.ResponseWriter, r *http.Request) {
+		heldCartHandler := handlers.NewHeldCartHandler(heldCartService)
+
+		switch r.Method {
+		case "GET":
+			heldCartHandler.GetHeldCarts(w, r)
+		case "POST":
+			heldCartHandler.HoldCart(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/held-carts/", cashier, func(w http.ResponseWriter, r *http.Request) {
+		heldCartHandler := handlers.NewHeldCartHandler(heldCartService)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/resume") && r.Method == "POST":
+			heldCartHandler.ResumeHeldCart(w, r)
+		case r.Method == "GET":
+			heldCartHandler.GetHeldCartByID(w, r)
+		case r.Method == "DELETE":
+			heldCartHandler.DeleteHeldCart(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	terminalSessionHandler := handlers.NewTerminalSessionHandler(terminalSessionService)
+
+	api.HandleFunc("/api/terminal-sessions", cashier, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			terminalSessionHandler.StartTerminalSession(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/terminal-sessions/current", cashier, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			terminalSessionHandler.GetCurrentTerminalSession(w, r)
+		case "DELETE":
+			terminalSessionHandler.EndTerminalSession(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	permissionHandler := handlers.NewPermissionHandler(permissionService)
+
+	api.HandleFunc("/api/permissions", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			permissionHandler.GetPermissions(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/roles", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			permissionHandler.GetRoles(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/roles/", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PUT":
+			permissionHandler.SaveRole(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/me/permissions", cashier, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			permissionHandler.GetMyPermissions(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/admin/users/", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/pin") && r.Method == "PUT":
+			terminalSessionHandler.SetPIN(w, r)
+		case strings.HasSuffix(r.URL.Path, "/role") && r.Method == "PUT":
+			permissionHandler.SetUserRole(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/exports", admin, func(w http.ResponseWriter, r *http.Request) {
+		exportHandler := handlers.NewExportHandler(exportService)
+
+		switch r.Method {
+		case "GET":
+			exportHandler.GetExports(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/exports/stock-movements", admin, func(w http.ResponseWriter, r *http.Request) {
+		exportHandler := handlers.NewExportHandler(exportService)
+
+		switch r.Method {
+		case "POST":
+			exportHandler.ExportStockMovements(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/exports/expenses", admin, func(w http.ResponseWriter, r *http.Request) {
+		exportHandler := handlers.NewExportHandler(exportService)
+
+		switch r.Method {
+		case "POST":
+			exportHandler.ExportExpenses(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/exports/journal", admin, func(w http.ResponseWriter, r *http.Request) {
+		exportHandler := handlers.NewExportHandler(exportService)
+
+		switch r.Method {
+		case "POST":
+			exportHandler.ExportJournal(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/expenses", cashier, func(w http.ResponseWriter, r *http.Request) {
+		expenseHandler := handlers.NewExpenseHandler(expenseService)
+
+		switch r.Method {
+		case "GET":
+			expenseHandler.GetExpenses(w, r)
+		case "POST":
+			expenseHandler.CreateExpense(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/expenses/", cashier, func(w http.ResponseWriter, r *http.Request) {
+		expenseHandler := handlers.NewExpenseHandler(expenseService)
+
+		if strings.HasSuffix(r.URL.Path, "/attachment") {
+			switch r.Method {
+			case "GET":
+				expenseHandler.GetExpenseAttachment(w, r)
+			case "PUT":
+				expenseHandler.UploadExpenseAttachment(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			expenseHandler.GetExpenseByID(w, r)
+		case "DELETE":
+			expenseHandler.DeleteExpense(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/couriers", admin, func(w http.ResponseWriter, r *http.Request) {
+		courierHandler := handlers.NewCourierHandler(deliveryService)
+
+		switch r.Method {
+		case "GET":
+			courierHandler.GetCouriers(w, r)
+		case "POST":
+			courierHandler.CreateCourier(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/couriers/", admin, func(w http.ResponseWriter, r *http.Request) {
+		courierHandler := handlers.NewCourierHandler(deliveryService)
+
+		switch r.Method {
+		case "PUT":
+			courierHandler.UpdateCourier(w, r)
+		case "DELETE":
+			courierHandler.DeleteCourier(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	settingsHandler := handlers.NewSettingsHandler(settingsService)
+
+	api.HandleFunc("/api/settings", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			settingsHandler.GetSettings(w, r)
+		case "PUT":
+			settingsHandler.UpdateSettings(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	storeHandler := handlers.NewStoreHandler(services.NewStoreService(multiStore, repositories.NewStoreRepository(db)))
+
+	api.HandleFunc("/api/stores", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			storeHandler.GetStores(w, r)
+		case "POST":
+			storeHandler.CreateStore(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/stores/", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/prices"):
+			switch r.Method {
+			case "GET":
+				storeHandler.GetStorePrices(w, r)
+			case "PUT":
+				storeHandler.SetStorePrice(w, r)
+			case "DELETE":
+				storeHandler.DeleteStorePrice(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+		default:
+			switch r.Method {
+			case "PUT":
+				storeHandler.UpdateStore(w, r)
+			case "DELETE":
+				storeHandler.DeleteStore(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+		}
+	})
+
+	api.HandleFunc("/api/events", admin, func(w http.ResponseWriter, r *http.Request) {
+		eventHandler := handlers.NewEventHandler(eventBroadcaster)
+
+		switch r.Method {
+		case "GET":
+			eventHandler.StreamEvents(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	api.HandleFunc("/api/notification-channels", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			notificationHandler.GetNotificationChannels(w, r)
+		case "POST":
+			notificationHandler.CreateNotificationChannel(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/notification-channels/", admin, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/test"):
+			switch r.Method {
+			case "POST":
+				notificationHandler.TestNotificationChannel(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+		default:
+			switch r.Method {
+			case "PUT":
+				notificationHandler.UpdateNotificationChannel(w, r)
+			case "DELETE":
+				notificationHandler.DeleteNotificationChannel(w, r)
+			default:
+				utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+					Status:  "failed",
+					Message: "Method not allowed",
+				})
+			}
+		}
+	})
+
+	api.HandleFunc("/api/deliveries", cashier, func(w http.ResponseWriter, r *http.Request) {
+		deliveryHandler := handlers.NewDeliveryHandler(deliveryService)
+
+		switch r.Method {
+		case "GET":
+			deliveryHandler.GetDeliveries(w, r)
+		case "POST":
+			deliveryHandler.CreateDelivery(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/deliveries/", cashier, func(w http.ResponseWriter, r *http.Request) {
+		deliveryHandler := handlers.NewDeliveryHandler(deliveryService)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/courier") && r.Method == "PUT":
+			deliveryHandler.AssignDeliveryCourier(w, r)
+		case strings.HasSuffix(r.URL.Path, "/status") && r.Method == "PUT":
+			deliveryHandler.UpdateDeliveryStatus(w, r)
+		case strings.HasSuffix(r.URL.Path, "/proof") && r.Method == "PUT":
+			deliveryHandler.UploadDeliveryProof(w, r)
+		case strings.HasSuffix(r.URL.Path, "/proof") && r.Method == "GET":
+			deliveryHandler.GetDeliveryProof(w, r)
+		case r.Method == "GET":
+			deliveryHandler.GetDeliveryByID(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/tax-invoices", admin, func(w http.ResponseWriter, r *http.Request) {
+		taxInvoiceHandler := handlers.NewTaxInvoiceHandler(taxInvoiceService)
+
+		switch r.Method {
+		case "GET":
+			taxInvoiceHandler.GetTaxInvoices(w, r)
+		case "POST":
+			taxInvoiceHandler.IssueTaxInvoice(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/tax-invoices/", admin, func(w http.ResponseWriter, r *http.Request) {
+		taxInvoiceHandler := handlers.NewTaxInvoiceHandler(taxInvoiceService)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/pdf") && r.Method == "GET":
+			taxInvoiceHandler.GetTaxInvoicePDF(w, r)
+		case r.Method == "GET":
+			taxInvoiceHandler.GetTaxInvoiceByID(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	// Offline sync for POS terminals
+	// {{host}}/api/sync/changes?since=, {{host}}/api/sync/transactions
+	syncHandler := handlers.NewSyncHandler(services.NewTerminalSyncService(repositories.NewSyncRepository(db)))
+	api.HandleFunc("/api/sync/changes", cashier, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			syncHandler.GetChanges(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/sync/transactions", cashier, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			syncHandler.PushTransactions(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/exports/", admin, func(w http.ResponseWriter, r *http.Request) {
+		exportHandler := handlers.NewExportHandler(exportService)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/download") && r.Method == "GET":
+			exportHandler.DownloadExport(w, r)
+		case r.Method == "GET":
+			exportHandler.GetExportByID(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/push/vapid-public-key", admin, func(w http.ResponseWriter, r *http.Request) {
+		pushHandler := handlers.NewPushHandler(pushService)
+
+		switch r.Method {
+		case "GET":
+			pushHandler.GetVAPIDPublicKey(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/push/subscriptions", admin, func(w http.ResponseWriter, r *http.Request) {
+		pushHandler := handlers.NewPushHandler(pushService)
+
+		switch r.Method {
+		case "GET":
+			pushHandler.GetPushSubscriptions(w, r)
+		case "POST":
+			pushHandler.Subscribe(w, r)
+		case "DELETE":
+			pushHandler.Unsubscribe(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	api.HandleFunc("/api/push/test", admin, func(w http.ResponseWriter, r *http.Request) {
+		pushHandler := handlers.NewPushHandler(pushService)
+
+		switch r.Method {
+		case "POST":
+			pushHandler.SendTestPush(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	mobileService := services.NewMobileService(repositories.NewMobileRepository(db, settingsService), eventBus)
+	api.HandleFunc("/api/mobile/summary", admin, func(w http.ResponseWriter, r *http.Request) {
+		mobileHandler := handlers.NewMobileHandler(mobileService)
+
+		switch r.Method {
+		case "GET":
+			mobileHandler.GetMobileSummary(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	statsService := services.NewStatsService(repositories.NewStatsRepository(db, settingsService), eventBus)
+	api.HandleFunc("/api/stats", admin, func(w http.ResponseWriter, r *http.Request) {
+		statsHandler := handlers.NewStatsHandler(statsService)
+
+		switch r.Method {
+		case "GET":
+			statsHandler.GetStats(w, r)
+		default:
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+		}
+	})
+
+	// every subscriber is on the bus by now
+	outboxRelay.Start(outboxRelayInterval)
+
+	server := &http.Server{Addr: ":" + portStr, Handler: handler}
+
+	go func() {
+		fmt.Println("Server running on http://localhost:" + portStr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Error running server:", err)
+		}
+	}()
+	if diagnosticsServer != nil {
+		go func() {
+			log.Printf("Diagnostics: serving /debug/vars and /debug/pprof/ on %s", diagnosticsServer.Addr)
+			if err := diagnosticsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatal("Error running diagnostics server:", err)
+			}
+		}()
+	}
+
+	// graceful shutdown: stop accepting requests, then let running jobs drain
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+
+	shutdownTimeout := viper.GetDuration("SHUTDOWN_TIMEOUT")
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	log.Println("Shutting down...")
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println("Error shutting down server:", err)
+	}
+	if diagnosticsServer != nil {
+		diagnosticsServer.Close()
+	}
+	if err := jobRunner.Shutdown(ctx); err != nil {
+		log.Println("Jobs did not finish before shutdown timeout:", err)
+	}
+	outboxRelay.Stop()
+	reliabilityService.Stop()
+	if traceExporter != nil {
+		if err := traceExporter.Shutdown(ctx); err != nil {
+			log.Println("Error sending the last traces:", err)
+		}
+	}
+}
+
+// newDiagnosticsServer serves the runtime stats and pprof profiles at addr,
+// 127.0.0.1:6060 unless set. Only loopback addresses are allowed: nothing in
+// front of it checks who is asking.
+func newDiagnosticsServer(addr string, diagnostics *handlers.DiagnosticsHandler) (*http.Server, error) {
+	if addr == "" {
+		addr = "127.0.0.1:6060"
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DIAGNOSTICS_ADDR %q: %v", addr, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("DIAGNOSTICS_ADDR %q is not a loopback address", addr)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /debug/vars", diagnostics.GetVars)
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return &http.Server{Addr: addr, Handler: mux}, nil
+}
+
+// newReceiptNumbering hands out receipt numbers like INV/2024/06/000123, the
+// counter restarts every reset period
+func newReceiptNumbering(repo repositories.SequenceStore) *services.ReceiptNumbering {
+	receiptNumberFormat := viper.GetString("RECEIPT_NUMBER_FORMAT")
+	if receiptNumberFormat == "" {
+		receiptNumberFormat = "INV/{YYYY}/{MM}/{SEQ:6}"
+	}
+	receiptNumberReset := viper.GetString("RECEIPT_NUMBER_RESET")
+	if receiptNumberReset == "" {
+		receiptNumberReset = sequence.ResetMonthly
+	}
+
+	receiptFormat, err := sequence.ParseFormat(receiptNumberFormat, receiptNumberReset)
+	if err != nil {
+		log.Fatal("Error parsing receipt number format:", err)
+	}
+	receiptNumbering, err := services.NewReceiptNumbering(repo, receiptFormat, viper.GetString("STORE_CODE"))
+	if err != nil {
+		log.Fatal("Error configuring receipt numbers:", err)
+	}
+	return receiptNumbering
+}
+
+// compressionMinSize is the smallest response compressed, COMPRESSION_MIN_SIZE
+// bytes or 1 KiB
+func compressionMinSize() int {
+	if size := viper.GetInt("COMPRESSION_MIN_SIZE"); size > 0 {
+		return size
+	}
+	return 1024
+}
+
+// setErrorTracker sends panics and failed background work to Sentry when
+// SENTRY_DSN is set, tagged with SENTRY_ENVIRONMENT, APP_ENV unless set, and
+// SENTRY_RELEASE; otherwise they are only logged
+func setErrorTracker() {
+	dsn := viper.GetString("SENTRY_DSN")
+	if dsn == "" {
+		return
+	}
+	environment := viper.GetString("SENTRY_ENVIRONMENT")
+	if environment == "" {
+		environment = viper.GetString("APP_ENV")
+	}
+	tracker, err := errortracking.NewSentry(dsn, environment, viper.GetString("SENTRY_RELEASE"))
+	if err != nil {
+		log.Fatal("Error parsing SENTRY_DSN:", err)
+	}
+	errortracking.SetTracker(tracker)
+	log.Println("Error tracking: reporting to Sentry as", environment)
+}
+
+// categoryDeletePolicy is the policy of category deletions that don't name one
+func categoryDeletePolicy() string {
+	policy := viper.GetString("CATEGORY_DELETE_POLICY")
+	if !services.ValidDeletePolicy(policy) {
+		log.Fatal("CATEGORY_DELETE_POLICY must be block, cascade or reassign, not ", policy)
+	}
+	return policy
+}
+
+// cashRounding is how totals are rounded for cash, CASH_ROUNDING (none,
+// nearest, down or up) to multiples of CASH_ROUNDING_UNIT
+func cashRounding() money.Rounding {
+	rounding, err := money.NewRounding(viper.GetString("CASH_ROUNDING"), viper.GetInt64("CASH_ROUNDING_UNIT"))
+	if err != nil {
+		log.Fatal("Error parsing CASH_ROUNDING:", err)
+	}
+	return rounding
+}
+
+// defaultSettings are the store settings before they are first saved:
+// TAX_SELLER_NAME, TAX_SELLER_ADDRESS and TAX_SELLER_NPWP, STORE_CURRENCY,
+// IDR unless set, TAX_RATE, 11 unless set, and STORE_TIMEZONE
+func defaultSettings() models.StoreSettings {
+	settings := models.StoreSettings{
+		Name:     viper.GetString("TAX_SELLER_NAME"),
+		Address:  viper.GetString("TAX_SELLER_ADDRESS"),
+		Currency: viper.GetString("STORE_CURRENCY"),
+		TaxRate:  viper.GetInt("TAX_RATE"),
+		Timezone: storeTimezone(),
+	}
+	if npwp := viper.GetString("TAX_SELLER_NPWP"); npwp != "" {
+		var err error
+		if settings.NPWP, err = services.NormalizeNPWP(npwp); err != nil {
+			log.Fatal("Error parsing TAX_SELLER_NPWP:", err)
+		}
+	}
+	if settings.Currency == "" {
+		settings.Currency = money.Default
+	}
+	if settings.TaxRate <= 0 {
+		settings.TaxRate = 11
+	}
+	return settings
+}
+
+// storeTimezone is STORE_TIMEZONE, the IANA time zone the days of reports
+// start in unless a report asks for another
+func storeTimezone() string {
+	timezone := viper.GetString("STORE_TIMEZONE")
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "Local" {
+		log.Fatal("STORE_TIMEZONE must be an IANA time zone, not ", timezone)
+	}
+	return timezone
+}
+
+// catalogSyncHint lists what a terminal with a stale catalog fetches again
+const catalogSyncHint = "/api/product, /api/category, /api/pricing-rules"
+
+// newSKUNumbering hands out SKUs like SKU-000123 to products created without
+// one; the counter never resets
+func newSKUNumbering(repo repositories.SequenceStore) *services.SKUNumbering {
+	skuFormat := viper.GetString("SKU_FORMAT")
+	if skuFormat == "" {
+		skuFormat = "SKU-{SEQ:6}"
+	}
+
+	format, err := sequence.ParseFormat(skuFormat, sequence.ResetNever)
+	if err != nil {
+		log.Fatal("Error parsing SKU format:", err)
+	}
+	skuNumbering, err := services.NewSKUNumbering(repo, format, viper.GetString("STORE_CODE"))
+	if err != nil {
+		log.Fatal("Error configuring SKUs:", err)
+	}
+	return skuNumbering
+}
+
+// newTaxInvoiceNumbering hands out tax invoice numbers like 010.000-24.00000001,
+// transaction code, status and branch, the year and a counter restarting
+// yearly; the next value is set to the start of the range the tax office allots
+func newTaxInvoiceNumbering() *services.TaxInvoiceNumbering {
+	taxInvoiceFormat := viper.GetString("TAX_INVOICE_NUMBER_FORMAT")
+	if taxInvoiceFormat == "" {
+		taxInvoiceFormat = "010.000-{YY}.{SEQ:8}"
+	}
+	taxInvoiceReset := viper.GetString("TAX_INVOICE_NUMBER_RESET")
+	if taxInvoiceReset == "" {
+		taxInvoiceReset = sequence.ResetYearly
+	}
+
+	format, err := sequence.ParseFormat(taxInvoiceFormat, taxInvoiceReset)
+	if err != nil {
+		log.Fatal("Error parsing tax invoice number format:", err)
+	}
+	taxInvoiceNumbering, err := services.NewTaxInvoiceNumbering(format, viper.GetString("STORE_CODE"))
+	if err != nil {
+		log.Fatal("Error configuring tax invoice numbers:", err)
+	}
+	return taxInvoiceNumbering
+}
+
+// newStoreLocale picks the STORE_LOCALE preset, id-ID unless set, and applies
+// the store's currency, currencyCode, and its own currency and date formats
+// on top of it
+func newStoreLocale(currencyCode string) locale.Locale {
+	localeName := viper.GetString("STORE_LOCALE")
+	if localeName == "" {
+		localeName = locale.Default
+	}
+	storeLocale, err := locale.Lookup(localeName)
+	if err != nil {
+		log.Fatal("Error configuring store locale:", err)
+	}
+
+	storeLocale.Currency, err = money.Lookup(currencyCode)
+	if err != nil {
+		log.Fatal("Error configuring store currency:", err)
+	}
+
+	if currencyFormat := viper.GetString("STORE_CURRENCY_FORMAT"); currencyFormat != "" {
+		storeLocale.CurrencyFormat = currencyFormat
+	}
+	if dateFormat := viper.GetString("STORE_DATE_FORMAT"); dateFormat != "" {
+		storeLocale.DateFormat = dateFormat
+	}
+	if dateTimeFormat := viper.GetString("STORE_DATETIME_FORMAT"); dateTimeFormat != "" {
+		storeLocale.DateTimeFormat = dateTimeFormat
+	}
+	if err := storeLocale.Validate(); err != nil {
+		log.Fatal("Error configuring store locale:", err)
+	}
+	return storeLocale
+}
+
+// checkSettings is the settings check of the self-test: settings that are
+// optional on their own but required by the way the server is configured
+func checkSettings(ctx context.Context) error {
+	var missing []string
+	// exports link to the API, which customers can't reach on localhost
+	if viper.GetString("APP_ENV") == "production" && viper.GetString("APP_HOST") == "" {
+		missing = append(missing, "APP_HOST")
+	}
+	// mail servers reject receipts without a sender
+	if viper.GetString("SMTP_HOST") != "" && viper.GetString("SMTP_FROM") == "" {
+		missing = append(missing, "SMTP_FROM")
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing settings: %s", strings.Join(missing, ", "))
 	}
+	return nil
 }
@@ -0,0 +1,177 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// collector is a metric family that can render itself in the Prometheus text format
+type collector interface {
+	name() string
+	write(b *strings.Builder)
+}
+
+// Registry holds the metric families exposed on the metrics endpoint
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// Default is the registry NewCounter and NewSummary register with
+var Default = &Registry{}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Handler serves every registered metric in the Prometheus text exposition format
+func (r *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		collectors := append([]collector(nil), r.collectors...)
+		r.mu.Unlock()
+
+		sort.Slice(collectors, func(i, j int) bool { return collectors[i].name() < collectors[j].name() })
+
+		var b strings.Builder
+		for _, c := range collectors {
+			c.write(&b)
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write([]byte(b.String()))
+	}
+}
+
+// family keeps one value per combination of label values
+type family struct {
+	mu     sync.Mutex
+	metric string
+	help   string
+	labels []string
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	sum         float64
+	count       uint64
+}
+
+func newFamily(name, help string, labels []string) *family {
+	return &family{metric: name, help: help, labels: labels, series: map[string]*series{}}
+}
+
+func (f *family) name() string {
+	return f.metric
+}
+
+// get returns the series for labelValues; callers hold f.mu
+func (f *family) get(labelValues []string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.metric, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (f *family) sortedSeries() []*series {
+	list := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return strings.Join(list[i].labelValues, "\xff") < strings.Join(list[j].labelValues, "\xff")
+	})
+	return list
+}
+
+func (f *family) labelPairs(s *series) string {
+	if len(f.labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(f.labels))
+	for i, label := range f.labels {
+		pairs[i] = fmt.Sprintf("%s=%q", label, s.labelValues[i])
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Counter is a monotonically increasing value, e.g. the number of checkouts
+type Counter struct {
+	*family
+}
+
+// NewCounter creates a counter registered with the Default registry
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{newFamily(name, help, labels)}
+	Default.register(c)
+	return c
+}
+
+// Inc adds one to the series with the given label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v to the series with the given label values
+func (c *Counter) Add(v float64, labelValues ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.get(labelValues).value += v
+}
+
+func (c *Counter) write(b *strings.Builder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s counter\n", c.metric, c.help, c.metric)
+	for _, s := range c.sortedSeries() {
+		fmt.Fprintf(b, "%s%s %v\n", c.metric, c.labelPairs(s), s.value)
+	}
+}
+
+// Summary tracks the count and sum of observations, enough to chart averages
+// such as lines per basket
+type Summary struct {
+	*family
+}
+
+// NewSummary creates a summary registered with the Default registry
+func NewSummary(name, help string, labels ...string) *Summary {
+	s := &Summary{newFamily(name, help, labels)}
+	Default.register(s)
+	return s
+}
+
+// Observe records one observation in the series with the given label values
+func (s *Summary) Observe(v float64, labelValues ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	series := s.get(labelValues)
+	series.sum += v
+	series.count++
+}
+
+func (s *Summary) write(b *strings.Builder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s summary\n", s.metric, s.help, s.metric)
+	for _, series := range s.sortedSeries() {
+		labels := s.labelPairs(series)
+		fmt.Fprintf(b, "%s_sum%s %v\n", s.metric, labels, series.sum)
+		fmt.Fprintf(b, "%s_count%s %d\n", s.metric, labels, series.count)
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// CatalogVersionHeader carries the catalog version a response was served at
+	CatalogVersionHeader = "X-Catalog-Version"
+	// IfCatalogVersionHeader is sent by terminals with the version they cached
+	IfCatalogVersionHeader = "If-Catalog-Version"
+	// CatalogStaleHeader and CatalogSyncHeader tell a terminal its cache is
+	// behind and which resources to fetch again
+	CatalogStaleHeader = "X-Catalog-Stale"
+	CatalogSyncHeader  = "X-Catalog-Sync"
+)
+
+// CatalogVersions reads and moves the version of the catalog
+type CatalogVersions interface {
+	Current() (int64, error)
+	Bump() (int64, error)
+}
+
+// CatalogVersion stamps catalog responses with the catalog version, moving
+// it on every change that succeeds. A terminal sending If-Catalog-Version
+// with an older version is told to sync, so distributed terminals catch up
+// without a manual refresh.
+type CatalogVersion struct {
+	versions CatalogVersions
+	syncHint string
+}
+
+// NewCatalogVersion answers stale terminals with syncHint, the resources
+// holding the catalog
+func NewCatalogVersion(versions CatalogVersions, syncHint string) *CatalogVersion {
+	return &CatalogVersion{versions: versions, syncHint: syncHint}
+}
+
+// catalogWriter sets the catalog headers once the status is known, before
+// anything is written
+type catalogWriter struct {
+	http.ResponseWriter
+	stamp   func(status int)
+	stamped bool
+}
+
+func (w *catalogWriter) WriteHeader(status int) {
+	if !w.stamped {
+		w.stamped = true
+		w.stamp(status)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the writer underneath, e.g. to
+// flush a stream
+func (w *catalogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *catalogWriter) Write(b []byte) (int, error) {
+	if !w.stamped {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Middleware wraps next with the catalog version
+func (c *CatalogVersion) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		changes := r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions
+
+		cw := &catalogWriter{ResponseWriter: w}
+		cw.stamp = func(status int) {
+			var version int64
+			var err error
+			changed := changes && status < 400
+			if changed {
+				version, err = c.versions.Bump()
+			} else {
+				version, err = c.versions.Current()
+			}
+			if err != nil {
+				log.Println("Error reading catalog version:", err)
+				return
+			}
+			w.Header().Set(CatalogVersionHeader, strconv.FormatInt(version, 10))
+
+			cached := r.Header.Get(IfCatalogVersionHeader)
+			if cached == "" {
+				return
+			}
+			// the terminal's own change is the one that moved the version
+			expected := version
+			if changed {
+				expected--
+			}
+			if cached != strconv.FormatInt(expected, 10) {
+				w.Header().Set(CatalogStaleHeader, "true")
+				w.Header().Set(CatalogSyncHeader, c.syncHint)
+			}
+		}
+		next.ServeHTTP(cw, r)
+	})
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kasir-api/utils"
+)
+
+// ChaosRule injects faults into requests whose path starts with Path. Latency
+// is added to every matching request; ErrorRate and PartialRate are the
+// chances, from 0 to 1, of a simulated database error or a partial failure.
+type ChaosRule struct {
+	Method      string
+	Path        string
+	Latency     time.Duration
+	ErrorRate   float64
+	PartialRate float64
+}
+
+// ParseChaosRules parses rules separated by ";", each written as
+//
+//	[METHOD ]/path:fault=value[,fault=value]
+//
+// e.g. "POST /api/checkout:latency=2s,partial=0.1;/api/held-carts:error=0.2".
+// Faults are latency (a duration), error and partial (rates from 0 to 1).
+func ParseChaosRules(s string) ([]ChaosRule, error) {
+	rules := []ChaosRule{}
+	for _, spec := range strings.Split(s, ";") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		target, faults, ok := strings.Cut(spec, ":")
+		if !ok {
+			return nil, fmt.Errorf("chaos rule %q has no faults", spec)
+		}
+
+		var rule ChaosRule
+		fields := strings.Fields(target)
+		switch len(fields) {
+		case 1:
+			rule.Path = fields[0]
+		case 2:
+			rule.Method, rule.Path = strings.ToUpper(fields[0]), fields[1]
+		default:
+			return nil, fmt.Errorf("chaos rule %q has an invalid target", spec)
+		}
+
+		for _, fault := range strings.Split(faults, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(fault), "=")
+			var err error
+			switch name {
+			case "latency":
+				rule.Latency, err = time.ParseDuration(value)
+			case "error":
+				rule.ErrorRate, err = parseRate(value)
+			case "partial":
+				rule.PartialRate, err = parseRate(value)
+			default:
+				err = fmt.Errorf("unknown fault %q", name)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("chaos rule %q: %v", spec, err)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseRate(s string) (float64, error) {
+	rate, err := strconv.ParseFloat(s, 64)
+	if err != nil || rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("rate %q must be between 0 and 1", s)
+	}
+	return rate, nil
+}
+
+// Chaos injects latency, database errors and partial failures into matching
+// requests so the terminals' retry and idempotency handling can be exercised.
+// It is meant for development and staging only.
+type Chaos struct {
+	rules []ChaosRule
+}
+
+func NewChaos(rules []ChaosRule) *Chaos {
+	return &Chaos{rules: rules}
+}
+
+// discardWriter swallows the response of a request that fails after being processed
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+// Middleware wraps next with fault injection
+func (c *Chaos) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rule, ok := c.match(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if rule.Latency > 0 {
+			time.Sleep(rule.Latency)
+		}
+
+		// the request never reaches the handler, as if the database were down
+		if rand.Float64() < rule.ErrorRate {
+			log.Printf("Chaos: injected database error for %s %s", r.Method, r.URL.Path)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+				Status:  "failed",
+				Message: "Injected failure: pq: could not connect to database",
+			})
+			return
+		}
+
+		// the request is processed but the connection drops before the
+		// response arrives, so a retry repeats work that already happened
+		if rand.Float64() < rule.PartialRate {
+			log.Printf("Chaos: dropping response for %s %s after processing", r.Method, r.URL.Path)
+			next.ServeHTTP(&discardWriter{header: http.Header{}}, r)
+			panic(http.ErrAbortHandler)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *Chaos) match(r *http.Request) (ChaosRule, bool) {
+	for _, rule := range c.rules {
+		if rule.Method != "" && rule.Method != r.Method {
+			continue
+		}
+		if strings.HasPrefix(r.URL.Path, rule.Path) {
+			return rule, true
+		}
+	}
+	return ChaosRule{}, false
+}
@@ -0,0 +1,231 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// brotliLevel is the brotli quality responses are compressed at; the higher
+// ones cost more time than they save on a response made for each request
+const brotliLevel = 5
+
+// encoders are the content codings responses are compressed with, most
+// preferred first when a client takes several equally
+var encoders = []struct {
+	name      string
+	newWriter func(w io.Writer) io.WriteCloser
+}{
+	{"br", func(w io.Writer) io.WriteCloser { return brotli.NewWriterLevel(w, brotliLevel) }},
+	{"gzip", func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }},
+	{"deflate", func(w io.Writer) io.WriteCloser {
+		fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return fw
+	}},
+}
+
+// incompressible are the content types left as they are: media and archives
+// are compressed already, and event streams are flushed event by event
+var incompressible = []string{"image/", "video/", "audio/", "font/woff", "application/zip", "application/gzip", "application/pdf", "application/octet-stream", "text/event-stream"}
+
+// Compression compresses responses of at least minSize bytes in the coding
+// the client prefers of those it accepts in Accept-Encoding. Smaller
+// responses, whose headers would outweigh the saving, are sent as they are.
+type Compression struct {
+	minSize int
+}
+
+func NewCompression(minSize int) *Compression {
+	return &Compression{minSize: minSize}
+}
+
+// negotiate returns the encoder of the coding with the highest q-value in
+// accept, or -1 when no coding is acceptable
+func negotiate(accept string) int {
+	best, bestQ := -1, 0.0
+	wildcard := -1.0
+	qs := map[string]float64{}
+	for _, part := range strings.Split(accept, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "*" {
+			wildcard = q
+			continue
+		}
+		qs[coding] = q
+	}
+
+	for i, enc := range encoders {
+		q, ok := qs[enc.name]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = i, q
+		}
+	}
+	return best
+}
+
+// compressWriter holds the start of a response back until it reaches the
+// size worth compressing or the handler is done
+type compressWriter struct {
+	http.ResponseWriter
+	c       *Compression
+	encoder int
+	status  int
+	buf     bytes.Buffer
+	decided bool
+	enc     io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	// bodies that are empty, ranges or compressed already are passed through
+	if status < 200 || status == http.StatusNoContent || status == http.StatusNotModified || status == http.StatusPartialContent ||
+		w.Header().Get("Content-Encoding") != "" || !compressible(w.Header().Get("Content-Type")) {
+		w.passThrough()
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.decided {
+		if w.enc != nil {
+			return w.enc.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf.Write(b)
+	if w.buf.Len() >= w.c.minSize {
+		if err := w.compress(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// passThrough sends the response uncompressed from now on
+func (w *compressWriter) passThrough() {
+	w.decided = true
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() > 0 {
+		w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// compress starts the encoder with what was held back
+func (w *compressWriter) compress() error {
+	w.decided = true
+	h := w.Header()
+	h.Set("Content-Encoding", encoders[w.encoder].name)
+	h.Del("Content-Length")
+	// the compressed bytes differ from those a strong ETag was made of
+	if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+		h.Set("ETag", "W/"+etag)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	w.enc = encoders[w.encoder].newWriter(w.ResponseWriter)
+	_, err := w.enc.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// FlushError sends what is held back, compressed when it has to be, e.g.
+// for a long report written in parts
+func (w *compressWriter) FlushError() error {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		if err := w.compress(); err != nil {
+			return err
+		}
+	}
+	if f, ok := w.enc.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the writer underneath, e.g. to
+// set a deadline
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close sends what is left: a response that stayed below the size worth
+// compressing is sent as it is
+func (w *compressWriter) close() error {
+	if w.status == 0 {
+		// the handler wrote nothing at all
+		return nil
+	}
+	if !w.decided {
+		w.passThrough()
+		return nil
+	}
+	if w.enc != nil {
+		return w.enc.Close()
+	}
+	return nil
+}
+
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	for _, prefix := range incompressible {
+		if strings.HasPrefix(mediaType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware wraps next with response compression
+func (c *Compression) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the response differs by Accept-Encoding, whether compressed or not
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoder := negotiate(r.Header.Get("Accept-Encoding"))
+		if encoder < 0 || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, c: c, encoder: encoder}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
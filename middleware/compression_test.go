@@ -0,0 +1,33 @@
+package middleware
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"GZIP", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"deflate, gzip", "gzip"},
+		{"br;q=0.5, gzip;q=0.8", "gzip"},
+		{"br;q=0, gzip;q=0", ""},
+		{"*", "br"},
+		{"*;q=0.5, br;q=0", "gzip"},
+		{"gzip;q=0.2, *;q=0.5", "br"},
+		{"gzip;q=bogus, deflate", "deflate"},
+		{" gzip ; q=0.9 , deflate;q=1", "deflate"},
+	}
+	for _, tt := range tests {
+		got := ""
+		if i := negotiate(tt.accept); i >= 0 {
+			got = encoders[i].name
+		}
+		if got != tt.want {
+			t.Errorf("negotiate(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// DiagnosticsGuard answers 404 Not Found to every path under prefix, keeping
+// the runtime diagnostics off the API's listener. It is needed because
+// net/http/pprof serves its profiles from the default mux as soon as it is
+// imported.
+type DiagnosticsGuard struct {
+	prefix string
+}
+
+func NewDiagnosticsGuard(prefix string) *DiagnosticsGuard {
+	return &DiagnosticsGuard{prefix: prefix}
+}
+
+// Middleware wraps next with the guard
+func (g *DiagnosticsGuard) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, g.prefix) {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,326 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"kasir-api/utils"
+)
+
+// spec is the subset of a Swagger 2.0 document needed to validate requests
+type spec struct {
+	BasePath    string                          `json:"basePath"`
+	Paths       map[string]map[string]operation `json:"paths"`
+	Definitions map[string]*schema              `json:"definitions"`
+}
+
+type operation struct {
+	Parameters []parameter `json:"parameters"`
+}
+
+type parameter struct {
+	Name     string        `json:"name"`
+	In       string        `json:"in"`
+	Required bool          `json:"required"`
+	Type     string        `json:"type"`
+	Enum     []interface{} `json:"enum"`
+	Schema   *schema       `json:"schema"`
+}
+
+type schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Properties map[string]*schema `json:"properties"`
+	Items      *schema            `json:"items"`
+	Required   []string           `json:"required"`
+	Minimum    *float64           `json:"minimum"`
+	Maximum    *float64           `json:"maximum"`
+	Enum       []interface{}      `json:"enum"`
+}
+
+type route struct {
+	segments   []string
+	operations map[string]operation
+}
+
+// RequestValidator rejects requests that don't match the OpenAPI (Swagger 2.0)
+// document generated from the handler annotations. Routes missing from the
+// document are passed through untouched.
+type RequestValidator struct {
+	spec   spec
+	routes []route
+}
+
+// NewRequestValidator parses a Swagger 2.0 JSON document
+func NewRequestValidator(doc []byte) (*RequestValidator, error) {
+	var s spec
+	if err := json.Unmarshal(doc, &s); err != nil {
+		return nil, fmt.Errorf("error parsing OpenAPI document: %v", err)
+	}
+
+	v := &RequestValidator{spec: s}
+	for path, ops := range s.Paths {
+		normalized := make(map[string]operation, len(ops))
+		for method, op := range ops {
+			normalized[strings.ToUpper(method)] = op
+		}
+		v.routes = append(v.routes, route{
+			segments:   splitPath(strings.TrimSuffix(s.BasePath, "/") + path),
+			operations: normalized,
+		})
+	}
+
+	// static segments win over parameters, e.g. /report/profit before /report/{id}
+	sort.Slice(v.routes, func(i, j int) bool {
+		return countParams(v.routes[i].segments) < countParams(v.routes[j].segments)
+	})
+
+	return v, nil
+}
+
+// Middleware wraps next with request validation
+func (v *RequestValidator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op, pathParams, ok := v.match(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if errs := v.validate(r, op, pathParams); len(errs) > 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:    "failed",
+				Message:   "Request validation failed: " + errs[0].Message,
+				ErrorCode: "validation_failed",
+				Errors:    errs,
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (v *RequestValidator) match(r *http.Request) (operation, map[string]string, bool) {
+	segments := splitPath(r.URL.Path)
+
+	for _, rt := range v.routes {
+		if len(rt.segments) != len(segments) {
+			continue
+		}
+
+		params := map[string]string{}
+		matched := true
+		for i, seg := range rt.segments {
+			if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+				params[seg[1:len(seg)-1]] = segments[i]
+				continue
+			}
+			if seg != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		op, ok := rt.operations[r.Method]
+		return op, params, ok
+	}
+
+	return operation{}, nil, false
+}
+
+// validate returns every problem found with the request, not just the first
+func (v *RequestValidator) validate(r *http.Request, op operation, pathParams map[string]string) []utils.FieldError {
+	query := r.URL.Query()
+	var errs []utils.FieldError
+
+	for _, p := range op.Parameters {
+		switch p.In {
+		case "path":
+			if err := validateParam(p, pathParams[p.Name], true); err != nil {
+				errs = append(errs, utils.FieldError{Field: p.Name, Message: err.Error()})
+			}
+		case "query":
+			if err := validateParam(p, query.Get(p.Name), query.Has(p.Name)); err != nil {
+				errs = append(errs, utils.FieldError{Field: p.Name, Message: err.Error()})
+			}
+		case "body":
+			errs = v.validateBody(r, p, errs)
+		}
+	}
+
+	return errs
+}
+
+func validateParam(p parameter, value string, present bool) error {
+	if !present || value == "" {
+		if p.Required {
+			return fmt.Errorf("%s parameter %q is required", p.In, p.Name)
+		}
+		return nil
+	}
+
+	switch p.Type {
+	case "integer":
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%s parameter %q must be an integer", p.In, p.Name)
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%s parameter %q must be a number", p.In, p.Name)
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s parameter %q must be a boolean", p.In, p.Name)
+		}
+	}
+
+	if len(p.Enum) > 0 && !inEnum(value, p.Enum) {
+		return fmt.Errorf("%s parameter %q must be one of %v", p.In, p.Name, p.Enum)
+	}
+	return nil
+}
+
+func (v *RequestValidator) validateBody(r *http.Request, p parameter, errs []utils.FieldError) []utils.FieldError {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return append(errs, utils.FieldError{Field: "body", Message: "error reading request body"})
+	}
+	r.Body.Close()
+	// handlers decode the body again after validation
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if p.Required {
+			return append(errs, utils.FieldError{Field: "body", Message: "request body is required"})
+		}
+		return errs
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return append(errs, utils.FieldError{Field: "body", Message: "request body is not valid JSON"})
+	}
+
+	if p.Schema == nil {
+		return errs
+	}
+	return v.validateValue("body", p.Schema, value, errs)
+}
+
+func (v *RequestValidator) validateValue(field string, s *schema, value interface{}, errs []utils.FieldError) []utils.FieldError {
+	invalid := func(format string, args ...interface{}) []utils.FieldError {
+		return append(errs, utils.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	s = v.resolve(s)
+	if s == nil || value == nil {
+		return errs
+	}
+
+	switch s.Type {
+	case "object", "":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			if s.Type == "" && len(s.Properties) == 0 {
+				return errs
+			}
+			return invalid("%s must be an object", field)
+		}
+		for _, name := range s.Required {
+			if val, ok := obj[name]; !ok || val == nil {
+				errs = append(errs, utils.FieldError{Field: field + "." + name, Message: fmt.Sprintf("%s.%s is required", field, name)})
+			}
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if val, ok := obj[name]; ok {
+				errs = v.validateValue(field+"."+name, s.Properties[name], val, errs)
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return invalid("%s must be an array", field)
+		}
+		for i, item := range arr {
+			errs = v.validateValue(fmt.Sprintf("%s[%d]", field, i), s.Items, item, errs)
+		}
+	case "integer", "number":
+		num, ok := value.(json.Number)
+		if !ok {
+			return invalid("%s must be of type %s", field, s.Type)
+		}
+		f, err := num.Float64()
+		if err != nil || (s.Type == "integer" && f != math.Trunc(f)) {
+			return invalid("%s must be of type %s", field, s.Type)
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			return invalid("%s must be at least %v", field, *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			return invalid("%s must be at most %v", field, *s.Maximum)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return invalid("%s must be a string", field)
+		}
+		if len(s.Enum) > 0 && !inEnum(str, s.Enum) {
+			return invalid("%s must be one of %v", field, s.Enum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return invalid("%s must be a boolean", field)
+		}
+	}
+
+	return errs
+}
+
+// resolve follows a local "#/definitions/..." reference
+func (v *RequestValidator) resolve(s *schema) *schema {
+	for s != nil && s.Ref != "" {
+		s = v.spec.Definitions[strings.TrimPrefix(s.Ref, "#/definitions/")]
+	}
+	return s
+}
+
+func inEnum(value string, enum []interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func countParams(segments []string) int {
+	n := 0
+	for _, seg := range segments {
+		if strings.HasPrefix(seg, "{") {
+			n++
+		}
+	}
+	return n
+}
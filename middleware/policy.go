@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/utils"
+)
+
+// PermissionSource resolves the permissions of whoever a terminal session
+// token is for; ok is false when the token signs no one in
+type PermissionSource interface {
+	Permissions(ctx context.Context, token string) (permissions []string, ok bool, err error)
+}
+
+// Policy refuses the requests to a route its rules guard unless the cashier
+// signed in with the X-Terminal-Session they carry holds the permission:
+// 401 Unauthorized without a session, 403 Forbidden without the permission.
+// The first rule matching a request applies. API routes matching no rule are
+// refused with 403 unless they are public; other paths, such as the docs,
+// pass.
+type Policy struct {
+	source PermissionSource
+	rules  []models.PermissionRule
+	public []string
+}
+
+func NewPolicy(source PermissionSource, rules []models.PermissionRule, public []string) *Policy {
+	return &Policy{source: source, rules: rules, public: public}
+}
+
+// Middleware wraps next with the policy
+func (p *Policy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions || p.isPublic(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rule, ok := p.match(r)
+		if !ok && strings.HasPrefix(r.URL.Path, "/api/") {
+			utils.WriteJSON(w, http.StatusForbidden, utils.Response{
+				Status:  "failed",
+				Message: "No permission grants this route",
+			})
+			return
+		}
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		permissions, ok, err := p.source.Permissions(r.Context(), strings.TrimSpace(r.Header.Get("X-Terminal-Session")))
+		if err != nil {
+			log.Println("Error checking permissions:", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+				Status:  "failed",
+				Message: "Failed to check permissions",
+			})
+			return
+		}
+		if !ok {
+			utils.WriteJSON(w, http.StatusUnauthorized, utils.Response{
+				Status:  "failed",
+				Message: "Sign in on the terminal with a PIN first",
+			})
+			return
+		}
+		for _, permission := range permissions {
+			if permission == rule.Permission {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		utils.WriteJSON(w, http.StatusForbidden, utils.Response{
+			Status:  "failed",
+			Message: "Missing permission " + rule.Permission,
+		})
+	})
+}
+
+// match returns the first rule guarding r
+func (p *Policy) match(r *http.Request) (models.PermissionRule, bool) {
+	for _, rule := range p.rules {
+		if len(rule.Methods) > 0 && !containsMethod(rule.Methods, r.Method) {
+			continue
+		}
+		if matchesRoute(rule.Path, r.URL.Path) {
+			return rule, true
+		}
+	}
+	return models.PermissionRule{}, false
+}
+
+func (p *Policy) isPublic(path string) bool {
+	for _, pattern := range p.public {
+		if matchesRoute(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesRoute reports whether path is served by a route pattern: one ending
+// in / serves everything under it, * stands for one path segment
+func matchesRoute(pattern, p string) bool {
+	if strings.Contains(pattern, "*") {
+		ok, _ := path.Match(pattern, p)
+		return ok
+	}
+	if strings.HasSuffix(pattern, "/") {
+		return strings.HasPrefix(p, pattern)
+	}
+	return pattern == p
+}
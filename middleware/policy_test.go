@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"kasir-api/models"
+)
+
+func TestMatchesRoute(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/api/product", "/api/product", true},
+		{"/api/product", "/api/product/1", false},
+		{"/api/product", "/api/products", false},
+		{"/api/product/", "/api/product/1", true},
+		{"/api/product/", "/api/product/1/stock", true},
+		{"/api/product/", "/api/product", false},
+		{"/api/product/*/stock", "/api/product/1/stock", true},
+		{"/api/product/*/stock", "/api/product/1/2/stock", false},
+		{"/api/product/*/stock", "/api/product/1/suppliers", false},
+		{"/api/*/prices", "/api/stores/prices", true},
+	}
+	for _, tt := range tests {
+		if got := matchesRoute(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchesRoute(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyMatch(t *testing.T) {
+	policy := NewPolicy(nil, []models.PermissionRule{
+		{Methods: []string{"GET"}, Path: "/api/product/", Permission: "product.view"},
+		{Path: "/api/product/*/stock", Permission: "stock.adjust"},
+		{Path: "/api/product/", Permission: "product.manage"},
+		{Methods: []string{"POST", "PUT"}, Path: "/api/checkout", Permission: "sale.create"},
+	}, nil)
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{"GET", "/api/product/1/stock", "product.view"},
+		{"POST", "/api/product/1/stock", "stock.adjust"},
+		{"DELETE", "/api/product/1", "product.manage"},
+		{"POST", "/api/checkout", "sale.create"},
+		{"GET", "/api/checkout", ""},
+		{"GET", "/api/report", ""},
+	}
+	for _, tt := range tests {
+		rule, ok := policy.match(httptest.NewRequest(tt.method, tt.path, nil))
+		if got := rule.Permission; got != tt.want || ok != (tt.want != "") {
+			t.Errorf("match(%s %s) = %q, %v, want %q", tt.method, tt.path, got, ok, tt.want)
+		}
+	}
+}
+
+// permissions is a PermissionSource signing in the tokens it holds
+type permissions map[string][]string
+
+func (p permissions) Permissions(ctx context.Context, token string) ([]string, bool, error) {
+	granted, ok := p[token]
+	return granted, ok, nil
+}
+
+func TestPolicyMiddleware(t *testing.T) {
+	policy := NewPolicy(permissions{"cashier": {"sale.create"}}, []models.PermissionRule{
+		{Path: "/api/checkout", Permission: "sale.create"},
+		{Path: "/api/report", Permission: "report.view"},
+	}, []string{"/api/public/"})
+	handler := policy.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"POST", "/api/checkout", "cashier", http.StatusNoContent},
+		{"POST", "/api/checkout", "", http.StatusUnauthorized},
+		{"POST", "/api/checkout", "stranger", http.StatusUnauthorized},
+		{"GET", "/api/report", "cashier", http.StatusForbidden},
+		{"GET", "/api/unguarded", "cashier", http.StatusForbidden},
+		{"GET", "/api/public/availability", "", http.StatusNoContent},
+		{"OPTIONS", "/api/report", "", http.StatusNoContent},
+		{"GET", "/docs/index.html", "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.token != "" {
+			r.Header.Set("X-Terminal-Session", tt.token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s %s with %q = %d, want %d", tt.method, tt.path, tt.token, w.Code, tt.want)
+		}
+	}
+}
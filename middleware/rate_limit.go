@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kasir-api/utils"
+)
+
+// bucket holds the requests a client may still make; it refills continuously
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimit allows each client limit requests per window, with bursts of up
+// to limit, and answers 429 beyond that. Clients are told apart by IP
+// address; behind a reverse proxy on the same host or private network, the
+// address the proxy appended to X-Forwarded-For is used instead.
+type RateLimit struct {
+	mu        sync.Mutex
+	limit     float64
+	window    time.Duration
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewRateLimit(limit int, window time.Duration) *RateLimit {
+	return &RateLimit{
+		limit:   float64(limit),
+		window:  window,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Middleware wraps next with the rate limit
+func (l *RateLimit) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wait, ok := l.take(clientIP(r), time.Now())
+		if !ok {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			utils.WriteJSON(w, http.StatusTooManyRequests, utils.Response{
+				Status:  "failed",
+				Message: "Too many requests, try again later",
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take spends one of client's tokens, or reports how long until one is available
+func (l *RateLimit) take(client string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rate := l.limit / l.window.Seconds()
+	l.sweep(now, rate)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.limit, last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = math.Min(l.limit, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// sweep forgets the clients whose buckets have refilled, at most once a
+// window, so the map doesn't grow with every address ever seen; callers hold l.mu
+func (l *RateLimit) sweep(now time.Time, rate float64) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rate >= l.limit {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	// only a proxy we run can be trusted with X-Forwarded-For; its last entry
+	// is the address the proxy itself saw, the earlier ones are the client's word
+	ip := net.ParseIP(host)
+	if ip != nil && (ip.IsLoopback() || ip.IsPrivate()) {
+		forwarded := r.Header.Values("X-Forwarded-For")
+		if len(forwarded) > 0 {
+			hops := strings.Split(forwarded[len(forwarded)-1], ",")
+			if last := strings.TrimSpace(hops[len(hops)-1]); net.ParseIP(last) != nil {
+				return last
+			}
+		}
+	}
+	return host
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"kasir-api/errortracking"
+	"kasir-api/utils"
+)
+
+// Recovery turns a panicking handler into a 500 response in the usual
+// envelope instead of a connection closed on the terminal, and logs the
+// stack with the request ID so it can be found again. The panic is reported
+// to the error tracker, when one is set.
+type Recovery struct{}
+
+func NewRecovery() *Recovery {
+	return &Recovery{}
+}
+
+// recoveryWriter remembers whether the response was started, after which a
+// 500 can no longer be written
+type recoveryWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *recoveryWriter) WriteHeader(status int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recoveryWriter) Write(b []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the writer underneath, e.g. to
+// flush a stream
+func (w *recoveryWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Middleware wraps next with panic recovery
+func (m *Recovery) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &recoveryWriter{ResponseWriter: w}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			// the handler giving up on purpose, e.g. a proxied stream
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			stack := debug.Stack()
+			log.Printf("Panic serving %s %s (request %s): %v\n%s", r.Method, r.URL.Path, r.Header.Get(utils.RequestIDHeader), p, stack)
+			errortracking.ReportPanic(r, p, stack)
+
+			// half a response is all the terminal gets; closing the connection says so
+			if rw.started {
+				panic(http.ErrAbortHandler)
+			}
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+				Status:  "failed",
+				Message: "Internal server error",
+			})
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
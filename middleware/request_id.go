@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"kasir-api/utils"
+)
+
+// maxRequestIDLength bounds the IDs accepted from clients
+const maxRequestIDLength = 64
+
+// RequestID gives every request an ID, echoed in the X-Request-Id response
+// header and in the response body, so a terminal's report can be matched
+// with the server logs and the request journal. Terminals may send their own
+// ID; anything else gets a random one.
+type RequestID struct{}
+
+func NewRequestID() *RequestID {
+	return &RequestID{}
+}
+
+// Middleware wraps next with request IDs
+func (m *RequestID) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(utils.RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+			r.Header.Set(utils.RequestIDHeader, id)
+		}
+		w.Header().Set(utils.RequestIDHeader, id)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/utils"
+)
+
+// maxJournalResponse is the most of a response body kept in the journal, in bytes
+const maxJournalResponse = 64 << 10
+
+// JournalRecorder stores captured requests; it is responsible for redacting them
+type JournalRecorder interface {
+	Record(entry models.JournalEntry) error
+}
+
+// RequestJournal captures request/response pairs of selected endpoints so a
+// terminal's failing request can be inspected and replayed later
+type RequestJournal struct {
+	recorder JournalRecorder
+	paths    []string
+}
+
+// NewRequestJournal captures requests whose path starts with one of paths
+func NewRequestJournal(recorder JournalRecorder, paths []string) *RequestJournal {
+	return &RequestJournal{recorder: recorder, paths: paths}
+}
+
+// journalWriter keeps the status and the start of the body written by a handler
+type journalWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *journalWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the writer underneath, e.g. to
+// flush a stream
+func (w *journalWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *journalWriter) Write(b []byte) (int, error) {
+	if room := maxJournalResponse - w.body.Len(); room > 0 {
+		if len(b) < room {
+			room = len(b)
+		}
+		w.body.Write(b[:room])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Middleware wraps next with request capture
+func (j *RequestJournal) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !j.captures(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Response{
+				Status:  "failed",
+				Message: "Error reading request body",
+			})
+			return
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		start := time.Now()
+		jw := &journalWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(jw, r)
+
+		headers := make(map[string]string, len(r.Header))
+		for name := range r.Header {
+			headers[name] = r.Header.Get(name)
+		}
+
+		err = j.recorder.Record(models.JournalEntry{
+			Method:         r.Method,
+			Path:           r.URL.Path,
+			Query:          r.URL.RawQuery,
+			RequestHeaders: headers,
+			RequestBody:    string(body),
+			ResponseStatus: jw.status,
+			ResponseBody:   jw.body.String(),
+			DurationMs:     int(time.Since(start).Milliseconds()),
+		})
+		if err != nil {
+			log.Println("Error recording request journal entry:", err)
+		}
+	})
+}
+
+func (j *RequestJournal) captures(path string) bool {
+	for _, prefix := range j.paths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ResponseCounter tallies responses by status code
+type ResponseCounter interface {
+	CountResponse(status int)
+}
+
+// ResponseCount feeds the status of every API response to a counter, the
+// request side of the error budget. Docs, health checks and metrics scrapes
+// are left out so they don't water down the error rate.
+type ResponseCount struct {
+	counter ResponseCounter
+	prefix  string
+}
+
+// NewResponseCount counts responses to requests whose path starts with prefix
+func NewResponseCount(counter ResponseCounter, prefix string) *ResponseCount {
+	return &ResponseCount{counter: counter, prefix: prefix}
+}
+
+// statusWriter remembers the status code written by a handler
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the writer underneath, e.g. to
+// flush a stream
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Middleware wraps next with response counting
+func (c *ResponseCount) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, c.prefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		// a panicking handler still counts, as the 500 the server answers with
+		defer func() {
+			if err := recover(); err != nil {
+				c.counter.CountResponse(http.StatusInternalServerError)
+				panic(err)
+			}
+			c.counter.CountResponse(sw.status)
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
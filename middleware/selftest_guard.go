@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"kasir-api/utils"
+)
+
+// HealthReporter tells whether the critical startup checks passed
+type HealthReporter interface {
+	Healthy() bool
+}
+
+// SelfTestGuard refuses requests that change data while a critical startup
+// check fails, e.g. when the database was migrated by a newer build. Reads
+// keep working so the problem can be looked into.
+type SelfTestGuard struct {
+	health HealthReporter
+	exempt []string
+}
+
+// NewSelfTestGuard guards every path except those starting with one of exempt,
+// such as the endpoint that runs the self-test again
+func NewSelfTestGuard(health HealthReporter, exempt ...string) *SelfTestGuard {
+	return &SelfTestGuard{health: health, exempt: exempt}
+}
+
+// Middleware wraps next with the guard
+func (g *SelfTestGuard) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		for _, prefix := range g.exempt {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if !g.health.Healthy() {
+			utils.WriteJSON(w, http.StatusServiceUnavailable, utils.Response{
+				Status:    "failed",
+				Message:   "Self-test failed, changes are refused until it passes; see /api/admin/selftest",
+				ErrorCode: "self_test_failed",
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,24 @@
+package models
+
+import "kasir-api/money"
+
+// ProductRevenue is the revenue of a product over the classification window
+type ProductRevenue struct {
+	ProductID int
+	Revenue   money.Amount
+}
+
+type ABCClassSummary struct {
+	Class        string       `json:"class"`
+	Products     int          `json:"products"`
+	Revenue      money.Amount `json:"revenue"`
+	RevenueShare float64      `json:"revenue_share"`
+}
+
+// ABCSummary is the outcome of the latest ABC classification
+type ABCSummary struct {
+	WindowDays   int               `json:"window_days"`
+	TotalRevenue money.Amount      `json:"total_revenue"`
+	ClassifiedAt string            `json:"classified_at,omitempty"`
+	Classes      []ABCClassSummary `json:"classes"`
+}
@@ -0,0 +1,25 @@
+package models
+
+// ActivityEntry is one thing that happened to the catalog or to a sale, e.g. a
+// product repriced or a sale refunded. OldValue and NewValue are what changed:
+// the old and new name or price, the total and reason of a sale voided or
+// refunded, or what a line of a sale came to and was sold for at a price
+// override, who approved it and why. Message says it in words.
+type ActivityEntry struct {
+	ID        int    `json:"id"`
+	Entity    string `json:"entity" enums:"product,category,transaction"`
+	EntityID  int    `json:"entity_id"`
+	Name      string `json:"name"`
+	Action    string `json:"action" enums:"created,renamed,price_changed,deleted,voided,refunded,price_overridden"`
+	OldValue  string `json:"old_value,omitempty"`
+	NewValue  string `json:"new_value,omitempty"`
+	Message   string `json:"message" example:"Price of Indomie Goreng changed from Rp3000 to Rp3500"`
+	CreatedAt string `json:"created_at"`
+}
+
+// ActivityPage is a page of the activity feed, newest first. NextCursor
+// fetches the page after it and is left out on the last page.
+type ActivityPage struct {
+	Activities []ActivityEntry `json:"activities"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,37 @@
+package models
+
+// AdminUser is a back-office account; the password hash never leaves the repository
+type AdminUser struct {
+	ID            int    `json:"id"`
+	Username      string `json:"username"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified bool   `json:"email_verified"`
+	Role          string `json:"role"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// RegisterAdminRequest creates an account; with an email, a link to confirm it is sent there
+type RegisterAdminRequest struct {
+	Username string `json:"username" validate:"required" example:"budi"`
+	Email    string `json:"email" example:"budi@example.com"`
+	Password string `json:"password" validate:"required" example:"rahasia123"`
+	// Role is the role whose permissions the account gets; only a holder of
+	// system.admin can give the admin role
+	Role string `json:"role" validate:"required" example:"cashier"`
+}
+
+// AuthEmailRequest names the address a verification or password reset link is sent to
+type AuthEmailRequest struct {
+	Email string `json:"email" validate:"required" example:"budi@example.com"`
+}
+
+// VerifyEmailRequest confirms an admin's email address with the token from the link sent there
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ResetPasswordRequest sets a new password with the token from a password reset link
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required" example:"rahasia456"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// ProductAvailability is what the public availability widget may show about a
+// product: whether it can be bought, never how many are left
+type ProductAvailability struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	InStock bool   `json:"in_stock"`
+}
+
+type PublicAvailabilityRequest struct {
+	Public bool `json:"public" validate:"required"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "kasir-api/money"
+
+// BundleComponent is one product a bundle is made of, Quantity units per bundle
+type BundleComponent struct {
+	ProductID   int          `json:"product_id" validate:"required" minimum:"1"`
+	ProductName string       `json:"product_name,omitempty"`
+	Quantity    int          `json:"quantity" validate:"required" minimum:"1"`
+	Price       money.Amount `json:"price"`
+	Stock       int          `json:"stock"`
+}
+
+type BundleRequest struct {
+	Components []BundleComponent `json:"components" validate:"required"`
+}
+
+// BundleComponentSale is what a bundle line took out of stock, with its share
+// of the line's subtotal
+type BundleComponentSale struct {
+	ProductID      int          `json:"product_id"`
+	ProductName    string       `json:"product_name,omitempty"`
+	ProductDeleted bool         `json:"product_deleted,omitempty"`
+	Quantity       int          `json:"quantity"`
+	Revenue        money.Amount `json:"revenue"`
+	Cost           money.Amount `json:"-"`
+}
@@ -1,11 +1,31 @@
 package models
 
-import "google.golang.org/protobuf/types/known/timestamppb"
+import "time"
 
 // Category represents a category in the cashier system
 type Category struct {
-	ID          int                    `json:"id,omitempty"`
-	Name        string                 `json:"name,omitempty"`
-	Description string                 `json:"description,omitempty"`
-	DeletedAt   *timestamppb.Timestamp `json:"deleted_at,omitempty"`
+	ID          int        `json:"id,omitempty"`
+	Name        string     `json:"name,omitempty"`
+	Description string     `json:"description,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+}
+
+// CategoryBatchResult is the outcome of one category of a batch, at the
+// index it had in the request
+type CategoryBatchResult struct {
+	Index    int       `json:"index"`
+	Status   string    `json:"status" enums:"created,updated,failed,skipped"`
+	Category *Category `json:"category,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// CategoryDeletion is what deleting a category did to its products, under
+// the policy it was deleted with
+type CategoryDeletion struct {
+	Policy             string `json:"policy" enums:"block,cascade,reassign"`
+	ProductsDeleted    int    `json:"products_deleted"`
+	ProductsReassigned int    `json:"products_reassigned"`
+	UncategorizedID    int    `json:"uncategorized_id,omitempty"`
 }
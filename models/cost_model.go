@@ -0,0 +1,66 @@
+package models
+
+import "kasir-api/money"
+
+// CostMovement is one entry of a product's stock ledger as replayed by the
+// cost recalculation. UnitCost is the cost recorded on the goods receipt,
+// 0 for movements other than receipts.
+type CostMovement struct {
+	ProductID int
+	Quantity  int
+	Receipt   bool
+	UnitCost  money.Amount
+}
+
+// ProductCost is the current cost price of a product with the stock it values
+type ProductCost struct {
+	ProductID int
+	Name      string
+	CostPrice money.Amount
+	Stock     int
+	// LossQuantity is the stock lost to cycle counts, stock opnames and
+	// adjustments, which the shrinkage report values at the current cost price
+	LossQuantity int
+}
+
+// CostRecalculation is the difference between the cost prices on record and
+// the moving average cost rebuilt from the goods receipts
+type CostRecalculation struct {
+	DryRun bool `json:"dry_run"`
+	// Updated is the number of cost prices written, 0 on a dry run
+	Updated  int                 `json:"updated"`
+	Products []ProductCostChange `json:"products"`
+	Totals   CostChangeTotals    `json:"totals"`
+}
+
+// ProductCostChange is a product whose cost price changes. The value fields
+// show the impact on reports valued at the cost price: the stock on hand and
+// the shrinkage of losses without a cost of their own. Profit of past sales
+// keeps the cost recorded at checkout.
+type ProductCostChange struct {
+	ProductID            int          `json:"product_id"`
+	Name                 string       `json:"name"`
+	Receipts             int          `json:"receipts"`
+	OldCost              money.Amount `json:"old_cost"`
+	NewCost              money.Amount `json:"new_cost"`
+	Stock                int          `json:"stock"`
+	OldStockValue        money.Amount `json:"old_stock_value"`
+	NewStockValue        money.Amount `json:"new_stock_value"`
+	StockValueChange     money.Amount `json:"stock_value_change"`
+	LossQuantity         int          `json:"loss_quantity"`
+	ShrinkageValueChange money.Amount `json:"shrinkage_value_change"`
+}
+
+type CostChangeTotals struct {
+	Products             int          `json:"products"`
+	OldStockValue        money.Amount `json:"old_stock_value"`
+	NewStockValue        money.Amount `json:"new_stock_value"`
+	StockValueChange     money.Amount `json:"stock_value_change"`
+	ShrinkageValueChange money.Amount `json:"shrinkage_value_change"`
+}
+
+// ReceiptCostCorrection fixes the unit cost recorded for a product on a goods receipt
+type ReceiptCostCorrection struct {
+	ProductID int          `json:"product_id" validate:"required" minimum:"1"`
+	UnitCost  money.Amount `json:"unit_cost" minimum:"0"`
+}
@@ -0,0 +1,25 @@
+package models
+
+// ExchangeRate is a foreign currency the store accepts. Rate is how many whole
+// units of the store currency one whole unit is worth; a fetched rate is kept
+// up to date from the exchange rate API, otherwise it is set by hand.
+type ExchangeRate struct {
+	Currency  string  `json:"currency" example:"USD"`
+	Rate      float64 `json:"rate" example:"16250"`
+	Fetched   bool    `json:"fetched"`
+	UpdatedAt string  `json:"updated_at"`
+}
+
+// ExchangeRateRequest accepts a currency at Rate, or at the rate fetched from
+// the exchange rate API when Fetched is set
+type ExchangeRateRequest struct {
+	Rate    float64 `json:"rate" minimum:"0" example:"16250"`
+	Fetched bool    `json:"fetched"`
+}
+
+// AcceptedCurrencies lists the currencies a sale can be paid in besides Base,
+// the store currency
+type AcceptedCurrencies struct {
+	Base  string         `json:"base" example:"IDR"`
+	Rates []ExchangeRate `json:"rates"`
+}
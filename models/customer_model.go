@@ -0,0 +1,69 @@
+package models
+
+import "kasir-api/money"
+
+// Customer represents a customer who can take kasbon (store credit)
+type Customer struct {
+	ID              int    `json:"id"`
+	Name            string `json:"name"`
+	Phone           string `json:"phone"`
+	Email           string `json:"email"`
+	ReminderChannel string `json:"reminder_channel" enums:"whatsapp,sms,email"`
+	CreatedAt       string `json:"created_at,omitempty"`
+}
+
+type Kasbon struct {
+	ID         int          `json:"id"`
+	CustomerID int          `json:"customer_id"`
+	Amount     money.Amount `json:"amount" validate:"required" minimum:"1"`
+	DueDate    string       `json:"due_date" validate:"required" example:"2024-07-01"`
+	Note       string       `json:"note"`
+	CreatedAt  string       `json:"created_at,omitempty"`
+}
+
+type KasbonPayment struct {
+	ID         int          `json:"id"`
+	CustomerID int          `json:"customer_id"`
+	Amount     money.Amount `json:"amount" validate:"required" minimum:"1"`
+	Note       string       `json:"note"`
+	CreatedAt  string       `json:"created_at,omitempty"`
+}
+
+// CustomerCredit summarizes the kasbon balance of a customer
+type CustomerCredit struct {
+	CustomerID     int             `json:"customer_id"`
+	TotalKasbon    money.Amount    `json:"total_kasbon"`
+	TotalPaid      money.Amount    `json:"total_paid"`
+	Balance        money.Amount    `json:"balance"`
+	OverdueBalance money.Amount    `json:"overdue_balance"`
+	Kasbon         []Kasbon        `json:"kasbon"`
+	Payments       []KasbonPayment `json:"payments"`
+}
+
+// CustomerImportIssue is a CSV row that was not imported; Row counts the
+// header as row 1, as spreadsheets do
+type CustomerImportIssue struct {
+	Row     int    `json:"row"`
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+// CustomerImportResult reports what a CSV import did, or would do on a dry run
+type CustomerImportResult struct {
+	DryRun   bool                  `json:"dry_run"`
+	Imported int                   `json:"imported"`
+	Skipped  []CustomerImportIssue `json:"skipped"`
+	Errors   []CustomerImportIssue `json:"errors"`
+}
+
+// DuplicateCustomers are customers that share a phone number or email and
+// are likely the same person
+type DuplicateCustomers struct {
+	MatchedOn []string   `json:"matched_on" enums:"phone,email"`
+	Customers []Customer `json:"customers"`
+}
+
+type CustomerMergeRequest struct {
+	// CustomerIDs are merged into the customer in the path and then deleted
+	CustomerIDs []int `json:"customer_ids" validate:"required"`
+}
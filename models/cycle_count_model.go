@@ -0,0 +1,46 @@
+package models
+
+type CycleCountTask struct {
+	ID           int    `json:"id"`
+	ProductID    int    `json:"product_id"`
+	ProductName  string `json:"product_name,omitempty"`
+	ABCClass     string `json:"abc_class"`
+	ScheduledFor string `json:"scheduled_for"`
+	Status       string `json:"status"`
+	ExpectedQty  *int   `json:"expected_qty,omitempty"`
+	CountedQty   *int   `json:"counted_qty,omitempty"`
+	Variance     *int   `json:"variance,omitempty"`
+	CreatedAt    string `json:"created_at,omitempty"`
+	CountedAt    string `json:"counted_at,omitempty"`
+}
+
+type CycleCountRequest struct {
+	CountedQty int `json:"counted_qty" validate:"required" minimum:"0"`
+}
+
+// CycleCountCandidate is a product considered for the daily count selection
+type CycleCountCandidate struct {
+	ProductID int
+	ABCClass  string
+	// DaysSinceCount is nil when the product was never counted
+	DaysSinceCount *int
+	HasOpenTask    bool
+}
+
+type CountAccuracyPoint struct {
+	Date     string  `json:"date"`
+	Expected int     `json:"expected"`
+	Counted  int     `json:"counted"`
+	Variance int     `json:"variance"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+// ProductCountAccuracy summarizes how well recorded stock matched cycle counts
+type ProductCountAccuracy struct {
+	ProductID       int                  `json:"product_id"`
+	ProductName     string               `json:"product_name"`
+	Counts          int                  `json:"counts"`
+	ExactCounts     int                  `json:"exact_counts"`
+	AverageAccuracy float64              `json:"average_accuracy"`
+	Trend           []CountAccuracyPoint `json:"trend"`
+}
@@ -0,0 +1,58 @@
+package models
+
+// Courier delivers orders; Phone is in international form
+type Courier struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name" validate:"required" example:"Pak Budi"`
+	Phone     string `json:"phone" example:"+6281234567890"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// Delivery takes a sale out to the customer. It is packed, taken out by its
+// courier and delivered once a photo proves it arrived; the customer is
+// messaged on the way.
+type Delivery struct {
+	ID            int             `json:"id"`
+	TransactionID int             `json:"transaction_id"`
+	ReceiptNumber string          `json:"receipt_number,omitempty"`
+	CustomerName  string          `json:"customer_name"`
+	CustomerPhone string          `json:"customer_phone"`
+	Address       string          `json:"address"`
+	Note          string          `json:"note"`
+	CourierID     int             `json:"courier_id,omitempty"`
+	CourierName   string          `json:"courier_name,omitempty"`
+	CourierPhone  string          `json:"courier_phone,omitempty"`
+	Status        string          `json:"status" enums:"pending,packed,out_for_delivery,delivered,cancelled"`
+	HasProof      bool            `json:"has_proof"`
+	DeliveredAt   string          `json:"delivered_at,omitempty"`
+	CreatedAt     string          `json:"created_at"`
+	Events        []DeliveryEvent `json:"events,omitempty"`
+	ProofName     string          `json:"-"`
+	ProofType     string          `json:"-"`
+}
+
+// DeliveryEvent is a status a delivery went through, with the courier it had then
+type DeliveryEvent struct {
+	Status    string `json:"status"`
+	CourierID int    `json:"courier_id,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+type DeliveryRequest struct {
+	TransactionID int    `json:"transaction_id" validate:"required" minimum:"1"`
+	CustomerName  string `json:"customer_name" example:"Bu Sri"`
+	CustomerPhone string `json:"customer_phone" example:"0812-3456-7890"`
+	Address       string `json:"address" validate:"required" example:"Jl. Melati 12, RT 03/RW 05"`
+	Note          string `json:"note" example:"Rumah pagar hijau"`
+	CourierID     int    `json:"courier_id,omitempty" minimum:"1"`
+}
+
+type DeliveryCourierRequest struct {
+	CourierID int `json:"courier_id" validate:"required" minimum:"1"`
+}
+
+// DeliveryStatusRequest moves a delivery on; it is marked delivered by
+// uploading its photo proof instead
+type DeliveryStatusRequest struct {
+	Status string `json:"status" validate:"required" enums:"packed,out_for_delivery,cancelled"`
+}
@@ -0,0 +1,47 @@
+package models
+
+// BuildInfo identifies the binary serving the API
+type BuildInfo struct {
+	GoVersion    string `json:"go_version"`
+	Module       string `json:"module"`
+	Version      string `json:"version"`
+	Revision     string `json:"revision,omitempty"`
+	RevisionTime string `json:"revision_time,omitempty"`
+	Modified     bool   `json:"modified"`
+}
+
+// MemoryStats is what the Go runtime holds and has collected
+type MemoryStats struct {
+	HeapAllocBytes uint64 `json:"heap_alloc_bytes"`
+	HeapObjects    uint64 `json:"heap_objects"`
+	SysBytes       uint64 `json:"sys_bytes"`
+	NumGC          uint32 `json:"num_gc"`
+	GCPauseTotalMs int64  `json:"gc_pause_total_ms"`
+}
+
+// DBPoolStats is the state of the database connection pool; requests
+// waiting for a connection show up in WaitCount and WaitDurationMs
+type DBPoolStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMs     int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+// Diagnostics is a snapshot of the running process, for diagnosing a
+// slowdown in production
+type Diagnostics struct {
+	Build         BuildInfo   `json:"build"`
+	StartedAt     string      `json:"started_at"`
+	UptimeSeconds int64       `json:"uptime_seconds"`
+	Goroutines    int         `json:"goroutines"`
+	GOMAXPROCS    int         `json:"gomaxprocs"`
+	NumCPU        int         `json:"num_cpu"`
+	Memory        MemoryStats `json:"memory"`
+	DBPool        DBPoolStats `json:"db_pool"`
+}
@@ -0,0 +1,37 @@
+package models
+
+// DisplayMedia is an image or video of the advertising playlist the
+// customer-facing display shows while the till is idle
+type DisplayMedia struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	MediaType   string `json:"media_type" enums:"image,video"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	URL         string `json:"url"`
+	// DurationSeconds is how long an image stays on screen; a video plays to
+	// its end when it is 0
+	DurationSeconds int    `json:"duration_seconds"`
+	Position        int    `json:"position"`
+	StartTime       string `json:"start_time,omitempty" example:"07:00"`
+	EndTime         string `json:"end_time,omitempty" example:"11:00"`
+	Active          bool   `json:"active"`
+	CreatedAt       string `json:"created_at,omitempty"`
+	FileName        string `json:"-"`
+}
+
+type DisplayMediaRequest struct {
+	Title           string `json:"title"`
+	DurationSeconds int    `json:"duration_seconds" minimum:"0"`
+	Position        int    `json:"position"`
+	StartTime       string `json:"start_time" example:"07:00"`
+	EndTime         string `json:"end_time" example:"11:00"`
+	Active          bool   `json:"active"`
+}
+
+// DisplayPlaylist is what the customer display plays at the moment, in order.
+// Version changes whenever the items do.
+type DisplayPlaylist struct {
+	Version string         `json:"version"`
+	Items   []DisplayMedia `json:"items"`
+}
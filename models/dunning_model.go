@@ -0,0 +1,26 @@
+package models
+
+import "kasir-api/money"
+
+type ReminderTemplate struct {
+	Channel string `json:"channel"`
+	Body    string `json:"body" validate:"required"`
+}
+
+type ReminderHistory struct {
+	ID         int    `json:"id"`
+	CustomerID int    `json:"customer_id"`
+	Channel    string `json:"channel"`
+	Recipient  string `json:"recipient"`
+	Message    string `json:"message"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	SentAt     string `json:"sent_at"`
+}
+
+// OverdueCustomer is a customer due for a kasbon reminder
+type OverdueCustomer struct {
+	Customer       Customer
+	OverdueBalance money.Amount
+	OldestDueDate  string
+}
@@ -0,0 +1,17 @@
+package models
+
+type EmailDelivery struct {
+	ID            int    `json:"id"`
+	TransactionID int    `json:"transaction_id"`
+	Recipient     string `json:"recipient"`
+	Subject       string `json:"subject"`
+	Status        string `json:"status"`
+	Attempts      int    `json:"attempts"`
+	Error         string `json:"error,omitempty"`
+	CreatedAt     string `json:"created_at,omitempty"`
+	SentAt        string `json:"sent_at,omitempty"`
+}
+
+type EmailReceiptRequest struct {
+	Email string `json:"email" validate:"required" example:"customer@example.com"`
+}
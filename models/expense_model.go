@@ -0,0 +1,16 @@
+package models
+
+import "kasir-api/money"
+
+// Expense is a petty cash voucher for money paid out of the register
+type Expense struct {
+	ID             int          `json:"id"`
+	Amount         money.Amount `json:"amount" validate:"required" minimum:"1"`
+	Category       string       `json:"category" example:"supplies"`
+	Description    string       `json:"description"`
+	SpentOn        string       `json:"spent_on" example:"2026-01-31"`
+	HasAttachment  bool         `json:"has_attachment"`
+	CreatedAt      string       `json:"created_at,omitempty"`
+	AttachmentName string       `json:"-"`
+	AttachmentType string       `json:"-"`
+}
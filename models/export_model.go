@@ -0,0 +1,35 @@
+package models
+
+import "kasir-api/money"
+
+// DataExport is a file produced in the background for download
+type DataExport struct {
+	ID          int    `json:"id"`
+	Type        string `json:"type"`
+	Format      string `json:"format"`
+	DateFrom    string `json:"date_from"`
+	DateTo      string `json:"date_to"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+	CreatedAt   string `json:"created_at,omitempty"`
+	CompletedAt string `json:"completed_at,omitempty"`
+	FileName    string `json:"-"`
+}
+
+// DailyTakings is what the sales of a day came to and how they were paid:
+// by voucher, through a payment link, the part left on installments, and the
+// rest at the counter
+type DailyTakings struct {
+	Date         string       `json:"date"`
+	Transactions int          `json:"transactions"`
+	Total        money.Amount `json:"total"`
+	Voucher      money.Amount `json:"voucher"`
+	PaymentLink  money.Amount `json:"payment_link"`
+	Installment  money.Amount `json:"installment"`
+}
+
+type ExportRequest struct {
+	DateFrom string `json:"date_from" validate:"required" example:"2026-01-01"`
+	DateTo   string `json:"date_to" validate:"required" example:"2026-12-31"`
+	Format   string `json:"format" enums:"csv,xlsx"`
+}
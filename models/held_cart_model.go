@@ -0,0 +1,17 @@
+package models
+
+// HeldCart is a cart parked by a cashier terminal to be resumed later
+type HeldCart struct {
+	ID         string         `json:"id"`
+	TerminalID string         `json:"terminal_id"`
+	Note       string         `json:"note,omitempty"`
+	Items      []CheckoutItem `json:"items"`
+	HeldAt     string         `json:"held_at"`
+	ExpiresAt  string         `json:"expires_at"`
+}
+
+type HoldCartRequest struct {
+	TerminalID string         `json:"terminal_id" validate:"required"`
+	Note       string         `json:"note"`
+	Items      []CheckoutItem `json:"items" validate:"required"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "kasir-api/money"
+
+type InstallmentPlan struct {
+	ID               int           `json:"id"`
+	TransactionID    int           `json:"transaction_id"`
+	CustomerID       *int          `json:"customer_id,omitempty"`
+	TotalAmount      money.Amount  `json:"total_amount"`
+	DownPayment      money.Amount  `json:"down_payment"`
+	InstallmentCount int           `json:"installment_count"`
+	PaidAmount       money.Amount  `json:"paid_amount"`
+	Outstanding      money.Amount  `json:"outstanding"`
+	Status           string        `json:"status"`
+	CreatedAt        string        `json:"created_at,omitempty"`
+	Installments     []Installment `json:"installments,omitempty"`
+}
+
+type Installment struct {
+	ID         int          `json:"id"`
+	PlanID     int          `json:"plan_id"`
+	Sequence   int          `json:"sequence"`
+	DueDate    string       `json:"due_date"`
+	Amount     money.Amount `json:"amount"`
+	PaidAmount money.Amount `json:"paid_amount"`
+	PaidAt     string       `json:"paid_at,omitempty"`
+	Status     string       `json:"status"`
+}
+
+type InstallmentPayment struct {
+	ID            int          `json:"id"`
+	PlanID        int          `json:"plan_id"`
+	InstallmentID int          `json:"installment_id"`
+	Amount        money.Amount `json:"amount"`
+	CreatedAt     string       `json:"created_at,omitempty"`
+}
+
+type CreateInstallmentPlanRequest struct {
+	TransactionID    int          `json:"transaction_id" validate:"required" minimum:"1"`
+	CustomerID       *int         `json:"customer_id,omitempty"`
+	DownPayment      money.Amount `json:"down_payment" minimum:"0"`
+	InstallmentCount int          `json:"installment_count" validate:"required" minimum:"2"`
+	FirstDueDate     string       `json:"first_due_date" validate:"required" example:"2024-07-01"`
+}
+
+type InstallmentPaymentRequest struct {
+	Amount money.Amount `json:"amount" validate:"required" minimum:"1"`
+}
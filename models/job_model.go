@@ -0,0 +1,17 @@
+package models
+
+import "encoding/json"
+
+// Job is a unit of background work persisted in the job table
+type Job struct {
+	ID          int             `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload" swaggertype:"object"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	LastError   string          `json:"last_error,omitempty"`
+	RunAt       string          `json:"run_at,omitempty"`
+	CreatedAt   string          `json:"created_at,omitempty"`
+	FinishedAt  string          `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// MaintenanceWindow is a time of the week heavy scheduled jobs may run in.
+// Days are 0 (Sunday) to 6 (Saturday), none meaning every day; a window
+// whose end is before its start runs past midnight into the next day.
+type MaintenanceWindow struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Days      []int  `json:"days" example:"1,2,3,4,5"`
+	Start     string `json:"start" example:"22:00"`
+	End       string `json:"end" example:"05:00"`
+	CreatedAt string `json:"created_at"`
+}
+
+type MaintenanceWindowRequest struct {
+	Name  string `json:"name" validate:"required" example:"Overnight"`
+	Days  []int  `json:"days" example:"1,2,3,4,5"`
+	Start string `json:"start" validate:"required" example:"22:00"`
+	End   string `json:"end" validate:"required" example:"05:00"`
+}
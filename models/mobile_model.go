@@ -0,0 +1,24 @@
+package models
+
+import "kasir-api/money"
+
+// MobileSummary is today's key numbers for the owner's phone app, kept small for poor connections
+type MobileSummary struct {
+	Date         string       `json:"date"`
+	Revenue      money.Amount `json:"revenue"`
+	Transactions int          `json:"transactions"`
+	GrossProfit  money.Amount `json:"gross_profit"`
+	Expenses     money.Amount `json:"expenses"`
+	// LowStock is the number of products at or below their reorder point
+	LowStock int `json:"low_stock"`
+	// Overdue is the unpaid amount of installments past their due date
+	Overdue     int           `json:"overdue"`
+	FailedJobs  int           `json:"failed_jobs"`
+	Alerts      []MobileAlert `json:"alerts"`
+	GeneratedAt string        `json:"generated_at"`
+}
+
+type MobileAlert struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
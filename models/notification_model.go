@@ -0,0 +1,12 @@
+package models
+
+// NotificationChannel is a chat the store's alerts are sent to. Recipient is
+// the chat ID for Telegram and the phone number for WhatsApp.
+type NotificationChannel struct {
+	ID        int      `json:"id"`
+	Provider  string   `json:"provider" validate:"required" enums:"telegram,whatsapp"`
+	Recipient string   `json:"recipient" validate:"required" example:"-1001234567890"`
+	Events    []string `json:"events" validate:"required" enums:"low_stock,daily_summary"`
+	Active    bool     `json:"active"`
+	CreatedAt string   `json:"created_at,omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// NumberingSequence is the state of a numbering sequence in its current
+// reset period. NextNumber is what the next receipt, product or tax invoice
+// will get.
+type NumberingSequence struct {
+	Name       string `json:"name" example:"receipt"`
+	Format     string `json:"format" example:"INV/{YYYY}/{MM}/{SEQ:6}"`
+	Reset      string `json:"reset" enums:"never,yearly,monthly,daily"`
+	Store      string `json:"store"`
+	Scope      string `json:"scope" example:"|2024-06"`
+	NextValue  int64  `json:"next_value"`
+	NextNumber string `json:"next_number" example:"INV/2024/06/000124"`
+}
+
+// NumberingAdjustmentRequest sets the next value of a sequence's current period
+type NumberingAdjustmentRequest struct {
+	NextValue int64  `json:"next_value" validate:"required" minimum:"1"`
+	Reason    string `json:"reason" validate:"required" example:"Continue from the paper receipt book"`
+}
+
+// NumberingAdjustment is the audit entry of a next value set by hand
+type NumberingAdjustment struct {
+	ID           int    `json:"id"`
+	Sequence     string `json:"sequence"`
+	Scope        string `json:"scope"`
+	PreviousNext int64  `json:"previous_next"`
+	NextValue    int64  `json:"next_value"`
+	Reason       string `json:"reason"`
+	CreatedAt    string `json:"created_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is a domain event kept in the outbox until it is relayed
+type OutboxEvent struct {
+	ID        int64
+	Event     string
+	Payload   json.RawMessage
+	CreatedAt time.Time
+}
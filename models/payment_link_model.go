@@ -0,0 +1,38 @@
+package models
+
+import "kasir-api/money"
+
+// PaymentLink is a gateway link shared with the customer of a remote order,
+// e.g. taken over WhatsApp, to pay its draft transaction. The transaction is
+// marked paid once the gateway reports the link paid in full; paid short, the
+// link is underpaid and the transaction stays pending_payment.
+type PaymentLink struct {
+	ID            int          `json:"id"`
+	Reference     string       `json:"reference"`
+	TransactionID int          `json:"transaction_id"`
+	CustomerName  string       `json:"customer_name,omitempty"`
+	CustomerPhone string       `json:"customer_phone,omitempty"`
+	Note          string       `json:"note,omitempty"`
+	Amount        money.Amount `json:"amount"`
+	URL           string       `json:"url"`
+	Status        string       `json:"status" enums:"pending,paid,underpaid,expired"`
+	PaidAmount    money.Amount `json:"paid_amount,omitempty"`
+	ExpiresAt     string       `json:"expires_at"`
+	PaidAt        string       `json:"paid_at,omitempty"`
+	CreatedAt     string       `json:"created_at"`
+}
+
+// PaymentLinkRequest asks for a link to pay a draft or pending_payment transaction
+type PaymentLinkRequest struct {
+	TransactionID int    `json:"transaction_id" validate:"required" example:"42"`
+	CustomerName  string `json:"customer_name"`
+	CustomerPhone string `json:"customer_phone"`
+	Note          string `json:"note"`
+}
+
+// PaymentCallback is what the payment gateway posts when a link is paid or expires
+type PaymentCallback struct {
+	Reference string       `json:"reference" validate:"required"`
+	Status    string       `json:"status" validate:"required" enums:"paid,expired"`
+	Amount    money.Amount `json:"amount"`
+}
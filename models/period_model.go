@@ -0,0 +1,27 @@
+package models
+
+// AccountingPeriod is a closed month; nothing dated in it changes until it is reopened
+type AccountingPeriod struct {
+	Month    string `json:"month" example:"2026-09"`
+	Note     string `json:"note"`
+	ClosedAt string `json:"closed_at"`
+}
+
+// PeriodCloseRequest closes a month, with an optional note for the audit trail
+type PeriodCloseRequest struct {
+	Note string `json:"note" example:"Confirmed with the accountant"`
+}
+
+// PeriodReopenRequest reopens a closed month; the reason goes in the audit trail
+type PeriodReopenRequest struct {
+	Reason string `json:"reason" validate:"required" example:"Supplier invoice booked in the wrong month"`
+}
+
+// PeriodEvent is the audit entry of a month closed or reopened
+type PeriodEvent struct {
+	ID        int    `json:"id"`
+	Month     string `json:"month" example:"2026-09"`
+	Action    string `json:"action" enums:"close,reopen"`
+	Reason    string `json:"reason"`
+	CreatedAt string `json:"created_at"`
+}
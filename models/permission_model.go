@@ -0,0 +1,45 @@
+package models
+
+// Permission is one thing a role may be allowed to do, e.g. product.write
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Role is a set of permissions accounts are given together
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// RoleRequest creates a role or replaces what it may do
+type RoleRequest struct {
+	Description string   `json:"description" example:"Shift supervisor"`
+	Permissions []string `json:"permissions" example:"product.view,transaction.refund"`
+}
+
+// SetRoleRequest moves an account to another role
+type SetRoleRequest struct {
+	Role string `json:"role" validate:"required" example:"cashier"`
+}
+
+// PermissionRule is the permission the requests to a route need. Path is a
+// route pattern: ending in / for everything under it, with * for one path
+// segment; no Methods means every method.
+type PermissionRule struct {
+	Methods    []string `json:"methods,omitempty"`
+	Path       string   `json:"path"`
+	Permission string   `json:"permission"`
+}
+
+// EffectivePermissions is what the cashier signed in on a terminal session
+// may do. When Enforced is false nothing is refused yet, the list only tells
+// the frontend what to show.
+type EffectivePermissions struct {
+	UserID      int      `json:"user_id"`
+	Username    string   `json:"username"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+	Enforced    bool     `json:"enforced"`
+}
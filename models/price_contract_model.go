@@ -0,0 +1,33 @@
+package models
+
+import "kasir-api/money"
+
+// PriceContract is a price list negotiated with a wholesale customer. From
+// ValidFrom through ValidUntil, or with no end when it is empty, its prices
+// replace the product price and pricing rules at checkout for the customer.
+type PriceContract struct {
+	ID           int                 `json:"id"`
+	CustomerID   int                 `json:"customer_id" validate:"required" minimum:"1"`
+	CustomerName string              `json:"customer_name,omitempty"`
+	Name         string              `json:"name" example:"Carton prices 2026"`
+	ValidFrom    string              `json:"valid_from" validate:"required" example:"2026-01-01"`
+	ValidUntil   string              `json:"valid_until,omitempty" example:"2026-12-31"`
+	Items        []PriceContractItem `json:"items" validate:"required"`
+	CreatedAt    string              `json:"created_at,omitempty"`
+}
+
+// PriceContractItem is the unit price a contract gives a product
+type PriceContractItem struct {
+	ProductID   int          `json:"product_id" validate:"required" minimum:"1"`
+	ProductName string       `json:"product_name,omitempty"`
+	Price       money.Amount `json:"price" minimum:"0"`
+}
+
+// AppliedPriceContract is the contract a checkout line was priced with and
+// the amount it took off the line compared to the product price
+type AppliedPriceContract struct {
+	ID       int          `json:"id"`
+	Name     string       `json:"name"`
+	Price    money.Amount `json:"price"`
+	Discount money.Amount `json:"discount"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "kasir-api/money"
+
+// PriceOverride is a price or extra discount a cashier asks for on a checkout
+// line: the line sells at Price a unit instead of what it is priced at, less
+// Discount, and needs a manager's approval
+type PriceOverride struct {
+	Price    money.Amount `json:"price,omitempty" minimum:"1"`
+	Discount money.Amount `json:"discount,omitempty" minimum:"1"`
+	Reason   string       `json:"reason" validate:"required" example:"Kemasan penyok"`
+}
+
+// OverrideApproval is the manager approving the price overrides of a checkout,
+// and its lines priced past the guardrails, with their PIN
+type OverrideApproval struct {
+	Username string `json:"username" validate:"required" example:"siti"`
+	PIN      string `json:"pin" validate:"required" example:"1234"`
+}
+
+// AppliedPriceOverride is the override a checkout line was sold with, in place
+// of the pricing rule or contract that priced it at OriginalSubtotal, and the
+// amount the line sells for under the product price
+type AppliedPriceOverride struct {
+	OriginalSubtotal money.Amount `json:"original_subtotal"`
+	Price            money.Amount `json:"price,omitempty"`
+	ExtraDiscount    money.Amount `json:"extra_discount,omitempty"`
+	Discount         money.Amount `json:"discount"`
+	Reason           string       `json:"reason"`
+	ApprovedByID     int          `json:"approved_by_id"`
+	ApprovedBy       string       `json:"approved_by"`
+}
+
+// PriceViolation is a checkout line priced past a guardrail without approval:
+// discounted by more than MAX_DISCOUNT_PERCENT of its price, or sold under the
+// cost of its units with BLOCK_BELOW_COST on
+type PriceViolation struct {
+	Field     string `json:"field" example:"items[0]"`
+	ProductID int    `json:"product_id"`
+	Guardrail string `json:"guardrail" enums:"max_discount,below_cost"`
+	Message   string `json:"message"`
+}
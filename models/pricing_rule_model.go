@@ -0,0 +1,26 @@
+package models
+
+import "kasir-api/money"
+
+// PricingRule is a quantity price break: every full group of MinQuantity
+// units sells for Price, e.g. 3 for 25,000
+type PricingRule struct {
+	ID          int          `json:"id"`
+	ProductID   int          `json:"product_id" validate:"required" minimum:"1"`
+	ProductName string       `json:"product_name,omitempty"`
+	Name        string       `json:"name" example:"3 for 25,000"`
+	MinQuantity int          `json:"min_quantity" validate:"required" minimum:"2"`
+	Price       money.Amount `json:"price" validate:"required" minimum:"1"`
+	Active      bool         `json:"active"`
+	CreatedAt   string       `json:"created_at,omitempty"`
+}
+
+// AppliedPricingRule is the rule a checkout line was priced with and the
+// amount it took off the line compared to the unit price
+type AppliedPricingRule struct {
+	ID          int          `json:"id"`
+	Name        string       `json:"name"`
+	MinQuantity int          `json:"min_quantity"`
+	Price       money.Amount `json:"price"`
+	Discount    money.Amount `json:"discount"`
+}
@@ -0,0 +1,44 @@
+package models
+
+// Printer is a kitchen or bar printer reached over raw TCP, port 9100 on most
+// ESC/POS printers. It prints the restaurant order lines of products in
+// CategoryIDs; the default printer prints the lines no other printer takes.
+type Printer struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name" validate:"required" example:"Bar"`
+	Address     string `json:"address" validate:"required" example:"192.168.1.50:9100"`
+	CategoryIDs []int  `json:"category_ids"`
+	IsDefault   bool   `json:"is_default"`
+	Active      bool   `json:"active"`
+	CreatedAt   string `json:"created_at,omitempty"`
+}
+
+type UpdatePrinterRequest struct {
+	Name        string `json:"name"`
+	Address     string `json:"address"`
+	CategoryIDs []int  `json:"category_ids"`
+	IsDefault   *bool  `json:"is_default"`
+	Active      *bool  `json:"active"`
+}
+
+// PrintJob is the ticket a printer is sent for the lines an order took at
+// once, with the outcome of the attempts to print it
+type PrintJob struct {
+	ID            int    `json:"id"`
+	PrinterID     int    `json:"printer_id"`
+	PrinterName   string `json:"printer_name"`
+	TransactionID int    `json:"transaction_id"`
+	Content       string `json:"content"`
+	Status        string `json:"status" enums:"pending,printed,failed"`
+	Attempts      int    `json:"attempts"`
+	Error         string `json:"error,omitempty"`
+	CreatedAt     string `json:"created_at,omitempty"`
+	PrintedAt     string `json:"printed_at,omitempty"`
+}
+
+// PrintLine is an order line waiting to be routed to a printer
+type PrintLine struct {
+	Name        string
+	Quantity    int
+	CategoryIDs []int
+}
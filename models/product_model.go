@@ -1,14 +1,42 @@
 package models
 
-import "google.golang.org/protobuf/types/known/timestamppb"
+import (
+	"kasir-api/money"
+	"time"
+)
 
 // Product represents a product in the cashier system
 type Product struct {
-	ID         int                    `json:"id"`
-	Name       string                 `json:"name"`
-	Price      int                    `json:"price"`
-	Stock      int                    `json:"stock"`
-	CategoryID int                    `json:"category_id"`
-	Category   *Category              `json:"category,omitempty"`
-	DeletedAt  *timestamppb.Timestamp `json:"deleted_at"`
+	ID           int               `json:"id"`
+	Name         string            `json:"name"`
+	Barcode      string            `json:"barcode"`
+	SKU          string            `json:"sku" example:"SKU-000123"`
+	Price        money.Amount      `json:"price" minimum:"0"`
+	CostPrice    money.Amount      `json:"cost_price" minimum:"0"`
+	Stock        int               `json:"stock" minimum:"0"`
+	ReorderPoint int               `json:"reorder_point" minimum:"0"`
+	ReorderQty   int               `json:"reorder_qty" minimum:"0"`
+	ABCClass     string            `json:"abc_class" enums:"A,B,C"`
+	IsBundle     bool              `json:"is_bundle"`
+	Components   []BundleComponent `json:"components,omitempty"`
+	CategoryIDs  []int             `json:"category_ids"`
+	Categories   []Category        `json:"categories,omitempty"`
+	CreatedAt    *time.Time        `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time        `json:"updated_at,omitempty"`
+	DeletedAt    *time.Time        `json:"deleted_at"`
+}
+
+// ProductBatchResult is the outcome of one product of a batch, at the index
+// it had in the request
+type ProductBatchResult struct {
+	Index   int      `json:"index"`
+	Status  string   `json:"status" enums:"created,updated,failed,skipped"`
+	Product *Product `json:"product,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// StockAdjustmentRequest changes the stock of a product by Quantity, negative
+// to take stock, e.g. for goods found or lost outside a count
+type StockAdjustmentRequest struct {
+	Quantity int `json:"quantity" validate:"required" example:"-3"`
 }
@@ -0,0 +1,57 @@
+package models
+
+import "kasir-api/money"
+
+type PurchaseOrder struct {
+	ID           int                 `json:"id"`
+	SupplierID   int                 `json:"supplier_id"`
+	SupplierName string              `json:"supplier_name,omitempty"`
+	Status       string              `json:"status"`
+	CloseReason  string              `json:"close_reason,omitempty"`
+	CreatedAt    string              `json:"created_at,omitempty"`
+	ClosedAt     string              `json:"closed_at,omitempty"`
+	Items        []PurchaseOrderItem `json:"items"`
+}
+
+// PurchaseOrderItem.QuantityRemaining is still expected from the supplier, 0 once the order is closed
+type PurchaseOrderItem struct {
+	ID                int          `json:"id"`
+	PurchaseOrderID   int          `json:"purchase_order_id"`
+	ProductID         int          `json:"product_id"`
+	ProductName       string       `json:"product_name,omitempty"`
+	QuantityOrdered   int          `json:"quantity_ordered"`
+	QuantityReceived  int          `json:"quantity_received"`
+	QuantityRemaining int          `json:"quantity_remaining"`
+	UnitCost          money.Amount `json:"unit_cost"`
+}
+
+type PurchaseOrderItemRequest struct {
+	ProductID int          `json:"product_id" validate:"required" minimum:"1"`
+	Quantity  int          `json:"quantity" validate:"required" minimum:"1"`
+	UnitCost  money.Amount `json:"unit_cost" minimum:"0"`
+}
+
+type CreatePurchaseOrderRequest struct {
+	SupplierID int                        `json:"supplier_id" validate:"required" minimum:"1"`
+	Items      []PurchaseOrderItemRequest `json:"items" validate:"required"`
+}
+
+type ClosePurchaseOrderRequest struct {
+	Reason string `json:"reason" example:"Supplier out of stock"`
+}
+
+// GoodsReceipt is one delivery booked against a purchase order
+type GoodsReceipt struct {
+	ID              int                `json:"id"`
+	PurchaseOrderID int                `json:"purchase_order_id"`
+	SessionID       *int               `json:"session_id,omitempty"`
+	CreatedAt       string             `json:"created_at,omitempty"`
+	Items           []GoodsReceiptItem `json:"items"`
+}
+
+type GoodsReceiptItem struct {
+	ProductID   int          `json:"product_id"`
+	ProductName string       `json:"product_name,omitempty"`
+	Quantity    int          `json:"quantity"`
+	UnitCost    money.Amount `json:"unit_cost"`
+}
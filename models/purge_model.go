@@ -0,0 +1,25 @@
+package models
+
+// PurgeEntry is a soft deleted row the recycle bin purge removed, or kept
+// because other records still refer to it, e.g. a product that was sold
+type PurgeEntry struct {
+	ID        int    `json:"id"`
+	DryRun    bool   `json:"dry_run"`
+	TableName string `json:"table_name" example:"product"`
+	RecordID  int    `json:"record_id"`
+	DeletedAt string `json:"deleted_at"`
+	Outcome   string `json:"outcome" enums:"purged,kept"`
+	Reason    string `json:"reason,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// PurgeResult is one run of the recycle bin purge over the rows soft deleted
+// before Cutoff; a dry run reports what it would purge and purges nothing
+type PurgeResult struct {
+	DryRun        bool         `json:"dry_run"`
+	RetentionDays int          `json:"retention_days"`
+	Cutoff        string       `json:"cutoff"`
+	Purged        int          `json:"purged"`
+	Kept          int          `json:"kept"`
+	Entries       []PurgeEntry `json:"entries"`
+}
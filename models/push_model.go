@@ -0,0 +1,28 @@
+package models
+
+// PushSubscription is a browser subscribed to Web Push alerts; the JSON
+// matches PushSubscription.toJSON() in the browser
+type PushSubscription struct {
+	ID        int                  `json:"id"`
+	Endpoint  string               `json:"endpoint" validate:"required"`
+	Keys      PushSubscriptionKeys `json:"keys" validate:"required"`
+	UserAgent string               `json:"user_agent,omitempty"`
+	CreatedAt string               `json:"created_at,omitempty"`
+}
+
+type PushSubscriptionKeys struct {
+	P256dh string `json:"p256dh" validate:"required"`
+	Auth   string `json:"auth" validate:"required"`
+}
+
+type UnsubscribeRequest struct {
+	Endpoint string `json:"endpoint" validate:"required"`
+}
+
+// PushAlert is the payload the service worker receives and shows as a notification
+type PushAlert struct {
+	Kind  string `json:"kind"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	At    string `json:"at"`
+}
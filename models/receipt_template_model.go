@@ -0,0 +1,37 @@
+package models
+
+// ReceiptTemplate is a version of the store's receipt branding. Header and
+// footer may span lines; Body, when set, is an html/template replacing the
+// built-in layout.
+type ReceiptTemplate struct {
+	ID        int    `json:"id"`
+	LogoURL   string `json:"logo_url"`
+	Header    string `json:"header"`
+	Footer    string `json:"footer"`
+	Promo     string `json:"promo"`
+	Body      string `json:"body,omitempty"`
+	Note      string `json:"note,omitempty"`
+	Active    bool   `json:"active"`
+	CreatedAt string `json:"created_at"`
+}
+
+// ReceiptTemplateRequest saves a new version of the receipt, made active. A
+// Body is executed with the transaction's fields and Branding, which holds
+// LogoURL, Header, Footer and Promo, and StoreName, StoreAddress and NPWP of
+// the store settings, and the functions money, moneyIn, number, date,
+// datetime, neg and lines. Without a footer, that of the settings is used.
+type ReceiptTemplateRequest struct {
+	LogoURL string `json:"logo_url" example:"https://example.com/logo.png"`
+	Header  string `json:"header" example:"Toko Maju Jaya\nJl. Merdeka 10, Bandung"`
+	Footer  string `json:"footer" example:"Barang yang sudah dibeli tidak dapat ditukar"`
+	Promo   string `json:"promo" example:"Diskon 10% untuk pembelian berikutnya"`
+	Body    string `json:"body"`
+	Note    string `json:"note"`
+}
+
+// ReceiptPreviewRequest renders a receipt version before it is saved, with a
+// transaction or, without one, a sample sale
+type ReceiptPreviewRequest struct {
+	Template      ReceiptTemplateRequest `json:"template"`
+	TransactionID int                    `json:"transaction_id,omitempty"`
+}
@@ -0,0 +1,35 @@
+package models
+
+type ReceivingSession struct {
+	ID              int             `json:"id"`
+	PurchaseOrderID int             `json:"purchase_order_id"`
+	Status          string          `json:"status"`
+	CreatedAt       string          `json:"created_at,omitempty"`
+	CommittedAt     string          `json:"committed_at,omitempty"`
+	Lines           []ReceivingLine `json:"lines"`
+	HasDiscrepancy  bool            `json:"has_discrepancy"`
+}
+
+// ReceivingLine compares what was scanned in a session with what is still
+// expected on the purchase order. Variance is scanned minus expected.
+type ReceivingLine struct {
+	ProductID   int    `json:"product_id"`
+	ProductName string `json:"product_name"`
+	Barcode     string `json:"barcode"`
+	Expected    int    `json:"expected"`
+	Scanned     int    `json:"scanned"`
+	Variance    int    `json:"variance"`
+	Flag        string `json:"flag"`
+}
+
+type OpenReceivingRequest struct {
+	PurchaseOrderID int `json:"purchase_order_id" validate:"required" minimum:"1"`
+}
+
+type ScanRequest struct {
+	Barcode  string `json:"barcode" validate:"required"`
+	Quantity int    `json:"quantity" minimum:"1"`
+	// ExpiresOn dates the units scanned, e.g. for perishables; they are
+	// stocked as a batch sold first-expired-first-out
+	ExpiresOn string `json:"expires_on,omitempty" format:"date" example:"2026-12-31"`
+}
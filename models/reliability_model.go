@@ -0,0 +1,26 @@
+package models
+
+// ReliabilityDay counts what went wrong on one day, or over the whole report
+// period when Date is empty
+type ReliabilityDay struct {
+	Date            string `json:"date,omitempty"`
+	WebhookFailures int    `json:"webhook_failures"`
+	JobFailures     int    `json:"job_failures"`
+	// GatewayErrors are reminders the WhatsApp, SMS or email gateway refused
+	GatewayErrors   int     `json:"gateway_errors"`
+	Requests        int64   `json:"requests"`
+	ServerErrors    int64   `json:"server_errors"`
+	ServerErrorRate float64 `json:"server_error_rate"`
+}
+
+// ReliabilityReport is the error budget of the install over the last Days days
+type ReliabilityReport struct {
+	Days int `json:"days"`
+	// ErrorBudget is the share of API requests allowed to fail with a 5xx
+	ErrorBudget float64 `json:"error_budget"`
+	// BudgetUsed is the server error rate as a share of the budget; above 1 the budget is spent
+	BudgetUsed float64          `json:"budget_used"`
+	Healthy    bool             `json:"healthy"`
+	Totals     ReliabilityDay   `json:"totals"`
+	Trend      []ReliabilityDay `json:"trend"`
+}
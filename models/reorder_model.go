@@ -0,0 +1,17 @@
+package models
+
+import "kasir-api/money"
+
+// ReorderSuggestion is a product at or below its reorder point. OnOrder is the
+// quantity still back-ordered on open purchase orders.
+type ReorderSuggestion struct {
+	ProductID        int            `json:"product_id"`
+	ProductName      string         `json:"product_name"`
+	ABCClass         string         `json:"abc_class"`
+	Stock            int            `json:"stock"`
+	OnOrder          int            `json:"on_order"`
+	ReorderPoint     int            `json:"reorder_point"`
+	SuggestedQty     int            `json:"suggested_qty"`
+	CheapestSupplier *SupplierPrice `json:"cheapest_supplier,omitempty"`
+	EstimatedCost    money.Amount   `json:"estimated_cost"`
+}
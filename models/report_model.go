@@ -1,12 +1,190 @@
 package models
 
+import "kasir-api/money"
+
 type SalesReport struct {
-	TotalRevenue   int         `json:"total_revenue"`
-	TotalTransaksi int         `json:"total_transaksi"`
-	ProdukTerlaris *TopProduct `json:"produk_terlaris,omitempty"`
+	TotalRevenue   money.Amount `json:"total_revenue"`
+	TotalTransaksi int          `json:"total_transaksi"`
+	// RoundingAdjustment is what rounding totals for cash added to revenue,
+	// negative when it took off more than it added
+	RoundingAdjustment money.Amount      `json:"rounding_adjustment"`
+	ProdukTerlaris     *TopProduct       `json:"produk_terlaris,omitempty"`
+	Payments           []CurrencyTakings `json:"payments"`
+	OpenItems          []OpenItemSales   `json:"open_items"`
+}
+
+// OpenItemSales is what was sold as open items under Description, lines
+// with no product that product sales don't count
+type OpenItemSales struct {
+	Description string       `json:"description" example:"Ongkos kirim"`
+	Lines       int          `json:"lines"`
+	Quantity    int          `json:"quantity"`
+	Revenue     money.Amount `json:"revenue"`
+}
+
+// CurrencyTakings is what sales of a period were paid in Currency, Amount in
+// its minor units and BaseAmount in the store currency at the rates of the
+// moment, change included
+type CurrencyTakings struct {
+	Currency     string       `json:"currency" example:"USD"`
+	Transactions int          `json:"transactions"`
+	Amount       money.Amount `json:"amount"`
+	BaseAmount   money.Amount `json:"base_amount"`
+	Change       money.Amount `json:"change"`
 }
 
+// Products deleted since they sold are still reported, with Deleted set
 type TopProduct struct {
 	Nama       string `json:"nama"`
 	QtyTerjual int    `json:"qty_terjual"`
+	Deleted    bool   `json:"deleted,omitempty"`
+}
+
+// PriceContractSalesReport is what was sold under price contracts in a
+// period; Discount is what the contracts took off the product prices
+type PriceContractSalesReport struct {
+	TotalRevenue  money.Amount         `json:"total_revenue"`
+	TotalDiscount money.Amount         `json:"total_discount"`
+	Contracts     []PriceContractSales `json:"contracts"`
+}
+
+type PriceContractSales struct {
+	ContractID   int          `json:"contract_id"`
+	Name         string       `json:"name"`
+	CustomerID   int          `json:"customer_id"`
+	CustomerName string       `json:"customer_name"`
+	Transactions int          `json:"transactions"`
+	QtySold      int          `json:"qty_sold"`
+	Revenue      money.Amount `json:"revenue"`
+	Discount     money.Amount `json:"discount"`
+}
+
+// ComparisonReport sets the sales of a month or year against those of
+// another; growth is the change from Previous as a percentage, null when
+// Previous sold nothing to grow from
+type ComparisonReport struct {
+	Period            string            `json:"period" example:"month"`
+	Current           PeriodSales       `json:"current"`
+	Previous          PeriodSales       `json:"previous"`
+	RevenueGrowth     *float64          `json:"revenue_growth"`
+	TransactionGrowth *float64          `json:"transaction_growth"`
+	TopProducts       []ComparedProduct `json:"top_products"`
+}
+
+type PeriodSales struct {
+	Period       string       `json:"period" example:"2024-06"`
+	Revenue      money.Amount `json:"revenue"`
+	Transactions int          `json:"transactions"`
+}
+
+// ComparedProduct is a best seller of the current period next to what it
+// sold in the previous one
+type ComparedProduct struct {
+	ProductID       int          `json:"product_id"`
+	Nama            string       `json:"nama"`
+	QtyTerjual      int          `json:"qty_terjual"`
+	Revenue         money.Amount `json:"revenue"`
+	PreviousQty     int          `json:"previous_qty_terjual"`
+	PreviousRevenue money.Amount `json:"previous_revenue"`
+	RevenueGrowth   *float64     `json:"revenue_growth"`
+	Deleted         bool         `json:"deleted,omitempty"`
+}
+
+// ProfitLossReport sums up a period the way a profit and loss statement
+// does. Sales refunded since are counted in GrossSales and taken out again
+// as Refunds; prices include PPN, so TaxCollected is part of NetSales.
+type ProfitLossReport struct {
+	// GrossSales is what the sales came to at the prices before discounts
+	GrossSales money.Amount `json:"gross_sales"`
+	// Discounts is what pricing rules and price contracts took off
+	Discounts          money.Amount `json:"discounts"`
+	RoundingAdjustment money.Amount `json:"rounding_adjustment"`
+	Refunds            money.Amount `json:"refunds"`
+	NetSales           money.Amount `json:"net_sales"`
+	TaxCollected       money.Amount `json:"tax_collected"`
+	// Revenue is NetSales without the PPN in it
+	Revenue     money.Amount `json:"revenue"`
+	COGS        money.Amount `json:"cogs"`
+	GrossProfit money.Amount `json:"gross_profit"`
+	GrossMargin float64      `json:"gross_margin"`
+	// Expenses is the petty cash paid out of the register
+	Expenses  money.Amount `json:"expenses"`
+	NetProfit money.Amount `json:"net_profit"`
+	NetMargin float64      `json:"net_margin"`
+}
+
+type ProfitReport struct {
+	TotalRevenue money.Amount    `json:"total_revenue"`
+	TotalCost    money.Amount    `json:"total_cost"`
+	GrossProfit  money.Amount    `json:"gross_profit"`
+	Margin       float64         `json:"margin"`
+	Products     []ProductProfit `json:"products"`
+	Periods      []PeriodProfit  `json:"periods"`
+}
+
+type ProductProfit struct {
+	ProductID   int          `json:"product_id"`
+	Nama        string       `json:"nama"`
+	QtyTerjual  int          `json:"qty_terjual"`
+	Revenue     money.Amount `json:"revenue"`
+	Cost        money.Amount `json:"cost"`
+	GrossProfit money.Amount `json:"gross_profit"`
+	Margin      float64      `json:"margin"`
+	Deleted     bool         `json:"deleted,omitempty"`
+}
+
+type PeriodProfit struct {
+	Tanggal     string       `json:"tanggal"`
+	Revenue     money.Amount `json:"revenue"`
+	Cost        money.Amount `json:"cost"`
+	GrossProfit money.Amount `json:"gross_profit"`
+	Margin      float64      `json:"margin"`
+}
+
+// ReceivablesAging groups outstanding installment amounts by how long they are overdue
+type ReceivablesAging struct {
+	AgingBuckets
+	Customers []CustomerAging `json:"customers"`
+}
+
+type AgingBuckets struct {
+	Current    money.Amount `json:"current"`
+	Days1To30  money.Amount `json:"days_1_30"`
+	Days31To60 money.Amount `json:"days_31_60"`
+	Days61To90 money.Amount `json:"days_61_90"`
+	Over90     money.Amount `json:"over_90"`
+	Total      money.Amount `json:"total"`
+}
+
+type CustomerAging struct {
+	CustomerID *int   `json:"customer_id"`
+	Nama       string `json:"nama"`
+	AgingBuckets
+}
+
+// ShrinkageReport values stock lost other than by a sale: damaged and expired
+// write-offs and shortages found by cycle counts and stock opnames. Returns to
+// suppliers are reported apart since they are usually credited back.
+type ShrinkageReport struct {
+	TotalQuantity   int                 `json:"total_quantity"`
+	TotalValue      money.Amount        `json:"total_value"`
+	SalesCost       money.Amount        `json:"sales_cost"`
+	ShrinkageRate   float64             `json:"shrinkage_rate"`
+	SupplierReturns ShrinkageByReason   `json:"supplier_returns"`
+	Reasons         []ShrinkageByReason `json:"reasons"`
+	Products        []ProductShrinkage  `json:"products"`
+}
+
+type ShrinkageByReason struct {
+	Reason   string       `json:"reason"`
+	Quantity int          `json:"quantity"`
+	Value    money.Amount `json:"value"`
+}
+
+type ProductShrinkage struct {
+	ProductID int          `json:"product_id"`
+	Nama      string       `json:"nama"`
+	Quantity  int          `json:"quantity"`
+	Value     money.Amount `json:"value"`
+	Deleted   bool         `json:"deleted,omitempty"`
 }
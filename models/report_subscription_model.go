@@ -0,0 +1,12 @@
+package models
+
+// ReportSubscription is a manager getting the sales summary by email; a pdf
+// subscription gets it attached as PDF too
+type ReportSubscription struct {
+	ID         int    `json:"id"`
+	Email      string `json:"email" validate:"required" example:"manager@example.com"`
+	Frequency  string `json:"frequency" validate:"required" enums:"daily,weekly"`
+	Format     string `json:"format" enums:"html,pdf"`
+	LastSentOn string `json:"last_sent_on,omitempty"`
+	CreatedAt  string `json:"created_at,omitempty"`
+}
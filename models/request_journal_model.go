@@ -0,0 +1,30 @@
+package models
+
+// JournalEntry is a captured request and the response it got, with personal
+// data redacted
+type JournalEntry struct {
+	ID             int64             `json:"id"`
+	Method         string            `json:"method"`
+	Path           string            `json:"path"`
+	Query          string            `json:"query"`
+	TerminalID     string            `json:"terminal_id"`
+	RequestHeaders map[string]string `json:"request_headers"`
+	RequestBody    string            `json:"request_body"`
+	ResponseStatus int               `json:"response_status"`
+	ResponseBody   string            `json:"response_body"`
+	DurationMs     int               `json:"duration_ms"`
+	CreatedAt      string            `json:"created_at,omitempty"`
+}
+
+// ReplayResult compares the captured response with the one a staging
+// instance gives for the same request
+type ReplayResult struct {
+	EntryID        int64  `json:"entry_id"`
+	TargetURL      string `json:"target_url"`
+	OriginalStatus int    `json:"original_status"`
+	OriginalBody   string `json:"original_body"`
+	ReplayStatus   int    `json:"replay_status"`
+	ReplayBody     string `json:"replay_body"`
+	DurationMs     int    `json:"duration_ms"`
+	StatusMatches  bool   `json:"status_matches"`
+}
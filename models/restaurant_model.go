@@ -0,0 +1,38 @@
+package models
+
+// OrderRequest opens a restaurant order served at TableNumber or, without
+// one, taken away under the next queue number of the day; it is priced at
+// the prices of StoreID when given, items added later too
+type OrderRequest struct {
+	Items       []CheckoutItem    `json:"items" validate:"required"`
+	CustomerID  int               `json:"customer_id,omitempty" minimum:"1"`
+	TableNumber string            `json:"table_number,omitempty" example:"12"`
+	StoreID     int               `json:"store_id,omitempty" minimum:"1"`
+	Approval    *OverrideApproval `json:"approval,omitempty"`
+}
+
+// OrderItemsRequest adds items to an open order
+type OrderItemsRequest struct {
+	Items    []CheckoutItem    `json:"items" validate:"required"`
+	Approval *OverrideApproval `json:"approval,omitempty"`
+}
+
+// KitchenOrder is an open order as the kitchen sees it: what to prepare and
+// who it is for, without prices
+type KitchenOrder struct {
+	TransactionID int                `json:"transaction_id"`
+	ReceiptNumber string             `json:"receipt_number,omitempty"`
+	TableNumber   string             `json:"table_number,omitempty" example:"12"`
+	QueueNumber   int                `json:"queue_number,omitempty"`
+	Status        string             `json:"status" enums:"draft,pending_payment"`
+	CreatedAt     string             `json:"created_at"`
+	Items         []KitchenOrderItem `json:"items"`
+}
+
+// KitchenOrderItem is a line of an open order; lines ordered before the
+// restaurant mode have no OrderedAt
+type KitchenOrderItem struct {
+	Name      string `json:"name"`
+	Quantity  int    `json:"quantity"`
+	OrderedAt string `json:"ordered_at,omitempty"`
+}
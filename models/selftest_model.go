@@ -0,0 +1,18 @@
+package models
+
+// SelfTestResult is the outcome of one startup check
+type SelfTestResult struct {
+	Name       string `json:"name"`
+	Critical   bool   `json:"critical"`
+	OK         bool   `json:"ok"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int    `json:"duration_ms"`
+}
+
+// SelfTestReport is the outcome of the startup self-test. OK is false when a
+// critical check failed.
+type SelfTestReport struct {
+	OK        bool             `json:"ok"`
+	CheckedAt string           `json:"checked_at"`
+	Checks    []SelfTestResult `json:"checks"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// StoreSettings is the profile of the store: who it is on receipts and tax
+// invoices, the currency amounts are in, the PPN rate prices include and the
+// time zone its days start in
+type StoreSettings struct {
+	Name          string `json:"name" validate:"required" example:"Toko Maju Jaya"`
+	Address       string `json:"address" example:"Jl. Merdeka 10, Bandung"`
+	NPWP          string `json:"npwp" example:"012345678901000"`
+	ReceiptFooter string `json:"receipt_footer" example:"Barang yang sudah dibeli tidak dapat ditukar"`
+	Currency      string `json:"currency" validate:"required" example:"IDR"`
+	TaxRate       int    `json:"tax_rate" minimum:"0" maximum:"100" example:"11"`
+	Timezone      string `json:"timezone" validate:"required" example:"Asia/Jakarta"`
+	UpdatedAt     string `json:"updated_at,omitempty"`
+}
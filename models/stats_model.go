@@ -0,0 +1,35 @@
+package models
+
+import "kasir-api/money"
+
+// StoreStats is the store's key business numbers for dashboards and
+// monitoring, recomputed at most once a minute
+type StoreStats struct {
+	// CatalogSize is the number of products in the catalog, bundles included
+	CatalogSize int `json:"catalog_size"`
+	// ActiveProducts is the number of catalog products sold in the last 30 days
+	ActiveProducts int          `json:"active_products"`
+	TotalCustomers int          `json:"total_customers"`
+	Revenue7Days   RevenueTrend `json:"revenue_7d"`
+	Revenue30Days  RevenueTrend `json:"revenue_30d"`
+	GeneratedAt    string       `json:"generated_at"`
+}
+
+// RevenueTrend is the revenue of the last Days full store days against the
+// Days before them, read from the daily summaries or the sales of a day not
+// summarized; today isn't over yet, so it isn't counted
+type RevenueTrend struct {
+	Days            int          `json:"days"`
+	Revenue         money.Amount `json:"revenue"`
+	Transactions    int          `json:"transactions"`
+	PreviousRevenue money.Amount `json:"previous_revenue"`
+	// ChangePercent is the change from PreviousRevenue, 0 when there was none
+	ChangePercent float64        `json:"change_percent"`
+	Daily         []DailyRevenue `json:"daily"`
+}
+
+type DailyRevenue struct {
+	Date         string       `json:"date"`
+	Revenue      money.Amount `json:"revenue"`
+	Transactions int          `json:"transactions"`
+}
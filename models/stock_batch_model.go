@@ -0,0 +1,17 @@
+package models
+
+import "kasir-api/money"
+
+// ExpiringBatch is a batch of stock that expires within the window asked
+// for, or has expired already and waits to be written off
+type ExpiringBatch struct {
+	ID          int    `json:"id"`
+	ProductID   int    `json:"product_id"`
+	ProductName string `json:"product_name"`
+	Quantity    int    `json:"quantity"`
+	ExpiresOn   string `json:"expires_on" example:"2026-12-31"`
+	// DaysLeft is negative once the batch has expired
+	DaysLeft int `json:"days_left"`
+	// Value is the batch at cost price
+	Value money.Amount `json:"value"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// StockMovement is a single change of product stock
+type StockMovement struct {
+	ID           int    `json:"id"`
+	ProductID    int    `json:"product_id"`
+	Quantity     int    `json:"quantity"`
+	BalanceAfter int    `json:"balance_after"`
+	Reason       string `json:"reason"`
+	ReferenceID  int    `json:"reference_id,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// StockMovementPage is a page of the stock movement history, newest first.
+// NextCursor fetches the page after it and is left out on the last page.
+type StockMovementPage struct {
+	Movements  []StockMovement `json:"movements"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// StockBalance is the stock of a product at the start of an export range
+type StockBalance struct {
+	ProductID   int
+	ProductName string
+	Opening     int
+}
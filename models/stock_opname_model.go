@@ -0,0 +1,52 @@
+package models
+
+import "kasir-api/money"
+
+// StockOpname is a physical inventory count session. It moves from open
+// (counting) to submitted (awaiting approval) to approved or rejected.
+type StockOpname struct {
+	ID              int               `json:"id"`
+	CategoryID      *int              `json:"category_id,omitempty"`
+	Status          string            `json:"status"`
+	Note            string            `json:"note"`
+	ReviewNote      string            `json:"review_note,omitempty"`
+	CountedProducts int               `json:"counted_products"`
+	NetVariance     int               `json:"net_variance"`
+	VarianceValue   money.Amount      `json:"variance_value"`
+	CreatedAt       string            `json:"created_at,omitempty"`
+	SubmittedAt     string            `json:"submitted_at,omitempty"`
+	ReviewedAt      string            `json:"reviewed_at,omitempty"`
+	Lines           []StockOpnameLine `json:"lines,omitempty"`
+}
+
+// StockOpnameLine compares the counted quantity of a product with its
+// recorded stock. Variance is counted minus expected, valued at cost price.
+type StockOpnameLine struct {
+	ProductID     int          `json:"product_id"`
+	ProductName   string       `json:"product_name"`
+	Barcode       string       `json:"barcode"`
+	ExpectedQty   int          `json:"expected_qty"`
+	CountedQty    int          `json:"counted_qty"`
+	Variance      int          `json:"variance"`
+	VarianceValue money.Amount `json:"variance_value"`
+	Flag          string       `json:"flag"`
+	CountedAt     string       `json:"counted_at,omitempty"`
+}
+
+type StartOpnameRequest struct {
+	CategoryID int    `json:"category_id" minimum:"0"`
+	Note       string `json:"note"`
+}
+
+type OpnameCountRequest struct {
+	Items []OpnameCountItem `json:"items" validate:"required"`
+}
+
+type OpnameCountItem struct {
+	ProductID  int `json:"product_id" validate:"required" minimum:"1"`
+	CountedQty int `json:"counted_qty" minimum:"0"`
+}
+
+type ReviewOpnameRequest struct {
+	Note string `json:"note"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "kasir-api/money"
+
+// Store is an outlet of a multi-store install; the outlets share the catalog
+// and stock but may price products differently
+type Store struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name" validate:"required" example:"Cabang Bandung"`
+	Address   string `json:"address" example:"Jl. Braga No. 10, Bandung"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// StorePrice is the price a store sells a product at instead of its own
+type StorePrice struct {
+	ProductID    int          `json:"product_id" validate:"required"`
+	ProductName  string       `json:"product_name,omitempty"`
+	Price        money.Amount `json:"price" validate:"required" minimum:"0"`
+	ProductPrice money.Amount `json:"product_price,omitempty"`
+	UpdatedAt    string       `json:"updated_at,omitempty"`
+}
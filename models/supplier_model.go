@@ -0,0 +1,39 @@
+package models
+
+import "kasir-api/money"
+
+type Supplier struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Phone     string `json:"phone"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// SupplierPrice is the current price a supplier offers for a product
+type SupplierPrice struct {
+	ID           int          `json:"id"`
+	SupplierID   int          `json:"supplier_id"`
+	SupplierName string       `json:"supplier_name,omitempty"`
+	ProductID    int          `json:"product_id" validate:"required" minimum:"1"`
+	ProductName  string       `json:"product_name,omitempty"`
+	Price        money.Amount `json:"price" validate:"required" minimum:"1"`
+	UpdatedAt    string       `json:"updated_at,omitempty"`
+}
+
+type PricePoint struct {
+	Price      money.Amount `json:"price"`
+	RecordedAt string       `json:"recorded_at"`
+}
+
+// PriceTrend is the price history of one product at one supplier
+type PriceTrend struct {
+	SupplierID    int          `json:"supplier_id"`
+	ProductID     int          `json:"product_id"`
+	FirstPrice    money.Amount `json:"first_price"`
+	LatestPrice   money.Amount `json:"latest_price"`
+	LowestPrice   money.Amount `json:"lowest_price"`
+	HighestPrice  money.Amount `json:"highest_price"`
+	ChangePercent float64      `json:"change_percent"`
+	History       []PricePoint `json:"history"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import "kasir-api/money"
+
+// CatalogSnapshot is the active catalog of the central server as pulled by an
+// offline terminal. Products carry their components when they are bundles.
+type CatalogSnapshot struct {
+	// Version is the catalog version of the central server when the snapshot was taken
+	Version      int64
+	Categories   []Category
+	Products     []Product
+	PricingRules []PricingRule
+}
+
+// SyncStatus reports how far an offline terminal is behind the central server
+type SyncStatus struct {
+	Online              bool   `json:"online"`
+	PendingTransactions int    `json:"pending_transactions"`
+	LastSyncAt          string `json:"last_sync_at,omitempty"`
+	LastError           string `json:"last_error,omitempty"`
+	LastErrorAt         string `json:"last_error_at,omitempty"`
+}
+
+// CatalogChanges is what changed in the catalog since a terminal's cursor.
+// Deleted products and categories are included with DeletedAt set; a bundle
+// is included when one of its components changed, since its stock did too.
+// The terminal sends Cursor with its next pull; a row may come again then.
+type CatalogChanges struct {
+	Cursor     int64      `json:"cursor"`
+	Categories []Category `json:"categories"`
+	Products   []Product  `json:"products"`
+}
+
+// SyncPushRequest is a batch of sales a terminal rang up offline
+type SyncPushRequest struct {
+	Transactions []SyncTransaction `json:"transactions" validate:"required"`
+}
+
+// SyncTransaction is a sale rung up offline, pushed as it was priced on the
+// terminal. Its receipt number identifies it, so it can be pushed again
+// safely until the terminal has seen the result.
+type SyncTransaction struct {
+	ReceiptNumber string       `json:"receipt_number" validate:"required" example:"T01/2026/10/000042"`
+	Status        string       `json:"status,omitempty" enums:"draft,pending_payment,paid,completed"`
+	TotalAmount   money.Amount `json:"total_amount" minimum:"0"`
+	// RoundingAdjustment is what rounding the total for cash added to the
+	// items, negative when it took off
+	RoundingAdjustment money.Amount          `json:"rounding_adjustment,omitempty"`
+	CreatedAt          string                `json:"created_at" validate:"required" example:"2026-10-14 09:30:00"`
+	Items              []SyncTransactionItem `json:"items" validate:"required"`
+}
+
+// SyncTransactionItem is a line of a sale rung up offline, with the pricing
+// rule and discount it got on the terminal
+type SyncTransactionItem struct {
+	ProductID     int          `json:"product_id" validate:"required"`
+	Quantity      int          `json:"quantity" minimum:"1"`
+	Subtotal      money.Amount `json:"subtotal" minimum:"0"`
+	PricingRuleID int          `json:"pricing_rule_id,omitempty"`
+	Discount      money.Amount `json:"discount,omitempty" minimum:"0"`
+}
+
+// SyncPushResult is what became of a pushed sale: created, duplicate when it
+// was pushed before, conflict when its receipt number belongs to a different
+// sale, deferred while its accounting period is closed, or rejected
+type SyncPushResult struct {
+	ReceiptNumber string `json:"receipt_number"`
+	Result        string `json:"result" enums:"created,duplicate,conflict,deferred,rejected"`
+	TransactionID int    `json:"transaction_id,omitempty"`
+	Message       string `json:"message,omitempty"`
+}
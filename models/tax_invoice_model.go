@@ -0,0 +1,30 @@
+package models
+
+import "kasir-api/money"
+
+// TaxInvoice is the faktur pajak of a sale to a business buyer. Prices include
+// PPN: TaxBase (DPP) is the total without it and TaxAmount the PPN at TaxRate
+// percent, adding up to TotalAmount. NPWPs are kept as digits and written out
+// in their usual form.
+type TaxInvoice struct {
+	ID            int          `json:"id"`
+	Number        string       `json:"number" example:"010.000-24.00000001"`
+	TransactionID int          `json:"transaction_id"`
+	ReceiptNumber string       `json:"receipt_number,omitempty"`
+	BuyerNPWP     string       `json:"buyer_npwp" example:"012345678901000"`
+	BuyerName     string       `json:"buyer_name" example:"PT Sumber Rejeki"`
+	BuyerAddress  string       `json:"buyer_address"`
+	TaxRate       int          `json:"tax_rate" example:"11"`
+	TaxBase       money.Amount `json:"tax_base"`
+	TaxAmount     money.Amount `json:"tax_amount"`
+	TotalAmount   money.Amount `json:"total_amount"`
+	IssuedAt      string       `json:"issued_at"`
+}
+
+// TaxInvoiceRequest issues the tax invoice of a transaction to its buyer
+type TaxInvoiceRequest struct {
+	TransactionID int    `json:"transaction_id" validate:"required" minimum:"1"`
+	BuyerNPWP     string `json:"buyer_npwp" validate:"required" example:"01.234.567.8-901.000"`
+	BuyerName     string `json:"buyer_name" validate:"required" example:"PT Sumber Rejeki"`
+	BuyerAddress  string `json:"buyer_address" validate:"required" example:"Jl. Gatot Subroto 10, Jakarta"`
+}
@@ -0,0 +1,34 @@
+package models
+
+// TerminalSession is the cashier signed in on a terminal, until it expires or
+// another cashier switches in. The token goes with the terminal's requests in
+// X-Terminal-Session.
+type TerminalSession struct {
+	Token      string `json:"token,omitempty"`
+	TerminalID string `json:"terminal_id"`
+	UserID     int    `json:"user_id"`
+	Username   string `json:"username"`
+	Role       string `json:"role"`
+	StartedAt  string `json:"started_at"`
+	ExpiresAt  string `json:"expires_at"`
+}
+
+// StartTerminalSessionRequest switches a terminal to the cashier whose PIN it is
+type StartTerminalSessionRequest struct {
+	TerminalID string `json:"terminal_id" validate:"required" example:"kasir-1"`
+	Username   string `json:"username" validate:"required" example:"budi"`
+	PIN        string `json:"pin" validate:"required" example:"1234"`
+}
+
+// PasswordSignInRequest signs an admin in on a terminal with the password of
+// their account, e.g. at the back office where no PIN is set
+type PasswordSignInRequest struct {
+	TerminalID string `json:"terminal_id" validate:"required" example:"kantor-1"`
+	Username   string `json:"username" validate:"required" example:"budi"`
+	Password   string `json:"password" validate:"required" example:"rahasia123"`
+}
+
+// SetPINRequest gives an account the PIN it switches into terminals with
+type SetPINRequest struct {
+	PIN string `json:"pin" validate:"required" example:"1234"`
+}
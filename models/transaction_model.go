@@ -1,27 +1,126 @@
 package models
 
+import "kasir-api/money"
+
 type Transaction struct {
-	ID          int                 `json:"id"`
-	TotalAmount int                 `json:"total_amount"`
-	CreatedAt   string              `json:"created_at,omitempty"`
-	DeletedAt   string              `json:"deleted_at,omitempty"`
-	Details     []TransactionDetail `json:"details"`
+	ID            int          `json:"id"`
+	ReceiptNumber string       `json:"receipt_number,omitempty"`
+	CustomerID    int          `json:"customer_id,omitempty"`
+	StoreID       int          `json:"store_id,omitempty"`
+	Status        string       `json:"status" enums:"draft,pending_payment,paid,completed,voided,refunded"`
+	TableNumber   string       `json:"table_number,omitempty" example:"12"`
+	QueueNumber   int          `json:"queue_number,omitempty"`
+	TotalAmount   money.Amount `json:"total_amount"`
+	// RoundingAdjustment is what rounding the total for cash added to the
+	// lines, negative when it took off; TotalAmount includes it
+	RoundingAdjustment money.Amount        `json:"rounding_adjustment,omitempty"`
+	CreatedAt          string              `json:"created_at,omitempty"`
+	DeletedAt          string              `json:"deleted_at,omitempty"`
+	Details            []TransactionDetail `json:"details"`
+	Payment            *TransactionPayment `json:"payment,omitempty"`
+}
+
+// TransactionSummary is a sale as listed in the transaction history, without
+// its lines
+type TransactionSummary struct {
+	ID            int          `json:"id"`
+	ReceiptNumber string       `json:"receipt_number,omitempty"`
+	CustomerID    int          `json:"customer_id,omitempty"`
+	Status        string       `json:"status" enums:"draft,pending_payment,paid,completed,voided,refunded"`
+	TableNumber   string       `json:"table_number,omitempty" example:"12"`
+	QueueNumber   int          `json:"queue_number,omitempty"`
+	TotalAmount   money.Amount `json:"total_amount"`
+	CreatedAt     string       `json:"created_at"`
+	DeletedAt     string       `json:"deleted_at,omitempty"`
+}
+
+// TransactionPage is a page of the transaction history, newest first.
+// NextCursor fetches the page after it and is left out on the last page.
+type TransactionPage struct {
+	Transactions []TransactionSummary `json:"transactions"`
+	NextCursor   string               `json:"next_cursor,omitempty"`
+}
+
+// TransactionPayment is what a sale was paid with: Amount in Currency, worth
+// BaseAmount in the store currency at Rate, of which Change was given back,
+// in the store currency, and the part paid with Voucher. A sale paid with a
+// voucher alone has no Currency.
+type TransactionPayment struct {
+	Currency   string       `json:"currency,omitempty" example:"USD"`
+	Amount     money.Amount `json:"amount"`
+	Rate       float64      `json:"rate,omitempty" example:"16250"`
+	BaseAmount money.Amount `json:"base_amount"`
+	Change     money.Amount `json:"change"`
+	// Tendered is Amount with the currency it is in, telling its minor unit
+	Tendered *money.Money    `json:"tendered,omitempty"`
+	Voucher  *VoucherPayment `json:"voucher,omitempty"`
 }
 
+// TransactionDetail is a line of a sale. A product deleted since is still
+// named, with ProductDeleted set. An open item has no product and is named
+// by its description.
 type TransactionDetail struct {
-	ID            int    `json:"id"`
-	TransactionID int    `json:"transaction_id"`
-	ProductID     int    `json:"product_id"`
-	ProductName   string `json:"product_name,omitempty"`
-	Quantity      int    `json:"quantity"`
-	Subtotal      int    `json:"subtotal"`
+	ID             int                   `json:"id"`
+	TransactionID  int                   `json:"transaction_id"`
+	ProductID      int                   `json:"product_id"`
+	ProductName    string                `json:"product_name,omitempty"`
+	ProductDeleted bool                  `json:"product_deleted,omitempty"`
+	OpenItem       bool                  `json:"open_item,omitempty"`
+	Quantity       int                   `json:"quantity"`
+	Subtotal       money.Amount          `json:"subtotal"`
+	CostPrice      money.Amount          `json:"-"`
+	Components     []BundleComponentSale `json:"components,omitempty"`
+	PricingRule    *AppliedPricingRule   `json:"pricing_rule,omitempty"`
+	PriceContract  *AppliedPriceContract `json:"price_contract,omitempty"`
+	PriceOverride  *AppliedPriceOverride `json:"price_override,omitempty"`
 }
 
+// CheckoutItem is a product sold, or an open item: a line with no product,
+// sold under a Description at a Price of its own, e.g. a delivery fee. A
+// product line may be sold at an Override a manager approves.
 type CheckoutItem struct {
-	ProductID int `json:"product_id"`
-	Quantity  int `json:"quantity"`
+	ProductID   int            `json:"product_id,omitempty" minimum:"1"`
+	Quantity    int            `json:"quantity" validate:"required" minimum:"1"`
+	Description string         `json:"description,omitempty" example:"Ongkos kirim"`
+	Price       money.Amount   `json:"price,omitempty" minimum:"1"`
+	Override    *PriceOverride `json:"override,omitempty"`
 }
 
+// CheckoutPayment is the cash tendered for a checkout, in minor units of
+// Currency, the store currency or one it accepts
+type CheckoutPayment struct {
+	Currency string       `json:"currency" validate:"required" example:"USD"`
+	Amount   money.Amount `json:"amount" validate:"required" minimum:"1"`
+}
+
+// CheckoutRequest is a sale; attaching a customer prices it with the
+// customer's price contracts, and selling at a store of a multi-store install
+// at the store's prices. A voucher pays as much of it as its balance
+// allows, the payment the rest. It is completed unless Status says it is
+// still a draft, waiting for payment, or paid but not yet handed over. Lines
+// with a price override or priced past the guardrails need the Approval of a
+// manager.
 type CheckoutRequest struct {
-	Items []CheckoutItem `json:"items"`
+	Items       []CheckoutItem    `json:"items" validate:"required"`
+	CustomerID  int               `json:"customer_id,omitempty" minimum:"1"`
+	Status      string            `json:"status,omitempty" enums:"draft,pending_payment,paid,completed"`
+	Payment     *CheckoutPayment  `json:"payment,omitempty"`
+	VoucherCode string            `json:"voucher_code,omitempty" example:"GV-7K3M-Q9XP"`
+	StoreID     int               `json:"store_id,omitempty" minimum:"1"`
+	Approval    *OverrideApproval `json:"approval,omitempty"`
+}
+
+// TransactionStatusRequest moves a sale on; voiding or refunding it needs a reason
+type TransactionStatusRequest struct {
+	Status string `json:"status" validate:"required" enums:"pending_payment,paid,completed,voided,refunded"`
+	Reason string `json:"reason" example:"Customer returned the goods"`
+}
+
+// TransactionStatusEvent is a status a sale went through
+type TransactionStatusEvent struct {
+	TransactionID int    `json:"transaction_id"`
+	FromStatus    string `json:"from_status"`
+	Status        string `json:"status"`
+	Reason        string `json:"reason"`
+	CreatedAt     string `json:"created_at"`
 }
@@ -0,0 +1,44 @@
+package models
+
+import "kasir-api/money"
+
+// Voucher is a gift card or voucher: a code with a balance in the store
+// currency, spent at checkout until it runs out or expires at the end of
+// ExpiresOn. Redemptions are given with a single voucher.
+type Voucher struct {
+	ID            int                 `json:"id"`
+	Code          string              `json:"code" example:"GV-7K3M-Q9XP"`
+	InitialAmount money.Amount        `json:"initial_amount"`
+	Balance       money.Amount        `json:"balance"`
+	ExpiresOn     string              `json:"expires_on,omitempty" example:"2026-12-31"`
+	Note          string              `json:"note,omitempty"`
+	CreatedAt     string              `json:"created_at"`
+	Redemptions   []VoucherRedemption `json:"redemptions,omitempty"`
+}
+
+// VoucherRequest issues a voucher worth Amount; a code is generated unless
+// one is given
+type VoucherRequest struct {
+	Code      string       `json:"code" example:"LEBARAN-2026"`
+	Amount    money.Amount `json:"amount" validate:"required" minimum:"1"`
+	ExpiresOn string       `json:"expires_on" example:"2026-12-31"`
+	Note      string       `json:"note"`
+}
+
+// VoucherRedemption is what a sale took from a voucher; a reversed one was
+// given back when the sale was voided or refunded
+type VoucherRedemption struct {
+	TransactionID int          `json:"transaction_id"`
+	Amount        money.Amount `json:"amount"`
+	ReversedAt    string       `json:"reversed_at,omitempty"`
+	CreatedAt     string       `json:"created_at"`
+}
+
+// VoucherPayment is the part of a sale paid with a voucher, and the balance
+// the voucher has left
+type VoucherPayment struct {
+	VoucherID int          `json:"-"`
+	Code      string       `json:"code"`
+	Amount    money.Amount `json:"amount"`
+	Balance   money.Amount `json:"balance"`
+}
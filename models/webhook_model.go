@@ -0,0 +1,39 @@
+package models
+
+import "encoding/json"
+
+type Webhook struct {
+	ID        int      `json:"id"`
+	URL       string   `json:"url" validate:"required" example:"https://example.com/hooks/kasir"`
+	Events    []string `json:"events" validate:"required" example:"transaction.created,product.low_stock"`
+	Secret    string   `json:"secret"`
+	Active    bool     `json:"active"`
+	CreatedAt string   `json:"created_at,omitempty"`
+}
+
+type UpdateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active"`
+}
+
+// WebhookDelivery is one attempt log entry of sending an event to a webhook
+type WebhookDelivery struct {
+	ID           int             `json:"id"`
+	WebhookID    int             `json:"webhook_id"`
+	Event        string          `json:"event"`
+	Payload      json.RawMessage `json:"payload" swaggertype:"object"`
+	Status       string          `json:"status"`
+	Attempts     int             `json:"attempts"`
+	ResponseCode int             `json:"response_code"`
+	Error        string          `json:"error,omitempty"`
+	CreatedAt    string          `json:"created_at,omitempty"`
+	DeliveredAt  string          `json:"delivered_at,omitempty"`
+}
+
+// WebhookEvent is the JSON body posted to webhook URLs
+type WebhookEvent struct {
+	Event      string      `json:"event"`
+	OccurredAt string      `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
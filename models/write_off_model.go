@@ -0,0 +1,27 @@
+package models
+
+import "kasir-api/money"
+
+// WriteOff is stock removed as damaged, expired or returned to a supplier
+type WriteOff struct {
+	ID           int          `json:"id"`
+	ProductID    int          `json:"product_id"`
+	ProductName  string       `json:"product_name,omitempty"`
+	SupplierID   *int         `json:"supplier_id,omitempty"`
+	SupplierName string       `json:"supplier_name,omitempty"`
+	Reason       string       `json:"reason"`
+	Quantity     int          `json:"quantity"`
+	UnitCost     money.Amount `json:"unit_cost"`
+	TotalCost    money.Amount `json:"total_cost"`
+	Note         string       `json:"note"`
+	CreatedAt    string       `json:"created_at,omitempty"`
+}
+
+type WriteOffRequest struct {
+	ProductID int    `json:"product_id" validate:"required" minimum:"1"`
+	Reason    string `json:"reason" validate:"required" enums:"damaged,expired,supplier_return"`
+	Quantity  int    `json:"quantity" validate:"required" minimum:"1"`
+	// SupplierID is required when the reason is supplier_return
+	SupplierID int    `json:"supplier_id" minimum:"0"`
+	Note       string `json:"note"`
+}
@@ -0,0 +1,107 @@
+// Package money describes the amounts the store handles. Amounts are integers
+// in the minor unit of the store currency, e.g. whole rupiah for IDR and cents
+// for USD, so Price 1550 is Rp1.550 in one store and $15.50 in another.
+package money
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Default is the currency used when a store sets none
+const Default = "IDR"
+
+// Currency is an ISO 4217 currency. Digits is how many digits of the minor
+// unit make up a whole unit, 0 for IDR and 2 for USD.
+type Currency struct {
+	Code   string `json:"code" example:"USD"`
+	Symbol string `json:"symbol" example:"$"`
+	Digits int    `json:"digits" example:"2"`
+}
+
+var currencies = map[string]Currency{
+	"IDR": {Code: "IDR", Symbol: "Rp", Digits: 0},
+	"MYR": {Code: "MYR", Symbol: "RM", Digits: 2},
+	"SGD": {Code: "SGD", Symbol: "S$", Digits: 2},
+	"USD": {Code: "USD", Symbol: "$", Digits: 2},
+	"EUR": {Code: "EUR", Symbol: "€", Digits: 2},
+	"JPY": {Code: "JPY", Symbol: "¥", Digits: 0},
+}
+
+// Lookup returns the currency with the ISO 4217 code, such as "IDR"
+func Lookup(code string) (Currency, error) {
+	c, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, fmt.Errorf("unknown currency %q", code)
+	}
+	return c, nil
+}
+
+// Amount is a sum of a sale in the minor unit of the store currency, as the
+// BIGINT money columns keep it. Product prices are in the same unit, so a
+// price converts to an Amount as it is.
+type Amount int64
+
+// Times returns a for each of n units, e.g. the subtotal of n units at a
+func (a Amount) Times(n int) Amount {
+	return a * Amount(n)
+}
+
+// In returns a as Money of currency c
+func (a Amount) In(c Currency) Money {
+	return New(int64(a), c)
+}
+
+// Money is an amount in the minor unit of its currency, which it names
+// in JSON, e.g. {"amount": 1550, "currency": {"code": "USD", ...}}
+type Money struct {
+	Amount   int64    `json:"amount" example:"1550"`
+	Currency Currency `json:"currency"`
+}
+
+// New returns amount minor units of c
+func New(amount int64, c Currency) Money {
+	return Money{Amount: amount, Currency: c}
+}
+
+// Whole splits the amount into whole units and the minor units left over,
+// both without the sign: 1550 cents is 15 and 50
+func (m Money) Whole() (whole, fraction int64) {
+	n := m.Amount
+	if n < 0 {
+		n = -n
+	}
+	unit := int64(1)
+	for i := 0; i < m.Currency.Digits; i++ {
+		unit *= 10
+	}
+	return n / unit, n % unit
+}
+
+// Decimal writes the amount in whole units with a "." before the fraction
+// and no grouping, as spreadsheets and other machines read it: "-15.50"
+func (m Money) Decimal() string {
+	whole, fraction := m.Whole()
+	s := strconv.FormatInt(whole, 10)
+	if m.Currency.Digits > 0 {
+		s += fmt.Sprintf(".%0*d", m.Currency.Digits, fraction)
+	}
+	if m.Amount < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// String writes the amount after its currency code, e.g. "USD 15.50"
+func (m Money) String() string {
+	return m.Currency.Code + " " + m.Decimal()
+}
+
+// Convert returns m in currency to at rate, the whole units of to one whole
+// unit of m's currency is worth, rounded to the nearest minor unit of to
+func (m Money) Convert(to Currency, rate float64) Money {
+	scale := math.Pow10(to.Digits - m.Currency.Digits)
+	return New(int64(math.Round(float64(m.Amount)*rate*scale)), to)
+}
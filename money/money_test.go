@@ -0,0 +1,69 @@
+package money
+
+import "testing"
+
+func TestConvert(t *testing.T) {
+	idr, usd, jpy := currencies["IDR"], currencies["USD"], currencies["JPY"]
+	tests := []struct {
+		name string
+		from Money
+		to   Currency
+		rate float64
+		want int64
+	}{
+		{"whole dollars to rupiah", New(2000, usd), idr, 16250, 325000},
+		{"dollars and cents to rupiah", New(1550, usd), idr, 16250, 251875},
+		{"rupiah to cents", New(325000, idr), usd, 1.0 / 16250, 2000},
+		{"same digits", New(1000, idr), jpy, 0.0095, 10},
+		{"negative", New(-1550, usd), idr, 16250, -251875},
+		{"half way rounds up", New(150, usd), jpy, 1, 2},
+		{"negative half way rounds away from zero", New(-150, usd), jpy, 1, -2},
+		{"below half rounds down", New(149, usd), jpy, 1, 1},
+		{"zero", New(0, usd), idr, 16250, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.from.Convert(tt.to, tt.rate)
+			if got.Amount != tt.want || got.Currency != tt.to {
+				t.Errorf("Convert(%s, %v) = %s %d, want %s %d", tt.to.Code, tt.rate, got.Currency.Code, got.Amount, tt.to.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{New(1550, currencies["USD"]), "15.50"},
+		{New(5, currencies["USD"]), "0.05"},
+		{New(-1550, currencies["USD"]), "-15.50"},
+		{New(-5, currencies["USD"]), "-0.05"},
+		{New(1550, currencies["IDR"]), "1550"},
+		{New(-1550, currencies["IDR"]), "-1550"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.Decimal(); got != tt.want {
+			t.Errorf("Decimal of %d %s = %q, want %q", tt.m.Amount, tt.m.Currency.Code, got, tt.want)
+		}
+	}
+}
+
+func TestTimes(t *testing.T) {
+	tests := []struct {
+		a    Amount
+		n    int
+		want Amount
+	}{
+		{3500, 3, 10500},
+		{3500, 0, 0},
+		{-250, 4, -1000},
+		{250, -4, -1000},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Times(tt.n); got != tt.want {
+			t.Errorf("%d.Times(%d) = %d, want %d", tt.a, tt.n, got, tt.want)
+		}
+	}
+}
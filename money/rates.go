@@ -0,0 +1,55 @@
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RateSource quotes exchange rates against a base currency
+type RateSource interface {
+	// Rates returns how many whole units of each currency one whole unit of
+	// base buys, keyed by currency code
+	Rates(base string) (map[string]float64, error)
+}
+
+// HTTPRateSource fetches rates from a JSON API answering
+// {"base": "IDR", "rates": {"USD": 0.0000615, ...}}, the format of most
+// free exchange rate APIs. A "{base}" in URL is replaced with the base code.
+type HTTPRateSource struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewHTTPRateSource(url string) *HTTPRateSource {
+	return &HTTPRateSource{
+		URL:    url,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *HTTPRateSource) Rates(base string) (map[string]float64, error) {
+	resp, err := s.Client.Get(strings.ReplaceAll(s.URL, "{base}", base))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("exchange rate API responded with status %d", resp.StatusCode)
+	}
+
+	var quote struct {
+		Base  string             `json:"base"`
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&quote); err != nil {
+		return nil, fmt.Errorf("invalid exchange rate response: %v", err)
+	}
+	if quote.Base != "" && !strings.EqualFold(quote.Base, base) {
+		return nil, fmt.Errorf("exchange rate API quoted against %s, not %s", quote.Base, base)
+	}
+	return quote.Rates, nil
+}
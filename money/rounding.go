@@ -0,0 +1,63 @@
+package money
+
+import "fmt"
+
+// Rounding modes
+const (
+	RoundNone    = "none"
+	RoundNearest = "nearest"
+	RoundDown    = "down"
+	RoundUp      = "up"
+)
+
+// Rounding is how totals paid in cash are rounded when coins below Unit
+// are out of use, e.g. to the nearest 100 rupiah. The zero Rounding leaves
+// totals as they are.
+type Rounding struct {
+	Mode string
+	Unit int64
+}
+
+// NewRounding returns the rounding of mode to multiples of unit
+func NewRounding(mode string, unit int64) (Rounding, error) {
+	switch mode {
+	case "", RoundNone:
+		return Rounding{}, nil
+	case RoundNearest, RoundDown, RoundUp:
+	default:
+		return Rounding{}, fmt.Errorf("unknown rounding mode %q", mode)
+	}
+	if unit <= 1 {
+		return Rounding{}, fmt.Errorf("rounding unit must be above 1, not %d", unit)
+	}
+	return Rounding{Mode: mode, Unit: unit}, nil
+}
+
+// Round returns amount rounded to a multiple of the unit; halfway amounts
+// round up under RoundNearest, so Rp1.250 is Rp1.300 to the nearest 100
+func (r Rounding) Round(amount int64) int64 {
+	if r.Unit <= 1 {
+		return amount
+	}
+	rest := amount % r.Unit
+	if rest < 0 {
+		rest += r.Unit
+	}
+	if rest == 0 {
+		return amount
+	}
+
+	down := amount - rest
+	switch r.Mode {
+	case RoundDown:
+		return down
+	case RoundUp:
+		return down + r.Unit
+	case RoundNearest:
+		if 2*rest >= r.Unit {
+			return down + r.Unit
+		}
+		return down
+	}
+	return amount
+}
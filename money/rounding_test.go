@@ -0,0 +1,67 @@
+package money
+
+import "testing"
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		mode   string
+		amount int64
+		want   int64
+	}{
+		{RoundNearest, 1249, 1200},
+		{RoundNearest, 1250, 1300},
+		{RoundNearest, 1251, 1300},
+		{RoundNearest, 1200, 1200},
+		{RoundNearest, -1249, -1200},
+		{RoundNearest, -1250, -1200},
+		{RoundNearest, -1251, -1300},
+		{RoundNearest, 0, 0},
+		{RoundDown, 1299, 1200},
+		{RoundDown, -1201, -1300},
+		{RoundDown, 1200, 1200},
+		{RoundUp, 1201, 1300},
+		{RoundUp, -1299, -1200},
+		{RoundUp, 1200, 1200},
+	}
+	for _, tt := range tests {
+		r, err := NewRounding(tt.mode, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.Round(tt.amount); got != tt.want {
+			t.Errorf("Round(%d) to the %s 100 = %d, want %d", tt.amount, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestRoundNone(t *testing.T) {
+	for _, mode := range []string{"", RoundNone} {
+		r, err := NewRounding(mode, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.Round(1250); got != 1250 {
+			t.Errorf("Round(1250) with mode %q = %d, want 1250", mode, got)
+		}
+	}
+}
+
+func TestNewRounding(t *testing.T) {
+	tests := []struct {
+		mode    string
+		unit    int64
+		wantErr bool
+	}{
+		{RoundNearest, 100, false},
+		{RoundUp, 2, false},
+		{RoundNearest, 1, true},
+		{RoundDown, 0, true},
+		{RoundDown, -100, true},
+		{"bankers", 100, true},
+	}
+	for _, tt := range tests {
+		if _, err := NewRounding(tt.mode, tt.unit); (err != nil) != tt.wantErr {
+			t.Errorf("NewRounding(%q, %d) error = %v, want error %v", tt.mode, tt.unit, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Sender delivers a plain text message to a recipient (phone number or email)
+type Sender interface {
+	Send(recipient, message string) error
+}
+
+// LogSender only logs messages, used when no gateway is configured
+type LogSender struct {
+	Channel string
+}
+
+func (s LogSender) Send(recipient, message string) error {
+	log.Printf("[%s] to %s: %s", s.Channel, recipient, message)
+	return nil
+}
+
+// GatewaySender posts messages as JSON to an HTTP gateway (WhatsApp, SMS or email provider)
+type GatewaySender struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+func NewGatewaySender(url, token string) *GatewaySender {
+	return &GatewaySender{
+		URL:    url,
+		Token:  token,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *GatewaySender) Send(recipient, message string) error {
+	body, err := json.Marshal(map[string]string{
+		"to":      recipient,
+		"message": message,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("gateway responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// TelegramSender sends messages through a Telegram bot to chats it was added
+// to; the recipient is the chat ID
+type TelegramSender struct {
+	Token  string
+	Client *http.Client
+	// APIURL is the Bot API address, https://api.telegram.org unless set
+	APIURL string
+}
+
+func NewTelegramSender(token string) *TelegramSender {
+	return &TelegramSender{
+		Token:  token,
+		Client: &http.Client{Timeout: 10 * time.Second},
+		APIURL: "https://api.telegram.org",
+	}
+}
+
+func (s *TelegramSender) Send(recipient, message string) error {
+	return postJSON(s.Client, s.APIURL+"/bot"+s.Token+"/sendMessage", "", map[string]string{
+		"chat_id": recipient,
+		"text":    message,
+	})
+}
+
+// WhatsAppSender sends text messages from a number of the WhatsApp Business
+// Cloud API; the recipient is a phone number in international form
+type WhatsAppSender struct {
+	PhoneNumberID string
+	Token         string
+	Client        *http.Client
+	// APIURL is the Graph API address and version, https://graph.facebook.com/v19.0 unless set
+	APIURL string
+}
+
+func NewWhatsAppSender(phoneNumberID, token string) *WhatsAppSender {
+	return &WhatsAppSender{
+		PhoneNumberID: phoneNumberID,
+		Token:         token,
+		Client:        &http.Client{Timeout: 10 * time.Second},
+		APIURL:        "https://graph.facebook.com/v19.0",
+	}
+}
+
+func (s *WhatsAppSender) Send(recipient, message string) error {
+	return postJSON(s.Client, s.APIURL+"/"+s.PhoneNumberID+"/messages", s.Token, map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"to":                strings.TrimPrefix(recipient, "+"),
+		"type":              "text",
+		"text":              map[string]string{"body": message},
+	})
+}
+
+// postJSON posts payload to endpoint, with token as bearer token when set
+func postJSON(client *http.Client, endpoint, token string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// the URL is left out, the Bot API's carries the token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("error sending message: %v", urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("provider responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package payment
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"kasir-api/money"
+)
+
+// Statuses a gateway reports for a payment link
+const (
+	StatusPaid    = "paid"
+	StatusExpired = "expired"
+)
+
+// LinkRequest asks a gateway for a payment page of Amount, identified by
+// Reference in the gateway's callbacks
+type LinkRequest struct {
+	Reference   string       `json:"reference"`
+	Amount      money.Amount `json:"amount"`
+	Description string       `json:"description"`
+	ExpiresAt   time.Time    `json:"expires_at"`
+}
+
+// Gateway creates payment links; its callbacks are verified with Verify
+type Gateway interface {
+	CreateLink(req LinkRequest) (string, error)
+}
+
+// HTTPGateway posts link requests as JSON to a payment gateway, or an adapter
+// in front of one, which answers with {"url": "<payment page>"}
+type HTTPGateway struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+func NewHTTPGateway(url, token string) *HTTPGateway {
+	return &HTTPGateway{
+		URL:    url,
+		Token:  token,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (g *HTTPGateway) CreateLink(link LinkRequest) (string, error) {
+	body, err := json.Marshal(link)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, g.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("gateway responded with status %d", resp.StatusCode)
+	}
+
+	var created struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("invalid gateway response: %v", err)
+	}
+	if created.URL == "" {
+		return "", errors.New("gateway response has no url")
+	}
+	return created.URL, nil
+}
+
+// Verify reports whether signature, sent as "sha256=<hex>" in the
+// X-Callback-Signature header, is the HMAC-SHA256 of body with secret
+func Verify(secret string, body []byte, signature string) bool {
+	if secret == "" {
+		return false
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
@@ -0,0 +1,44 @@
+package printer
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// ESC/POS commands sent around a ticket: reset the printer before it, feed
+// the paper past the cutter and cut after it
+var (
+	escInit = []byte{0x1b, '@'}
+	escCut  = []byte{0x1d, 'V', 66, 0}
+)
+
+// Timeout bounds connecting to a printer and sending it a ticket
+const Timeout = 10 * time.Second
+
+// Send prints text on the ESC/POS printer listening on addr (host:port),
+// cutting the paper after it. Lines end in "\n".
+func Send(ctx context.Context, addr, text string) error {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	ticket := make([]byte, 0, len(escInit)+len(text)+len(escCut))
+	ticket = append(ticket, escInit...)
+	ticket = append(ticket, text...)
+	ticket = append(ticket, escCut...)
+	if _, err := conn.Write(ticket); err != nil {
+		return err
+	}
+	return conn.Close()
+}
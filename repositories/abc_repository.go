@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"database/sql"
+	"kasir-api/models"
+
+	"github.com/lib/pq"
+)
+
+type ABCRepository struct {
+	db *sql.DB
+}
+
+func NewABCRepository(db *sql.DB) *ABCRepository {
+	return &ABCRepository{db: db}
+}
+
+// GetProductRevenue retrieves every active product with its revenue over the last days days
+func (r *ABCRepository) GetProductRevenue(days int) ([]models.ProductRevenue, error) {
+	rows, err := r.db.Query(`
+		SELECT p.id, COALESCE(sales.revenue, 0)
+		FROM product p
+		LEFT JOIN (
+			SELECT sl.product_id, SUM(sl.subtotal) as revenue
+			FROM product_sales_line sl
+			INNER JOIN transactions t ON sl.transaction_id = t.id
+			WHERE t.deleted_at IS NULL AND t.created_at >= CURRENT_DATE - $1::int
+			GROUP BY sl.product_id
+		) sales ON sales.product_id = p.id
+		WHERE p.deleted_at IS NULL AND NOT p.is_bundle
+	`, days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revenues := []models.ProductRevenue{}
+	for rows.Next() {
+		var pr models.ProductRevenue
+		if err := rows.Scan(&pr.ProductID, &pr.Revenue); err != nil {
+			return nil, err
+		}
+		revenues = append(revenues, pr)
+	}
+	return revenues, rows.Err()
+}
+
+// SaveClasses stores the class of every product in one transaction
+func (r *ABCRepository) SaveClasses(classes map[int]string) error {
+	byClass := map[string][]int{}
+	for productID, class := range classes {
+		byClass[class] = append(byClass[class], productID)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for class, ids := range byClass {
+		_, err := tx.Exec(
+			"UPDATE product SET abc_class = $1, abc_classified_at = NOW() WHERE id = ANY($2)",
+			class, pq.Array(ids),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetLastClassifiedAt returns when products were last classified, or sql.ErrNoRows
+func (r *ABCRepository) GetLastClassifiedAt() (string, error) {
+	var classifiedAt sql.NullTime
+	err := r.db.QueryRow("SELECT MAX(abc_classified_at) FROM product WHERE deleted_at IS NULL").Scan(&classifiedAt)
+	if err != nil {
+		return "", err
+	}
+	if !classifiedAt.Valid {
+		return "", sql.ErrNoRows
+	}
+	return classifiedAt.Time.Format("2006-01-02 15:04:05"), nil
+}
+
+// GetClassSummary retrieves the product count and revenue over the last days days per stored class
+func (r *ABCRepository) GetClassSummary(days int) ([]models.ABCClassSummary, error) {
+	rows, err := r.db.Query(`
+		SELECT p.abc_class, COUNT(*), COALESCE(SUM(sales.revenue), 0)
+		FROM product p
+		LEFT JOIN (
+			SELECT sl.product_id, SUM(sl.subtotal) as revenue
+			FROM product_sales_line sl
+			INNER JOIN transactions t ON sl.transaction_id = t.id
+			WHERE t.deleted_at IS NULL AND t.created_at >= CURRENT_DATE - $1::int
+			GROUP BY sl.product_id
+		) sales ON sales.product_id = p.id
+		WHERE p.deleted_at IS NULL AND NOT p.is_bundle
+		GROUP BY p.abc_class
+		ORDER BY p.abc_class
+	`, days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	classes := []models.ABCClassSummary{}
+	for rows.Next() {
+		var c models.ABCClassSummary
+		if err := rows.Scan(&c.Class, &c.Products, &c.Revenue); err != nil {
+			return nil, err
+		}
+		classes = append(classes, c)
+	}
+	return classes, rows.Err()
+}
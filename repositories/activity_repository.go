@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"kasir-api/models"
+)
+
+type ActivityRepository struct {
+	db *sql.DB
+}
+
+func NewActivityRepository(db *sql.DB) *ActivityRepository {
+	return &ActivityRepository{db: db}
+}
+
+// GetPage retrieves up to limit activity entries after cursor, newest first,
+// of one entity unless entity is empty, and the cursor of the page after
+func (r *ActivityRepository) GetPage(entity string, cursor Cursor, limit int) (models.ActivityPage, error) {
+	query := "SELECT id, entity, entity_id, name, action, old_value, new_value, created_at FROM activity_log WHERE TRUE"
+	args := []interface{}{}
+	if entity != "" {
+		args = append(args, entity)
+		query += fmt.Sprintf(" AND entity = $%d", len(args))
+	}
+	if !cursor.IsZero() {
+		args = append(args, cursor.CreatedAt, cursor.ID)
+		query += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+	// one row more tells whether there is a page after
+	args = append(args, limit+1)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return models.ActivityPage{}, err
+	}
+	defer rows.Close()
+
+	page := models.ActivityPage{Activities: []models.ActivityEntry{}}
+	var last Cursor
+	for rows.Next() {
+		var a models.ActivityEntry
+		var createdAt time.Time
+		if err := rows.Scan(&a.ID, &a.Entity, &a.EntityID, &a.Name, &a.Action, &a.OldValue, &a.NewValue, &createdAt); err != nil {
+			return models.ActivityPage{}, err
+		}
+		if len(page.Activities) == limit {
+			page.NextCursor = last.String()
+			break
+		}
+
+		a.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+		page.Activities = append(page.Activities, a)
+		last = Cursor{CreatedAt: createdAt, ID: a.ID}
+	}
+	return page, rows.Err()
+}
@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"kasir-api/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDuplicateAdminUser = errors.New("an admin with this username or email already exists")
+	ErrRoleNotFound       = errors.New("role not found")
+)
+
+type AdminUserRepository struct {
+	db *sql.DB
+}
+
+func NewAdminUserRepository(db *sql.DB) *AdminUserRepository {
+	return &AdminUserRepository{db: db}
+}
+
+// Create inserts an admin with an already hashed password; email may be empty
+func (r *AdminUserRepository) Create(username, email, role, passwordHash string) (models.AdminUser, error) {
+	admin := models.AdminUser{Username: username, Email: email, Role: role}
+	var createdAt sql.NullTime
+	err := r.db.QueryRow(`
+		INSERT INTO admin_user (username, email, role, password_hash) VALUES ($1, NULLIF($2, ''), $3, $4)
+		ON CONFLICT DO NOTHING
+		RETURNING id, created_at
+	`, username, email, role, passwordHash).Scan(&admin.ID, &createdAt)
+	if err == sql.ErrNoRows {
+		return models.AdminUser{}, ErrDuplicateAdminUser
+	}
+	if isRoleViolation(err) {
+		return models.AdminUser{}, fmt.Errorf("%w: %s", ErrRoleNotFound, role)
+	}
+	if err != nil {
+		return models.AdminUser{}, err
+	}
+
+	if createdAt.Valid {
+		admin.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return admin, nil
+}
+
+// GetByEmail retrieves the admin with an email address, sql.ErrNoRows when none has it
+func (r *AdminUserRepository) GetByEmail(email string) (models.AdminUser, error) {
+	var admin models.AdminUser
+	var createdAt, verifiedAt sql.NullTime
+	err := r.db.QueryRow(
+		"SELECT id, username, email, email_verified_at, role, created_at FROM admin_user WHERE email = $1",
+		email,
+	).Scan(&admin.ID, &admin.Username, &admin.Email, &verifiedAt, &admin.Role, &createdAt)
+	if err != nil {
+		return models.AdminUser{}, err
+	}
+
+	admin.EmailVerified = verifiedAt.Valid
+	if createdAt.Valid {
+		admin.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return admin, nil
+}
+
+// GetByID retrieves an admin, sql.ErrNoRows when there is no such admin
+func (r *AdminUserRepository) GetByID(id int) (models.AdminUser, error) {
+	var admin models.AdminUser
+	var email sql.NullString
+	var createdAt, verifiedAt sql.NullTime
+	err := r.db.QueryRow(
+		"SELECT id, username, email, email_verified_at, role, created_at FROM admin_user WHERE id = $1",
+		id,
+	).Scan(&admin.ID, &admin.Username, &email, &verifiedAt, &admin.Role, &createdAt)
+	if err != nil {
+		return models.AdminUser{}, err
+	}
+
+	admin.Email = email.String
+	admin.EmailVerified = verifiedAt.Valid
+	if createdAt.Valid {
+		admin.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return admin, nil
+}
+
+// SetPIN replaces an admin's PIN hash, sql.ErrNoRows when there is no such admin
+func (r *AdminUserRepository) SetPIN(id int, pinHash string) error {
+	result, err := r.db.Exec("UPDATE admin_user SET pin_hash = $1 WHERE id = $2", pinHash, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetPIN retrieves an admin by username with its PIN hash, empty when no PIN
+// is set; sql.ErrNoRows when there is no such admin
+func (r *AdminUserRepository) GetPIN(username string) (models.AdminUser, string, error) {
+	return r.getWithHash("pin_hash", username)
+}
+
+// GetPassword retrieves an admin by username with its password hash;
+// sql.ErrNoRows when there is no such admin
+func (r *AdminUserRepository) GetPassword(username string) (models.AdminUser, string, error) {
+	return r.getWithHash("password_hash", username)
+}
+
+// getWithHash retrieves an admin by username with the hash in column
+func (r *AdminUserRepository) getWithHash(column, username string) (models.AdminUser, string, error) {
+	var admin models.AdminUser
+	var email, hash sql.NullString
+	var createdAt, verifiedAt sql.NullTime
+	err := r.db.QueryRow(
+		"SELECT id, username, email, email_verified_at, role, created_at, "+column+" FROM admin_user WHERE username = $1",
+		username,
+	).Scan(&admin.ID, &admin.Username, &email, &verifiedAt, &admin.Role, &createdAt, &hash)
+	if err != nil {
+		return models.AdminUser{}, "", err
+	}
+
+	admin.Email = email.String
+	admin.EmailVerified = verifiedAt.Valid
+	if createdAt.Valid {
+		admin.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return admin, hash.String, nil
+}
+
+// SetRole moves an admin to another role, sql.ErrNoRows when there is no such admin
+func (r *AdminUserRepository) SetRole(id int, role string) error {
+	result, err := r.db.Exec("UPDATE admin_user SET role = $1 WHERE id = $2", role, id)
+	if isRoleViolation(err) {
+		return fmt.Errorf("%w: %s", ErrRoleNotFound, role)
+	}
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// isRoleViolation reports whether err is the foreign key of admin_user.role refusing an unknown role
+func isRoleViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrInvalidAuthToken = errors.New("token is invalid, expired or already used")
+
+// AuthTokenRepository keeps the single-use tokens of email verification and
+// password reset links, by the SHA-256 of their secret part
+type AuthTokenRepository struct {
+	db *sql.DB
+}
+
+func NewAuthTokenRepository(db *sql.DB) *AuthTokenRepository {
+	return &AuthTokenRepository{db: db}
+}
+
+// Create records a token of an admin and returns its ID
+func (r *AuthTokenRepository) Create(adminID int, purpose, tokenHash string, expiresAt time.Time) (int, error) {
+	var id int
+	err := r.db.QueryRow(
+		"INSERT INTO admin_user_token (admin_user_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4) RETURNING id",
+		adminID, purpose, tokenHash, expiresAt,
+	).Scan(&id)
+	return id, err
+}
+
+// CountSince counts the tokens issued to an admin for purpose since a time
+func (r *AuthTokenRepository) CountSince(adminID int, purpose string, since time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		"SELECT COUNT(*) FROM admin_user_token WHERE admin_user_id = $1 AND purpose = $2 AND created_at >= $3",
+		adminID, purpose, since,
+	).Scan(&count)
+	return count, err
+}
+
+// consumeAuthToken marks an unused, unexpired token as used and returns its admin
+func consumeAuthToken(tx *sql.Tx, tokenID int, purpose, tokenHash string) (int, error) {
+	var adminID int
+	err := tx.QueryRow(`
+		UPDATE admin_user_token SET used_at = NOW()
+		WHERE id = $1 AND purpose = $2 AND token_hash = $3 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING admin_user_id
+	`, tokenID, purpose, tokenHash).Scan(&adminID)
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidAuthToken
+	}
+	return adminID, err
+}
+
+// VerifyEmail spends an email verification token and marks its admin's
+// address verified
+func (r *AuthTokenRepository) VerifyEmail(tokenID int, purpose, tokenHash string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	adminID, err := consumeAuthToken(tx, tokenID, purpose, tokenHash)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE admin_user SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1", adminID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ResetPassword spends a password reset token, replaces its admin's password
+// hash and voids the admin's other reset tokens. Following the link proves
+// the address too, so it is marked verified.
+func (r *AuthTokenRepository) ResetPassword(tokenID int, purpose, tokenHash, passwordHash string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	adminID, err := consumeAuthToken(tx, tokenID, purpose, tokenHash)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"UPDATE admin_user SET password_hash = $1, email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $2",
+		passwordHash, adminID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"UPDATE admin_user_token SET used_at = NOW() WHERE admin_user_id = $1 AND purpose = $2 AND used_at IS NULL",
+		adminID, purpose,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
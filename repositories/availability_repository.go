@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"database/sql"
+	"kasir-api/models"
+
+	"github.com/lib/pq"
+)
+
+// maxPublicProducts bounds the products returned to the public widget in one call
+const maxPublicProducts = 100
+
+type AvailabilityRepository struct {
+	db *sql.DB
+}
+
+func NewAvailabilityRepository(db *sql.DB) *AvailabilityRepository {
+	return &AvailabilityRepository{db: db}
+}
+
+// GetPublic returns the availability of the opted-in products among ids, or
+// of every opted-in product when ids is empty. Products that are not opted in
+// are left out as if they didn't exist.
+func (r *AvailabilityRepository) GetPublic(ids []int) ([]models.ProductAvailability, error) {
+	rows, err := r.db.Query(`
+		SELECT p.id, p.name, `+productStock+` > 0
+		FROM product p
+		WHERE p.public_availability AND p.deleted_at IS NULL
+		  AND (cardinality($1::int[]) = 0 OR p.id = ANY($1))
+		ORDER BY p.name, p.id
+		LIMIT $2
+	`, pq.Array(ids), maxPublicProducts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []models.ProductAvailability{}
+	for rows.Next() {
+		var p models.ProductAvailability
+		if err := rows.Scan(&p.ID, &p.Name, &p.InStock); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+// SetPublic opts a product in to or out of the public availability widget
+func (r *AvailabilityRepository) SetPublic(productID int, public bool) error {
+	result, err := r.db.Exec("UPDATE product SET public_availability = $1, updated_at = NOW() WHERE id = $2 AND deleted_at IS NULL", public, productID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"database/sql"
+	"strconv"
+)
+
+// SaveEach runs save for each of n items in tx, each in a savepoint of its
+// own so a failed item leaves tx usable for the others; errs[i] is what item
+// i failed with. When atomic it stops at the first failure, and tx is only
+// good to be rolled back.
+func SaveEach(tx *sql.Tx, n int, atomic bool, save func(i int) error) (errs []error, failed bool, err error) {
+	errs = make([]error, n)
+	for i := 0; i < n; i++ {
+		savepoint := "batch_item_" + strconv.Itoa(i)
+		if _, err := tx.Exec("SAVEPOINT " + savepoint); err != nil {
+			return nil, false, err
+		}
+
+		if errs[i] = save(i); errs[i] != nil {
+			failed = true
+			if atomic {
+				return errs, true, nil
+			}
+			if _, err := tx.Exec("ROLLBACK TO SAVEPOINT " + savepoint); err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+
+		if _, err := tx.Exec("RELEASE SAVEPOINT " + savepoint); err != nil {
+			return nil, false, err
+		}
+	}
+	return errs, failed, nil
+}
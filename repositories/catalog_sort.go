@@ -0,0 +1,28 @@
+package repositories
+
+import "strings"
+
+// catalogSortFields are the fields products and categories can be listed by
+var catalogSortFields = map[string]bool{"name": true, "created_at": true, "updated_at": true}
+
+// ValidCatalogSort reports whether sort is empty or a catalog sort field,
+// prefixed with - for descending order
+func ValidCatalogSort(sort string) bool {
+	return sort == "" || catalogSortFields[strings.TrimPrefix(sort, "-")]
+}
+
+// CatalogOrderBy returns the ORDER BY clause for sort on the table aliased as
+// alias. Ties are broken by id in the same direction, so the newest of rows
+// stamped in the same second comes first on -created_at; an empty or unknown
+// sort orders by id.
+func CatalogOrderBy(alias, sort string) string {
+	field := strings.TrimPrefix(sort, "-")
+	if !catalogSortFields[field] {
+		return " ORDER BY " + alias + "id"
+	}
+	direction := " ASC"
+	if strings.HasPrefix(sort, "-") {
+		direction = " DESC"
+	}
+	return " ORDER BY " + alias + field + direction + ", " + alias + "id" + direction
+}
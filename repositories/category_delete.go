@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+)
+
+// What happens to the active products of a category being deleted. Products
+// with another active category only leave the deleted one; the policy is for
+// those it would leave with none.
+const (
+	// refuse while the category has active products
+	CategoryDeleteBlock = "block"
+	// soft delete the products left without a category
+	CategoryDeleteCascade = "cascade"
+	// move the products left without a category to Uncategorized
+	CategoryDeleteReassign = "reassign"
+)
+
+// UncategorizedName is the category products are reassigned to, created the
+// first time it is needed
+const UncategorizedName = "Uncategorized"
+
+var ErrDeleteUncategorized = errors.New("the Uncategorized category takes the products of deleted categories, delete it with another policy")
+
+// CategoryHasProductsError refuses to delete a category that still has
+// active products
+type CategoryHasProductsError struct {
+	Products int
+}
+
+func (e *CategoryHasProductsError) Error() string {
+	return fmt.Sprintf("category has %d active products", e.Products)
+}
@@ -2,11 +2,15 @@ package repositories
 
 import (
 	"database/sql"
+	"errors"
 	"kasir-api/models"
+	"time"
 
-	"google.golang.org/protobuf/types/known/timestamppb"
+	"github.com/lib/pq"
 )
 
+var ErrDuplicateCategoryName = errors.New("another category already has this name")
+
 type CategoryRepository struct {
 	db *sql.DB
 }
@@ -15,9 +19,20 @@ func NewCategoryRepository(db *sql.DB) *CategoryRepository {
 	return &CategoryRepository{db: db}
 }
 
-// GetCategories retrieves all active categories from the database
-func (r *CategoryRepository) GetAll() ([]models.Category, error) {
-	rows, err := r.db.Query("SELECT id, name, description, deleted_at FROM category WHERE deleted_at IS NULL")
+// GetCategories retrieves all active categories from the database, in the
+// order of sort. With updatedSince set only the categories updated from then
+// on are retrieved, deleted ones included.
+func (r *CategoryRepository) GetAll(sort string, updatedSince time.Time) ([]models.Category, error) {
+	args := []interface{}{}
+	query := "SELECT id, name, description, created_at, updated_at, deleted_at FROM category"
+	if updatedSince.IsZero() {
+		query += " WHERE deleted_at IS NULL"
+	} else {
+		args = append(args, updatedSince)
+		query += " WHERE updated_at >= $1"
+	}
+
+	rows, err := r.db.Query(query+CatalogOrderBy("", sort), args...)
 	if err != nil {
 		return nil, err
 	}
@@ -26,95 +41,251 @@ func (r *CategoryRepository) GetAll() ([]models.Category, error) {
 	var categories []models.Category
 	for rows.Next() {
 		var c models.Category
-		var deletedAt sql.NullTime
-		if err := rows.Scan(&c.ID, &c.Name, &c.Description, &deletedAt); err != nil {
+		var createdAt, updatedAt, deletedAt sql.NullTime
+		if err := rows.Scan(&c.ID, &c.Name, &c.Description, &createdAt, &updatedAt, &deletedAt); err != nil {
 			return nil, err
 		}
-		if deletedAt.Valid {
-			c.DeletedAt = timestamppb.New(deletedAt.Time)
-		}
+		c.CreatedAt = timePtr(createdAt)
+		c.UpdatedAt = timePtr(updatedAt)
+		c.DeletedAt = timePtr(deletedAt)
 		categories = append(categories, c)
 	}
 
 	return categories, nil
 }
 
+// isDuplicateCategoryName reports whether err is a violation of the unique
+// category name index
+func isDuplicateCategoryName(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_category_name"
+}
+
 // Create inserts a new category into the database
 func (r *CategoryRepository) Create(category models.Category) (models.Category, error) {
-	var deletedAt sql.NullTime
-	err := r.db.QueryRow(
-		"INSERT INTO category (name, description) VALUES ($1, $2) RETURNING id, deleted_at",
-		category.Name, category.Description,
-	).Scan(&category.ID, &deletedAt)
+	return r.save(category, createCategory)
+}
 
+// CreateBatch creates categories as Create does, all in one database
+// transaction; see SaveEach for errs and atomic
+func (r *CategoryRepository) CreateBatch(categories []models.Category, atomic bool) ([]models.Category, []error, error) {
+	return r.saveBatch(categories, atomic, createCategory)
+}
+
+// UpdateBatch updates categories as Update does, all in one database
+// transaction; see SaveEach for errs and atomic
+func (r *CategoryRepository) UpdateBatch(categories []models.Category, atomic bool) ([]models.Category, []error, error) {
+	return r.saveBatch(categories, atomic, updateCategory)
+}
+
+func (r *CategoryRepository) save(category models.Category, write func(tx *sql.Tx, category *models.Category) error) (models.Category, error) {
+	tx, err := r.db.Begin()
 	if err != nil {
 		return models.Category{}, err
 	}
+	defer tx.Rollback()
 
-	if deletedAt.Valid {
-		category.DeletedAt = timestamppb.New(deletedAt.Time)
+	if err := write(tx, &category); err != nil {
+		return models.Category{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Category{}, err
 	}
-
 	return category, nil
 }
 
+func (r *CategoryRepository) saveBatch(categories []models.Category, atomic bool, write func(tx *sql.Tx, category *models.Category) error) ([]models.Category, []error, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	saved := append([]models.Category(nil), categories...)
+	errs, failed, err := SaveEach(tx, len(saved), atomic, func(i int) error {
+		return write(tx, &saved[i])
+	})
+	if err != nil || (atomic && failed) {
+		return nil, errs, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return saved, errs, nil
+}
+
+// createCategory inserts category in tx, filling in its ID and timestamps
+func createCategory(tx *sql.Tx, category *models.Category) error {
+	var createdAt, updatedAt, deletedAt sql.NullTime
+	err := tx.QueryRow(
+		"INSERT INTO category (name, description) VALUES ($1, $2) RETURNING id, created_at, updated_at, deleted_at",
+		category.Name, category.Description,
+	).Scan(&category.ID, &createdAt, &updatedAt, &deletedAt)
+
+	if isDuplicateCategoryName(err) {
+		return ErrDuplicateCategoryName
+	}
+	if err != nil {
+		return err
+	}
+
+	category.CreatedAt = timePtr(createdAt)
+	category.UpdatedAt = timePtr(updatedAt)
+	category.DeletedAt = timePtr(deletedAt)
+	return nil
+}
+
 // GetByID retrieves a category by its ID
 func (r *CategoryRepository) GetByID(id int) (models.Category, error) {
 	var c models.Category
-	var deletedAt sql.NullTime
+	var createdAt, updatedAt, deletedAt sql.NullTime
 	err := r.db.QueryRow(
-		"SELECT id, name, description, deleted_at FROM category WHERE id = $1 AND deleted_at IS NULL",
+		"SELECT id, name, description, created_at, updated_at, deleted_at FROM category WHERE id = $1 AND deleted_at IS NULL",
 		id,
-	).Scan(&c.ID, &c.Name, &c.Description, &deletedAt)
+	).Scan(&c.ID, &c.Name, &c.Description, &createdAt, &updatedAt, &deletedAt)
 
 	if err != nil {
 		return models.Category{}, err
 	}
 
-	if deletedAt.Valid {
-		c.DeletedAt = timestamppb.New(deletedAt.Time)
+	c.CreatedAt = timePtr(createdAt)
+	c.UpdatedAt = timePtr(updatedAt)
+	c.DeletedAt = timePtr(deletedAt)
+
+	return c, nil
+}
+
+// GetByName retrieves the active category with name, ignoring case
+func (r *CategoryRepository) GetByName(name string) (models.Category, error) {
+	var c models.Category
+	var createdAt, updatedAt, deletedAt sql.NullTime
+	err := r.db.QueryRow(
+		"SELECT id, name, description, created_at, updated_at, deleted_at FROM category WHERE LOWER(name) = LOWER($1) AND deleted_at IS NULL",
+		name,
+	).Scan(&c.ID, &c.Name, &c.Description, &createdAt, &updatedAt, &deletedAt)
+
+	if err != nil {
+		return models.Category{}, err
 	}
 
+	c.CreatedAt = timePtr(createdAt)
+	c.UpdatedAt = timePtr(updatedAt)
+	c.DeletedAt = timePtr(deletedAt)
+
 	return c, nil
 }
 
-// Delete soft deletes a category by its ID
-func (r *CategoryRepository) Delete(id int) error {
-	result, err := r.db.Exec(
-		"UPDATE category SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL",
-		id,
-	)
+// Delete soft deletes a category by its ID, dealing with its active products
+// as policy says
+func (r *CategoryRepository) Delete(id int, policy string) (models.CategoryDeletion, error) {
+	deletion := models.CategoryDeletion{Policy: policy}
+
+	tx, err := r.db.Begin()
 	if err != nil {
-		return err
+		return deletion, err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	var locked int
+	err = tx.QueryRow("SELECT id FROM category WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id).Scan(&locked)
 	if err != nil {
-		return err
+		return deletion, err
 	}
 
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	if policy == CategoryDeleteBlock {
+		var products int
+		err := tx.QueryRow(`
+			SELECT COUNT(*) FROM product_category pc
+			INNER JOIN product p ON pc.product_id = p.id
+			WHERE pc.category_id = $1 AND p.deleted_at IS NULL
+		`, id).Scan(&products)
+		if err != nil {
+			return deletion, err
+		}
+		if products > 0 {
+			return deletion, &CategoryHasProductsError{Products: products}
+		}
+	} else {
+		// the products left without an active category
+		var stranded pq.Int64Array
+		err := tx.QueryRow(`
+			SELECT COALESCE(ARRAY_AGG(p.id ORDER BY p.id), '{}') FROM product_category pc
+			INNER JOIN product p ON pc.product_id = p.id
+			WHERE pc.category_id = $1 AND p.deleted_at IS NULL AND NOT EXISTS (
+				SELECT 1 FROM product_category o
+				INNER JOIN category c ON o.category_id = c.id
+				WHERE o.product_id = p.id AND o.category_id <> $1 AND c.deleted_at IS NULL
+			)
+		`, id).Scan(&stranded)
+		if err != nil {
+			return deletion, err
+		}
+
+		if len(stranded) > 0 && policy == CategoryDeleteCascade {
+			if _, err := tx.Exec("UPDATE product SET deleted_at = NOW(), updated_at = NOW() WHERE id = ANY($1)", stranded); err != nil {
+				return deletion, err
+			}
+			deletion.ProductsDeleted = len(stranded)
+		}
+
+		if len(stranded) > 0 && policy == CategoryDeleteReassign {
+			err := tx.QueryRow("SELECT id FROM category WHERE LOWER(name) = LOWER($1) AND deleted_at IS NULL", UncategorizedName).Scan(&deletion.UncategorizedID)
+			if err == sql.ErrNoRows {
+				err = tx.QueryRow(
+					"INSERT INTO category (name, description) VALUES ($1, $2) RETURNING id",
+					UncategorizedName, "Products of deleted categories",
+				).Scan(&deletion.UncategorizedID)
+			}
+			if err != nil {
+				return deletion, err
+			}
+			if deletion.UncategorizedID == id {
+				return deletion, ErrDeleteUncategorized
+			}
+
+			if _, err := tx.Exec(
+				"INSERT INTO product_category (product_id, category_id) SELECT UNNEST($1::INTEGER[]), $2 ON CONFLICT DO NOTHING",
+				stranded, deletion.UncategorizedID,
+			); err != nil {
+				return deletion, err
+			}
+			// terminals pulling catalog changes see the new category
+			if _, err := tx.Exec("UPDATE product SET updated_at = NOW() WHERE id = ANY($1)", stranded); err != nil {
+				return deletion, err
+			}
+			deletion.ProductsReassigned = len(stranded)
+		}
 	}
 
-	return nil
+	if _, err := tx.Exec("UPDATE category SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1", id); err != nil {
+		return deletion, err
+	}
+	return deletion, tx.Commit()
 }
 
 // Update updates an existing category in the database
 func (r *CategoryRepository) Update(category models.Category) (models.Category, error) {
-	var deletedAt sql.NullTime
-	err := r.db.QueryRow(
-		"UPDATE category SET name = $1, description = $2 WHERE id = $3 AND deleted_at IS NULL RETURNING id, name, description, deleted_at",
+	return r.save(category, updateCategory)
+}
+
+// updateCategory updates category in tx, filling in its timestamps; an
+// unknown or deleted category is sql.ErrNoRows
+func updateCategory(tx *sql.Tx, category *models.Category) error {
+	var createdAt, updatedAt, deletedAt sql.NullTime
+	err := tx.QueryRow(
+		"UPDATE category SET name = $1, description = $2, updated_at = NOW() WHERE id = $3 AND deleted_at IS NULL RETURNING id, name, description, created_at, updated_at, deleted_at",
 		category.Name, category.Description, category.ID,
-	).Scan(&category.ID, &category.Name, &category.Description, &deletedAt)
+	).Scan(&category.ID, &category.Name, &category.Description, &createdAt, &updatedAt, &deletedAt)
 
-	if err != nil {
-		return models.Category{}, err
+	if isDuplicateCategoryName(err) {
+		return ErrDuplicateCategoryName
 	}
-
-	if deletedAt.Valid {
-		category.DeletedAt = timestamppb.New(deletedAt.Time)
+	if err != nil {
+		return err
 	}
 
-	return category, nil
+	category.CreatedAt = timePtr(createdAt)
+	category.UpdatedAt = timePtr(updatedAt)
+	category.DeletedAt = timePtr(deletedAt)
+	return nil
 }
@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"kasir-api/models"
+)
+
+var ErrReceiptItemNotFound = errors.New("product not found on goods receipt")
+
+type CostRepository struct {
+	db *sql.DB
+}
+
+func NewCostRepository(db *sql.DB) *CostRepository {
+	return &CostRepository{db: db}
+}
+
+// GetCostHistory returns the stock ledger of every product ever received, in
+// the order the movements happened, with the unit cost of each receipt
+func (r *CostRepository) GetCostHistory() ([]models.CostMovement, error) {
+	rows, err := r.db.Query(`
+		SELECT m.product_id, m.quantity, m.reason = $1, COALESCE(gri.unit_cost, 0)
+		FROM stock_movement m
+		INNER JOIN product p ON m.product_id = p.id
+		LEFT JOIN (
+			SELECT receipt_id, product_id, SUM(quantity * unit_cost) / NULLIF(SUM(quantity), 0) as unit_cost
+			FROM goods_receipt_item
+			GROUP BY receipt_id, product_id
+		) gri ON m.reason = $1 AND gri.receipt_id = m.reference_id AND gri.product_id = m.product_id
+		WHERE p.deleted_at IS NULL AND NOT p.is_bundle
+			AND m.product_id IN (SELECT product_id FROM goods_receipt_item)
+		ORDER BY m.product_id, m.created_at, m.id
+	`, MovementReceipt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.CostMovement{}
+	for rows.Next() {
+		var m models.CostMovement
+		if err := rows.Scan(&m.ProductID, &m.Quantity, &m.Receipt, &m.UnitCost); err != nil {
+			return nil, err
+		}
+		history = append(history, m)
+	}
+	return history, rows.Err()
+}
+
+// GetProductCosts returns the cost price and stock of the products ever received
+func (r *CostRepository) GetProductCosts() ([]models.ProductCost, error) {
+	rows, err := r.db.Query(`
+		SELECT p.id, p.name, p.cost_price, p.stock, COALESCE((
+			SELECT -SUM(m.quantity) FROM stock_movement m
+			WHERE m.product_id = p.id AND m.quantity < 0 AND m.reason IN ($1, $2, $3)
+		), 0)
+		FROM product p
+		WHERE p.deleted_at IS NULL AND NOT p.is_bundle
+			AND p.id IN (SELECT product_id FROM goods_receipt_item)
+		ORDER BY p.id
+	`, MovementCycleCount, MovementOpname, MovementAdjustment)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	costs := []models.ProductCost{}
+	for rows.Next() {
+		var c models.ProductCost
+		if err := rows.Scan(&c.ProductID, &c.Name, &c.CostPrice, &c.Stock, &c.LossQuantity); err != nil {
+			return nil, err
+		}
+		costs = append(costs, c)
+	}
+	return costs, rows.Err()
+}
+
+// UpdateCostPrices sets the new cost of each change in one transaction. A
+// product whose cost price moved since the changes were computed is left
+// alone; the number of products updated is returned.
+func (r *CostRepository) UpdateCostPrices(changes []models.ProductCostChange) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	updated := 0
+	for _, c := range changes {
+		res, err := tx.Exec(
+			"UPDATE product SET cost_price = $1, updated_at = NOW() WHERE id = $2 AND cost_price = $3",
+			c.NewCost, c.ProductID, c.OldCost,
+		)
+		if err != nil {
+			return 0, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		updated += int(n)
+	}
+
+	return updated, tx.Commit()
+}
+
+// GetReceiptDate returns when a goods receipt was received
+func (r *CostRepository) GetReceiptDate(receiptID int) (time.Time, error) {
+	var receivedAt time.Time
+	err := r.db.QueryRow("SELECT created_at FROM goods_receipt WHERE id = $1", receiptID).Scan(&receivedAt)
+	if err == sql.ErrNoRows {
+		return receivedAt, ErrReceiptItemNotFound
+	}
+	return receivedAt, err
+}
+
+// CorrectReceiptCost sets the unit cost recorded for a product on a goods receipt
+func (r *CostRepository) CorrectReceiptCost(receiptID int, correction models.ReceiptCostCorrection) error {
+	res, err := r.db.Exec(
+		"UPDATE goods_receipt_item SET unit_cost = $1 WHERE receipt_id = $2 AND product_id = $3",
+		correction.UnitCost, receiptID, correction.ProductID,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrReceiptItemNotFound
+	}
+	return nil
+}
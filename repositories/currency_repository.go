@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"kasir-api/models"
+)
+
+// CurrencyRepository keeps the foreign currencies the store accepts with
+// their exchange rates
+type CurrencyRepository struct {
+	db *sql.DB
+}
+
+func NewCurrencyRepository(db *sql.DB) *CurrencyRepository {
+	return &CurrencyRepository{db: db}
+}
+
+const exchangeRateColumns = "currency, rate, fetched, updated_at"
+
+func scanExchangeRate(row rowScanner) (models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+	var updatedAt time.Time
+	if err := row.Scan(&rate.Currency, &rate.Rate, &rate.Fetched, &updatedAt); err != nil {
+		return models.ExchangeRate{}, err
+	}
+	rate.UpdatedAt = updatedAt.Format("2006-01-02 15:04:05")
+	return rate, nil
+}
+
+func (r *CurrencyRepository) GetAll() ([]models.ExchangeRate, error) {
+	rows, err := r.db.Query("SELECT " + exchangeRateColumns + " FROM exchange_rate ORDER BY currency")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []models.ExchangeRate{}
+	for rows.Next() {
+		rate, err := scanExchangeRate(rows)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, rows.Err()
+}
+
+// GetRate returns the rate of an accepted currency, sql.ErrNoRows when it isn't accepted
+func (r *CurrencyRepository) GetRate(currency string) (models.ExchangeRate, error) {
+	return scanExchangeRate(r.db.QueryRow("SELECT "+exchangeRateColumns+" FROM exchange_rate WHERE currency = $1", currency))
+}
+
+// Set accepts a currency at rate, or updates the rate of one already accepted
+func (r *CurrencyRepository) Set(rate models.ExchangeRate) (models.ExchangeRate, error) {
+	return scanExchangeRate(r.db.QueryRow(`
+		INSERT INTO exchange_rate (currency, rate, fetched) VALUES ($1, $2, $3)
+		ON CONFLICT (currency) DO UPDATE SET rate = EXCLUDED.rate, fetched = EXCLUDED.fetched, updated_at = NOW()
+		RETURNING `+exchangeRateColumns,
+		rate.Currency, rate.Rate, rate.Fetched,
+	))
+}
+
+// UpdateFetched sets the rate of a currency whose rate is fetched; one no
+// longer accepted or since set by hand is left alone
+func (r *CurrencyRepository) UpdateFetched(currency string, rate float64) error {
+	_, err := r.db.Exec("UPDATE exchange_rate SET rate = $1, updated_at = NOW() WHERE currency = $2 AND fetched", rate, currency)
+	return err
+}
+
+// Delete stops accepting a currency; payments already taken in it are kept
+func (r *CurrencyRepository) Delete(currency string) error {
+	result, err := r.db.Exec("DELETE FROM exchange_rate WHERE currency = $1", currency)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cursor is the last row of a page of a list ordered by (created_at, id),
+// newest first; the next page starts right after it. Unlike an offset it
+// stays put while rows are added, and the page is read from the index
+// however deep into the list it is.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// String encodes c for clients, which send it back as it is
+func (c Cursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.CreatedAt.UnixMicro(), 10) + "." + strconv.Itoa(c.ID)))
+}
+
+// IsZero reports whether c is the start of the list
+func (c Cursor) IsZero() bool {
+	return c.ID == 0
+}
+
+// ParseCursor decodes a cursor made by Cursor.String; an empty s is the start
+// of the list
+func ParseCursor(s string) (Cursor, bool) {
+	if s == "" {
+		return Cursor{}, true
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, false
+	}
+	micros, id, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return Cursor{}, false
+	}
+	createdAt, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return Cursor{}, false
+	}
+	c := Cursor{CreatedAt: time.UnixMicro(createdAt).UTC()}
+	c.ID, err = strconv.Atoi(id)
+	if err != nil || c.ID <= 0 {
+		return Cursor{}, false
+	}
+	return c, true
+}
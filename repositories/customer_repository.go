@@ -0,0 +1,284 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"kasir-api/models"
+	"kasir-api/money"
+
+	"github.com/lib/pq"
+)
+
+var ErrCustomerNotFound = errors.New("customer not found")
+
+type CustomerRepository struct {
+	db *sql.DB
+}
+
+func NewCustomerRepository(db *sql.DB) *CustomerRepository {
+	return &CustomerRepository{db: db}
+}
+
+// GetAll retrieves all active customers
+func (r *CustomerRepository) GetAll() ([]models.Customer, error) {
+	rows, err := r.db.Query("SELECT id, name, phone, email, reminder_channel, created_at FROM customer WHERE deleted_at IS NULL ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	customers := []models.Customer{}
+	for rows.Next() {
+		var c models.Customer
+		var createdAt sql.NullTime
+		if err := rows.Scan(&c.ID, &c.Name, &c.Phone, &c.Email, &c.ReminderChannel, &createdAt); err != nil {
+			return nil, err
+		}
+		if createdAt.Valid {
+			c.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+		}
+		customers = append(customers, c)
+	}
+	return customers, nil
+}
+
+// GetByID retrieves a customer by ID
+func (r *CustomerRepository) GetByID(id int) (models.Customer, error) {
+	var c models.Customer
+	var createdAt sql.NullTime
+	err := r.db.QueryRow(
+		"SELECT id, name, phone, email, reminder_channel, created_at FROM customer WHERE id = $1 AND deleted_at IS NULL",
+		id,
+	).Scan(&c.ID, &c.Name, &c.Phone, &c.Email, &c.ReminderChannel, &createdAt)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	if createdAt.Valid {
+		c.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return c, nil
+}
+
+// Create inserts a new customer
+func (r *CustomerRepository) Create(customer models.Customer) (models.Customer, error) {
+	var createdAt sql.NullTime
+	err := r.db.QueryRow(
+		"INSERT INTO customer (name, phone, email, reminder_channel) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		customer.Name, customer.Phone, customer.Email, customer.ReminderChannel,
+	).Scan(&customer.ID, &createdAt)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	if createdAt.Valid {
+		customer.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return customer, nil
+}
+
+// CreateMany inserts customers in one transaction, so an import either lands
+// completely or not at all
+func (r *CustomerRepository) CreateMany(customers []models.Customer) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO customer (name, phone, email, reminder_channel) VALUES ($1, $2, $3, $4)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, c := range customers {
+		if _, err := stmt.Exec(c.Name, c.Phone, c.Email, c.ReminderChannel); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Merge moves the kasbon, payments, reminders, installment plans, price
+// contracts and sales of sourceIDs to targetID, fills the target's missing phone and email from
+// them and soft deletes them. Every customer must be active.
+func (r *CustomerRepository) Merge(targetID int, sourceIDs []int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var locked int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM (
+			SELECT id FROM customer WHERE (id = $1 OR id = ANY($2)) AND deleted_at IS NULL FOR UPDATE
+		) c
+	`, targetID, pq.Array(sourceIDs)).Scan(&locked)
+	if err != nil {
+		return err
+	}
+	if locked != len(sourceIDs)+1 {
+		return ErrCustomerNotFound
+	}
+
+	for _, table := range []string{"kasbon", "kasbon_payment", "reminder_history", "installment_plan", "price_contract", "transactions"} {
+		_, err := tx.Exec("UPDATE "+table+" SET customer_id = $1 WHERE customer_id = ANY($2)", targetID, pq.Array(sourceIDs))
+		if err != nil {
+			return err
+		}
+	}
+
+	// the lowest ID is the oldest record, the likeliest to be right
+	_, err = tx.Exec(`
+		UPDATE customer t SET
+			phone = CASE WHEN t.phone = '' THEN COALESCE((SELECT s.phone FROM customer s WHERE s.id = ANY($2) AND s.phone <> '' ORDER BY s.id LIMIT 1), '') ELSE t.phone END,
+			email = CASE WHEN t.email = '' THEN COALESCE((SELECT s.email FROM customer s WHERE s.id = ANY($2) AND s.email <> '' ORDER BY s.id LIMIT 1), '') ELSE t.email END
+		WHERE t.id = $1
+	`, targetID, pq.Array(sourceIDs))
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE customer SET deleted_at = NOW() WHERE id = ANY($1)", pq.Array(sourceIDs)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Update updates an existing customer
+func (r *CustomerRepository) Update(customer models.Customer) (models.Customer, error) {
+	_, err := r.db.Exec(
+		"UPDATE customer SET name = $1, phone = $2, email = $3, reminder_channel = $4 WHERE id = $5 AND deleted_at IS NULL",
+		customer.Name, customer.Phone, customer.Email, customer.ReminderChannel, customer.ID,
+	)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	return customer, nil
+}
+
+// Delete soft deletes a customer
+func (r *CustomerRepository) Delete(id int) error {
+	result, err := r.db.Exec("UPDATE customer SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CreateKasbon records new store credit for a customer
+func (r *CustomerRepository) CreateKasbon(kasbon models.Kasbon) (models.Kasbon, error) {
+	var createdAt sql.NullTime
+	err := r.db.QueryRow(
+		"INSERT INTO kasbon (customer_id, amount, due_date, note) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		kasbon.CustomerID, kasbon.Amount, kasbon.DueDate, kasbon.Note,
+	).Scan(&kasbon.ID, &createdAt)
+	if err != nil {
+		return models.Kasbon{}, err
+	}
+
+	if createdAt.Valid {
+		kasbon.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return kasbon, nil
+}
+
+// CreatePayment records a kasbon repayment for a customer
+func (r *CustomerRepository) CreatePayment(payment models.KasbonPayment) (models.KasbonPayment, error) {
+	var createdAt sql.NullTime
+	err := r.db.QueryRow(
+		"INSERT INTO kasbon_payment (customer_id, amount, note) VALUES ($1, $2, $3) RETURNING id, created_at",
+		payment.CustomerID, payment.Amount, payment.Note,
+	).Scan(&payment.ID, &createdAt)
+	if err != nil {
+		return models.KasbonPayment{}, err
+	}
+
+	if createdAt.Valid {
+		payment.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return payment, nil
+}
+
+// GetCredit retrieves the kasbon entries, payments and balances of a customer.
+// Payments are applied to the oldest kasbon first, so the overdue balance is
+// everything past its due date minus all payments made so far.
+func (r *CustomerRepository) GetCredit(customerID int) (*models.CustomerCredit, error) {
+	credit := &models.CustomerCredit{
+		CustomerID: customerID,
+		Kasbon:     []models.Kasbon{},
+		Payments:   []models.KasbonPayment{},
+	}
+
+	rows, err := r.db.Query(
+		"SELECT id, customer_id, amount, due_date::text, note, created_at FROM kasbon WHERE customer_id = $1 ORDER BY due_date, id",
+		customerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var k models.Kasbon
+		var createdAt sql.NullTime
+		if err := rows.Scan(&k.ID, &k.CustomerID, &k.Amount, &k.DueDate, &k.Note, &createdAt); err != nil {
+			return nil, err
+		}
+		if createdAt.Valid {
+			k.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+		}
+		credit.TotalKasbon += k.Amount
+		credit.Kasbon = append(credit.Kasbon, k)
+	}
+
+	paymentRows, err := r.db.Query(
+		"SELECT id, customer_id, amount, note, created_at FROM kasbon_payment WHERE customer_id = $1 ORDER BY created_at, id",
+		customerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer paymentRows.Close()
+
+	for paymentRows.Next() {
+		var p models.KasbonPayment
+		var createdAt sql.NullTime
+		if err := paymentRows.Scan(&p.ID, &p.CustomerID, &p.Amount, &p.Note, &createdAt); err != nil {
+			return nil, err
+		}
+		if createdAt.Valid {
+			p.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+		}
+		credit.TotalPaid += p.Amount
+		credit.Payments = append(credit.Payments, p)
+	}
+
+	var overdue money.Amount
+	err = r.db.QueryRow(
+		"SELECT COALESCE(SUM(amount), 0) FROM kasbon WHERE customer_id = $1 AND due_date < CURRENT_DATE",
+		customerID,
+	).Scan(&overdue)
+	if err != nil {
+		return nil, err
+	}
+
+	credit.Balance = credit.TotalKasbon - credit.TotalPaid
+	credit.OverdueBalance = overdue - credit.TotalPaid
+	if credit.OverdueBalance < 0 {
+		credit.OverdueBalance = 0
+	}
+
+	return credit, nil
+}
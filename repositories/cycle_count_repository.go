@@ -0,0 +1,216 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"kasir-api/models"
+)
+
+var ErrTaskAlreadyCounted = errors.New("cycle count task is already counted")
+
+type CycleCountRepository struct {
+	db *sql.DB
+}
+
+func NewCycleCountRepository(db *sql.DB) *CycleCountRepository {
+	return &CycleCountRepository{db: db}
+}
+
+// GetCandidates retrieves every active product with its ABC class and the days since its last count
+func (r *CycleCountRepository) GetCandidates() ([]models.CycleCountCandidate, error) {
+	rows, err := r.db.Query(`
+		SELECT p.id,
+		       p.abc_class,
+		       CURRENT_DATE - counts.last_counted,
+		       EXISTS (SELECT 1 FROM cycle_count_task t WHERE t.product_id = p.id AND t.status = 'pending')
+		FROM product p
+		LEFT JOIN (
+			SELECT product_id, MAX(counted_at)::date as last_counted
+			FROM cycle_count_task
+			WHERE status = 'counted'
+			GROUP BY product_id
+		) counts ON counts.product_id = p.id
+		WHERE p.deleted_at IS NULL AND NOT p.is_bundle
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := []models.CycleCountCandidate{}
+	for rows.Next() {
+		var c models.CycleCountCandidate
+		var daysSince sql.NullInt64
+		if err := rows.Scan(&c.ProductID, &c.ABCClass, &daysSince, &c.HasOpenTask); err != nil {
+			return nil, err
+		}
+		if daysSince.Valid {
+			days := int(daysSince.Int64)
+			c.DaysSinceCount = &days
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// CreateTasks inserts count tasks for a date, skipping products that already
+// have one that day, and returns the number of tasks created
+func (r *CycleCountRepository) CreateTasks(date string, tasks []models.CycleCountTask) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	created := 0
+	for _, task := range tasks {
+		result, err := tx.Exec(
+			"INSERT INTO cycle_count_task (product_id, abc_class, scheduled_for) VALUES ($1, $2, $3) ON CONFLICT (product_id, scheduled_for) DO NOTHING",
+			task.ProductID, task.ABCClass, date,
+		)
+		if err != nil {
+			return 0, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		created += int(n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return created, nil
+}
+
+const cycleCountTaskQuery = `
+	SELECT t.id, t.product_id, p.name, t.abc_class, t.scheduled_for, t.status, t.expected_qty, t.counted_qty, t.created_at, t.counted_at
+	FROM cycle_count_task t
+	INNER JOIN product p ON t.product_id = p.id
+`
+
+func scanCycleCountTask(row rowScanner) (models.CycleCountTask, error) {
+	var t models.CycleCountTask
+	var scheduledFor, createdAt, countedAt sql.NullTime
+	var expected, counted sql.NullInt64
+	err := row.Scan(&t.ID, &t.ProductID, &t.ProductName, &t.ABCClass, &scheduledFor, &t.Status, &expected, &counted, &createdAt, &countedAt)
+	if err != nil {
+		return models.CycleCountTask{}, err
+	}
+
+	if scheduledFor.Valid {
+		t.ScheduledFor = scheduledFor.Time.Format("2006-01-02")
+	}
+	if expected.Valid && counted.Valid {
+		e, c := int(expected.Int64), int(counted.Int64)
+		v := c - e
+		t.ExpectedQty, t.CountedQty, t.Variance = &e, &c, &v
+	}
+	if createdAt.Valid {
+		t.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	if countedAt.Valid {
+		t.CountedAt = countedAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return t, nil
+}
+
+// GetTasks retrieves the count tasks, optionally filtered by scheduled date and status
+func (r *CycleCountRepository) GetTasks(date, status string) ([]models.CycleCountTask, error) {
+	rows, err := r.db.Query(cycleCountTaskQuery+`
+		WHERE ($1 = '' OR t.scheduled_for = $1::date) AND ($2 = '' OR t.status = $2)
+		ORDER BY t.scheduled_for DESC, t.abc_class, p.name
+	`, date, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []models.CycleCountTask{}
+	for rows.Next() {
+		t, err := scanCycleCountTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// GetTaskByID retrieves a count task by ID
+func (r *CycleCountRepository) GetTaskByID(id int) (models.CycleCountTask, error) {
+	return scanCycleCountTask(r.db.QueryRow(cycleCountTaskQuery+" WHERE t.id = $1", id))
+}
+
+// RecordCount stores the counted quantity against the recorded stock and
+// corrects the product stock to what was counted
+func (r *CycleCountRepository) RecordCount(taskID, countedQty int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var productID int
+	var status string
+	err = tx.QueryRow("SELECT product_id, status FROM cycle_count_task WHERE id = $1 FOR UPDATE", taskID).Scan(&productID, &status)
+	if err != nil {
+		return err
+	}
+	if status != "pending" {
+		return ErrTaskAlreadyCounted
+	}
+
+	var stock int
+	err = tx.QueryRow("SELECT stock FROM product WHERE id = $1 FOR UPDATE", productID).Scan(&stock)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE cycle_count_task SET status = 'counted', expected_qty = $1, counted_qty = $2, counted_at = NOW() WHERE id = $3",
+		stock, countedQty, taskID,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE product SET stock = $1 WHERE id = $2", countedQty, productID)
+	if err != nil {
+		return err
+	}
+	if err := settleBatches(tx, productID); err != nil {
+		return err
+	}
+
+	if countedQty != stock {
+		if err := recordStockMovement(tx, productID, countedQty-stock, MovementCycleCount, taskID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetCountedTasks retrieves completed counts, oldest first, optionally for one product
+func (r *CycleCountRepository) GetCountedTasks(productID int) ([]models.CycleCountTask, error) {
+	rows, err := r.db.Query(cycleCountTaskQuery+`
+		WHERE t.status = 'counted' AND ($1 = 0 OR t.product_id = $1)
+		ORDER BY t.product_id, t.counted_at
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []models.CycleCountTask{}
+	for rows.Next() {
+		t, err := scanCycleCountTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
@@ -0,0 +1,308 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+
+	"kasir-api/models"
+)
+
+// Delivery statuses, in the order a delivery normally goes through them
+const (
+	DeliveryPending   = "pending"
+	DeliveryPacked    = "packed"
+	DeliveryOut       = "out_for_delivery"
+	DeliveryDelivered = "delivered"
+	DeliveryCancelled = "cancelled"
+)
+
+var (
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrCourierNotFound     = errors.New("courier not found")
+	ErrDeliveryExists      = errors.New("transaction is already marked for delivery")
+	// ErrDeliveryChanged is returned when a delivery moved on since it was read
+	ErrDeliveryChanged = errors.New("delivery was changed meanwhile, reload it")
+)
+
+// DeliveryRepository keeps deliveries, their status history and the couriers
+type DeliveryRepository struct {
+	db *sql.DB
+}
+
+func NewDeliveryRepository(db *sql.DB) *DeliveryRepository {
+	return &DeliveryRepository{db: db}
+}
+
+func (r *DeliveryRepository) GetCouriers() ([]models.Courier, error) {
+	rows, err := r.db.Query("SELECT id, name, phone, created_at FROM courier WHERE deleted_at IS NULL ORDER BY name, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	couriers := []models.Courier{}
+	for rows.Next() {
+		var c models.Courier
+		var createdAt time.Time
+		if err := rows.Scan(&c.ID, &c.Name, &c.Phone, &createdAt); err != nil {
+			return nil, err
+		}
+		c.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+		couriers = append(couriers, c)
+	}
+	return couriers, rows.Err()
+}
+
+func (r *DeliveryRepository) CreateCourier(c models.Courier) (models.Courier, error) {
+	var createdAt time.Time
+	err := r.db.QueryRow(
+		"INSERT INTO courier (name, phone) VALUES ($1, $2) RETURNING id, created_at",
+		c.Name, c.Phone,
+	).Scan(&c.ID, &createdAt)
+	if err != nil {
+		return models.Courier{}, err
+	}
+	c.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+	return c, nil
+}
+
+func (r *DeliveryRepository) UpdateCourier(c models.Courier) (models.Courier, error) {
+	var createdAt time.Time
+	err := r.db.QueryRow(
+		"UPDATE courier SET name = $1, phone = $2 WHERE id = $3 AND deleted_at IS NULL RETURNING created_at",
+		c.Name, c.Phone, c.ID,
+	).Scan(&createdAt)
+	if err != nil {
+		return models.Courier{}, err
+	}
+	c.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+	return c, nil
+}
+
+// DeleteCourier soft deletes a courier; deliveries they made keep them
+func (r *DeliveryRepository) DeleteCourier(id int) error {
+	result, err := r.db.Exec("UPDATE courier SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+const deliveryColumns = `d.id, d.transaction_id, COALESCE(t.receipt_number, ''), d.customer_name, d.customer_phone, d.address, d.note,
+	COALESCE(d.courier_id, 0), COALESCE(c.name, ''), COALESCE(c.phone, ''), d.status, d.proof_name, d.proof_type, d.delivered_at, d.created_at`
+
+const deliveryFrom = `
+	FROM delivery d
+	INNER JOIN transactions t ON d.transaction_id = t.id
+	LEFT JOIN courier c ON d.courier_id = c.id
+`
+
+func scanDelivery(row rowScanner) (models.Delivery, error) {
+	var d models.Delivery
+	var deliveredAt sql.NullTime
+	var createdAt time.Time
+	err := row.Scan(&d.ID, &d.TransactionID, &d.ReceiptNumber, &d.CustomerName, &d.CustomerPhone, &d.Address, &d.Note,
+		&d.CourierID, &d.CourierName, &d.CourierPhone, &d.Status, &d.ProofName, &d.ProofType, &deliveredAt, &createdAt)
+	if err != nil {
+		return models.Delivery{}, err
+	}
+	d.HasProof = d.ProofName != ""
+	if deliveredAt.Valid {
+		d.DeliveredAt = deliveredAt.Time.Format("2006-01-02 15:04:05")
+	}
+	d.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+	return d, nil
+}
+
+// GetAll retrieves deliveries, newest first, filtered by status and courier
+// when they are not empty
+func (r *DeliveryRepository) GetAll(status string, courierID int) ([]models.Delivery, error) {
+	rows, err := r.db.Query(`
+		SELECT `+deliveryColumns+deliveryFrom+`
+		WHERE ($1 = '' OR d.status = $1) AND ($2 = 0 OR d.courier_id = $2)
+		ORDER BY d.id DESC
+	`, status, courierID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetByID retrieves a delivery with its status history, oldest first
+func (r *DeliveryRepository) GetByID(id int) (models.Delivery, error) {
+	d, err := scanDelivery(r.db.QueryRow("SELECT "+deliveryColumns+deliveryFrom+" WHERE d.id = $1", id))
+	if err != nil {
+		return models.Delivery{}, err
+	}
+
+	rows, err := r.db.Query("SELECT status, COALESCE(courier_id, 0), created_at FROM delivery_event WHERE delivery_id = $1 ORDER BY id", id)
+	if err != nil {
+		return models.Delivery{}, err
+	}
+	defer rows.Close()
+
+	d.Events = []models.DeliveryEvent{}
+	for rows.Next() {
+		var e models.DeliveryEvent
+		var createdAt time.Time
+		if err := rows.Scan(&e.Status, &e.CourierID, &createdAt); err != nil {
+			return models.Delivery{}, err
+		}
+		e.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+		d.Events = append(d.Events, e)
+	}
+	return d, rows.Err()
+}
+
+// Create marks an active transaction for delivery, pending, with an active
+// courier when courierID is not 0
+func (r *DeliveryRepository) Create(d models.Delivery) (models.Delivery, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.Delivery{}, err
+	}
+	defer tx.Rollback()
+
+	if d.CourierID != 0 {
+		if err := checkCourier(tx, d.CourierID); err != nil {
+			return models.Delivery{}, err
+		}
+	}
+
+	var id int
+	err = tx.QueryRow(`
+		INSERT INTO delivery (transaction_id, customer_name, customer_phone, address, note, courier_id, status)
+		SELECT id, $2, $3, $4, $5, NULLIF($6, 0), $7 FROM transactions WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id
+	`, d.TransactionID, d.CustomerName, d.CustomerPhone, d.Address, d.Note, d.CourierID, DeliveryPending).Scan(&id)
+	if err == sql.ErrNoRows {
+		return models.Delivery{}, ErrTransactionNotFound
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return models.Delivery{}, ErrDeliveryExists
+	}
+	if err != nil {
+		return models.Delivery{}, err
+	}
+
+	if err := recordDeliveryEvent(tx, id); err != nil {
+		return models.Delivery{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Delivery{}, err
+	}
+	return r.GetByID(id)
+}
+
+// AssignCourier gives a delivery still in status to an active courier
+func (r *DeliveryRepository) AssignCourier(id int, status string, courierID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkCourier(tx, courierID); err != nil {
+		return err
+	}
+	if err := updateDelivery(tx, id, status, "courier_id = $3", courierID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateStatus moves a delivery from status from to status to
+func (r *DeliveryRepository) UpdateStatus(id int, from, to string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateDelivery(tx, id, from, "status = $3", to); err != nil {
+		return err
+	}
+	if err := recordDeliveryEvent(tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetDelivered marks a delivery in status from delivered, with its stored
+// photo proof
+func (r *DeliveryRepository) SetDelivered(id int, from, proofName, proofType string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = updateDelivery(tx, id, from, "status = $3, proof_name = $4, proof_type = $5, delivered_at = NOW()", DeliveryDelivered, proofName, proofType)
+	if err != nil {
+		return err
+	}
+	if err := recordDeliveryEvent(tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// updateDelivery sets the columns in set, numbered from $3, of a delivery
+// still in status; ErrDeliveryChanged when it moved on
+func updateDelivery(tx *sql.Tx, id int, status, set string, args ...interface{}) error {
+	result, err := tx.Exec("UPDATE delivery SET "+set+" WHERE id = $1 AND status = $2", append([]interface{}{id, status}, args...)...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrDeliveryChanged
+	}
+	return nil
+}
+
+func checkCourier(tx *sql.Tx, courierID int) error {
+	var active bool
+	err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM courier WHERE id = $1 AND deleted_at IS NULL)", courierID).Scan(&active)
+	if err != nil {
+		return err
+	}
+	if !active {
+		return ErrCourierNotFound
+	}
+	return nil
+}
+
+// recordDeliveryEvent adds the current status and courier of a delivery to its history
+func recordDeliveryEvent(tx *sql.Tx, id int) error {
+	_, err := tx.Exec("INSERT INTO delivery_event (delivery_id, status, courier_id) SELECT id, status, courier_id FROM delivery WHERE id = $1", id)
+	return err
+}
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"database/sql"
+	"strings"
+
+	"kasir-api/models"
+)
+
+type DisplayRepository struct {
+	db *sql.DB
+}
+
+func NewDisplayRepository(db *sql.DB) *DisplayRepository {
+	return &DisplayRepository{db: db}
+}
+
+const displayMediaColumns = `id, title, file_name, content_type, size, duration_seconds, position,
+	COALESCE(to_char(start_time, 'HH24:MI'), ''), COALESCE(to_char(end_time, 'HH24:MI'), ''), active, created_at`
+
+func scanDisplayMedia(row rowScanner) (models.DisplayMedia, error) {
+	var m models.DisplayMedia
+	var createdAt sql.NullTime
+	err := row.Scan(&m.ID, &m.Title, &m.FileName, &m.ContentType, &m.Size, &m.DurationSeconds, &m.Position,
+		&m.StartTime, &m.EndTime, &m.Active, &createdAt)
+	if err != nil {
+		return models.DisplayMedia{}, err
+	}
+
+	m.MediaType = "image"
+	if strings.HasPrefix(m.ContentType, "video/") {
+		m.MediaType = "video"
+	}
+	if createdAt.Valid {
+		m.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return m, nil
+}
+
+// GetAll retrieves the playlist in playing order, inactive items too unless activeOnly
+func (r *DisplayRepository) GetAll(activeOnly bool) ([]models.DisplayMedia, error) {
+	rows, err := r.db.Query("SELECT "+displayMediaColumns+" FROM display_media WHERE active OR NOT $1 ORDER BY position, id", activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	media := []models.DisplayMedia{}
+	for rows.Next() {
+		m, err := scanDisplayMedia(rows)
+		if err != nil {
+			return nil, err
+		}
+		media = append(media, m)
+	}
+	return media, rows.Err()
+}
+
+func (r *DisplayRepository) GetByID(id int) (models.DisplayMedia, error) {
+	return scanDisplayMedia(r.db.QueryRow("SELECT "+displayMediaColumns+" FROM display_media WHERE id = $1", id))
+}
+
+func (r *DisplayRepository) Create(m models.DisplayMedia) (models.DisplayMedia, error) {
+	return scanDisplayMedia(r.db.QueryRow(`
+		INSERT INTO display_media (title, file_name, content_type, size, duration_seconds, position, start_time, end_time, active)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, '')::TIME, NULLIF($8, '')::TIME, $9)
+		RETURNING `+displayMediaColumns,
+		m.Title, m.FileName, m.ContentType, m.Size, m.DurationSeconds, m.Position, m.StartTime, m.EndTime, m.Active,
+	))
+}
+
+// Update changes the title and schedule of an item; the file stays
+func (r *DisplayRepository) Update(id int, req models.DisplayMediaRequest) (models.DisplayMedia, error) {
+	return scanDisplayMedia(r.db.QueryRow(`
+		UPDATE display_media SET title = $1, duration_seconds = $2, position = $3,
+			start_time = NULLIF($4, '')::TIME, end_time = NULLIF($5, '')::TIME, active = $6
+		WHERE id = $7
+		RETURNING `+displayMediaColumns,
+		req.Title, req.DurationSeconds, req.Position, req.StartTime, req.EndTime, req.Active, id,
+	))
+}
+
+// Delete removes an item and returns it, so its file can be removed too
+func (r *DisplayRepository) Delete(id int) (models.DisplayMedia, error) {
+	return scanDisplayMedia(r.db.QueryRow("DELETE FROM display_media WHERE id = $1 RETURNING "+displayMediaColumns, id))
+}
@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"database/sql"
+	"kasir-api/models"
+)
+
+type DunningRepository struct {
+	db *sql.DB
+}
+
+func NewDunningRepository(db *sql.DB) *DunningRepository {
+	return &DunningRepository{db: db}
+}
+
+// GetOverdueCustomers retrieves customers with an overdue kasbon balance whose
+// last reminder (or last payment) is older than cadenceDays. A recorded payment
+// restarts the cadence, and a fully paid balance drops the customer entirely.
+func (r *DunningRepository) GetOverdueCustomers(cadenceDays int) ([]models.OverdueCustomer, error) {
+	query := `
+		SELECT 
+			c.id, c.name, c.phone, c.email, c.reminder_channel,
+			k.overdue_total - COALESCE(p.paid, 0) as overdue_balance,
+			k.oldest_due::text
+		FROM customer c
+		INNER JOIN (
+			SELECT customer_id, SUM(amount) as overdue_total, MIN(due_date) as oldest_due
+			FROM kasbon
+			WHERE due_date < CURRENT_DATE
+			GROUP BY customer_id
+		) k ON k.customer_id = c.id
+		LEFT JOIN (
+			SELECT customer_id, SUM(amount) as paid, MAX(created_at) as last_payment
+			FROM kasbon_payment
+			GROUP BY customer_id
+		) p ON p.customer_id = c.id
+		LEFT JOIN (
+			SELECT customer_id, MAX(sent_at) as last_reminder
+			FROM reminder_history
+			WHERE status = 'sent'
+			GROUP BY customer_id
+		) h ON h.customer_id = c.id
+		WHERE c.deleted_at IS NULL
+			AND k.overdue_total - COALESCE(p.paid, 0) > 0
+			AND (
+				GREATEST(h.last_reminder, p.last_payment) IS NULL
+				OR GREATEST(h.last_reminder, p.last_payment) < NOW() - make_interval(days => $1)
+			)
+		ORDER BY c.id
+	`
+
+	rows, err := r.db.Query(query, cadenceDays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var customers []models.OverdueCustomer
+	for rows.Next() {
+		var oc models.OverdueCustomer
+		c := &oc.Customer
+		if err := rows.Scan(&c.ID, &c.Name, &c.Phone, &c.Email, &c.ReminderChannel, &oc.OverdueBalance, &oc.OldestDueDate); err != nil {
+			return nil, err
+		}
+		customers = append(customers, oc)
+	}
+	return customers, rows.Err()
+}
+
+// GetTemplates retrieves all reminder templates
+func (r *DunningRepository) GetTemplates() ([]models.ReminderTemplate, error) {
+	rows, err := r.db.Query("SELECT channel, body FROM reminder_template ORDER BY channel")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.ReminderTemplate{}
+	for rows.Next() {
+		var t models.ReminderTemplate
+		if err := rows.Scan(&t.Channel, &t.Body); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// GetTemplate retrieves the reminder template for a channel
+func (r *DunningRepository) GetTemplate(channel string) (models.ReminderTemplate, error) {
+	var t models.ReminderTemplate
+	err := r.db.QueryRow("SELECT channel, body FROM reminder_template WHERE channel = $1", channel).Scan(&t.Channel, &t.Body)
+	if err != nil {
+		return models.ReminderTemplate{}, err
+	}
+	return t, nil
+}
+
+// SaveTemplate creates or replaces the reminder template for a channel
+func (r *DunningRepository) SaveTemplate(template models.ReminderTemplate) (models.ReminderTemplate, error) {
+	_, err := r.db.Exec(
+		"INSERT INTO reminder_template (channel, body) VALUES ($1, $2) ON CONFLICT (channel) DO UPDATE SET body = EXCLUDED.body",
+		template.Channel, template.Body,
+	)
+	if err != nil {
+		return models.ReminderTemplate{}, err
+	}
+	return template, nil
+}
+
+// CreateHistory records a reminder attempt
+func (r *DunningRepository) CreateHistory(history models.ReminderHistory) error {
+	_, err := r.db.Exec(
+		"INSERT INTO reminder_history (customer_id, channel, recipient, message, status, error) VALUES ($1, $2, $3, $4, $5, $6)",
+		history.CustomerID, history.Channel, history.Recipient, history.Message, history.Status, history.Error,
+	)
+	return err
+}
+
+// GetHistory retrieves the reminder history of a customer, newest first
+func (r *DunningRepository) GetHistory(customerID int) ([]models.ReminderHistory, error) {
+	rows, err := r.db.Query(
+		"SELECT id, customer_id, channel, recipient, message, status, error, sent_at FROM reminder_history WHERE customer_id = $1 ORDER BY sent_at DESC, id DESC",
+		customerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.ReminderHistory{}
+	for rows.Next() {
+		var h models.ReminderHistory
+		var sentAt sql.NullTime
+		if err := rows.Scan(&h.ID, &h.CustomerID, &h.Channel, &h.Recipient, &h.Message, &h.Status, &h.Error, &sentAt); err != nil {
+			return nil, err
+		}
+		if sentAt.Valid {
+			h.SentAt = sentAt.Time.Format("2006-01-02 15:04:05")
+		}
+		history = append(history, h)
+	}
+	return history, nil
+}
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"database/sql"
+	"kasir-api/models"
+)
+
+type EmailRepository struct {
+	db *sql.DB
+}
+
+func NewEmailRepository(db *sql.DB) *EmailRepository {
+	return &EmailRepository{db: db}
+}
+
+// CreateDelivery records a queued email
+func (r *EmailRepository) CreateDelivery(delivery models.EmailDelivery) (models.EmailDelivery, error) {
+	var createdAt sql.NullTime
+	err := r.db.QueryRow(
+		"INSERT INTO email_delivery (transaction_id, recipient, subject, status) VALUES ($1, $2, $3, 'queued') RETURNING id, status, created_at",
+		delivery.TransactionID, delivery.Recipient, delivery.Subject,
+	).Scan(&delivery.ID, &delivery.Status, &createdAt)
+	if err != nil {
+		return models.EmailDelivery{}, err
+	}
+
+	if createdAt.Valid {
+		delivery.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return delivery, nil
+}
+
+// UpdateStatus records the final outcome of a delivery
+func (r *EmailRepository) UpdateStatus(id int, status string, attempts int, errMsg string) error {
+	_, err := r.db.Exec(
+		"UPDATE email_delivery SET status = $1, attempts = $2, error = $3, sent_at = CASE WHEN $1 = 'sent' THEN NOW() ELSE sent_at END WHERE id = $4",
+		status, attempts, errMsg, id,
+	)
+	return err
+}
+
+// GetByTransactionID retrieves all deliveries of a transaction receipt, newest first
+func (r *EmailRepository) GetByTransactionID(transactionID int) ([]models.EmailDelivery, error) {
+	rows, err := r.db.Query(
+		"SELECT id, transaction_id, recipient, subject, status, attempts, error, created_at, sent_at FROM email_delivery WHERE transaction_id = $1 ORDER BY id DESC",
+		transactionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.EmailDelivery{}
+	for rows.Next() {
+		var d models.EmailDelivery
+		var createdAt, sentAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.TransactionID, &d.Recipient, &d.Subject, &d.Status, &d.Attempts, &d.Error, &createdAt, &sentAt); err != nil {
+			return nil, err
+		}
+		if createdAt.Valid {
+			d.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+		}
+		if sentAt.Valid {
+			d.SentAt = sentAt.Time.Format("2006-01-02 15:04:05")
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
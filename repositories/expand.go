@@ -0,0 +1,39 @@
+package repositories
+
+import "strings"
+
+// ExpandCategory embeds the categories of each product in a product listing
+const ExpandCategory = "category"
+
+// ProductRelations are the relations products can be listed with
+var ProductRelations = []string{ExpandCategory}
+
+// Expansion is the set of relations a listing embeds in each of its rows, as
+// asked for with ?expand=. Each relation is fetched for all rows at once, so
+// a listing takes one query per relation whatever its length.
+type Expansion map[string]bool
+
+// ParseExpansion reads expand, relation names separated by commas, and
+// reports whether each of them is one of relations
+func ParseExpansion(expand string, relations []string) (Expansion, bool) {
+	expansion := Expansion{}
+	if strings.TrimSpace(expand) == "" {
+		return expansion, true
+	}
+
+	for _, name := range strings.Split(expand, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		known := false
+		for _, relation := range relations {
+			if name == relation {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, false
+		}
+		expansion[name] = true
+	}
+	return expansion, true
+}
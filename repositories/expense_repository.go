@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"kasir-api/models"
+)
+
+const expenseColumns = "id, amount, category, description, spent_on, attachment_name, attachment_type, created_at"
+
+type ExpenseRepository struct {
+	db *sql.DB
+}
+
+func NewExpenseRepository(db *sql.DB) *ExpenseRepository {
+	return &ExpenseRepository{db: db}
+}
+
+func scanExpense(row rowScanner) (models.Expense, error) {
+	var e models.Expense
+	var spentOn time.Time
+	var createdAt sql.NullTime
+	err := row.Scan(&e.ID, &e.Amount, &e.Category, &e.Description, &spentOn, &e.AttachmentName, &e.AttachmentType, &createdAt)
+	if err != nil {
+		return e, err
+	}
+
+	e.SpentOn = spentOn.Format("2006-01-02")
+	e.HasAttachment = e.AttachmentName != ""
+	if createdAt.Valid {
+		e.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return e, nil
+}
+
+// GetAll retrieves active expenses spent between the dates, inclusive, oldest first; empty dates are unbounded
+func (r *ExpenseRepository) GetAll(dateFrom, dateTo string) ([]models.Expense, error) {
+	rows, err := r.db.Query(`
+		SELECT `+expenseColumns+` FROM expense
+		WHERE deleted_at IS NULL
+		  AND ($1 = '' OR spent_on >= $1::date)
+		  AND ($2 = '' OR spent_on <= $2::date)
+		ORDER BY spent_on, id
+	`, dateFrom, dateTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	expenses := []models.Expense{}
+	for rows.Next() {
+		e, err := scanExpense(rows)
+		if err != nil {
+			return nil, err
+		}
+		expenses = append(expenses, e)
+	}
+	return expenses, rows.Err()
+}
+
+func (r *ExpenseRepository) GetByID(id int) (models.Expense, error) {
+	return scanExpense(r.db.QueryRow("SELECT "+expenseColumns+" FROM expense WHERE id = $1 AND deleted_at IS NULL", id))
+}
+
+func (r *ExpenseRepository) Create(e models.Expense) (models.Expense, error) {
+	return scanExpense(r.db.QueryRow(
+		"INSERT INTO expense (amount, category, description, spent_on) VALUES ($1, $2, $3, $4) RETURNING "+expenseColumns,
+		e.Amount, e.Category, e.Description, e.SpentOn,
+	))
+}
+
+// SetAttachment points the expense at its stored receipt photo
+func (r *ExpenseRepository) SetAttachment(id int, name, contentType string) error {
+	result, err := r.db.Exec(
+		"UPDATE expense SET attachment_name = $1, attachment_type = $2 WHERE id = $3 AND deleted_at IS NULL",
+		name, contentType, id,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Delete soft deletes an expense
+func (r *ExpenseRepository) Delete(id int) error {
+	result, err := r.db.Exec("UPDATE expense SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"kasir-api/models"
+)
+
+const exportColumns = "id, type, format, date_from, date_to, status, file_name, error, created_at, completed_at"
+
+type ExportRepository struct {
+	db *sql.DB
+}
+
+func NewExportRepository(db *sql.DB) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+func scanExport(row rowScanner) (models.DataExport, error) {
+	var e models.DataExport
+	var dateFrom, dateTo time.Time
+	var createdAt, completedAt sql.NullTime
+	err := row.Scan(&e.ID, &e.Type, &e.Format, &dateFrom, &dateTo, &e.Status, &e.FileName, &e.Error, &createdAt, &completedAt)
+	if err != nil {
+		return e, err
+	}
+
+	e.DateFrom = dateFrom.Format("2006-01-02")
+	e.DateTo = dateTo.Format("2006-01-02")
+	if createdAt.Valid {
+		e.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	if completedAt.Valid {
+		e.CompletedAt = completedAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return e, nil
+}
+
+// Create inserts a pending export
+func (r *ExportRepository) Create(e models.DataExport) (models.DataExport, error) {
+	return scanExport(r.db.QueryRow(
+		"INSERT INTO data_export (type, format, date_from, date_to) VALUES ($1, $2, $3, $4) RETURNING "+exportColumns,
+		e.Type, e.Format, e.DateFrom, e.DateTo,
+	))
+}
+
+func (r *ExportRepository) GetByID(id int) (models.DataExport, error) {
+	return scanExport(r.db.QueryRow("SELECT "+exportColumns+" FROM data_export WHERE id = $1", id))
+}
+
+// GetAll retrieves the most recent exports, newest first
+func (r *ExportRepository) GetAll(limit int) ([]models.DataExport, error) {
+	rows, err := r.db.Query("SELECT "+exportColumns+" FROM data_export ORDER BY id DESC LIMIT $1", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exports := []models.DataExport{}
+	for rows.Next() {
+		e, err := scanExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, e)
+	}
+	return exports, rows.Err()
+}
+
+// UpdateStatus records the outcome of an attempt; done and failed exports are completed
+func (r *ExportRepository) UpdateStatus(id int, status, fileName, errMsg string) error {
+	_, err := r.db.Exec(
+		"UPDATE data_export SET status = $1, file_name = $2, error = $3, completed_at = CASE WHEN $1 IN ('done', 'failed') THEN NOW() ELSE NULL END WHERE id = $4",
+		status, fileName, errMsg, id,
+	)
+	return err
+}
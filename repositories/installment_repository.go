@@ -0,0 +1,259 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"kasir-api/models"
+	"kasir-api/money"
+)
+
+var ErrPaymentExceedsBalance = errors.New("payment exceeds outstanding installment balance")
+
+type InstallmentRepository struct {
+	db *sql.DB
+}
+
+func NewInstallmentRepository(db *sql.DB) *InstallmentRepository {
+	return &InstallmentRepository{db: db}
+}
+
+// GetTransactionTotal retrieves the total amount of an active transaction
+func (r *InstallmentRepository) GetTransactionTotal(transactionID int) (money.Amount, error) {
+	var total money.Amount
+	err := r.db.QueryRow("SELECT total_amount FROM transactions WHERE id = $1 AND deleted_at IS NULL", transactionID).Scan(&total)
+	return total, err
+}
+
+// CreatePlan inserts a plan together with its installment schedule
+func (r *InstallmentRepository) CreatePlan(plan models.InstallmentPlan) (models.InstallmentPlan, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.InstallmentPlan{}, err
+	}
+	defer tx.Rollback()
+
+	var createdAt sql.NullTime
+	err = tx.QueryRow(
+		"INSERT INTO installment_plan (transaction_id, customer_id, total_amount, down_payment, installment_count) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at",
+		plan.TransactionID, plan.CustomerID, plan.TotalAmount, plan.DownPayment, plan.InstallmentCount,
+	).Scan(&plan.ID, &createdAt)
+	if err != nil {
+		return models.InstallmentPlan{}, err
+	}
+
+	for i := range plan.Installments {
+		inst := &plan.Installments[i]
+		inst.PlanID = plan.ID
+		err = tx.QueryRow(
+			"INSERT INTO installment (plan_id, sequence, due_date, amount) VALUES ($1, $2, $3, $4) RETURNING id",
+			inst.PlanID, inst.Sequence, inst.DueDate, inst.Amount,
+		).Scan(&inst.ID)
+		if err != nil {
+			return models.InstallmentPlan{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.InstallmentPlan{}, err
+	}
+
+	if createdAt.Valid {
+		plan.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return plan, nil
+}
+
+// GetAll retrieves all plans, optionally filtered by customer
+func (r *InstallmentRepository) GetAll(customerID int) ([]models.InstallmentPlan, error) {
+	args := []interface{}{}
+	query := `
+		SELECT p.id, p.transaction_id, p.customer_id, p.total_amount, p.down_payment, p.installment_count, p.created_at,
+		       COALESCE(SUM(i.paid_amount), 0), ` + planStatusColumn + `
+		FROM installment_plan p
+		LEFT JOIN installment i ON i.plan_id = p.id
+	`
+	if customerID != 0 {
+		query += " WHERE p.customer_id = $1"
+		args = append(args, customerID)
+	}
+	query += " GROUP BY p.id ORDER BY p.id DESC"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := []models.InstallmentPlan{}
+	for rows.Next() {
+		p, err := scanPlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, p)
+	}
+	return plans, rows.Err()
+}
+
+// GetByID retrieves a plan with its installment schedule
+func (r *InstallmentRepository) GetByID(id int) (models.InstallmentPlan, error) {
+	row := r.db.QueryRow(`
+		SELECT p.id, p.transaction_id, p.customer_id, p.total_amount, p.down_payment, p.installment_count, p.created_at,
+		       COALESCE(SUM(i.paid_amount), 0), `+planStatusColumn+`
+		FROM installment_plan p
+		LEFT JOIN installment i ON i.plan_id = p.id
+		WHERE p.id = $1
+		GROUP BY p.id
+	`, id)
+
+	plan, err := scanPlan(row)
+	if err != nil {
+		return models.InstallmentPlan{}, err
+	}
+
+	installments, err := r.getInstallments(id)
+	if err != nil {
+		return models.InstallmentPlan{}, err
+	}
+	plan.Installments = installments
+
+	return plan, nil
+}
+
+func (r *InstallmentRepository) getInstallments(planID int) ([]models.Installment, error) {
+	rows, err := r.db.Query(
+		"SELECT id, plan_id, sequence, due_date::text, amount, paid_amount, paid_at FROM installment WHERE plan_id = $1 ORDER BY sequence",
+		planID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var installments []models.Installment
+	for rows.Next() {
+		var inst models.Installment
+		var paidAt sql.NullTime
+		if err := rows.Scan(&inst.ID, &inst.PlanID, &inst.Sequence, &inst.DueDate, &inst.Amount, &inst.PaidAmount, &paidAt); err != nil {
+			return nil, err
+		}
+		if paidAt.Valid {
+			inst.PaidAt = paidAt.Time.Format("2006-01-02 15:04:05")
+		}
+		installments = append(installments, inst)
+	}
+	return installments, rows.Err()
+}
+
+// planStatusColumn derives the plan status from its installments, matching the
+// per-installment rules applied by the service
+const planStatusColumn = `
+		CASE
+			WHEN COUNT(*) FILTER (WHERE i.paid_amount < i.amount AND i.due_date < CURRENT_DATE) > 0 THEN 'overdue'
+			WHEN COUNT(*) FILTER (WHERE i.paid_amount < i.amount) > 0 THEN 'active'
+			ELSE 'paid'
+		END`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPlan(row rowScanner) (models.InstallmentPlan, error) {
+	var p models.InstallmentPlan
+	var customerID sql.NullInt64
+	var createdAt sql.NullTime
+	err := row.Scan(&p.ID, &p.TransactionID, &customerID, &p.TotalAmount, &p.DownPayment, &p.InstallmentCount, &createdAt, &p.PaidAmount, &p.Status)
+	if err != nil {
+		return models.InstallmentPlan{}, err
+	}
+
+	if customerID.Valid {
+		id := int(customerID.Int64)
+		p.CustomerID = &id
+	}
+	if createdAt.Valid {
+		p.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	p.Outstanding = p.TotalAmount - p.DownPayment - p.PaidAmount
+	return p, nil
+}
+
+// RecordPayment applies a payment to the earliest unpaid installments of a plan
+func (r *InstallmentRepository) RecordPayment(planID int, amount money.Amount) ([]models.InstallmentPayment, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(
+		"SELECT id, amount - paid_amount FROM installment WHERE plan_id = $1 AND paid_amount < amount ORDER BY sequence FOR UPDATE",
+		planID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	type unpaid struct {
+		id        int
+		remaining money.Amount
+	}
+	var pending []unpaid
+	for rows.Next() {
+		var u unpaid
+		if err := rows.Scan(&u.id, &u.remaining); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		pending = append(pending, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	payments := []models.InstallmentPayment{}
+	left := amount
+	for _, u := range pending {
+		if left == 0 {
+			break
+		}
+		applied := u.remaining
+		if left < applied {
+			applied = left
+		}
+
+		_, err := tx.Exec(
+			"UPDATE installment SET paid_amount = paid_amount + $1, paid_at = CASE WHEN paid_amount + $1 >= amount THEN NOW() ELSE paid_at END WHERE id = $2",
+			applied, u.id,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		payment := models.InstallmentPayment{PlanID: planID, InstallmentID: u.id, Amount: applied}
+		var createdAt sql.NullTime
+		err = tx.QueryRow(
+			"INSERT INTO installment_payment (plan_id, installment_id, amount) VALUES ($1, $2, $3) RETURNING id, created_at",
+			planID, u.id, applied,
+		).Scan(&payment.ID, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+		if createdAt.Valid {
+			payment.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+		}
+
+		payments = append(payments, payment)
+		left -= applied
+	}
+
+	if left > 0 {
+		return nil, ErrPaymentExceedsBalance
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"kasir-api/models"
+	"time"
+)
+
+var ErrJobNotFailed = errors.New("only failed jobs can be retried")
+
+type JobRepository struct {
+	db *sql.DB
+}
+
+func NewJobRepository(db *sql.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+const jobColumns = "id, type, payload, status, attempts, max_attempts, last_error, run_at, created_at, finished_at"
+
+func scanJob(row rowScanner) (models.Job, error) {
+	var j models.Job
+	var payload []byte
+	var runAt, createdAt, finishedAt sql.NullTime
+	if err := row.Scan(&j.ID, &j.Type, &payload, &j.Status, &j.Attempts, &j.MaxAttempts, &j.LastError, &runAt, &createdAt, &finishedAt); err != nil {
+		return models.Job{}, err
+	}
+
+	j.Payload = payload
+	if runAt.Valid {
+		j.RunAt = runAt.Time.Format("2006-01-02 15:04:05")
+	}
+	if createdAt.Valid {
+		j.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	if finishedAt.Valid {
+		j.FinishedAt = finishedAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return j, nil
+}
+
+// Create inserts a pending job due at runAt, now when zero. A non-empty
+// uniqueKey makes the insert a no-op when a job with the same key exists,
+// which is reported as sql.ErrNoRows.
+func (r *JobRepository) Create(jobType string, payload []byte, maxAttempts int, uniqueKey string, runAt time.Time) (models.Job, error) {
+	row := r.db.QueryRow(`
+		INSERT INTO job (type, payload, max_attempts, unique_key, run_at) VALUES ($1, $2, $3, NULLIF($4, ''), COALESCE($5, NOW()))
+		ON CONFLICT (unique_key) DO NOTHING
+		RETURNING `+jobColumns,
+		jobType, payload, maxAttempts, uniqueKey, sql.NullTime{Time: runAt, Valid: !runAt.IsZero()},
+	)
+	return scanJob(row)
+}
+
+// Claim locks the next due pending job for this worker and counts the attempt
+func (r *JobRepository) Claim() (models.Job, error) {
+	row := r.db.QueryRow(`
+		UPDATE job SET status = 'running', attempts = attempts + 1, locked_at = NOW()
+		WHERE id = (
+			SELECT id FROM job
+			WHERE status = 'pending' AND run_at <= NOW()
+			ORDER BY run_at, id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING ` + jobColumns)
+	return scanJob(row)
+}
+
+// Complete marks a job as done
+func (r *JobRepository) Complete(id int) error {
+	_, err := r.db.Exec("UPDATE job SET status = 'done', last_error = '', locked_at = NULL, finished_at = NOW() WHERE id = $1", id)
+	return err
+}
+
+// Reschedule puts a failed attempt back in the queue to run again at runAt
+func (r *JobRepository) Reschedule(id int, errMsg string, runAt time.Time) error {
+	_, err := r.db.Exec("UPDATE job SET status = 'pending', last_error = $1, run_at = $2, locked_at = NULL WHERE id = $3", errMsg, runAt, id)
+	return err
+}
+
+// Fail marks a job as failed after its last attempt
+func (r *JobRepository) Fail(id int, errMsg string) error {
+	_, err := r.db.Exec("UPDATE job SET status = 'failed', last_error = $1, locked_at = NULL, finished_at = NOW() WHERE id = $2", errMsg, id)
+	return err
+}
+
+// ReleaseStale returns jobs left running by a crashed worker to the queue
+func (r *JobRepository) ReleaseStale(lockedBefore time.Time) (int64, error) {
+	result, err := r.db.Exec("UPDATE job SET status = 'pending', locked_at = NULL WHERE status = 'running' AND locked_at < $1", lockedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetAll retrieves the most recent jobs, optionally filtered by status
+func (r *JobRepository) GetAll(status string, limit int) ([]models.Job, error) {
+	rows, err := r.db.Query(
+		"SELECT "+jobColumns+" FROM job WHERE ($1 = '' OR status = $1) ORDER BY id DESC LIMIT $2",
+		status, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []models.Job{}
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+// GetByID retrieves a job by ID
+func (r *JobRepository) GetByID(id int) (models.Job, error) {
+	return scanJob(r.db.QueryRow("SELECT "+jobColumns+" FROM job WHERE id = $1", id))
+}
+
+// Retry queues a failed job again with a fresh set of attempts
+func (r *JobRepository) Retry(id int) (models.Job, error) {
+	job, err := scanJob(r.db.QueryRow(`
+		UPDATE job SET status = 'pending', attempts = 0, last_error = '', run_at = NOW(), finished_at = NULL
+		WHERE id = $1 AND status = 'failed'
+		RETURNING `+jobColumns, id))
+	if err == sql.ErrNoRows {
+		if _, getErr := r.GetByID(id); getErr != nil {
+			return models.Job{}, getErr
+		}
+		return models.Job{}, ErrJobNotFailed
+	}
+	return job, err
+}
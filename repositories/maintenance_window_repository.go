@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"kasir-api/models"
+
+	"github.com/lib/pq"
+)
+
+type MaintenanceWindowRepository struct {
+	db *sql.DB
+}
+
+func NewMaintenanceWindowRepository(db *sql.DB) *MaintenanceWindowRepository {
+	return &MaintenanceWindowRepository{db: db}
+}
+
+const maintenanceWindowColumns = "id, name, days, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI'), created_at"
+
+func scanMaintenanceWindow(row rowScanner) (models.MaintenanceWindow, error) {
+	var mw models.MaintenanceWindow
+	var days pq.Int64Array
+	var createdAt sql.NullTime
+	if err := row.Scan(&mw.ID, &mw.Name, &days, &mw.Start, &mw.End, &createdAt); err != nil {
+		return models.MaintenanceWindow{}, err
+	}
+
+	mw.Days = make([]int, len(days))
+	for i, d := range days {
+		mw.Days[i] = int(d)
+	}
+	if createdAt.Valid {
+		mw.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return mw, nil
+}
+
+func (r *MaintenanceWindowRepository) GetAll() ([]models.MaintenanceWindow, error) {
+	rows, err := r.db.Query("SELECT " + maintenanceWindowColumns + " FROM maintenance_window ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	windows := []models.MaintenanceWindow{}
+	for rows.Next() {
+		mw, err := scanMaintenanceWindow(rows)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, mw)
+	}
+	return windows, rows.Err()
+}
+
+func (r *MaintenanceWindowRepository) Create(req models.MaintenanceWindowRequest) (models.MaintenanceWindow, error) {
+	days := make(pq.Int64Array, len(req.Days))
+	for i, d := range req.Days {
+		days[i] = int64(d)
+	}
+
+	row := r.db.QueryRow(
+		"INSERT INTO maintenance_window (name, days, start_time, end_time) VALUES ($1, $2, $3, $4) RETURNING "+maintenanceWindowColumns,
+		req.Name, days, req.Start, req.End,
+	)
+	return scanMaintenanceWindow(row)
+}
+
+func (r *MaintenanceWindowRepository) Delete(id int) error {
+	result, err := r.db.Exec("DELETE FROM maintenance_window WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"kasir-api/models"
+)
+
+type MobileRepository struct {
+	db       *sql.DB
+	settings SettingsReader
+}
+
+func NewMobileRepository(db *sql.DB, settings SettingsReader) *MobileRepository {
+	return &MobileRepository{db: db, settings: settings}
+}
+
+// GetSummary retrieves the numbers of the store's day in a single round trip;
+// revenue and gross profit are of the sales paid or completed
+func (r *MobileRepository) GetSummary() (*models.MobileSummary, error) {
+	s := &models.MobileSummary{}
+	err := r.db.QueryRow(`
+		WITH today AS (
+			SELECT (NOW() AT TIME ZONE $1)::date AS date, ((NOW() AT TIME ZONE $1)::date::timestamp AT TIME ZONE $1) AS start
+		)
+		SELECT
+			today.date::text,
+			COALESCE((SELECT SUM(t.total_amount) FROM transactions t WHERE t.created_at >= today.start AND t.deleted_at IS NULL AND `+settled("t")+`), 0),
+			(SELECT COUNT(*) FROM transactions t WHERE t.created_at >= today.start AND t.deleted_at IS NULL AND `+settled("t")+`),
+			COALESCE((
+				SELECT SUM(td.subtotal - td.cost_price * td.quantity)
+				FROM transaction_details td
+				INNER JOIN transactions t ON td.transaction_id = t.id
+				WHERE t.created_at >= today.start AND t.deleted_at IS NULL AND `+settled("t")+`
+			), 0),
+			COALESCE((SELECT SUM(amount) FROM expense WHERE spent_on = today.date AND deleted_at IS NULL), 0),
+			(SELECT COUNT(*) FROM product WHERE reorder_point > 0 AND stock <= reorder_point AND NOT is_bundle AND deleted_at IS NULL),
+			COALESCE((SELECT SUM(amount - paid_amount) FROM installment WHERE paid_amount < amount AND due_date < today.date), 0),
+			(SELECT COUNT(*) FROM job WHERE status = 'failed')
+		FROM today
+	`, r.settings.Settings().Timezone).Scan(&s.Date, &s.Revenue, &s.Transactions, &s.GrossProfit, &s.Expenses, &s.LowStock, &s.Overdue, &s.FailedJobs)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetLowStockNames retrieves the names of the products furthest below their reorder point
+func (r *MobileRepository) GetLowStockNames(limit int) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT name FROM product
+		WHERE reorder_point > 0 AND stock <= reorder_point AND NOT is_bundle AND deleted_at IS NULL
+		ORDER BY abc_class, stock - reorder_point, name
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
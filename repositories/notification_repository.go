@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"kasir-api/models"
+
+	"github.com/lib/pq"
+)
+
+const notificationChannelColumns = "id, provider, recipient, events, active, created_at"
+
+type NotificationRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+func scanNotificationChannel(row rowScanner) (models.NotificationChannel, error) {
+	var c models.NotificationChannel
+	var createdAt sql.NullTime
+	err := row.Scan(&c.ID, &c.Provider, &c.Recipient, pq.Array(&c.Events), &c.Active, &createdAt)
+	if err != nil {
+		return c, err
+	}
+	if createdAt.Valid {
+		c.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return c, nil
+}
+
+func (r *NotificationRepository) queryChannels(query string, args ...interface{}) ([]models.NotificationChannel, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	channels := []models.NotificationChannel{}
+	for rows.Next() {
+		c, err := scanNotificationChannel(rows)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, c)
+	}
+	return channels, rows.Err()
+}
+
+func (r *NotificationRepository) GetAll() ([]models.NotificationChannel, error) {
+	return r.queryChannels("SELECT " + notificationChannelColumns + " FROM notification_channel ORDER BY id")
+}
+
+// GetActiveByEvent retrieves the active channels subscribed to an event
+func (r *NotificationRepository) GetActiveByEvent(event string) ([]models.NotificationChannel, error) {
+	return r.queryChannels("SELECT "+notificationChannelColumns+" FROM notification_channel WHERE active AND $1 = ANY(events) ORDER BY id", event)
+}
+
+func (r *NotificationRepository) GetByID(id int) (models.NotificationChannel, error) {
+	return scanNotificationChannel(r.db.QueryRow("SELECT "+notificationChannelColumns+" FROM notification_channel WHERE id = $1", id))
+}
+
+// Save stores a channel; adding a chat again replaces its events and
+// activates it
+func (r *NotificationRepository) Save(c models.NotificationChannel) (models.NotificationChannel, error) {
+	return scanNotificationChannel(r.db.QueryRow(`
+		INSERT INTO notification_channel (provider, recipient, events, active) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (provider, recipient) DO UPDATE SET events = EXCLUDED.events, active = EXCLUDED.active
+		RETURNING `+notificationChannelColumns,
+		c.Provider, c.Recipient, pq.Array(c.Events), c.Active))
+}
+
+// Update changes the events of a channel and whether it is active
+func (r *NotificationRepository) Update(c models.NotificationChannel) (models.NotificationChannel, error) {
+	return scanNotificationChannel(r.db.QueryRow(
+		"UPDATE notification_channel SET events = $2, active = $3 WHERE id = $1 RETURNING "+notificationChannelColumns,
+		c.ID, pq.Array(c.Events), c.Active))
+}
+
+// Delete removes a channel, returning sql.ErrNoRows if there is none
+func (r *NotificationRepository) Delete(id int) error {
+	result, err := r.db.Exec("DELETE FROM notification_channel WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
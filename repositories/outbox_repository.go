@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"kasir-api/models"
+
+	"github.com/lib/pq"
+)
+
+// OutboxRepository keeps the domain events of business writes until they are
+// relayed
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Record writes event to the outbox in tx, so it is kept exactly when the
+// write it announces is. A nil outbox records nothing, as in kiosk installs.
+func (r *OutboxRepository) Record(tx *sql.Tx, event string, payload interface{}) error {
+	if r == nil {
+		return nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO outbox (event, payload) VALUES ($1, $2)", event, data)
+	return err
+}
+
+// Relay hands up to limit undelivered events, oldest first, to deliver and
+// marks them delivered, returning how many there were. Events another
+// server is relaying are skipped; ones handed out before a failure to mark
+// them are handed out again.
+func (r *OutboxRepository) Relay(limit int, deliver func(models.OutboxEvent)) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT id, event, payload, created_at FROM outbox
+		WHERE delivered_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return 0, err
+	}
+	pending := []models.OutboxEvent{}
+	for rows.Next() {
+		var e models.OutboxEvent
+		if err := rows.Scan(&e.ID, &e.Event, &e.Payload, &e.CreatedAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]int64, len(pending))
+	for i, e := range pending {
+		deliver(e)
+		ids[i] = e.ID
+	}
+	if _, err := tx.Exec("UPDATE outbox SET delivered_at = NOW() WHERE id = ANY($1)", pq.Array(ids)); err != nil {
+		return 0, err
+	}
+	return len(pending), tx.Commit()
+}
+
+// Prune deletes the events delivered before cutoff
+func (r *OutboxRepository) Prune(cutoff time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM outbox WHERE delivered_at < $1", cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
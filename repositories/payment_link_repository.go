@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"kasir-api/events"
+	"kasir-api/models"
+	"kasir-api/money"
+)
+
+// Payment link statuses; a pending link past its expiry reads as expired, a
+// link paid less than its amount is underpaid
+const (
+	PaymentLinkPending   = "pending"
+	PaymentLinkPaid      = "paid"
+	PaymentLinkUnderpaid = "underpaid"
+	PaymentLinkExpired   = "expired"
+)
+
+var ErrPaymentLinkAlreadyPaid = errors.New("payment link is already paid")
+
+type PaymentLinkRepository struct {
+	db     *sql.DB
+	outbox *OutboxRepository
+}
+
+func NewPaymentLinkRepository(db *sql.DB) *PaymentLinkRepository {
+	return &PaymentLinkRepository{db: db}
+}
+
+// WithOutbox has the links r settles announced through outbox, in their own
+// database transactions; without it they announce nothing
+func (r *PaymentLinkRepository) WithOutbox(outbox *OutboxRepository) *PaymentLinkRepository {
+	r.outbox = outbox
+	return r
+}
+
+const paymentLinkColumns = `id, reference, COALESCE(transaction_id, 0), customer_name, customer_phone, note, amount, url,
+	CASE WHEN status = 'pending' AND expires_at < NOW() THEN 'expired' ELSE status END,
+	paid_amount, expires_at, paid_at, created_at`
+
+func scanPaymentLink(row rowScanner) (models.PaymentLink, error) {
+	var link models.PaymentLink
+	var expiresAt time.Time
+	var paidAt, createdAt sql.NullTime
+	err := row.Scan(&link.ID, &link.Reference, &link.TransactionID, &link.CustomerName, &link.CustomerPhone, &link.Note, &link.Amount, &link.URL,
+		&link.Status, &link.PaidAmount, &expiresAt, &paidAt, &createdAt)
+	if err != nil {
+		return models.PaymentLink{}, err
+	}
+
+	link.ExpiresAt = expiresAt.Format("2006-01-02 15:04:05")
+	if paidAt.Valid {
+		link.PaidAt = paidAt.Time.Format("2006-01-02 15:04:05")
+	}
+	if createdAt.Valid {
+		link.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return link, nil
+}
+
+// GetAll retrieves the payment links, newest first, optionally only those with status
+func (r *PaymentLinkRepository) GetAll(status string) ([]models.PaymentLink, error) {
+	rows, err := r.db.Query(`
+		SELECT `+paymentLinkColumns+` FROM payment_link
+		WHERE $1 = '' OR CASE WHEN status = 'pending' AND expires_at < NOW() THEN 'expired' ELSE status END = $1
+		ORDER BY created_at DESC, id DESC
+	`, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []models.PaymentLink{}
+	for rows.Next() {
+		link, err := scanPaymentLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+func (r *PaymentLinkRepository) GetByID(id int) (models.PaymentLink, error) {
+	return scanPaymentLink(r.db.QueryRow("SELECT "+paymentLinkColumns+" FROM payment_link WHERE id = $1", id))
+}
+
+// HasOpen reports whether a pending link, not yet expired, is out for the
+// transaction, so it isn't paid twice
+func (r *PaymentLinkRepository) HasOpen(transactionID int) (bool, error) {
+	var open bool
+	err := r.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM payment_link WHERE transaction_id = $1 AND status = 'pending' AND expires_at >= NOW())",
+		transactionID,
+	).Scan(&open)
+	return open, err
+}
+
+// Create saves a pending payment link, before the gateway is asked for its URL
+func (r *PaymentLinkRepository) Create(link models.PaymentLink, expiresAt time.Time) (models.PaymentLink, error) {
+	return scanPaymentLink(r.db.QueryRow(`
+		INSERT INTO payment_link (reference, transaction_id, customer_name, customer_phone, note, amount, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+paymentLinkColumns,
+		link.Reference, link.TransactionID, link.CustomerName, link.CustomerPhone, link.Note, link.Amount, expiresAt,
+	))
+}
+
+func (r *PaymentLinkRepository) SetURL(id int, url string) error {
+	_, err := r.db.Exec("UPDATE payment_link SET url = $1 WHERE id = $2", url, id)
+	return err
+}
+
+// Delete removes a link the gateway never created
+func (r *PaymentLinkRepository) Delete(id int) error {
+	_, err := r.db.Exec("DELETE FROM payment_link WHERE id = $1", id)
+	return err
+}
+
+// MarkPaid records the payment of the link with reference, underpaid when
+// amount is less than the link's. It is done once: a link already paid
+// returns ErrPaymentLinkAlreadyPaid, so a repeated callback doesn't mark the
+// sale paid twice. An expired link can still be paid, the gateway may settle
+// late.
+func (r *PaymentLinkRepository) MarkPaid(reference string, amount money.Amount) (models.PaymentLink, error) {
+	link, err := scanPaymentLink(r.db.QueryRow(`
+		UPDATE payment_link SET status = CASE WHEN $2 < amount THEN 'underpaid' ELSE 'paid' END, paid_amount = $2, paid_at = NOW()
+		WHERE reference = $1 AND status NOT IN ('paid', 'underpaid')
+		RETURNING `+paymentLinkColumns,
+		reference, amount,
+	))
+	if err != sql.ErrNoRows {
+		return link, err
+	}
+
+	var exists bool
+	if err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM payment_link WHERE reference = $1)", reference).Scan(&exists); err != nil {
+		return models.PaymentLink{}, err
+	}
+	if exists {
+		return models.PaymentLink{}, ErrPaymentLinkAlreadyPaid
+	}
+	return models.PaymentLink{}, sql.ErrNoRows
+}
+
+// MarkExpired closes the pending link with reference; a paid or underpaid
+// link stays so
+func (r *PaymentLinkRepository) MarkExpired(reference string) (models.PaymentLink, error) {
+	link, err := scanPaymentLink(r.db.QueryRow(`
+		UPDATE payment_link SET status = 'expired'
+		WHERE reference = $1 AND status = 'pending'
+		RETURNING `+paymentLinkColumns,
+		reference,
+	))
+	if err != sql.ErrNoRows {
+		return link, err
+	}
+	return scanPaymentLink(r.db.QueryRow("SELECT "+paymentLinkColumns+" FROM payment_link WHERE reference = $1", reference))
+}
+
+// AnnouncePaid announces a link paid, once its transaction is marked paid
+func (r *PaymentLinkRepository) AnnouncePaid(link models.PaymentLink) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.outbox.Record(tx, events.PaymentLinkPaid, link); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,183 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"kasir-api/models"
+)
+
+const (
+	PeriodClose  = "close"
+	PeriodReopen = "reopen"
+)
+
+var (
+	// ErrPeriodClosed is returned, wrapped with the month, for a change dated
+	// in a closed accounting period
+	ErrPeriodClosed        = errors.New("accounting period is closed, reopen it first")
+	ErrPeriodAlreadyClosed = errors.New("accounting period is already closed")
+	ErrPeriodNotClosed     = errors.New("accounting period is not closed")
+)
+
+// closedPeriodError names the closed month of at in ErrPeriodClosed
+func closedPeriodError(at time.Time) error {
+	return fmt.Errorf("%w: %s", ErrPeriodClosed, at.Format("2006-01"))
+}
+
+// checkPeriodOpen returns ErrPeriodClosed when the month of at is a closed
+// accounting period, for a change to a sale made then
+func checkPeriodOpen(tx *sql.Tx, at time.Time) error {
+	var closed bool
+	err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM accounting_period WHERE month = date_trunc('month', $1::timestamptz)::date)", at).Scan(&closed)
+	if err != nil {
+		return err
+	}
+	if closed {
+		return closedPeriodError(at)
+	}
+	return nil
+}
+
+// PeriodRepository keeps the closed accounting months. A month is closed while
+// it has a row; every close and reopen is recorded in the audit trail.
+type PeriodRepository struct {
+	db *sql.DB
+}
+
+func NewPeriodRepository(db *sql.DB) *PeriodRepository {
+	return &PeriodRepository{db: db}
+}
+
+// GetAll returns the closed months, newest first
+func (r *PeriodRepository) GetAll() ([]models.AccountingPeriod, error) {
+	rows, err := r.db.Query("SELECT month, note, closed_at FROM accounting_period ORDER BY month DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	periods := []models.AccountingPeriod{}
+	for rows.Next() {
+		var p models.AccountingPeriod
+		var month, closedAt time.Time
+		if err := rows.Scan(&month, &p.Note, &closedAt); err != nil {
+			return nil, err
+		}
+		p.Month = month.Format("2006-01")
+		p.ClosedAt = closedAt.Format("2006-01-02 15:04:05")
+		periods = append(periods, p)
+	}
+	return periods, rows.Err()
+}
+
+// CheckOpen returns ErrPeriodClosed when the month of at is closed
+func (r *PeriodRepository) CheckOpen(at time.Time) error {
+	var closed bool
+	err := r.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM accounting_period WHERE month = $1::date)",
+		at.Format("2006-01")+"-01",
+	).Scan(&closed)
+	if err != nil {
+		return err
+	}
+	if closed {
+		return closedPeriodError(at)
+	}
+	return nil
+}
+
+// Close closes the month starting at month and records it in the audit trail
+func (r *PeriodRepository) Close(month time.Time, note string) (models.AccountingPeriod, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.AccountingPeriod{}, err
+	}
+	defer tx.Rollback()
+
+	var closedAt time.Time
+	err = tx.QueryRow(`
+		INSERT INTO accounting_period (month, note) VALUES ($1::date, $2)
+		ON CONFLICT (month) DO NOTHING
+		RETURNING closed_at
+	`, month.Format("2006-01-02"), note).Scan(&closedAt)
+	if err == sql.ErrNoRows {
+		return models.AccountingPeriod{}, ErrPeriodAlreadyClosed
+	}
+	if err != nil {
+		return models.AccountingPeriod{}, err
+	}
+
+	if err := recordPeriodEvent(tx, month, PeriodClose, note); err != nil {
+		return models.AccountingPeriod{}, err
+	}
+
+	return models.AccountingPeriod{
+		Month:    month.Format("2006-01"),
+		Note:     note,
+		ClosedAt: closedAt.Format("2006-01-02 15:04:05"),
+	}, tx.Commit()
+}
+
+// Reopen reopens the month starting at month and records why in the audit trail
+func (r *PeriodRepository) Reopen(month time.Time, reason string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM accounting_period WHERE month = $1::date", month.Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrPeriodNotClosed
+	}
+
+	if err := recordPeriodEvent(tx, month, PeriodReopen, reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetEvents returns the latest limit closes and reopens, newest first
+func (r *PeriodRepository) GetEvents(limit int) ([]models.PeriodEvent, error) {
+	rows, err := r.db.Query(`
+		SELECT id, month, action, reason, created_at
+		FROM accounting_period_event
+		ORDER BY id DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.PeriodEvent{}
+	for rows.Next() {
+		var e models.PeriodEvent
+		var month, createdAt time.Time
+		if err := rows.Scan(&e.ID, &month, &e.Action, &e.Reason, &createdAt); err != nil {
+			return nil, err
+		}
+		e.Month = month.Format("2006-01")
+		e.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func recordPeriodEvent(tx *sql.Tx, month time.Time, action, reason string) error {
+	_, err := tx.Exec(
+		"INSERT INTO accounting_period_event (month, action, reason) VALUES ($1::date, $2, $3)",
+		month.Format("2006-01-02"), action, reason,
+	)
+	return err
+}
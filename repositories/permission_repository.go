@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"kasir-api/models"
+
+	"github.com/lib/pq"
+)
+
+var ErrPermissionNotFound = errors.New("permission not found")
+
+// PermissionRepository keeps the permissions there are and the roles granted them
+type PermissionRepository struct {
+	db *sql.DB
+}
+
+func NewPermissionRepository(db *sql.DB) *PermissionRepository {
+	return &PermissionRepository{db: db}
+}
+
+func (r *PermissionRepository) GetPermissions() ([]models.Permission, error) {
+	rows, err := r.db.Query("SELECT name, description FROM permission ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []models.Permission{}
+	for rows.Next() {
+		var p models.Permission
+		if err := rows.Scan(&p.Name, &p.Description); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+	return permissions, rows.Err()
+}
+
+// GetRoles retrieves every role with the permissions granted to it
+func (r *PermissionRepository) GetRoles() ([]models.Role, error) {
+	rows, err := r.db.Query(`
+		SELECT r.name, r.description, COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+		FROM role r
+		LEFT JOIN role_permission rp ON rp.role = r.name
+		GROUP BY r.name, r.description
+		ORDER BY r.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role.Name, &role.Description, pq.Array(&role.Permissions)); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// SaveRole creates a role or replaces its description and permissions
+func (r *PermissionRepository) SaveRole(role models.Role) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		"INSERT INTO role (name, description) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description",
+		role.Name, role.Description,
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM role_permission WHERE role = $1", role.Name); err != nil {
+		return err
+	}
+	for _, permission := range role.Permissions {
+		_, err := tx.Exec("INSERT INTO role_permission (role, permission) VALUES ($1, $2) ON CONFLICT DO NOTHING", role.Name, permission)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return fmt.Errorf("%w: %s", ErrPermissionNotFound, permission)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,273 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"kasir-api/models"
+
+	"github.com/lib/pq"
+)
+
+const priceContractColumns = "c.id, c.customer_id, cu.name, c.name, c.valid_from, c.valid_until, c.created_at"
+
+// PriceContractRepository keeps the price lists negotiated with wholesale customers
+type PriceContractRepository struct {
+	db *sql.DB
+}
+
+func NewPriceContractRepository(db *sql.DB) *PriceContractRepository {
+	return &PriceContractRepository{db: db}
+}
+
+func scanPriceContract(row rowScanner) (models.PriceContract, error) {
+	var c models.PriceContract
+	var validFrom, createdAt time.Time
+	var validUntil sql.NullTime
+	if err := row.Scan(&c.ID, &c.CustomerID, &c.CustomerName, &c.Name, &validFrom, &validUntil, &createdAt); err != nil {
+		return models.PriceContract{}, err
+	}
+	c.ValidFrom = validFrom.Format("2006-01-02")
+	if validUntil.Valid {
+		c.ValidUntil = validUntil.Time.Format("2006-01-02")
+	}
+	c.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+	c.Items = []models.PriceContractItem{}
+	return c, nil
+}
+
+// GetAll retrieves contracts with their items, of one customer when customerID is not 0
+func (r *PriceContractRepository) GetAll(customerID int) ([]models.PriceContract, error) {
+	rows, err := r.db.Query(`
+		SELECT `+priceContractColumns+`
+		FROM price_contract c
+		INNER JOIN customer cu ON c.customer_id = cu.id
+		WHERE c.deleted_at IS NULL AND ($1 = 0 OR c.customer_id = $1)
+		ORDER BY c.customer_id, c.valid_from DESC, c.id
+	`, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contracts := []models.PriceContract{}
+	index := make(map[int]int)
+	ids := []int{}
+	for rows.Next() {
+		c, err := scanPriceContract(rows)
+		if err != nil {
+			return nil, err
+		}
+		index[c.ID] = len(contracts)
+		ids = append(ids, c.ID)
+		contracts = append(contracts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	itemRows, err := r.db.Query(`
+		SELECT i.contract_id, i.product_id, p.name, i.price
+		FROM price_contract_item i
+		INNER JOIN product p ON i.product_id = p.id
+		WHERE i.contract_id = ANY($1)
+		ORDER BY i.contract_id, p.name
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var contractID int
+		var item models.PriceContractItem
+		if err := itemRows.Scan(&contractID, &item.ProductID, &item.ProductName, &item.Price); err != nil {
+			return nil, err
+		}
+		i := index[contractID]
+		contracts[i].Items = append(contracts[i].Items, item)
+	}
+	return contracts, itemRows.Err()
+}
+
+func (r *PriceContractRepository) GetByID(id int) (models.PriceContract, error) {
+	c, err := scanPriceContract(r.db.QueryRow(`
+		SELECT `+priceContractColumns+`
+		FROM price_contract c
+		INNER JOIN customer cu ON c.customer_id = cu.id
+		WHERE c.id = $1 AND c.deleted_at IS NULL
+	`, id))
+	if err != nil {
+		return models.PriceContract{}, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT i.product_id, p.name, i.price
+		FROM price_contract_item i
+		INNER JOIN product p ON i.product_id = p.id
+		WHERE i.contract_id = $1
+		ORDER BY p.name
+	`, id)
+	if err != nil {
+		return models.PriceContract{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.PriceContractItem
+		if err := rows.Scan(&item.ProductID, &item.ProductName, &item.Price); err != nil {
+			return models.PriceContract{}, err
+		}
+		c.Items = append(c.Items, item)
+	}
+	return c, rows.Err()
+}
+
+// Create adds a contract for an active customer on active products
+func (r *PriceContractRepository) Create(c models.PriceContract) (models.PriceContract, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.PriceContract{}, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`
+		INSERT INTO price_contract (customer_id, name, valid_from, valid_until)
+		SELECT id, $2, $3::date, NULLIF($4, '')::date FROM customer WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id
+	`, c.CustomerID, c.Name, c.ValidFrom, c.ValidUntil).Scan(&id)
+	if err == sql.ErrNoRows {
+		return models.PriceContract{}, ErrCustomerNotFound
+	}
+	if err != nil {
+		return models.PriceContract{}, err
+	}
+
+	if err := insertPriceContractItems(tx, id, c.Items); err != nil {
+		return models.PriceContract{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.PriceContract{}, err
+	}
+	return r.GetByID(id)
+}
+
+// Update replaces the terms and items of a contract; it stays with the same
+// customer. Lines already sold under it keep their prices.
+func (r *PriceContractRepository) Update(c models.PriceContract) (models.PriceContract, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.PriceContract{}, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"UPDATE price_contract SET name = $1, valid_from = $2::date, valid_until = NULLIF($3, '')::date WHERE id = $4 AND deleted_at IS NULL",
+		c.Name, c.ValidFrom, c.ValidUntil, c.ID,
+	)
+	if err != nil {
+		return models.PriceContract{}, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.PriceContract{}, err
+	}
+
+	if rowsAffected == 0 {
+		return models.PriceContract{}, sql.ErrNoRows
+	}
+
+	if _, err := tx.Exec("DELETE FROM price_contract_item WHERE contract_id = $1", c.ID); err != nil {
+		return models.PriceContract{}, err
+	}
+	if err := insertPriceContractItems(tx, c.ID, c.Items); err != nil {
+		return models.PriceContract{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.PriceContract{}, err
+	}
+	return r.GetByID(c.ID)
+}
+
+// Delete soft deletes a contract; lines already sold under it keep pointing at it
+func (r *PriceContractRepository) Delete(id int) error {
+	result, err := r.db.Exec("UPDATE price_contract SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetActive retrieves the prices the contracts of an active customer valid
+// today give the products, keyed by product; of overlapping contracts the
+// cheapest wins
+func (r *PriceContractRepository) GetActive(customerID int, productIDs []int) (map[int]models.AppliedPriceContract, error) {
+	var active bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM customer WHERE id = $1 AND deleted_at IS NULL)", customerID).Scan(&active)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, fmt.Errorf("%w: id %d", ErrCustomerNotFound, customerID)
+	}
+
+	rows, err := r.db.Query(`
+		SELECT DISTINCT ON (i.product_id) i.product_id, c.id, c.name, i.price
+		FROM price_contract c
+		INNER JOIN price_contract_item i ON i.contract_id = c.id
+		WHERE c.customer_id = $1 AND c.deleted_at IS NULL
+			AND c.valid_from <= CURRENT_DATE AND (c.valid_until IS NULL OR c.valid_until >= CURRENT_DATE)
+			AND i.product_id = ANY($2)
+		ORDER BY i.product_id, i.price, c.id
+	`, customerID, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make(map[int]models.AppliedPriceContract)
+	for rows.Next() {
+		var productID int
+		var c models.AppliedPriceContract
+		if err := rows.Scan(&productID, &c.ID, &c.Name, &c.Price); err != nil {
+			return nil, err
+		}
+		prices[productID] = c
+	}
+	return prices, rows.Err()
+}
+
+// insertPriceContractItems adds the items of a contract; every product must be active
+func insertPriceContractItems(tx *sql.Tx, contractID int, items []models.PriceContractItem) error {
+	for _, item := range items {
+		result, err := tx.Exec(`
+			INSERT INTO price_contract_item (contract_id, product_id, price)
+			SELECT $1, id, $3 FROM product WHERE id = $2 AND deleted_at IS NULL
+		`, contractID, item.ProductID, item.Price)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("%w: id %d", ErrProductNotFound, item.ProductID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package repositories
+
+import (
+	"database/sql"
+	"strconv"
+
+	"kasir-api/models"
+
+	"github.com/lib/pq"
+)
+
+// PrinterRepository keeps the kitchen printers and the print jobs sent to
+// them. A printer is soft deleted so its print jobs are kept.
+type PrinterRepository struct {
+	db *sql.DB
+}
+
+func NewPrinterRepository(db *sql.DB) *PrinterRepository {
+	return &PrinterRepository{db: db}
+}
+
+const printerColumns = "id, name, address, category_ids, is_default, active, created_at"
+
+func scanPrinter(row rowScanner) (models.Printer, error) {
+	var p models.Printer
+	var categoryIDs pq.Int64Array
+	var createdAt sql.NullTime
+	if err := row.Scan(&p.ID, &p.Name, &p.Address, &categoryIDs, &p.IsDefault, &p.Active, &createdAt); err != nil {
+		return models.Printer{}, err
+	}
+
+	p.CategoryIDs = make([]int, len(categoryIDs))
+	for i, id := range categoryIDs {
+		p.CategoryIDs[i] = int(id)
+	}
+	if createdAt.Valid {
+		p.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return p, nil
+}
+
+func (r *PrinterRepository) queryPrinters(where string) ([]models.Printer, error) {
+	rows, err := r.db.Query("SELECT " + printerColumns + " FROM printer WHERE deleted_at IS NULL" + where + " ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	printers := []models.Printer{}
+	for rows.Next() {
+		p, err := scanPrinter(rows)
+		if err != nil {
+			return nil, err
+		}
+		printers = append(printers, p)
+	}
+	return printers, rows.Err()
+}
+
+// GetAll retrieves every printer
+func (r *PrinterRepository) GetAll() ([]models.Printer, error) {
+	return r.queryPrinters("")
+}
+
+// GetActive retrieves the printers order lines are routed to
+func (r *PrinterRepository) GetActive() ([]models.Printer, error) {
+	return r.queryPrinters(" AND active")
+}
+
+// GetByID retrieves a printer by ID
+func (r *PrinterRepository) GetByID(id int) (models.Printer, error) {
+	return scanPrinter(r.db.QueryRow("SELECT "+printerColumns+" FROM printer WHERE id = $1 AND deleted_at IS NULL", id))
+}
+
+// Create adds a printer; a default printer takes over from the one before
+func (r *PrinterRepository) Create(p models.Printer) (models.Printer, error) {
+	return r.save(p, func(tx *sql.Tx, categoryIDs pq.Int64Array) *sql.Row {
+		return tx.QueryRow(
+			"INSERT INTO printer (name, address, category_ids, is_default, active) VALUES ($1, $2, $3, $4, $5) RETURNING "+printerColumns,
+			p.Name, p.Address, categoryIDs, p.IsDefault, p.Active,
+		)
+	})
+}
+
+// Update updates a printer; a default printer takes over from the one before
+func (r *PrinterRepository) Update(p models.Printer) (models.Printer, error) {
+	return r.save(p, func(tx *sql.Tx, categoryIDs pq.Int64Array) *sql.Row {
+		return tx.QueryRow(
+			"UPDATE printer SET name = $1, address = $2, category_ids = $3, is_default = $4, active = $5 WHERE id = $6 AND deleted_at IS NULL RETURNING "+printerColumns,
+			p.Name, p.Address, categoryIDs, p.IsDefault, p.Active, p.ID,
+		)
+	})
+}
+
+func (r *PrinterRepository) save(p models.Printer, write func(tx *sql.Tx, categoryIDs pq.Int64Array) *sql.Row) (models.Printer, error) {
+	categoryIDs := make(pq.Int64Array, len(p.CategoryIDs))
+	for i, id := range p.CategoryIDs {
+		categoryIDs[i] = int64(id)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.Printer{}, err
+	}
+	defer tx.Rollback()
+
+	if p.IsDefault {
+		if _, err := tx.Exec("UPDATE printer SET is_default = FALSE WHERE is_default AND id <> $1", p.ID); err != nil {
+			return models.Printer{}, err
+		}
+	}
+
+	saved, err := scanPrinter(write(tx, categoryIDs))
+	if err != nil {
+		return models.Printer{}, err
+	}
+	return saved, tx.Commit()
+}
+
+// Delete soft deletes a printer, keeping its print jobs
+func (r *PrinterRepository) Delete(id int) error {
+	result, err := r.db.Exec("UPDATE printer SET deleted_at = NOW(), active = FALSE, is_default = FALSE WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RouteLines claims the lines of transactionID not sent to the printers yet
+// and records the print jobs route makes of them, returning their IDs. Lines
+// are claimed once, so an order printing while it takes more items sends
+// each line one time.
+func (r *PrinterRepository) RouteLines(transactionID int, route func(lines []models.PrintLine) ([]models.PrintJob, error)) ([]int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		WITH claimed AS (
+			UPDATE transaction_details SET routed_at = NOW()
+			WHERE transaction_id = $1 AND routed_at IS NULL
+			RETURNING id, product_id, description, quantity
+		)
+		SELECT COALESCE(p.name, c.description, ''), c.quantity,
+			ARRAY(SELECT pc.category_id FROM product_category pc WHERE pc.product_id = c.product_id ORDER BY pc.category_id)
+		FROM claimed c
+		LEFT JOIN product p ON c.product_id = p.id
+		ORDER BY c.id
+	`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []models.PrintLine{}
+	for rows.Next() {
+		var line models.PrintLine
+		var categoryIDs pq.Int64Array
+		if err := rows.Scan(&line.Name, &line.Quantity, &categoryIDs); err != nil {
+			return nil, err
+		}
+		for _, id := range categoryIDs {
+			line.CategoryIDs = append(line.CategoryIDs, int(id))
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	jobs, err := route(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(jobs))
+	for _, job := range jobs {
+		var id int
+		if err := tx.QueryRow(
+			"INSERT INTO print_job (printer_id, transaction_id, content) VALUES ($1, $2, $3) RETURNING id",
+			job.PrinterID, transactionID, job.Content,
+		).Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, tx.Commit()
+}
+
+const printJobColumns = `j.id, j.printer_id, p.name, j.transaction_id, j.content, j.status, j.attempts, j.error, j.created_at, j.printed_at`
+
+func scanPrintJob(row rowScanner) (models.PrintJob, error) {
+	var j models.PrintJob
+	var createdAt, printedAt sql.NullTime
+	if err := row.Scan(&j.ID, &j.PrinterID, &j.PrinterName, &j.TransactionID, &j.Content, &j.Status, &j.Attempts, &j.Error, &createdAt, &printedAt); err != nil {
+		return models.PrintJob{}, err
+	}
+	if createdAt.Valid {
+		j.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	if printedAt.Valid {
+		j.PrintedAt = printedAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return j, nil
+}
+
+// GetJob retrieves a print job by ID
+func (r *PrinterRepository) GetJob(id int) (models.PrintJob, error) {
+	return scanPrintJob(r.db.QueryRow("SELECT "+printJobColumns+" FROM print_job j JOIN printer p ON j.printer_id = p.id WHERE j.id = $1", id))
+}
+
+// GetJobs retrieves the most recent print jobs, of one status unless status
+// is empty
+func (r *PrinterRepository) GetJobs(status string, limit int) ([]models.PrintJob, error) {
+	query := "SELECT " + printJobColumns + " FROM print_job j JOIN printer p ON j.printer_id = p.id"
+	args := []interface{}{}
+	if status != "" {
+		args = append(args, status)
+		query += " WHERE j.status = $1"
+	}
+	args = append(args, limit)
+	query += " ORDER BY j.id DESC LIMIT $" + strconv.Itoa(len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []models.PrintJob{}
+	for rows.Next() {
+		j, err := scanPrintJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// UpdateJob records the outcome of an attempt to print a job
+func (r *PrinterRepository) UpdateJob(id int, status string, attempts int, errMsg string) error {
+	_, err := r.db.Exec(
+		"UPDATE print_job SET status = $1, attempts = $2, error = $3, printed_at = CASE WHEN $1 = 'printed' THEN NOW() ELSE printed_at END WHERE id = $4",
+		status, attempts, errMsg, id,
+	)
+	return err
+}
@@ -2,27 +2,93 @@ package repositories
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"kasir-api/events"
 	"kasir-api/models"
+	"time"
 
-	"google.golang.org/protobuf/types/known/timestamppb"
+	"github.com/lib/pq"
 )
 
+var (
+	ErrCategoryNotFound       = errors.New("category not found")
+	ErrBundleHasStock         = errors.New("product has stock of its own, bring it to zero before making it a bundle")
+	ErrInvalidBundleComponent = errors.New("bundle components must be other, active products that are not bundles themselves")
+	ErrDuplicateSKU           = errors.New("another product already has this sku")
+	ErrBundleStock            = errors.New("a bundle has no stock of its own, adjust its components instead")
+)
+
+// productStock is the stock of p; a bundle has as many units as its scarcest component allows
+const productStock = `CASE WHEN p.is_bundle THEN COALESCE((
+		SELECT MIN(c.stock / b.quantity)
+		FROM product_bundle_item b
+		INNER JOIN product c ON b.component_id = c.id
+		WHERE b.bundle_id = p.id
+	), 0) ELSE p.stock END`
+
+const productColumns = "p.id, p.name, p.barcode, p.sku, p.price, p.cost_price, " + productStock + ", p.reorder_point, p.reorder_qty, p.abc_class, p.is_bundle, p.created_at, p.updated_at, p.deleted_at"
+
+// scanProduct scans a row of productColumns
+func scanProduct(row rowScanner) (models.Product, error) {
+	var p models.Product
+	var createdAt, updatedAt, deletedAt sql.NullTime
+	if err := row.Scan(&p.ID, &p.Name, &p.Barcode, &p.SKU, &p.Price, &p.CostPrice, &p.Stock, &p.ReorderPoint, &p.ReorderQty, &p.ABCClass, &p.IsBundle, &createdAt, &updatedAt, &deletedAt); err != nil {
+		return models.Product{}, err
+	}
+	p.CreatedAt = timePtr(createdAt)
+	p.UpdatedAt = timePtr(updatedAt)
+	p.DeletedAt = timePtr(deletedAt)
+	return p, nil
+}
+
+// timePtr is t as a model timestamp, nil when NULL
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
 type ProductRepository struct {
-	db *sql.DB
+	db     *sql.DB
+	stmts  *Statements
+	outbox *OutboxRepository
 }
 
 func NewProductRepository(db *sql.DB) *ProductRepository {
-	return &ProductRepository{db: db}
+	return &ProductRepository{db: db, stmts: StatementsOf(db)}
+}
+
+// WithOutbox has the products r creates announced through outbox, in their
+// own database transactions; without it they announce nothing
+func (r *ProductRepository) WithOutbox(outbox *OutboxRepository) *ProductRepository {
+	r.outbox = outbox
+	return r
 }
 
-// GetAll retrieves all active products
-func (r *ProductRepository) GetAll(name string) ([]models.Product, error) {
+// GetAll retrieves all active products, optionally filtered by name and ABC
+// class, in the order of sort. With updatedSince set only the products updated
+// from then on are retrieved, deleted ones included. Their categories are
+// embedded when expand has them, otherwise only their IDs are.
+func (r *ProductRepository) GetAll(name, abcClass, sort string, updatedSince time.Time, expand Expansion) ([]models.Product, error) {
 	args := []interface{}{}
-	query := "SELECT id, name, price, stock, category_id, deleted_at FROM product WHERE deleted_at IS NULL"
+	query := "SELECT " + productColumns + " FROM product p"
+	if updatedSince.IsZero() {
+		query += " WHERE p.deleted_at IS NULL"
+	} else {
+		args = append(args, updatedSince)
+		query += " WHERE p.updated_at >= $1"
+	}
 	if name != "" {
-		query += " AND name ILIKE $1"
 		args = append(args, "%"+name+"%")
+		query += fmt.Sprintf(" AND p.name ILIKE $%d", len(args))
+	}
+	if abcClass != "" {
+		args = append(args, abcClass)
+		query += fmt.Sprintf(" AND p.abc_class = $%d", len(args))
 	}
+	query += CatalogOrderBy("p.", sort)
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
@@ -31,93 +97,471 @@ func (r *ProductRepository) GetAll(name string) ([]models.Product, error) {
 	defer rows.Close()
 
 	var products []models.Product
+	var ids []int
 	for rows.Next() {
-		var p models.Product
-		var deletedAt sql.NullTime
-		if err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Stock, &p.CategoryID, &deletedAt); err != nil {
+		p, err := scanProduct(rows)
+		if err != nil {
 			return nil, err
 		}
-		if deletedAt.Valid {
-			p.DeletedAt = timestamppb.New(deletedAt.Time)
-		}
 		products = append(products, p)
+		ids = append(ids, p.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	categories, err := r.getCategories(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range products {
+		setProductCategories(&products[i], categories[products[i].ID])
+		if !expand[ExpandCategory] {
+			products[i].Categories = nil
+		}
 	}
 	return products, nil
 }
 
 // GetByID retrieves a product by ID
 func (r *ProductRepository) GetByID(id int) (models.Product, error) {
-	var p models.Product
-	var c models.Category
-	var deletedAt sql.NullTime
-	var categoryName sql.NullString
+	p, err := scanProduct(r.stmts.QueryRow("SELECT "+productColumns+" FROM product p WHERE p.id = $1 AND p.deleted_at IS NULL", id))
+	if err != nil {
+		return models.Product{}, err
+	}
 
-	query := `
-		SELECT p.id, p.name, p.price, p.stock, p.category_id, p.deleted_at, 
-		       c.name
-		FROM product p
-		LEFT JOIN category c ON p.category_id = c.id
-		WHERE p.id = $1 AND p.deleted_at IS NULL
-	`
+	categories, err := r.getCategories([]int{p.ID})
+	if err != nil {
+		return models.Product{}, err
+	}
+	setProductCategories(&p, categories[p.ID])
 
-	err := r.db.QueryRow(query, id).Scan(
-		&p.ID, &p.Name, &p.Price, &p.Stock, &p.CategoryID, &deletedAt,
-		&categoryName,
-	)
+	if p.IsBundle {
+		p.Components, err = r.GetComponents(p.ID)
+		if err != nil {
+			return models.Product{}, err
+		}
+	}
+	return p, nil
+}
 
+// GetBySKU retrieves the active product with the given SKU
+func (r *ProductRepository) GetBySKU(sku string) (models.Product, error) {
+	var id int
+	err := r.stmts.QueryRow("SELECT id FROM product WHERE sku = $1 AND deleted_at IS NULL", sku).Scan(&id)
 	if err != nil {
 		return models.Product{}, err
 	}
+	return r.GetByID(id)
+}
+
+// isDuplicateSKU reports whether err is a violation of the unique SKU index
+func isDuplicateSKU(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_product_sku"
+}
+
+// Create inserts a new product, logging its initial stock as an adjustment
+func (r *ProductRepository) Create(product models.Product) (models.Product, error) {
+	return r.save(product, r.createProduct)
+}
+
+// Update updates an existing product, logging a changed stock as an
+// adjustment. The stock of a bundle is derived from its components and is
+// left untouched.
+func (r *ProductRepository) Update(product models.Product) (models.Product, error) {
+	return r.save(product, updateProduct)
+}
+
+// CreateBatch creates products as Create does, all in one database
+// transaction; see SaveEach for errs and atomic
+func (r *ProductRepository) CreateBatch(products []models.Product, atomic bool) ([]models.Product, []error, error) {
+	return r.saveBatch(products, atomic, r.createProduct)
+}
 
-	if categoryName.Valid {
-		c.Name = categoryName.String
-		p.Category = &c
+// createProduct is createProduct announcing the product through the outbox,
+// with the categories it was filed under
+func (r *ProductRepository) createProduct(tx *sql.Tx, product *models.Product) error {
+	if err := createProduct(tx, product); err != nil {
+		return err
+	}
+	if r.outbox == nil {
+		return nil
 	}
 
-	if deletedAt.Valid {
-		p.DeletedAt = timestamppb.New(deletedAt.Time)
+	categories, err := txCategories(tx, product.ID)
+	if err != nil {
+		return err
 	}
-	return p, nil
+	setProductCategories(product, categories)
+	return r.outbox.Record(tx, events.ProductCreated, product)
 }
 
-// Create inserts a new product
-func (r *ProductRepository) Create(product models.Product) (models.Product, error) {
-	var deletedAt sql.NullTime
-	err := r.db.QueryRow(
-		"INSERT INTO product (name, price, stock, category_id) VALUES ($1, $2, $3, $4) RETURNING id, deleted_at",
-		product.Name, product.Price, product.Stock, product.CategoryID,
-	).Scan(&product.ID, &deletedAt)
+// UpdateBatch updates products as Update does, all in one database
+// transaction; see SaveEach for errs and atomic
+func (r *ProductRepository) UpdateBatch(products []models.Product, atomic bool) ([]models.Product, []error, error) {
+	return r.saveBatch(products, atomic, updateProduct)
+}
 
+func (r *ProductRepository) save(product models.Product, write func(tx *sql.Tx, product *models.Product) error) (models.Product, error) {
+	tx, err := r.db.Begin()
 	if err != nil {
 		return models.Product{}, err
 	}
+	defer tx.Rollback()
+
+	if err := write(tx, &product); err != nil {
+		return models.Product{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Product{}, err
+	}
 
-	if deletedAt.Valid {
-		product.DeletedAt = timestamppb.New(deletedAt.Time)
+	categories, err := r.getCategories([]int{product.ID})
+	if err != nil {
+		return models.Product{}, err
 	}
+	setProductCategories(&product, categories[product.ID])
 	return product, nil
 }
 
-// Update updates an existing product
-func (r *ProductRepository) Update(product models.Product) (models.Product, error) {
-	var deletedAt sql.NullTime
-	err := r.db.QueryRow(
-		"UPDATE product SET name = $1, price = $2, stock = $3, category_id = $4 WHERE id = $5 RETURNING deleted_at",
-		product.Name, product.Price, product.Stock, product.CategoryID, product.ID,
-	).Scan(&deletedAt)
+func (r *ProductRepository) saveBatch(products []models.Product, atomic bool, write func(tx *sql.Tx, product *models.Product) error) ([]models.Product, []error, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	saved := append([]models.Product(nil), products...)
+	errs, failed, err := SaveEach(tx, len(saved), atomic, func(i int) error {
+		return write(tx, &saved[i])
+	})
+	if err != nil || (atomic && failed) {
+		return nil, errs, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
 
+	ids := []int{}
+	for i := range saved {
+		if errs[i] == nil {
+			ids = append(ids, saved[i].ID)
+		}
+	}
+	categories, err := r.getCategories(ids)
 	if err != nil {
-		return models.Product{}, err
+		return nil, nil, err
+	}
+	for i := range saved {
+		if errs[i] == nil {
+			setProductCategories(&saved[i], categories[saved[i].ID])
+		}
+	}
+	return saved, errs, nil
+}
+
+// createProduct inserts product in tx, filling in its ID and timestamps
+func createProduct(tx *sql.Tx, product *models.Product) error {
+	var createdAt, updatedAt, deletedAt sql.NullTime
+	err := tx.QueryRow(
+		"INSERT INTO product (name, barcode, sku, price, cost_price, stock, reorder_point, reorder_qty) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, abc_class, created_at, updated_at, deleted_at",
+		product.Name, product.Barcode, product.SKU, product.Price, product.CostPrice, product.Stock, product.ReorderPoint, product.ReorderQty,
+	).Scan(&product.ID, &product.ABCClass, &createdAt, &updatedAt, &deletedAt)
+
+	if isDuplicateSKU(err) {
+		return ErrDuplicateSKU
+	}
+	if err != nil {
+		return err
 	}
 
-	if deletedAt.Valid {
-		product.DeletedAt = timestamppb.New(deletedAt.Time)
+	if err := setCategories(tx, product.ID, product.CategoryIDs); err != nil {
+		return err
 	}
-	return product, nil
+
+	if product.Stock != 0 {
+		if err := recordStockMovement(tx, product.ID, product.Stock, MovementAdjustment, 0); err != nil {
+			return err
+		}
+	}
+
+	product.CreatedAt = timePtr(createdAt)
+	product.UpdatedAt = timePtr(updatedAt)
+	product.DeletedAt = timePtr(deletedAt)
+	return nil
+}
+
+// updateProduct updates product in tx, filling in its timestamps
+func updateProduct(tx *sql.Tx, product *models.Product) error {
+	var previousStock int
+	var isBundle bool
+	err := tx.QueryRow("SELECT stock, is_bundle FROM product WHERE id = $1 FOR UPDATE", product.ID).Scan(&previousStock, &isBundle)
+	if err != nil {
+		return err
+	}
+	if isBundle {
+		product.Stock = previousStock
+	}
+
+	var createdAt, updatedAt, deletedAt sql.NullTime
+	err = tx.QueryRow(
+		"UPDATE product SET name = $1, barcode = $2, sku = $3, price = $4, cost_price = $5, stock = $6, reorder_point = $7, reorder_qty = $8, updated_at = NOW() WHERE id = $9 RETURNING abc_class, created_at, updated_at, deleted_at",
+		product.Name, product.Barcode, product.SKU, product.Price, product.CostPrice, product.Stock, product.ReorderPoint, product.ReorderQty, product.ID,
+	).Scan(&product.ABCClass, &createdAt, &updatedAt, &deletedAt)
+
+	if isDuplicateSKU(err) {
+		return ErrDuplicateSKU
+	}
+	if err != nil {
+		return err
+	}
+	product.IsBundle = isBundle
+
+	if err := setCategories(tx, product.ID, product.CategoryIDs); err != nil {
+		return err
+	}
+
+	if product.Stock != previousStock {
+		if err := recordStockMovement(tx, product.ID, product.Stock-previousStock, MovementAdjustment, 0); err != nil {
+			return err
+		}
+		if err := settleBatches(tx, product.ID); err != nil {
+			return err
+		}
+	}
+
+	product.CreatedAt = timePtr(createdAt)
+	product.UpdatedAt = timePtr(updatedAt)
+	product.DeletedAt = timePtr(deletedAt)
+	return nil
+}
+
+// getCategories retrieves the active categories of the given products, by product ID
+func (r *ProductRepository) getCategories(productIDs []int) (map[int][]models.Category, error) {
+	rows, err := r.stmts.Query(`
+		SELECT pc.product_id, c.id, c.name, c.description
+		FROM product_category pc
+		INNER JOIN category c ON pc.category_id = c.id
+		WHERE pc.product_id = ANY($1) AND c.deleted_at IS NULL
+		ORDER BY c.name
+	`, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make(map[int][]models.Category)
+	for rows.Next() {
+		var productID int
+		var c models.Category
+		if err := rows.Scan(&productID, &c.ID, &c.Name, &c.Description); err != nil {
+			return nil, err
+		}
+		categories[productID] = append(categories[productID], c)
+	}
+	return categories, rows.Err()
+}
+
+// txCategories retrieves the active categories of a product in tx, which
+// sees those it was just filed under
+func txCategories(tx *sql.Tx, productID int) ([]models.Category, error) {
+	rows, err := tx.Query(`
+		SELECT c.id, c.name, c.description
+		FROM product_category pc
+		INNER JOIN category c ON pc.category_id = c.id
+		WHERE pc.product_id = $1 AND c.deleted_at IS NULL
+		ORDER BY c.name
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []models.Category{}
+	for rows.Next() {
+		var c models.Category
+		if err := rows.Scan(&c.ID, &c.Name, &c.Description); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// setProductCategories fills in the categories of p and their IDs
+func setProductCategories(p *models.Product, categories []models.Category) {
+	p.CategoryIDs = []int{}
+	p.Categories = categories
+	for _, c := range categories {
+		p.CategoryIDs = append(p.CategoryIDs, c.ID)
+	}
+}
+
+// setCategories replaces the categories of a product; categoryIDs hold no duplicates
+func setCategories(tx *sql.Tx, productID int, categoryIDs []int) error {
+	if _, err := tx.Exec("DELETE FROM product_category WHERE product_id = $1", productID); err != nil {
+		return err
+	}
+	for _, categoryID := range categoryIDs {
+		result, err := tx.Exec(
+			"INSERT INTO product_category (product_id, category_id) SELECT $1, id FROM category WHERE id = $2 AND deleted_at IS NULL",
+			productID, categoryID,
+		)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrCategoryNotFound
+		}
+	}
+	return nil
 }
 
 // Delete soft deletes a product
 func (r *ProductRepository) Delete(id int) error {
-	_, err := r.db.Exec("UPDATE product SET deleted_at = NOW() WHERE id = $1", id)
-	return err
+	result, err := r.db.Exec("UPDATE product SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AdjustStock adds quantity, negative to take stock, to the stock of an
+// active product that is not a bundle, logging it as an adjustment. Stock
+// taken comes out of the batches expiring first and can't go below zero.
+func (r *ProductRepository) AdjustStock(id, quantity int) (models.Product, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.Product{}, err
+	}
+	defer tx.Rollback()
+
+	var name string
+	var stock int
+	var isBundle bool
+	err = tx.QueryRow("SELECT name, stock, is_bundle FROM product WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id).Scan(&name, &stock, &isBundle)
+	if err != nil {
+		return models.Product{}, err
+	}
+	if isBundle {
+		return models.Product{}, ErrBundleStock
+	}
+	if stock+quantity < 0 {
+		return models.Product{}, &InsufficientStockError{ProductID: id, Name: name, Available: stock, Requested: -quantity}
+	}
+
+	if _, err := tx.Exec("UPDATE product SET stock = stock + $1, updated_at = NOW() WHERE id = $2", quantity, id); err != nil {
+		return models.Product{}, err
+	}
+	if quantity < 0 {
+		if err := takeBatches(tx, id, -quantity); err != nil {
+			return models.Product{}, err
+		}
+	}
+	if err := recordStockMovement(tx, id, quantity, MovementAdjustment, 0); err != nil {
+		return models.Product{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Product{}, err
+	}
+	return r.GetByID(id)
+}
+
+// GetComponents retrieves the products a bundle is made of
+func (r *ProductRepository) GetComponents(bundleID int) ([]models.BundleComponent, error) {
+	rows, err := r.db.Query(`
+		SELECT c.id, c.name, b.quantity, c.price, c.stock
+		FROM product_bundle_item b
+		INNER JOIN product c ON b.component_id = c.id
+		WHERE b.bundle_id = $1
+		ORDER BY c.name
+	`, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	components := []models.BundleComponent{}
+	for rows.Next() {
+		var c models.BundleComponent
+		if err := rows.Scan(&c.ProductID, &c.ProductName, &c.Quantity, &c.Price, &c.Stock); err != nil {
+			return nil, err
+		}
+		components = append(components, c)
+	}
+	return components, rows.Err()
+}
+
+// SetComponents replaces what a bundle is made of; with no components the
+// product is sold as a regular product again
+func (r *ProductRepository) SetComponents(bundleID int, components []models.BundleComponent) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var stock int
+	var isBundle bool
+	err = tx.QueryRow("SELECT stock, is_bundle FROM product WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", bundleID).Scan(&stock, &isBundle)
+	if err != nil {
+		return err
+	}
+	if !isBundle && stock != 0 && len(components) > 0 {
+		return ErrBundleHasStock
+	}
+
+	// bundles don't nest, a component of another bundle can't become one
+	var isComponent bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM product_bundle_item WHERE component_id = $1)", bundleID).Scan(&isComponent)
+	if err != nil {
+		return err
+	}
+	if isComponent && len(components) > 0 {
+		return ErrInvalidBundleComponent
+	}
+
+	if _, err := tx.Exec("DELETE FROM product_bundle_item WHERE bundle_id = $1", bundleID); err != nil {
+		return err
+	}
+
+	for _, c := range components {
+		if c.ProductID == bundleID {
+			return ErrInvalidBundleComponent
+		}
+		result, err := tx.Exec(`
+			INSERT INTO product_bundle_item (bundle_id, component_id, quantity)
+			SELECT $1, id, $3 FROM product WHERE id = $2 AND NOT is_bundle AND deleted_at IS NULL
+		`, bundleID, c.ProductID, c.Quantity)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrInvalidBundleComponent
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE product SET is_bundle = $1, updated_at = NOW() WHERE id = $2", len(components) > 0, bundleID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
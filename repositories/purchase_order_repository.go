@@ -0,0 +1,202 @@
+package repositories
+
+import (
+	"database/sql"
+	"kasir-api/models"
+)
+
+type PurchaseOrderRepository struct {
+	db *sql.DB
+}
+
+func NewPurchaseOrderRepository(db *sql.DB) *PurchaseOrderRepository {
+	return &PurchaseOrderRepository{db: db}
+}
+
+// Create inserts a purchase order with its items
+func (r *PurchaseOrderRepository) Create(po models.PurchaseOrder) (*models.PurchaseOrder, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var createdAt sql.NullTime
+	err = tx.QueryRow(
+		"INSERT INTO purchase_order (supplier_id) VALUES ($1) RETURNING id, status, created_at",
+		po.SupplierID,
+	).Scan(&po.ID, &po.Status, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range po.Items {
+		item := &po.Items[i]
+		item.PurchaseOrderID = po.ID
+		err = tx.QueryRow(
+			"INSERT INTO purchase_order_item (purchase_order_id, product_id, quantity_ordered, unit_cost) VALUES ($1, $2, $3, $4) RETURNING id",
+			item.PurchaseOrderID, item.ProductID, item.QuantityOrdered, item.UnitCost,
+		).Scan(&item.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if createdAt.Valid {
+		po.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return &po, nil
+}
+
+// GetAll retrieves all purchase orders without items, optionally filtered by status
+func (r *PurchaseOrderRepository) GetAll(status string) ([]models.PurchaseOrder, error) {
+	args := []interface{}{}
+	query := `
+		SELECT po.id, po.supplier_id, s.name, po.status, po.close_reason, po.created_at, po.closed_at
+		FROM purchase_order po
+		INNER JOIN supplier s ON po.supplier_id = s.id
+	`
+	if status != "" {
+		query += " WHERE po.status = $1"
+		args = append(args, status)
+	}
+	query += " ORDER BY po.id DESC"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []models.PurchaseOrder{}
+	for rows.Next() {
+		var po models.PurchaseOrder
+		var createdAt, closedAt sql.NullTime
+		if err := rows.Scan(&po.ID, &po.SupplierID, &po.SupplierName, &po.Status, &po.CloseReason, &createdAt, &closedAt); err != nil {
+			return nil, err
+		}
+		if createdAt.Valid {
+			po.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+		}
+		if closedAt.Valid {
+			po.ClosedAt = closedAt.Time.Format("2006-01-02 15:04:05")
+		}
+		po.Items = []models.PurchaseOrderItem{}
+		orders = append(orders, po)
+	}
+	return orders, rows.Err()
+}
+
+// GetByID retrieves a purchase order with its items
+func (r *PurchaseOrderRepository) GetByID(id int) (*models.PurchaseOrder, error) {
+	po := &models.PurchaseOrder{}
+	var createdAt, closedAt sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT po.id, po.supplier_id, s.name, po.status, po.close_reason, po.created_at, po.closed_at
+		FROM purchase_order po
+		INNER JOIN supplier s ON po.supplier_id = s.id
+		WHERE po.id = $1
+	`, id).Scan(&po.ID, &po.SupplierID, &po.SupplierName, &po.Status, &po.CloseReason, &createdAt, &closedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if createdAt.Valid {
+		po.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	if closedAt.Valid {
+		po.ClosedAt = closedAt.Time.Format("2006-01-02 15:04:05")
+	}
+
+	rows, err := r.db.Query(`
+		SELECT poi.id, poi.purchase_order_id, poi.product_id, p.name, poi.quantity_ordered, poi.quantity_received, poi.unit_cost
+		FROM purchase_order_item poi
+		INNER JOIN product p ON poi.product_id = p.id
+		WHERE poi.purchase_order_id = $1
+		ORDER BY poi.id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	po.Items = []models.PurchaseOrderItem{}
+	for rows.Next() {
+		var item models.PurchaseOrderItem
+		if err := rows.Scan(&item.ID, &item.PurchaseOrderID, &item.ProductID, &item.ProductName, &item.QuantityOrdered, &item.QuantityReceived, &item.UnitCost); err != nil {
+			return nil, err
+		}
+		po.Items = append(po.Items, item)
+	}
+	return po, rows.Err()
+}
+
+// ShortClose closes a purchase order that will not be delivered in full.
+// Returns sql.ErrNoRows when the order is not open anymore or is being received.
+func (r *PurchaseOrderRepository) ShortClose(id int, reason string) error {
+	result, err := r.db.Exec(`
+		UPDATE purchase_order SET status = 'short_closed', close_reason = $1, closed_at = NOW()
+		WHERE id = $2 AND status IN ('open', 'partially_received')
+			AND NOT EXISTS (SELECT 1 FROM receiving_session WHERE purchase_order_id = $2 AND status = 'open')
+	`, reason, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetReceipts retrieves all goods receipts of a purchase order, oldest first
+func (r *PurchaseOrderRepository) GetReceipts(purchaseOrderID int) ([]models.GoodsReceipt, error) {
+	rows, err := r.db.Query(`
+		SELECT gr.id, gr.session_id, gr.created_at, gri.product_id, p.name, gri.quantity, gri.unit_cost
+		FROM goods_receipt gr
+		INNER JOIN goods_receipt_item gri ON gri.receipt_id = gr.id
+		INNER JOIN product p ON gri.product_id = p.id
+		WHERE gr.purchase_order_id = $1
+		ORDER BY gr.id, gri.id
+	`, purchaseOrderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	receipts := []models.GoodsReceipt{}
+	for rows.Next() {
+		var receiptID int
+		var sessionID sql.NullInt64
+		var createdAt sql.NullTime
+		var item models.GoodsReceiptItem
+		if err := rows.Scan(&receiptID, &sessionID, &createdAt, &item.ProductID, &item.ProductName, &item.Quantity, &item.UnitCost); err != nil {
+			return nil, err
+		}
+
+		if len(receipts) == 0 || receipts[len(receipts)-1].ID != receiptID {
+			receipt := models.GoodsReceipt{ID: receiptID, PurchaseOrderID: purchaseOrderID, Items: []models.GoodsReceiptItem{}}
+			if sessionID.Valid {
+				id := int(sessionID.Int64)
+				receipt.SessionID = &id
+			}
+			if createdAt.Valid {
+				receipt.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+			}
+			receipts = append(receipts, receipt)
+		}
+
+		last := &receipts[len(receipts)-1]
+		last.Items = append(last.Items, item)
+	}
+	return receipts, rows.Err()
+}
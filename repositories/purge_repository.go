@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"kasir-api/models"
+
+	"github.com/lib/pq"
+)
+
+// Outcomes of a soft deleted row in a recycle bin purge
+const (
+	PurgePurged = "purged"
+	PurgeKept   = "kept"
+)
+
+// purgeTable is a table whose soft deleted rows are purged, with the link
+// rows deleted along with each, $1 being its ID
+type purgeTable struct {
+	name  string
+	links []string
+}
+
+// purgeTables are purged in this order, rows that point at others first.
+// Sales, expenses and the ledgers are never purged: a row they still refer
+// to is kept.
+var purgeTables = []purgeTable{
+	{name: "pricing_rule"},
+	{name: "price_contract", links: []string{"DELETE FROM price_contract_item WHERE contract_id = $1"}},
+	{name: "category", links: []string{"DELETE FROM product_category WHERE category_id = $1"}},
+	{name: "product", links: []string{"DELETE FROM product_category WHERE product_id = $1", "DELETE FROM product_bundle_item WHERE bundle_id = $1", "DELETE FROM store_price WHERE product_id = $1"}},
+	{name: "customer"},
+	{name: "supplier", links: []string{"DELETE FROM supplier_price WHERE supplier_id = $1"}},
+	{name: "courier"},
+	{name: "webhook"},
+	{name: "printer"},
+	{name: "store", links: []string{"DELETE FROM store_price WHERE store_id = $1"}},
+}
+
+type PurgeRepository struct {
+	db *sql.DB
+}
+
+func NewPurgeRepository(db *sql.DB) *PurgeRepository {
+	return &PurgeRepository{db: db}
+}
+
+// Purge hard deletes the rows soft deleted before cutoff and logs each,
+// purged or kept because other records refer to it. A dry run rolls the
+// deletes back and logs what they did.
+func (r *PurgeRepository) Purge(cutoff time.Time, dryRun bool) ([]models.PurgeEntry, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	entries := []models.PurgeEntry{}
+	for _, table := range purgeTables {
+		purged, err := purgeRows(tx, table, cutoff)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, purged...)
+	}
+
+	if dryRun {
+		if err := tx.Rollback(); err != nil {
+			return nil, err
+		}
+		tx, err = r.db.Begin()
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+	}
+
+	for i := range entries {
+		entries[i].DryRun = dryRun
+		var createdAt time.Time
+		err := tx.QueryRow(
+			"INSERT INTO purge_log (dry_run, table_name, record_id, deleted_at, outcome, reason) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at",
+			dryRun, entries[i].TableName, entries[i].RecordID, entries[i].DeletedAt, entries[i].Outcome, entries[i].Reason,
+		).Scan(&entries[i].ID, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+		entries[i].CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+	}
+	return entries, tx.Commit()
+}
+
+// purgeRows deletes the rows of table soft deleted before cutoff in tx, each
+// in a savepoint so a row still referred to is kept while the rest go
+func purgeRows(tx *sql.Tx, table purgeTable, cutoff time.Time) ([]models.PurgeEntry, error) {
+	rows, err := tx.Query("SELECT id, deleted_at FROM "+table.name+" WHERE deleted_at < $1 ORDER BY id", cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.PurgeEntry{}
+	for rows.Next() {
+		var e models.PurgeEntry
+		var deletedAt time.Time
+		if err := rows.Scan(&e.RecordID, &deletedAt); err != nil {
+			return nil, err
+		}
+		e.TableName = table.name
+		e.DeletedAt = deletedAt.Format("2006-01-02 15:04:05")
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	errs, _, err := SaveEach(tx, len(entries), false, func(i int) error {
+		for _, link := range table.links {
+			if _, err := tx.Exec(link, entries[i].RecordID); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec("DELETE FROM "+table.name+" WHERE id = $1", entries[i].RecordID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, err := range errs {
+		var pqErr *pq.Error
+		switch {
+		case err == nil:
+			entries[i].Outcome = PurgePurged
+		case errors.As(err, &pqErr) && pqErr.Code == "23503":
+			// e.g. Key (id)=(5) is still referenced from table "stock_movement".
+			entries[i].Outcome = PurgeKept
+			entries[i].Reason = pqErr.Detail
+		default:
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// GetLog retrieves the most recent purge log entries, newest first
+func (r *PurgeRepository) GetLog(limit int) ([]models.PurgeEntry, error) {
+	rows, err := r.db.Query(
+		"SELECT id, dry_run, table_name, record_id, deleted_at, outcome, reason, created_at FROM purge_log ORDER BY id DESC LIMIT $1",
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.PurgeEntry{}
+	for rows.Next() {
+		var e models.PurgeEntry
+		var deletedAt, createdAt time.Time
+		if err := rows.Scan(&e.ID, &e.DryRun, &e.TableName, &e.RecordID, &deletedAt, &e.Outcome, &e.Reason, &createdAt); err != nil {
+			return nil, err
+		}
+		e.DeletedAt = deletedAt.Format("2006-01-02 15:04:05")
+		e.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"kasir-api/models"
+)
+
+type PushRepository struct {
+	db *sql.DB
+}
+
+func NewPushRepository(db *sql.DB) *PushRepository {
+	return &PushRepository{db: db}
+}
+
+func scanPushSubscription(row rowScanner) (models.PushSubscription, error) {
+	var s models.PushSubscription
+	var createdAt sql.NullTime
+	err := row.Scan(&s.ID, &s.Endpoint, &s.Keys.P256dh, &s.Keys.Auth, &s.UserAgent, &createdAt)
+	if err != nil {
+		return s, err
+	}
+	if createdAt.Valid {
+		s.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return s, nil
+}
+
+// Save stores a subscription; a browser subscribing again refreshes its keys
+func (r *PushRepository) Save(s models.PushSubscription) (models.PushSubscription, error) {
+	return scanPushSubscription(r.db.QueryRow(`
+		INSERT INTO push_subscription (endpoint, p256dh, auth, user_agent) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (endpoint) DO UPDATE SET p256dh = EXCLUDED.p256dh, auth = EXCLUDED.auth, user_agent = EXCLUDED.user_agent
+		RETURNING id, endpoint, p256dh, auth, user_agent, created_at
+	`, s.Endpoint, s.Keys.P256dh, s.Keys.Auth, s.UserAgent))
+}
+
+func (r *PushRepository) GetAll() ([]models.PushSubscription, error) {
+	rows, err := r.db.Query("SELECT id, endpoint, p256dh, auth, user_agent, created_at FROM push_subscription ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []models.PushSubscription{}
+	for rows.Next() {
+		s, err := scanPushSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	return subscriptions, rows.Err()
+}
+
+func (r *PushRepository) GetByID(id int) (models.PushSubscription, error) {
+	return scanPushSubscription(r.db.QueryRow("SELECT id, endpoint, p256dh, auth, user_agent, created_at FROM push_subscription WHERE id = $1", id))
+}
+
+// DeleteByEndpoint removes a subscription, returning sql.ErrNoRows if there is none
+func (r *PushRepository) DeleteByEndpoint(endpoint string) error {
+	result, err := r.db.Exec("DELETE FROM push_subscription WHERE endpoint = $1", endpoint)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
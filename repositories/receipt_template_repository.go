@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"kasir-api/models"
+)
+
+// ReceiptTemplateRepository keeps every version of the receipt branding; at
+// most one is active
+type ReceiptTemplateRepository struct {
+	db *sql.DB
+}
+
+func NewReceiptTemplateRepository(db *sql.DB) *ReceiptTemplateRepository {
+	return &ReceiptTemplateRepository{db: db}
+}
+
+const receiptTemplateColumns = "id, logo_url, header, footer, promo, body, note, active, created_at"
+
+func scanReceiptTemplate(row rowScanner) (models.ReceiptTemplate, error) {
+	var t models.ReceiptTemplate
+	var createdAt sql.NullTime
+	if err := row.Scan(&t.ID, &t.LogoURL, &t.Header, &t.Footer, &t.Promo, &t.Body, &t.Note, &t.Active, &createdAt); err != nil {
+		return models.ReceiptTemplate{}, err
+	}
+	if createdAt.Valid {
+		t.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return t, nil
+}
+
+// GetAll returns every version, newest first
+func (r *ReceiptTemplateRepository) GetAll() ([]models.ReceiptTemplate, error) {
+	rows, err := r.db.Query("SELECT " + receiptTemplateColumns + " FROM receipt_template ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.ReceiptTemplate{}
+	for rows.Next() {
+		t, err := scanReceiptTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+// GetActive returns the active version, sql.ErrNoRows before the first is saved
+func (r *ReceiptTemplateRepository) GetActive() (models.ReceiptTemplate, error) {
+	return scanReceiptTemplate(r.db.QueryRow("SELECT " + receiptTemplateColumns + " FROM receipt_template WHERE active"))
+}
+
+// Create saves req as a new version and makes it the active one
+func (r *ReceiptTemplateRepository) Create(req models.ReceiptTemplateRequest) (models.ReceiptTemplate, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.ReceiptTemplate{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE receipt_template SET active = FALSE WHERE active"); err != nil {
+		return models.ReceiptTemplate{}, err
+	}
+	t, err := scanReceiptTemplate(tx.QueryRow(
+		"INSERT INTO receipt_template (logo_url, header, footer, promo, body, note, active) VALUES ($1, $2, $3, $4, $5, $6, TRUE) RETURNING "+receiptTemplateColumns,
+		req.LogoURL, req.Header, req.Footer, req.Promo, req.Body, req.Note,
+	))
+	if err != nil {
+		return models.ReceiptTemplate{}, err
+	}
+	return t, tx.Commit()
+}
+
+// Activate makes version id the active one again
+func (r *ReceiptTemplateRepository) Activate(id int) (models.ReceiptTemplate, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.ReceiptTemplate{}, err
+	}
+	defer tx.Rollback()
+
+	// the old version is switched off first, so the active index never sees two
+	if _, err := tx.Exec("UPDATE receipt_template SET active = FALSE WHERE active AND id <> $1", id); err != nil {
+		return models.ReceiptTemplate{}, err
+	}
+	t, err := scanReceiptTemplate(tx.QueryRow("UPDATE receipt_template SET active = TRUE WHERE id = $1 RETURNING "+receiptTemplateColumns, id))
+	if err != nil {
+		return models.ReceiptTemplate{}, err
+	}
+	return t, tx.Commit()
+}
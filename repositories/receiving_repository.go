@@ -0,0 +1,253 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"kasir-api/models"
+)
+
+var (
+	ErrSessionNotOpen    = errors.New("receiving session is not open")
+	ErrProductNotOnOrder = errors.New("product is not on the purchase order")
+)
+
+type ReceivingRepository struct {
+	db *sql.DB
+}
+
+func NewReceivingRepository(db *sql.DB) *ReceivingRepository {
+	return &ReceivingRepository{db: db}
+}
+
+// Open starts a receiving session for a purchase order
+func (r *ReceivingRepository) Open(purchaseOrderID int) (*models.ReceivingSession, error) {
+	session := &models.ReceivingSession{PurchaseOrderID: purchaseOrderID}
+	var createdAt sql.NullTime
+	err := r.db.QueryRow(
+		"INSERT INTO receiving_session (purchase_order_id) VALUES ($1) RETURNING id, status, created_at",
+		purchaseOrderID,
+	).Scan(&session.ID, &session.Status, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if createdAt.Valid {
+		session.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return session, nil
+}
+
+// GetOpenSessionID returns the open session of a purchase order, or sql.ErrNoRows
+func (r *ReceivingRepository) GetOpenSessionID(purchaseOrderID int) (int, error) {
+	var id int
+	err := r.db.QueryRow(
+		"SELECT id FROM receiving_session WHERE purchase_order_id = $1 AND status = 'open'",
+		purchaseOrderID,
+	).Scan(&id)
+	return id, err
+}
+
+// GetByID retrieves a session with one line per purchase order item
+func (r *ReceivingRepository) GetByID(id int) (*models.ReceivingSession, error) {
+	session := &models.ReceivingSession{}
+	var createdAt, committedAt sql.NullTime
+	err := r.db.QueryRow(
+		"SELECT id, purchase_order_id, status, created_at, committed_at FROM receiving_session WHERE id = $1",
+		id,
+	).Scan(&session.ID, &session.PurchaseOrderID, &session.Status, &createdAt, &committedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if createdAt.Valid {
+		session.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	if committedAt.Valid {
+		session.CommittedAt = committedAt.Time.Format("2006-01-02 15:04:05")
+	}
+
+	// once committed, the scanned quantity is already part of quantity_received
+	rows, err := r.db.Query(`
+		SELECT p.id, p.name, p.barcode,
+		       GREATEST(poi.quantity_ordered - poi.quantity_received
+		                + CASE WHEN $3 = 'committed' THEN COALESCE(rsi.scanned_qty, 0) ELSE 0 END, 0) as expected,
+		       COALESCE(rsi.scanned_qty, 0) as scanned
+		FROM purchase_order_item poi
+		INNER JOIN product p ON poi.product_id = p.id
+		LEFT JOIN receiving_session_item rsi ON rsi.product_id = poi.product_id AND rsi.session_id = $1
+		WHERE poi.purchase_order_id = $2
+		ORDER BY poi.id
+	`, id, session.PurchaseOrderID, session.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	session.Lines = []models.ReceivingLine{}
+	for rows.Next() {
+		var line models.ReceivingLine
+		if err := rows.Scan(&line.ProductID, &line.ProductName, &line.Barcode, &line.Expected, &line.Scanned); err != nil {
+			return nil, err
+		}
+		session.Lines = append(session.Lines, line)
+	}
+	return session, rows.Err()
+}
+
+// Scan adds quantity to the scanned count of the product with the given
+// barcode, dated expiresOn unless it is empty
+func (r *ReceivingRepository) Scan(sessionID int, barcode string, quantity int, expiresOn string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var purchaseOrderID int
+	err = tx.QueryRow("SELECT status, purchase_order_id FROM receiving_session WHERE id = $1 FOR UPDATE", sessionID).Scan(&status, &purchaseOrderID)
+	if err != nil {
+		return err
+	}
+	if status != "open" {
+		return ErrSessionNotOpen
+	}
+
+	var productID int
+	err = tx.QueryRow(`
+		SELECT p.id
+		FROM product p
+		INNER JOIN purchase_order_item poi ON poi.product_id = p.id AND poi.purchase_order_id = $1
+		WHERE p.barcode = $2 AND p.deleted_at IS NULL
+	`, purchaseOrderID, barcode).Scan(&productID)
+	if err == sql.ErrNoRows {
+		return ErrProductNotOnOrder
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO receiving_session_item (session_id, product_id, scanned_qty) VALUES ($1, $2, $3)
+		ON CONFLICT (session_id, product_id) DO UPDATE SET scanned_qty = receiving_session_item.scanned_qty + EXCLUDED.scanned_qty
+	`, sessionID, productID, quantity)
+	if err != nil {
+		return err
+	}
+
+	if expiresOn != "" {
+		_, err = tx.Exec(`
+			INSERT INTO receiving_session_batch (session_id, product_id, expires_on, quantity) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (session_id, product_id, expires_on) DO UPDATE SET quantity = receiving_session_batch.quantity + EXCLUDED.quantity
+		`, sessionID, productID, expiresOn, quantity)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Commit adds all scanned quantities to stock and to the purchase order,
+// records a goods receipt and closes the session
+func (r *ReceivingRepository) Commit(sessionID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var purchaseOrderID int
+	err = tx.QueryRow("SELECT status, purchase_order_id FROM receiving_session WHERE id = $1 FOR UPDATE", sessionID).Scan(&status, &purchaseOrderID)
+	if err != nil {
+		return err
+	}
+	if status != "open" {
+		return ErrSessionNotOpen
+	}
+
+	var receiptID int
+	err = tx.QueryRow(
+		"INSERT INTO goods_receipt (purchase_order_id, session_id) VALUES ($1, $2) RETURNING id",
+		purchaseOrderID, sessionID,
+	).Scan(&receiptID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO goods_receipt_item (receipt_id, product_id, quantity, unit_cost)
+		SELECT $1, rsi.product_id, rsi.scanned_qty, poi.unit_cost
+		FROM receiving_session_item rsi
+		INNER JOIN purchase_order_item poi ON poi.product_id = rsi.product_id AND poi.purchase_order_id = $2
+		WHERE rsi.session_id = $3 AND rsi.scanned_qty > 0
+	`, receiptID, purchaseOrderID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE product p SET stock = p.stock + rsi.scanned_qty
+		FROM receiving_session_item rsi
+		WHERE rsi.product_id = p.id AND rsi.session_id = $1
+	`, sessionID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO stock_movement (product_id, quantity, balance_after, reason, reference_id)
+		SELECT p.id, rsi.scanned_qty, p.stock, $1, $2
+		FROM receiving_session_item rsi
+		INNER JOIN product p ON p.id = rsi.product_id
+		WHERE rsi.session_id = $3 AND rsi.scanned_qty > 0
+	`, MovementReceipt, receiptID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	// every product row was locked by the stock update above
+	_, err = tx.Exec(`
+		INSERT INTO stock_batch (product_id, quantity, expires_on, receipt_id)
+		SELECT product_id, quantity, expires_on, $1
+		FROM receiving_session_batch
+		WHERE session_id = $2 AND quantity > 0
+		ORDER BY product_id, expires_on
+	`, receiptID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE purchase_order_item poi SET quantity_received = poi.quantity_received + rsi.scanned_qty
+		FROM receiving_session_item rsi
+		WHERE rsi.product_id = poi.product_id AND rsi.session_id = $1 AND poi.purchase_order_id = $2
+	`, sessionID, purchaseOrderID)
+	if err != nil {
+		return err
+	}
+
+	// closes the order once every item arrived, otherwise the rest stays back-ordered
+	_, err = tx.Exec(`
+		UPDATE purchase_order po SET
+			status = CASE WHEN totals.remaining = 0 THEN 'received' ELSE 'partially_received' END,
+			closed_at = CASE WHEN totals.remaining = 0 THEN NOW() ELSE NULL END
+		FROM (
+			SELECT SUM(GREATEST(quantity_ordered - quantity_received, 0)) as remaining, SUM(quantity_received) as received
+			FROM purchase_order_item
+			WHERE purchase_order_id = $1
+		) totals
+		WHERE po.id = $1 AND totals.received > 0
+	`, purchaseOrderID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE receiving_session SET status = 'committed', committed_at = NOW() WHERE id = $1", sessionID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
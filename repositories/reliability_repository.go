@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"database/sql"
+	"kasir-api/models"
+	"time"
+)
+
+type ReliabilityRepository struct {
+	db *sql.DB
+}
+
+func NewReliabilityRepository(db *sql.DB) *ReliabilityRepository {
+	return &ReliabilityRepository{db: db}
+}
+
+// AddHTTPStats adds request and server error counts to the totals of day,
+// given as YYYY-MM-DD
+func (r *ReliabilityRepository) AddHTTPStats(day string, requests, serverErrors int64) error {
+	_, err := r.db.Exec(`
+		INSERT INTO http_daily_stats (day, requests, server_errors) VALUES ($1, $2, $3)
+		ON CONFLICT (day) DO UPDATE SET
+			requests = http_daily_stats.requests + excluded.requests,
+			server_errors = http_daily_stats.server_errors + excluded.server_errors
+	`, day, requests, serverErrors)
+	return err
+}
+
+// GetDaily returns the failures of each of the last days days, oldest first,
+// including days where nothing happened. Webhook deliveries count once they
+// have run out of retries; their jobs, of type webhookJobType, are left out of
+// the job failures so the same failure isn't counted twice. Gateway errors are reminders that reached
+// a gateway and were refused, not those that had no recipient or sender.
+func (r *ReliabilityRepository) GetDaily(days int, webhookJobType string) ([]models.ReliabilityDay, error) {
+	rows, err := r.db.Query(`
+		WITH day AS (
+			SELECT d::date AS day FROM generate_series(CURRENT_DATE - ($1::int - 1), CURRENT_DATE, INTERVAL '1 day') d
+		),
+		webhook_failures AS (
+			SELECT created_at::date AS day, COUNT(*) AS n FROM webhook_delivery
+			WHERE status = 'failed' AND created_at >= CURRENT_DATE - ($1::int - 1)
+			GROUP BY 1
+		),
+		job_failures AS (
+			SELECT finished_at::date AS day, COUNT(*) AS n FROM job
+			WHERE status = 'failed' AND type <> $2 AND finished_at >= CURRENT_DATE - ($1::int - 1)
+			GROUP BY 1
+		),
+		gateway_errors AS (
+			SELECT sent_at::date AS day, COUNT(*) AS n FROM reminder_history
+			WHERE status = 'failed' AND message <> '' AND sent_at >= CURRENT_DATE - ($1::int - 1)
+			GROUP BY 1
+		)
+		SELECT day.day, COALESCE(w.n, 0), COALESCE(j.n, 0), COALESCE(g.n, 0), COALESCE(h.requests, 0), COALESCE(h.server_errors, 0)
+		FROM day
+		LEFT JOIN webhook_failures w ON w.day = day.day
+		LEFT JOIN job_failures j ON j.day = day.day
+		LEFT JOIN gateway_errors g ON g.day = day.day
+		LEFT JOIN http_daily_stats h ON h.day = day.day
+		ORDER BY day.day
+	`, days, webhookJobType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trend := []models.ReliabilityDay{}
+	for rows.Next() {
+		var d models.ReliabilityDay
+		var day time.Time
+		if err := rows.Scan(&day, &d.WebhookFailures, &d.JobFailures, &d.GatewayErrors, &d.Requests, &d.ServerErrors); err != nil {
+			return nil, err
+		}
+		d.Date = day.Format("2006-01-02")
+		trend = append(trend, d)
+	}
+	return trend, rows.Err()
+}
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"database/sql"
+	"kasir-api/models"
+	"kasir-api/money"
+)
+
+type ReorderRepository struct {
+	db *sql.DB
+}
+
+func NewReorderRepository(db *sql.DB) *ReorderRepository {
+	return &ReorderRepository{db: db}
+}
+
+// GetSuggestions retrieves products at or below their reorder point, counting
+// quantities still back-ordered on open purchase orders as stock, together with
+// the cheapest current supplier price, if any. A items come first.
+func (r *ReorderRepository) GetSuggestions() ([]models.ReorderSuggestion, error) {
+	query := `
+		SELECT 
+			p.id, p.name, p.abc_class, p.stock, COALESCE(on_order.quantity, 0), p.reorder_point, p.reorder_qty,
+			cheapest.id, cheapest.supplier_id, cheapest.supplier_name, cheapest.price, cheapest.updated_at
+		FROM product p
+		LEFT JOIN LATERAL (
+			SELECT sp.id, sp.supplier_id, s.name as supplier_name, sp.price, sp.updated_at
+			FROM supplier_price sp
+			INNER JOIN supplier s ON sp.supplier_id = s.id AND s.deleted_at IS NULL
+			WHERE sp.product_id = p.id
+			ORDER BY sp.price, sp.updated_at DESC
+			LIMIT 1
+		) cheapest ON true
+		LEFT JOIN (
+			SELECT poi.product_id, SUM(GREATEST(poi.quantity_ordered - poi.quantity_received, 0)) as quantity
+			FROM purchase_order_item poi
+			INNER JOIN purchase_order po ON poi.purchase_order_id = po.id
+			WHERE po.status IN ('open', 'partially_received')
+			GROUP BY poi.product_id
+		) on_order ON on_order.product_id = p.id
+		WHERE p.deleted_at IS NULL AND NOT p.is_bundle
+			AND p.reorder_point > 0
+			AND p.stock + COALESCE(on_order.quantity, 0) <= p.reorder_point
+		ORDER BY p.abc_class, p.stock + COALESCE(on_order.quantity, 0) - p.reorder_point, p.name
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []models.ReorderSuggestion{}
+	for rows.Next() {
+		var s models.ReorderSuggestion
+		var reorderQty int
+		var priceID, supplierID, price sql.NullInt64
+		var supplierName sql.NullString
+		var updatedAt sql.NullTime
+		err := rows.Scan(
+			&s.ProductID, &s.ProductName, &s.ABCClass, &s.Stock, &s.OnOrder, &s.ReorderPoint, &reorderQty,
+			&priceID, &supplierID, &supplierName, &price, &updatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		s.SuggestedQty = reorderQty
+		if priceID.Valid {
+			s.CheapestSupplier = &models.SupplierPrice{
+				ID:           int(priceID.Int64),
+				SupplierID:   int(supplierID.Int64),
+				SupplierName: supplierName.String,
+				ProductID:    s.ProductID,
+				ProductName:  s.ProductName,
+				Price:        money.Amount(price.Int64),
+			}
+			if updatedAt.Valid {
+				s.CheapestSupplier.UpdatedAt = updatedAt.Time.Format("2006-01-02 15:04:05")
+			}
+		}
+
+		suggestions = append(suggestions, s)
+	}
+	return suggestions, rows.Err()
+}
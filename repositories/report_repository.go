@@ -2,68 +2,200 @@ package repositories
 
 import (
 	"database/sql"
+	"time"
+
 	"kasir-api/models"
+	"kasir-api/money"
+
+	"github.com/lib/pq"
 )
 
+// ReportRepository reads the reports of ranges [start, end) of time, whose
+// sales are grouped into the days of a time zone whatever the time zone of
+// the server or the database session is
 type ReportRepository struct {
-	db *sql.DB
+	db       *sql.DB
+	settings SettingsReader
 }
 
-func NewReportRepository(db *sql.DB) *ReportRepository {
-	return &ReportRepository{db: db}
+// NewReportRepository reports in the store time zone of settings unless
+// asked for another; the daily summaries are of its days
+func NewReportRepository(db *sql.DB, settings SettingsReader) *ReportRepository {
+	return &ReportRepository{db: db, settings: settings}
 }
 
-// GetDailySalesReport retrieves sales report for today
-func (r *ReportRepository) GetDailySalesReport() (*models.SalesReport, error) {
-	// Get current date from database (which is already in Asia/Jakarta timezone)
-	var today string
-	err := r.db.QueryRow("SELECT CURRENT_DATE::text").Scan(&today)
-	if err != nil {
-		return nil, err
+// Timezone returns the store time zone
+func (r *ReportRepository) Timezone() string {
+	return r.settings.Settings().Timezone
+}
+
+// aggregated returns the CTE listing the days of [$1, $2) that daily
+// summaries can be read for: only in the store time zone, whose days they
+// are, as other days span two of them
+func (r *ReportRepository) aggregated(tz string) string {
+	if tz != r.Timezone() {
+		return noAggregatedDays
 	}
+	return aggregatedDays
+}
 
-	startOfDay := today + " 00:00:00"
-	endOfDay := today + " 23:59:59"
+// inRange is the condition of a timestamp column falling in [$1, $2)
+func inRange(column string) string {
+	return column + " >= $1 AND " + column + " < $2"
+}
 
-	return r.GetSalesReportByDateRange(startOfDay, endOfDay)
+// settled is the condition of a sale of table, or its alias, counting as
+// revenue: paid or completed, not a draft or still waiting for payment
+func settled(table string) string {
+	return table + ".status IN ('" + TransactionPaid + "', '" + TransactionCompleted + "')"
 }
 
-// GetSalesReportByDateRange retrieves sales report for a specific date range
-func (r *ReportRepository) GetSalesReportByDateRange(startDate, endDate string) (*models.SalesReport, error) {
+// aggregatedDays lists the days of the range [$1, $2), in time zone $3,
+// already rolled up into daily_sales_summary. Today is never aggregated, so
+// it is always read live.
+const aggregatedDays = `
+	aggregated AS (
+		SELECT date FROM daily_sales_summary
+		WHERE date >= ($1::timestamptz AT TIME ZONE $3)::date AND date < ($2::timestamptz AT TIME ZONE $3)::date
+			AND date < (NOW() AT TIME ZONE $3)::date
+	)
+`
+
+// noAggregatedDays stands in for aggregatedDays when every day is read live
+const noAggregatedDays = `
+	aggregated AS (
+		SELECT NULL::date AS date WHERE FALSE
+	)
+`
+
+// productSales unions the aggregated per-product sales with live sales lines
+// of the days not aggregated yet; bundles count as their components
+var productSales = `
+	product_sales AS (
+		SELECT ps.date, ps.product_id, ps.qty_sold, ps.revenue, ps.cost
+		FROM daily_product_sales ps
+		INNER JOIN aggregated a ON a.date = ps.date
+		UNION ALL
+		SELECT (t.created_at AT TIME ZONE $3)::date, sl.product_id, sl.quantity, sl.subtotal, sl.cost
+		FROM product_sales_line sl
+		INNER JOIN transactions t ON sl.transaction_id = t.id
+		WHERE ` + inRange("t.created_at") + `
+			AND t.deleted_at IS NULL AND ` + settled("t") + `
+			AND (t.created_at AT TIME ZONE $3)::date NOT IN (SELECT date FROM aggregated)
+	)
+`
+
+// GetSalesReportByDateRange retrieves sales report for [start, end), days
+// in tz
+func (r *ReportRepository) GetSalesReportByDateRange(start, end time.Time, tz string) (*models.SalesReport, error) {
 	report := &models.SalesReport{}
 
 	// Get total revenue and transaction count
 	query := `
+		WITH ` + r.aggregated(tz) + `,
+		live AS (
+			SELECT COALESCE(SUM(total_amount), 0) as revenue, COUNT(*) as transactions
+			FROM transactions
+			WHERE ` + inRange("created_at") + `
+				AND deleted_at IS NULL AND ` + settled("transactions") + `
+				AND (created_at AT TIME ZONE $3)::date NOT IN (SELECT date FROM aggregated)
+		),
+		summary AS (
+			SELECT COALESCE(SUM(s.total_revenue), 0) as revenue, COALESCE(SUM(s.total_transactions), 0) as transactions
+			FROM daily_sales_summary s
+			INNER JOIN aggregated a ON a.date = s.date
+		)
 		SELECT 
-			COALESCE(SUM(total_amount), 0) as total_revenue,
-			COUNT(*) as total_transaksi
-		FROM transactions
-		WHERE created_at >= $1 AND created_at <= $2
-			AND deleted_at IS NULL
+			summary.revenue + live.revenue as total_revenue,
+			summary.transactions + live.transactions as total_transaksi
+		FROM summary, live
 	`
 
-	err := r.db.QueryRow(query, startDate, endDate).Scan(&report.TotalRevenue, &report.TotalTransaksi)
+	err := r.db.QueryRow(query, start, end, tz).Scan(&report.TotalRevenue, &report.TotalTransaksi)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get top selling product
-	topProductQuery := `
-		SELECT 
-			p.name,
-			SUM(td.quantity) as qty_terjual
+	// rounding isn't rolled up into the summaries, it is part of their revenue
+	err = r.db.QueryRow(`
+		SELECT COALESCE(SUM(rounding_adjustment), 0) FROM transactions
+		WHERE `+inRange("created_at")+` AND deleted_at IS NULL AND `+settled("transactions")+`
+	`, start, end).Scan(&report.RoundingAdjustment)
+	if err != nil {
+		return nil, err
+	}
+
+	// what the revenue was paid in; sales without a recorded payment aren't counted
+	paymentRows, err := r.db.Query(`
+		SELECT p.currency, COUNT(*), SUM(p.amount), SUM(p.base_amount), SUM(p.change)
+		FROM transaction_payment p
+		INNER JOIN transactions t ON p.transaction_id = t.id
+		WHERE `+inRange("t.created_at")+`
+			AND t.deleted_at IS NULL AND `+settled("t")+`
+		GROUP BY p.currency
+		ORDER BY SUM(p.base_amount) DESC
+	`, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer paymentRows.Close()
+
+	report.Payments = []models.CurrencyTakings{}
+	for paymentRows.Next() {
+		var c models.CurrencyTakings
+		if err := paymentRows.Scan(&c.Currency, &c.Transactions, &c.Amount, &c.BaseAmount, &c.Change); err != nil {
+			return nil, err
+		}
+		report.Payments = append(report.Payments, c)
+	}
+	if err := paymentRows.Err(); err != nil {
+		return nil, err
+	}
+
+	// open items have no product, they are reported by description
+	openItemRows, err := r.db.Query(`
+		SELECT td.description, COUNT(*), SUM(td.quantity), SUM(td.subtotal)
 		FROM transaction_details td
 		INNER JOIN transactions t ON td.transaction_id = t.id
-		INNER JOIN product p ON td.product_id = p.id
-		WHERE t.created_at >= $1 AND t.created_at <= $2
-			AND t.deleted_at IS NULL
-		GROUP BY p.id, p.name
+		WHERE td.product_id IS NULL
+			AND `+inRange("t.created_at")+`
+			AND t.deleted_at IS NULL AND `+settled("t")+`
+		GROUP BY td.description
+		ORDER BY SUM(td.subtotal) DESC, td.description
+	`, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer openItemRows.Close()
+
+	report.OpenItems = []models.OpenItemSales{}
+	for openItemRows.Next() {
+		var o models.OpenItemSales
+		if err := openItemRows.Scan(&o.Description, &o.Lines, &o.Quantity, &o.Revenue); err != nil {
+			return nil, err
+		}
+		report.OpenItems = append(report.OpenItems, o)
+	}
+	if err := openItemRows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Get top selling product; products deleted since count as sold
+	topProductQuery := `
+		WITH ` + r.aggregated(tz) + `, ` + productSales + `
+		SELECT 
+			COALESCE(p.name, ''),
+			SUM(ps.qty_sold) as qty_terjual,
+			p.id IS NULL OR p.deleted_at IS NOT NULL
+		FROM product_sales ps
+		LEFT JOIN product p ON ps.product_id = p.id
+		GROUP BY ps.product_id, p.id, p.name, p.deleted_at
 		ORDER BY qty_terjual DESC
 		LIMIT 1
 	`
 
 	var topProduct models.TopProduct
-	err = r.db.QueryRow(topProductQuery, startDate, endDate).Scan(&topProduct.Nama, &topProduct.QtyTerjual)
+	err = r.db.QueryRow(topProductQuery, start, end, tz).Scan(&topProduct.Nama, &topProduct.QtyTerjual, &topProduct.Deleted)
 	if err == sql.ErrNoRows {
 		// No transactions in this period, return report with null top product
 		return report, nil
@@ -75,3 +207,427 @@ func (r *ReportRepository) GetSalesReportByDateRange(startDate, endDate string)
 	report.ProdukTerlaris = &topProduct
 	return report, nil
 }
+
+// GetProductSales retrieves the quantity and revenue of every product sold
+// in [start, end), days in tz, best sellers first
+func (r *ReportRepository) GetProductSales(start, end time.Time, tz string) ([]models.ComparedProduct, error) {
+	rows, err := r.db.Query(`
+		WITH `+r.aggregated(tz)+`, `+productSales+`
+		SELECT 
+			ps.product_id,
+			COALESCE(p.name, ''),
+			SUM(ps.qty_sold) as qty_terjual,
+			SUM(ps.revenue) as revenue,
+			p.id IS NULL OR p.deleted_at IS NOT NULL
+		FROM product_sales ps
+		LEFT JOIN product p ON ps.product_id = p.id
+		GROUP BY ps.product_id, p.id, p.name, p.deleted_at
+		ORDER BY revenue DESC, ps.product_id
+	`, start, end, tz)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []models.ComparedProduct{}
+	for rows.Next() {
+		var p models.ComparedProduct
+		if err := rows.Scan(&p.ProductID, &p.Nama, &p.QtyTerjual, &p.Revenue, &p.Deleted); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+// GetPriceContractSalesReport retrieves what was sold under each price
+// contract in [start, end), contracts deleted since included
+func (r *ReportRepository) GetPriceContractSalesReport(start, end time.Time) (*models.PriceContractSalesReport, error) {
+	rows, err := r.db.Query(`
+		SELECT pc.id, pc.name, pc.customer_id, cu.name,
+		       COUNT(DISTINCT t.id), SUM(td.quantity), SUM(td.subtotal), SUM(td.discount)
+		FROM transaction_details td
+		INNER JOIN transactions t ON td.transaction_id = t.id
+		INNER JOIN price_contract pc ON td.price_contract_id = pc.id
+		INNER JOIN customer cu ON pc.customer_id = cu.id
+		WHERE `+inRange("t.created_at")+`
+			AND t.deleted_at IS NULL AND `+settled("t")+`
+		GROUP BY pc.id, pc.name, pc.customer_id, cu.name
+		ORDER BY SUM(td.subtotal) DESC, pc.id
+	`, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &models.PriceContractSalesReport{Contracts: []models.PriceContractSales{}}
+	for rows.Next() {
+		var c models.PriceContractSales
+		if err := rows.Scan(&c.ContractID, &c.Name, &c.CustomerID, &c.CustomerName, &c.Transactions, &c.QtySold, &c.Revenue, &c.Discount); err != nil {
+			return nil, err
+		}
+		report.TotalRevenue += c.Revenue
+		report.TotalDiscount += c.Discount
+		report.Contracts = append(report.Contracts, c)
+	}
+	return report, rows.Err()
+}
+
+// GetProfitReport retrieves gross profit per product and per day, days in
+// tz, for [start, end)
+func (r *ReportRepository) GetProfitReport(start, end time.Time, tz string) (*models.ProfitReport, error) {
+	report := &models.ProfitReport{
+		Products: []models.ProductProfit{},
+		Periods:  []models.PeriodProfit{},
+	}
+
+	productQuery := `
+		WITH ` + r.aggregated(tz) + `, ` + productSales + `
+		SELECT 
+			ps.product_id,
+			COALESCE(p.name, ''),
+			SUM(ps.qty_sold) as qty_terjual,
+			SUM(ps.revenue) as revenue,
+			SUM(ps.cost) as cost,
+			p.id IS NULL OR p.deleted_at IS NOT NULL
+		FROM product_sales ps
+		LEFT JOIN product p ON ps.product_id = p.id
+		GROUP BY ps.product_id, p.id, p.name, p.deleted_at
+		ORDER BY revenue DESC
+	`
+
+	rows, err := r.db.Query(productQuery, start, end, tz)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pp models.ProductProfit
+		if err := rows.Scan(&pp.ProductID, &pp.Nama, &pp.QtyTerjual, &pp.Revenue, &pp.Cost, &pp.Deleted); err != nil {
+			return nil, err
+		}
+		report.Products = append(report.Products, pp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	periodQuery := `
+		WITH ` + r.aggregated(tz) + `, ` + productSales + `
+		SELECT 
+			ps.date::text as tanggal,
+			SUM(ps.revenue) as revenue,
+			SUM(ps.cost) as cost
+		FROM product_sales ps
+		GROUP BY tanggal
+		ORDER BY tanggal
+	`
+
+	periodRows, err := r.db.Query(periodQuery, start, end, tz)
+	if err != nil {
+		return nil, err
+	}
+	defer periodRows.Close()
+
+	for periodRows.Next() {
+		var pp models.PeriodProfit
+		if err := periodRows.Scan(&pp.Tanggal, &pp.Revenue, &pp.Cost); err != nil {
+			return nil, err
+		}
+		report.Periods = append(report.Periods, pp)
+	}
+	if err := periodRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// GetProfitLossReport retrieves the sales, refunds, discounts, cost of goods
+// sold and expenses of [start, end), days in tz. Sales are read live, as
+// the daily summaries don't keep discounts or the sales refunded since.
+func (r *ReportRepository) GetProfitLossReport(start, end time.Time, tz string) (*models.ProfitLossReport, error) {
+	report := &models.ProfitLossReport{}
+
+	var sales money.Amount
+	err := r.db.QueryRow(`
+		SELECT COALESCE(SUM(total_amount), 0),
+		       COALESCE(SUM(rounding_adjustment), 0),
+		       COALESCE(SUM(total_amount) FILTER (WHERE status = $3), 0)
+		FROM transactions
+		WHERE `+inRange("created_at")+`
+			AND ((deleted_at IS NULL AND `+settled("transactions")+`) OR status = $3)
+	`, start, end, TransactionRefunded).Scan(&sales, &report.RoundingAdjustment, &report.Refunds)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.QueryRow(`
+		SELECT COALESCE(SUM(td.discount), 0)
+		FROM transaction_details td
+		INNER JOIN transactions t ON td.transaction_id = t.id
+		WHERE `+inRange("t.created_at")+`
+			AND ((t.deleted_at IS NULL AND `+settled("t")+`) OR t.status = $3)
+	`, start, end, TransactionRefunded).Scan(&report.Discounts)
+	if err != nil {
+		return nil, err
+	}
+	report.GrossSales = sales - report.RoundingAdjustment + report.Discounts
+
+	err = r.db.QueryRow(`
+		WITH `+r.aggregated(tz)+`, `+productSales+`
+		SELECT COALESCE(SUM(cost), 0) FROM product_sales
+	`, start, end, tz).Scan(&report.COGS)
+	if err != nil {
+		return nil, err
+	}
+
+	// expenses are dated by the store's days, not timestamped
+	err = r.db.QueryRow(`
+		SELECT COALESCE(SUM(amount), 0) FROM expense
+		WHERE deleted_at IS NULL
+			AND spent_on >= ($1::timestamptz AT TIME ZONE $3)::date
+			AND spent_on < ($2::timestamptz AT TIME ZONE $3)::date
+	`, start, end, tz).Scan(&report.Expenses)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// GetDailyTakings retrieves what the sales of each day of [start, end), in
+// the store time zone, came to and how they were paid; a voucher given back
+// by a void isn't counted
+func (r *ReportRepository) GetDailyTakings(start, end time.Time) ([]models.DailyTakings, error) {
+	rows, err := r.db.Query(`
+		SELECT (t.created_at AT TIME ZONE $3)::date::text AS date,
+		       COUNT(*),
+		       SUM(t.total_amount),
+		       COALESCE(SUM(v.amount), 0),
+		       COALESCE(SUM(t.total_amount) FILTER (WHERE pl.id IS NOT NULL), 0),
+		       COALESCE(SUM(ip.total_amount - ip.down_payment), 0)
+		FROM transactions t
+		LEFT JOIN voucher_redemption v ON v.transaction_id = t.id AND v.reversed_at IS NULL
+		LEFT JOIN payment_link pl ON pl.transaction_id = t.id
+		LEFT JOIN installment_plan ip ON ip.transaction_id = t.id
+		WHERE `+inRange("t.created_at")+`
+			AND t.deleted_at IS NULL AND `+settled("t")+`
+		GROUP BY date
+		ORDER BY date
+	`, start, end, r.Timezone())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	takings := []models.DailyTakings{}
+	for rows.Next() {
+		var d models.DailyTakings
+		if err := rows.Scan(&d.Date, &d.Transactions, &d.Total, &d.Voucher, &d.PaymentLink, &d.Installment); err != nil {
+			return nil, err
+		}
+		takings = append(takings, d)
+	}
+	return takings, rows.Err()
+}
+
+// shrinkageLosses lists the stock lost in [$1, $2) under the reasons in $3,
+// valued at the cost recorded on the write-off or else the current cost price
+var shrinkageLosses = `
+	losses AS (
+		SELECT m.product_id, m.reason, -m.quantity as quantity,
+		       -m.quantity * COALESCE(w.unit_cost, p.cost_price, 0) as value
+		FROM stock_movement m
+		LEFT JOIN product p ON m.product_id = p.id
+		LEFT JOIN stock_write_off w ON w.id = m.reference_id AND m.reason IN ('damaged', 'expired', 'supplier_return')
+		WHERE ` + inRange("m.created_at") + `
+			AND m.quantity < 0
+			AND m.reason = ANY($3)
+	)
+`
+
+// GetShrinkageReport retrieves stock losses per reason and per product, and
+// the cost of goods sold in [start, end), days in tz
+func (r *ReportRepository) GetShrinkageReport(start, end time.Time, tz string, reasons []string) (*models.ShrinkageReport, error) {
+	report := &models.ShrinkageReport{
+		Reasons:  []models.ShrinkageByReason{},
+		Products: []models.ProductShrinkage{},
+	}
+
+	rows, err := r.db.Query(`
+		WITH `+shrinkageLosses+`
+		SELECT reason, SUM(quantity), SUM(value)
+		FROM losses
+		GROUP BY reason
+		ORDER BY SUM(value) DESC
+	`, start, end, pq.Array(reasons))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sr models.ShrinkageByReason
+		if err := rows.Scan(&sr.Reason, &sr.Quantity, &sr.Value); err != nil {
+			return nil, err
+		}
+		report.Reasons = append(report.Reasons, sr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	productRows, err := r.db.Query(`
+		WITH `+shrinkageLosses+`
+		SELECT l.product_id, COALESCE(p.name, ''), SUM(l.quantity), SUM(l.value), p.id IS NULL OR p.deleted_at IS NOT NULL
+		FROM losses l
+		LEFT JOIN product p ON l.product_id = p.id
+		WHERE l.reason <> 'supplier_return'
+		GROUP BY l.product_id, p.id, p.name, p.deleted_at
+		ORDER BY SUM(l.value) DESC
+	`, start, end, pq.Array(reasons))
+	if err != nil {
+		return nil, err
+	}
+	defer productRows.Close()
+
+	for productRows.Next() {
+		var ps models.ProductShrinkage
+		if err := productRows.Scan(&ps.ProductID, &ps.Nama, &ps.Quantity, &ps.Value, &ps.Deleted); err != nil {
+			return nil, err
+		}
+		report.Products = append(report.Products, ps)
+	}
+	if err := productRows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = r.db.QueryRow(`
+		WITH `+r.aggregated(tz)+`, `+productSales+`
+		SELECT COALESCE(SUM(cost), 0) FROM product_sales
+	`, start, end, tz).Scan(&report.SalesCost)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// GetPendingAggregationDays returns the past days, in the store time zone,
+// that have transactions but no daily summary yet, oldest first
+func (r *ReportRepository) GetPendingAggregationDays() ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT (t.created_at AT TIME ZONE $1)::date::text as day
+		FROM transactions t
+		WHERE (t.created_at AT TIME ZONE $1)::date < (NOW() AT TIME ZONE $1)::date
+			AND NOT EXISTS (SELECT 1 FROM daily_sales_summary s WHERE s.date = (t.created_at AT TIME ZONE $1)::date)
+		ORDER BY day
+	`, r.Timezone())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []string{}
+	for rows.Next() {
+		var day string
+		if err := rows.Scan(&day); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
+
+// AggregateDay (re)builds the daily summary and per-product sales of one
+// day in the store time zone, of its paid and completed sales
+func (r *ReportRepository) AggregateDay(date string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// product rows are removed by the cascade
+	if _, err := tx.Exec("DELETE FROM daily_sales_summary WHERE date = $1", date); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO daily_sales_summary (date, total_revenue, total_transactions)
+		SELECT $1::date, COALESCE(SUM(total_amount), 0), COUNT(*)
+		FROM transactions
+		WHERE (created_at AT TIME ZONE $2)::date = $1::date AND deleted_at IS NULL AND `+settled("transactions")+`
+	`, date, r.Timezone())
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO daily_product_sales (date, product_id, qty_sold, revenue, cost)
+		SELECT $1::date, sl.product_id, SUM(sl.quantity), SUM(sl.subtotal), SUM(sl.cost)
+		FROM product_sales_line sl
+		INNER JOIN transactions t ON sl.transaction_id = t.id
+		WHERE (t.created_at AT TIME ZONE $2)::date = $1::date AND t.deleted_at IS NULL AND `+settled("t")+`
+		GROUP BY sl.product_id
+	`, date, r.Timezone())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetReceivablesAging retrieves outstanding installment amounts bucketed by days overdue, per customer
+func (r *ReportRepository) GetReceivablesAging() (*models.ReceivablesAging, error) {
+	report := &models.ReceivablesAging{Customers: []models.CustomerAging{}}
+
+	query := `
+		SELECT 
+			p.customer_id,
+			COALESCE(c.name, ''),
+			COALESCE(SUM(CASE WHEN CURRENT_DATE - i.due_date <= 0 THEN i.amount - i.paid_amount END), 0) as current,
+			COALESCE(SUM(CASE WHEN CURRENT_DATE - i.due_date BETWEEN 1 AND 30 THEN i.amount - i.paid_amount END), 0) as days_1_30,
+			COALESCE(SUM(CASE WHEN CURRENT_DATE - i.due_date BETWEEN 31 AND 60 THEN i.amount - i.paid_amount END), 0) as days_31_60,
+			COALESCE(SUM(CASE WHEN CURRENT_DATE - i.due_date BETWEEN 61 AND 90 THEN i.amount - i.paid_amount END), 0) as days_61_90,
+			COALESCE(SUM(CASE WHEN CURRENT_DATE - i.due_date > 90 THEN i.amount - i.paid_amount END), 0) as over_90
+		FROM installment i
+		INNER JOIN installment_plan p ON i.plan_id = p.id
+		LEFT JOIN customer c ON p.customer_id = c.id
+		WHERE i.paid_amount < i.amount
+		GROUP BY p.customer_id, c.name
+		ORDER BY c.name NULLS LAST
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ca models.CustomerAging
+		var customerID sql.NullInt64
+		if err := rows.Scan(&customerID, &ca.Nama, &ca.Current, &ca.Days1To30, &ca.Days31To60, &ca.Days61To90, &ca.Over90); err != nil {
+			return nil, err
+		}
+		if customerID.Valid {
+			id := int(customerID.Int64)
+			ca.CustomerID = &id
+		}
+		ca.Total = ca.Current + ca.Days1To30 + ca.Days31To60 + ca.Days61To90 + ca.Over90
+
+		report.Current += ca.Current
+		report.Days1To30 += ca.Days1To30
+		report.Days31To60 += ca.Days31To60
+		report.Days61To90 += ca.Days61To90
+		report.Over90 += ca.Over90
+		report.Total += ca.Total
+
+		report.Customers = append(report.Customers, ca)
+	}
+
+	return report, rows.Err()
+}
@@ -0,0 +1,85 @@
+package repositories_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"kasir-api/database"
+	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/repositories"
+)
+
+type benchSettings struct{}
+
+func (benchSettings) Settings() models.StoreSettings {
+	return models.StoreSettings{Timezone: "UTC"}
+}
+
+// BenchmarkAggregateDay rebuilds the daily summary and per-product sales of a
+// day of sales. The aggregation is written for Postgres, so it runs only
+// against the throwaway database at BENCH_DATABASE_URL, which it adds products
+// and sales of today to:
+//
+//	BENCH_DATABASE_URL=postgres://... go test -run '^$' -bench AggregateDay ./repositories
+func BenchmarkAggregateDay(b *testing.B) {
+	connStr := os.Getenv("BENCH_DATABASE_URL")
+	if connStr == "" {
+		b.Skip("BENCH_DATABASE_URL is not set")
+	}
+	db, err := database.Connect(connStr)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+	if err := database.Migrate(db); err != nil {
+		b.Fatal(err)
+	}
+
+	// SKUs and receipt numbers of each run are their own, so runs can repeat
+	// on the same database
+	run := time.Now().UnixNano()
+	products := repositories.NewProductRepository(db)
+	productIDs := make([]int, 50)
+	for i := range productIDs {
+		p, err := products.Create(models.Product{
+			Name:      fmt.Sprintf("Bench product %d", i+1),
+			SKU:       fmt.Sprintf("BENCH-%d-%06d", run, i+1),
+			Price:     money.Amount(5000 + 500*(i%20)),
+			CostPrice: money.Amount(4000 + 400*(i%20)),
+			Stock:     1 << 30,
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		productIDs[i] = p.ID
+	}
+
+	price := func(productID int, unitPrice, costPrice money.Amount, quantity int, override *models.PriceOverride) repositories.LinePrice {
+		return repositories.LinePrice{Subtotal: unitPrice.Times(quantity)}
+	}
+	transactions := repositories.NewTransactionRepository(db)
+	for n := range 500 {
+		items := []models.CheckoutItem{
+			{ProductID: productIDs[n%len(productIDs)], Quantity: 1},
+			{ProductID: productIDs[(n*7+3)%len(productIDs)], Quantity: 2},
+			{ProductID: productIDs[(n*13+5)%len(productIDs)], Quantity: 1},
+		}
+		receipt := fmt.Sprintf("BENCH/%d/%06d", run, n)
+		if _, err := transactions.CreateTransaction(context.Background(), items, 0, 0, receipt, repositories.TransactionCompleted, repositories.OrderNumber{}, price, nil, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	reports := repositories.NewReportRepository(db, benchSettings{})
+	date := time.Now().UTC().Format("2006-01-02")
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := reports.AggregateDay(date); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
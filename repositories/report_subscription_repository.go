@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"kasir-api/models"
+)
+
+const reportSubscriptionColumns = "id, email, frequency, format, last_sent_on, created_at"
+
+type ReportSubscriptionRepository struct {
+	db *sql.DB
+}
+
+func NewReportSubscriptionRepository(db *sql.DB) *ReportSubscriptionRepository {
+	return &ReportSubscriptionRepository{db: db}
+}
+
+func scanReportSubscription(row rowScanner) (models.ReportSubscription, error) {
+	var s models.ReportSubscription
+	var lastSentOn, createdAt sql.NullTime
+	err := row.Scan(&s.ID, &s.Email, &s.Frequency, &s.Format, &lastSentOn, &createdAt)
+	if err != nil {
+		return s, err
+	}
+	if lastSentOn.Valid {
+		s.LastSentOn = lastSentOn.Time.Format("2006-01-02")
+	}
+	if createdAt.Valid {
+		s.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return s, nil
+}
+
+// Save stores a subscription; subscribing an address again at the same
+// frequency changes its format
+func (r *ReportSubscriptionRepository) Save(s models.ReportSubscription) (models.ReportSubscription, error) {
+	return scanReportSubscription(r.db.QueryRow(`
+		INSERT INTO report_subscription (email, frequency, format) VALUES ($1, $2, $3)
+		ON CONFLICT (email, frequency) DO UPDATE SET format = EXCLUDED.format
+		RETURNING `+reportSubscriptionColumns,
+		s.Email, s.Frequency, s.Format))
+}
+
+func (r *ReportSubscriptionRepository) GetAll() ([]models.ReportSubscription, error) {
+	rows, err := r.db.Query("SELECT " + reportSubscriptionColumns + " FROM report_subscription ORDER BY email, frequency")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []models.ReportSubscription{}
+	for rows.Next() {
+		s, err := scanReportSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	return subscriptions, rows.Err()
+}
+
+// Delete removes a subscription, returning sql.ErrNoRows if there is none
+func (r *ReportSubscriptionRepository) Delete(id int) error {
+	result, err := r.db.Exec("DELETE FROM report_subscription WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// MarkSent records that the period ending on periodEnd was sent to a
+// subscription, reporting false if it already was
+func (r *ReportSubscriptionRepository) MarkSent(id int, periodEnd string) (bool, error) {
+	result, err := r.db.Exec(
+		"UPDATE report_subscription SET last_sent_on = $2 WHERE id = $1 AND (last_sent_on IS NULL OR last_sent_on < $2)",
+		id, periodEnd,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"kasir-api/models"
+)
+
+type RequestJournalRepository struct {
+	db *sql.DB
+}
+
+func NewRequestJournalRepository(db *sql.DB) *RequestJournalRepository {
+	return &RequestJournalRepository{db: db}
+}
+
+const requestJournalColumns = "id, method, path, query, terminal_id, request_headers, request_body, response_status, response_body, duration_ms, created_at"
+
+func scanJournalEntry(row rowScanner) (models.JournalEntry, error) {
+	var e models.JournalEntry
+	var headers []byte
+	var createdAt sql.NullTime
+	err := row.Scan(&e.ID, &e.Method, &e.Path, &e.Query, &e.TerminalID, &headers, &e.RequestBody, &e.ResponseStatus, &e.ResponseBody, &e.DurationMs, &createdAt)
+	if err != nil {
+		return models.JournalEntry{}, err
+	}
+
+	if err := json.Unmarshal(headers, &e.RequestHeaders); err != nil {
+		return models.JournalEntry{}, err
+	}
+	if createdAt.Valid {
+		e.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return e, nil
+}
+
+// Create stores an entry and drops the oldest ones beyond limit
+func (r *RequestJournalRepository) Create(e models.JournalEntry, limit int) error {
+	headers, err := json.Marshal(e.RequestHeaders)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO request_journal (method, path, query, terminal_id, request_headers, request_body, response_status, response_body, duration_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, e.Method, e.Path, e.Query, e.TerminalID, headers, e.RequestBody, e.ResponseStatus, e.ResponseBody, e.DurationMs)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM request_journal
+		WHERE id <= (SELECT id FROM request_journal ORDER BY id DESC OFFSET $1 LIMIT 1)
+	`, limit)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetAll retrieves entries newest first, optionally for one terminal and path
+func (r *RequestJournalRepository) GetAll(terminalID, path string, limit int) ([]models.JournalEntry, error) {
+	rows, err := r.db.Query(`
+		SELECT `+requestJournalColumns+` FROM request_journal
+		WHERE ($1 = '' OR terminal_id = $1) AND ($2 = '' OR path = $2)
+		ORDER BY id DESC
+		LIMIT $3
+	`, terminalID, path, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.JournalEntry{}
+	for rows.Next() {
+		e, err := scanJournalEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetByID retrieves an entry by ID
+func (r *RequestJournalRepository) GetByID(id int64) (models.JournalEntry, error) {
+	return scanJournalEntry(r.db.QueryRow("SELECT "+requestJournalColumns+" FROM request_journal WHERE id = $1", id))
+}
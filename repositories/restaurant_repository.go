@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"kasir-api/models"
+
+	"github.com/lib/pq"
+)
+
+// RestaurantRepository reads the open restaurant orders, the draft and
+// pending_payment sales carrying a table or queue number
+type RestaurantRepository struct {
+	db *sql.DB
+}
+
+func NewRestaurantRepository(db *sql.DB) *RestaurantRepository {
+	return &RestaurantRepository{db: db}
+}
+
+// GetOpenOrders returns the open orders with their lines, oldest first
+func (r *RestaurantRepository) GetOpenOrders() ([]models.KitchenOrder, error) {
+	rows, err := r.db.Query(`
+		SELECT id, COALESCE(receipt_number, ''), COALESCE(table_number, ''), COALESCE(queue_number, 0), status, created_at
+		FROM transactions
+		WHERE status IN ($1, $2) AND deleted_at IS NULL
+			AND (table_number IS NOT NULL OR queue_number IS NOT NULL)
+		ORDER BY id
+	`, TransactionDraft, TransactionPendingPayment)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []models.KitchenOrder{}
+	index := map[int]int{}
+	ids := []int64{}
+	for rows.Next() {
+		o := models.KitchenOrder{Items: []models.KitchenOrderItem{}}
+		var createdAt time.Time
+		if err := rows.Scan(&o.TransactionID, &o.ReceiptNumber, &o.TableNumber, &o.QueueNumber, &o.Status, &createdAt); err != nil {
+			return nil, err
+		}
+		o.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+		index[o.TransactionID] = len(orders)
+		ids = append(ids, int64(o.TransactionID))
+		orders = append(orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return orders, nil
+	}
+
+	itemRows, err := r.db.Query(`
+		SELECT td.transaction_id, COALESCE(p.name, td.description), td.quantity, td.ordered_at
+		FROM transaction_details td
+		LEFT JOIN product p ON td.product_id = p.id
+		WHERE td.transaction_id = ANY($1)
+		ORDER BY td.id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var transactionID int
+		var item models.KitchenOrderItem
+		var orderedAt sql.NullTime
+		if err := itemRows.Scan(&transactionID, &item.Name, &item.Quantity, &orderedAt); err != nil {
+			return nil, err
+		}
+		if orderedAt.Valid {
+			item.OrderedAt = orderedAt.Time.Format("2006-01-02 15:04:05")
+		}
+		o := &orders[index[transactionID]]
+		o.Items = append(o.Items, item)
+	}
+	return orders, itemRows.Err()
+}
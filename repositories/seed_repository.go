@@ -0,0 +1,39 @@
+package repositories
+
+import "database/sql"
+
+// SeedRepository holds the queries demo data needs beyond the regular
+// repositories; it works on PostgreSQL and on the SQLite file of a kiosk
+type SeedRepository struct {
+	db *sql.DB
+}
+
+func NewSeedRepository(db *sql.DB) *SeedRepository {
+	return &SeedRepository{db: db}
+}
+
+// CountTransactions counts the transactions whose receipt number starts with prefix
+func (r *SeedRepository) CountTransactions(prefix string) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM transactions WHERE receipt_number LIKE $1", prefix+"%").Scan(&count)
+	return count, err
+}
+
+// Backdate moves a transaction and the stock movements of its sale to at,
+// a local time formatted as "2006-01-02 15:04:05"
+func (r *SeedRepository) Backdate(transactionID int, at string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE transactions SET created_at = $1 WHERE id = $2", at, transactionID); err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE stock_movement SET created_at = $1 WHERE reason = $2 AND reference_id = $3", at, MovementSale, transactionID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
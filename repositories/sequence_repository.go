@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"kasir-api/models"
+)
+
+var ErrSequenceBehind = errors.New("next value must be greater than the last number already issued")
+
+// CatalogScope counts the changes to the catalog; receipt scopes always hold a "|"
+const CatalogScope = "catalog"
+
+type SequenceRepository struct {
+	db *sql.DB
+}
+
+func NewSequenceRepository(db *sql.DB) *SequenceRepository {
+	return &SequenceRepository{db: db}
+}
+
+// Next increments the counter of scope, starting it at 1, and returns the new
+// value. The upsert is atomic, so concurrent callers never get the same value.
+func (r *SequenceRepository) Next(scope string) (int64, error) {
+	var value int64
+	err := r.db.QueryRow(`
+		INSERT INTO receipt_sequence (scope, last_value) VALUES ($1, 1)
+		ON CONFLICT (scope) DO UPDATE SET last_value = receipt_sequence.last_value + 1
+		RETURNING last_value
+	`, scope).Scan(&value)
+	return value, err
+}
+
+// Last returns the last value handed out in scope, 0 when none was
+func (r *SequenceRepository) Last(scope string) (int64, error) {
+	var value int64
+	err := r.db.QueryRow("SELECT last_value FROM receipt_sequence WHERE scope = $1", scope).Scan(&value)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return value, err
+}
+
+// SetNext makes adj.NextValue the next value of adj.Scope and records adj in
+// the audit trail. Going back to a value already handed out is refused with
+// ErrSequenceBehind, so no number is issued twice.
+func (r *SequenceRepository) SetNext(adj models.NumberingAdjustment) (models.NumberingAdjustment, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.NumberingAdjustment{}, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("INSERT INTO receipt_sequence (scope, last_value) VALUES ($1, 0) ON CONFLICT (scope) DO NOTHING", adj.Scope)
+	if err != nil {
+		return models.NumberingAdjustment{}, err
+	}
+
+	var last int64
+	err = tx.QueryRow("SELECT last_value FROM receipt_sequence WHERE scope = $1 FOR UPDATE", adj.Scope).Scan(&last)
+	if err != nil {
+		return models.NumberingAdjustment{}, err
+	}
+	if adj.NextValue <= last {
+		return models.NumberingAdjustment{}, ErrSequenceBehind
+	}
+
+	_, err = tx.Exec("UPDATE receipt_sequence SET last_value = $1 WHERE scope = $2", adj.NextValue-1, adj.Scope)
+	if err != nil {
+		return models.NumberingAdjustment{}, err
+	}
+
+	adj.PreviousNext = last + 1
+	var createdAt sql.NullTime
+	err = tx.QueryRow(`
+		INSERT INTO numbering_adjustment (sequence, scope, previous_next, next_value, reason)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, adj.Sequence, adj.Scope, adj.PreviousNext, adj.NextValue, adj.Reason).Scan(&adj.ID, &createdAt)
+	if err != nil {
+		return models.NumberingAdjustment{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.NumberingAdjustment{}, err
+	}
+
+	if createdAt.Valid {
+		adj.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return adj, nil
+}
+
+// GetAdjustments returns the latest limit adjustments, newest first
+func (r *SequenceRepository) GetAdjustments(limit int) ([]models.NumberingAdjustment, error) {
+	rows, err := r.db.Query(`
+		SELECT id, sequence, scope, previous_next, next_value, reason, created_at
+		FROM numbering_adjustment
+		ORDER BY id DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	adjustments := []models.NumberingAdjustment{}
+	for rows.Next() {
+		var a models.NumberingAdjustment
+		var createdAt sql.NullTime
+		if err := rows.Scan(&a.ID, &a.Sequence, &a.Scope, &a.PreviousNext, &a.NextValue, &a.Reason, &createdAt); err != nil {
+			return nil, err
+		}
+		if createdAt.Valid {
+			a.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+		}
+		adjustments = append(adjustments, a)
+	}
+	return adjustments, rows.Err()
+}
@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"kasir-api/models"
+)
+
+// SettingsReader reads the store settings in force, which may change while
+// the server runs
+type SettingsReader interface {
+	Settings() models.StoreSettings
+}
+
+// SettingsRepository keeps the store settings, a single row
+type SettingsRepository struct {
+	db *sql.DB
+}
+
+func NewSettingsRepository(db *sql.DB) *SettingsRepository {
+	return &SettingsRepository{db: db}
+}
+
+// Get returns the saved settings, sql.ErrNoRows before they are first saved
+func (r *SettingsRepository) Get() (models.StoreSettings, error) {
+	var s models.StoreSettings
+	var updatedAt time.Time
+	err := r.db.QueryRow(
+		"SELECT name, address, npwp, receipt_footer, currency, tax_rate, timezone, updated_at FROM store_settings WHERE id = 1",
+	).Scan(&s.Name, &s.Address, &s.NPWP, &s.ReceiptFooter, &s.Currency, &s.TaxRate, &s.Timezone, &updatedAt)
+	if err != nil {
+		return models.StoreSettings{}, err
+	}
+	s.UpdatedAt = updatedAt.Format("2006-01-02 15:04:05")
+	return s, nil
+}
+
+// Save saves s in place of the settings. The daily summaries are dropped
+// when resetSummaries is set, as after a change of time zone they are of
+// days that no longer are the store's; they are rolled up again by the next
+// aggregation and read live until then.
+func (r *SettingsRepository) Save(s models.StoreSettings, resetSummaries bool) (models.StoreSettings, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.StoreSettings{}, err
+	}
+	defer tx.Rollback()
+
+	var updatedAt time.Time
+	err = tx.QueryRow(`
+		INSERT INTO store_settings (id, name, address, npwp, receipt_footer, currency, tax_rate, timezone)
+		VALUES (1, $1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name, address = EXCLUDED.address, npwp = EXCLUDED.npwp,
+			receipt_footer = EXCLUDED.receipt_footer, currency = EXCLUDED.currency,
+			tax_rate = EXCLUDED.tax_rate, timezone = EXCLUDED.timezone, updated_at = NOW()
+		RETURNING updated_at
+	`, s.Name, s.Address, s.NPWP, s.ReceiptFooter, s.Currency, s.TaxRate, s.Timezone).Scan(&updatedAt)
+	if err != nil {
+		return models.StoreSettings{}, err
+	}
+
+	// product rows are removed by the cascade
+	if resetSummaries {
+		if _, err := tx.Exec("DELETE FROM daily_sales_summary"); err != nil {
+			return models.StoreSettings{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.StoreSettings{}, err
+	}
+	s.UpdatedAt = updatedAt.Format("2006-01-02 15:04:05")
+	return s, nil
+}
+
+// HasSales reports whether any sale was ever recorded, whose amounts are in
+// the store currency
+func (r *SettingsRepository) HasSales() (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM transactions)").Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,288 @@
+package sqlite
+
+import (
+	"database/sql"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"strings"
+	"time"
+)
+
+type CategoryRepository struct {
+	db *sql.DB
+}
+
+func NewCategoryRepository(db *sql.DB) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+// GetAll retrieves all active categories, in the order of sort. With
+// updatedSince set only the categories updated from then on are retrieved,
+// deleted ones included.
+func (r *CategoryRepository) GetAll(sort string, updatedSince time.Time) ([]models.Category, error) {
+	args := []interface{}{}
+	query := "SELECT id, name, description, created_at, updated_at, deleted_at FROM category"
+	if updatedSince.IsZero() {
+		query += " WHERE deleted_at IS NULL"
+	} else {
+		args = append(args, updatedSince.In(time.Local).Format("2006-01-02 15:04:05"))
+		query += " WHERE updated_at >= $1"
+	}
+
+	rows, err := r.db.Query(query+repositories.CatalogOrderBy("", sort), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.Category
+	for rows.Next() {
+		var c models.Category
+		var createdAt, updatedAt, deletedAt sql.NullTime
+		if err := rows.Scan(&c.ID, &c.Name, &c.Description, &createdAt, &updatedAt, &deletedAt); err != nil {
+			return nil, err
+		}
+		c.CreatedAt = timePtr(createdAt)
+		c.UpdatedAt = timePtr(updatedAt)
+		c.DeletedAt = timePtr(deletedAt)
+		categories = append(categories, c)
+	}
+
+	return categories, rows.Err()
+}
+
+// isDuplicateCategoryName reports whether err is a violation of the unique
+// category name index; an index on an expression is named by SQLite
+func isDuplicateCategoryName(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed: index 'idx_category_name'")
+}
+
+func (r *CategoryRepository) Create(category models.Category) (models.Category, error) {
+	return r.save(category, createCategory)
+}
+
+// CreateBatch creates categories as Create does, all in one database
+// transaction; see repositories.SaveEach for errs and atomic
+func (r *CategoryRepository) CreateBatch(categories []models.Category, atomic bool) ([]models.Category, []error, error) {
+	return r.saveBatch(categories, atomic, createCategory)
+}
+
+// UpdateBatch updates categories as Update does, all in one database
+// transaction; see repositories.SaveEach for errs and atomic
+func (r *CategoryRepository) UpdateBatch(categories []models.Category, atomic bool) ([]models.Category, []error, error) {
+	return r.saveBatch(categories, atomic, updateCategory)
+}
+
+func (r *CategoryRepository) save(category models.Category, write func(tx *sql.Tx, category *models.Category) error) (models.Category, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.Category{}, err
+	}
+	defer tx.Rollback()
+
+	if err := write(tx, &category); err != nil {
+		return models.Category{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Category{}, err
+	}
+	return category, nil
+}
+
+func (r *CategoryRepository) saveBatch(categories []models.Category, atomic bool, write func(tx *sql.Tx, category *models.Category) error) ([]models.Category, []error, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	saved := append([]models.Category(nil), categories...)
+	errs, failed, err := repositories.SaveEach(tx, len(saved), atomic, func(i int) error {
+		return write(tx, &saved[i])
+	})
+	if err != nil || (atomic && failed) {
+		return nil, errs, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return saved, errs, nil
+}
+
+// createCategory inserts category in tx, filling in its ID and timestamps
+func createCategory(tx *sql.Tx, category *models.Category) error {
+	var createdAt, updatedAt sql.NullTime
+	err := tx.QueryRow(
+		"INSERT INTO category (name, description, created_at, updated_at) VALUES ($1, $2, datetime('now', 'localtime'), datetime('now', 'localtime')) RETURNING id, created_at, updated_at",
+		category.Name, category.Description,
+	).Scan(&category.ID, &createdAt, &updatedAt)
+	if isDuplicateCategoryName(err) {
+		return repositories.ErrDuplicateCategoryName
+	}
+	if err != nil {
+		return err
+	}
+	category.CreatedAt = timePtr(createdAt)
+	category.UpdatedAt = timePtr(updatedAt)
+	return nil
+}
+
+func (r *CategoryRepository) GetByID(id int) (models.Category, error) {
+	var c models.Category
+	var createdAt, updatedAt sql.NullTime
+	err := r.db.QueryRow(
+		"SELECT id, name, description, created_at, updated_at FROM category WHERE id = $1 AND deleted_at IS NULL",
+		id,
+	).Scan(&c.ID, &c.Name, &c.Description, &createdAt, &updatedAt)
+	if err != nil {
+		return models.Category{}, err
+	}
+	c.CreatedAt = timePtr(createdAt)
+	c.UpdatedAt = timePtr(updatedAt)
+	return c, nil
+}
+
+// GetByName retrieves the active category with name, ignoring ASCII case
+func (r *CategoryRepository) GetByName(name string) (models.Category, error) {
+	var c models.Category
+	var createdAt, updatedAt sql.NullTime
+	err := r.db.QueryRow(
+		"SELECT id, name, description, created_at, updated_at FROM category WHERE LOWER(name) = LOWER($1) AND deleted_at IS NULL",
+		name,
+	).Scan(&c.ID, &c.Name, &c.Description, &createdAt, &updatedAt)
+	if err != nil {
+		return models.Category{}, err
+	}
+	c.CreatedAt = timePtr(createdAt)
+	c.UpdatedAt = timePtr(updatedAt)
+	return c, nil
+}
+
+// Delete soft deletes a category, dealing with its active products as policy
+// says
+func (r *CategoryRepository) Delete(id int, policy string) (models.CategoryDeletion, error) {
+	deletion := models.CategoryDeletion{Policy: policy}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return deletion, err
+	}
+	defer tx.Rollback()
+
+	var found int
+	if err := tx.QueryRow("SELECT id FROM category WHERE id = $1 AND deleted_at IS NULL", id).Scan(&found); err != nil {
+		return deletion, err
+	}
+
+	if policy == repositories.CategoryDeleteBlock {
+		var products int
+		err := tx.QueryRow(`
+			SELECT COUNT(*) FROM product_category pc
+			INNER JOIN product p ON pc.product_id = p.id
+			WHERE pc.category_id = $1 AND p.deleted_at IS NULL
+		`, id).Scan(&products)
+		if err != nil {
+			return deletion, err
+		}
+		if products > 0 {
+			return deletion, &repositories.CategoryHasProductsError{Products: products}
+		}
+	} else {
+		stranded, err := strandedProducts(tx, id)
+		if err != nil {
+			return deletion, err
+		}
+
+		if len(stranded) > 0 && policy == repositories.CategoryDeleteCascade {
+			ids, args := inList(stranded, nil)
+			if _, err := tx.Exec("UPDATE product SET deleted_at = datetime('now', 'localtime'), updated_at = datetime('now', 'localtime') WHERE id IN ("+ids+")", args...); err != nil {
+				return deletion, err
+			}
+			deletion.ProductsDeleted = len(stranded)
+		}
+
+		if len(stranded) > 0 && policy == repositories.CategoryDeleteReassign {
+			err := tx.QueryRow("SELECT id FROM category WHERE LOWER(name) = LOWER($1) AND deleted_at IS NULL", repositories.UncategorizedName).Scan(&deletion.UncategorizedID)
+			if err == sql.ErrNoRows {
+				err = tx.QueryRow(
+					"INSERT INTO category (name, description) VALUES ($1, $2) RETURNING id",
+					repositories.UncategorizedName, "Products of deleted categories",
+				).Scan(&deletion.UncategorizedID)
+			}
+			if err != nil {
+				return deletion, err
+			}
+			if deletion.UncategorizedID == id {
+				return deletion, repositories.ErrDeleteUncategorized
+			}
+
+			for _, productID := range stranded {
+				if _, err := tx.Exec("INSERT OR IGNORE INTO product_category (product_id, category_id) VALUES ($1, $2)", productID, deletion.UncategorizedID); err != nil {
+					return deletion, err
+				}
+			}
+			ids, args := inList(stranded, nil)
+			if _, err := tx.Exec("UPDATE product SET updated_at = datetime('now', 'localtime') WHERE id IN ("+ids+")", args...); err != nil {
+				return deletion, err
+			}
+			deletion.ProductsReassigned = len(stranded)
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE category SET deleted_at = datetime('now', 'localtime'), updated_at = datetime('now', 'localtime') WHERE id = $1", id); err != nil {
+		return deletion, err
+	}
+	return deletion, tx.Commit()
+}
+
+// strandedProducts returns the active products of category categoryID that
+// have no other active category
+func strandedProducts(tx *sql.Tx, categoryID int) ([]int, error) {
+	rows, err := tx.Query(`
+		SELECT p.id FROM product_category pc
+		INNER JOIN product p ON pc.product_id = p.id
+		WHERE pc.category_id = $1 AND p.deleted_at IS NULL AND NOT EXISTS (
+			SELECT 1 FROM product_category o
+			INNER JOIN category c ON o.category_id = c.id
+			WHERE o.product_id = p.id AND o.category_id <> $1 AND c.deleted_at IS NULL
+		)
+		ORDER BY p.id
+	`, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *CategoryRepository) Update(category models.Category) (models.Category, error) {
+	return r.save(category, updateCategory)
+}
+
+// updateCategory updates category in tx, filling in its timestamps; an
+// unknown or deleted category is sql.ErrNoRows
+func updateCategory(tx *sql.Tx, category *models.Category) error {
+	var createdAt, updatedAt sql.NullTime
+	err := tx.QueryRow(
+		"UPDATE category SET name = $1, description = $2, updated_at = datetime('now', 'localtime') WHERE id = $3 AND deleted_at IS NULL RETURNING id, name, description, created_at, updated_at",
+		category.Name, category.Description, category.ID,
+	).Scan(&category.ID, &category.Name, &category.Description, &createdAt, &updatedAt)
+	if isDuplicateCategoryName(err) {
+		return repositories.ErrDuplicateCategoryName
+	}
+	if err != nil {
+		return err
+	}
+	category.CreatedAt = timePtr(createdAt)
+	category.UpdatedAt = timePtr(updatedAt)
+	return nil
+}
@@ -0,0 +1,141 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+const pricingRuleColumns = "r.id, r.product_id, p.name, r.name, r.min_quantity, r.price, r.active, r.created_at"
+
+type PricingRuleRepository struct {
+	db *sql.DB
+}
+
+func NewPricingRuleRepository(db *sql.DB) *PricingRuleRepository {
+	return &PricingRuleRepository{db: db}
+}
+
+func scanPricingRule(row rowScanner) (models.PricingRule, error) {
+	var rule models.PricingRule
+	var createdAt sql.NullTime
+	err := row.Scan(&rule.ID, &rule.ProductID, &rule.ProductName, &rule.Name, &rule.MinQuantity, &rule.Price, &rule.Active, &createdAt)
+	if err != nil {
+		return rule, err
+	}
+
+	if createdAt.Valid {
+		rule.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return rule, nil
+}
+
+func (r *PricingRuleRepository) query(where string, args ...interface{}) ([]models.PricingRule, error) {
+	rows, err := r.db.Query(`
+		SELECT `+pricingRuleColumns+`
+		FROM pricing_rule r
+		INNER JOIN product p ON r.product_id = p.id
+		WHERE r.deleted_at IS NULL AND `+where+`
+		ORDER BY r.product_id, r.min_quantity, r.id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []models.PricingRule{}
+	for rows.Next() {
+		rule, err := scanPricingRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// GetAll retrieves pricing rules, of one product when productID is not 0
+func (r *PricingRuleRepository) GetAll(productID int) ([]models.PricingRule, error) {
+	return r.query("($1 = 0 OR r.product_id = $1)", productID)
+}
+
+// GetActive retrieves the active rules of the products, keyed by product
+func (r *PricingRuleRepository) GetActive(productIDs []int) (map[int][]models.PricingRule, error) {
+	in, args := inList(productIDs, nil)
+	rules, err := r.query("r.active AND r.product_id IN ("+in+")", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	byProduct := make(map[int][]models.PricingRule)
+	for _, rule := range rules {
+		byProduct[rule.ProductID] = append(byProduct[rule.ProductID], rule)
+	}
+	return byProduct, nil
+}
+
+func (r *PricingRuleRepository) GetByID(id int) (models.PricingRule, error) {
+	return scanPricingRule(r.db.QueryRow(`
+		SELECT `+pricingRuleColumns+`
+		FROM pricing_rule r
+		INNER JOIN product p ON r.product_id = p.id
+		WHERE r.id = $1 AND r.deleted_at IS NULL
+	`, id))
+}
+
+// Create adds a rule to an active product
+func (r *PricingRuleRepository) Create(rule models.PricingRule) (models.PricingRule, error) {
+	var id int
+	err := r.db.QueryRow(`
+		INSERT INTO pricing_rule (product_id, name, min_quantity, price, active)
+		SELECT id, $2, $3, $4, $5 FROM product WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id
+	`, rule.ProductID, rule.Name, rule.MinQuantity, rule.Price, rule.Active).Scan(&id)
+	if err == sql.ErrNoRows {
+		return models.PricingRule{}, repositories.ErrProductNotFound
+	}
+	if err != nil {
+		return models.PricingRule{}, err
+	}
+	return r.GetByID(id)
+}
+
+// Update changes the terms of a rule; it stays on the same product
+func (r *PricingRuleRepository) Update(rule models.PricingRule) (models.PricingRule, error) {
+	result, err := r.db.Exec(
+		"UPDATE pricing_rule SET name = $1, min_quantity = $2, price = $3, active = $4 WHERE id = $5 AND deleted_at IS NULL",
+		rule.Name, rule.MinQuantity, rule.Price, rule.Active, rule.ID,
+	)
+	if err != nil {
+		return models.PricingRule{}, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.PricingRule{}, err
+	}
+
+	if rowsAffected == 0 {
+		return models.PricingRule{}, sql.ErrNoRows
+	}
+	return r.GetByID(rule.ID)
+}
+
+// Delete soft deletes a rule; lines already priced with it keep pointing at it
+func (r *PricingRuleRepository) Delete(id int) error {
+	result, err := r.db.Exec("UPDATE pricing_rule SET deleted_at = datetime('now', 'localtime') WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
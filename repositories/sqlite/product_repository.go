@@ -0,0 +1,502 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// productStock is the stock of p; a bundle has as many units as its scarcest component allows
+const productStock = `CASE WHEN p.is_bundle THEN COALESCE((
+		SELECT MIN(c.stock / b.quantity)
+		FROM product_bundle_item b
+		INNER JOIN product c ON b.component_id = c.id
+		WHERE b.bundle_id = p.id
+	), 0) ELSE p.stock END`
+
+const productColumns = "p.id, p.name, p.barcode, p.sku, p.price, p.cost_price, " + productStock + ", p.reorder_point, p.reorder_qty, p.abc_class, p.is_bundle, p.created_at, p.updated_at, p.deleted_at"
+
+// timePtr is t as a model timestamp, nil when NULL
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// scanProduct scans a row of productColumns
+func scanProduct(row rowScanner) (models.Product, error) {
+	var p models.Product
+	var createdAt, updatedAt, deletedAt sql.NullTime
+	if err := row.Scan(&p.ID, &p.Name, &p.Barcode, &p.SKU, &p.Price, &p.CostPrice, &p.Stock, &p.ReorderPoint, &p.ReorderQty, &p.ABCClass, &p.IsBundle, &createdAt, &updatedAt, &deletedAt); err != nil {
+		return models.Product{}, err
+	}
+	p.CreatedAt = timePtr(createdAt)
+	p.UpdatedAt = timePtr(updatedAt)
+	p.DeletedAt = timePtr(deletedAt)
+	return p, nil
+}
+
+type ProductRepository struct {
+	db    *sql.DB
+	stmts *repositories.Statements
+}
+
+func NewProductRepository(db *sql.DB) *ProductRepository {
+	return &ProductRepository{db: db, stmts: repositories.StatementsOf(db)}
+}
+
+// GetAll retrieves all active products, optionally filtered by name and ABC
+// class, in the order of sort. With updatedSince set only the products updated
+// from then on are retrieved, deleted ones included. Their categories are
+// embedded when expand has them, otherwise only their IDs are.
+func (r *ProductRepository) GetAll(name, abcClass, sort string, updatedSince time.Time, expand repositories.Expansion) ([]models.Product, error) {
+	args := []interface{}{}
+	query := "SELECT " + productColumns + " FROM product p"
+	if updatedSince.IsZero() {
+		query += " WHERE p.deleted_at IS NULL"
+	} else {
+		// timestamps are kept as local time text, which sorts like the time
+		args = append(args, updatedSince.In(time.Local).Format("2006-01-02 15:04:05"))
+		query += " WHERE p.updated_at >= $1"
+	}
+	if name != "" {
+		// LIKE is case-insensitive for ASCII in SQLite
+		args = append(args, "%"+name+"%")
+		query += fmt.Sprintf(" AND p.name LIKE $%d", len(args))
+	}
+	if abcClass != "" {
+		args = append(args, abcClass)
+		query += fmt.Sprintf(" AND p.abc_class = $%d", len(args))
+	}
+	query += repositories.CatalogOrderBy("p.", sort)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []models.Product
+	var ids []int
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+		ids = append(ids, p.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	categories, err := r.getCategories(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range products {
+		setProductCategories(&products[i], categories[products[i].ID])
+		if !expand[repositories.ExpandCategory] {
+			products[i].Categories = nil
+		}
+	}
+	return products, nil
+}
+
+func (r *ProductRepository) GetByID(id int) (models.Product, error) {
+	p, err := scanProduct(r.stmts.QueryRow("SELECT "+productColumns+" FROM product p WHERE p.id = $1 AND p.deleted_at IS NULL", id))
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	categories, err := r.getCategories([]int{p.ID})
+	if err != nil {
+		return models.Product{}, err
+	}
+	setProductCategories(&p, categories[p.ID])
+
+	if p.IsBundle {
+		p.Components, err = r.GetComponents(p.ID)
+		if err != nil {
+			return models.Product{}, err
+		}
+	}
+	return p, nil
+}
+
+// GetBySKU retrieves the active product with the given SKU
+func (r *ProductRepository) GetBySKU(sku string) (models.Product, error) {
+	var id int
+	err := r.stmts.QueryRow("SELECT id FROM product WHERE sku = $1 AND deleted_at IS NULL", sku).Scan(&id)
+	if err != nil {
+		return models.Product{}, err
+	}
+	return r.GetByID(id)
+}
+
+// isDuplicateSKU reports whether err is a violation of the unique SKU index;
+// SQLite names the column rather than the index
+func isDuplicateSKU(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed: product.sku")
+}
+
+// Create inserts a new product, logging its initial stock as an adjustment
+func (r *ProductRepository) Create(product models.Product) (models.Product, error) {
+	return r.save(product, createProduct)
+}
+
+// Update updates an existing product, logging a changed stock as an
+// adjustment. The stock of a bundle is derived from its components and is
+// left untouched.
+func (r *ProductRepository) Update(product models.Product) (models.Product, error) {
+	return r.save(product, updateProduct)
+}
+
+// CreateBatch creates products as Create does, all in one database
+// transaction; see repositories.SaveEach for errs and atomic
+func (r *ProductRepository) CreateBatch(products []models.Product, atomic bool) ([]models.Product, []error, error) {
+	return r.saveBatch(products, atomic, createProduct)
+}
+
+// UpdateBatch updates products as Update does, all in one database
+// transaction; see repositories.SaveEach for errs and atomic
+func (r *ProductRepository) UpdateBatch(products []models.Product, atomic bool) ([]models.Product, []error, error) {
+	return r.saveBatch(products, atomic, updateProduct)
+}
+
+func (r *ProductRepository) save(product models.Product, write func(tx *sql.Tx, product *models.Product) error) (models.Product, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.Product{}, err
+	}
+	defer tx.Rollback()
+
+	if err := write(tx, &product); err != nil {
+		return models.Product{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Product{}, err
+	}
+
+	categories, err := r.getCategories([]int{product.ID})
+	if err != nil {
+		return models.Product{}, err
+	}
+	setProductCategories(&product, categories[product.ID])
+	return product, nil
+}
+
+func (r *ProductRepository) saveBatch(products []models.Product, atomic bool, write func(tx *sql.Tx, product *models.Product) error) ([]models.Product, []error, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	saved := append([]models.Product(nil), products...)
+	errs, failed, err := repositories.SaveEach(tx, len(saved), atomic, func(i int) error {
+		return write(tx, &saved[i])
+	})
+	if err != nil || (atomic && failed) {
+		return nil, errs, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	ids := []int{}
+	for i := range saved {
+		if errs[i] == nil {
+			ids = append(ids, saved[i].ID)
+		}
+	}
+	categories, err := r.getCategories(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range saved {
+		if errs[i] == nil {
+			setProductCategories(&saved[i], categories[saved[i].ID])
+		}
+	}
+	return saved, errs, nil
+}
+
+// createProduct inserts product in tx, filling in its ID and timestamps
+func createProduct(tx *sql.Tx, product *models.Product) error {
+	var createdAt, updatedAt sql.NullTime
+	err := tx.QueryRow(`
+		INSERT INTO product (name, barcode, sku, price, cost_price, stock, reorder_point, reorder_qty, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, datetime('now', 'localtime'), datetime('now', 'localtime'))
+		RETURNING id, abc_class, created_at, updated_at
+	`, product.Name, product.Barcode, product.SKU, product.Price, product.CostPrice, product.Stock, product.ReorderPoint, product.ReorderQty,
+	).Scan(&product.ID, &product.ABCClass, &createdAt, &updatedAt)
+	if isDuplicateSKU(err) {
+		return repositories.ErrDuplicateSKU
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := setCategories(tx, product.ID, product.CategoryIDs); err != nil {
+		return err
+	}
+
+	if product.Stock != 0 {
+		if err := recordStockMovement(tx, product.ID, product.Stock, repositories.MovementAdjustment); err != nil {
+			return err
+		}
+	}
+
+	product.CreatedAt = timePtr(createdAt)
+	product.UpdatedAt = timePtr(updatedAt)
+	return nil
+}
+
+// updateProduct updates product in tx, filling in its timestamps
+func updateProduct(tx *sql.Tx, product *models.Product) error {
+	var previousStock int
+	var isBundle bool
+	err := tx.QueryRow("SELECT stock, is_bundle FROM product WHERE id = $1", product.ID).Scan(&previousStock, &isBundle)
+	if err != nil {
+		return err
+	}
+	if isBundle {
+		product.Stock = previousStock
+	}
+
+	var createdAt, updatedAt sql.NullTime
+	err = tx.QueryRow(
+		"UPDATE product SET name = $1, barcode = $2, sku = $3, price = $4, cost_price = $5, stock = $6, reorder_point = $7, reorder_qty = $8, updated_at = datetime('now', 'localtime') WHERE id = $9 RETURNING abc_class, created_at, updated_at",
+		product.Name, product.Barcode, product.SKU, product.Price, product.CostPrice, product.Stock, product.ReorderPoint, product.ReorderQty, product.ID,
+	).Scan(&product.ABCClass, &createdAt, &updatedAt)
+	if isDuplicateSKU(err) {
+		return repositories.ErrDuplicateSKU
+	}
+	if err != nil {
+		return err
+	}
+	product.IsBundle = isBundle
+
+	if err := setCategories(tx, product.ID, product.CategoryIDs); err != nil {
+		return err
+	}
+
+	if product.Stock != previousStock {
+		if err := recordStockMovement(tx, product.ID, product.Stock-previousStock, repositories.MovementAdjustment); err != nil {
+			return err
+		}
+	}
+
+	product.CreatedAt = timePtr(createdAt)
+	product.UpdatedAt = timePtr(updatedAt)
+	return nil
+}
+
+// getCategories retrieves the active categories of the given products, by product ID
+func (r *ProductRepository) getCategories(productIDs []int) (map[int][]models.Category, error) {
+	categories := make(map[int][]models.Category)
+	if len(productIDs) == 0 {
+		return categories, nil
+	}
+
+	list, args := inList(productIDs, nil)
+	rows, err := r.db.Query(`
+		SELECT pc.product_id, c.id, c.name, c.description
+		FROM product_category pc
+		INNER JOIN category c ON pc.category_id = c.id
+		WHERE pc.product_id IN (`+list+`) AND c.deleted_at IS NULL
+		ORDER BY c.name
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID int
+		var c models.Category
+		if err := rows.Scan(&productID, &c.ID, &c.Name, &c.Description); err != nil {
+			return nil, err
+		}
+		categories[productID] = append(categories[productID], c)
+	}
+	return categories, rows.Err()
+}
+
+// setProductCategories fills in the categories of p and their IDs
+func setProductCategories(p *models.Product, categories []models.Category) {
+	p.CategoryIDs = []int{}
+	p.Categories = categories
+	for _, c := range categories {
+		p.CategoryIDs = append(p.CategoryIDs, c.ID)
+	}
+}
+
+// setCategories replaces the categories of a product; categoryIDs hold no duplicates
+func setCategories(tx *sql.Tx, productID int, categoryIDs []int) error {
+	if _, err := tx.Exec("DELETE FROM product_category WHERE product_id = $1", productID); err != nil {
+		return err
+	}
+	for _, categoryID := range categoryIDs {
+		result, err := tx.Exec(
+			"INSERT INTO product_category (product_id, category_id) SELECT $1, id FROM category WHERE id = $2 AND deleted_at IS NULL",
+			productID, categoryID,
+		)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return repositories.ErrCategoryNotFound
+		}
+	}
+	return nil
+}
+
+// Delete soft deletes a product
+func (r *ProductRepository) Delete(id int) error {
+	result, err := r.db.Exec("UPDATE product SET deleted_at = datetime('now', 'localtime'), updated_at = datetime('now', 'localtime') WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AdjustStock adds quantity, negative to take stock, to the stock of an
+// active product that is not a bundle, logging it as an adjustment; stock
+// can't go below zero. Kiosks hold no batches.
+func (r *ProductRepository) AdjustStock(id, quantity int) (models.Product, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.Product{}, err
+	}
+	defer tx.Rollback()
+
+	var name string
+	var stock int
+	var isBundle bool
+	err = tx.QueryRow("SELECT name, stock, is_bundle FROM product WHERE id = $1 AND deleted_at IS NULL", id).Scan(&name, &stock, &isBundle)
+	if err != nil {
+		return models.Product{}, err
+	}
+	if isBundle {
+		return models.Product{}, repositories.ErrBundleStock
+	}
+	if stock+quantity < 0 {
+		return models.Product{}, &repositories.InsufficientStockError{ProductID: id, Name: name, Available: stock, Requested: -quantity}
+	}
+
+	if _, err := tx.Exec("UPDATE product SET stock = stock + $1, updated_at = datetime('now', 'localtime') WHERE id = $2", quantity, id); err != nil {
+		return models.Product{}, err
+	}
+	if err := recordStockMovement(tx, id, quantity, repositories.MovementAdjustment); err != nil {
+		return models.Product{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Product{}, err
+	}
+	return r.GetByID(id)
+}
+
+// GetComponents retrieves the products a bundle is made of
+func (r *ProductRepository) GetComponents(bundleID int) ([]models.BundleComponent, error) {
+	rows, err := r.db.Query(`
+		SELECT c.id, c.name, b.quantity, c.price, c.stock
+		FROM product_bundle_item b
+		INNER JOIN product c ON b.component_id = c.id
+		WHERE b.bundle_id = $1
+		ORDER BY c.name
+	`, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	components := []models.BundleComponent{}
+	for rows.Next() {
+		var c models.BundleComponent
+		if err := rows.Scan(&c.ProductID, &c.ProductName, &c.Quantity, &c.Price, &c.Stock); err != nil {
+			return nil, err
+		}
+		components = append(components, c)
+	}
+	return components, rows.Err()
+}
+
+// SetComponents replaces what a bundle is made of; with no components the
+// product is sold as a regular product again
+func (r *ProductRepository) SetComponents(bundleID int, components []models.BundleComponent) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var stock int
+	var isBundle bool
+	err = tx.QueryRow("SELECT stock, is_bundle FROM product WHERE id = $1 AND deleted_at IS NULL", bundleID).Scan(&stock, &isBundle)
+	if err != nil {
+		return err
+	}
+	if !isBundle && stock != 0 && len(components) > 0 {
+		return repositories.ErrBundleHasStock
+	}
+
+	// bundles don't nest, a component of another bundle can't become one
+	var isComponent bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM product_bundle_item WHERE component_id = $1)", bundleID).Scan(&isComponent)
+	if err != nil {
+		return err
+	}
+	if isComponent && len(components) > 0 {
+		return repositories.ErrInvalidBundleComponent
+	}
+
+	if _, err := tx.Exec("DELETE FROM product_bundle_item WHERE bundle_id = $1", bundleID); err != nil {
+		return err
+	}
+
+	for _, c := range components {
+		if c.ProductID == bundleID {
+			return repositories.ErrInvalidBundleComponent
+		}
+		result, err := tx.Exec(`
+			INSERT INTO product_bundle_item (bundle_id, component_id, quantity)
+			SELECT $1, id, $3 FROM product WHERE id = $2 AND NOT is_bundle AND deleted_at IS NULL
+		`, bundleID, c.ProductID, c.Quantity)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return repositories.ErrInvalidBundleComponent
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE product SET is_bundle = $1, updated_at = datetime('now', 'localtime') WHERE id = $2", len(components) > 0, bundleID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
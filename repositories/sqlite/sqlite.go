@@ -0,0 +1,43 @@
+// Package sqlite implements the sales stores of package repositories on
+// SQLite, for single-terminal kiosk installs that run without a database
+// server. Only queries that differ from the PostgreSQL ones live here; the
+// transaction and receipt sequence repositories use SQL both understand.
+package sqlite
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"kasir-api/repositories"
+)
+
+var (
+	_ repositories.CategoryStore    = (*CategoryRepository)(nil)
+	_ repositories.ProductStore     = (*ProductRepository)(nil)
+	_ repositories.PricingRuleStore = (*PricingRuleRepository)(nil)
+)
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// recordStockMovement logs a change of quantity already applied to the product row in tx
+func recordStockMovement(tx *sql.Tx, productID, quantity int, reason string) error {
+	_, err := tx.Exec(`
+		INSERT INTO stock_movement (product_id, quantity, balance_after, reason)
+		SELECT id, $2, stock, $3 FROM product WHERE id = $1
+	`, productID, quantity, reason)
+	return err
+}
+
+// inList returns "$n, $n+1, ..." placeholders for ids, appending them to args;
+// SQLite has no array parameters
+func inList(ids []int, args []interface{}) (string, []interface{}) {
+	placeholders := make([]string, len(ids))
+	for i, id := range ids {
+		args = append(args, id)
+		placeholders[i] = "$" + strconv.Itoa(len(args))
+	}
+	return strings.Join(placeholders, ", "), args
+}
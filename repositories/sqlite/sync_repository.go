@@ -0,0 +1,263 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// SyncRepository is the terminal side of offline sync: it hands out the sales
+// not yet pushed to the central server and applies the catalog pulled from it.
+// Catalog rows keep their central IDs, so the kiosk catalog is not edited
+// locally while syncing.
+type SyncRepository struct {
+	db *sql.DB
+}
+
+func NewSyncRepository(db *sql.DB) *SyncRepository {
+	return &SyncRepository{db: db}
+}
+
+// GetPendingTransactions retrieves up to limit sales not yet pushed, oldest
+// first, with the cost and bundle allocation of every line
+func (r *SyncRepository) GetPendingTransactions(limit int) ([]models.Transaction, error) {
+	rows, err := r.db.Query(
+		"SELECT id, COALESCE(receipt_number, ''), status, total_amount, rounding_adjustment, created_at FROM transactions WHERE synced_at IS NULL AND deleted_at IS NULL ORDER BY id LIMIT $1",
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := []models.Transaction{}
+	for rows.Next() {
+		var t models.Transaction
+		var createdAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.ReceiptNumber, &t.Status, &t.TotalAmount, &t.RoundingAdjustment, &createdAt); err != nil {
+			return nil, err
+		}
+		t.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+		transactions = append(transactions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range transactions {
+		details, err := r.getDetails(transactions[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		transactions[i].Details = details
+	}
+	return transactions, nil
+}
+
+func (r *SyncRepository) getDetails(transactionID int) ([]models.TransactionDetail, error) {
+	rows, err := r.db.Query(`
+		SELECT d.id, d.product_id, d.quantity, d.subtotal, d.cost_price, d.is_bundle, d.pricing_rule_id, COALESCE(pr.name, ''), COALESCE(pr.min_quantity, 0), COALESCE(pr.price, 0), d.discount
+		FROM transaction_details d
+		LEFT JOIN pricing_rule pr ON d.pricing_rule_id = pr.id
+		WHERE d.transaction_id = $1
+		ORDER BY d.id
+	`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	details := []models.TransactionDetail{}
+	for rows.Next() {
+		var d models.TransactionDetail
+		var isBundle bool
+		var ruleID sql.NullInt64
+		var rule models.AppliedPricingRule
+		if err := rows.Scan(&d.ID, &d.ProductID, &d.Quantity, &d.Subtotal, &d.CostPrice, &isBundle, &ruleID, &rule.Name, &rule.MinQuantity, &rule.Price, &rule.Discount); err != nil {
+			return nil, err
+		}
+		d.TransactionID = transactionID
+		if ruleID.Valid {
+			rule.ID = int(ruleID.Int64)
+			d.PricingRule = &rule
+		}
+		if isBundle {
+			d.Components = []models.BundleComponentSale{}
+		}
+		details = append(details, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range details {
+		if details[i].Components == nil {
+			continue
+		}
+		componentRows, err := r.db.Query(
+			"SELECT product_id, quantity, revenue, cost FROM transaction_bundle_component WHERE detail_id = $1 ORDER BY product_id",
+			details[i].ID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for componentRows.Next() {
+			var c models.BundleComponentSale
+			if err := componentRows.Scan(&c.ProductID, &c.Quantity, &c.Revenue, &c.Cost); err != nil {
+				componentRows.Close()
+				return nil, err
+			}
+			details[i].Components = append(details[i].Components, c)
+		}
+		componentRows.Close()
+		if err := componentRows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return details, nil
+}
+
+// MarkSynced records that the central server has the sale
+func (r *SyncRepository) MarkSynced(transactionID int) error {
+	_, err := r.db.Exec("UPDATE transactions SET synced_at = datetime('now', 'localtime') WHERE id = $1", transactionID)
+	return err
+}
+
+// PendingCount returns the number of sales not yet pushed
+func (r *SyncRepository) PendingCount() (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM transactions WHERE synced_at IS NULL AND deleted_at IS NULL").Scan(&count)
+	return count, err
+}
+
+// ApplyCatalog replaces the local catalog with snapshot. Rows missing from the
+// snapshot are soft deleted. Stock is taken from the central server only when
+// every local sale has been pushed, otherwise it would count those sales twice
+// until the next pull; changes are logged as sync movements.
+func (r *SyncRepository) ApplyCatalog(snapshot models.CatalogSnapshot) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var pending int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM transactions WHERE synced_at IS NULL AND deleted_at IS NULL").Scan(&pending); err != nil {
+		return err
+	}
+
+	// names can move between categories on the central server; the categories
+	// are retired first so the unique name index only sees those already
+	// brought up to date, the upsert revives them
+	if _, err := tx.Exec("UPDATE category SET deleted_at = datetime('now', 'localtime') WHERE deleted_at IS NULL"); err != nil {
+		return err
+	}
+
+	categoryIDs := make([]int, len(snapshot.Categories))
+	for i, c := range snapshot.Categories {
+		_, err := tx.Exec(`
+			INSERT INTO category (id, name, description, created_at, updated_at)
+			VALUES ($1, $2, $3, COALESCE($4, datetime('now', 'localtime')), COALESCE($5, datetime('now', 'localtime')))
+			ON CONFLICT (id) DO UPDATE SET
+				name = excluded.name, description = excluded.description,
+				created_at = excluded.created_at, updated_at = excluded.updated_at, deleted_at = NULL
+		`, c.ID, c.Name, c.Description, c.CreatedAt, c.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		categoryIDs[i] = c.ID
+	}
+
+	// SKUs can move between products on the central server; cleared first so
+	// the unique index doesn't trip over a product not yet updated
+	if _, err := tx.Exec("UPDATE product SET sku = ''"); err != nil {
+		return err
+	}
+
+	productIDs := make([]int, len(snapshot.Products))
+	for i, p := range snapshot.Products {
+		_, err := tx.Exec(`
+			INSERT INTO product (id, name, barcode, sku, price, cost_price, stock, reorder_point, reorder_qty, abc_class, is_bundle, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, COALESCE($12, datetime('now', 'localtime')), COALESCE($13, datetime('now', 'localtime')))
+			ON CONFLICT (id) DO UPDATE SET
+				name = excluded.name, barcode = excluded.barcode, sku = excluded.sku, price = excluded.price, cost_price = excluded.cost_price,
+				reorder_point = excluded.reorder_point, reorder_qty = excluded.reorder_qty, abc_class = excluded.abc_class,
+				is_bundle = excluded.is_bundle, created_at = excluded.created_at, updated_at = excluded.updated_at, deleted_at = NULL
+		`, p.ID, p.Name, p.Barcode, p.SKU, p.Price, p.CostPrice, p.Stock, p.ReorderPoint, p.ReorderQty, p.ABCClass, p.IsBundle, p.CreatedAt, p.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		productIDs[i] = p.ID
+
+		if err := setCategories(tx, p.ID, p.CategoryIDs); err != nil {
+			return err
+		}
+	}
+
+	// stock and bundle items refer to other products, so they go in once every product exists
+	for _, p := range snapshot.Products {
+		if _, err := tx.Exec("DELETE FROM product_bundle_item WHERE bundle_id = $1", p.ID); err != nil {
+			return err
+		}
+		for _, c := range p.Components {
+			_, err := tx.Exec("INSERT INTO product_bundle_item (bundle_id, component_id, quantity) VALUES ($1, $2, $3)", p.ID, c.ProductID, c.Quantity)
+			if err != nil {
+				return err
+			}
+		}
+
+		if p.IsBundle || pending > 0 {
+			continue
+		}
+		var stock int
+		if err := tx.QueryRow("SELECT stock FROM product WHERE id = $1", p.ID).Scan(&stock); err != nil {
+			return err
+		}
+		if stock == p.Stock {
+			continue
+		}
+		if _, err := tx.Exec("UPDATE product SET stock = $1 WHERE id = $2", p.Stock, p.ID); err != nil {
+			return err
+		}
+		if err := recordStockMovement(tx, p.ID, p.Stock-stock, repositories.MovementSync); err != nil {
+			return err
+		}
+	}
+
+	ruleIDs := make([]int, len(snapshot.PricingRules))
+	for i, rule := range snapshot.PricingRules {
+		_, err := tx.Exec(`
+			INSERT INTO pricing_rule (id, product_id, name, min_quantity, price, active) VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (id) DO UPDATE SET
+				product_id = excluded.product_id, name = excluded.name, min_quantity = excluded.min_quantity,
+				price = excluded.price, active = excluded.active, deleted_at = NULL
+		`, rule.ID, rule.ProductID, rule.Name, rule.MinQuantity, rule.Price, rule.Active)
+		if err != nil {
+			return err
+		}
+		ruleIDs[i] = rule.ID
+	}
+
+	for _, table := range []struct {
+		name string
+		ids  []int
+	}{{"pricing_rule", ruleIDs}, {"product", productIDs}, {"category", categoryIDs}} {
+		list, args := inList(table.ids, nil)
+		_, err := tx.Exec("UPDATE "+table.name+" SET deleted_at = datetime('now', 'localtime') WHERE deleted_at IS NULL AND id NOT IN ("+list+")", args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	// terminals of this kiosk compare their catalog against the central version
+	_, err = tx.Exec(`
+		INSERT INTO receipt_sequence (scope, last_value) VALUES ($1, $2)
+		ON CONFLICT (scope) DO UPDATE SET last_value = excluded.last_value
+	`, repositories.CatalogScope, snapshot.Version)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"database/sql"
+	"sync"
+	"sync/atomic"
+)
+
+// Statements keeps the prepared statement of each query run through it on
+// one database, so the queries of the hot paths, a scan at the till or a
+// checkout, are parsed and planned once per connection instead of on every
+// call. Repositories made for each request share the Statements of their
+// database; only queries of fixed text belong here, one built per call
+// would fill it up.
+type Statements struct {
+	db    *sql.DB
+	mu    sync.RWMutex
+	stmts map[string]*sql.Stmt
+}
+
+var (
+	statementsMu sync.Mutex
+	statements   = map[*sql.DB]*Statements{}
+
+	// unprepared is set behind a pooler such as PgBouncer in transaction
+	// mode, whose server connections don't keep prepared statements
+	unprepared atomic.Bool
+)
+
+// UsePreparedStatements turns reusing prepared statements on, the default, or
+// off; with it off queries run as they are
+func UsePreparedStatements(on bool) {
+	unprepared.Store(!on)
+}
+
+// StatementsOf returns the statements prepared on db
+func StatementsOf(db *sql.DB) *Statements {
+	statementsMu.Lock()
+	defer statementsMu.Unlock()
+
+	s, ok := statements[db]
+	if !ok {
+		s = &Statements{db: db, stmts: map[string]*sql.Stmt{}}
+		statements[db] = s
+	}
+	return s
+}
+
+// stmt returns the statement of query, preparing it the first time; nil when
+// statements aren't reused or query doesn't prepare, which then runs as it is
+// and reports its error there
+func (s *Statements) stmt(query string) *sql.Stmt {
+	if unprepared.Load() {
+		return nil
+	}
+
+	s.mu.RLock()
+	stmt, ok := s.stmts[query]
+	s.mu.RUnlock()
+	if ok {
+		return stmt
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stmt, ok := s.stmts[query]; ok {
+		return stmt
+	}
+	stmt, err := s.db.Prepare(query)
+	if err != nil {
+		return nil
+	}
+	s.stmts[query] = stmt
+	return stmt
+}
+
+// QueryRow is db.QueryRow on the prepared statement of query
+func (s *Statements) QueryRow(query string, args ...interface{}) *sql.Row {
+	if stmt := s.stmt(query); stmt != nil {
+		return stmt.QueryRow(args...)
+	}
+	return s.db.QueryRow(query, args...)
+}
+
+// Query is db.Query on the prepared statement of query
+func (s *Statements) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	if stmt := s.stmt(query); stmt != nil {
+		return stmt.Query(args...)
+	}
+	return s.db.Query(query, args...)
+}
+
+// txStmt is stmt for a query run in a transaction. Preparing takes a
+// connection of its own, which a pool of one, such as the in-memory database
+// of a kiosk, can't give while the transaction holds it; there the query runs
+// as it is until it has been prepared outside a transaction.
+func (s *Statements) txStmt(query string) *sql.Stmt {
+	if s.db.Stats().MaxOpenConnections != 1 {
+		return s.stmt(query)
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stmts[query]
+}
+
+// TxQueryRow is tx.QueryRow on the prepared statement of query; the
+// statement is prepared once on each connection a transaction runs on
+func (s *Statements) TxQueryRow(tx *sql.Tx, query string, args ...interface{}) *sql.Row {
+	if stmt := s.txStmt(query); stmt != nil {
+		return tx.Stmt(stmt).QueryRow(args...)
+	}
+	return tx.QueryRow(query, args...)
+}
+
+// TxExec is tx.Exec on the prepared statement of query
+func (s *Statements) TxExec(tx *sql.Tx, query string, args ...interface{}) (sql.Result, error) {
+	if stmt := s.txStmt(query); stmt != nil {
+		return tx.Stmt(stmt).Exec(args...)
+	}
+	return tx.Exec(query, args...)
+}
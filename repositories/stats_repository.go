@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"kasir-api/models"
+)
+
+type StatsRepository struct {
+	db       *sql.DB
+	settings SettingsReader
+}
+
+func NewStatsRepository(db *sql.DB, settings SettingsReader) *StatsRepository {
+	return &StatsRepository{db: db, settings: settings}
+}
+
+// GetCounts retrieves the catalog size, the products sold in the last
+// activeDays summarized store days and the number of customers in a single
+// round trip
+func (r *StatsRepository) GetCounts(activeDays int) (*models.StoreStats, error) {
+	s := &models.StoreStats{}
+	err := r.db.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM product WHERE deleted_at IS NULL),
+			(
+				SELECT COUNT(DISTINCT ps.product_id)
+				FROM daily_product_sales ps
+				INNER JOIN product p ON ps.product_id = p.id
+				WHERE ps.date >= (NOW() AT TIME ZONE $2)::date - $1::int AND p.deleted_at IS NULL
+			),
+			(SELECT COUNT(*) FROM customer WHERE deleted_at IS NULL)
+	`, activeDays, r.settings.Settings().Timezone).Scan(&s.CatalogSize, &s.ActiveProducts, &s.TotalCustomers)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetDailyRevenue retrieves the revenue of each of the last days full store
+// days, oldest first. A day is read from its summary, or from the sales
+// themselves when it has none yet, e.g. after a status change or an offline
+// push dropped it until the next aggregation.
+func (r *StatsRepository) GetDailyRevenue(days int) ([]models.DailyRevenue, error) {
+	rows, err := r.db.Query(`
+		WITH today AS (
+			SELECT (NOW() AT TIME ZONE $2)::date AS date
+		),
+		live AS (
+			SELECT (t.created_at AT TIME ZONE $2)::date AS date, SUM(t.total_amount) AS revenue, COUNT(*) AS transactions
+			FROM transactions t, today
+			WHERE t.created_at >= ((today.date - $1::int)::timestamp AT TIME ZONE $2)
+				AND t.created_at < (today.date::timestamp AT TIME ZONE $2)
+				AND t.deleted_at IS NULL AND `+settled("t")+`
+			GROUP BY 1
+		)
+		SELECT d::date::text,
+			COALESCE(s.total_revenue, l.revenue, 0),
+			COALESCE(s.total_transactions, l.transactions, 0)
+		FROM today, generate_series(today.date - $1::int, today.date - 1, interval '1 day') d
+		LEFT JOIN daily_sales_summary s ON s.date = d::date
+		LEFT JOIN live l ON l.date = d::date
+		ORDER BY d
+	`, days, r.settings.Settings().Timezone)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	daily := []models.DailyRevenue{}
+	for rows.Next() {
+		var d models.DailyRevenue
+		if err := rows.Scan(&d.Date, &d.Revenue, &d.Transactions); err != nil {
+			return nil, err
+		}
+		daily = append(daily, d)
+	}
+	return daily, rows.Err()
+}
@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"kasir-api/models"
+)
+
+// takeBatchesQuery takes $2 units of product $1 from its batches, the one
+// expiring first first; what the batches don't cover is undated stock. The
+// running total needs a window, which can't be locked FOR UPDATE, so callers
+// hold the product row instead. Checkout runs it on kiosks too, so it keeps
+// to what SQLite takes as well.
+const takeBatchesQuery = `
+	WITH ordered AS (
+		SELECT id, quantity, SUM(quantity) OVER (ORDER BY expires_on, id) - quantity AS before
+		FROM stock_batch
+		WHERE product_id = $1 AND quantity > 0
+	)
+	UPDATE stock_batch SET quantity = stock_batch.quantity - CASE WHEN o.quantity < $2 - o.before THEN o.quantity ELSE $2 - o.before END
+	FROM ordered o
+	WHERE stock_batch.id = o.id AND o.before < $2
+`
+
+// takeBatches takes quantity units of a product whose row is locked in tx from
+// its batches, first expired first out
+func takeBatches(tx *sql.Tx, productID, quantity int) error {
+	_, err := tx.Exec(takeBatchesQuery, productID, quantity)
+	return err
+}
+
+// settleBatches brings the batches of a product whose stock was just set in tx,
+// e.g. by a count, back within its stock, taking what was lost from the
+// batches expiring first
+func settleBatches(tx *sql.Tx, productID int) error {
+	var excess int
+	err := tx.QueryRow(`
+		SELECT COALESCE((SELECT SUM(quantity) FROM stock_batch WHERE product_id = $1), 0) - stock
+		FROM product WHERE id = $1
+	`, productID).Scan(&excess)
+	if err != nil {
+		return err
+	}
+	if excess <= 0 {
+		return nil
+	}
+	return takeBatches(tx, productID, excess)
+}
+
+type StockBatchRepository struct {
+	db *sql.DB
+}
+
+func NewStockBatchRepository(db *sql.DB) *StockBatchRepository {
+	return &StockBatchRepository{db: db}
+}
+
+// GetExpiring retrieves the batches left that expire within days from today,
+// those expired already included, expiring first first
+func (r *StockBatchRepository) GetExpiring(days int) ([]models.ExpiringBatch, error) {
+	rows, err := r.db.Query(`
+		SELECT b.id, b.product_id, p.name, b.quantity, b.expires_on, b.expires_on - CURRENT_DATE, b.quantity * p.cost_price
+		FROM stock_batch b
+		INNER JOIN product p ON b.product_id = p.id
+		WHERE b.quantity > 0 AND b.expires_on <= CURRENT_DATE + $1::int AND p.deleted_at IS NULL
+		ORDER BY b.expires_on, p.name, b.id
+	`, days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batches := []models.ExpiringBatch{}
+	for rows.Next() {
+		var b models.ExpiringBatch
+		var expiresOn time.Time
+		if err := rows.Scan(&b.ID, &b.ProductID, &b.ProductName, &b.Quantity, &expiresOn, &b.DaysLeft, &b.Value); err != nil {
+			return nil, err
+		}
+		b.ExpiresOn = expiresOn.Format("2006-01-02")
+		batches = append(batches, b)
+	}
+	return batches, rows.Err()
+}
+
+// expiredBatch is a batch past its expiry date with stock left
+type expiredBatch struct {
+	id, productID, quantity int
+	expiresOn               time.Time
+}
+
+// WriteOffExpired writes off what is left of each batch past its expiry date,
+// as a write-off for expired stock, and returns the number of batches written off
+func (r *StockBatchRepository) WriteOffExpired() (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// the products are locked first, in order, as sales lock them
+	rows, err := tx.Query(`
+		SELECT b.id, b.product_id, b.quantity, b.expires_on
+		FROM stock_batch b
+		WHERE b.quantity > 0 AND b.expires_on < CURRENT_DATE
+		ORDER BY b.product_id, b.expires_on, b.id
+	`)
+	if err != nil {
+		return 0, err
+	}
+	expired := []expiredBatch{}
+	for rows.Next() {
+		var b expiredBatch
+		if err := rows.Scan(&b.id, &b.productID, &b.quantity, &b.expiresOn); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		expired = append(expired, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, b := range expired {
+		var stock, costPrice int
+		err := tx.QueryRow("SELECT stock, cost_price FROM product WHERE id = $1 FOR UPDATE", b.productID).Scan(&stock, &costPrice)
+		if err != nil {
+			return 0, err
+		}
+		// a batch can't hold more than is in stock
+		quantity := min(b.quantity, stock)
+
+		if _, err := tx.Exec("UPDATE stock_batch SET quantity = 0 WHERE id = $1", b.id); err != nil {
+			return 0, err
+		}
+		if quantity <= 0 {
+			continue
+		}
+
+		var writeOffID int
+		err = tx.QueryRow(`
+			INSERT INTO stock_write_off (product_id, reason, quantity, unit_cost, note)
+			VALUES ($1, $2, $3, $4, $5) RETURNING id
+		`, b.productID, MovementExpired, quantity, costPrice, fmt.Sprintf("Batch #%d expired on %s", b.id, b.expiresOn.Format("2006-01-02"))).Scan(&writeOffID)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec("UPDATE product SET stock = stock - $1 WHERE id = $2", quantity, b.productID); err != nil {
+			return 0, err
+		}
+		if err := recordStockMovement(tx, b.productID, -quantity, MovementExpired, writeOffID); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(expired), nil
+}
@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"kasir-api/models"
+)
+
+// Stock movement reasons
+const (
+	MovementOpening    = "opening"
+	MovementSale       = "sale"
+	MovementReceipt    = "receipt"
+	MovementCycleCount = "cycle_count"
+	MovementAdjustment = "adjustment"
+	MovementOpname     = "stock_opname"
+	// write-offs, see stock_write_off
+	MovementDamaged        = "damaged"
+	MovementExpired        = "expired"
+	MovementSupplierReturn = "supplier_return"
+	// an offline terminal taking the stock of the central server
+	MovementSync = "sync"
+	// the stock of a voided or refunded sale put back
+	MovementVoid   = "void"
+	MovementRefund = "refund"
+)
+
+// recordStockMovement logs a change of quantity already applied to the product
+// row in tx; referenceID is the transaction, goods receipt, task, stock
+// opname or write-off, 0 for none
+func recordStockMovement(tx *sql.Tx, productID, quantity int, reason string, referenceID int) error {
+	_, err := tx.Exec(`
+		INSERT INTO stock_movement (product_id, quantity, balance_after, reason, reference_id)
+		SELECT id, $2, stock, $3, NULLIF($4, 0) FROM product WHERE id = $1
+	`, productID, quantity, reason, referenceID)
+	return err
+}
+
+type StockMovementRepository struct {
+	db *sql.DB
+}
+
+func NewStockMovementRepository(db *sql.DB) *StockMovementRepository {
+	return &StockMovementRepository{db: db}
+}
+
+// GetOpeningBalances retrieves the stock of every product with movements up
+// to dateTo, as it was at the start of dateFrom
+func (r *StockMovementRepository) GetOpeningBalances(dateFrom, dateTo string) ([]models.StockBalance, error) {
+	rows, err := r.db.Query(`
+		SELECT p.id, p.name, COALESCE((
+			SELECT m.balance_after FROM stock_movement m
+			WHERE m.product_id = p.id AND m.created_at < $1::date
+			ORDER BY m.created_at DESC, m.id DESC
+			LIMIT 1
+		), 0)
+		FROM product p
+		WHERE EXISTS (SELECT 1 FROM stock_movement m WHERE m.product_id = p.id AND m.created_at < $2::date + 1)
+		ORDER BY p.id
+	`, dateFrom, dateTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := []models.StockBalance{}
+	for rows.Next() {
+		var b models.StockBalance
+		if err := rows.Scan(&b.ProductID, &b.ProductName, &b.Opening); err != nil {
+			return nil, err
+		}
+		balances = append(balances, b)
+	}
+	return balances, rows.Err()
+}
+
+// GetMovements retrieves the movements from dateFrom through dateTo, by product in chronological order
+func (r *StockMovementRepository) GetMovements(dateFrom, dateTo string) ([]models.StockMovement, error) {
+	rows, err := r.db.Query(`
+		SELECT id, product_id, quantity, balance_after, reason, COALESCE(reference_id, 0), created_at
+		FROM stock_movement
+		WHERE created_at >= $1::date AND created_at < $2::date + 1
+		ORDER BY product_id, created_at, id
+	`, dateFrom, dateTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movements := []models.StockMovement{}
+	for rows.Next() {
+		var m models.StockMovement
+		var createdAt sql.NullTime
+		if err := rows.Scan(&m.ID, &m.ProductID, &m.Quantity, &m.BalanceAfter, &m.Reason, &m.ReferenceID, &createdAt); err != nil {
+			return nil, err
+		}
+		if createdAt.Valid {
+			m.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+		}
+		movements = append(movements, m)
+	}
+	return movements, rows.Err()
+}
+
+// GetPage retrieves up to limit movements after cursor, newest first, of one
+// product unless productID is 0, and the cursor of the page after
+func (r *StockMovementRepository) GetPage(productID int, cursor Cursor, limit int) (models.StockMovementPage, error) {
+	query := "SELECT id, product_id, quantity, balance_after, reason, COALESCE(reference_id, 0), created_at FROM stock_movement WHERE TRUE"
+	args := []interface{}{}
+	if productID != 0 {
+		args = append(args, productID)
+		query += " AND product_id = $1"
+	}
+	if !cursor.IsZero() {
+		args = append(args, cursor.CreatedAt, cursor.ID)
+		query += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+	// one row more tells whether there is a page after
+	args = append(args, limit+1)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return models.StockMovementPage{}, err
+	}
+	defer rows.Close()
+
+	page := models.StockMovementPage{Movements: []models.StockMovement{}}
+	var last Cursor
+	for rows.Next() {
+		var m models.StockMovement
+		var createdAt time.Time
+		if err := rows.Scan(&m.ID, &m.ProductID, &m.Quantity, &m.BalanceAfter, &m.Reason, &m.ReferenceID, &createdAt); err != nil {
+			return models.StockMovementPage{}, err
+		}
+		if len(page.Movements) == limit {
+			page.NextCursor = last.String()
+			break
+		}
+
+		m.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+		page.Movements = append(page.Movements, m)
+		last = Cursor{CreatedAt: createdAt, ID: m.ID}
+	}
+	return page, rows.Err()
+}
@@ -0,0 +1,321 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"kasir-api/models"
+	"kasir-api/money"
+)
+
+var (
+	ErrOpnameNotOpen      = errors.New("stock opname is not open for counting")
+	ErrOpnameNotSubmitted = errors.New("stock opname is not awaiting approval")
+	ErrOpnameNotCounted   = errors.New("stock opname has no counted products")
+	ErrProductNotInOpname = errors.New("product is not part of the stock opname")
+)
+
+type StockOpnameRepository struct {
+	db *sql.DB
+}
+
+func NewStockOpnameRepository(db *sql.DB) *StockOpnameRepository {
+	return &StockOpnameRepository{db: db}
+}
+
+const stockOpnameQuery = `
+	SELECT o.id, o.category_id, o.status, o.note, o.review_note,
+	       COUNT(l.product_id),
+	       COALESCE(SUM(l.counted_qty - l.expected_qty), 0),
+	       COALESCE(SUM((l.counted_qty - l.expected_qty) * p.cost_price), 0),
+	       o.created_at, o.submitted_at, o.reviewed_at
+	FROM stock_opname o
+	LEFT JOIN stock_opname_line l ON l.opname_id = o.id
+	LEFT JOIN product p ON l.product_id = p.id
+`
+
+func scanStockOpname(row rowScanner) (models.StockOpname, error) {
+	var o models.StockOpname
+	var categoryID sql.NullInt64
+	var createdAt, submittedAt, reviewedAt sql.NullTime
+	err := row.Scan(&o.ID, &categoryID, &o.Status, &o.Note, &o.ReviewNote,
+		&o.CountedProducts, &o.NetVariance, &o.VarianceValue,
+		&createdAt, &submittedAt, &reviewedAt)
+	if err != nil {
+		return models.StockOpname{}, err
+	}
+
+	if categoryID.Valid {
+		id := int(categoryID.Int64)
+		o.CategoryID = &id
+	}
+	if createdAt.Valid {
+		o.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	if submittedAt.Valid {
+		o.SubmittedAt = submittedAt.Time.Format("2006-01-02 15:04:05")
+	}
+	if reviewedAt.Valid {
+		o.ReviewedAt = reviewedAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return o, nil
+}
+
+// Create starts a count session, for one category or the whole store when categoryID is 0
+func (r *StockOpnameRepository) Create(categoryID int, note string) (int, error) {
+	var id int
+	err := r.db.QueryRow(
+		"INSERT INTO stock_opname (category_id, note) VALUES (NULLIF($1, 0), $2) RETURNING id",
+		categoryID, note,
+	).Scan(&id)
+	return id, err
+}
+
+// HasInProgress reports whether a count session is still open or awaiting approval
+func (r *StockOpnameRepository) HasInProgress() (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM stock_opname WHERE status IN ('open', 'submitted'))").Scan(&exists)
+	return exists, err
+}
+
+// GetAll retrieves the count sessions with their variance totals, newest first
+func (r *StockOpnameRepository) GetAll(status string) ([]models.StockOpname, error) {
+	rows, err := r.db.Query(stockOpnameQuery+`
+		WHERE ($1 = '' OR o.status = $1)
+		GROUP BY o.id
+		ORDER BY o.created_at DESC
+	`, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	opnames := []models.StockOpname{}
+	for rows.Next() {
+		o, err := scanStockOpname(rows)
+		if err != nil {
+			return nil, err
+		}
+		opnames = append(opnames, o)
+	}
+	return opnames, rows.Err()
+}
+
+// GetByID retrieves a count session with one line per counted product
+func (r *StockOpnameRepository) GetByID(id int) (*models.StockOpname, error) {
+	o, err := scanStockOpname(r.db.QueryRow(stockOpnameQuery+" WHERE o.id = $1 GROUP BY o.id", id))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT p.id, p.name, p.barcode, l.expected_qty, l.counted_qty, p.cost_price, l.counted_at
+		FROM stock_opname_line l
+		INNER JOIN product p ON l.product_id = p.id
+		WHERE l.opname_id = $1
+		ORDER BY p.name
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	o.Lines = []models.StockOpnameLine{}
+	for rows.Next() {
+		var line models.StockOpnameLine
+		var costPrice money.Amount
+		var countedAt sql.NullTime
+		if err := rows.Scan(&line.ProductID, &line.ProductName, &line.Barcode, &line.ExpectedQty, &line.CountedQty, &costPrice, &countedAt); err != nil {
+			return nil, err
+		}
+		line.Variance = line.CountedQty - line.ExpectedQty
+		line.VarianceValue = costPrice.Times(line.Variance)
+		if countedAt.Valid {
+			line.CountedAt = countedAt.Time.Format("2006-01-02 15:04:05")
+		}
+		o.Lines = append(o.Lines, line)
+	}
+	return &o, rows.Err()
+}
+
+// lockStockOpname locks a count session for the rest of tx and returns its status and category
+func lockStockOpname(tx *sql.Tx, id int) (string, sql.NullInt64, error) {
+	var status string
+	var categoryID sql.NullInt64
+	err := tx.QueryRow("SELECT status, category_id FROM stock_opname WHERE id = $1 FOR UPDATE", id).Scan(&status, &categoryID)
+	return status, categoryID, err
+}
+
+// RecordCounts stores counted quantities against the current recorded stock.
+// Counting a product again replaces its earlier count.
+func (r *StockOpnameRepository) RecordCounts(id int, items []models.OpnameCountItem) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	status, categoryID, err := lockStockOpname(tx, id)
+	if err != nil {
+		return err
+	}
+	if status != "open" {
+		return ErrOpnameNotOpen
+	}
+
+	for _, item := range items {
+		var stock int
+		err := tx.QueryRow(`
+			SELECT stock FROM product
+			WHERE id = $1 AND deleted_at IS NULL AND NOT is_bundle
+			AND ($2::int IS NULL OR EXISTS (SELECT 1 FROM product_category pc WHERE pc.product_id = product.id AND pc.category_id = $2))
+		`, item.ProductID, categoryID).Scan(&stock)
+		if err == sql.ErrNoRows {
+			return ErrProductNotInOpname
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO stock_opname_line (opname_id, product_id, expected_qty, counted_qty) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (opname_id, product_id) DO UPDATE
+			SET expected_qty = EXCLUDED.expected_qty, counted_qty = EXCLUDED.counted_qty, counted_at = NOW()
+		`, id, item.ProductID, stock, item.CountedQty)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Submit closes counting and sends the session for approval
+func (r *StockOpnameRepository) Submit(id int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	status, _, err := lockStockOpname(tx, id)
+	if err != nil {
+		return err
+	}
+	if status != "open" {
+		return ErrOpnameNotOpen
+	}
+
+	var lines int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM stock_opname_line WHERE opname_id = $1", id).Scan(&lines); err != nil {
+		return err
+	}
+	if lines == 0 {
+		return ErrOpnameNotCounted
+	}
+
+	_, err = tx.Exec("UPDATE stock_opname SET status = 'submitted', submitted_at = NOW() WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Approve books every variance into stock through the stock ledger. The
+// variance is applied to the current stock, so sales made after a product
+// was counted are kept.
+func (r *StockOpnameRepository) Approve(id int, note string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	status, _, err := lockStockOpname(tx, id)
+	if err != nil {
+		return err
+	}
+	if status != "submitted" {
+		return ErrOpnameNotSubmitted
+	}
+
+	rows, err := tx.Query(`
+		SELECT product_id, counted_qty - expected_qty FROM stock_opname_line
+		WHERE opname_id = $1 AND counted_qty <> expected_qty
+		ORDER BY product_id
+	`, id)
+	if err != nil {
+		return err
+	}
+	variances := map[int]int{}
+	productIDs := []int{}
+	for rows.Next() {
+		var productID, variance int
+		if err := rows.Scan(&productID, &variance); err != nil {
+			rows.Close()
+			return err
+		}
+		variances[productID] = variance
+		productIDs = append(productIDs, productID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, productID := range productIDs {
+		var stock int
+		if err := tx.QueryRow("SELECT stock FROM product WHERE id = $1 FOR UPDATE", productID).Scan(&stock); err != nil {
+			return err
+		}
+
+		newStock := stock + variances[productID]
+		if newStock < 0 {
+			newStock = 0
+		}
+		if newStock == stock {
+			continue
+		}
+
+		if _, err := tx.Exec("UPDATE product SET stock = $1 WHERE id = $2", newStock, productID); err != nil {
+			return err
+		}
+		if err := settleBatches(tx, productID); err != nil {
+			return err
+		}
+		if err := recordStockMovement(tx, productID, newStock-stock, MovementOpname, id); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE stock_opname SET status = 'approved', review_note = $1, reviewed_at = NOW() WHERE id = $2", note, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Reject closes a submitted session without touching stock
+func (r *StockOpnameRepository) Reject(id int, note string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	status, _, err := lockStockOpname(tx, id)
+	if err != nil {
+		return err
+	}
+	if status != "submitted" {
+		return ErrOpnameNotSubmitted
+	}
+
+	_, err = tx.Exec("UPDATE stock_opname SET status = 'rejected', review_note = $1, reviewed_at = NOW() WHERE id = $2", note, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
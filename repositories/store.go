@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"kasir-api/models"
+)
+
+// The stores below are what the sales services need from a database. The
+// repositories in this package implement them on PostgreSQL; package
+// repositories/sqlite implements them for single-terminal kiosk installs.
+
+type CategoryStore interface {
+	GetAll(sort string, updatedSince time.Time) ([]models.Category, error)
+	GetByID(id int) (models.Category, error)
+	GetByName(name string) (models.Category, error)
+	Create(category models.Category) (models.Category, error)
+	Update(category models.Category) (models.Category, error)
+	CreateBatch(categories []models.Category, atomic bool) ([]models.Category, []error, error)
+	UpdateBatch(categories []models.Category, atomic bool) ([]models.Category, []error, error)
+	Delete(id int, policy string) (models.CategoryDeletion, error)
+}
+
+type ProductStore interface {
+	GetAll(name, abcClass, sort string, updatedSince time.Time, expand Expansion) ([]models.Product, error)
+	GetByID(id int) (models.Product, error)
+	GetBySKU(sku string) (models.Product, error)
+	Create(product models.Product) (models.Product, error)
+	Update(product models.Product) (models.Product, error)
+	CreateBatch(products []models.Product, atomic bool) ([]models.Product, []error, error)
+	UpdateBatch(products []models.Product, atomic bool) ([]models.Product, []error, error)
+	Delete(id int) error
+	AdjustStock(id, quantity int) (models.Product, error)
+	GetComponents(bundleID int) ([]models.BundleComponent, error)
+	SetComponents(bundleID int, components []models.BundleComponent) error
+}
+
+type TransactionStore interface {
+	CreateTransaction(ctx context.Context, items []models.CheckoutItem, customerID, storeID int, receiptNumber, status string, order OrderNumber, price LinePricer, round Rounder, tender Tender) (*models.Transaction, error)
+	AppendItems(id int, items []models.CheckoutItem, price LinePricer) error
+	GetByID(id int) (*models.Transaction, error)
+}
+
+type PricingRuleStore interface {
+	GetAll(productID int) ([]models.PricingRule, error)
+	GetActive(productIDs []int) (map[int][]models.PricingRule, error)
+	GetByID(id int) (models.PricingRule, error)
+	Create(rule models.PricingRule) (models.PricingRule, error)
+	Update(rule models.PricingRule) (models.PricingRule, error)
+	Delete(id int) error
+}
+
+type SequenceStore interface {
+	Next(scope string) (int64, error)
+}
+
+var (
+	_ CategoryStore    = (*CategoryRepository)(nil)
+	_ ProductStore     = (*ProductRepository)(nil)
+	_ TransactionStore = (*TransactionRepository)(nil)
+	_ PricingRuleStore = (*PricingRuleRepository)(nil)
+	_ SequenceStore    = (*SequenceRepository)(nil)
+)
@@ -0,0 +1,207 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/money"
+
+	"github.com/lib/pq"
+)
+
+var ErrStoreNotFound = errors.New("store not found")
+
+// StoreRepository keeps the stores of a multi-store install and the prices
+// they override
+type StoreRepository struct {
+	db *sql.DB
+}
+
+func NewStoreRepository(db *sql.DB) *StoreRepository {
+	return &StoreRepository{db: db}
+}
+
+func (r *StoreRepository) GetAll() ([]models.Store, error) {
+	rows, err := r.db.Query("SELECT id, name, address, created_at FROM store WHERE deleted_at IS NULL ORDER BY name, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stores := []models.Store{}
+	for rows.Next() {
+		var s models.Store
+		var createdAt time.Time
+		if err := rows.Scan(&s.ID, &s.Name, &s.Address, &createdAt); err != nil {
+			return nil, err
+		}
+		s.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+		stores = append(stores, s)
+	}
+	return stores, rows.Err()
+}
+
+func (r *StoreRepository) Create(s models.Store) (models.Store, error) {
+	var createdAt time.Time
+	err := r.db.QueryRow(
+		"INSERT INTO store (name, address) VALUES ($1, $2) RETURNING id, created_at",
+		s.Name, s.Address,
+	).Scan(&s.ID, &createdAt)
+	if err != nil {
+		return models.Store{}, err
+	}
+	s.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+	return s, nil
+}
+
+func (r *StoreRepository) Update(s models.Store) (models.Store, error) {
+	var createdAt time.Time
+	err := r.db.QueryRow(
+		"UPDATE store SET name = $1, address = $2 WHERE id = $3 AND deleted_at IS NULL RETURNING created_at",
+		s.Name, s.Address, s.ID,
+	).Scan(&createdAt)
+	if err != nil {
+		return models.Store{}, err
+	}
+	s.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+	return s, nil
+}
+
+// Delete soft deletes a store; its prices stay until the store is purged
+func (r *StoreRepository) Delete(id int) error {
+	result, err := r.db.Exec("UPDATE store SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// checkStore returns ErrStoreNotFound unless storeID is an active store
+func (r *StoreRepository) checkStore(storeID int) error {
+	var active bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM store WHERE id = $1 AND deleted_at IS NULL)", storeID).Scan(&active)
+	if err != nil {
+		return err
+	}
+	if !active {
+		return fmt.Errorf("%w: id %d", ErrStoreNotFound, storeID)
+	}
+	return nil
+}
+
+// GetPrices retrieves the prices an active store overrides, of active
+// products, next to their own prices
+func (r *StoreRepository) GetPrices(storeID int) ([]models.StorePrice, error) {
+	if err := r.checkStore(storeID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT sp.product_id, p.name, sp.price, p.price, sp.updated_at
+		FROM store_price sp
+		INNER JOIN product p ON sp.product_id = p.id
+		WHERE sp.store_id = $1 AND p.deleted_at IS NULL
+		ORDER BY p.name, p.id
+	`, storeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := []models.StorePrice{}
+	for rows.Next() {
+		var p models.StorePrice
+		var updatedAt time.Time
+		if err := rows.Scan(&p.ProductID, &p.ProductName, &p.Price, &p.ProductPrice, &updatedAt); err != nil {
+			return nil, err
+		}
+		p.UpdatedAt = updatedAt.Format("2006-01-02 15:04:05")
+		prices = append(prices, p)
+	}
+	return prices, rows.Err()
+}
+
+// SetPrice sets the price an active store sells an active product at,
+// replacing the one it had
+func (r *StoreRepository) SetPrice(storeID int, price models.StorePrice) (models.StorePrice, error) {
+	if err := r.checkStore(storeID); err != nil {
+		return models.StorePrice{}, err
+	}
+
+	var updatedAt time.Time
+	err := r.db.QueryRow(`
+		INSERT INTO store_price (store_id, product_id, price)
+		SELECT $1, id, $3 FROM product WHERE id = $2 AND deleted_at IS NULL
+		ON CONFLICT (store_id, product_id) DO UPDATE SET price = EXCLUDED.price, updated_at = NOW()
+		RETURNING updated_at
+	`, storeID, price.ProductID, price.Price).Scan(&updatedAt)
+	if err == sql.ErrNoRows {
+		return models.StorePrice{}, fmt.Errorf("%w: id %d", ErrProductNotFound, price.ProductID)
+	}
+	if err != nil {
+		return models.StorePrice{}, err
+	}
+
+	err = r.db.QueryRow("SELECT name, price FROM product WHERE id = $1", price.ProductID).Scan(&price.ProductName, &price.ProductPrice)
+	if err != nil {
+		return models.StorePrice{}, err
+	}
+	price.UpdatedAt = updatedAt.Format("2006-01-02 15:04:05")
+	return price, nil
+}
+
+// DeletePrice drops the price a store overrides, so it sells the product at
+// the product's own price again
+func (r *StoreRepository) DeletePrice(storeID, productID int) error {
+	result, err := r.db.Exec("DELETE FROM store_price WHERE store_id = $1 AND product_id = $2", storeID, productID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetActive retrieves the prices an active store overrides of productIDs,
+// keyed by product
+func (r *StoreRepository) GetActive(storeID int, productIDs []int) (map[int]money.Amount, error) {
+	if err := r.checkStore(storeID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query("SELECT product_id, price FROM store_price WHERE store_id = $1 AND product_id = ANY($2)", storeID, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make(map[int]money.Amount)
+	for rows.Next() {
+		var productID int
+		var price money.Amount
+		if err := rows.Scan(&productID, &price); err != nil {
+			return nil, err
+		}
+		prices[productID] = price
+	}
+	return prices, rows.Err()
+}
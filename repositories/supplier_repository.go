@@ -0,0 +1,203 @@
+package repositories
+
+import (
+	"database/sql"
+	"kasir-api/models"
+)
+
+type SupplierRepository struct {
+	db *sql.DB
+}
+
+func NewSupplierRepository(db *sql.DB) *SupplierRepository {
+	return &SupplierRepository{db: db}
+}
+
+// GetAll retrieves all active suppliers
+func (r *SupplierRepository) GetAll() ([]models.Supplier, error) {
+	rows, err := r.db.Query("SELECT id, name, phone, email, created_at FROM supplier WHERE deleted_at IS NULL ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppliers := []models.Supplier{}
+	for rows.Next() {
+		var s models.Supplier
+		var createdAt sql.NullTime
+		if err := rows.Scan(&s.ID, &s.Name, &s.Phone, &s.Email, &createdAt); err != nil {
+			return nil, err
+		}
+		if createdAt.Valid {
+			s.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+		}
+		suppliers = append(suppliers, s)
+	}
+	return suppliers, nil
+}
+
+// GetByID retrieves a supplier by ID
+func (r *SupplierRepository) GetByID(id int) (models.Supplier, error) {
+	var s models.Supplier
+	var createdAt sql.NullTime
+	err := r.db.QueryRow(
+		"SELECT id, name, phone, email, created_at FROM supplier WHERE id = $1 AND deleted_at IS NULL",
+		id,
+	).Scan(&s.ID, &s.Name, &s.Phone, &s.Email, &createdAt)
+	if err != nil {
+		return models.Supplier{}, err
+	}
+
+	if createdAt.Valid {
+		s.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return s, nil
+}
+
+// Create inserts a new supplier
+func (r *SupplierRepository) Create(supplier models.Supplier) (models.Supplier, error) {
+	var createdAt sql.NullTime
+	err := r.db.QueryRow(
+		"INSERT INTO supplier (name, phone, email) VALUES ($1, $2, $3) RETURNING id, created_at",
+		supplier.Name, supplier.Phone, supplier.Email,
+	).Scan(&supplier.ID, &createdAt)
+	if err != nil {
+		return models.Supplier{}, err
+	}
+
+	if createdAt.Valid {
+		supplier.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return supplier, nil
+}
+
+// Update updates an existing supplier
+func (r *SupplierRepository) Update(supplier models.Supplier) (models.Supplier, error) {
+	_, err := r.db.Exec(
+		"UPDATE supplier SET name = $1, phone = $2, email = $3 WHERE id = $4 AND deleted_at IS NULL",
+		supplier.Name, supplier.Phone, supplier.Email, supplier.ID,
+	)
+	if err != nil {
+		return models.Supplier{}, err
+	}
+	return supplier, nil
+}
+
+// Delete soft deletes a supplier
+func (r *SupplierRepository) Delete(id int) error {
+	result, err := r.db.Exec("UPDATE supplier SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SavePrice upserts the current price of a product at a supplier and appends it to the price history
+func (r *SupplierRepository) SavePrice(price models.SupplierPrice) (models.SupplierPrice, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.SupplierPrice{}, err
+	}
+	defer tx.Rollback()
+
+	var updatedAt sql.NullTime
+	err = tx.QueryRow(`
+		INSERT INTO supplier_price (supplier_id, product_id, price) VALUES ($1, $2, $3)
+		ON CONFLICT (supplier_id, product_id) DO UPDATE SET price = EXCLUDED.price, updated_at = NOW()
+		RETURNING id, updated_at
+	`, price.SupplierID, price.ProductID, price.Price).Scan(&price.ID, &updatedAt)
+	if err != nil {
+		return models.SupplierPrice{}, err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO supplier_price_history (supplier_id, product_id, price) VALUES ($1, $2, $3)",
+		price.SupplierID, price.ProductID, price.Price,
+	)
+	if err != nil {
+		return models.SupplierPrice{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.SupplierPrice{}, err
+	}
+
+	if updatedAt.Valid {
+		price.UpdatedAt = updatedAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return price, nil
+}
+
+const supplierPriceSelect = `
+	SELECT sp.id, sp.supplier_id, s.name, sp.product_id, p.name, sp.price, sp.updated_at
+	FROM supplier_price sp
+	INNER JOIN supplier s ON sp.supplier_id = s.id AND s.deleted_at IS NULL
+	INNER JOIN product p ON sp.product_id = p.id AND p.deleted_at IS NULL
+`
+
+// GetPricesBySupplier retrieves the catalog of a supplier
+func (r *SupplierRepository) GetPricesBySupplier(supplierID int) ([]models.SupplierPrice, error) {
+	return r.queryPrices(supplierPriceSelect+" WHERE sp.supplier_id = $1 ORDER BY p.name", supplierID)
+}
+
+// GetPricesByProduct retrieves all supplier prices of a product, cheapest first
+func (r *SupplierRepository) GetPricesByProduct(productID int) ([]models.SupplierPrice, error) {
+	return r.queryPrices(supplierPriceSelect+" WHERE sp.product_id = $1 ORDER BY sp.price, sp.updated_at DESC", productID)
+}
+
+func (r *SupplierRepository) queryPrices(query string, args ...interface{}) ([]models.SupplierPrice, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := []models.SupplierPrice{}
+	for rows.Next() {
+		var sp models.SupplierPrice
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&sp.ID, &sp.SupplierID, &sp.SupplierName, &sp.ProductID, &sp.ProductName, &sp.Price, &updatedAt); err != nil {
+			return nil, err
+		}
+		if updatedAt.Valid {
+			sp.UpdatedAt = updatedAt.Time.Format("2006-01-02 15:04:05")
+		}
+		prices = append(prices, sp)
+	}
+	return prices, rows.Err()
+}
+
+// GetPriceHistory retrieves every recorded price of a product at a supplier, oldest first
+func (r *SupplierRepository) GetPriceHistory(supplierID, productID int) ([]models.PricePoint, error) {
+	rows, err := r.db.Query(
+		"SELECT price, recorded_at FROM supplier_price_history WHERE supplier_id = $1 AND product_id = $2 ORDER BY recorded_at, id",
+		supplierID, productID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.PricePoint{}
+	for rows.Next() {
+		var pp models.PricePoint
+		var recordedAt sql.NullTime
+		if err := rows.Scan(&pp.Price, &recordedAt); err != nil {
+			return nil, err
+		}
+		if recordedAt.Valid {
+			pp.RecordedAt = recordedAt.Time.Format("2006-01-02 15:04:05")
+		}
+		history = append(history, pp)
+	}
+	return history, rows.Err()
+}
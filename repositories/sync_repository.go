@@ -0,0 +1,453 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"kasir-api/models"
+	"kasir-api/money"
+)
+
+// ErrSyncConflict is returned for a pushed sale whose receipt number is held
+// by a different sale
+var ErrSyncConflict = errors.New("receipt number is already used by a different sale")
+
+// SyncRepository is the central side of offline terminal sync: it takes in
+// sales made offline and hands out the catalog
+type SyncRepository struct {
+	db *sql.DB
+}
+
+func NewSyncRepository(db *sql.DB) *SyncRepository {
+	return &SyncRepository{db: db}
+}
+
+func (r *SyncRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// PushTransaction records a sale made offline as it was rung up: prices,
+// pricing rules and bundle allocations are kept, not recalculated. Stock is
+// taken out as of now and clamped at zero, since the goods already left the
+// store. The sale keeps its status, completed for terminals from before
+// statuses. Receipt numbers identify sales, so a sale pushed twice is stored
+// once; pushed reports whether it was new. A receipt number already held by a
+// sale of another total is refused with ErrSyncConflict, and a new sale dated
+// in a closed accounting period with ErrPeriodClosed. The summaries of a past
+// day the sale is pushed into are dropped to be rolled up again with it.
+func (r *SyncRepository) PushTransaction(t models.Transaction) (transactionID int, pushed bool, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	if t.Status == "" {
+		t.Status = TransactionCompleted
+	}
+	var createdAt time.Time
+	err = tx.QueryRow(`
+		INSERT INTO transactions (receipt_number, total_amount, rounding_adjustment, status, created_at) VALUES ($1, $2, $3, $4, $5::timestamp)
+		ON CONFLICT (receipt_number) DO NOTHING
+		RETURNING id, created_at
+	`, t.ReceiptNumber, t.TotalAmount, t.RoundingAdjustment, t.Status, t.CreatedAt).Scan(&transactionID, &createdAt)
+	if err == sql.ErrNoRows {
+		var total money.Amount
+		err := tx.QueryRow("SELECT id, total_amount FROM transactions WHERE receipt_number = $1", t.ReceiptNumber).Scan(&transactionID, &total)
+		if err != nil {
+			return 0, false, err
+		}
+		if total != t.TotalAmount {
+			return transactionID, false, ErrSyncConflict
+		}
+		return transactionID, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if err := recordStatusEvent(tx, transactionID, "", t.Status, ""); err != nil {
+		return 0, false, err
+	}
+
+	// a late sale would change the numbers of a closed month, so it waits on
+	// the terminal until the month is reopened
+	var closedMonth time.Time
+	err = tx.QueryRow(
+		"SELECT month FROM accounting_period WHERE month = date_trunc('month', $1::timestamp)::date",
+		t.CreatedAt,
+	).Scan(&closedMonth)
+	if err == nil {
+		return 0, false, closedPeriodError(closedMonth)
+	}
+	if err != sql.ErrNoRows {
+		return 0, false, err
+	}
+	if err := dropDailySummaries(tx, createdAt); err != nil {
+		return 0, false, err
+	}
+
+	sold := make(map[int]int)
+	soldIDs := []int{}
+	sell := func(productID, quantity int) {
+		if _, ok := sold[productID]; !ok {
+			soldIDs = append(soldIDs, productID)
+		}
+		sold[productID] += quantity
+	}
+
+	for _, d := range t.Details {
+		var ruleID sql.NullInt64
+		var discount money.Amount
+		if d.PricingRule != nil {
+			ruleID = sql.NullInt64{Int64: int64(d.PricingRule.ID), Valid: true}
+			discount = d.PricingRule.Discount
+		}
+
+		// a rule the central server doesn't know is dropped, the discount is kept
+		var detailID int
+		err := tx.QueryRow(`
+			INSERT INTO transaction_details (transaction_id, product_id, quantity, subtotal, cost_price, is_bundle, pricing_rule_id, discount)
+			VALUES ($1, $2, $3, $4, $5, $6, (SELECT id FROM pricing_rule WHERE id = $7), $8)
+			RETURNING id
+		`, transactionID, d.ProductID, d.Quantity, d.Subtotal, d.CostPrice, d.Components != nil, ruleID, discount).Scan(&detailID)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if d.Components == nil {
+			sell(d.ProductID, d.Quantity)
+			continue
+		}
+		for _, c := range d.Components {
+			_, err = tx.Exec(
+				"INSERT INTO transaction_bundle_component (detail_id, transaction_id, product_id, quantity, revenue, cost) VALUES ($1, $2, $3, $4, $5, $6)",
+				detailID, transactionID, c.ProductID, c.Quantity, c.Revenue, c.Cost,
+			)
+			if err != nil {
+				return 0, false, err
+			}
+			sell(c.ProductID, c.Quantity)
+		}
+	}
+
+	for _, productID := range soldIDs {
+		var previousStock int
+		err := tx.QueryRow("SELECT stock FROM product WHERE id = $1 FOR UPDATE", productID).Scan(&previousStock)
+		if err != nil {
+			return 0, false, err
+		}
+
+		taken := sold[productID]
+		if taken > previousStock {
+			taken = previousStock
+		}
+		if _, err := tx.Exec("UPDATE product SET stock = stock - $1 WHERE id = $2", taken, productID); err != nil {
+			return 0, false, err
+		}
+		if err := takeBatches(tx, productID, taken); err != nil {
+			return 0, false, err
+		}
+		if err := recordStockMovement(tx, productID, -taken, MovementSale, transactionID); err != nil {
+			return 0, false, err
+		}
+	}
+
+	return transactionID, true, tx.Commit()
+}
+
+// CostTransaction fills in the cost of every line of t, a sale pushed with its
+// prices only, and splits its bundle lines over their components, all from
+// the catalog as of now. Products deleted since are costed all the same, as
+// the goods were sold; an ID the catalog never held is ErrProductNotFound.
+func (r *SyncRepository) CostTransaction(t *models.Transaction) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, d := range t.Details {
+		var isBundle bool
+		err := tx.QueryRow("SELECT cost_price, is_bundle FROM product WHERE id = $1", d.ProductID).Scan(&t.Details[i].CostPrice, &isBundle)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: id %d", ErrProductNotFound, d.ProductID)
+		}
+		if err != nil {
+			return err
+		}
+		if !isBundle {
+			continue
+		}
+
+		parts, err := getBundleParts(tx, d.ProductID)
+		if err != nil {
+			return err
+		}
+		t.Details[i].CostPrice = 0
+		for _, part := range parts {
+			t.Details[i].CostPrice += part.costPrice.Times(part.quantity)
+		}
+		t.Details[i].Components = allocateBundleRevenue(d.Subtotal, parts, d.Quantity)
+	}
+	return nil
+}
+
+// GetChanges retrieves the products and categories changed by transactions
+// from since on, deleted ones included, and the cursor to pull from next. The
+// cursor is the oldest transaction still running when the changes were read,
+// so a change committed late is pulled next time rather than skipped. A
+// cursor of 0 pulls the active catalog.
+func (r *SyncRepository) GetChanges(since int64) (models.CatalogChanges, error) {
+	changes := models.CatalogChanges{
+		Categories: []models.Category{},
+		Products:   []models.Product{},
+	}
+
+	// one snapshot for the cursor and the rows, so they agree
+	tx, err := r.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return changes, err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow("SELECT txid_snapshot_xmin(txid_current_snapshot())").Scan(&changes.Cursor); err != nil {
+		return changes, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT id, name, description, created_at, updated_at, deleted_at FROM category
+		WHERE change_txid >= $1::bigint AND ($1::bigint > 0 OR deleted_at IS NULL)
+		ORDER BY id
+	`, since)
+	if err != nil {
+		return changes, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c models.Category
+		var createdAt, updatedAt, deletedAt sql.NullTime
+		if err := rows.Scan(&c.ID, &c.Name, &c.Description, &createdAt, &updatedAt, &deletedAt); err != nil {
+			return changes, err
+		}
+		c.CreatedAt = timePtr(createdAt)
+		c.UpdatedAt = timePtr(updatedAt)
+		c.DeletedAt = timePtr(deletedAt)
+		changes.Categories = append(changes.Categories, c)
+	}
+	if err := rows.Err(); err != nil {
+		return changes, err
+	}
+
+	productRows, err := tx.Query(`
+		SELECT p.id, p.name, p.barcode, p.sku, p.price, p.cost_price, `+productStock+`, p.reorder_point, p.reorder_qty, p.abc_class, p.is_bundle, p.created_at, p.updated_at, p.deleted_at
+		FROM product p
+		WHERE ($1::bigint > 0 OR p.deleted_at IS NULL) AND (p.change_txid >= $1::bigint OR EXISTS (
+			SELECT 1 FROM product_bundle_item b
+			INNER JOIN product c ON b.component_id = c.id
+			WHERE b.bundle_id = p.id AND c.change_txid >= $1::bigint
+		))
+		ORDER BY p.id
+	`, since)
+	if err != nil {
+		return changes, err
+	}
+	defer productRows.Close()
+
+	index := make(map[int]int)
+	ids := []int{}
+	for productRows.Next() {
+		var p models.Product
+		var createdAt, updatedAt, deletedAt sql.NullTime
+		if err := productRows.Scan(&p.ID, &p.Name, &p.Barcode, &p.SKU, &p.Price, &p.CostPrice, &p.Stock, &p.ReorderPoint, &p.ReorderQty, &p.ABCClass, &p.IsBundle, &createdAt, &updatedAt, &deletedAt); err != nil {
+			return changes, err
+		}
+		p.CreatedAt = timePtr(createdAt)
+		p.UpdatedAt = timePtr(updatedAt)
+		p.DeletedAt = timePtr(deletedAt)
+		p.CategoryIDs = []int{}
+		index[p.ID] = len(changes.Products)
+		ids = append(ids, p.ID)
+		changes.Products = append(changes.Products, p)
+	}
+	if err := productRows.Err(); err != nil {
+		return changes, err
+	}
+
+	itemRows, err := tx.Query(`
+		SELECT bundle_id, component_id, quantity FROM product_bundle_item
+		WHERE bundle_id = ANY($1)
+		ORDER BY bundle_id, component_id
+	`, pq.Array(ids))
+	if err != nil {
+		return changes, err
+	}
+	defer itemRows.Close()
+	for itemRows.Next() {
+		var bundleID int
+		var c models.BundleComponent
+		if err := itemRows.Scan(&bundleID, &c.ProductID, &c.Quantity); err != nil {
+			return changes, err
+		}
+		i := index[bundleID]
+		changes.Products[i].Components = append(changes.Products[i].Components, c)
+	}
+	if err := itemRows.Err(); err != nil {
+		return changes, err
+	}
+
+	categoryRows, err := tx.Query(`
+		SELECT pc.product_id, pc.category_id
+		FROM product_category pc
+		INNER JOIN category c ON pc.category_id = c.id
+		WHERE pc.product_id = ANY($1) AND c.deleted_at IS NULL
+		ORDER BY pc.product_id, pc.category_id
+	`, pq.Array(ids))
+	if err != nil {
+		return changes, err
+	}
+	defer categoryRows.Close()
+	for categoryRows.Next() {
+		var productID, categoryID int
+		if err := categoryRows.Scan(&productID, &categoryID); err != nil {
+			return changes, err
+		}
+		i := index[productID]
+		changes.Products[i].CategoryIDs = append(changes.Products[i].CategoryIDs, categoryID)
+	}
+	return changes, categoryRows.Err()
+}
+
+// GetCatalog retrieves the catalog version, the active categories, products
+// with their stock and bundle components, and pricing rules
+func (r *SyncRepository) GetCatalog() (models.CatalogSnapshot, error) {
+	snapshot := models.CatalogSnapshot{
+		Categories:   []models.Category{},
+		Products:     []models.Product{},
+		PricingRules: []models.PricingRule{},
+	}
+
+	// read first, so a change made while the catalog is read shows as a newer version
+	err := r.db.QueryRow("SELECT COALESCE(MAX(last_value), 0) FROM receipt_sequence WHERE scope = $1", CatalogScope).Scan(&snapshot.Version)
+	if err != nil {
+		return snapshot, err
+	}
+
+	rows, err := r.db.Query("SELECT id, name, description, created_at, updated_at FROM category WHERE deleted_at IS NULL ORDER BY id")
+	if err != nil {
+		return snapshot, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c models.Category
+		var createdAt, updatedAt sql.NullTime
+		if err := rows.Scan(&c.ID, &c.Name, &c.Description, &createdAt, &updatedAt); err != nil {
+			return snapshot, err
+		}
+		c.CreatedAt = timePtr(createdAt)
+		c.UpdatedAt = timePtr(updatedAt)
+		snapshot.Categories = append(snapshot.Categories, c)
+	}
+	if err := rows.Err(); err != nil {
+		return snapshot, err
+	}
+
+	productRows, err := r.db.Query(`
+		SELECT id, name, barcode, sku, price, cost_price, stock, reorder_point, reorder_qty, abc_class, is_bundle, created_at, updated_at
+		FROM product WHERE deleted_at IS NULL ORDER BY id
+	`)
+	if err != nil {
+		return snapshot, err
+	}
+	defer productRows.Close()
+
+	index := make(map[int]int)
+	for productRows.Next() {
+		var p models.Product
+		var createdAt, updatedAt sql.NullTime
+		if err := productRows.Scan(&p.ID, &p.Name, &p.Barcode, &p.SKU, &p.Price, &p.CostPrice, &p.Stock, &p.ReorderPoint, &p.ReorderQty, &p.ABCClass, &p.IsBundle, &createdAt, &updatedAt); err != nil {
+			return snapshot, err
+		}
+		p.CreatedAt = timePtr(createdAt)
+		p.UpdatedAt = timePtr(updatedAt)
+		p.CategoryIDs = []int{}
+		index[p.ID] = len(snapshot.Products)
+		snapshot.Products = append(snapshot.Products, p)
+	}
+	if err := productRows.Err(); err != nil {
+		return snapshot, err
+	}
+
+	itemRows, err := r.db.Query(`
+		SELECT b.bundle_id, b.component_id, b.quantity
+		FROM product_bundle_item b
+		INNER JOIN product p ON b.bundle_id = p.id
+		WHERE p.deleted_at IS NULL
+		ORDER BY b.bundle_id, b.component_id
+	`)
+	if err != nil {
+		return snapshot, err
+	}
+	defer itemRows.Close()
+	for itemRows.Next() {
+		var bundleID int
+		var c models.BundleComponent
+		if err := itemRows.Scan(&bundleID, &c.ProductID, &c.Quantity); err != nil {
+			return snapshot, err
+		}
+		if i, ok := index[bundleID]; ok {
+			snapshot.Products[i].Components = append(snapshot.Products[i].Components, c)
+		}
+	}
+	if err := itemRows.Err(); err != nil {
+		return snapshot, err
+	}
+
+	categoryRows, err := r.db.Query(`
+		SELECT pc.product_id, pc.category_id
+		FROM product_category pc
+		INNER JOIN category c ON pc.category_id = c.id
+		WHERE c.deleted_at IS NULL
+		ORDER BY pc.product_id, pc.category_id
+	`)
+	if err != nil {
+		return snapshot, err
+	}
+	defer categoryRows.Close()
+	for categoryRows.Next() {
+		var productID, categoryID int
+		if err := categoryRows.Scan(&productID, &categoryID); err != nil {
+			return snapshot, err
+		}
+		if i, ok := index[productID]; ok {
+			snapshot.Products[i].CategoryIDs = append(snapshot.Products[i].CategoryIDs, categoryID)
+		}
+	}
+	if err := categoryRows.Err(); err != nil {
+		return snapshot, err
+	}
+
+	ruleRows, err := r.db.Query(`
+		SELECT r.id, r.product_id, r.name, r.min_quantity, r.price, r.active
+		FROM pricing_rule r
+		INNER JOIN product p ON r.product_id = p.id
+		WHERE r.deleted_at IS NULL AND p.deleted_at IS NULL
+		ORDER BY r.id
+	`)
+	if err != nil {
+		return snapshot, err
+	}
+	defer ruleRows.Close()
+	for ruleRows.Next() {
+		var rule models.PricingRule
+		if err := ruleRows.Scan(&rule.ID, &rule.ProductID, &rule.Name, &rule.MinQuantity, &rule.Price, &rule.Active); err != nil {
+			return snapshot, err
+		}
+		snapshot.PricingRules = append(snapshot.PricingRules, rule)
+	}
+	return snapshot, ruleRows.Err()
+}
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"kasir-api/models"
+)
+
+var ErrTaxInvoiceExists = errors.New("transaction already has a tax invoice")
+
+// TaxInvoiceRepository keeps the tax invoices issued to business buyers
+type TaxInvoiceRepository struct {
+	db *sql.DB
+}
+
+func NewTaxInvoiceRepository(db *sql.DB) *TaxInvoiceRepository {
+	return &TaxInvoiceRepository{db: db}
+}
+
+const taxInvoiceColumns = `ti.id, ti.number, ti.transaction_id, COALESCE(t.receipt_number, ''), ti.buyer_npwp, ti.buyer_name, ti.buyer_address,
+	ti.tax_rate, ti.tax_base, ti.tax_amount, t.total_amount, ti.issued_at`
+
+func scanTaxInvoice(row rowScanner) (models.TaxInvoice, error) {
+	var inv models.TaxInvoice
+	var issuedAt time.Time
+	err := row.Scan(&inv.ID, &inv.Number, &inv.TransactionID, &inv.ReceiptNumber, &inv.BuyerNPWP, &inv.BuyerName, &inv.BuyerAddress,
+		&inv.TaxRate, &inv.TaxBase, &inv.TaxAmount, &inv.TotalAmount, &issuedAt)
+	if err != nil {
+		return models.TaxInvoice{}, err
+	}
+	inv.IssuedAt = issuedAt.Format("2006-01-02 15:04:05")
+	return inv, nil
+}
+
+// GetAll retrieves the tax invoices issued within a date range, in number order
+func (r *TaxInvoiceRepository) GetAll(dateFrom, dateTo string) ([]models.TaxInvoice, error) {
+	rows, err := r.db.Query(`
+		SELECT `+taxInvoiceColumns+`
+		FROM tax_invoice ti
+		INNER JOIN transactions t ON ti.transaction_id = t.id
+		WHERE ($1 = '' OR ti.issued_at >= $1::date)
+		  AND ($2 = '' OR ti.issued_at < $2::date + 1)
+		ORDER BY ti.id
+	`, dateFrom, dateTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := []models.TaxInvoice{}
+	for rows.Next() {
+		inv, err := scanTaxInvoice(rows)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, inv)
+	}
+	return invoices, rows.Err()
+}
+
+func (r *TaxInvoiceRepository) GetByID(id int) (models.TaxInvoice, error) {
+	return scanTaxInvoice(r.db.QueryRow(`
+		SELECT `+taxInvoiceColumns+`
+		FROM tax_invoice ti
+		INNER JOIN transactions t ON ti.transaction_id = t.id
+		WHERE ti.id = $1
+	`, id))
+}
+
+// Create issues inv for an active transaction. Its number is drawn from the
+// counter of scope and rendered by number in the same transaction, so a
+// failed issue doesn't leave a gap in the numbers.
+func (r *TaxInvoiceRepository) Create(inv models.TaxInvoice, scope string, number func(seq int64) string) (models.TaxInvoice, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.TaxInvoice{}, err
+	}
+	defer tx.Rollback()
+
+	// locks the sale so it can't get two invoices at once
+	var exists bool
+	err = tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM tax_invoice WHERE transaction_id = t.id)
+		FROM transactions t WHERE t.id = $1 AND t.deleted_at IS NULL
+		FOR UPDATE
+	`, inv.TransactionID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return models.TaxInvoice{}, ErrTransactionNotFound
+	}
+	if err != nil {
+		return models.TaxInvoice{}, err
+	}
+	if exists {
+		return models.TaxInvoice{}, ErrTaxInvoiceExists
+	}
+
+	var seq int64
+	err = tx.QueryRow(`
+		INSERT INTO receipt_sequence (scope, last_value) VALUES ($1, 1)
+		ON CONFLICT (scope) DO UPDATE SET last_value = receipt_sequence.last_value + 1
+		RETURNING last_value
+	`, scope).Scan(&seq)
+	if err != nil {
+		return models.TaxInvoice{}, err
+	}
+
+	var id int
+	err = tx.QueryRow(`
+		INSERT INTO tax_invoice (number, transaction_id, buyer_npwp, buyer_name, buyer_address, tax_rate, tax_base, tax_amount)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`, number(seq), inv.TransactionID, inv.BuyerNPWP, inv.BuyerName, inv.BuyerAddress, inv.TaxRate, inv.TaxBase, inv.TaxAmount).Scan(&id)
+	if err != nil {
+		return models.TaxInvoice{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.TaxInvoice{}, err
+	}
+	return r.GetByID(id)
+}
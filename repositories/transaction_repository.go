@@ -1,133 +1,986 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"kasir-api/events"
 	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/tracing"
 	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Transaction statuses; voided and refunded sales are soft deleted
+const (
+	TransactionDraft          = "draft"
+	TransactionPendingPayment = "pending_payment"
+	TransactionPaid           = "paid"
+	TransactionCompleted      = "completed"
+	TransactionVoided         = "voided"
+	TransactionRefunded       = "refunded"
+)
+
+var (
+	ErrProductNotFound        = errors.New("product not found")
+	ErrDuplicateReceiptNumber = errors.New("receipt number is already used")
+	// ErrTransactionChanged is returned when a sale moved on since it was read
+	ErrTransactionChanged = errors.New("transaction was changed meanwhile, reload it")
+	ErrTableOccupied      = errors.New("table already has an open order, add to it instead")
+	ErrOrderClosed        = errors.New("only a draft sale takes more items")
 )
 
+// InsufficientStockError rejects a checkout line asking for more than is in stock
+type InsufficientStockError struct {
+	ProductID int
+	Name      string
+	Available int
+	Requested int
+}
+
+func (e *InsufficientStockError) Error() string {
+	return fmt.Sprintf("insufficient stock for product '%s' (available: %d, requested: %d)", e.Name, e.Available, e.Requested)
+}
+
+// LinePrice is what a checkout line sells for and what priced it: a pricing
+// rule, a customer's price contract, a price override, or none of them at the
+// product price. Violation refuses the line when it is priced past a
+// guardrail without approval.
+type LinePrice struct {
+	Subtotal  money.Amount
+	Rule      *models.AppliedPricingRule
+	Contract  *models.AppliedPriceContract
+	Override  *models.AppliedPriceOverride
+	Violation *models.PriceViolation
+}
+
+// LinePricer prices a checkout line of quantity units of a product sold at
+// unitPrice and costing costPrice each, at the price override asked for on it
+// unless it is nil
+type LinePricer func(productID int, unitPrice, costPrice money.Amount, quantity int, override *models.PriceOverride) LinePrice
+
+// PriceGuardrailError refuses a sale with lines priced past a guardrail
+// without approval, every one of them listed
+type PriceGuardrailError struct {
+	Violations []models.PriceViolation
+}
+
+func (e *PriceGuardrailError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Field + ": " + v.Message
+	}
+	return "lines priced past the guardrails need approval: " + strings.Join(messages, "; ")
+}
+
+// Tender returns the payment taken in tx for a sale of total, or an error
+// when it doesn't cover it
+type Tender func(tx *sql.Tx, total money.Amount) (*models.TransactionPayment, error)
+
+// Rounder returns what a sale whose lines come to total is charged, e.g.
+// rounded to the nearest 100 rupiah for cash
+type Rounder func(total money.Amount) money.Amount
+
 type TransactionRepository struct {
-	db *sql.DB
+	db     *sql.DB
+	stmts  *Statements
+	outbox *OutboxRepository
 }
 
 func NewTransactionRepository(db *sql.DB) *TransactionRepository {
-	return &TransactionRepository{db: db}
+	return &TransactionRepository{db: db, stmts: StatementsOf(db)}
 }
 
-// CreateTransaction creates a new transaction with its details
-func (repo *TransactionRepository) CreateTransaction(items []models.CheckoutItem) (*models.Transaction, error) {
-	tx, err := repo.db.Begin()
+// WithOutbox has the sales and refunds repo records announced through outbox,
+// in their own database transactions; without it they announce nothing
+func (repo *TransactionRepository) WithOutbox(outbox *OutboxRepository) *TransactionRepository {
+	repo.outbox = outbox
+	return repo
+}
+
+// isDuplicateReceiptNumber reports whether err is a violation of the unique
+// receipt number index, in PostgreSQL or in the SQLite file of a kiosk
+func isDuplicateReceiptNumber(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505" && pqErr.Constraint == "idx_transactions_receipt_number"
+	}
+	return strings.Contains(err.Error(), "UNIQUE constraint failed: transactions.receipt_number")
+}
+
+// OrderNumber is what a restaurant order is called out by: the table it is
+// served at or, taken away, its number in the queue of the day. Other sales
+// have neither.
+type OrderNumber struct {
+	Table string
+	Queue int
+}
+
+// CreateTransaction creates a new transaction in status with its details, each
+// product line priced by price and each open item at its own price, sold to
+// customerID at storeID unless they are 0. The total is rounded by round unless it is nil,
+// and the payment tendered for it is recorded unless tender is nil.
+func (repo *TransactionRepository) CreateTransaction(ctx context.Context, items []models.CheckoutItem, customerID, storeID int, receiptNumber, status string, order OrderNumber, price LinePricer, round Rounder, tender Tender) (*models.Transaction, error) {
+	ctx, span := tracing.Start(ctx, "TransactionRepository.CreateTransaction")
+	defer span.End()
+
+	transaction, err := repo.createTransaction(ctx, items, customerID, storeID, receiptNumber, status, order, price, round, tender)
+	span.SetError(err)
+	return transaction, err
+}
+
+func (repo *TransactionRepository) createTransaction(ctx context.Context, items []models.CheckoutItem, customerID, storeID int, receiptNumber, status string, order OrderNumber, price LinePricer, round Rounder, tender Tender) (*models.Transaction, error) {
+	// the statements of the sale are traced in ctx; a caller going away no
+	// longer rolls back a sale already under way
+	tx, err := repo.db.BeginTx(context.WithoutCancel(ctx), nil)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	totalAmount := 0
-	details := make([]models.TransactionDetail, 0)
+	sale, err := repo.priceSale(tx, items, price)
+	if err != nil {
+		return nil, err
+	}
+	totalAmount := sale.total
+	details := sale.details
 
-	// Step 1: Validate all products and check stock availability
-	type productInfo struct {
-		name  string
-		price int
-		stock int
+	var roundingAdjustment money.Amount
+	if round != nil {
+		totalAmount = round(sale.total)
+		roundingAdjustment = totalAmount - sale.total
 	}
-	productData := make(map[int]productInfo)
 
-	for _, item := range items {
-		var name string
-		var price, stock int
+	var payment *models.TransactionPayment
+	if tender != nil {
+		payment, err = tender(tx, totalAmount)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-		err := tx.QueryRow("SELECT name, price, stock FROM product WHERE id = $1 AND deleted_at IS NULL", item.ProductID).Scan(&name, &price, &stock)
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("product id %d not found", item.ProductID)
+	// Step 4: Insert transaction record
+	var transactionID int
+	var createdAt, deletedAt sql.NullTime
+	err = tx.QueryRow(
+		"INSERT INTO transactions (receipt_number, total_amount, rounding_adjustment, customer_id, store_id, status, table_number, queue_number) VALUES ($1, $2, $3, NULLIF($4, 0), NULLIF($5, 0), $6, NULLIF($7, ''), NULLIF($8, 0)) RETURNING id, created_at, deleted_at",
+		receiptNumber, totalAmount, roundingAdjustment, customerID, storeID, status, order.Table, order.Queue,
+	).Scan(&transactionID, &createdAt, &deletedAt)
+	if isDuplicateReceiptNumber(err) {
+		return nil, ErrDuplicateReceiptNumber
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_transactions_open_table" {
+		return nil, ErrTableOccupied
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := recordStatusEvent(tx, transactionID, "", status, ""); err != nil {
+		return nil, err
+	}
+
+	// Step 4b: Record the payment tendered, and what a voucher paid of it
+	if payment != nil && payment.Currency != "" {
+		_, err = tx.Exec(
+			"INSERT INTO transaction_payment (transaction_id, currency, amount, rate, base_amount, change) VALUES ($1, $2, $3, $4, $5, $6)",
+			transactionID, payment.Currency, payment.Amount, payment.Rate, payment.BaseAmount, payment.Change,
+		)
+		if err != nil {
+			return nil, err
 		}
+	}
+	if payment != nil && payment.Voucher != nil {
+		_, err = tx.Exec(
+			"INSERT INTO voucher_redemption (voucher_id, transaction_id, amount) VALUES ($1, $2, $3)",
+			payment.Voucher.VoucherID, transactionID, payment.Voucher.Amount,
+		)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		// Validate stock availability
-		if stock < item.Quantity {
-			return nil, fmt.Errorf("insufficient stock for product '%s' (available: %d, requested: %d)", name, stock, item.Quantity)
+	if err := repo.recordSale(tx, transactionID, sale); err != nil {
+		return nil, err
+	}
+
+	transaction := &models.Transaction{
+		ID:            transactionID,
+		ReceiptNumber: receiptNumber,
+		CustomerID:    customerID,
+		Status:        status,
+		TableNumber:   order.Table,
+		QueueNumber:   order.Queue,
+		TotalAmount:   totalAmount,
+		Details:       details,
+		Payment:       payment,
+
+		RoundingAdjustment: roundingAdjustment,
+	}
+
+	// Database connection already handles timezone conversion
+	// Timestamps are returned in Asia/Jakarta timezone (UTC+7)
+	if createdAt.Valid {
+		transaction.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+
+	if deletedAt.Valid {
+		transaction.DeletedAt = deletedAt.Time.Format("2006-01-02 15:04:05")
+	}
+
+	if err := repo.announceSale(tx, transaction, sale); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// announceSale records the sale in the outbox, and the products it brought
+// down to their reorder point
+func (repo *TransactionRepository) announceSale(tx *sql.Tx, transaction *models.Transaction, sale pricedSale) error {
+	if repo.outbox == nil {
+		return nil
+	}
+	if err := repo.outbox.Record(tx, events.TransactionCompleted, transaction); err != nil {
+		return err
+	}
+	if len(sale.stockIDs) == 0 {
+		return nil
+	}
+
+	rows, err := tx.Query(
+		"SELECT id, name, barcode, stock, reorder_point, reorder_qty, abc_class FROM product WHERE id = ANY($1) AND reorder_point > 0 AND stock <= reorder_point",
+		pq.Array(sale.stockIDs),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	newlyLow := []models.Product{}
+	for rows.Next() {
+		var p models.Product
+		if err := rows.Scan(&p.ID, &p.Name, &p.Barcode, &p.Stock, &p.ReorderPoint, &p.ReorderQty, &p.ABCClass); err != nil {
+			return err
+		}
+		// already low before this sale, it was announced then
+		if p.Stock+sale.needed[p.ID] <= p.ReorderPoint {
+			continue
 		}
+		newlyLow = append(newlyLow, p)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(newlyLow) == 0 {
+		return nil
+	}
+	return repo.outbox.Record(tx, events.ProductLowStock, newlyLow)
+}
+
+// AppendItems adds items, each product line priced by price, to the draft
+// transaction id and takes their stock. A sale no longer a draft is refused
+// with ErrOrderClosed, one made in a closed accounting period with
+// ErrPeriodClosed.
+func (repo *TransactionRepository) AppendItems(id int, items []models.CheckoutItem, price LinePricer) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var createdAt time.Time
+	err = tx.QueryRow("SELECT status, created_at FROM transactions WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id).Scan(&status, &createdAt)
+	if err != nil {
+		return err
+	}
+	if status != TransactionDraft {
+		return ErrOrderClosed
+	}
+	if err := checkPeriodOpen(tx, createdAt); err != nil {
+		return err
+	}
+
+	sale, err := repo.priceSale(tx, items, price)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE transactions SET total_amount = total_amount + $2 WHERE id = $1", id, sale.total); err != nil {
+		return err
+	}
+	if err := repo.recordSale(tx, id, sale); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// pricedSale is what lines come to and the stock they take, before it is taken
+type pricedSale struct {
+	total    money.Amount
+	details  []models.TransactionDetail
+	stockIDs []int
+	needed   map[int]int
+}
 
-		productData[item.ProductID] = productInfo{
-			name:  name,
-			price: price,
-			stock: stock,
+// priceSale checks the products of items exist and have the stock they need,
+// and prices each line; lines priced past a guardrail are refused together
+func (repo *TransactionRepository) priceSale(tx *sql.Tx, items []models.CheckoutItem, price LinePricer) (pricedSale, error) {
+	var err error
+	sale := pricedSale{details: make([]models.TransactionDetail, 0), needed: make(map[int]int)}
+
+	// Step 1: Validate all products and check stock availability. A bundle
+	// needs stock of each of its components.
+	type productInfo struct {
+		name      string
+		price     money.Amount
+		costPrice money.Amount
+		stock     int
+		isBundle  bool
+	}
+	productData := make(map[int]productInfo)
+	bundleParts := make(map[int][]bundlePart)
+
+	need := func(productID, quantity int) {
+		if _, ok := sale.needed[productID]; !ok {
+			sale.stockIDs = append(sale.stockIDs, productID)
 		}
+		sale.needed[productID] += quantity
 	}
 
-	// Step 2: Calculate total and prepare details
 	for _, item := range items {
-		product := productData[item.ProductID]
-		subtotal := product.price * item.Quantity
-		totalAmount += subtotal
-
-		details = append(details, models.TransactionDetail{
-			ProductID:   item.ProductID,
-			ProductName: product.name,
-			Quantity:    item.Quantity,
-			Subtotal:    subtotal,
-		})
+		// an open item has no product and no stock
+		if item.ProductID == 0 {
+			continue
+		}
+		product, ok := productData[item.ProductID]
+		if !ok {
+			err := repo.stmts.TxQueryRow(tx, "SELECT name, price, cost_price, stock, is_bundle FROM product WHERE id = $1 AND deleted_at IS NULL", item.ProductID).Scan(&product.name, &product.price, &product.costPrice, &product.stock, &product.isBundle)
+			if err == sql.ErrNoRows {
+				return sale, fmt.Errorf("%w: id %d", ErrProductNotFound, item.ProductID)
+			}
+			if err != nil {
+				return sale, err
+			}
+			productData[item.ProductID] = product
+		}
+
+		if !product.isBundle {
+			need(item.ProductID, item.Quantity)
+			continue
+		}
+
+		parts, ok := bundleParts[item.ProductID]
+		if !ok {
+			parts, err = getBundleParts(tx, item.ProductID)
+			if err != nil {
+				return sale, err
+			}
+			bundleParts[item.ProductID] = parts
+		}
+		for _, part := range parts {
+			if _, ok := productData[part.productID]; !ok {
+				productData[part.productID] = productInfo{name: part.name, price: part.price, costPrice: part.costPrice, stock: part.stock}
+			}
+			need(part.productID, part.quantity*item.Quantity)
+		}
 	}
 
-	// Step 3: Update stock for all products
-	for _, item := range items {
-		_, err = tx.Exec("UPDATE product SET stock = stock - $1 WHERE id = $2", item.Quantity, item.ProductID)
-		if err != nil {
-			return nil, err
+	for _, productID := range sale.stockIDs {
+		product := productData[productID]
+		if product.stock < sale.needed[productID] {
+			return sale, &InsufficientStockError{ProductID: productID, Name: product.name, Available: product.stock, Requested: sale.needed[productID]}
 		}
 	}
 
-	// Step 4: Insert transaction record
-	var transactionID int
-	var createdAt, deletedAt sql.NullTime
-	err = tx.QueryRow("INSERT INTO transactions (total_amount) VALUES ($1) RETURNING id, created_at, deleted_at", totalAmount).Scan(&transactionID, &createdAt, &deletedAt)
-	if err != nil {
-		return nil, err
+	// Step 2: Calculate total and prepare details
+	var violations []models.PriceViolation
+	for i, item := range items {
+		if item.ProductID == 0 {
+			subtotal := item.Price.Times(item.Quantity)
+			sale.total += subtotal
+			sale.details = append(sale.details, models.TransactionDetail{
+				ProductName: item.Description,
+				OpenItem:    true,
+				Quantity:    item.Quantity,
+				Subtotal:    subtotal,
+			})
+			continue
+		}
+
+		product := productData[item.ProductID]
+		costPrice := product.costPrice
+		if product.isBundle {
+			costPrice = 0
+			for _, part := range bundleParts[item.ProductID] {
+				costPrice += part.costPrice.Times(part.quantity)
+			}
+		}
+		line := price(item.ProductID, product.price, costPrice, item.Quantity, item.Override)
+		if line.Violation != nil {
+			violation := *line.Violation
+			violation.Field, violation.ProductID = fmt.Sprintf("items[%d]", i), item.ProductID
+			violations = append(violations, violation)
+		}
+		subtotal := line.Subtotal
+		sale.total += subtotal
+
+		detail := models.TransactionDetail{
+			ProductID:     item.ProductID,
+			ProductName:   product.name,
+			Quantity:      item.Quantity,
+			Subtotal:      subtotal,
+			CostPrice:     costPrice,
+			PricingRule:   line.Rule,
+			PriceContract: line.Contract,
+			PriceOverride: line.Override,
+		}
+		if product.isBundle {
+			detail.Components = allocateBundleRevenue(subtotal, bundleParts[item.ProductID], item.Quantity)
+		}
+		sale.details = append(sale.details, detail)
+	}
+	if len(violations) > 0 {
+		return sale, &PriceGuardrailError{Violations: violations}
+	}
+	return sale, nil
+}
+
+// recordSale takes the stock of sale, bundles through their components, and
+// inserts its lines into transaction transactionID
+func (repo *TransactionRepository) recordSale(tx *sql.Tx, transactionID int, sale pricedSale) error {
+	details := sale.details
+
+	// Step 3: Update stock for all products, bundles through their components,
+	// perishables from the batch expiring first
+	for _, productID := range sale.stockIDs {
+		if _, err := repo.stmts.TxExec(tx, "UPDATE product SET stock = stock - $1 WHERE id = $2", sale.needed[productID], productID); err != nil {
+			return err
+		}
+		if _, err := repo.stmts.TxExec(tx, takeBatchesQuery, productID, sale.needed[productID]); err != nil {
+			return err
+		}
 	}
 
 	// Step 5: Batch insert transaction details
 	if len(details) > 0 {
 		valueStrings := make([]string, 0, len(details))
-		valueArgs := make([]interface{}, 0, len(details)*4)
+		valueArgs := make([]interface{}, 0, len(details)*10)
 
 		for i, detail := range details {
 			details[i].TransactionID = transactionID
-			valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d, $%d)",
-				i*4+1, i*4+2, i*4+3, i*4+4))
-			valueArgs = append(valueArgs, transactionID, detail.ProductID, detail.Quantity, detail.Subtotal)
+			valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+				i*10+1, i*10+2, i*10+3, i*10+4, i*10+5, i*10+6, i*10+7, i*10+8, i*10+9, i*10+10))
+
+			var productID, ruleID, contractID sql.NullInt64
+			description := ""
+			if detail.OpenItem {
+				description = detail.ProductName
+			} else {
+				productID = sql.NullInt64{Int64: int64(detail.ProductID), Valid: true}
+			}
+			var discount money.Amount
+			if detail.PricingRule != nil {
+				ruleID = sql.NullInt64{Int64: int64(detail.PricingRule.ID), Valid: true}
+				discount = detail.PricingRule.Discount
+			}
+			if detail.PriceContract != nil {
+				contractID = sql.NullInt64{Int64: int64(detail.PriceContract.ID), Valid: true}
+				discount = detail.PriceContract.Discount
+			}
+			if detail.PriceOverride != nil {
+				discount = detail.PriceOverride.Discount
+			}
+			valueArgs = append(valueArgs, transactionID, productID, description, detail.Quantity, detail.Subtotal, detail.CostPrice, detail.Components != nil, ruleID, contractID, discount)
 		}
 
-		query := fmt.Sprintf("INSERT INTO transaction_details (transaction_id, product_id, quantity, subtotal) VALUES %s",
+		query := fmt.Sprintf("INSERT INTO transaction_details (transaction_id, product_id, description, quantity, subtotal, cost_price, is_bundle, pricing_rule_id, price_contract_id, discount) VALUES %s RETURNING id",
 			strings.Join(valueStrings, ","))
 
-		_, err = tx.Exec(query, valueArgs...)
+		rows, err := tx.Query(query, valueArgs...)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		for i := 0; rows.Next(); i++ {
+			if err := rows.Scan(&details[i].ID); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
+	// Step 5b: Record what each bundle line took from its components
+	for _, detail := range details {
+		for _, c := range detail.Components {
+			_, err := repo.stmts.TxExec(tx,
+				"INSERT INTO transaction_bundle_component (detail_id, transaction_id, product_id, quantity, revenue, cost) VALUES ($1, $2, $3, $4, $5, $6)",
+				detail.ID, transactionID, c.ProductID, c.Quantity, c.Revenue, c.Cost,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Step 5c: Record the price overrides and who approved them
+	for _, detail := range details {
+		if o := detail.PriceOverride; o != nil {
+			_, err := tx.Exec(
+				"INSERT INTO price_override (detail_id, transaction_id, original_subtotal, price, extra_discount, reason, approved_by, approver) VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6, $7, $8)",
+				detail.ID, transactionID, o.OriginalSubtotal, o.Price, o.ExtraDiscount, o.Reason, o.ApprovedByID, o.ApprovedBy,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Step 6: Log one stock movement per product sold
+	for _, productID := range sale.stockIDs {
+		if err := recordStockMovement(tx, productID, -sale.needed[productID], MovementSale, transactionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetStatus returns the status of a transaction, voided and refunded ones included
+func (repo *TransactionRepository) GetStatus(id int) (string, error) {
+	var status string
+	err := repo.db.QueryRow("SELECT status FROM transactions WHERE id = $1", id).Scan(&status)
+	return status, err
+}
+
+// UpdateStatus moves a transaction from status from to status to and records
+// why. A sale made in a closed accounting period is refused with
+// ErrPeriodClosed, as settling it would change the month's revenue as much as
+// voiding it. A voided or refunded sale is soft deleted and its stock and
+// voucher balance put back. A draft billed, moved on to anything but voided,
+// has its total rounded by round unless it is nil, as a checkout would have.
+// The daily summaries of the sale's day are dropped to be rolled up again.
+func (repo *TransactionRepository) UpdateStatus(id int, from, to, reason string, round Rounder) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ends := to == TransactionVoided || to == TransactionRefunded
+	var createdAt time.Time
+	err = tx.QueryRow(`
+		UPDATE transactions SET status = $3, deleted_at = CASE WHEN $4 THEN NOW() ELSE deleted_at END
+		WHERE id = $1 AND status = $2
+		RETURNING created_at
+	`, id, from, to, ends).Scan(&createdAt)
+	if err == sql.ErrNoRows {
+		return ErrTransactionChanged
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := checkPeriodOpen(tx, createdAt); err != nil {
+		return err
+	}
+	if from == TransactionDraft && !ends && round != nil {
+		if err := roundTotal(tx, id, round); err != nil {
+			return err
+		}
+	}
+	if ends {
+		reason := MovementVoid
+		if to == TransactionRefunded {
+			reason = MovementRefund
+		}
+		if err := restock(tx, id, reason); err != nil {
+			return err
+		}
+		if err := reverseRedemption(tx, id); err != nil {
+			return err
+		}
+	}
+
+	if err := dropDailySummaries(tx, createdAt); err != nil {
+		return err
+	}
+
+	if err := recordStatusEvent(tx, id, from, to, reason); err != nil {
+		return err
+	}
+	if to == TransactionRefunded {
+		err := repo.outbox.Record(tx, events.RefundIssued, models.TransactionStatusEvent{
+			TransactionID: id,
+			FromStatus:    from,
+			Status:        to,
+			Reason:        reason,
+			CreatedAt:     time.Now().Format("2006-01-02 15:04:05"),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// roundTotal rounds the total of transaction id by round, from what its lines
+// come to, and records the adjustment
+func roundTotal(tx *sql.Tx, id int, round Rounder) error {
+	var lines money.Amount
+	if err := tx.QueryRow("SELECT total_amount - rounding_adjustment FROM transactions WHERE id = $1", id).Scan(&lines); err != nil {
+		return err
+	}
+	total := round(lines)
+	_, err := tx.Exec("UPDATE transactions SET total_amount = $2, rounding_adjustment = $3 WHERE id = $1", id, total, total-lines)
+	return err
+}
+
+// dropDailySummaries removes the summaries of the day of a sale made at at,
+// which no longer hold: the day is read live until the next aggregation rolls
+// it up again. Days are the store's, so the ones either side of the UTC date
+// go too; product rows are removed by the cascade.
+func dropDailySummaries(tx *sql.Tx, at time.Time) error {
+	_, err := tx.Exec(`
+		DELETE FROM daily_sales_summary
+		WHERE date BETWEEN ($1::timestamptz AT TIME ZONE 'UTC')::date - 1 AND ($1::timestamptz AT TIME ZONE 'UTC')::date + 1
+	`, at)
+	return err
+}
+
+// restock puts back the stock a sale took, as a movement for reason;
+// perishables come back undated, their batches aren't restored
+func restock(tx *sql.Tx, transactionID int, reason string) error {
+	rows, err := tx.Query(`
+		SELECT product_id, -SUM(quantity) FROM stock_movement
+		WHERE reason = $1 AND reference_id = $2
+		GROUP BY product_id
+		ORDER BY product_id
+	`, MovementSale, transactionID)
+	if err != nil {
+		return err
+	}
+	taken := map[int]int{}
+	productIDs := []int{}
+	for rows.Next() {
+		var productID, quantity int
+		if err := rows.Scan(&productID, &quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		taken[productID] = quantity
+		productIDs = append(productIDs, productID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, productID := range productIDs {
+		if taken[productID] == 0 {
+			continue
+		}
+		if _, err := tx.Exec("UPDATE product SET stock = stock + $1 WHERE id = $2", taken[productID], productID); err != nil {
+			return err
+		}
+		if err := recordStockMovement(tx, productID, taken[productID], reason, transactionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetStatusHistory returns the statuses a transaction went through, oldest first
+func (repo *TransactionRepository) GetStatusHistory(id int) ([]models.TransactionStatusEvent, error) {
+	if _, err := repo.GetStatus(id); err != nil {
 		return nil, err
 	}
 
-	transaction := &models.Transaction{
-		ID:          transactionID,
-		TotalAmount: totalAmount,
-		Details:     details,
+	rows, err := repo.db.Query("SELECT from_status, status, reason, created_at FROM transaction_status_event WHERE transaction_id = $1 ORDER BY id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.TransactionStatusEvent{}
+	for rows.Next() {
+		e := models.TransactionStatusEvent{TransactionID: id}
+		var createdAt time.Time
+		if err := rows.Scan(&e.FromStatus, &e.Status, &e.Reason, &createdAt); err != nil {
+			return nil, err
+		}
+		e.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func recordStatusEvent(tx *sql.Tx, transactionID int, from, to, reason string) error {
+	_, err := tx.Exec(
+		"INSERT INTO transaction_status_event (transaction_id, from_status, status, reason) VALUES ($1, $2, $3, $4)",
+		transactionID, from, to, reason,
+	)
+	return err
+}
+
+// GetByID retrieves an active transaction with its details
+func (repo *TransactionRepository) GetByID(id int) (*models.Transaction, error) {
+	transaction := &models.Transaction{}
+	var createdAt, deletedAt sql.NullTime
+	err := repo.stmts.QueryRow(
+		"SELECT id, COALESCE(receipt_number, ''), COALESCE(customer_id, 0), COALESCE(store_id, 0), status, COALESCE(table_number, ''), COALESCE(queue_number, 0), total_amount, rounding_adjustment, created_at, deleted_at FROM transactions WHERE id = $1 AND deleted_at IS NULL",
+		id,
+	).Scan(&transaction.ID, &transaction.ReceiptNumber, &transaction.CustomerID, &transaction.StoreID, &transaction.Status, &transaction.TableNumber, &transaction.QueueNumber, &transaction.TotalAmount, &transaction.RoundingAdjustment, &createdAt, &deletedAt)
+	if err != nil {
+		return nil, err
 	}
 
-	// Database connection already handles timezone conversion
-	// Timestamps are returned in Asia/Jakarta timezone (UTC+7)
 	if createdAt.Valid {
 		transaction.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
 	}
-
 	if deletedAt.Valid {
 		transaction.DeletedAt = deletedAt.Time.Format("2006-01-02 15:04:05")
 	}
 
-	return transaction, nil
+	var payment models.TransactionPayment
+	err = repo.stmts.QueryRow(
+		"SELECT currency, amount, rate, base_amount, change FROM transaction_payment WHERE transaction_id = $1",
+		id,
+	).Scan(&payment.Currency, &payment.Amount, &payment.Rate, &payment.BaseAmount, &payment.Change)
+	if err == nil {
+		if c, err := money.Lookup(payment.Currency); err == nil {
+			tendered := payment.Amount.In(c)
+			payment.Tendered = &tendered
+		}
+		transaction.Payment = &payment
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	var voucher models.VoucherPayment
+	err = repo.stmts.QueryRow(`
+		SELECT v.id, v.code, r.amount, v.balance
+		FROM voucher_redemption r
+		INNER JOIN voucher v ON r.voucher_id = v.id
+		WHERE r.transaction_id = $1
+	`, id).Scan(&voucher.VoucherID, &voucher.Code, &voucher.Amount, &voucher.Balance)
+	if err == nil {
+		if transaction.Payment == nil {
+			transaction.Payment = &models.TransactionPayment{}
+		}
+		transaction.Payment.Voucher = &voucher
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	// products deleted since the sale still name their lines, open items are
+	// named by their description
+	rows, err := repo.stmts.Query(`
+		SELECT td.id, td.transaction_id, COALESCE(td.product_id, 0), COALESCE(p.name, td.description), td.product_id IS NOT NULL AND (p.id IS NULL OR p.deleted_at IS NOT NULL), td.product_id IS NULL, td.quantity, td.subtotal,
+		       pr.id, COALESCE(pr.name, ''), COALESCE(pr.min_quantity, 0), COALESCE(pr.price, 0), td.discount,
+		       pc.id, COALESCE(pc.name, ''),
+		       po.detail_id, COALESCE(po.original_subtotal, 0), COALESCE(po.price, 0), COALESCE(po.extra_discount, 0), COALESCE(po.reason, ''), COALESCE(po.approved_by, 0), COALESCE(po.approver, '')
+		FROM transaction_details td
+		LEFT JOIN product p ON td.product_id = p.id
+		LEFT JOIN pricing_rule pr ON td.pricing_rule_id = pr.id
+		LEFT JOIN price_contract pc ON td.price_contract_id = pc.id
+		LEFT JOIN price_override po ON po.detail_id = td.id
+		WHERE td.transaction_id = $1
+		ORDER BY td.id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transaction.Details = []models.TransactionDetail{}
+	for rows.Next() {
+		var d models.TransactionDetail
+		var ruleID, contractID, overrideID sql.NullInt64
+		var rule models.AppliedPricingRule
+		var contract models.AppliedPriceContract
+		var override models.AppliedPriceOverride
+		var discount money.Amount
+		if err := rows.Scan(&d.ID, &d.TransactionID, &d.ProductID, &d.ProductName, &d.ProductDeleted, &d.OpenItem, &d.Quantity, &d.Subtotal, &ruleID, &rule.Name, &rule.MinQuantity, &rule.Price, &discount, &contractID, &contract.Name,
+			&overrideID, &override.OriginalSubtotal, &override.Price, &override.ExtraDiscount, &override.Reason, &override.ApprovedByID, &override.ApprovedBy); err != nil {
+			return nil, err
+		}
+		if ruleID.Valid {
+			rule.ID = int(ruleID.Int64)
+			rule.Discount = discount
+			d.PricingRule = &rule
+		}
+		// contract lines sell every unit at the contract price
+		if contractID.Valid {
+			contract.ID = int(contractID.Int64)
+			contract.Price = d.Subtotal / money.Amount(d.Quantity)
+			contract.Discount = discount
+			d.PriceContract = &contract
+		}
+		if overrideID.Valid {
+			override.Discount = discount
+			d.PriceOverride = &override
+		}
+		transaction.Details = append(transaction.Details, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	componentRows, err := repo.stmts.Query(`
+		SELECT c.detail_id, c.product_id, COALESCE(p.name, ''), p.id IS NULL OR p.deleted_at IS NOT NULL, c.quantity, c.revenue, c.cost
+		FROM transaction_bundle_component c
+		LEFT JOIN product p ON c.product_id = p.id
+		WHERE c.transaction_id = $1
+		ORDER BY c.detail_id, p.name
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer componentRows.Close()
+
+	for componentRows.Next() {
+		var detailID int
+		var c models.BundleComponentSale
+		if err := componentRows.Scan(&detailID, &c.ProductID, &c.ProductName, &c.ProductDeleted, &c.Quantity, &c.Revenue, &c.Cost); err != nil {
+			return nil, err
+		}
+		for i := range transaction.Details {
+			if transaction.Details[i].ID == detailID {
+				transaction.Details[i].Components = append(transaction.Details[i].Components, c)
+			}
+		}
+	}
+
+	return transaction, componentRows.Err()
+}
+
+// bundlePart is a component of a bundle as sold at checkout
+type bundlePart struct {
+	productID int
+	name      string
+	quantity  int
+	price     money.Amount
+	costPrice money.Amount
+	stock     int
+}
+
+// getBundleParts retrieves the components of a bundle in tx, in a stable order
+func getBundleParts(tx *sql.Tx, bundleID int) ([]bundlePart, error) {
+	rows, err := tx.Query(`
+		SELECT c.id, c.name, b.quantity, c.price, c.cost_price, c.stock
+		FROM product_bundle_item b
+		INNER JOIN product c ON b.component_id = c.id
+		WHERE b.bundle_id = $1
+		ORDER BY c.id
+	`, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	parts := []bundlePart{}
+	for rows.Next() {
+		var part bundlePart
+		if err := rows.Scan(&part.productID, &part.name, &part.quantity, &part.price, &part.costPrice, &part.stock); err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+	return parts, rows.Err()
+}
+
+// allocateBundleRevenue splits the subtotal of a bundle line over its
+// components in proportion to what they would sell for on their own. The
+// rounding remainder goes to the last component so the shares add up.
+func allocateBundleRevenue(subtotal money.Amount, parts []bundlePart, bundles int) []models.BundleComponentSale {
+	weights := make([]int, len(parts))
+	totalWeight := 0
+	for i, part := range parts {
+		weights[i] = int(part.price.Times(part.quantity))
+		totalWeight += weights[i]
+	}
+	// components without a price of their own share by quantity
+	if totalWeight == 0 {
+		for i, part := range parts {
+			weights[i] = part.quantity
+			totalWeight += weights[i]
+		}
+	}
+
+	sales := make([]models.BundleComponentSale, len(parts))
+	var allocated money.Amount
+	for i, part := range parts {
+		var revenue money.Amount
+		if totalWeight > 0 {
+			revenue = subtotal * money.Amount(weights[i]) / money.Amount(totalWeight)
+		}
+		if i == len(parts)-1 {
+			revenue = subtotal - allocated
+		}
+		allocated += revenue
+
+		sales[i] = models.BundleComponentSale{
+			ProductID:   part.productID,
+			ProductName: part.name,
+			Quantity:    part.quantity * bundles,
+			Revenue:     revenue,
+			Cost:        part.costPrice.Times(part.quantity * bundles),
+		}
+	}
+	return sales
+}
+
+// GetPage retrieves up to limit sales after cursor, newest first, voided and
+// refunded ones included, and the cursor of the page after
+func (repo *TransactionRepository) GetPage(cursor Cursor, limit int) (models.TransactionPage, error) {
+	query := "SELECT id, COALESCE(receipt_number, ''), COALESCE(customer_id, 0), status, COALESCE(table_number, ''), COALESCE(queue_number, 0), total_amount, created_at, deleted_at FROM transactions"
+	args := []interface{}{}
+	if !cursor.IsZero() {
+		args = append(args, cursor.CreatedAt, cursor.ID)
+		query += " WHERE (created_at, id) < ($1, $2)"
+	}
+	// one row more tells whether there is a page after
+	args = append(args, limit+1)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return models.TransactionPage{}, err
+	}
+	defer rows.Close()
+
+	page := models.TransactionPage{Transactions: []models.TransactionSummary{}}
+	var last Cursor
+	for rows.Next() {
+		var t models.TransactionSummary
+		var createdAt time.Time
+		var deletedAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.ReceiptNumber, &t.CustomerID, &t.Status, &t.TableNumber, &t.QueueNumber, &t.TotalAmount, &createdAt, &deletedAt); err != nil {
+			return models.TransactionPage{}, err
+		}
+		if len(page.Transactions) == limit {
+			page.NextCursor = last.String()
+			break
+		}
+
+		t.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+		if deletedAt.Valid {
+			t.DeletedAt = deletedAt.Time.Format("2006-01-02 15:04:05")
+		}
+		page.Transactions = append(page.Transactions, t)
+		last = Cursor{CreatedAt: createdAt, ID: t.ID}
+	}
+	return page, rows.Err()
 }
@@ -0,0 +1,75 @@
+package repositories_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"kasir-api/database"
+	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/repositories"
+	"kasir-api/repositories/sqlite"
+)
+
+// benchCatalog migrates a fresh in-memory kiosk database and fills it with
+// products with stock enough for any run
+func benchCatalog(b *testing.B, products int) (*sql.DB, []int) {
+	b.Helper()
+	db, err := database.OpenSQLiteMemory()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+	if err := database.MigrateSQLite(db); err != nil {
+		b.Fatal(err)
+	}
+
+	store := sqlite.NewProductRepository(db)
+	ids := make([]int, products)
+	for i := range ids {
+		p, err := store.Create(models.Product{
+			Name:      fmt.Sprintf("Bench product %d", i+1),
+			SKU:       fmt.Sprintf("BENCH-%06d", i+1),
+			Price:     money.Amount(5000 + 500*(i%20)),
+			CostPrice: money.Amount(4000 + 400*(i%20)),
+			Stock:     1 << 30,
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		ids[i] = p.ID
+	}
+	return db, ids
+}
+
+// BenchmarkCreateTransaction records checkouts of a few lines each: pricing,
+// taking stock and writing the sale, its lines and movements in one database
+// transaction
+func BenchmarkCreateTransaction(b *testing.B) {
+	price := func(productID int, unitPrice, costPrice money.Amount, quantity int, override *models.PriceOverride) repositories.LinePrice {
+		return repositories.LinePrice{Subtotal: unitPrice.Times(quantity)}
+	}
+
+	for _, n := range []int{1, 5, 20} {
+		b.Run(fmt.Sprintf("items=%d", n), func(b *testing.B) {
+			db, productIDs := benchCatalog(b, 50)
+			repo := repositories.NewTransactionRepository(db)
+			items := make([]models.CheckoutItem, n)
+			for i := range items {
+				items[i] = models.CheckoutItem{ProductID: productIDs[i*7%len(productIDs)], Quantity: 1 + i%3}
+			}
+
+			b.ReportAllocs()
+			receipt := 0
+			for b.Loop() {
+				receipt++
+				_, err := repo.CreateTransaction(context.Background(), items, 0, 0, fmt.Sprintf("BENCH/%08d", receipt), repositories.TransactionCompleted, repositories.OrderNumber{}, price, nil, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/money"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrVoucherNotFound      = errors.New("voucher not found")
+	ErrVoucherExpired       = errors.New("voucher has expired")
+	ErrVoucherUsedUp        = errors.New("voucher has no balance left")
+	ErrDuplicateVoucherCode = errors.New("another voucher already has this code")
+)
+
+// VoucherRepository keeps gift cards and vouchers with what every sale took
+// from them. Codes are kept in upper case and looked up regardless of case.
+type VoucherRepository struct {
+	db *sql.DB
+}
+
+func NewVoucherRepository(db *sql.DB) *VoucherRepository {
+	return &VoucherRepository{db: db}
+}
+
+const voucherColumns = "id, code, initial_amount, balance, expires_on, note, created_at"
+
+func scanVoucher(row rowScanner) (models.Voucher, error) {
+	var v models.Voucher
+	var expiresOn, createdAt sql.NullTime
+	if err := row.Scan(&v.ID, &v.Code, &v.InitialAmount, &v.Balance, &expiresOn, &v.Note, &createdAt); err != nil {
+		return models.Voucher{}, err
+	}
+	if expiresOn.Valid {
+		v.ExpiresOn = expiresOn.Time.Format("2006-01-02")
+	}
+	if createdAt.Valid {
+		v.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return v, nil
+}
+
+// GetAll returns every voucher, newest first
+func (r *VoucherRepository) GetAll() ([]models.Voucher, error) {
+	rows, err := r.db.Query("SELECT " + voucherColumns + " FROM voucher ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	vouchers := []models.Voucher{}
+	for rows.Next() {
+		v, err := scanVoucher(rows)
+		if err != nil {
+			return nil, err
+		}
+		vouchers = append(vouchers, v)
+	}
+	return vouchers, rows.Err()
+}
+
+// GetByCode returns the voucher of code with its redemptions, oldest first
+func (r *VoucherRepository) GetByCode(code string) (models.Voucher, error) {
+	v, err := scanVoucher(r.db.QueryRow("SELECT "+voucherColumns+" FROM voucher WHERE code = $1", strings.ToUpper(code)))
+	if err == sql.ErrNoRows {
+		return models.Voucher{}, ErrVoucherNotFound
+	}
+	if err != nil {
+		return models.Voucher{}, err
+	}
+
+	rows, err := r.db.Query(
+		"SELECT transaction_id, amount, reversed_at, created_at FROM voucher_redemption WHERE voucher_id = $1 ORDER BY id",
+		v.ID,
+	)
+	if err != nil {
+		return models.Voucher{}, err
+	}
+	defer rows.Close()
+
+	v.Redemptions = []models.VoucherRedemption{}
+	for rows.Next() {
+		var redemption models.VoucherRedemption
+		var reversedAt, createdAt sql.NullTime
+		if err := rows.Scan(&redemption.TransactionID, &redemption.Amount, &reversedAt, &createdAt); err != nil {
+			return models.Voucher{}, err
+		}
+		if reversedAt.Valid {
+			redemption.ReversedAt = reversedAt.Time.Format("2006-01-02 15:04:05")
+		}
+		if createdAt.Valid {
+			redemption.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+		}
+		v.Redemptions = append(v.Redemptions, redemption)
+	}
+	return v, rows.Err()
+}
+
+// Create issues a voucher of code worth amount, expiring at the end of
+// expiresOn unless it is zero
+func (r *VoucherRepository) Create(code string, amount money.Amount, expiresOn time.Time, note string) (models.Voucher, error) {
+	var expires sql.NullString
+	if !expiresOn.IsZero() {
+		expires = sql.NullString{String: expiresOn.Format("2006-01-02"), Valid: true}
+	}
+
+	v, err := scanVoucher(r.db.QueryRow(
+		"INSERT INTO voucher (code, initial_amount, balance, expires_on, note) VALUES ($1, $2, $2, $3::date, $4) RETURNING "+voucherColumns,
+		strings.ToUpper(code), amount, expires, note,
+	))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return models.Voucher{}, ErrDuplicateVoucherCode
+	}
+	return v, err
+}
+
+// Redeem takes up to amount from the balance of the voucher of code in tx,
+// locking it until tx ends, and returns what was taken. The sale records the
+// redemption once it has an ID.
+func (r *VoucherRepository) Redeem(tx *sql.Tx, code string, amount money.Amount) (models.VoucherPayment, error) {
+	var payment models.VoucherPayment
+	var balance money.Amount
+	var expired bool
+	err := tx.QueryRow(
+		"SELECT id, code, balance, COALESCE(expires_on < CURRENT_DATE, FALSE) FROM voucher WHERE code = $1 FOR UPDATE",
+		strings.ToUpper(strings.TrimSpace(code)),
+	).Scan(&payment.VoucherID, &payment.Code, &balance, &expired)
+	if err == sql.ErrNoRows {
+		return payment, ErrVoucherNotFound
+	}
+	if err != nil {
+		return payment, err
+	}
+	if expired {
+		return payment, ErrVoucherExpired
+	}
+	if balance == 0 {
+		return payment, ErrVoucherUsedUp
+	}
+
+	payment.Amount = amount
+	if balance < amount {
+		payment.Amount = balance
+	}
+	if _, err := tx.Exec("UPDATE voucher SET balance = balance - $1 WHERE id = $2", payment.Amount, payment.VoucherID); err != nil {
+		return payment, err
+	}
+	payment.Balance = balance - payment.Amount
+	return payment, nil
+}
+
+// reverseRedemption gives a voucher back what a voided or refunded sale took
+// from it
+func reverseRedemption(tx *sql.Tx, transactionID int) error {
+	_, err := tx.Exec(`
+		WITH reversed AS (
+			UPDATE voucher_redemption SET reversed_at = NOW()
+			WHERE transaction_id = $1 AND reversed_at IS NULL
+			RETURNING voucher_id, amount
+		)
+		UPDATE voucher v SET balance = v.balance + reversed.amount
+		FROM reversed WHERE v.id = reversed.voucher_id
+	`, transactionID)
+	return err
+}
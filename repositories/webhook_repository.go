@@ -0,0 +1,191 @@
+package repositories
+
+import (
+	"database/sql"
+	"kasir-api/models"
+
+	"github.com/lib/pq"
+)
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// GetAll retrieves all registered webhooks
+func (r *WebhookRepository) GetAll() ([]models.Webhook, error) {
+	rows, err := r.db.Query("SELECT id, url, events, secret, active, created_at FROM webhook WHERE deleted_at IS NULL ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanWebhooks(rows)
+}
+
+// GetActiveByEvent retrieves the active webhooks subscribed to an event
+func (r *WebhookRepository) GetActiveByEvent(event string) ([]models.Webhook, error) {
+	rows, err := r.db.Query(
+		"SELECT id, url, events, secret, active, created_at FROM webhook WHERE deleted_at IS NULL AND active AND $1 = ANY(events) ORDER BY id",
+		event,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanWebhooks(rows)
+}
+
+func scanWebhooks(rows *sql.Rows) ([]models.Webhook, error) {
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var wh models.Webhook
+		var createdAt sql.NullTime
+		if err := rows.Scan(&wh.ID, &wh.URL, pq.Array(&wh.Events), &wh.Secret, &wh.Active, &createdAt); err != nil {
+			return nil, err
+		}
+		if createdAt.Valid {
+			wh.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+		}
+		webhooks = append(webhooks, wh)
+	}
+	return webhooks, nil
+}
+
+// GetByID retrieves a webhook by ID
+func (r *WebhookRepository) GetByID(id int) (models.Webhook, error) {
+	var wh models.Webhook
+	var createdAt sql.NullTime
+	err := r.db.QueryRow(
+		"SELECT id, url, events, secret, active, created_at FROM webhook WHERE id = $1 AND deleted_at IS NULL",
+		id,
+	).Scan(&wh.ID, &wh.URL, pq.Array(&wh.Events), &wh.Secret, &wh.Active, &createdAt)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	if createdAt.Valid {
+		wh.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return wh, nil
+}
+
+// Create registers a new webhook
+func (r *WebhookRepository) Create(wh models.Webhook) (models.Webhook, error) {
+	var createdAt sql.NullTime
+	err := r.db.QueryRow(
+		"INSERT INTO webhook (url, events, secret, active) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		wh.URL, pq.Array(wh.Events), wh.Secret, wh.Active,
+	).Scan(&wh.ID, &createdAt)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	if createdAt.Valid {
+		wh.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return wh, nil
+}
+
+// Update updates an existing webhook
+func (r *WebhookRepository) Update(wh models.Webhook) (models.Webhook, error) {
+	_, err := r.db.Exec(
+		"UPDATE webhook SET url = $1, events = $2, active = $3 WHERE id = $4 AND deleted_at IS NULL",
+		wh.URL, pq.Array(wh.Events), wh.Active, wh.ID,
+	)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	return wh, nil
+}
+
+// Delete soft deletes a webhook, keeping its delivery log
+func (r *WebhookRepository) Delete(id int) error {
+	result, err := r.db.Exec("UPDATE webhook SET deleted_at = NOW(), active = FALSE WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CreateDelivery records a pending delivery of an event payload
+func (r *WebhookRepository) CreateDelivery(webhookID int, event string, payload []byte) (int, error) {
+	var id int
+	err := r.db.QueryRow(
+		"INSERT INTO webhook_delivery (webhook_id, event, payload) VALUES ($1, $2, $3) RETURNING id",
+		webhookID, event, payload,
+	).Scan(&id)
+	return id, err
+}
+
+// GetDelivery retrieves a delivery by ID
+func (r *WebhookRepository) GetDelivery(id int) (models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload []byte
+	err := r.db.QueryRow(
+		"SELECT id, webhook_id, event, payload, status, attempts, response_code, error FROM webhook_delivery WHERE id = $1",
+		id,
+	).Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts, &d.ResponseCode, &d.Error)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	d.Payload = payload
+	return d, nil
+}
+
+// UpdateDelivery records the outcome of a delivery attempt
+func (r *WebhookRepository) UpdateDelivery(id int, status string, attempts, responseCode int, errMsg string) error {
+	_, err := r.db.Exec(
+		"UPDATE webhook_delivery SET status = $1, attempts = $2, response_code = $3, error = $4, delivered_at = CASE WHEN $1 = 'delivered' THEN NOW() ELSE delivered_at END WHERE id = $5",
+		status, attempts, responseCode, errMsg, id,
+	)
+	return err
+}
+
+// GetDeliveries retrieves the most recent deliveries of a webhook, newest first
+func (r *WebhookRepository) GetDeliveries(webhookID, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.Query(`
+		SELECT id, webhook_id, event, payload, status, attempts, response_code, error, created_at, delivered_at
+		FROM webhook_delivery
+		WHERE webhook_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var payload []byte
+		var createdAt, deliveredAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts, &d.ResponseCode, &d.Error, &createdAt, &deliveredAt); err != nil {
+			return nil, err
+		}
+		d.Payload = payload
+		if createdAt.Valid {
+			d.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+		}
+		if deliveredAt.Valid {
+			d.DeliveredAt = deliveredAt.Time.Format("2006-01-02 15:04:05")
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"kasir-api/models"
+)
+
+var ErrSupplierNotFound = errors.New("supplier not found")
+
+type WriteOffRepository struct {
+	db *sql.DB
+}
+
+func NewWriteOffRepository(db *sql.DB) *WriteOffRepository {
+	return &WriteOffRepository{db: db}
+}
+
+const writeOffQuery = `
+	SELECT w.id, w.product_id, p.name, w.supplier_id, COALESCE(s.name, ''), w.reason, w.quantity, w.unit_cost, w.note, w.created_at
+	FROM stock_write_off w
+	INNER JOIN product p ON w.product_id = p.id
+	LEFT JOIN supplier s ON w.supplier_id = s.id
+`
+
+func scanWriteOff(row rowScanner) (models.WriteOff, error) {
+	var w models.WriteOff
+	var supplierID sql.NullInt64
+	var createdAt sql.NullTime
+	err := row.Scan(&w.ID, &w.ProductID, &w.ProductName, &supplierID, &w.SupplierName, &w.Reason, &w.Quantity, &w.UnitCost, &w.Note, &createdAt)
+	if err != nil {
+		return models.WriteOff{}, err
+	}
+
+	if supplierID.Valid {
+		id := int(supplierID.Int64)
+		w.SupplierID = &id
+	}
+	w.TotalCost = w.UnitCost.Times(w.Quantity)
+	if createdAt.Valid {
+		w.CreatedAt = createdAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return w, nil
+}
+
+// Create takes the quantity out of stock and logs it in the stock ledger
+// under the write-off reason
+func (r *WriteOffRepository) Create(req models.WriteOffRequest) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var name string
+	var stock, costPrice int
+	err = tx.QueryRow(
+		"SELECT name, stock, cost_price FROM product WHERE id = $1 AND deleted_at IS NULL FOR UPDATE",
+		req.ProductID,
+	).Scan(&name, &stock, &costPrice)
+	if err == sql.ErrNoRows {
+		return 0, ErrProductNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if stock < req.Quantity {
+		return 0, &InsufficientStockError{ProductID: req.ProductID, Name: name, Available: stock, Requested: req.Quantity}
+	}
+
+	if req.SupplierID != 0 {
+		var exists bool
+		err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM supplier WHERE id = $1 AND deleted_at IS NULL)", req.SupplierID).Scan(&exists)
+		if err != nil {
+			return 0, err
+		}
+		if !exists {
+			return 0, ErrSupplierNotFound
+		}
+	}
+
+	var id int
+	err = tx.QueryRow(`
+		INSERT INTO stock_write_off (product_id, supplier_id, reason, quantity, unit_cost, note)
+		VALUES ($1, NULLIF($2, 0), $3, $4, $5, $6) RETURNING id
+	`, req.ProductID, req.SupplierID, req.Reason, req.Quantity, costPrice, req.Note).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec("UPDATE product SET stock = stock - $1 WHERE id = $2", req.Quantity, req.ProductID)
+	if err != nil {
+		return 0, err
+	}
+	if err := takeBatches(tx, req.ProductID, req.Quantity); err != nil {
+		return 0, err
+	}
+
+	if err := recordStockMovement(tx, req.ProductID, -req.Quantity, req.Reason, id); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetAll retrieves write-offs newest first, optionally filtered by reason and date range
+func (r *WriteOffRepository) GetAll(reason, dateFrom, dateTo string) ([]models.WriteOff, error) {
+	rows, err := r.db.Query(writeOffQuery+`
+		WHERE ($1 = '' OR w.reason = $1)
+			AND ($2 = '' OR w.created_at >= $2::date)
+			AND ($3 = '' OR w.created_at < $3::date + 1)
+		ORDER BY w.created_at DESC
+	`, reason, dateFrom, dateTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	writeOffs := []models.WriteOff{}
+	for rows.Next() {
+		w, err := scanWriteOff(rows)
+		if err != nil {
+			return nil, err
+		}
+		writeOffs = append(writeOffs, w)
+	}
+	return writeOffs, rows.Err()
+}
+
+// GetByID retrieves a write-off by ID
+func (r *WriteOffRepository) GetByID(id int) (models.WriteOff, error) {
+	return scanWriteOff(r.db.QueryRow(writeOffQuery+" WHERE w.id = $1", id))
+}
@@ -0,0 +1,179 @@
+package router
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"kasir-api/metrics"
+	"kasir-api/utils"
+)
+
+var deprecatedRequests = metrics.NewCounter("kasir_deprecated_requests_total", "Requests to deprecated routes by route and method", "route", "method")
+
+// maxCallers bounds how many distinct callers are remembered per deprecated route
+const maxCallers = 200
+
+// Deprecation marks a route as on its way out, e.g. once its v2 replacement
+// is out. Its responses carry the Deprecation header (RFC 9745), Sunset
+// (RFC 8594) when the date it goes away is set and a successor-version Link
+// when it has a replacement.
+type Deprecation struct {
+	Since     time.Time
+	Sunset    time.Time
+	Successor string
+}
+
+// DeprecatedRoute is a deprecated route with the callers still using it
+type DeprecatedRoute struct {
+	Pattern   string   `json:"pattern"`
+	Since     string   `json:"since"`
+	Sunset    string   `json:"sunset,omitempty"`
+	Successor string   `json:"successor,omitempty"`
+	Requests  int      `json:"requests"`
+	Callers   []Caller `json:"callers"`
+}
+
+// Caller is a client seen on a deprecated route, told apart by address and
+// user agent
+type Caller struct {
+	Address   string `json:"address"`
+	UserAgent string `json:"user_agent"`
+	Requests  int    `json:"requests"`
+	LastSeen  string `json:"last_seen"`
+}
+
+type deprecatedRoute struct {
+	Deprecation
+	mu       sync.Mutex
+	requests int
+	callers  map[[2]string]*Caller
+}
+
+// Deprecate marks the route registered, or to be registered, for pattern as
+// deprecated. Call it before serving.
+func (rt *Router) Deprecate(pattern string, d Deprecation) {
+	rt.deprecations[pattern] = &deprecatedRoute{Deprecation: d, callers: map[[2]string]*Caller{}}
+}
+
+// deprecated reports whether the route serving path is deprecated
+func (rt *Router) deprecated(path string) bool {
+	for _, r := range rt.routes {
+		if matches(r.pattern, path) {
+			_, ok := rt.deprecations[r.pattern]
+			return ok
+		}
+	}
+	return false
+}
+
+// announce sets the deprecation headers of a request to pattern and records
+// its caller, logging callers the first time they are seen. Past maxCallers
+// new callers are only counted.
+func (rt *Router) announce(pattern string, w http.ResponseWriter, r *http.Request) {
+	d, ok := rt.deprecations[pattern]
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+	if !d.Sunset.IsZero() {
+		w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Successor != "" {
+		w.Header().Add("Link", "<"+d.Successor+`>; rel="successor-version"`)
+	}
+	deprecatedRequests.Inc(pattern, r.Method)
+
+	address, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		address = r.RemoteAddr
+	}
+	key := [2]string{address, r.UserAgent()}
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.requests++
+	caller, seen := d.callers[key]
+	if !seen {
+		if len(d.callers) >= maxCallers {
+			return
+		}
+		log.Printf("Deprecated route %s %s called by %s (%s)", r.Method, pattern, address, r.UserAgent())
+		caller = &Caller{Address: address, UserAgent: r.UserAgent()}
+		d.callers[key] = caller
+	}
+	caller.Requests++
+	caller.LastSeen = now.Format("2006-01-02 15:04:05")
+}
+
+// Deprecations returns the deprecated routes, soonest sunset first, with the
+// callers seen on each since the server started, most requests first
+func (rt *Router) Deprecations() []DeprecatedRoute {
+	routes := []DeprecatedRoute{}
+	for pattern, d := range rt.deprecations {
+		route := DeprecatedRoute{
+			Pattern:   pattern,
+			Since:     d.Since.Format("2006-01-02"),
+			Successor: d.Successor,
+			Callers:   []Caller{},
+		}
+		if !d.Sunset.IsZero() {
+			route.Sunset = d.Sunset.Format("2006-01-02")
+		}
+
+		d.mu.Lock()
+		route.Requests = d.requests
+		for _, c := range d.callers {
+			route.Callers = append(route.Callers, *c)
+		}
+		d.mu.Unlock()
+
+		sort.Slice(route.Callers, func(i, j int) bool {
+			return route.Callers[i].Requests > route.Callers[j].Requests
+		})
+		routes = append(routes, route)
+	}
+
+	// routes without a sunset go last
+	sort.Slice(routes, func(i, j int) bool {
+		if (routes[i].Sunset == "") != (routes[j].Sunset == "") {
+			return routes[j].Sunset == ""
+		}
+		if routes[i].Sunset != routes[j].Sunset {
+			return routes[i].Sunset < routes[j].Sunset
+		}
+		return routes[i].Pattern < routes[j].Pattern
+	})
+	return routes
+}
+
+// DeprecationHandler godoc
+// @Summary      Get deprecated routes
+// @Description  Get the deprecated routes, soonest sunset first, with the callers seen on each since the server started, told apart by address and user agent, to see who still has to move before a route is removed
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Router       /admin/deprecations [get]
+func (rt *Router) DeprecationHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, utils.Response{
+			Status:  "success",
+			Message: "Deprecated routes retrieved successfully",
+			Data:    rt.Deprecations(),
+		})
+	}
+}
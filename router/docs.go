@@ -0,0 +1,146 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"kasir-api/utils"
+)
+
+// FilterDoc returns a copy of the Swagger 2.0 document that only contains the
+// paths meant for role, with unreferenced definitions and tags removed. The
+// operations of deprecated routes are flagged deprecated.
+func (rt *Router) FilterDoc(doc []byte, role Role) ([]byte, error) {
+	var spec map[string]interface{}
+	if err := json.Unmarshal(doc, &spec); err != nil {
+		return nil, fmt.Errorf("error parsing OpenAPI document: %v", err)
+	}
+
+	basePath, _ := spec["basePath"].(string)
+	basePath = strings.TrimSuffix(basePath, "/")
+
+	paths, _ := spec["paths"].(map[string]interface{})
+	for path, ops := range paths {
+		if !rt.Allows(basePath+path, role) {
+			delete(paths, path)
+			continue
+		}
+		if rt.deprecated(basePath + path) {
+			opsMap, _ := ops.(map[string]interface{})
+			for _, op := range opsMap {
+				if opMap, ok := op.(map[string]interface{}); ok {
+					opMap["deprecated"] = true
+				}
+			}
+		}
+	}
+
+	if info, ok := spec["info"].(map[string]interface{}); ok {
+		if title, _ := info["title"].(string); title != "" {
+			info["title"] = title + " (" + string(role) + ")"
+		}
+	}
+
+	// keep only the definitions reachable from the remaining paths
+	definitions, _ := spec["definitions"].(map[string]interface{})
+	used := map[string]bool{}
+	pending := collectRefs(paths, nil)
+	for len(pending) > 0 {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if used[name] {
+			continue
+		}
+		used[name] = true
+		pending = collectRefs(definitions[name], pending)
+	}
+	for name := range definitions {
+		if !used[name] {
+			delete(definitions, name)
+		}
+	}
+
+	if tags, ok := spec["tags"].([]interface{}); ok {
+		usedTags := collectTags(paths)
+		kept := make([]interface{}, 0, len(tags))
+		for _, tag := range tags {
+			if t, ok := tag.(map[string]interface{}); ok && usedTags[fmt.Sprint(t["name"])] {
+				kept = append(kept, tag)
+			}
+		}
+		spec["tags"] = kept
+	}
+
+	return json.Marshal(spec)
+}
+
+// DocHandler serves the document returned by readDoc filtered for the role
+// named by the last path segment, e.g. /docs/cashier.json
+func (rt *Router) DocHandler(readDoc func() string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			utils.WriteJSON(w, http.StatusMethodNotAllowed, utils.Response{
+				Status:  "failed",
+				Message: "Method not allowed",
+			})
+			return
+		}
+
+		name := strings.TrimSuffix(r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:], ".json")
+		role, ok := ParseRole(name)
+		if !ok {
+			utils.WriteJSON(w, http.StatusNotFound, utils.Response{
+				Status:  "failed",
+				Message: "Unknown role",
+			})
+			return
+		}
+
+		doc, err := rt.FilterDoc([]byte(readDoc()), role)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Response{
+				Status:  "failed",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(doc)
+	}
+}
+
+func collectRefs(node interface{}, refs []string) []string {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			if ref, ok := val.(string); ok && key == "$ref" {
+				refs = append(refs, strings.TrimPrefix(ref, "#/definitions/"))
+				continue
+			}
+			refs = collectRefs(val, refs)
+		}
+	case []interface{}:
+		for _, val := range v {
+			refs = collectRefs(val, refs)
+		}
+	}
+	return refs
+}
+
+func collectTags(paths map[string]interface{}) map[string]bool {
+	tags := map[string]bool{}
+	for _, ops := range paths {
+		opsMap, _ := ops.(map[string]interface{})
+		for _, op := range opsMap {
+			opMap, _ := op.(map[string]interface{})
+			list, _ := opMap["tags"].([]interface{})
+			for _, tag := range list {
+				tags[fmt.Sprint(tag)] = true
+			}
+		}
+	}
+	return tags
+}
@@ -0,0 +1,144 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"kasir-api/metrics"
+	"kasir-api/tracing"
+	"kasir-api/utils"
+)
+
+var (
+	httpRequests = metrics.NewCounter("kasir_http_requests_total", "HTTP requests by route, method and status code", "route", "method", "code")
+	httpDuration = metrics.NewSummary("kasir_http_request_duration_seconds", "Time spent serving HTTP requests by route", "route")
+)
+
+// Role is the persona an endpoint is meant for
+type Role string
+
+const (
+	RoleCashier Role = "cashier"
+	RoleAdmin   Role = "admin"
+	// RolePublic is anyone on the internet, e.g. the store's website
+	RolePublic Role = "public"
+)
+
+// Roles lists every persona that gets its own API documentation
+var Roles = []Role{RoleCashier, RoleAdmin, RolePublic}
+
+// ParseRole returns the role named by s
+func ParseRole(s string) (Role, bool) {
+	for _, role := range Roles {
+		if string(role) == s {
+			return role, true
+		}
+	}
+	return "", false
+}
+
+type route struct {
+	pattern string
+	roles   []Role
+}
+
+// Router registers handlers on a ServeMux and remembers which roles each
+// route is meant for, so the API documentation can be filtered per persona,
+// and which routes are deprecated.
+type Router struct {
+	mux          *http.ServeMux
+	routes       []route
+	deprecations map[string]*deprecatedRoute
+}
+
+func NewRouter(mux *http.ServeMux) *Router {
+	return &Router{mux: mux, deprecations: map[string]*deprecatedRoute{}}
+}
+
+// HandleFunc registers handler for pattern and records the roles allowed to
+// use it. Requests are counted per route pattern; those to a deprecated route
+// are announced as such.
+func (rt *Router) HandleFunc(pattern string, roles []Role, handler http.HandlerFunc) {
+	rt.mux.HandleFunc(pattern, instrument(pattern, func(w http.ResponseWriter, r *http.Request) {
+		rt.announce(pattern, w, r)
+		handler(w, r)
+	}))
+	rt.routes = append(rt.routes, route{pattern: pattern, roles: roles})
+
+	// longest pattern first, the same precedence ServeMux uses
+	sort.SliceStable(rt.routes, func(i, j int) bool {
+		return len(rt.routes[i].pattern) > len(rt.routes[j].pattern)
+	})
+}
+
+// Allows reports whether the route serving path is meant for role. Paths not
+// registered through the router are allowed for nobody.
+func (rt *Router) Allows(path string, role Role) bool {
+	for _, r := range rt.routes {
+		if !matches(r.pattern, path) {
+			continue
+		}
+		for _, allowed := range r.roles {
+			if allowed == role {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func matches(pattern, path string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		return strings.HasPrefix(path, pattern)
+	}
+	return pattern == path
+}
+
+// statusRecorder remembers the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the writer underneath, e.g. to
+// flush a stream
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// instrument counts and times the requests to pattern, and traces each as a
+// server span continuing the caller's trace
+func instrument(pattern string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx, span := tracing.StartServer(tracing.Extract(r.Context(), r.Header), r.Method+" "+pattern)
+		span.SetAttribute("http.request.method", r.Method)
+		span.SetAttribute("http.route", pattern)
+		span.SetAttribute("url.path", r.URL.Path)
+		if requestID := r.Header.Get(utils.RequestIDHeader); requestID != "" {
+			span.SetAttribute("http.request_id", requestID)
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler(rec, r.WithContext(ctx))
+
+		span.SetAttribute("http.response.status_code", rec.status)
+		if rec.status >= http.StatusInternalServerError {
+			span.SetError(errors.New(http.StatusText(rec.status)))
+		}
+		span.End()
+
+		httpRequests.Inc(pattern, r.Method, strconv.Itoa(rec.status))
+		httpDuration.Observe(time.Since(start).Seconds(), pattern)
+	}
+}
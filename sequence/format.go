@@ -0,0 +1,119 @@
+package sequence
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reset periods, after which the counter starts again at 1
+const (
+	ResetNever   = "never"
+	ResetYearly  = "yearly"
+	ResetMonthly = "monthly"
+	ResetDaily   = "daily"
+)
+
+var tokenPattern = regexp.MustCompile(`\{([A-Z]+)(?::(\d+))?\}`)
+
+// Format renders sequence numbers from a layout such as "INV/{YYYY}/{MM}/{SEQ:6}".
+// Tokens are {STORE}, {YYYY}, {YY}, {MM}, {DD} and {SEQ} or {SEQ:n}, the counter
+// zero padded to n digits.
+type Format struct {
+	layout string
+	reset  string
+}
+
+// ParseFormat validates layout for the reset period. The layout must hold
+// exactly one {SEQ} and the date parts the period resets on, otherwise the
+// same number would come round again after a reset.
+func ParseFormat(layout, reset string) (*Format, error) {
+	tokens := map[string]int{}
+	for _, m := range tokenPattern.FindAllStringSubmatch(layout, -1) {
+		switch m[1] {
+		case "STORE", "YYYY", "YY", "MM", "DD", "SEQ":
+			tokens[m[1]]++
+		default:
+			return nil, fmt.Errorf("unknown token {%s} in format %q", m[1], layout)
+		}
+		if m[2] != "" && m[1] != "SEQ" {
+			return nil, fmt.Errorf("only {SEQ} takes a width in format %q", layout)
+		}
+	}
+	if tokens["SEQ"] != 1 {
+		return nil, fmt.Errorf("format %q must contain {SEQ} exactly once", layout)
+	}
+
+	hasYear := tokens["YYYY"] > 0 || tokens["YY"] > 0
+	var required bool
+	switch reset {
+	case ResetNever:
+		required = true
+	case ResetYearly:
+		required = hasYear
+	case ResetMonthly:
+		required = hasYear && tokens["MM"] > 0
+	case ResetDaily:
+		required = hasYear && tokens["MM"] > 0 && tokens["DD"] > 0
+	default:
+		return nil, fmt.Errorf("reset must be one of never, yearly, monthly, daily")
+	}
+	if !required {
+		return nil, fmt.Errorf("format %q must contain the date parts of a %s reset", layout, reset)
+	}
+
+	return &Format{layout: layout, reset: reset}, nil
+}
+
+// Layout returns the layout the format was parsed from
+func (f *Format) Layout() string {
+	return f.layout
+}
+
+// Reset returns the period after which the counter starts again at 1
+func (f *Format) Reset() string {
+	return f.reset
+}
+
+// HasStore reports whether numbers include the store code
+func (f *Format) HasStore() bool {
+	return strings.Contains(f.layout, "{STORE}")
+}
+
+// Scope returns the key of the counter a number of store at t is drawn from
+func (f *Format) Scope(store string, t time.Time) string {
+	switch f.reset {
+	case ResetYearly:
+		return store + "|" + t.Format("2006")
+	case ResetMonthly:
+		return store + "|" + t.Format("2006-01")
+	case ResetDaily:
+		return store + "|" + t.Format("2006-01-02")
+	default:
+		return store + "|"
+	}
+}
+
+// Render formats counter value seq of store at t
+func (f *Format) Render(store string, t time.Time, seq int64) string {
+	return tokenPattern.ReplaceAllStringFunc(f.layout, func(token string) string {
+		m := tokenPattern.FindStringSubmatch(token)
+		switch m[1] {
+		case "STORE":
+			return store
+		case "YYYY":
+			return t.Format("2006")
+		case "YY":
+			return t.Format("06")
+		case "MM":
+			return t.Format("01")
+		case "DD":
+			return t.Format("02")
+		default:
+			width, _ := strconv.Atoi(m[2])
+			return fmt.Sprintf("%0*d", width, seq)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package sequence
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		layout string
+		reset  string
+		ok     bool
+	}{
+		{"INV/{YYYY}/{MM}/{SEQ:6}", ResetMonthly, true},
+		{"INV/{YY}{MM}{DD}-{SEQ}", ResetDaily, true},
+		{"{STORE}/{YYYY}/{SEQ:4}", ResetYearly, true},
+		{"{SEQ:8}", ResetNever, true},
+		{"{YYYY}-{SEQ}", ResetNever, true},
+		{"INV/{MM}/{SEQ}", ResetMonthly, false},
+		{"INV/{YYYY}/{SEQ}", ResetMonthly, false},
+		{"INV/{YYYY}/{MM}/{SEQ}", ResetDaily, false},
+		{"INV/{SEQ}", ResetYearly, false},
+		{"INV/{YYYY}", ResetNever, false},
+		{"INV/{SEQ}/{SEQ}", ResetNever, false},
+		{"INV/{WEEK}/{SEQ}", ResetNever, false},
+		{"INV/{YYYY:4}/{SEQ}", ResetYearly, false},
+		{"INV/{YYYY}/{SEQ}", "weekly", false},
+	}
+	for _, tt := range tests {
+		f, err := ParseFormat(tt.layout, tt.reset)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseFormat(%q, %q) error = %v, want ok %v", tt.layout, tt.reset, err, tt.ok)
+			continue
+		}
+		if tt.ok && (f.Layout() != tt.layout || f.Reset() != tt.reset) {
+			t.Errorf("ParseFormat(%q, %q) = %q, %q", tt.layout, tt.reset, f.Layout(), f.Reset())
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	at := time.Date(2026, 3, 7, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		layout string
+		reset  string
+		seq    int64
+		want   string
+	}{
+		{"INV/{YYYY}/{MM}/{SEQ:6}", ResetMonthly, 42, "INV/2026/03/000042"},
+		{"{STORE}-{YY}{MM}{DD}-{SEQ:3}", ResetDaily, 7, "T01-260307-007"},
+		{"{SEQ}", ResetNever, 1234567, "1234567"},
+		{"{SEQ:2}", ResetNever, 1234, "1234"},
+		{"{STORE}/{YYYY}/{SEQ:4}", ResetYearly, 0, "T01/2026/0000"},
+	}
+	for _, tt := range tests {
+		f, err := ParseFormat(tt.layout, tt.reset)
+		if err != nil {
+			t.Fatalf("ParseFormat(%q, %q): %v", tt.layout, tt.reset, err)
+		}
+		if got := f.Render("T01", at, tt.seq); got != tt.want {
+			t.Errorf("Render of %q at %d = %q, want %q", tt.layout, tt.seq, got, tt.want)
+		}
+	}
+}
+
+func TestScope(t *testing.T) {
+	at := time.Date(2026, 3, 7, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		reset string
+		want  string
+	}{
+		{ResetNever, "T01|"},
+		{ResetYearly, "T01|2026"},
+		{ResetMonthly, "T01|2026-03"},
+		{ResetDaily, "T01|2026-03-07"},
+	}
+	for _, tt := range tests {
+		f, err := ParseFormat("{STORE}{YYYY}{MM}{DD}{SEQ}", tt.reset)
+		if err != nil {
+			t.Fatalf("ParseFormat for %s: %v", tt.reset, err)
+		}
+		if got := f.Scope("T01", at); got != tt.want {
+			t.Errorf("Scope for %s = %q, want %q", tt.reset, got, tt.want)
+		}
+	}
+}
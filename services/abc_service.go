@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sort"
+
+	"kasir-api/jobs"
+	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/repositories"
+)
+
+const JobABCClassification = "inventory.abc_classification"
+
+// abcWindowDays is the sales history products are classified on
+const abcWindowDays = 90
+
+type ABCService struct {
+	repo *repositories.ABCRepository
+}
+
+func NewABCService(repo *repositories.ABCRepository, runner *jobs.Runner) *ABCService {
+	s := &ABCService{repo: repo}
+	runner.Register(JobABCClassification, s.classifyJob)
+	return s
+}
+
+func (s *ABCService) classifyJob(ctx context.Context, job models.Job) error {
+	classified, err := s.Classify()
+	if err == nil {
+		log.Printf("Classified %d products into ABC classes", classified)
+	}
+	return err
+}
+
+// Classify recomputes the class of every product and returns the number of products classified
+func (s *ABCService) Classify() (int, error) {
+	revenues, err := s.repo.GetProductRevenue(abcWindowDays)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.repo.SaveClasses(classifyABC(revenues)); err != nil {
+		return 0, err
+	}
+	return len(revenues), nil
+}
+
+// GetSummary returns the product count and revenue share of each class
+func (s *ABCService) GetSummary() (*models.ABCSummary, error) {
+	classes, err := s.repo.GetClassSummary(abcWindowDays)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.ABCSummary{WindowDays: abcWindowDays, Classes: classes}
+	for _, c := range classes {
+		summary.TotalRevenue += c.Revenue
+	}
+	for i := range summary.Classes {
+		summary.Classes[i].RevenueShare = margin(summary.Classes[i].Revenue, summary.TotalRevenue)
+	}
+
+	classifiedAt, err := s.repo.GetLastClassifiedAt()
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	summary.ClassifiedAt = classifiedAt
+
+	return summary, nil
+}
+
+// classifyABC ranks products by revenue: A items make up the first 80% of
+// revenue, B the next 15% and C the rest, including products without sales
+func classifyABC(revenues []models.ProductRevenue) map[int]string {
+	sorted := make([]models.ProductRevenue, len(revenues))
+	copy(sorted, revenues)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Revenue > sorted[j].Revenue })
+
+	var total money.Amount
+	for _, pr := range sorted {
+		total += pr.Revenue
+	}
+
+	classes := make(map[int]string, len(sorted))
+	var cumulative money.Amount
+	for _, pr := range sorted {
+		class := "C"
+		if pr.Revenue > 0 {
+			share := float64(cumulative) / float64(total)
+			switch {
+			case share < 0.80:
+				class = "A"
+			case share < 0.95:
+				class = "B"
+			}
+		}
+		cumulative += pr.Revenue
+		classes[pr.ProductID] = class
+	}
+	return classes
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var ErrInvalidActivityEntity = errors.New("entity must be product, category or transaction")
+
+// activityEntities are the kinds of records the activity feed covers
+var activityEntities = []string{"product", "category", "transaction"}
+
+// ActivityService pages through the activity feed, what happened to the
+// catalog and to sales, for the manager app
+type ActivityService struct {
+	repo *repositories.ActivityRepository
+}
+
+func NewActivityService(repo *repositories.ActivityRepository) *ActivityService {
+	return &ActivityService{repo: repo}
+}
+
+// GetPage retrieves the page of activity after cursor, of one entity unless
+// entity is empty
+func (s *ActivityService) GetPage(entity, cursor string, limit int) (models.ActivityPage, error) {
+	if entity != "" && !slices.Contains(activityEntities, entity) {
+		return models.ActivityPage{}, ErrInvalidActivityEntity
+	}
+	c, ok := repositories.ParseCursor(cursor)
+	if !ok {
+		return models.ActivityPage{}, ErrInvalidCursor
+	}
+
+	page, err := s.repo.GetPage(entity, c, min(limit, maxHistoryPage))
+	if err != nil {
+		return models.ActivityPage{}, err
+	}
+	for i := range page.Activities {
+		page.Activities[i].Message = activityMessage(page.Activities[i])
+	}
+	return page, nil
+}
+
+// activityMessage says what a happened, e.g. "Product Indomie Goreng created"
+func activityMessage(a models.ActivityEntry) string {
+	if a.Entity == "transaction" {
+		sale := "#" + fmt.Sprint(a.EntityID)
+		if a.Name != "" {
+			sale = a.Name
+		}
+		if a.Action == "price_overridden" {
+			return fmt.Sprintf("Line of sale %s repriced from Rp%s to Rp%s", sale, a.OldValue, a.NewValue)
+		}
+		message := fmt.Sprintf("Sale %s of Rp%s voided", sale, a.OldValue)
+		if a.Action == "refunded" {
+			message = fmt.Sprintf("Refund of Rp%s issued for sale %s", a.OldValue, sale)
+		}
+		if a.NewValue != "" {
+			message += ": " + a.NewValue
+		}
+		return message
+	}
+
+	entity := strings.ToUpper(a.Entity[:1]) + a.Entity[1:]
+	switch a.Action {
+	case "renamed":
+		return fmt.Sprintf("%s %s renamed to %s", entity, a.OldValue, a.NewValue)
+	case "price_changed":
+		return fmt.Sprintf("Price of %s changed from Rp%s to Rp%s", a.Name, a.OldValue, a.NewValue)
+	default:
+		return fmt.Sprintf("%s %s %s", entity, a.Name, a.Action)
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var (
+	ErrInvalidUsername = errors.New("username must be 3 to 32 lowercase letters, digits, dots, dashes or underscores")
+	ErrWeakPassword    = errors.New("password must be at least 8 characters")
+	ErrRoleRequired    = errors.New("role is required")
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9._-]{3,32}$`)
+
+// PBKDF2 parameters of new password hashes; the iteration count follows the
+// OWASP recommendation for PBKDF2-HMAC-SHA256
+const (
+	passwordIterations = 600000
+	passwordSaltLen    = 16
+	passwordKeyLen     = 32
+)
+
+type AdminUserService struct {
+	repo *repositories.AdminUserRepository
+}
+
+func NewAdminUserService(repo *repositories.AdminUserRepository) *AdminUserService {
+	return &AdminUserService{repo: repo}
+}
+
+// Create adds an admin in role with a hashed copy of password; email is optional
+func (s *AdminUserService) Create(username, email, role, password string) (models.AdminUser, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	if !usernamePattern.MatchString(username) {
+		return models.AdminUser{}, ErrInvalidUsername
+	}
+	email = normalizeEmail(email)
+	if email != "" {
+		if _, err := mail.ParseAddress(email); err != nil {
+			return models.AdminUser{}, ErrInvalidEmail
+		}
+	}
+	if len([]rune(password)) < 8 {
+		return models.AdminUser{}, ErrWeakPassword
+	}
+
+	role = strings.TrimSpace(role)
+	if role == "" {
+		return models.AdminUser{}, ErrRoleRequired
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return models.AdminUser{}, err
+	}
+	return s.repo.Create(username, email, role, hash)
+}
+
+// normalizeEmail lowercases an address, so it matches however it is typed
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// hashPassword returns password as "pbkdf2-sha256$iterations$salt$key" with
+// the salt and key in unpadded base64
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, passwordSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, passwordKeyLen)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", passwordIterations, enc.EncodeToString(salt), enc.EncodeToString(key)), nil
+}
+
+// checkPassword reports whether password is the one hash was made of by hashPassword
+func checkPassword(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+
+	enc := base64.RawStdEncoding
+	salt, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := enc.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(key, want) == 1
+}
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"kasir-api/mailer"
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var (
+	ErrAuthNotConfigured   = errors.New("email links are not configured, set AUTH_TOKEN_SECRET")
+	ErrVerificationNotSent = errors.New("the admin was created, but the verification email could not be sent")
+)
+
+// Purposes of the tokens in email links; a token only works for its own
+const (
+	TokenVerifyEmail   = "verify_email"
+	TokenResetPassword = "reset_password"
+)
+
+// authEmailsPerHour caps the links of each purpose mailed to one admin an
+// hour, so the public endpoints can't be used to flood an inbox
+const authEmailsPerHour = 3
+
+var authEmailTemplate = template.Must(template.New("auth").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif;">
+	<p>Halo {{.Username}},</p>
+	<p>{{.Intro}}</p>
+	<p><a href="{{.Link}}">{{.Action}}</a></p>
+	<p>Tautan ini berlaku sampai {{.ExpiresAt}} dan hanya bisa dipakai sekali. Abaikan email ini jika Anda tidak memintanya.</p>
+</body>
+</html>`))
+
+type authEmailView struct {
+	Username  string
+	Intro     string
+	Action    string
+	Link      string
+	ExpiresAt string
+}
+
+// AuthService confirms admins' email addresses and resets forgotten
+// passwords, the ones they sign in with at /api/auth/login, through links
+// mailed there. A link carries
+// "<id>.<expiry>.<secret>.<signature>": the signature is an HMAC of the rest
+// and the purpose with AUTH_TOKEN_SECRET, and only a hash of the secret part
+// is stored, so the tokens table alone can't be used to forge or replay one.
+type AuthService struct {
+	admins    *AdminUserService
+	users     *repositories.AdminUserRepository
+	tokens    *repositories.AuthTokenRepository
+	mailer    mailer.Mailer
+	secret    []byte
+	appURL    string
+	verifyTTL time.Duration
+	resetTTL  time.Duration
+}
+
+// NewAuthService links to appURL's /verify-email and /reset-password pages;
+// without a secret no link is issued
+func NewAuthService(users *repositories.AdminUserRepository, tokens *repositories.AuthTokenRepository, m mailer.Mailer, secret, appURL string, verifyTTL, resetTTL time.Duration) *AuthService {
+	return &AuthService{
+		admins:    NewAdminUserService(users),
+		users:     users,
+		tokens:    tokens,
+		mailer:    m,
+		secret:    []byte(secret),
+		appURL:    strings.TrimRight(appURL, "/"),
+		verifyTTL: verifyTTL,
+		resetTTL:  resetTTL,
+	}
+}
+
+// Register creates an admin and, with an email, mails it a link to confirm
+// the address. The admin is created even when the email then fails, which
+// ErrVerificationNotSent reports; the link can be sent again.
+func (s *AuthService) Register(req models.RegisterAdminRequest) (models.AdminUser, error) {
+	if strings.TrimSpace(req.Email) != "" && len(s.secret) == 0 {
+		return models.AdminUser{}, ErrAuthNotConfigured
+	}
+
+	admin, err := s.admins.Create(req.Username, req.Email, req.Role, req.Password)
+	if err != nil {
+		return models.AdminUser{}, err
+	}
+	if admin.Email == "" {
+		return admin, nil
+	}
+
+	msg, err := s.verificationEmail(admin)
+	if err == nil {
+		err = s.mailer.Send(msg)
+	}
+	if err != nil {
+		return admin, fmt.Errorf("%w: %v", ErrVerificationNotSent, err)
+	}
+	return admin, nil
+}
+
+// ResendVerification mails a new confirmation link to an unverified address.
+// Whether the address belongs to an admin isn't revealed: unknown and
+// verified addresses, and the ones over authEmailsPerHour, are skipped alike.
+func (s *AuthService) ResendVerification(email string) error {
+	admin, ok, err := s.recipient(email, TokenVerifyEmail)
+	if err != nil || !ok || admin.EmailVerified {
+		return err
+	}
+
+	msg, err := s.verificationEmail(admin)
+	if err != nil {
+		return err
+	}
+	s.deliver(msg)
+	return nil
+}
+
+// ForgotPassword mails a password reset link to an admin's address, without
+// revealing whether the address belongs to one
+func (s *AuthService) ForgotPassword(email string) error {
+	admin, ok, err := s.recipient(email, TokenResetPassword)
+	if err != nil || !ok {
+		return err
+	}
+
+	token, expiresAt, err := s.issue(admin, TokenResetPassword, s.resetTTL)
+	if err != nil {
+		return err
+	}
+	msg, err := s.email(admin, "Atur ulang kata sandi Kasir", authEmailView{
+		Intro:  "Kami menerima permintaan untuk mengatur ulang kata sandi akun Kasir Anda.",
+		Action: "Atur ulang kata sandi",
+		Link:   s.link("/reset-password", token),
+	}, expiresAt)
+	if err != nil {
+		return err
+	}
+	s.deliver(msg)
+	return nil
+}
+
+// VerifyEmail confirms the address a verification token was sent to
+func (s *AuthService) VerifyEmail(token string) error {
+	id, secret, err := s.parse(token, TokenVerifyEmail)
+	if err != nil {
+		return err
+	}
+	return s.tokens.VerifyEmail(id, TokenVerifyEmail, hashAuthToken(secret))
+}
+
+// ResetPassword replaces the password of the admin a reset token was sent
+// to; the admin's other reset links stop working
+func (s *AuthService) ResetPassword(token, password string) error {
+	if len([]rune(password)) < 8 {
+		return ErrWeakPassword
+	}
+	id, secret, err := s.parse(token, TokenResetPassword)
+	if err != nil {
+		return err
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	return s.tokens.ResetPassword(id, TokenResetPassword, hashAuthToken(secret), hash)
+}
+
+// recipient looks up the admin a link of purpose is for; ok is false when
+// there is none, or when authEmailsPerHour of them were already sent
+func (s *AuthService) recipient(email, purpose string) (models.AdminUser, bool, error) {
+	if len(s.secret) == 0 {
+		return models.AdminUser{}, false, ErrAuthNotConfigured
+	}
+
+	admin, err := s.users.GetByEmail(normalizeEmail(email))
+	if err == sql.ErrNoRows {
+		return models.AdminUser{}, false, nil
+	}
+	if err != nil {
+		return models.AdminUser{}, false, err
+	}
+
+	sent, err := s.tokens.CountSince(admin.ID, purpose, time.Now().Add(-time.Hour))
+	if err != nil {
+		return models.AdminUser{}, false, err
+	}
+	if sent >= authEmailsPerHour {
+		log.Printf("Not sending %s link to admin %d: %d sent in the last hour", purpose, admin.ID, sent)
+		return models.AdminUser{}, false, nil
+	}
+	return admin, true, nil
+}
+
+func (s *AuthService) verificationEmail(admin models.AdminUser) (mailer.Message, error) {
+	token, expiresAt, err := s.issue(admin, TokenVerifyEmail, s.verifyTTL)
+	if err != nil {
+		return mailer.Message{}, err
+	}
+	return s.email(admin, "Konfirmasi email akun Kasir", authEmailView{
+		Intro:  "Konfirmasi bahwa alamat email ini milik akun Kasir Anda.",
+		Action: "Konfirmasi email",
+		Link:   s.link("/verify-email", token),
+	}, expiresAt)
+}
+
+func (s *AuthService) email(admin models.AdminUser, subject string, view authEmailView, expiresAt time.Time) (mailer.Message, error) {
+	view.Username = admin.Username
+	view.ExpiresAt = expiresAt.Format("2006-01-02 15:04 MST")
+
+	var buf bytes.Buffer
+	if err := authEmailTemplate.Execute(&buf, view); err != nil {
+		return mailer.Message{}, err
+	}
+	return mailer.Message{To: admin.Email, Subject: subject, HTML: buf.String()}, nil
+}
+
+// deliver sends msg in the background, so the public endpoints answer as
+// fast for unknown addresses as for known ones
+func (s *AuthService) deliver(msg mailer.Message) {
+	go func() {
+		if err := s.mailer.Send(msg); err != nil {
+			log.Println("Error sending auth email:", err)
+		}
+	}()
+}
+
+func (s *AuthService) link(path, token string) string {
+	return s.appURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// issue records a new token of purpose for admin and returns it signed
+func (s *AuthService) issue(admin models.AdminUser, purpose string, ttl time.Duration) (string, time.Time, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(raw)
+	expiresAt := time.Now().Add(ttl)
+
+	id, err := s.tokens.Create(admin.ID, purpose, hashAuthToken(secret), expiresAt)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	payload := fmt.Sprintf("%d.%d.%s", id, expiresAt.Unix(), secret)
+	return payload + "." + s.sign(purpose, payload), expiresAt, nil
+}
+
+// parse checks a token's signature and expiry and returns its ID and secret part
+func (s *AuthService) parse(token, purpose string) (int, string, error) {
+	if len(s.secret) == 0 {
+		return 0, "", ErrAuthNotConfigured
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return 0, "", repositories.ErrInvalidAuthToken
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(s.sign(purpose, payload))) {
+		return 0, "", repositories.ErrInvalidAuthToken
+	}
+
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", repositories.ErrInvalidAuthToken
+	}
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() >= expiresAt {
+		return 0, "", repositories.ErrInvalidAuthToken
+	}
+	return id, parts[2], nil
+}
+
+func (s *AuthService) sign(purpose, payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(purpose + ":" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func hashAuthToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var (
+	ErrInvalidProductIDs = errors.New("ids must be a comma-separated list of product IDs")
+	ErrTooManyProductIDs = errors.New("at most 100 product IDs can be requested at once")
+)
+
+// maxAvailabilityIDs matches the most products the repository returns in one call
+const maxAvailabilityIDs = 100
+
+type AvailabilityService struct {
+	repo *repositories.AvailabilityRepository
+}
+
+func NewAvailabilityService(repo *repositories.AvailabilityRepository) *AvailabilityService {
+	return &AvailabilityService{repo: repo}
+}
+
+// GetPublic returns the availability of the opted-in products listed in ids,
+// a comma-separated list such as "1,2,3"; an empty list means every
+// opted-in product
+func (s *AvailabilityService) GetPublic(ids string) ([]models.ProductAvailability, error) {
+	productIDs := []int{}
+	for _, field := range strings.Split(ids, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.Atoi(field)
+		if err != nil || id <= 0 {
+			return nil, ErrInvalidProductIDs
+		}
+		productIDs = append(productIDs, id)
+	}
+
+	productIDs = uniqueIDs(productIDs)
+	if len(productIDs) > maxAvailabilityIDs {
+		return nil, ErrTooManyProductIDs
+	}
+	return s.repo.GetPublic(productIDs)
+}
+
+// SetPublic opts a product in to or out of the public availability widget
+func (s *AvailabilityService) SetPublic(productID int, public bool) error {
+	return s.repo.SetPublic(productID, public)
+}
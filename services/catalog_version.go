@@ -0,0 +1,23 @@
+package services
+
+import "kasir-api/repositories"
+
+// CatalogVersion counts the changes to products, categories and pricing
+// rules, so terminals caching the catalog can tell when theirs is stale
+type CatalogVersion struct {
+	repo *repositories.SequenceRepository
+}
+
+func NewCatalogVersion(repo *repositories.SequenceRepository) *CatalogVersion {
+	return &CatalogVersion{repo: repo}
+}
+
+// Current returns the version of the catalog, 0 before its first change
+func (v *CatalogVersion) Current() (int64, error) {
+	return v.repo.Last(repositories.CatalogScope)
+}
+
+// Bump records a change to the catalog and returns the new version
+func (v *CatalogVersion) Bump() (int64, error) {
+	return v.repo.Next(repositories.CatalogScope)
+}
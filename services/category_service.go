@@ -1,34 +1,200 @@
 package services
 
 import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
 	"kasir-api/models"
 	"kasir-api/repositories"
 )
 
+var (
+	ErrInvalidDeletePolicy  = errors.New("policy must be block, cascade or reassign")
+	ErrInvalidCategoryBatch = errors.New("a batch holds 1 to 500 categories")
+)
+
+// maxBatchCategories bounds the categories of one batch
+const maxBatchCategories = 500
+
 type CategoryService struct {
-	Repo *repositories.CategoryRepository
+	Repo repositories.CategoryStore
+	// DeletePolicy is the policy of deletions that don't name one
+	DeletePolicy string
+}
+
+func NewCategoryService(repo repositories.CategoryStore, deletePolicy string) *CategoryService {
+	return &CategoryService{Repo: repo, DeletePolicy: deletePolicy}
 }
 
-func NewCategoryService(repo *repositories.CategoryRepository) *CategoryService {
-	return &CategoryService{Repo: repo}
+// ValidDeletePolicy reports whether policy is one of the category delete
+// policies of package repositories
+func ValidDeletePolicy(policy string) bool {
+	return policy == repositories.CategoryDeleteBlock || policy == repositories.CategoryDeleteCascade || policy == repositories.CategoryDeleteReassign
 }
 
-func (s *CategoryService) GetAll() ([]models.Category, error) {
-	return s.Repo.GetAll()
+// GetAll retrieves the active categories sorted by sort (see
+// repositories.CatalogOrderBy); with updatedSince set, the ones updated from
+// then on, deleted ones included
+func (s *CategoryService) GetAll(sort string, updatedSince time.Time) ([]models.Category, error) {
+	if !repositories.ValidCatalogSort(sort) {
+		return nil, ErrInvalidSort
+	}
+	return s.Repo.GetAll(sort, updatedSince)
 }
 
 func (s *CategoryService) GetByID(id int) (models.Category, error) {
 	return s.Repo.GetByID(id)
 }
 
+// Create saves a new category; a name already used by another active
+// category, in any case, is refused with repositories.ErrDuplicateCategoryName
 func (s *CategoryService) Create(category models.Category) (models.Category, error) {
+	category.Name = strings.TrimSpace(category.Name)
 	return s.Repo.Create(category)
 }
 
+// Upsert creates the category, or when one with the same name exists updates
+// its description, if given, and returns it; created tells which happened
+func (s *CategoryService) Upsert(category models.Category) (models.Category, bool, error) {
+	category.Name = strings.TrimSpace(category.Name)
+
+	// a category created by another request between the lookup and the
+	// insert is looked up again
+	for attempt := 1; ; attempt++ {
+		existing, err := s.Repo.GetByName(category.Name)
+		if err == sql.ErrNoRows {
+			created, err := s.Repo.Create(category)
+			if err == repositories.ErrDuplicateCategoryName && attempt < 2 {
+				continue
+			}
+			return created, err == nil, err
+		}
+		if err != nil {
+			return models.Category{}, false, err
+		}
+
+		if category.Description == "" || category.Description == existing.Description {
+			return existing, false, nil
+		}
+		existing.Description = category.Description
+		updated, err := s.Repo.Update(existing)
+		return updated, false, err
+	}
+}
+
 func (s *CategoryService) Update(category models.Category) (models.Category, error) {
+	category.Name = strings.TrimSpace(category.Name)
 	return s.Repo.Update(category)
 }
 
-func (s *CategoryService) Delete(id int) error {
-	return s.Repo.Delete(id)
+// MergeCategory returns existing with the fields set in changes
+func MergeCategory(existing, changes models.Category) models.Category {
+	if changes.Name != "" {
+		existing.Name = changes.Name
+	}
+	if changes.Description != "" {
+		existing.Description = changes.Description
+	}
+	return existing
+}
+
+// CreateBatch creates categories in one database transaction, as Create
+// would one by one, and reports on each. A category that fails is left out
+// and the others are saved; when atomic, none is saved unless all can be.
+func (s *CategoryService) CreateBatch(categories []models.Category, atomic bool) ([]models.CategoryBatchResult, error) {
+	if len(categories) == 0 || len(categories) > maxBatchCategories {
+		return nil, ErrInvalidCategoryBatch
+	}
+
+	prepared := make([]models.Category, len(categories))
+	for i, category := range categories {
+		category.Name = strings.TrimSpace(category.Name)
+		prepared[i] = category
+	}
+	return s.saveBatch(prepared, make([]error, len(categories)), atomic, BatchCreated, s.Repo.CreateBatch)
+}
+
+// UpdateBatch applies changes, each naming the category it changes by ID as
+// an update of it would, in one database transaction and reports on each.
+// A category that fails is left out and the others are saved; when atomic,
+// none is saved unless all can be.
+func (s *CategoryService) UpdateBatch(changes []models.Category, atomic bool) ([]models.CategoryBatchResult, error) {
+	if len(changes) == 0 || len(changes) > maxBatchCategories {
+		return nil, ErrInvalidCategoryBatch
+	}
+
+	prepared := make([]models.Category, len(changes))
+	errs := make([]error, len(changes))
+	for i, change := range changes {
+		if change.ID <= 0 {
+			errs[i] = ErrMissingBatchID
+			continue
+		}
+		existing, err := s.Repo.GetByID(change.ID)
+		if err == sql.ErrNoRows {
+			errs[i] = repositories.ErrCategoryNotFound
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		prepared[i] = MergeCategory(existing, change)
+		prepared[i].Name = strings.TrimSpace(prepared[i].Name)
+	}
+	return s.saveBatch(prepared, errs, atomic, BatchUpdated, s.Repo.UpdateBatch)
+}
+
+// saveBatch saves the categories that passed the checks, errs holding why
+// the others didn't, and reports on all of them
+func (s *CategoryService) saveBatch(categories []models.Category, errs []error, atomic bool, outcome string, save func([]models.Category, bool) ([]models.Category, []error, error)) ([]models.CategoryBatchResult, error) {
+	results := make([]models.CategoryBatchResult, len(categories))
+	valid := []models.Category{}
+	validIndex := []int{}
+	for i := range categories {
+		results[i] = models.CategoryBatchResult{Index: i, Status: BatchSkipped}
+		if errs[i] != nil {
+			results[i].Status, results[i].Error = BatchFailed, errs[i].Error()
+			continue
+		}
+		valid = append(valid, categories[i])
+		validIndex = append(validIndex, i)
+	}
+	if len(valid) == 0 || (atomic && len(valid) < len(categories)) {
+		return results, nil
+	}
+
+	saved, saveErrs, err := save(valid, atomic)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range validIndex {
+		switch {
+		case saveErrs[j] == sql.ErrNoRows:
+			// deleted since it was looked up
+			results[i].Status, results[i].Error = BatchFailed, repositories.ErrCategoryNotFound.Error()
+		case saveErrs[j] != nil:
+			results[i].Status, results[i].Error = BatchFailed, saveErrs[j].Error()
+		case saved != nil:
+			category := saved[j]
+			results[i].Status, results[i].Category = outcome, &category
+		}
+	}
+	return results, nil
+}
+
+// Delete soft deletes a category, dealing with its active products as policy
+// says, or DeletePolicy when it is empty: a category with active products is
+// refused under block, while cascade and reassign soft delete or move to
+// Uncategorized those it leaves without a category
+func (s *CategoryService) Delete(id int, policy string) (models.CategoryDeletion, error) {
+	if policy == "" {
+		policy = s.DeletePolicy
+	}
+	if !ValidDeletePolicy(policy) {
+		return models.CategoryDeletion{}, ErrInvalidDeletePolicy
+	}
+	return s.Repo.Delete(id, policy)
 }
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+	"math"
+
+	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/repositories"
+)
+
+var ErrInvalidCostCorrection = errors.New("product_id is required and unit_cost can't be negative")
+
+type CostService struct {
+	repo    *repositories.CostRepository
+	periods *PeriodService
+}
+
+func NewCostService(repo *repositories.CostRepository, periods *PeriodService) *CostService {
+	return &CostService{repo: repo, periods: periods}
+}
+
+// Recalculate rebuilds the cost price of every product ever received as the
+// moving average of its goods receipts, replaying the stock ledger: each
+// receipt averages its unit cost with the stock on hand, other movements
+// change the quantity only. Stock on hand before the first priced receipt is
+// taken at that receipt's cost, and receipts without a unit cost at the
+// running average. With dryRun nothing is written.
+func (s *CostService) Recalculate(dryRun bool) (models.CostRecalculation, error) {
+	history, err := s.repo.GetCostHistory()
+	if err != nil {
+		return models.CostRecalculation{}, err
+	}
+	costs, err := s.repo.GetProductCosts()
+	if err != nil {
+		return models.CostRecalculation{}, err
+	}
+
+	type average struct {
+		cost     float64
+		priced   bool
+		stock    int
+		receipts int
+	}
+	averages := map[int]*average{}
+	for _, m := range history {
+		a, ok := averages[m.ProductID]
+		if !ok {
+			a = &average{}
+			averages[m.ProductID] = a
+		}
+
+		if m.Receipt {
+			a.receipts++
+			if m.UnitCost > 0 && m.Quantity > 0 {
+				if !a.priced || a.stock <= 0 {
+					a.cost = float64(m.UnitCost)
+				} else {
+					a.cost = (float64(a.stock)*a.cost + float64(m.UnitCost.Times(m.Quantity))) / float64(a.stock+m.Quantity)
+				}
+				a.priced = true
+			}
+		}
+		a.stock += m.Quantity
+	}
+
+	result := models.CostRecalculation{DryRun: dryRun, Products: []models.ProductCostChange{}}
+	for _, c := range costs {
+		a, ok := averages[c.ProductID]
+		if !ok || !a.priced {
+			continue
+		}
+		newCost := money.Amount(math.Round(a.cost))
+		if newCost == c.CostPrice {
+			continue
+		}
+
+		change := models.ProductCostChange{
+			ProductID:            c.ProductID,
+			Name:                 c.Name,
+			Receipts:             a.receipts,
+			OldCost:              c.CostPrice,
+			NewCost:              newCost,
+			Stock:                c.Stock,
+			OldStockValue:        c.CostPrice.Times(c.Stock),
+			NewStockValue:        newCost.Times(c.Stock),
+			LossQuantity:         c.LossQuantity,
+			ShrinkageValueChange: (newCost - c.CostPrice).Times(c.LossQuantity),
+		}
+		change.StockValueChange = change.NewStockValue - change.OldStockValue
+		result.Products = append(result.Products, change)
+
+		result.Totals.Products++
+		result.Totals.OldStockValue += change.OldStockValue
+		result.Totals.NewStockValue += change.NewStockValue
+		result.Totals.StockValueChange += change.StockValueChange
+		result.Totals.ShrinkageValueChange += change.ShrinkageValueChange
+	}
+
+	if dryRun || len(result.Products) == 0 {
+		return result, nil
+	}
+
+	result.Updated, err = s.repo.UpdateCostPrices(result.Products)
+	if err != nil {
+		return models.CostRecalculation{}, err
+	}
+	return result, nil
+}
+
+// CorrectReceiptCost fixes the unit cost of a product on a goods receipt. Cost
+// prices stay as they are until the next recalculation. A receipt received in
+// a closed accounting period can't be corrected.
+func (s *CostService) CorrectReceiptCost(receiptID int, correction models.ReceiptCostCorrection) error {
+	if correction.ProductID <= 0 || correction.UnitCost < 0 {
+		return ErrInvalidCostCorrection
+	}
+	receivedAt, err := s.repo.GetReceiptDate(receiptID)
+	if err != nil {
+		return err
+	}
+	if err := s.periods.CheckOpen(receivedAt); err != nil {
+		return err
+	}
+	return s.repo.CorrectReceiptCost(receiptID, correction)
+}
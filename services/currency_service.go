@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"kasir-api/jobs"
+	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/repositories"
+)
+
+const JobExchangeRateRefresh = "exchange_rate.refresh"
+
+var (
+	ErrUnknownCurrency     = errors.New("unknown currency, use an ISO 4217 code such as USD")
+	ErrBaseCurrencyRate    = errors.New("the store currency needs no exchange rate")
+	ErrInvalidRate         = errors.New("rate must be greater than zero, or set fetched")
+	ErrNoRateSource        = errors.New("no exchange rate API is configured, set EXCHANGE_RATE_URL or the rate by hand")
+	ErrRateNotQuoted       = errors.New("the exchange rate API doesn't quote this currency")
+	ErrCurrencyNotAccepted = errors.New("currency isn't accepted")
+	ErrInsufficientPayment = errors.New("payment doesn't cover the total")
+)
+
+// CurrencyService takes payments in foreign currencies, e.g. from tourists,
+// at exchange rates set by hand or fetched from an exchange rate API. Sales
+// are always rung up and reported in the store currency, the base.
+type CurrencyService struct {
+	repo   *repositories.CurrencyRepository
+	base   money.Currency
+	source money.RateSource
+}
+
+// NewCurrencyService quotes rates against base; source may be nil when rates
+// are only set by hand
+func NewCurrencyService(repo *repositories.CurrencyRepository, base money.Currency, source money.RateSource, runner *jobs.Runner) *CurrencyService {
+	s := &CurrencyService{repo: repo, base: base, source: source}
+	runner.Register(JobExchangeRateRefresh, s.refreshJob)
+	return s
+}
+
+func (s *CurrencyService) GetAccepted() (models.AcceptedCurrencies, error) {
+	rates, err := s.repo.GetAll()
+	if err != nil {
+		return models.AcceptedCurrencies{}, err
+	}
+	return models.AcceptedCurrencies{Base: s.base.Code, Rates: rates}, nil
+}
+
+// Set accepts currency at the rate requested, or at the rate fetched now when
+// the request asks for fetched rates
+func (s *CurrencyService) Set(currency string, req models.ExchangeRateRequest) (models.ExchangeRate, error) {
+	c, err := money.Lookup(currency)
+	if err != nil {
+		return models.ExchangeRate{}, ErrUnknownCurrency
+	}
+	if c.Code == s.base.Code {
+		return models.ExchangeRate{}, ErrBaseCurrencyRate
+	}
+
+	rate := models.ExchangeRate{Currency: c.Code, Rate: req.Rate, Fetched: req.Fetched}
+	if req.Fetched {
+		rates, err := s.fetchRates()
+		if err != nil {
+			return models.ExchangeRate{}, err
+		}
+		rate.Rate = rates[c.Code]
+		if rate.Rate == 0 {
+			return models.ExchangeRate{}, ErrRateNotQuoted
+		}
+	} else if req.Rate <= 0 {
+		return models.ExchangeRate{}, ErrInvalidRate
+	}
+	return s.repo.Set(rate)
+}
+
+// Delete stops accepting currency
+func (s *CurrencyService) Delete(currency string) error {
+	return s.repo.Delete(strings.ToUpper(currency))
+}
+
+// Tender returns what records payment for a checkout: the amount tendered,
+// converted to the store currency at the current rate, must cover the total and
+// the rest is given back as change in the store currency.
+func (s *CurrencyService) Tender(payment models.CheckoutPayment) (repositories.Tender, error) {
+	c, err := money.Lookup(payment.Currency)
+	if err != nil {
+		return nil, ErrUnknownCurrency
+	}
+
+	rate := 1.0
+	if c.Code != s.base.Code {
+		accepted, err := s.repo.GetRate(c.Code)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrCurrencyNotAccepted, c.Code)
+		}
+		if err != nil {
+			return nil, err
+		}
+		rate = accepted.Rate
+	}
+
+	return func(tx *sql.Tx, total money.Amount) (*models.TransactionPayment, error) {
+		base := money.Amount(payment.Amount.In(c).Convert(s.base, rate).Amount)
+		if base < total {
+			return nil, fmt.Errorf("%w: %s is worth %s of %s", ErrInsufficientPayment,
+				payment.Amount.In(c), base.In(s.base), total.In(s.base))
+		}
+		tendered := payment.Amount.In(c)
+		return &models.TransactionPayment{
+			Currency:   c.Code,
+			Amount:     payment.Amount,
+			Tendered:   &tendered,
+			Rate:       rate,
+			BaseAmount: base,
+			Change:     base - total,
+		}, nil
+	}, nil
+}
+
+// RefreshRates updates the rates of the currencies whose rates are fetched
+func (s *CurrencyService) RefreshRates() (int, error) {
+	accepted, err := s.repo.GetAll()
+	if err != nil {
+		return 0, err
+	}
+
+	var fetched []models.ExchangeRate
+	for _, rate := range accepted {
+		if rate.Fetched {
+			fetched = append(fetched, rate)
+		}
+	}
+	if len(fetched) == 0 {
+		return 0, nil
+	}
+
+	rates, err := s.fetchRates()
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, rate := range fetched {
+		// a currency dropped by the API keeps its last rate
+		if rates[rate.Currency] == 0 {
+			log.Println("Exchange rate API no longer quotes", rate.Currency, "keeping the rate of", rate.UpdatedAt)
+			continue
+		}
+		if err := s.repo.UpdateFetched(rate.Currency, rates[rate.Currency]); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+func (s *CurrencyService) refreshJob(ctx context.Context, job models.Job) error {
+	updated, err := s.RefreshRates()
+	if updated > 0 {
+		log.Printf("Refreshed %d exchange rates", updated)
+	}
+	return err
+}
+
+// fetchRates returns the store currency one whole unit of each quoted
+// currency is worth, the inverse of what the API quotes
+func (s *CurrencyService) fetchRates() (map[string]float64, error) {
+	if s.source == nil {
+		return nil, ErrNoRateSource
+	}
+	quoted, err := s.source.Rates(s.base.Code)
+	if err != nil {
+		return nil, err
+	}
+	rates := make(map[string]float64, len(quoted))
+	for code, q := range quoted {
+		if q > 0 {
+			rates[strings.ToUpper(code)] = 1 / q
+		}
+	}
+	return rates, nil
+}
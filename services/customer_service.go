@@ -0,0 +1,355 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var (
+	ErrInvalidAmount    = errors.New("amount must be greater than zero")
+	ErrNegativeAmount   = errors.New("prices and costs can't be negative")
+	ErrInvalidDueDate   = errors.New("due_date must be in YYYY-MM-DD format")
+	ErrInvalidChannel   = errors.New("reminder_channel must be one of whatsapp, sms, email")
+	ErrInvalidImportCSV = errors.New("file must be a CSV with a header row holding at least a name column")
+	ErrInvalidMerge     = errors.New("customer_ids must list other customers than the one merged into")
+)
+
+// importColumns maps the header names accepted in a customer import, English
+// and Indonesian, to the customer field they fill
+var importColumns = map[string]string{
+	"name":             "name",
+	"nama":             "name",
+	"phone":            "phone",
+	"telepon":          "phone",
+	"hp":               "phone",
+	"no_hp":            "phone",
+	"whatsapp":         "phone",
+	"email":            "email",
+	"reminder_channel": "reminder_channel",
+	"channel":          "reminder_channel",
+}
+
+type CustomerService struct {
+	Repo *repositories.CustomerRepository
+	// PhoneCountryCode is given to phone numbers written without one, e.g. "62"
+	PhoneCountryCode string
+}
+
+func NewCustomerService(repo *repositories.CustomerRepository, phoneCountryCode string) *CustomerService {
+	return &CustomerService{Repo: repo, PhoneCountryCode: phoneCountryCode}
+}
+
+func (s *CustomerService) GetAll() ([]models.Customer, error) {
+	return s.Repo.GetAll()
+}
+
+func (s *CustomerService) GetByID(id int) (models.Customer, error) {
+	return s.Repo.GetByID(id)
+}
+
+func (s *CustomerService) Create(customer models.Customer) (models.Customer, error) {
+	if err := s.normalizeContact(&customer); err != nil {
+		return models.Customer{}, err
+	}
+	return s.Repo.Create(customer)
+}
+
+func (s *CustomerService) Update(customer models.Customer) (models.Customer, error) {
+	if err := s.normalizeContact(&customer); err != nil {
+		return models.Customer{}, err
+	}
+	return s.Repo.Update(customer)
+}
+
+// normalizeContact cleans up the phone and email of c so the same contact
+// is always written the same way, and checks its reminder channel. Without a
+// channel, customers with only an email are reminded by email.
+func (s *CustomerService) normalizeContact(c *models.Customer) error {
+	c.Name = strings.TrimSpace(c.Name)
+
+	phone, err := NormalizePhone(c.Phone, s.PhoneCountryCode)
+	if err != nil {
+		return err
+	}
+	c.Phone = phone
+
+	c.Email = strings.ToLower(strings.TrimSpace(c.Email))
+	if c.Email != "" {
+		if addr, err := mail.ParseAddress(c.Email); err != nil || addr.Address != c.Email {
+			return ErrInvalidEmail
+		}
+	}
+
+	if c.ReminderChannel == "" {
+		c.ReminderChannel = "whatsapp"
+		if c.Phone == "" && c.Email != "" {
+			c.ReminderChannel = "email"
+		}
+	}
+	if !IsReminderChannel(c.ReminderChannel) {
+		return ErrInvalidChannel
+	}
+	return nil
+}
+
+// Import adds the customers of a CSV file with a header row. Rows with an
+// invalid contact are reported as errors, rows whose phone or email is
+// already known, from the database or an earlier row, are skipped; nothing is
+// saved on a dry run.
+func (s *CustomerService) Import(file io.Reader, dryRun bool) (models.CustomerImportResult, error) {
+	result := models.CustomerImportResult{
+		DryRun:  dryRun,
+		Skipped: []models.CustomerImportIssue{},
+		Errors:  []models.CustomerImportIssue{},
+	}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return result, ErrInvalidImportCSV
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		// spreadsheet apps may start the file with a byte order mark
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if field, ok := importColumns[name]; ok {
+			if _, seen := columns[field]; !seen {
+				columns[field] = i
+			}
+		}
+	}
+	if _, ok := columns["name"]; !ok {
+		return result, ErrInvalidImportCSV
+	}
+
+	existing, err := s.Repo.GetAll()
+	if err != nil {
+		return result, err
+	}
+	known := map[string]string{}
+	for _, c := range existing {
+		for _, key := range s.contactKeys(c) {
+			known[key] = fmt.Sprintf("customer %d", c.ID)
+		}
+	}
+
+	var customers []models.Customer
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("%w: row %d: %v", ErrInvalidImportCSV, row, err)
+		}
+
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		c := models.Customer{
+			Name:            field("name"),
+			Phone:           field("phone"),
+			Email:           field("email"),
+			ReminderChannel: strings.ToLower(strings.TrimSpace(field("reminder_channel"))),
+		}
+		if err := s.normalizeContact(&c); err != nil {
+			result.Errors = append(result.Errors, models.CustomerImportIssue{Row: row, Name: c.Name, Message: err.Error()})
+			continue
+		}
+		if c.Name == "" {
+			result.Errors = append(result.Errors, models.CustomerImportIssue{Row: row, Message: "name is required"})
+			continue
+		}
+
+		keys := s.contactKeys(c)
+		duplicate := ""
+		for _, key := range keys {
+			if of, ok := known[key]; ok {
+				duplicate = fmt.Sprintf("%s is already used by %s", strings.SplitN(key, ":", 2)[0], of)
+				break
+			}
+		}
+		if duplicate != "" {
+			result.Skipped = append(result.Skipped, models.CustomerImportIssue{Row: row, Name: c.Name, Message: duplicate})
+			continue
+		}
+		for _, key := range keys {
+			known[key] = fmt.Sprintf("row %d", row)
+		}
+		customers = append(customers, c)
+	}
+
+	result.Imported = len(customers)
+	if dryRun || len(customers) == 0 {
+		return result, nil
+	}
+	if err := s.Repo.CreateMany(customers); err != nil {
+		return models.CustomerImportResult{}, err
+	}
+	return result, nil
+}
+
+// contactKeys returns the phone and email c can be recognized by, with the
+// phone normalized in case it was saved before numbers were
+func (s *CustomerService) contactKeys(c models.Customer) []string {
+	var keys []string
+	if phone, err := NormalizePhone(c.Phone, s.PhoneCountryCode); err == nil && phone != "" {
+		keys = append(keys, "phone:"+phone)
+	}
+	if email := strings.ToLower(strings.TrimSpace(c.Email)); email != "" {
+		keys = append(keys, "email:"+email)
+	}
+	return keys
+}
+
+// FindDuplicates groups the active customers that share a phone number or an
+// email, directly or through another customer, as candidates for a merge
+func (s *CustomerService) FindDuplicates() ([]models.DuplicateCustomers, error) {
+	customers, err := s.Repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// union-find over the customers, joined by every contact they share
+	parent := make([]int, len(customers))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	owners := map[string][]int{}
+	var keys []string
+	for i, c := range customers {
+		for _, key := range s.contactKeys(c) {
+			if len(owners[key]) == 0 {
+				keys = append(keys, key)
+			}
+			owners[key] = append(owners[key], i)
+		}
+	}
+	for _, key := range keys {
+		for _, i := range owners[key][1:] {
+			parent[find(i)] = find(owners[key][0])
+		}
+	}
+
+	// what each group has in common, "phone", "email" or both
+	matched := map[int]map[string]bool{}
+	for _, key := range keys {
+		if len(owners[key]) < 2 {
+			continue
+		}
+		root := find(owners[key][0])
+		if matched[root] == nil {
+			matched[root] = map[string]bool{}
+		}
+		matched[root][strings.SplitN(key, ":", 2)[0]] = true
+	}
+
+	groups := map[int]*models.DuplicateCustomers{}
+	var roots []int
+	for i, c := range customers {
+		root := find(i)
+		if len(matched[root]) == 0 {
+			continue
+		}
+		g, ok := groups[root]
+		if !ok {
+			g = &models.DuplicateCustomers{MatchedOn: []string{}}
+			for kind := range matched[root] {
+				g.MatchedOn = append(g.MatchedOn, kind)
+			}
+			sort.Strings(g.MatchedOn)
+			groups[root] = g
+			roots = append(roots, root)
+		}
+		g.Customers = append(g.Customers, c)
+	}
+
+	duplicates := []models.DuplicateCustomers{}
+	for _, root := range roots {
+		g := groups[root]
+		sort.Slice(g.Customers, func(i, j int) bool { return g.Customers[i].ID < g.Customers[j].ID })
+		duplicates = append(duplicates, *g)
+	}
+	return duplicates, nil
+}
+
+// Merge folds the customers in ids into targetID, see CustomerRepository.Merge,
+// and returns the merged customer
+func (s *CustomerService) Merge(targetID int, ids []int) (models.Customer, error) {
+	ids = uniqueIDs(ids)
+	if len(ids) == 0 {
+		return models.Customer{}, ErrInvalidMerge
+	}
+	for _, id := range ids {
+		if id == targetID {
+			return models.Customer{}, ErrInvalidMerge
+		}
+	}
+
+	if err := s.Repo.Merge(targetID, ids); err != nil {
+		return models.Customer{}, err
+	}
+	return s.Repo.GetByID(targetID)
+}
+
+func (s *CustomerService) Delete(id int) error {
+	return s.Repo.Delete(id)
+}
+
+func (s *CustomerService) CreateKasbon(kasbon models.Kasbon) (models.Kasbon, error) {
+	if kasbon.Amount <= 0 {
+		return models.Kasbon{}, ErrInvalidAmount
+	}
+	if _, err := time.Parse("2006-01-02", kasbon.DueDate); err != nil {
+		return models.Kasbon{}, ErrInvalidDueDate
+	}
+	return s.Repo.CreateKasbon(kasbon)
+}
+
+func (s *CustomerService) CreatePayment(payment models.KasbonPayment) (models.KasbonPayment, error) {
+	if payment.Amount <= 0 {
+		return models.KasbonPayment{}, ErrInvalidAmount
+	}
+	return s.Repo.CreatePayment(payment)
+}
+
+func (s *CustomerService) GetCredit(customerID int) (*models.CustomerCredit, error) {
+	return s.Repo.GetCredit(customerID)
+}
+
+// IsReminderChannel reports whether channel is a supported reminder channel
+func IsReminderChannel(channel string) bool {
+	switch channel {
+	case "whatsapp", "sms", "email":
+		return true
+	}
+	return false
+}
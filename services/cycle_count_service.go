@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+
+	"kasir-api/jobs"
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+const JobCycleCountGenerate = "cycle_count.generate"
+
+// abcWeights makes A items come up for counting four times as often as C items
+var abcWeights = map[string]int{"A": 4, "B": 2, "C": 1}
+
+// neverCountedDays is the age given to products that were never counted
+const neverCountedDays = 365
+
+type CycleCountService struct {
+	repo       *repositories.CycleCountRepository
+	dailyCount int
+}
+
+func NewCycleCountService(repo *repositories.CycleCountRepository, runner *jobs.Runner, dailyCount int) *CycleCountService {
+	s := &CycleCountService{repo: repo, dailyCount: dailyCount}
+	runner.Register(JobCycleCountGenerate, s.generateJob)
+	return s
+}
+
+func (s *CycleCountService) generateJob(ctx context.Context, job models.Job) error {
+	created, err := s.Generate(today())
+	if created > 0 {
+		log.Printf("Generated %d cycle count tasks", created)
+	}
+	return err
+}
+
+// Generate picks the products to count on date. Every product's priority is
+// the days since its last count times the weight of its ABC class, so the
+// selection rotates through the whole catalogue while A items are counted more often.
+func (s *CycleCountService) Generate(date string) (int, error) {
+	candidates, err := s.repo.GetCandidates()
+	if err != nil {
+		return 0, err
+	}
+
+	type scored struct {
+		candidate models.CycleCountCandidate
+		priority  int
+	}
+	pool := make([]scored, 0, len(candidates))
+	for _, c := range candidates {
+		if c.HasOpenTask {
+			continue
+		}
+		days := neverCountedDays
+		if c.DaysSinceCount != nil {
+			days = *c.DaysSinceCount
+		}
+		pool = append(pool, scored{candidate: c, priority: days * abcWeights[c.ABCClass]})
+	}
+
+	sort.Slice(pool, func(i, j int) bool {
+		if pool[i].priority != pool[j].priority {
+			return pool[i].priority > pool[j].priority
+		}
+		if pool[i].candidate.ABCClass != pool[j].candidate.ABCClass {
+			return pool[i].candidate.ABCClass < pool[j].candidate.ABCClass
+		}
+		return pool[i].candidate.ProductID < pool[j].candidate.ProductID
+	})
+
+	tasks := []models.CycleCountTask{}
+	for i := 0; i < len(pool) && i < s.dailyCount; i++ {
+		// counted yesterday, nothing is due yet
+		if pool[i].priority == 0 {
+			break
+		}
+		c := pool[i].candidate
+		tasks = append(tasks, models.CycleCountTask{ProductID: c.ProductID, ABCClass: c.ABCClass})
+	}
+
+	if len(tasks) == 0 {
+		return 0, nil
+	}
+	return s.repo.CreateTasks(date, tasks)
+}
+
+func (s *CycleCountService) GetTasks(date, status string) ([]models.CycleCountTask, error) {
+	return s.repo.GetTasks(date, status)
+}
+
+// RecordCount completes a task and corrects the product stock to the counted quantity
+func (s *CycleCountService) RecordCount(taskID, countedQty int) (models.CycleCountTask, error) {
+	if countedQty < 0 {
+		return models.CycleCountTask{}, ErrInvalidAmount
+	}
+
+	if err := s.repo.RecordCount(taskID, countedQty); err != nil {
+		return models.CycleCountTask{}, err
+	}
+	return s.repo.GetTaskByID(taskID)
+}
+
+// GetAccuracy returns the count accuracy trend per product, optionally for
+// one product (productID 0 means all)
+func (s *CycleCountService) GetAccuracy(productID int) ([]models.ProductCountAccuracy, error) {
+	tasks, err := s.repo.GetCountedTasks(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.ProductCountAccuracy{}
+	for _, t := range tasks {
+		if len(result) == 0 || result[len(result)-1].ProductID != t.ProductID {
+			result = append(result, models.ProductCountAccuracy{
+				ProductID:   t.ProductID,
+				ProductName: t.ProductName,
+				Trend:       []models.CountAccuracyPoint{},
+			})
+		}
+		pa := &result[len(result)-1]
+
+		point := models.CountAccuracyPoint{
+			Date:     t.ScheduledFor,
+			Expected: *t.ExpectedQty,
+			Counted:  *t.CountedQty,
+			Variance: *t.Variance,
+			Accuracy: countAccuracy(*t.ExpectedQty, *t.CountedQty),
+		}
+		pa.Trend = append(pa.Trend, point)
+		pa.Counts++
+		if point.Variance == 0 {
+			pa.ExactCounts++
+		}
+	}
+
+	for i := range result {
+		pa := &result[i]
+		total := 0.0
+		for _, p := range pa.Trend {
+			total += p.Accuracy
+		}
+		pa.AverageAccuracy = math.Round(total/float64(pa.Counts)*100) / 100
+	}
+	return result, nil
+}
+
+// countAccuracy is 100% for an exact count, decreasing with the variance relative to the recorded stock
+func countAccuracy(expected, counted int) float64 {
+	if expected == 0 {
+		if counted == 0 {
+			return 100
+		}
+		return 0
+	}
+
+	accuracy := 100 - math.Abs(float64(counted-expected))/float64(expected)*100
+	if accuracy < 0 {
+		return 0
+	}
+	return math.Round(accuracy*100) / 100
+}
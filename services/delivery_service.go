@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"kasir-api/jobs"
+	"kasir-api/models"
+	"kasir-api/notifier"
+	"kasir-api/repositories"
+	"kasir-api/storage"
+)
+
+var (
+	ErrCourierNameRequired = errors.New("courier name is required")
+	ErrAddressRequired     = errors.New("address is required")
+	ErrDeliveryTransition  = errors.New("delivery can't move to that status from its current one")
+	ErrCourierRequired     = errors.New("assign a courier before the delivery goes out")
+	ErrDeliveryClosed      = errors.New("delivery is already delivered or cancelled")
+)
+
+const JobDeliveryNotify = "delivery.notify"
+
+type deliveryJob struct {
+	DeliveryID int    `json:"delivery_id"`
+	Status     string `json:"status"`
+}
+
+// deliveryTransitions are the statuses a delivery can be moved to from each
+// status. It only becomes delivered through SetProof, once out.
+var deliveryTransitions = map[string][]string{
+	repositories.DeliveryPending: {repositories.DeliveryPacked, repositories.DeliveryCancelled},
+	repositories.DeliveryPacked:  {repositories.DeliveryOut, repositories.DeliveryCancelled},
+	repositories.DeliveryOut:     {repositories.DeliveryPacked, repositories.DeliveryCancelled},
+}
+
+// DeliveryService takes sales out to customers: a transaction is marked for
+// delivery, given a courier, packed, taken out and delivered with a photo as
+// proof. The customer is messaged over WhatsApp when it goes out, arrives or
+// is cancelled.
+type DeliveryService struct {
+	repo             *repositories.DeliveryRepository
+	files            storage.Storage
+	sender           notifier.Sender
+	runner           *jobs.Runner
+	phoneCountryCode string
+}
+
+func NewDeliveryService(repo *repositories.DeliveryRepository, files storage.Storage, sender notifier.Sender, runner *jobs.Runner, phoneCountryCode string) *DeliveryService {
+	s := &DeliveryService{repo: repo, files: files, sender: sender, runner: runner, phoneCountryCode: phoneCountryCode}
+	runner.Register(JobDeliveryNotify, s.notifyJob)
+	return s
+}
+
+func (s *DeliveryService) GetCouriers() ([]models.Courier, error) {
+	return s.repo.GetCouriers()
+}
+
+func (s *DeliveryService) CreateCourier(c models.Courier) (models.Courier, error) {
+	c, err := s.normalizeCourier(c)
+	if err != nil {
+		return models.Courier{}, err
+	}
+	return s.repo.CreateCourier(c)
+}
+
+func (s *DeliveryService) UpdateCourier(c models.Courier) (models.Courier, error) {
+	c, err := s.normalizeCourier(c)
+	if err != nil {
+		return models.Courier{}, err
+	}
+	return s.repo.UpdateCourier(c)
+}
+
+func (s *DeliveryService) DeleteCourier(id int) error {
+	return s.repo.DeleteCourier(id)
+}
+
+func (s *DeliveryService) normalizeCourier(c models.Courier) (models.Courier, error) {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return c, ErrCourierNameRequired
+	}
+	phone, err := NormalizePhone(c.Phone, s.phoneCountryCode)
+	if err != nil {
+		return c, err
+	}
+	c.Phone = phone
+	return c, nil
+}
+
+func (s *DeliveryService) GetAll(status string, courierID int) ([]models.Delivery, error) {
+	return s.repo.GetAll(status, courierID)
+}
+
+func (s *DeliveryService) GetByID(id int) (models.Delivery, error) {
+	return s.repo.GetByID(id)
+}
+
+// Create marks a transaction for delivery to the address given
+func (s *DeliveryService) Create(req models.DeliveryRequest) (models.Delivery, error) {
+	address := strings.TrimSpace(req.Address)
+	if address == "" {
+		return models.Delivery{}, ErrAddressRequired
+	}
+	phone, err := NormalizePhone(req.CustomerPhone, s.phoneCountryCode)
+	if err != nil {
+		return models.Delivery{}, err
+	}
+
+	return s.repo.Create(models.Delivery{
+		TransactionID: req.TransactionID,
+		CustomerName:  strings.TrimSpace(req.CustomerName),
+		CustomerPhone: phone,
+		Address:       address,
+		Note:          strings.TrimSpace(req.Note),
+		CourierID:     req.CourierID,
+	})
+}
+
+// AssignCourier gives a delivery to a courier, or to another one, until it is
+// delivered or cancelled
+func (s *DeliveryService) AssignCourier(id int, req models.DeliveryCourierRequest) (models.Delivery, error) {
+	d, err := s.repo.GetByID(id)
+	if err != nil {
+		return models.Delivery{}, err
+	}
+	if _, open := deliveryTransitions[d.Status]; !open {
+		return models.Delivery{}, ErrDeliveryClosed
+	}
+
+	if err := s.repo.AssignCourier(id, d.Status, req.CourierID); err != nil {
+		return models.Delivery{}, err
+	}
+	return s.repo.GetByID(id)
+}
+
+// UpdateStatus moves a delivery on to req.Status; it has to have a courier to
+// go out
+func (s *DeliveryService) UpdateStatus(id int, req models.DeliveryStatusRequest) (models.Delivery, error) {
+	d, err := s.repo.GetByID(id)
+	if err != nil {
+		return models.Delivery{}, err
+	}
+	next, open := deliveryTransitions[d.Status]
+	if !open {
+		return models.Delivery{}, ErrDeliveryClosed
+	}
+	if !containsStatus(next, req.Status) {
+		return models.Delivery{}, ErrDeliveryTransition
+	}
+	if req.Status == repositories.DeliveryOut && d.CourierID == 0 {
+		return models.Delivery{}, ErrCourierRequired
+	}
+
+	if err := s.repo.UpdateStatus(id, d.Status, req.Status); err != nil {
+		return models.Delivery{}, err
+	}
+	s.notify(id, req.Status)
+	return s.repo.GetByID(id)
+}
+
+// SetProof stores the photo taken at the door and marks a delivery that is out
+// delivered. The type is sniffed from the content rather than trusted from the
+// client.
+func (s *DeliveryService) SetProof(id int, photo io.Reader) (models.Delivery, error) {
+	d, err := s.repo.GetByID(id)
+	if err != nil {
+		return models.Delivery{}, err
+	}
+	if d.Status != repositories.DeliveryOut {
+		return models.Delivery{}, ErrDeliveryTransition
+	}
+
+	name, contentType, err := storePhoto(s.files, fmt.Sprintf("deliveries/%d/proof", id), photo)
+	if err != nil {
+		return models.Delivery{}, err
+	}
+
+	if err := s.repo.SetDelivered(id, d.Status, name, contentType); err != nil {
+		if err := s.files.Delete(name); err != nil && err != storage.ErrNotFound {
+			log.Println("Error deleting unused delivery proof:", err)
+		}
+		return models.Delivery{}, err
+	}
+	s.notify(id, repositories.DeliveryDelivered)
+	return s.repo.GetByID(id)
+}
+
+// OpenProof returns the delivery with its photo proof; the caller closes the file
+func (s *DeliveryService) OpenProof(id int) (models.Delivery, io.ReadCloser, error) {
+	d, err := s.repo.GetByID(id)
+	if err != nil {
+		return d, nil, err
+	}
+	if d.ProofName == "" {
+		return d, nil, storage.ErrNotFound
+	}
+
+	f, err := s.files.Open(d.ProofName)
+	return d, f, err
+}
+
+// notify queues the message telling the customer a delivery reached status;
+// the status change stands when it can't be queued
+func (s *DeliveryService) notify(id int, status string) {
+	if _, err := s.runner.Enqueue(JobDeliveryNotify, deliveryJob{DeliveryID: id, Status: status}); err != nil {
+		log.Println("Error queueing delivery notification:", err)
+	}
+}
+
+// notifyJob is the job handler of JobDeliveryNotify
+func (s *DeliveryService) notifyJob(ctx context.Context, job models.Job) error {
+	var dj deliveryJob
+	if err := json.Unmarshal(job.Payload, &dj); err != nil {
+		return err
+	}
+
+	d, err := s.repo.GetByID(dj.DeliveryID)
+	if err != nil {
+		return err
+	}
+	message := deliveryMessage(d, dj.Status)
+	if d.CustomerPhone == "" || message == "" {
+		return nil
+	}
+	return s.sender.Send(d.CustomerPhone, message)
+}
+
+// deliveryMessage is what the customer is told when d reaches status, empty
+// when they aren't told
+func deliveryMessage(d models.Delivery, status string) string {
+	order := d.ReceiptNumber
+	if order == "" {
+		order = fmt.Sprintf("#%d", d.TransactionID)
+	}
+
+	switch status {
+	case repositories.DeliveryOut:
+		courier := d.CourierName
+		if d.CourierPhone != "" {
+			courier += " (" + d.CourierPhone + ")"
+		}
+		return fmt.Sprintf("Pesanan %s sedang diantar oleh %s.", order, courier)
+	case repositories.DeliveryDelivered:
+		return fmt.Sprintf("Pesanan %s sudah diterima. Terima kasih telah berbelanja.", order)
+	case repositories.DeliveryCancelled:
+		return fmt.Sprintf("Pengantaran pesanan %s dibatalkan. Silakan hubungi toko untuk informasi lebih lanjut.", order)
+	}
+	return ""
+}
+
+func containsStatus(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"database/sql"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"kasir-api/models"
+)
+
+// PoolStatser reports the state of a connection pool, as *sql.DB does
+type PoolStatser interface {
+	Stats() sql.DBStats
+}
+
+// DiagnosticsService reports the state of the running process: its build,
+// goroutines, memory and database pool
+type DiagnosticsService struct {
+	pool      PoolStatser
+	startedAt time.Time
+	build     models.BuildInfo
+}
+
+func NewDiagnosticsService(pool PoolStatser, startedAt time.Time) *DiagnosticsService {
+	return &DiagnosticsService{pool: pool, startedAt: startedAt, build: readBuildInfo()}
+}
+
+// readBuildInfo reads what the toolchain stamped into the binary; the
+// revision is only known to builds from a checkout
+func readBuildInfo() models.BuildInfo {
+	build := models.BuildInfo{GoVersion: runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return build
+	}
+	build.Module = info.Main.Path
+	build.Version = info.Main.Version
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			build.Revision = s.Value
+		case "vcs.time":
+			build.RevisionTime = s.Value
+		case "vcs.modified":
+			build.Modified = s.Value == "true"
+		}
+	}
+	return build
+}
+
+// Snapshot reports the process as it is now. Reading the memory statistics
+// stops the world briefly, so it is not meant to be polled every second.
+func (s *DiagnosticsService) Snapshot() models.Diagnostics {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	pool := s.pool.Stats()
+
+	return models.Diagnostics{
+		Build:         s.build,
+		StartedAt:     s.startedAt.Format("2006-01-02 15:04:05"),
+		UptimeSeconds: int64(time.Since(s.startedAt).Seconds()),
+		Goroutines:    runtime.NumGoroutine(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		NumCPU:        runtime.NumCPU(),
+		Memory: models.MemoryStats{
+			HeapAllocBytes: mem.HeapAlloc,
+			HeapObjects:    mem.HeapObjects,
+			SysBytes:       mem.Sys,
+			NumGC:          mem.NumGC,
+			GCPauseTotalMs: time.Duration(mem.PauseTotalNs).Milliseconds(),
+		},
+		DBPool: models.DBPoolStats{
+			MaxOpenConnections: pool.MaxOpenConnections,
+			OpenConnections:    pool.OpenConnections,
+			InUse:              pool.InUse,
+			Idle:               pool.Idle,
+			WaitCount:          pool.WaitCount,
+			WaitDurationMs:     pool.WaitDuration.Milliseconds(),
+			MaxIdleClosed:      pool.MaxIdleClosed,
+			MaxIdleTimeClosed:  pool.MaxIdleTimeClosed,
+			MaxLifetimeClosed:  pool.MaxLifetimeClosed,
+		},
+	}
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/storage"
+)
+
+const (
+	// maxDisplayImageSize and maxDisplayVideoSize are the largest playlist
+	// files accepted, in bytes
+	maxDisplayImageSize = 5 << 20
+	maxDisplayVideoSize = 50 << 20
+	// MaxDisplayUpload bounds an upload request, leaving room for the
+	// multipart framing around the largest video
+	MaxDisplayUpload = maxDisplayVideoSize + 1<<20
+)
+
+var (
+	ErrInvalidDisplayMedia    = errors.New("file must be a JPEG, PNG, WebP or GIF image of at most 5 MB or an MP4 or WebM video of at most 50 MB")
+	ErrInvalidDisplaySchedule = errors.New("start_time and end_time must be different HH:MM times, or both empty to play all day; duration_seconds can't be negative")
+)
+
+// displayExtensions are the accepted playlist file types, keyed by sniffed content type
+var displayExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+	"video/mp4":  ".mp4",
+	"video/webm": ".webm",
+}
+
+// DisplayService keeps the advertising playlist of the customer-facing
+// display. Files live in the file storage; the display fetches the playlist
+// for the time of day and the files it links to.
+type DisplayService struct {
+	repo      *repositories.DisplayRepository
+	files     storage.Storage
+	publicURL string
+}
+
+func NewDisplayService(repo *repositories.DisplayRepository, files storage.Storage, publicURL string) *DisplayService {
+	return &DisplayService{repo: repo, files: files, publicURL: publicURL}
+}
+
+// GetAll retrieves every playlist item, scheduled now or not
+func (s *DisplayService) GetAll() ([]models.DisplayMedia, error) {
+	media, err := s.repo.GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+	for i := range media {
+		s.setURL(&media[i])
+	}
+	return media, nil
+}
+
+// Playlist returns the active items scheduled at t, in playing order
+func (s *DisplayService) Playlist(t time.Time) (models.DisplayPlaylist, error) {
+	media, err := s.repo.GetAll(true)
+	if err != nil {
+		return models.DisplayPlaylist{}, err
+	}
+
+	playlist := models.DisplayPlaylist{Items: []models.DisplayMedia{}}
+	for _, m := range media {
+		if playsAt(m, t) {
+			s.setURL(&m)
+			playlist.Items = append(playlist.Items, m)
+		}
+	}
+
+	encoded, err := json.Marshal(playlist.Items)
+	if err != nil {
+		return models.DisplayPlaylist{}, err
+	}
+	sum := sha256.Sum256(encoded)
+	playlist.Version = hex.EncodeToString(sum[:8])
+	return playlist, nil
+}
+
+// Upload stores an image or video and adds it to the playlist. The type is
+// sniffed from the content rather than trusted from the client.
+func (s *DisplayService) Upload(req models.DisplayMediaRequest, file io.Reader) (models.DisplayMedia, error) {
+	if err := validateDisplaySchedule(&req); err != nil {
+		return models.DisplayMedia{}, err
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return models.DisplayMedia{}, err
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	ext, ok := displayExtensions[contentType]
+	if n == 0 || !ok {
+		return models.DisplayMedia{}, ErrInvalidDisplayMedia
+	}
+	limit := int64(maxDisplayImageSize)
+	if strings.HasPrefix(contentType, "video/") {
+		limit = maxDisplayVideoSize
+	}
+
+	name := fmt.Sprintf("display/%d%s", time.Now().UnixNano(), ext)
+	f, err := s.files.Create(name)
+	if err != nil {
+		return models.DisplayMedia{}, err
+	}
+	size, err := io.Copy(f, io.LimitReader(io.MultiReader(bytes.NewReader(head), file), limit+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && size > limit {
+		err = ErrInvalidDisplayMedia
+	}
+	if err != nil {
+		s.deleteFile(name)
+		return models.DisplayMedia{}, err
+	}
+
+	media, err := s.repo.Create(models.DisplayMedia{
+		Title:           req.Title,
+		FileName:        name,
+		ContentType:     contentType,
+		Size:            size,
+		DurationSeconds: req.DurationSeconds,
+		Position:        req.Position,
+		StartTime:       req.StartTime,
+		EndTime:         req.EndTime,
+		Active:          req.Active,
+	})
+	if err != nil {
+		s.deleteFile(name)
+		return models.DisplayMedia{}, err
+	}
+	s.setURL(&media)
+	return media, nil
+}
+
+// Update changes the title and schedule of a playlist item
+func (s *DisplayService) Update(id int, req models.DisplayMediaRequest) (models.DisplayMedia, error) {
+	if err := validateDisplaySchedule(&req); err != nil {
+		return models.DisplayMedia{}, err
+	}
+	media, err := s.repo.Update(id, req)
+	if err != nil {
+		return models.DisplayMedia{}, err
+	}
+	s.setURL(&media)
+	return media, nil
+}
+
+// Delete removes an item from the playlist along with its file
+func (s *DisplayService) Delete(id int) error {
+	media, err := s.repo.Delete(id)
+	if err != nil {
+		return err
+	}
+	s.deleteFile(media.FileName)
+	return nil
+}
+
+// OpenMedia returns a playlist item with its file; the caller closes the file
+func (s *DisplayService) OpenMedia(id int) (models.DisplayMedia, io.ReadCloser, error) {
+	media, err := s.repo.GetByID(id)
+	if err != nil {
+		return media, nil, err
+	}
+	f, err := s.files.Open(media.FileName)
+	return media, f, err
+}
+
+func (s *DisplayService) setURL(m *models.DisplayMedia) {
+	m.URL = s.publicURL + "/api/display/media/" + strconv.Itoa(m.ID)
+}
+
+func (s *DisplayService) deleteFile(name string) {
+	if err := s.files.Delete(name); err != nil && err != storage.ErrNotFound {
+		log.Println("Error deleting display media file:", err)
+	}
+}
+
+func validateDisplaySchedule(req *models.DisplayMediaRequest) error {
+	req.Title = strings.TrimSpace(req.Title)
+	if req.DurationSeconds < 0 {
+		return ErrInvalidDisplaySchedule
+	}
+	if req.StartTime == "" && req.EndTime == "" {
+		return nil
+	}
+	start, startErr := time.Parse("15:04", req.StartTime)
+	end, endErr := time.Parse("15:04", req.EndTime)
+	if startErr != nil || endErr != nil || start.Equal(end) {
+		return ErrInvalidDisplaySchedule
+	}
+	return nil
+}
+
+// playsAt reports whether m is scheduled at the time of day of t; an item
+// ending before it starts plays past midnight
+func playsAt(m models.DisplayMedia, t time.Time) bool {
+	if m.StartTime == "" {
+		return true
+	}
+	now := t.Format("15:04")
+	if m.StartTime < m.EndTime {
+		return now >= m.StartTime && now < m.EndTime
+	}
+	return now >= m.StartTime || now < m.EndTime
+}
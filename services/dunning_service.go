@@ -0,0 +1,144 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"text/template"
+
+	"kasir-api/jobs"
+	"kasir-api/models"
+	"kasir-api/notifier"
+	"kasir-api/repositories"
+)
+
+var ErrInvalidTemplate = errors.New("invalid template")
+
+const JobDunningReminders = "dunning.reminders"
+
+type DunningService struct {
+	repo        *repositories.DunningRepository
+	senders     map[string]notifier.Sender
+	cadenceDays int
+}
+
+func NewDunningService(repo *repositories.DunningRepository, senders map[string]notifier.Sender, cadenceDays int, runner *jobs.Runner) *DunningService {
+	s := &DunningService{repo: repo, senders: senders, cadenceDays: cadenceDays}
+	runner.Register(JobDunningReminders, s.sendRemindersJob)
+	return s
+}
+
+// sendRemindersJob is the job handler of JobDunningReminders, run on the dunning schedule
+func (s *DunningService) sendRemindersJob(ctx context.Context, job models.Job) error {
+	sent, err := s.SendReminders()
+	if sent > 0 {
+		log.Printf("Sent %d kasbon reminders", sent)
+	}
+	return err
+}
+
+// SendReminders sends one reminder to every customer that is due for one and
+// returns the number of reminders delivered successfully
+func (s *DunningService) SendReminders() (int, error) {
+	customers, err := s.repo.GetOverdueCustomers(s.cadenceDays)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, oc := range customers {
+		history := s.remind(oc)
+		if err := s.repo.CreateHistory(history); err != nil {
+			return sent, err
+		}
+		if history.Status == "sent" {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// remind renders and delivers a reminder, returning the history entry to record
+func (s *DunningService) remind(oc models.OverdueCustomer) models.ReminderHistory {
+	channel := oc.Customer.ReminderChannel
+	history := models.ReminderHistory{
+		CustomerID: oc.Customer.ID,
+		Channel:    channel,
+		Recipient:  oc.Customer.Phone,
+		Status:     "failed",
+	}
+	if channel == "email" {
+		history.Recipient = oc.Customer.Email
+	}
+
+	if history.Recipient == "" {
+		history.Error = "customer has no contact for channel " + channel
+		return history
+	}
+
+	sender, ok := s.senders[channel]
+	if !ok {
+		history.Error = "no sender configured for channel " + channel
+		return history
+	}
+
+	tmpl, err := s.repo.GetTemplate(channel)
+	if err != nil {
+		history.Error = "template not found: " + err.Error()
+		return history
+	}
+
+	message, err := renderReminder(tmpl.Body, oc)
+	if err != nil {
+		history.Error = "invalid template: " + err.Error()
+		return history
+	}
+	history.Message = message
+
+	if err := sender.Send(history.Recipient, message); err != nil {
+		history.Error = err.Error()
+		return history
+	}
+
+	history.Status = "sent"
+	return history
+}
+
+func renderReminder(body string, oc models.OverdueCustomer) (string, error) {
+	tmpl, err := template.New("reminder").Parse(body)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{
+		"Name":    oc.Customer.Name,
+		"Balance": oc.OverdueBalance,
+		"DueDate": oc.OldestDueDate,
+	})
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (s *DunningService) GetHistory(customerID int) ([]models.ReminderHistory, error) {
+	return s.repo.GetHistory(customerID)
+}
+
+func (s *DunningService) GetTemplates() ([]models.ReminderTemplate, error) {
+	return s.repo.GetTemplates()
+}
+
+func (s *DunningService) SaveTemplate(tmpl models.ReminderTemplate) (models.ReminderTemplate, error) {
+	if !IsReminderChannel(tmpl.Channel) {
+		return models.ReminderTemplate{}, ErrInvalidChannel
+	}
+	if _, err := template.New("reminder").Parse(tmpl.Body); err != nil {
+		return models.ReminderTemplate{}, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return s.repo.SaveTemplate(tmpl)
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/storage"
+)
+
+// maxPhotoSize is the largest photo accepted, in bytes
+const maxPhotoSize = 5 << 20
+
+var (
+	ErrInvalidSpentOn = errors.New("spent_on must be in YYYY-MM-DD format")
+	ErrInvalidPhoto   = errors.New("photo must be a JPEG, PNG or WebP image of at most 5 MB")
+)
+
+// photoExtensions are the accepted photo types, keyed by sniffed content type
+var photoExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// ExpenseService records petty cash. Expenses spent in a closed accounting
+// period can't be added or deleted; a receipt photo can still be attached.
+type ExpenseService struct {
+	repo    *repositories.ExpenseRepository
+	files   storage.Storage
+	periods *PeriodService
+}
+
+func NewExpenseService(repo *repositories.ExpenseRepository, files storage.Storage, periods *PeriodService) *ExpenseService {
+	return &ExpenseService{repo: repo, files: files, periods: periods}
+}
+
+func (s *ExpenseService) GetAll(dateFrom, dateTo string) ([]models.Expense, error) {
+	return s.repo.GetAll(dateFrom, dateTo)
+}
+
+func (s *ExpenseService) GetByID(id int) (models.Expense, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *ExpenseService) Create(expense models.Expense) (models.Expense, error) {
+	if expense.Amount <= 0 {
+		return models.Expense{}, ErrInvalidAmount
+	}
+	if expense.SpentOn == "" {
+		expense.SpentOn = today()
+	}
+	spentOn, err := time.Parse("2006-01-02", expense.SpentOn)
+	if err != nil {
+		return models.Expense{}, ErrInvalidSpentOn
+	}
+	if err := s.periods.CheckOpen(spentOn); err != nil {
+		return models.Expense{}, err
+	}
+	return s.repo.Create(expense)
+}
+
+func (s *ExpenseService) Delete(id int) error {
+	expense, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	spentOn, err := time.Parse("2006-01-02", expense.SpentOn)
+	if err != nil {
+		return err
+	}
+	if err := s.periods.CheckOpen(spentOn); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+// AttachPhoto stores a photo of the receipt, replacing any earlier one. The
+// type is sniffed from the content rather than trusted from the client.
+func (s *ExpenseService) AttachPhoto(id int, photo io.Reader) (models.Expense, error) {
+	expense, err := s.repo.GetByID(id)
+	if err != nil {
+		return models.Expense{}, err
+	}
+
+	name, contentType, err := storePhoto(s.files, fmt.Sprintf("expenses/%d/receipt", id), photo)
+	if err != nil {
+		return models.Expense{}, err
+	}
+
+	if err := s.repo.SetAttachment(id, name, contentType); err != nil {
+		return models.Expense{}, err
+	}
+
+	if expense.AttachmentName != "" {
+		if err := s.files.Delete(expense.AttachmentName); err != nil && err != storage.ErrNotFound {
+			log.Println("Error deleting replaced expense attachment:", err)
+		}
+	}
+
+	expense.AttachmentName = name
+	expense.AttachmentType = contentType
+	expense.HasAttachment = true
+	return expense, nil
+}
+
+// OpenAttachment returns the expense with its receipt photo; the caller closes the file
+func (s *ExpenseService) OpenAttachment(id int) (models.Expense, io.ReadCloser, error) {
+	expense, err := s.repo.GetByID(id)
+	if err != nil {
+		return expense, nil, err
+	}
+	if expense.AttachmentName == "" {
+		return expense, nil, storage.ErrNotFound
+	}
+
+	f, err := s.files.Open(expense.AttachmentName)
+	return expense, f, err
+}
+
+// storePhoto checks that photo is an accepted image, sniffing its type from
+// the content rather than trusting the client, and stores it under a name
+// starting with prefix
+func storePhoto(files storage.Storage, prefix string, photo io.Reader) (name, contentType string, err error) {
+	data, err := io.ReadAll(io.LimitReader(photo, maxPhotoSize+1))
+	if err != nil {
+		return "", "", err
+	}
+	contentType = http.DetectContentType(data)
+	ext, ok := photoExtensions[contentType]
+	if len(data) == 0 || len(data) > maxPhotoSize || !ok {
+		return "", "", ErrInvalidPhoto
+	}
+
+	name = fmt.Sprintf("%s-%d%s", prefix, time.Now().UnixNano(), ext)
+	f, err := files.Create(name)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := io.Copy(f, bytes.NewReader(data)); err != nil {
+		f.Close()
+		return "", "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", "", err
+	}
+
+	return name, contentType, nil
+}
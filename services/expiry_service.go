@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"kasir-api/jobs"
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+const JobExpiredWriteOff = "inventory.expired_write_off"
+
+var ErrInvalidExpiryDays = errors.New("days must be between 0 and 365")
+
+// maxExpiryDays bounds how far ahead expiring batches are listed
+const maxExpiryDays = 365
+
+type ExpiryService struct {
+	repo *repositories.StockBatchRepository
+}
+
+func NewExpiryService(repo *repositories.StockBatchRepository, runner *jobs.Runner) *ExpiryService {
+	s := &ExpiryService{repo: repo}
+	runner.Register(JobExpiredWriteOff, s.writeOffJob)
+	return s
+}
+
+func (s *ExpiryService) writeOffJob(ctx context.Context, job models.Job) error {
+	batches, err := s.repo.WriteOffExpired()
+	if err == nil && batches > 0 {
+		log.Printf("Wrote off %d expired stock batches", batches)
+	}
+	return err
+}
+
+// GetExpiring returns the batches expiring within days, expired ones not
+// written off yet included
+func (s *ExpiryService) GetExpiring(days int) ([]models.ExpiringBatch, error) {
+	if days < 0 || days > maxExpiryDays {
+		return nil, ErrInvalidExpiryDays
+	}
+	return s.repo.GetExpiring(days)
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"kasir-api/jobs"
+	"kasir-api/locale"
+	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/repositories"
+	"kasir-api/spreadsheet"
+	"kasir-api/storage"
+)
+
+const (
+	JobStockMovementExport = "export.stock_movements"
+	JobExpenseExport       = "export.expenses"
+	JobJournalExport       = "export.journal"
+)
+
+// Export types
+const (
+	ExportStockMovements = "stock_movements"
+	ExportExpenses       = "expenses"
+	ExportJournal        = "journal"
+)
+
+var (
+	ErrInvalidExportFormat = errors.New("format must be csv or xlsx")
+	ErrInvalidExportRange  = errors.New("date_from and date_to must be in YYYY-MM-DD format with date_from not after date_to")
+	ErrExportNotReady      = errors.New("export is not ready")
+)
+
+// JournalAccounts are the codes, in the store's chart of accounts, that
+// journal exports post sales to: revenue, the PPN owed on it and an account
+// per way of paying
+type JournalAccounts struct {
+	Sales string
+	Tax   string
+	// Cash takes what was paid at the counter, in any currency
+	Cash        string
+	Voucher     string
+	PaymentLink string
+	// Installment takes what is left to be paid on installment plans
+	Installment string
+}
+
+type exportJob struct {
+	ExportID int `json:"export_id"`
+}
+
+type ExportService struct {
+	repo      *repositories.ExportRepository
+	movements *repositories.StockMovementRepository
+	expenses  *repositories.ExpenseRepository
+	reports   *repositories.ReportRepository
+	files     storage.Storage
+	runner    *jobs.Runner
+	// baseURL is the public address of the API, used for links in exports
+	baseURL string
+	// locale formats the dates in exports; amounts stay numeric
+	locale locale.Locale
+	// accounts are what journal exports post sales to; prices include PPN at
+	// the tax rate of settings
+	accounts JournalAccounts
+	settings repositories.SettingsReader
+}
+
+func NewExportService(repo *repositories.ExportRepository, movements *repositories.StockMovementRepository, expenses *repositories.ExpenseRepository, reports *repositories.ReportRepository, files storage.Storage, runner *jobs.Runner, baseURL string, loc locale.Locale, accounts JournalAccounts, settings repositories.SettingsReader) *ExportService {
+	s := &ExportService{repo: repo, movements: movements, expenses: expenses, reports: reports, files: files, runner: runner, baseURL: baseURL, locale: loc, accounts: accounts, settings: settings}
+	runner.Register(JobStockMovementExport, func(ctx context.Context, job models.Job) error {
+		return s.run(job, s.writeStockMovements)
+	})
+	runner.Register(JobExpenseExport, func(ctx context.Context, job models.Job) error {
+		return s.run(job, s.writeExpenses)
+	})
+	runner.Register(JobJournalExport, func(ctx context.Context, job models.Job) error {
+		return s.run(job, s.writeJournal)
+	})
+	return s
+}
+
+// RequestStockMovements queues an export of every stock movement between the
+// dates, inclusive, with the opening and closing balance of each product
+func (s *ExportService) RequestStockMovements(req models.ExportRequest) (models.DataExport, error) {
+	return s.request(ExportStockMovements, JobStockMovementExport, req)
+}
+
+// RequestExpenses queues an export of the expenses spent between the dates,
+// inclusive, with links to their receipt photos
+func (s *ExportService) RequestExpenses(req models.ExportRequest) (models.DataExport, error) {
+	return s.request(ExportExpenses, JobExpenseExport, req)
+}
+
+// RequestJournal queues an export of the sales between the dates, inclusive,
+// as journal entries to import into accounting software: one per day,
+// debiting how the sales were paid and crediting revenue and the PPN in it
+func (s *ExportService) RequestJournal(req models.ExportRequest) (models.DataExport, error) {
+	return s.request(ExportJournal, JobJournalExport, req)
+}
+
+func (s *ExportService) request(exportType, jobType string, req models.ExportRequest) (models.DataExport, error) {
+	if req.Format == "" {
+		req.Format = "csv"
+	}
+	if req.Format != "csv" && req.Format != "xlsx" {
+		return models.DataExport{}, ErrInvalidExportFormat
+	}
+
+	from, err := time.Parse("2006-01-02", req.DateFrom)
+	if err != nil {
+		return models.DataExport{}, ErrInvalidExportRange
+	}
+	to, err := time.Parse("2006-01-02", req.DateTo)
+	if err != nil || to.Before(from) {
+		return models.DataExport{}, ErrInvalidExportRange
+	}
+
+	export, err := s.repo.Create(models.DataExport{
+		Type:     exportType,
+		Format:   req.Format,
+		DateFrom: req.DateFrom,
+		DateTo:   req.DateTo,
+	})
+	if err != nil {
+		return models.DataExport{}, err
+	}
+
+	if _, err := s.runner.Enqueue(jobType, exportJob{ExportID: export.ID}); err != nil {
+		return models.DataExport{}, err
+	}
+	return export, nil
+}
+
+func (s *ExportService) GetAll(limit int) ([]models.DataExport, error) {
+	return s.repo.GetAll(limit)
+}
+
+func (s *ExportService) GetByID(id int) (models.DataExport, error) {
+	return s.repo.GetByID(id)
+}
+
+// Open returns a finished export with its file; the caller closes the file
+func (s *ExportService) Open(id int) (models.DataExport, io.ReadCloser, error) {
+	export, err := s.repo.GetByID(id)
+	if err != nil {
+		return export, nil, err
+	}
+	if export.Status != "done" {
+		return export, nil, ErrExportNotReady
+	}
+
+	f, err := s.files.Open(export.FileName)
+	return export, f, err
+}
+
+// run produces the file of an export job with write; failed attempts are
+// retried by the job runner with exponential backoff
+func (s *ExportService) run(job models.Job, write func(export models.DataExport, w spreadsheet.Writer) error) error {
+	var ej exportJob
+	if err := json.Unmarshal(job.Payload, &ej); err != nil {
+		return err
+	}
+
+	export, err := s.repo.GetByID(ej.ExportID)
+	if err != nil {
+		return err
+	}
+
+	fileName := fmt.Sprintf("exports/%s-%d-%s-%s.%s", strings.ReplaceAll(export.Type, "_", "-"), export.ID, export.DateFrom, export.DateTo, export.Format)
+	err = s.writeFile(export, fileName, write)
+	switch {
+	case err == nil:
+		s.recordResult(export.ID, "done", fileName, nil)
+	case job.Attempts >= job.MaxAttempts:
+		s.recordResult(export.ID, "failed", "", err)
+	default:
+		s.recordResult(export.ID, "pending", "", err)
+	}
+	return err
+}
+
+func (s *ExportService) writeFile(export models.DataExport, fileName string, write func(export models.DataExport, w spreadsheet.Writer) error) error {
+	f, err := s.files.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w, err := spreadsheet.NewWriter(export.Format, f)
+	if err != nil {
+		return err
+	}
+	if err := write(export, w); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func (s *ExportService) writeStockMovements(export models.DataExport, w spreadsheet.Writer) error {
+	balances, err := s.movements.GetOpeningBalances(export.DateFrom, export.DateTo)
+	if err != nil {
+		return err
+	}
+	movements, err := s.movements.GetMovements(export.DateFrom, export.DateTo)
+	if err != nil {
+		return err
+	}
+
+	if err := w.WriteRow("date", "product_id", "product_name", "movement", "reference_id", "quantity", "balance"); err != nil {
+		return err
+	}
+
+	// both lists are ordered by product, so movements are consumed alongside the balances
+	next := 0
+	for _, b := range balances {
+		if err := w.WriteRow(s.locale.Date(export.DateFrom), b.ProductID, b.ProductName, "opening", "", "", b.Opening); err != nil {
+			return err
+		}
+
+		closing := b.Opening
+		for ; next < len(movements) && movements[next].ProductID == b.ProductID; next++ {
+			m := movements[next]
+			reference := ""
+			if m.ReferenceID != 0 {
+				reference = fmt.Sprint(m.ReferenceID)
+			}
+			if err := w.WriteRow(s.locale.DateTime(m.CreatedAt), m.ProductID, b.ProductName, m.Reason, reference, m.Quantity, m.BalanceAfter); err != nil {
+				return err
+			}
+			closing = m.BalanceAfter
+		}
+
+		if err := w.WriteRow(s.locale.Date(export.DateTo), b.ProductID, b.ProductName, "closing", "", "", closing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ExportService) writeExpenses(export models.DataExport, w spreadsheet.Writer) error {
+	expenses, err := s.expenses.GetAll(export.DateFrom, export.DateTo)
+	if err != nil {
+		return err
+	}
+
+	if err := w.WriteRow("spent_on", "expense_id", "category", "description", "amount", "attachment"); err != nil {
+		return err
+	}
+	for _, e := range expenses {
+		attachment := ""
+		if e.HasAttachment {
+			attachment = fmt.Sprintf("%s/api/expenses/%d/attachment", s.baseURL, e.ID)
+		}
+		if err := w.WriteRow(s.locale.Date(e.SpentOn), e.ID, e.Category, e.Description, money.New(int64(e.Amount), s.locale.Currency), attachment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeJournal writes a balanced entry per day of the store time zone, a
+// line per account, with the generic columns Accurate, Jurnal and Xero map
+// their journal imports from
+func (s *ExportService) writeJournal(export models.DataExport, w spreadsheet.Writer) error {
+	loc, err := time.LoadLocation(s.reports.Timezone())
+	if err != nil {
+		return err
+	}
+	start, err := time.ParseInLocation("2006-01-02", export.DateFrom, loc)
+	if err != nil {
+		return err
+	}
+	end, err := time.ParseInLocation("2006-01-02", export.DateTo, loc)
+	if err != nil {
+		return err
+	}
+	takings, err := s.reports.GetDailyTakings(start, end.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+
+	if err := w.WriteRow("date", "reference", "description", "account_code", "debit", "credit"); err != nil {
+		return err
+	}
+	taxRate := s.settings.Settings().TaxRate
+	for _, d := range takings {
+		reference := "POS-" + strings.ReplaceAll(d.Date, "-", "")
+		description := fmt.Sprintf("Sales of %s (%d transactions)", d.Date, d.Transactions)
+		revenue := taxBase(d.Total, taxRate)
+		cash := d.Total - d.Voucher - d.PaymentLink - d.Installment
+
+		lines := []struct {
+			account       string
+			debit, credit money.Amount
+		}{
+			{s.accounts.Cash, cash, 0},
+			{s.accounts.Voucher, d.Voucher, 0},
+			{s.accounts.PaymentLink, d.PaymentLink, 0},
+			{s.accounts.Installment, d.Installment, 0},
+			{s.accounts.Sales, 0, revenue},
+			{s.accounts.Tax, 0, d.Total - revenue},
+		}
+		for _, l := range lines {
+			if l.debit == 0 && l.credit == 0 {
+				continue
+			}
+			var debit, credit interface{} = "", ""
+			if l.debit != 0 {
+				debit = money.New(int64(l.debit), s.locale.Currency)
+			}
+			if l.credit != 0 {
+				credit = money.New(int64(l.credit), s.locale.Currency)
+			}
+			if err := w.WriteRow(s.locale.Date(d.Date), reference, description, l.account, debit, credit); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *ExportService) recordResult(exportID int, status, fileName string, err error) {
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+
+	if updateErr := s.repo.UpdateStatus(exportID, status, fileName, errMsg); updateErr != nil {
+		log.Println("Error updating export status:", updateErr)
+	}
+}
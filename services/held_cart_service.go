@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/session"
+)
+
+var (
+	ErrTerminalRequired = errors.New("terminal_id is required")
+	ErrEmptyCart        = errors.New("cart must have at least one item")
+)
+
+// heldCartPrefix is the session store key prefix of held carts
+const heldCartPrefix = "held_cart:"
+
+type HeldCartService struct {
+	store session.Store
+	ttl   time.Duration
+}
+
+func NewHeldCartService(store session.Store, ttl time.Duration) *HeldCartService {
+	return &HeldCartService{store: store, ttl: ttl}
+}
+
+// Hold parks a cart until it's resumed, discarded or expires after the session TTL
+func (s *HeldCartService) Hold(ctx context.Context, req models.HoldCartRequest) (*models.HeldCart, error) {
+	req.TerminalID = strings.TrimSpace(req.TerminalID)
+	if req.TerminalID == "" {
+		return nil, ErrTerminalRequired
+	}
+	if len(req.Items) == 0 {
+		return nil, ErrEmptyCart
+	}
+	for _, item := range req.Items {
+		if item.Quantity <= 0 {
+			return nil, ErrInvalidAmount
+		}
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	cart := &models.HeldCart{
+		ID:         hex.EncodeToString(id),
+		TerminalID: req.TerminalID,
+		Note:       req.Note,
+		Items:      req.Items,
+		HeldAt:     now.Format("2006-01-02 15:04:05"),
+		ExpiresAt:  now.Add(s.ttl).Format("2006-01-02 15:04:05"),
+	}
+
+	value, err := json.Marshal(cart)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Set(ctx, heldCartPrefix+cart.ID, value, s.ttl); err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+// GetAll returns the held carts, oldest first, optionally only those of one terminal
+func (s *HeldCartService) GetAll(ctx context.Context, terminalID string) ([]models.HeldCart, error) {
+	keys, err := s.store.Keys(ctx, heldCartPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	carts := []models.HeldCart{}
+	for _, key := range keys {
+		cart, err := s.get(ctx, key)
+		if err == session.ErrNotFound {
+			// resumed or expired since the keys were listed
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if terminalID == "" || cart.TerminalID == terminalID {
+			carts = append(carts, *cart)
+		}
+	}
+
+	sort.Slice(carts, func(i, j int) bool { return carts[i].HeldAt < carts[j].HeldAt })
+	return carts, nil
+}
+
+func (s *HeldCartService) GetByID(ctx context.Context, id string) (*models.HeldCart, error) {
+	return s.get(ctx, heldCartPrefix+id)
+}
+
+// Resume removes the cart and returns it, so it can only be resumed on one terminal
+func (s *HeldCartService) Resume(ctx context.Context, id string) (*models.HeldCart, error) {
+	value, err := s.store.Take(ctx, heldCartPrefix+id)
+	if err != nil {
+		return nil, err
+	}
+	return decodeHeldCart(value)
+}
+
+func (s *HeldCartService) Delete(ctx context.Context, id string) error {
+	return s.store.Delete(ctx, heldCartPrefix+id)
+}
+
+func (s *HeldCartService) get(ctx context.Context, key string) (*models.HeldCart, error) {
+	value, err := s.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return decodeHeldCart(value)
+}
+
+func decodeHeldCart(value []byte) (*models.HeldCart, error) {
+	var cart models.HeldCart
+	if err := json.Unmarshal(value, &cart); err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
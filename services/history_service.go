@@ -0,0 +1,46 @@
+package services
+
+import (
+	"errors"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var ErrInvalidCursor = errors.New("cursor must be the next_cursor of an earlier page")
+
+// maxHistoryPage bounds the rows of one page of a history; a larger limit
+// gets this many
+const maxHistoryPage = 200
+
+// HistoryService pages through the transaction and stock movement histories
+// with cursors, which keep deep pages as quick as the first while rows are
+// added at the top
+type HistoryService struct {
+	transactions *repositories.TransactionRepository
+	movements    *repositories.StockMovementRepository
+}
+
+func NewHistoryService(transactions *repositories.TransactionRepository, movements *repositories.StockMovementRepository) *HistoryService {
+	return &HistoryService{transactions: transactions, movements: movements}
+}
+
+// GetTransactions retrieves the page of sales after cursor, the first page
+// when it is empty
+func (s *HistoryService) GetTransactions(cursor string, limit int) (models.TransactionPage, error) {
+	c, ok := repositories.ParseCursor(cursor)
+	if !ok {
+		return models.TransactionPage{}, ErrInvalidCursor
+	}
+	return s.transactions.GetPage(c, min(limit, maxHistoryPage))
+}
+
+// GetStockMovements retrieves the page of stock movements after cursor, of
+// one product unless productID is 0
+func (s *HistoryService) GetStockMovements(productID int, cursor string, limit int) (models.StockMovementPage, error) {
+	c, ok := repositories.ParseCursor(cursor)
+	if !ok {
+		return models.StockMovementPage{}, ErrInvalidCursor
+	}
+	return s.movements.GetPage(productID, c, min(limit, maxHistoryPage))
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/repositories"
+)
+
+var (
+	ErrInvalidInstallmentCount = errors.New("installment_count must be at least 2")
+	ErrInvalidDownPayment      = errors.New("down_payment must be between zero and the transaction total")
+	ErrInvalidFirstDueDate     = errors.New("first_due_date must be in YYYY-MM-DD format")
+)
+
+type InstallmentService struct {
+	repo *repositories.InstallmentRepository
+}
+
+func NewInstallmentService(repo *repositories.InstallmentRepository) *InstallmentService {
+	return &InstallmentService{repo: repo}
+}
+
+// CreatePlan splits the remaining amount of a transaction (after down payment)
+// into monthly installments. The rounding remainder goes to the last installment.
+func (s *InstallmentService) CreatePlan(req models.CreateInstallmentPlanRequest) (models.InstallmentPlan, error) {
+	if req.InstallmentCount < 2 {
+		return models.InstallmentPlan{}, ErrInvalidInstallmentCount
+	}
+
+	firstDue, err := time.Parse("2006-01-02", req.FirstDueDate)
+	if err != nil {
+		return models.InstallmentPlan{}, ErrInvalidFirstDueDate
+	}
+
+	total, err := s.repo.GetTransactionTotal(req.TransactionID)
+	if err != nil {
+		return models.InstallmentPlan{}, err
+	}
+
+	if req.DownPayment < 0 || req.DownPayment >= total {
+		return models.InstallmentPlan{}, ErrInvalidDownPayment
+	}
+
+	financed := total - req.DownPayment
+	base := financed / money.Amount(req.InstallmentCount)
+
+	plan := models.InstallmentPlan{
+		TransactionID:    req.TransactionID,
+		CustomerID:       req.CustomerID,
+		TotalAmount:      total,
+		DownPayment:      req.DownPayment,
+		InstallmentCount: req.InstallmentCount,
+	}
+
+	for i := 0; i < req.InstallmentCount; i++ {
+		amount := base
+		if i == req.InstallmentCount-1 {
+			amount = financed - base.Times(req.InstallmentCount-1)
+		}
+		plan.Installments = append(plan.Installments, models.Installment{
+			Sequence: i + 1,
+			DueDate:  firstDue.AddDate(0, i, 0).Format("2006-01-02"),
+			Amount:   amount,
+		})
+	}
+
+	plan, err = s.repo.CreatePlan(plan)
+	if err != nil {
+		return models.InstallmentPlan{}, err
+	}
+
+	plan.Outstanding = financed
+	applyInstallmentStatus(&plan, today())
+	return plan, nil
+}
+
+// GetAll lists plans, optionally filtered by customer and status (active, overdue, paid)
+func (s *InstallmentService) GetAll(customerID int, status string) ([]models.InstallmentPlan, error) {
+	plans, err := s.repo.GetAll(customerID)
+	if err != nil || status == "" {
+		return plans, err
+	}
+
+	filtered := []models.InstallmentPlan{}
+	for _, p := range plans {
+		if p.Status == status {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered, nil
+}
+
+func (s *InstallmentService) GetByID(id int) (models.InstallmentPlan, error) {
+	plan, err := s.repo.GetByID(id)
+	if err != nil {
+		return models.InstallmentPlan{}, err
+	}
+
+	applyInstallmentStatus(&plan, today())
+	return plan, nil
+}
+
+func (s *InstallmentService) RecordPayment(planID int, amount money.Amount) ([]models.InstallmentPayment, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	return s.repo.RecordPayment(planID, amount)
+}
+
+// applyInstallmentStatus derives the status of each installment and of the plan:
+// paid, overdue (past due and not fully paid), partial or pending.
+func applyInstallmentStatus(plan *models.InstallmentPlan, today string) {
+	plan.Status = "paid"
+	for i := range plan.Installments {
+		inst := &plan.Installments[i]
+		switch {
+		case inst.PaidAmount >= inst.Amount:
+			inst.Status = "paid"
+		case inst.DueDate < today:
+			inst.Status = "overdue"
+		case inst.PaidAmount > 0:
+			inst.Status = "partial"
+		default:
+			inst.Status = "pending"
+		}
+
+		if inst.Status == "overdue" {
+			plan.Status = "overdue"
+		} else if inst.Status != "paid" && plan.Status != "overdue" {
+			plan.Status = "active"
+		}
+	}
+}
+
+func today() string {
+	return time.Now().Format("2006-01-02")
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+type JobService struct {
+	repo *repositories.JobRepository
+}
+
+func NewJobService(repo *repositories.JobRepository) *JobService {
+	return &JobService{repo: repo}
+}
+
+func (s *JobService) GetAll(status string, limit int) ([]models.Job, error) {
+	return s.repo.GetAll(status, limit)
+}
+
+func (s *JobService) GetByID(id int) (models.Job, error) {
+	return s.repo.GetByID(id)
+}
+
+// Retry queues a failed job again, it is picked up on the next poll of a worker
+func (s *JobService) Retry(id int) (models.Job, error) {
+	return s.repo.Retry(id)
+}
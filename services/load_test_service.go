@@ -0,0 +1,144 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"text/template"
+	"time"
+
+	"kasir-api/models"
+)
+
+var (
+	ErrInvalidLoadTestSize = errors.New("count must be between 1 and 10000 and max_items between 1 and 20")
+	ErrNoStockForLoadTest  = errors.New("no product has stock to sell")
+)
+
+const (
+	maxLoadTestPayloads = 10000
+	maxLoadTestItems    = 20
+)
+
+// LoadTestService builds sample checkout payloads from the catalog for load
+// testing tools, so a scenario sells products that exist and have stock
+type LoadTestService struct {
+	products *ProductService
+}
+
+func NewLoadTestService(products *ProductService) *LoadTestService {
+	return &LoadTestService{products: products}
+}
+
+// CheckoutPayloads returns count checkouts of 1 to maxItems products in stock,
+// 1 to 3 of each. The same seed gives the same payloads for the same catalog.
+func (s *LoadTestService) CheckoutPayloads(count, maxItems int, seed uint64) ([]models.CheckoutRequest, error) {
+	if count < 1 || count > maxLoadTestPayloads || maxItems < 1 || maxItems > maxLoadTestItems {
+		return nil, ErrInvalidLoadTestSize
+	}
+
+	products, err := s.products.GetAll("", "", "", "", time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	inStock := []int{}
+	for _, p := range products {
+		if p.Stock > 0 {
+			inStock = append(inStock, p.ID)
+		}
+	}
+	if len(inStock) == 0 {
+		return nil, ErrNoStockForLoadTest
+	}
+
+	rng := rand.New(rand.NewPCG(seed, seed))
+	payloads := make([]models.CheckoutRequest, count)
+	for i := range payloads {
+		n := min(1+rng.IntN(maxItems), len(inStock))
+		items := make([]models.CheckoutItem, n)
+		for j, k := range rng.Perm(len(inStock))[:n] {
+			items[j] = models.CheckoutItem{ProductID: inStock[k], Quantity: 1 + rng.IntN(3)}
+		}
+		payloads[i] = models.CheckoutRequest{Items: items}
+	}
+	return payloads, nil
+}
+
+// vegetaTarget is a line of vegeta's JSON target format
+type vegetaTarget struct {
+	Method string              `json:"method"`
+	URL    string              `json:"url"`
+	Body   string              `json:"body"`
+	Header map[string][]string `json:"header"`
+}
+
+// VegetaTargets renders payloads as newline delimited vegeta targets posting
+// to the checkout of baseURL, for `vegeta attack -format=json`
+func VegetaTargets(payloads []models.CheckoutRequest, baseURL string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, p := range payloads {
+		body, err := json.Marshal(p)
+		if err != nil {
+			return nil, err
+		}
+		err = enc.Encode(vegetaTarget{
+			Method: "POST",
+			URL:    baseURL + "/api/checkout",
+			Body:   base64.StdEncoding.EncodeToString(body),
+			Header: map[string][]string{"Content-Type": {"application/json"}},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+var k6Script = template.Must(template.New("k6").Parse(`// Checkout load test generated by kasir-api, run with: k6 run checkout.js
+// BASE_URL overrides the server, e.g. k6 run -e BASE_URL=http://kasir:8080 checkout.js
+import http from 'k6/http';
+import { check } from 'k6';
+
+export const options = {
+  vus: 10,
+  duration: '30s',
+  thresholds: {
+    http_req_failed: ['rate<0.01'],
+    http_req_duration: ['p(95)<500'],
+  },
+};
+
+const baseURL = __ENV.BASE_URL || {{.BaseURL}};
+const payloads = {{.Payloads}};
+
+export default function () {
+  const payload = payloads[Math.floor(Math.random() * payloads.length)];
+  const res = http.post(baseURL + '/api/checkout', JSON.stringify(payload), {
+    headers: { 'Content-Type': 'application/json' },
+  });
+  check(res, { 'checkout created': (r) => r.status === 200 });
+}
+`))
+
+// K6Script renders a k6 scenario posting a random one of payloads to the
+// checkout of baseURL on every iteration
+func K6Script(payloads []models.CheckoutRequest, baseURL string) ([]byte, error) {
+	encodedURL, err := json.Marshal(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	encodedPayloads, err := json.Marshal(payloads)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = k6Script.Execute(&buf, map[string]string{
+		"BaseURL":  string(encodedURL),
+		"Payloads": string(encodedPayloads),
+	})
+	return buf.Bytes(), err
+}
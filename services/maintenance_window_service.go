@@ -0,0 +1,142 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var ErrInvalidMaintenanceWindow = errors.New("name is required, start and end must be different HH:MM times and days between 0 (Sunday) and 6 (Saturday)")
+
+// maintenanceWindowRefresh is how long windows read from the database are
+// trusted, so windows changed on another instance apply within a minute
+const maintenanceWindowRefresh = time.Minute
+
+// MaintenanceWindowService keeps the times of the week heavy scheduled jobs
+// may run, so rollups and reclassifications don't compete with the rush at
+// the till. Without any window they run whenever they are scheduled.
+type MaintenanceWindowService struct {
+	repo *repositories.MaintenanceWindowRepository
+
+	mu       sync.Mutex
+	windows  []models.MaintenanceWindow
+	loadedAt time.Time
+}
+
+func NewMaintenanceWindowService(repo *repositories.MaintenanceWindowRepository) *MaintenanceWindowService {
+	return &MaintenanceWindowService{repo: repo}
+}
+
+func (s *MaintenanceWindowService) GetAll() ([]models.MaintenanceWindow, error) {
+	return s.repo.GetAll()
+}
+
+func (s *MaintenanceWindowService) Create(req models.MaintenanceWindowRequest) (models.MaintenanceWindow, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	start, startErr := time.Parse("15:04", req.Start)
+	end, endErr := time.Parse("15:04", req.End)
+	if req.Name == "" || startErr != nil || endErr != nil || start.Equal(end) {
+		return models.MaintenanceWindow{}, ErrInvalidMaintenanceWindow
+	}
+	for _, d := range req.Days {
+		if d < 0 || d > 6 {
+			return models.MaintenanceWindow{}, ErrInvalidMaintenanceWindow
+		}
+	}
+
+	window, err := s.repo.Create(req)
+	if err != nil {
+		return models.MaintenanceWindow{}, err
+	}
+	s.invalidate()
+	return window, nil
+}
+
+func (s *MaintenanceWindowService) Delete(id int) error {
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+func (s *MaintenanceWindowService) invalidate() {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+func (s *MaintenanceWindowService) current() ([]models.MaintenanceWindow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.loadedAt) < maintenanceWindowRefresh {
+		return s.windows, nil
+	}
+
+	windows, err := s.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	s.windows = windows
+	s.loadedAt = time.Now()
+	return windows, nil
+}
+
+// NextOpen returns t when it falls in a maintenance window or no window is
+// defined, otherwise the time the next window opens
+func (s *MaintenanceWindowService) NextOpen(t time.Time) (time.Time, error) {
+	windows, err := s.current()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return nextOpen(windows, t), nil
+}
+
+func nextOpen(windows []models.MaintenanceWindow, t time.Time) time.Time {
+	if len(windows) == 0 {
+		return t
+	}
+
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	var next time.Time
+	for _, w := range windows {
+		start, _ := time.Parse("15:04", w.Start)
+		end, _ := time.Parse("15:04", w.End)
+		length := end.Sub(start)
+		if length <= 0 {
+			length += 24 * time.Hour
+		}
+
+		// yesterday's window may still be open past midnight
+		for d := -1; d <= 7; d++ {
+			day := midnight.AddDate(0, 0, d)
+			if !windowOnDay(w, day.Weekday()) {
+				continue
+			}
+			open := day.Add(time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute)
+			if !t.Before(open) && t.Before(open.Add(length)) {
+				return t
+			}
+			if open.After(t) && (next.IsZero() || open.Before(next)) {
+				next = open
+			}
+		}
+	}
+	return next
+}
+
+func windowOnDay(w models.MaintenanceWindow, day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if time.Weekday(d) == day {
+			return true
+		}
+	}
+	return false
+}
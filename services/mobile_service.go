@@ -0,0 +1,96 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"kasir-api/events"
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+const (
+	// mobileSummaryTTL is how long a computed summary is served before it's recomputed
+	mobileSummaryTTL = time.Minute
+	// lowStockAlertNames and alertNameLength keep the payload small
+	lowStockAlertNames = 5
+	alertNameLength    = 24
+)
+
+type MobileService struct {
+	repo *repositories.MobileRepository
+
+	mu        sync.Mutex
+	cached    *models.MobileSummary
+	expiresAt time.Time
+}
+
+// NewMobileService recomputes the summary after the sales and refunds
+// announced on bus, rather than serving it stale until mobileSummaryTTL
+func NewMobileService(repo *repositories.MobileRepository, bus *events.Bus) *MobileService {
+	s := &MobileService{repo: repo}
+	bus.Subscribe("mobile summary cache", s.invalidate, events.TransactionCompleted, events.RefundIssued)
+	return s
+}
+
+// invalidate is the bus Handler dropping the cached summary
+func (s *MobileService) invalidate(events.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cached = nil
+	return nil
+}
+
+// GetSummary returns today's numbers and alerts; every phone polling within
+// mobileSummaryTTL shares one computation
+func (s *MobileService) GetSummary() (*models.MobileSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != nil && time.Now().Before(s.expiresAt) {
+		return s.cached, nil
+	}
+
+	summary, err := s.repo.GetSummary()
+	if err != nil {
+		return nil, err
+	}
+
+	summary.Alerts = []models.MobileAlert{}
+	if summary.LowStock > 0 {
+		names, err := s.repo.GetLowStockNames(lowStockAlertNames)
+		if err != nil {
+			return nil, err
+		}
+		for i, name := range names {
+			if len([]rune(name)) > alertNameLength {
+				names[i] = string([]rune(name)[:alertNameLength-1]) + "…"
+			}
+		}
+		message := fmt.Sprintf("%d products low on stock: %s", summary.LowStock, strings.Join(names, ", "))
+		if summary.LowStock > len(names) {
+			message += ", …"
+		}
+		summary.Alerts = append(summary.Alerts, models.MobileAlert{Type: "low_stock", Message: message})
+	}
+	if summary.Overdue > 0 {
+		summary.Alerts = append(summary.Alerts, models.MobileAlert{
+			Type:    "overdue",
+			Message: fmt.Sprintf("Rp%d of installments overdue", summary.Overdue),
+		})
+	}
+	if summary.FailedJobs > 0 {
+		summary.Alerts = append(summary.Alerts, models.MobileAlert{
+			Type:    "failed_jobs",
+			Message: fmt.Sprintf("%d background jobs failed", summary.FailedJobs),
+		})
+	}
+
+	now := time.Now()
+	summary.GeneratedAt = now.Format("2006-01-02 15:04:05")
+	s.cached = summary
+	s.expiresAt = now.Add(mobileSummaryTTL)
+	return summary, nil
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"kasir-api/events"
+	"kasir-api/jobs"
+	"kasir-api/locale"
+	"kasir-api/models"
+	"kasir-api/notifier"
+	"kasir-api/repositories"
+)
+
+const (
+	JobNotification             = "notification.send"
+	JobNotificationDailySummary = "notification.daily_summary"
+)
+
+// Notification providers
+const (
+	ProviderTelegram = "telegram"
+	ProviderWhatsApp = "whatsapp"
+)
+
+// Notification events
+const (
+	NotifyLowStock     = "low_stock"
+	NotifyDailySummary = "daily_summary"
+)
+
+var (
+	ErrInvalidProvider       = errors.New("provider must be telegram or whatsapp")
+	ErrProviderNotConfigured = errors.New("provider is not configured: set TELEGRAM_BOT_TOKEN, or WHATSAPP_PHONE_NUMBER_ID and WHATSAPP_ACCESS_TOKEN")
+	ErrInvalidChatID         = errors.New("recipient must be a Telegram chat ID or @channel username")
+	ErrInvalidNotifyEvent    = errors.New("events must be one or more of low_stock, daily_summary")
+)
+
+type notificationJob struct {
+	Provider  string `json:"provider"`
+	Recipient string `json:"recipient"`
+	Message   string `json:"message"`
+}
+
+// NotificationService sends the store's alerts to the Telegram and WhatsApp
+// chats that subscribed to them; each message is retried on its own by the
+// job runner
+type NotificationService struct {
+	repo *repositories.NotificationRepository
+	// providers are the configured senders, keyed by provider
+	providers        map[string]notifier.Sender
+	reports          *ReportService
+	runner           *jobs.Runner
+	locale           locale.Locale
+	phoneCountryCode string
+}
+
+// NewNotificationService alerts about the low stock announced on bus
+func NewNotificationService(repo *repositories.NotificationRepository, providers map[string]notifier.Sender, reports *ReportService, runner *jobs.Runner, bus *events.Bus, loc locale.Locale, phoneCountryCode string) *NotificationService {
+	s := &NotificationService{repo: repo, providers: providers, reports: reports, runner: runner, locale: loc, phoneCountryCode: phoneCountryCode}
+	runner.Register(JobNotification, s.send)
+	runner.Register(JobNotificationDailySummary, s.dailySummary)
+	bus.Subscribe("notifications", s.lowStock, events.ProductLowStock)
+	return s
+}
+
+func (s *NotificationService) GetAll() ([]models.NotificationChannel, error) {
+	return s.repo.GetAll()
+}
+
+// Create adds a chat to send alerts to, active; its provider must be
+// configured
+func (s *NotificationService) Create(c models.NotificationChannel) (models.NotificationChannel, error) {
+	if c.Provider != ProviderTelegram && c.Provider != ProviderWhatsApp {
+		return models.NotificationChannel{}, ErrInvalidProvider
+	}
+	if _, ok := s.providers[c.Provider]; !ok {
+		return models.NotificationChannel{}, ErrProviderNotConfigured
+	}
+	if err := validateNotifyEvents(c.Events); err != nil {
+		return models.NotificationChannel{}, err
+	}
+
+	recipient := strings.TrimSpace(c.Recipient)
+	if c.Provider == ProviderWhatsApp {
+		phone, err := NormalizePhone(recipient, s.phoneCountryCode)
+		if err != nil || phone == "" {
+			return models.NotificationChannel{}, ErrInvalidPhone
+		}
+		recipient = phone
+	} else if _, err := strconv.ParseInt(recipient, 10, 64); err != nil && !(strings.HasPrefix(recipient, "@") && len(recipient) > 1) {
+		return models.NotificationChannel{}, ErrInvalidChatID
+	}
+	c.Recipient = recipient
+	c.Active = true
+
+	return s.repo.Save(c)
+}
+
+func (s *NotificationService) Update(c models.NotificationChannel) (models.NotificationChannel, error) {
+	if err := validateNotifyEvents(c.Events); err != nil {
+		return models.NotificationChannel{}, err
+	}
+	return s.repo.Update(c)
+}
+
+func (s *NotificationService) Delete(id int) error {
+	return s.repo.Delete(id)
+}
+
+// Test sends a test message to a channel right away, so a wrong chat or
+// token shows at once
+func (s *NotificationService) Test(id int) error {
+	c, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	sender, ok := s.providers[c.Provider]
+	if !ok {
+		return ErrProviderNotConfigured
+	}
+	return sender.Send(c.Recipient, "Tes notifikasi kasir: pesan ini sampai, notifikasi aktif.")
+}
+
+// Notify queues message to every active channel subscribed to event whose
+// provider is configured
+func (s *NotificationService) Notify(event, message string) error {
+	channels, err := s.repo.GetActiveByEvent(event)
+	if err != nil {
+		return err
+	}
+	for _, c := range channels {
+		if _, ok := s.providers[c.Provider]; !ok {
+			continue
+		}
+		if _, err := s.runner.Enqueue(JobNotification, notificationJob{Provider: c.Provider, Recipient: c.Recipient, Message: message}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lowStock is the bus Handler of ProductLowStock: it alerts about the
+// products a sale just brought down to their reorder point
+func (s *NotificationService) lowStock(e events.Event) error {
+	products, _ := e.Payload.([]models.Product)
+	if len(products) == 0 {
+		return nil
+	}
+	lines := []string{"Stok menipis:"}
+	for _, p := range products {
+		lines = append(lines, fmt.Sprintf("- %s: sisa %s (titik pesan ulang %s)", p.Name, s.locale.Number(p.Stock), s.locale.Number(p.ReorderPoint)))
+	}
+	return s.Notify(NotifyLowStock, strings.Join(lines, "\n"))
+}
+
+// dailySummary is the job handler of JobNotificationDailySummary, run at the
+// end of the day: it sends the sales of the day so far
+func (s *NotificationService) dailySummary(ctx context.Context, job models.Job) error {
+	report, err := s.reports.GetDailySalesReport("")
+	if err != nil {
+		return err
+	}
+	loc, err := s.reports.location("")
+	if err != nil {
+		return err
+	}
+
+	lines := []string{
+		"Ringkasan penjualan " + s.locale.Date(time.Now().In(loc).Format("2006-01-02")),
+		"Pendapatan: " + s.locale.Money(report.TotalRevenue),
+		"Transaksi: " + s.locale.Number(report.TotalTransaksi),
+	}
+	if p := report.ProdukTerlaris; p != nil {
+		lines = append(lines, fmt.Sprintf("Produk terlaris: %s (%s)", p.Nama, s.locale.Number(p.QtyTerjual)))
+	}
+	return s.Notify(NotifyDailySummary, strings.Join(lines, "\n"))
+}
+
+// send is the job handler of JobNotification
+func (s *NotificationService) send(ctx context.Context, job models.Job) error {
+	var nj notificationJob
+	if err := json.Unmarshal(job.Payload, &nj); err != nil {
+		return err
+	}
+	sender, ok := s.providers[nj.Provider]
+	if !ok {
+		return ErrProviderNotConfigured
+	}
+	return sender.Send(nj.Recipient, nj.Message)
+}
+
+func validateNotifyEvents(events []string) error {
+	if len(events) == 0 {
+		return ErrInvalidNotifyEvent
+	}
+	for _, event := range events {
+		if event != NotifyLowStock && event != NotifyDailySummary {
+			return ErrInvalidNotifyEvent
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/sequence"
+)
+
+// Numbering sequences that can be viewed and adjusted
+const (
+	SequenceReceipt    = "receipt"
+	SequenceSKU        = "sku"
+	SequenceTaxInvoice = "tax_invoice"
+)
+
+var (
+	ErrUnknownSequence  = errors.New("sequence must be receipt, sku or tax_invoice")
+	ErrInvalidNextValue = errors.New("next_value must be at least 1")
+	ErrAdjustmentReason = errors.New("reason is required")
+)
+
+var sequenceNames = []string{SequenceReceipt, SequenceSKU, SequenceTaxInvoice}
+
+// numberedSequence is what the numbering service needs to know of a sequence
+type numberedSequence struct {
+	format *sequence.Format
+	store  string
+	scope  func(t time.Time) string
+}
+
+// NumberingService shows where the receipt, SKU and tax invoice counters stand
+// and lets an admin move them forward, e.g. to continue from a paper receipt
+// book or to the start of a range of tax invoice numbers.
+// Adjustments only apply to the current reset period and are audited.
+type NumberingService struct {
+	repo      *repositories.SequenceRepository
+	sequences map[string]numberedSequence
+}
+
+func NewNumberingService(repo *repositories.SequenceRepository, receipts *ReceiptNumbering, skus *SKUNumbering, taxInvoices *TaxInvoiceNumbering) *NumberingService {
+	return &NumberingService{
+		repo: repo,
+		sequences: map[string]numberedSequence{
+			SequenceReceipt:    {format: receipts.format, store: receipts.store, scope: receipts.scope},
+			SequenceSKU:        {format: skus.format, store: skus.store, scope: skus.scope},
+			SequenceTaxInvoice: {format: taxInvoices.format, store: taxInvoices.store, scope: taxInvoices.scope},
+		},
+	}
+}
+
+func (s *NumberingService) GetAll() ([]models.NumberingSequence, error) {
+	all := make([]models.NumberingSequence, 0, len(sequenceNames))
+	for _, name := range sequenceNames {
+		seq, err := s.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, seq)
+	}
+	return all, nil
+}
+
+// Get returns the state of sequence name in the current reset period
+func (s *NumberingService) Get(name string) (models.NumberingSequence, error) {
+	seq, ok := s.sequences[name]
+	if !ok {
+		return models.NumberingSequence{}, ErrUnknownSequence
+	}
+
+	now := time.Now()
+	scope := seq.scope(now)
+	last, err := s.repo.Last(scope)
+	if err != nil {
+		return models.NumberingSequence{}, err
+	}
+
+	return models.NumberingSequence{
+		Name:       name,
+		Format:     seq.format.Layout(),
+		Reset:      seq.format.Reset(),
+		Store:      seq.store,
+		Scope:      scope,
+		NextValue:  last + 1,
+		NextNumber: seq.format.Render(seq.store, now, last+1),
+	}, nil
+}
+
+// SetNext makes req.NextValue the next value of sequence name in the current
+// reset period. It can only move forward, past every number already issued.
+func (s *NumberingService) SetNext(name string, req models.NumberingAdjustmentRequest) (models.NumberingAdjustment, error) {
+	seq, ok := s.sequences[name]
+	if !ok {
+		return models.NumberingAdjustment{}, ErrUnknownSequence
+	}
+	if req.NextValue < 1 {
+		return models.NumberingAdjustment{}, ErrInvalidNextValue
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return models.NumberingAdjustment{}, ErrAdjustmentReason
+	}
+
+	return s.repo.SetNext(models.NumberingAdjustment{
+		Sequence:  name,
+		Scope:     seq.scope(time.Now()),
+		NextValue: req.NextValue,
+		Reason:    req.Reason,
+	})
+}
+
+// GetAdjustments returns the latest limit adjustments, newest first
+func (s *NumberingService) GetAdjustments(limit int) ([]models.NumberingAdjustment, error) {
+	return s.repo.GetAdjustments(limit)
+}
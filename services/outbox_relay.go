@@ -0,0 +1,123 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"kasir-api/errortracking"
+	"kasir-api/events"
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+const (
+	// outboxBatch bounds the events relayed in one database transaction
+	outboxBatch = 100
+	// outboxRetention is how long relayed events are kept, e.g. to replay
+	// them by hand after a subscriber's outage
+	outboxRetention = 7 * 24 * time.Hour
+	// outboxPruneInterval is how often the relayed events past retention are
+	// deleted
+	outboxPruneInterval = time.Hour
+)
+
+// outboxPayloads decode the payloads of the events in the outbox into what
+// subscribers of the bus get published
+var outboxPayloads = map[string]func(json.RawMessage) (interface{}, error){
+	events.TransactionCompleted: decodePayload[*models.Transaction],
+	events.ProductLowStock:      decodePayload[[]models.Product],
+	events.ProductCreated:       decodePayload[models.Product],
+	events.RefundIssued:         decodePayload[models.TransactionStatusEvent],
+	events.PaymentLinkPaid:      decodePayload[models.PaymentLink],
+}
+
+func decodePayload[T any](data json.RawMessage) (interface{}, error) {
+	var payload T
+	err := json.Unmarshal(data, &payload)
+	return payload, err
+}
+
+// OutboxRelay publishes the events recorded in the outbox on the bus once
+// the writes they announce are committed. An event is delivered at least
+// once: one relayed just before the process dies may be relayed again.
+type OutboxRelay struct {
+	repo *repositories.OutboxRepository
+	bus  *events.Bus
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewOutboxRelay(repo *repositories.OutboxRepository, bus *events.Bus) *OutboxRelay {
+	return &OutboxRelay{repo: repo, bus: bus, stop: make(chan struct{})}
+}
+
+// Start relays the outbox every interval in the background, and deletes the
+// events relayed longer ago than outboxRetention
+func (r *OutboxRelay) Start(interval time.Duration) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		var pruned time.Time
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-time.After(interval):
+			}
+			if err := r.Relay(); err != nil {
+				log.Println("Error relaying outbox, retrying later:", err)
+			}
+
+			if time.Since(pruned) < outboxPruneInterval {
+				continue
+			}
+			if _, err := r.repo.Prune(time.Now().Add(-outboxRetention)); err != nil {
+				log.Println("Error pruning outbox:", err)
+				continue
+			}
+			pruned = time.Now()
+		}
+	}()
+}
+
+// Stop ends the background loop and relays what is still pending
+func (r *OutboxRelay) Stop() {
+	close(r.stop)
+	r.wg.Wait()
+	if err := r.Relay(); err != nil {
+		log.Println("Error relaying outbox:", err)
+	}
+}
+
+// Relay publishes the pending events, oldest first, until none are left
+func (r *OutboxRelay) Relay() error {
+	for {
+		n, err := r.repo.Relay(outboxBatch, r.publish)
+		if err != nil || n < outboxBatch {
+			return err
+		}
+	}
+}
+
+// publish publishes an event of the outbox; one that no longer decodes is
+// logged and dropped, so it doesn't hold up the ones after it
+func (r *OutboxRelay) publish(e models.OutboxEvent) {
+	decode, ok := outboxPayloads[e.Event]
+	if !ok {
+		log.Println("Dropping outbox event", e.ID, "of unknown event", e.Event)
+		errortracking.ReportError(fmt.Errorf("unknown outbox event %q", e.Event), map[string]string{"event": e.Event, "outbox_id": strconv.FormatInt(e.ID, 10)})
+		return
+	}
+	payload, err := decode(e.Payload)
+	if err != nil {
+		log.Println("Dropping outbox event", e.ID, "of", e.Event+":", err)
+		errortracking.ReportError(err, map[string]string{"event": e.Event, "outbox_id": strconv.FormatInt(e.ID, 10)})
+		return
+	}
+	r.bus.Deliver(events.Event{Name: e.Event, Payload: payload, OccurredAt: e.CreatedAt})
+}
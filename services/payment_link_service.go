@@ -0,0 +1,223 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"kasir-api/errortracking"
+	"kasir-api/locale"
+	"kasir-api/models"
+	"kasir-api/payment"
+	"kasir-api/repositories"
+)
+
+var (
+	ErrPaymentNotConfigured      = errors.New("payment links are not configured, set PAYMENT_GATEWAY_URL")
+	ErrInvalidCallbackSignature  = errors.New("invalid callback signature")
+	ErrInvalidPaymentCallback    = errors.New("callback must have a reference, a status of paid or expired and an amount that isn't negative")
+	ErrPaymentLinkStatus         = errors.New("status must be one of pending, paid, underpaid, expired")
+	ErrPaymentGatewayUnavailable = errors.New("payment gateway failed to create the link")
+	ErrPaymentLinkTransaction    = errors.New("payment links are for draft or pending_payment transactions")
+	ErrPaymentLinkOpen           = errors.New("a payment link for this transaction is still open")
+)
+
+type PaymentLinkService struct {
+	repo             *repositories.PaymentLinkRepository
+	transactions     *repositories.TransactionRepository
+	statuses         *TransactionStatusService
+	gateway          payment.Gateway
+	callbackSecret   string
+	ttl              time.Duration
+	push             *PushService
+	locale           locale.Locale
+	phoneCountryCode string
+}
+
+// NewPaymentLinkService creates links through gateway, which may be nil when
+// no gateway is configured, and moves the transactions paid by them on
+// through statuses; callbacks are verified with callbackSecret
+func NewPaymentLinkService(repo *repositories.PaymentLinkRepository, transactions *repositories.TransactionRepository, statuses *TransactionStatusService, gateway payment.Gateway, callbackSecret string, ttl time.Duration, push *PushService, loc locale.Locale, phoneCountryCode string) *PaymentLinkService {
+	return &PaymentLinkService{
+		repo:             repo,
+		transactions:     transactions,
+		statuses:         statuses,
+		gateway:          gateway,
+		callbackSecret:   callbackSecret,
+		ttl:              ttl,
+		push:             push,
+		locale:           loc,
+		phoneCountryCode: phoneCountryCode,
+	}
+}
+
+func (s *PaymentLinkService) GetAll(status string) ([]models.PaymentLink, error) {
+	switch status {
+	case "", repositories.PaymentLinkPending, repositories.PaymentLinkPaid, repositories.PaymentLinkUnderpaid, repositories.PaymentLinkExpired:
+	default:
+		return nil, ErrPaymentLinkStatus
+	}
+	return s.repo.GetAll(status)
+}
+
+func (s *PaymentLinkService) GetByID(id int) (models.PaymentLink, error) {
+	return s.repo.GetByID(id)
+}
+
+// Create asks the gateway for a link to pay a transaction of a remote order.
+// A draft is billed first, moved to pending_payment; the link is for what the
+// bill comes to.
+func (s *PaymentLinkService) Create(req models.PaymentLinkRequest) (models.PaymentLink, error) {
+	if s.gateway == nil {
+		return models.PaymentLink{}, ErrPaymentNotConfigured
+	}
+	phone, err := NormalizePhone(req.CustomerPhone, s.phoneCountryCode)
+	if err != nil {
+		return models.PaymentLink{}, err
+	}
+
+	transaction, err := s.transactions.GetByID(req.TransactionID)
+	if err != nil {
+		return models.PaymentLink{}, err
+	}
+	switch transaction.Status {
+	case repositories.TransactionDraft:
+	case repositories.TransactionPendingPayment:
+		open, err := s.repo.HasOpen(transaction.ID)
+		if err != nil {
+			return models.PaymentLink{}, err
+		}
+		if open {
+			return models.PaymentLink{}, ErrPaymentLinkOpen
+		}
+	default:
+		return models.PaymentLink{}, ErrPaymentLinkTransaction
+	}
+	if transaction.Status == repositories.TransactionDraft {
+		if _, err := s.statuses.UpdateStatus(transaction.ID, models.TransactionStatusRequest{Status: repositories.TransactionPendingPayment}); err != nil {
+			return models.PaymentLink{}, err
+		}
+		if transaction, err = s.transactions.GetByID(transaction.ID); err != nil {
+			return models.PaymentLink{}, err
+		}
+	}
+
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return models.PaymentLink{}, err
+	}
+
+	expiresAt := time.Now().Add(s.ttl)
+	link, err := s.repo.Create(models.PaymentLink{
+		Reference:     "PL-" + strings.ToUpper(hex.EncodeToString(id)),
+		TransactionID: transaction.ID,
+		CustomerName:  strings.TrimSpace(req.CustomerName),
+		CustomerPhone: phone,
+		Note:          req.Note,
+		Amount:        transaction.TotalAmount,
+	}, expiresAt)
+	if err != nil {
+		return models.PaymentLink{}, err
+	}
+
+	description := "Order " + link.Reference
+	if link.CustomerName != "" {
+		description += " for " + link.CustomerName
+	}
+	url, err := s.gateway.CreateLink(payment.LinkRequest{
+		Reference:   link.Reference,
+		Amount:      link.Amount,
+		Description: description,
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		if deleteErr := s.repo.Delete(link.ID); deleteErr != nil {
+			log.Println("Error deleting payment link", link.Reference, "after gateway failure:", deleteErr)
+		}
+		return models.PaymentLink{}, fmt.Errorf("%w: %v", ErrPaymentGatewayUnavailable, err)
+	}
+
+	if err := s.repo.SetURL(link.ID, url); err != nil {
+		return models.PaymentLink{}, err
+	}
+	link.URL = url
+	return link, nil
+}
+
+// HandleCallback applies a signed gateway callback. A link paid in full marks
+// its transaction paid; one paid short leaves it pending_payment for the store
+// to settle the rest. The store is notified either way; a callback for a link
+// already paid changes nothing, gateways retry callbacks until they are
+// acknowledged.
+func (s *PaymentLinkService) HandleCallback(body []byte, signature string) (models.PaymentLink, error) {
+	if !payment.Verify(s.callbackSecret, body, signature) {
+		return models.PaymentLink{}, ErrInvalidCallbackSignature
+	}
+
+	var cb models.PaymentCallback
+	if err := json.Unmarshal(body, &cb); err != nil || cb.Reference == "" || cb.Amount < 0 {
+		return models.PaymentLink{}, ErrInvalidPaymentCallback
+	}
+
+	switch cb.Status {
+	case payment.StatusExpired:
+		return s.repo.MarkExpired(cb.Reference)
+	case payment.StatusPaid:
+	default:
+		return models.PaymentLink{}, ErrInvalidPaymentCallback
+	}
+
+	link, err := s.repo.MarkPaid(cb.Reference, cb.Amount)
+	if err != nil {
+		return models.PaymentLink{}, err
+	}
+
+	if link.Status == repositories.PaymentLinkUnderpaid {
+		s.alert("Payment short", fmt.Sprintf("%s paid %s for order %s, which came to %s; the sale is still pending payment",
+			s.customer(link), s.locale.Money(cb.Amount), link.Reference, s.locale.Money(link.Amount)))
+		return link, nil
+	}
+
+	// the customer has paid, so a sale that can't be marked paid now, e.g.
+	// voided in the meantime, is left to the store to settle rather than to
+	// the gateway
+	if _, err := s.statuses.UpdateStatus(link.TransactionID, models.TransactionStatusRequest{Status: repositories.TransactionPaid}); err != nil {
+		log.Println("Error marking the sale of payment link", link.Reference, "paid:", err)
+		errortracking.ReportError(err, map[string]string{"payment_link": link.Reference})
+		s.alert("Payment received, sale not marked paid", fmt.Sprintf("%s paid %s for order %s but the sale could not be marked paid: %v", s.customer(link), s.locale.Money(cb.Amount), link.Reference, err))
+		return link, nil
+	}
+	if err := s.repo.AnnouncePaid(link); err != nil {
+		return link, err
+	}
+
+	message := fmt.Sprintf("%s paid %s for order %s", s.customer(link), s.locale.Money(cb.Amount), link.Reference)
+	if cb.Amount > link.Amount {
+		message += fmt.Sprintf("; the order came to %s", s.locale.Money(link.Amount))
+	}
+	s.alert("Payment received", message)
+
+	return link, nil
+}
+
+func (s *PaymentLinkService) alert(title, body string) {
+	if err := s.push.Alert(AlertPaymentReceived, title, body); err != nil {
+		log.Println("Error raising payment alert:", err)
+	}
+}
+
+// customer names the payer of link in alerts
+func (s *PaymentLinkService) customer(link models.PaymentLink) string {
+	switch {
+	case link.CustomerName != "":
+		return link.CustomerName
+	case link.CustomerPhone != "":
+		return link.CustomerPhone
+	}
+	return "A customer"
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var (
+	ErrInvalidPeriod  = errors.New("month must be in YYYY-MM format")
+	ErrPeriodNotEnded = errors.New("only a month that has ended can be closed")
+	ErrReopenReason   = errors.New("reason is required")
+)
+
+// PeriodService closes accounting months once the accountant has confirmed
+// their numbers. Expenses, offline sales and goods receipt costs dated in a
+// closed month are refused until it is reopened, which needs a reason; both
+// are kept in the audit trail. Sales, stock adjustments and payments are
+// always dated now, in a month that can't be closed yet.
+type PeriodService struct {
+	repo *repositories.PeriodRepository
+}
+
+func NewPeriodService(repo *repositories.PeriodRepository) *PeriodService {
+	return &PeriodService{repo: repo}
+}
+
+func (s *PeriodService) GetAll() ([]models.AccountingPeriod, error) {
+	return s.repo.GetAll()
+}
+
+// CheckOpen returns repositories.ErrPeriodClosed, wrapped with the month, when
+// the month of at is closed
+func (s *PeriodService) CheckOpen(at time.Time) error {
+	return s.repo.CheckOpen(at)
+}
+
+// Close closes month, given as YYYY-MM
+func (s *PeriodService) Close(month string, req models.PeriodCloseRequest) (models.AccountingPeriod, error) {
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return models.AccountingPeriod{}, ErrInvalidPeriod
+	}
+	if start.Format("2006-01") >= time.Now().Format("2006-01") {
+		return models.AccountingPeriod{}, ErrPeriodNotEnded
+	}
+	return s.repo.Close(start, strings.TrimSpace(req.Note))
+}
+
+// Reopen reopens month, given as YYYY-MM, for the reason given
+func (s *PeriodService) Reopen(month string, req models.PeriodReopenRequest) error {
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return ErrInvalidPeriod
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return ErrReopenReason
+	}
+	return s.repo.Reopen(start, req.Reason)
+}
+
+// GetEvents returns the latest limit closes and reopens, newest first
+func (s *PeriodService) GetEvents(limit int) ([]models.PeriodEvent, error) {
+	return s.repo.GetEvents(limit)
+}
@@ -0,0 +1,440 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// RoleAdmin holds every permission, whatever is granted to it
+const RoleAdmin = "admin"
+
+// Permissions, as seeded in the permission table
+const (
+	PermProductView       = "product.view"
+	PermProductWrite      = "product.write"
+	PermInventoryManage   = "inventory.manage"
+	PermTransactionCreate = "transaction.create"
+	PermTransactionView   = "transaction.view"
+	PermTransactionRefund = "transaction.refund"
+	PermPriceOverride     = "transaction.override_price"
+	PermCustomerManage    = "customer.manage"
+	PermExpenseManage     = "expense.manage"
+	PermReportView        = "report.view"
+	PermSettingsView      = "settings.view"
+	PermSettingsManage    = "settings.manage"
+	PermUserManage        = "user.manage"
+	PermSystemAdmin       = "system.admin"
+)
+
+// knownPermissions are the permissions above, the admin role's while the
+// permission table can't be read
+var knownPermissions = []string{
+	PermProductView, PermProductWrite, PermInventoryManage, PermTransactionCreate, PermTransactionView, PermTransactionRefund, PermPriceOverride,
+	PermCustomerManage, PermExpenseManage, PermReportView, PermSettingsView, PermSettingsManage, PermUserManage, PermSystemAdmin,
+}
+
+var (
+	ErrInvalidRoleName = errors.New("role name must be 2 to 32 lowercase letters, digits, dashes or underscores")
+	ErrAdminRoleFixed  = errors.New("the admin role holds every permission and can't be changed")
+	ErrNotPermitted    = errors.New("the account doesn't hold the permission")
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z0-9_-]{2,32}$`)
+
+// permissionsTTL is how long the roles' permissions are served from memory;
+// grants changed through another instance are in force here within it.
+// permissionsRetry is how long the last ones read stay in force after reading
+// them again failed, before the next try.
+const (
+	permissionsTTL   = time.Minute
+	permissionsRetry = 5 * time.Second
+)
+
+var (
+	readMethods  = []string{"GET", "HEAD"}
+	writeMethods = []string{"POST", "PUT", "PATCH", "DELETE"}
+)
+
+// PublicRoutes are the API routes that need no permission: signing in, on a
+// terminal or by email link, what the signed in cashier may do, the public
+// pages and the payment gateway's callbacks
+var PublicRoutes = []string{
+	"/api/auth/",
+	"/api/terminal-sessions",
+	"/api/terminal-sessions/current",
+	"/api/me/permissions",
+	"/api/public/",
+	"/api/payment-links/callback",
+}
+
+// PermissionRules are the permissions the routes need, most specific first:
+// a request needs the permission of the first rule matching it. API routes
+// neither a rule nor PublicRoutes match are refused.
+var PermissionRules = []models.PermissionRule{
+	{Path: "/api/admin/users", Permission: PermUserManage},
+	{Path: "/api/admin/users/", Permission: PermUserManage},
+	{Path: "/api/roles", Permission: PermUserManage},
+	{Path: "/api/roles/", Permission: PermUserManage},
+	{Path: "/api/permissions", Permission: PermUserManage},
+	{Path: "/api/admin/receipt-templates", Permission: PermSettingsManage},
+	{Path: "/api/admin/receipt-templates/", Permission: PermSettingsManage},
+	{Path: "/api/admin/display/media", Permission: PermSettingsManage},
+	{Path: "/api/admin/display/media/", Permission: PermSettingsManage},
+	{Path: "/api/admin/", Permission: PermSystemAdmin},
+	{Path: "/api/jobs", Permission: PermSystemAdmin},
+	{Path: "/api/jobs/", Permission: PermSystemAdmin},
+	{Path: "/api/request-journal", Permission: PermSystemAdmin},
+	{Path: "/api/request-journal/", Permission: PermSystemAdmin},
+	{Path: "/api/events", Permission: PermSystemAdmin},
+
+	{Methods: writeMethods, Path: "/api/settings", Permission: PermSettingsManage},
+	{Path: "/api/settings", Permission: PermSettingsView},
+	{Path: "/api/stores", Permission: PermSettingsManage},
+	{Path: "/api/stores/", Permission: PermSettingsManage},
+	{Path: "/api/printers", Permission: PermSettingsManage},
+	{Path: "/api/printers/", Permission: PermSettingsManage},
+	{Path: "/api/webhook", Permission: PermSettingsManage},
+	{Path: "/api/webhook/", Permission: PermSettingsManage},
+	{Path: "/api/notification-channels", Permission: PermSettingsManage},
+	{Path: "/api/notification-channels/", Permission: PermSettingsManage},
+	{Methods: writeMethods, Path: "/api/currencies/", Permission: PermSettingsManage},
+	{Path: "/api/couriers", Permission: PermSettingsManage},
+	{Path: "/api/couriers/", Permission: PermSettingsManage},
+	{Path: "/api/display/playlist", Permission: PermProductView},
+	{Path: "/api/display/media/", Permission: PermProductView},
+	{Path: "/api/sync/changes", Permission: PermProductView},
+
+	{Methods: readMethods, Path: "/api/product", Permission: PermProductView},
+	{Methods: readMethods, Path: "/api/product/", Permission: PermProductView},
+	{Methods: readMethods, Path: "/api/category", Permission: PermProductView},
+	{Methods: readMethods, Path: "/api/category/", Permission: PermProductView},
+	{Path: "/api/product", Permission: PermProductWrite},
+	{Path: "/api/product/", Permission: PermProductWrite},
+	{Path: "/api/category", Permission: PermProductWrite},
+	{Path: "/api/category/", Permission: PermProductWrite},
+	{Path: "/api/pricing-rules", Permission: PermProductWrite},
+	{Path: "/api/pricing-rules/", Permission: PermProductWrite},
+	{Path: "/api/vouchers", Permission: PermProductWrite},
+	{Path: "/api/vouchers/", Permission: PermProductWrite},
+
+	{Path: "/api/stock-movements", Permission: PermInventoryManage},
+	{Path: "/api/stock-opname", Permission: PermInventoryManage},
+	{Path: "/api/stock-opname/", Permission: PermInventoryManage},
+	{Path: "/api/write-offs", Permission: PermInventoryManage},
+	{Path: "/api/write-offs/", Permission: PermInventoryManage},
+	{Path: "/api/receiving", Permission: PermInventoryManage},
+	{Path: "/api/receiving/", Permission: PermInventoryManage},
+	{Path: "/api/supplier", Permission: PermInventoryManage},
+	{Path: "/api/supplier/", Permission: PermInventoryManage},
+	{Path: "/api/purchase-order", Permission: PermInventoryManage},
+	{Path: "/api/purchase-order/", Permission: PermInventoryManage},
+	{Path: "/api/reorder/", Permission: PermInventoryManage},
+	{Path: "/api/cycle-count/", Permission: PermInventoryManage},
+
+	// voiding and refunding need PermTransactionRefund as well, which the
+	// handler checks as it depends on the status asked for
+	{Methods: []string{"PUT"}, Path: "/api/transactions/*/status", Permission: PermTransactionCreate},
+	{Path: "/api/transactions", Permission: PermTransactionView},
+	{Path: "/api/transactions/", Permission: PermTransactionView},
+	{Path: "/api/checkout", Permission: PermTransactionCreate},
+	{Path: "/api/orders", Permission: PermTransactionCreate},
+	{Path: "/api/orders/", Permission: PermTransactionCreate},
+	{Path: "/api/held-carts", Permission: PermTransactionCreate},
+	{Path: "/api/held-carts/", Permission: PermTransactionCreate},
+	{Path: "/api/sync/transactions", Permission: PermTransactionCreate},
+	{Path: "/api/payment-links", Permission: PermTransactionCreate},
+	{Path: "/api/payment-links/", Permission: PermTransactionCreate},
+	{Path: "/api/currencies", Permission: PermTransactionCreate},
+	{Path: "/api/print-jobs", Permission: PermTransactionCreate},
+	{Path: "/api/print-jobs/", Permission: PermTransactionCreate},
+	{Path: "/api/deliveries", Permission: PermTransactionCreate},
+	{Path: "/api/deliveries/", Permission: PermTransactionCreate},
+	{Path: "/api/kitchen/orders", Permission: PermTransactionView},
+
+	{Path: "/api/customer", Permission: PermCustomerManage},
+	{Path: "/api/customer/", Permission: PermCustomerManage},
+	{Path: "/api/installment", Permission: PermCustomerManage},
+	{Path: "/api/installment/", Permission: PermCustomerManage},
+	{Path: "/api/price-contracts", Permission: PermCustomerManage},
+	{Path: "/api/price-contracts/", Permission: PermCustomerManage},
+	{Path: "/api/dunning/", Permission: PermCustomerManage},
+
+	{Path: "/api/expenses", Permission: PermExpenseManage},
+	{Path: "/api/expenses/", Permission: PermExpenseManage},
+
+	{Path: "/api/report", Permission: PermReportView},
+	{Path: "/api/report/", Permission: PermReportView},
+	{Path: "/api/report-subscriptions", Permission: PermReportView},
+	{Path: "/api/report-subscriptions/", Permission: PermReportView},
+	{Path: "/api/exports", Permission: PermReportView},
+	{Path: "/api/exports/", Permission: PermReportView},
+	{Path: "/api/stats", Permission: PermReportView},
+	{Path: "/api/mobile/summary", Permission: PermReportView},
+	{Path: "/api/abc-classification", Permission: PermReportView},
+	{Path: "/api/abc-classification/", Permission: PermReportView},
+	{Path: "/api/tax-invoices", Permission: PermReportView},
+	{Path: "/api/tax-invoices/", Permission: PermReportView},
+	{Path: "/api/activity", Permission: PermReportView},
+	{Path: "/api/push/", Permission: PermReportView},
+}
+
+// PermissionService grants permissions to roles and resolves what the
+// cashier signed in on a terminal session may do. With enforced off the
+// permissions are only reported, nothing is refused.
+type PermissionService struct {
+	repo     *repositories.PermissionRepository
+	users    *repositories.AdminUserRepository
+	sessions *TerminalSessionService
+	enforced bool
+
+	mu       sync.Mutex
+	all      []string
+	grants   map[string][]string
+	loadedAt time.Time
+	retryAt  time.Time
+}
+
+func NewPermissionService(repo *repositories.PermissionRepository, users *repositories.AdminUserRepository, sessions *TerminalSessionService, enforced bool) *PermissionService {
+	return &PermissionService{repo: repo, users: users, sessions: sessions, enforced: enforced}
+}
+
+func (s *PermissionService) GetPermissions() ([]models.Permission, error) {
+	return s.repo.GetPermissions()
+}
+
+// GetRoles returns every role with its permissions, the admin role with all of them
+func (s *PermissionService) GetRoles() ([]models.Role, error) {
+	roles, err := s.repo.GetRoles()
+	if err != nil {
+		return nil, err
+	}
+	all, err := s.repo.GetPermissions()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range roles {
+		if roles[i].Name == RoleAdmin {
+			roles[i].Permissions = permissionNames(all)
+		}
+	}
+	return roles, nil
+}
+
+// SaveRole creates a role or replaces what it may do, with permissions of the
+// permission table only. A role granting PermSystemAdmin, before or after, is
+// saved only for a holder of it. Accounts in the role signed in on a terminal
+// get the new permissions within permissionsTTL.
+func (s *PermissionService) SaveRole(ctx context.Context, token, name string, req models.RoleRequest) (models.Role, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !roleNamePattern.MatchString(name) {
+		return models.Role{}, ErrInvalidRoleName
+	}
+	if name == RoleAdmin {
+		return models.Role{}, ErrAdminRoleFixed
+	}
+
+	all, err := s.repo.GetPermissions()
+	if err != nil {
+		return models.Role{}, err
+	}
+	known := map[string]bool{}
+	for _, p := range all {
+		known[p.Name] = true
+	}
+
+	role := models.Role{Name: name, Description: strings.TrimSpace(req.Description), Permissions: []string{}}
+	seen := map[string]bool{}
+	for _, permission := range req.Permissions {
+		permission = strings.TrimSpace(permission)
+		if permission == "" || seen[permission] {
+			continue
+		}
+		if !known[permission] {
+			return models.Role{}, fmt.Errorf("%w: %s", repositories.ErrPermissionNotFound, permission)
+		}
+		seen[permission] = true
+		role.Permissions = append(role.Permissions, permission)
+	}
+	if seen[PermSystemAdmin] || s.grantsSystemAdmin(name) {
+		if err := s.Require(ctx, token, PermSystemAdmin); err != nil {
+			return models.Role{}, err
+		}
+	}
+	if err := s.repo.SaveRole(role); err != nil {
+		return models.Role{}, err
+	}
+
+	s.mu.Lock()
+	s.loadedAt, s.retryAt = time.Time{}, time.Time{}
+	s.mu.Unlock()
+	return role, nil
+}
+
+// SetUserRole moves an account to another role, in force from the next time
+// it signs in on a terminal. Moving an account into or out of a role granting
+// PermSystemAdmin, such as admin, needs the cashier signed in with token to
+// hold it.
+func (s *PermissionService) SetUserRole(ctx context.Context, token string, userID int, role string) error {
+	role = strings.ToLower(strings.TrimSpace(role))
+	if role == "" {
+		return ErrRoleRequired
+	}
+	admin, err := s.users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.AuthorizeRole(ctx, token, admin.Role); err != nil {
+		return err
+	}
+	if err := s.AuthorizeRole(ctx, token, role); err != nil {
+		return err
+	}
+	return s.users.SetRole(userID, role)
+}
+
+// AuthorizeRole returns ErrNotPermitted unless the cashier signed in with
+// token may give an account role: the admin role and the other roles
+// granting PermSystemAdmin need them to hold it
+func (s *PermissionService) AuthorizeRole(ctx context.Context, token, role string) error {
+	if !s.grantsSystemAdmin(strings.ToLower(strings.TrimSpace(role))) {
+		return nil
+	}
+	return s.Require(ctx, token, PermSystemAdmin)
+}
+
+func (s *PermissionService) grantsSystemAdmin(role string) bool {
+	for _, p := range s.forRole(role) {
+		if p == PermSystemAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Require returns ErrNotPermitted unless the cashier signed in with token
+// holds permission, for what a request needs beyond the permission of its
+// route. With enforced off nothing is refused, as with the routes.
+func (s *PermissionService) Require(ctx context.Context, token, permission string) error {
+	if !s.enforced {
+		return nil
+	}
+	permissions, ok, err := s.Permissions(ctx, token)
+	if err != nil {
+		return err
+	}
+	if ok {
+		for _, p := range permissions {
+			if p == permission {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: this needs %s", ErrNotPermitted, permission)
+}
+
+// Effective returns what the cashier signed in with a terminal session token may do
+func (s *PermissionService) Effective(ctx context.Context, token string) (*models.EffectivePermissions, error) {
+	ts, err := s.sessions.Current(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return &models.EffectivePermissions{
+		UserID:      ts.UserID,
+		Username:    ts.Username,
+		Role:        ts.Role,
+		Permissions: s.forRole(ts.Role),
+		Enforced:    s.enforced,
+	}, nil
+}
+
+// Approve returns the account whose PIN it is when it holds permission, e.g.
+// a manager approving a price override at a terminal they aren't signed in on
+func (s *PermissionService) Approve(ctx context.Context, username, pin, permission string) (*models.AdminUser, error) {
+	admin, err := s.sessions.VerifyPIN(ctx, username, pin)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range s.forRole(admin.Role) {
+		if p == permission {
+			return admin, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s needs %s", ErrNotPermitted, admin.Username, permission)
+}
+
+// Permissions returns the permissions of the cashier signed in with a
+// terminal session token; ok is false when no one is
+func (s *PermissionService) Permissions(ctx context.Context, token string) ([]string, bool, error) {
+	effective, err := s.Effective(ctx, token)
+	if err == ErrTerminalSessionRequired || err == ErrNoTerminalSession {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return effective.Permissions, true, nil
+}
+
+// forRole returns the permissions of a role, read again once older than
+// permissionsTTL; the last ones read stay in force while that fails, tried
+// again every permissionsRetry
+func (s *PermissionService) forRole(role string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.loadedAt) > permissionsTTL && !now.Before(s.retryAt) {
+		if err := s.load(); err != nil {
+			log.Println("Error reading role permissions:", err)
+			s.retryAt = now.Add(permissionsRetry)
+		}
+	}
+	if role == RoleAdmin {
+		if s.all == nil {
+			return knownPermissions
+		}
+		return s.all
+	}
+	if granted, ok := s.grants[role]; ok {
+		return granted
+	}
+	return []string{}
+}
+
+// load reads the permissions and roles; callers hold s.mu
+func (s *PermissionService) load() error {
+	permissions, err := s.repo.GetPermissions()
+	if err != nil {
+		return err
+	}
+	roles, err := s.repo.GetRoles()
+	if err != nil {
+		return err
+	}
+
+	s.all = permissionNames(permissions)
+	s.grants = make(map[string][]string, len(roles))
+	for _, role := range roles {
+		s.grants[role.Name] = role.Permissions
+	}
+	s.loadedAt = time.Now()
+	return nil
+}
+
+func permissionNames(permissions []models.Permission) []string {
+	names := make([]string, len(permissions))
+	for i, p := range permissions {
+		names[i] = p.Name
+	}
+	return names
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidPhone = errors.New("phone must be a phone number of 8 to 15 digits")
+
+// NormalizePhone writes phone in international form, e.g. "0812-3456 7890"
+// becomes "+6281234567890" for country code 62. Numbers written with 00 or +
+// keep their own country code; local numbers, with or without the leading 0,
+// get countryCode. An empty phone stays empty.
+func NormalizePhone(phone, countryCode string) (string, error) {
+	var digits strings.Builder
+	for i, c := range strings.TrimSpace(phone) {
+		switch {
+		case c >= '0' && c <= '9':
+			digits.WriteRune(c)
+		case c == '+' && i == 0:
+			digits.WriteString("00")
+		case c == ' ' || c == '-' || c == '.' || c == '(' || c == ')' || c == '/':
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+
+	number := digits.String()
+	switch {
+	case number == "":
+		return "", nil
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	case strings.HasPrefix(number, "0"):
+		number = countryCode + number[1:]
+	case !strings.HasPrefix(number, countryCode):
+		number = countryCode + number
+	}
+
+	if len(number) < 8 || len(number) > 15 {
+		return "", ErrInvalidPhone
+	}
+	return "+" + number, nil
+}
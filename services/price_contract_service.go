@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var (
+	ErrInvalidContractDates  = errors.New("valid_from must be a YYYY-MM-DD date and valid_until, when set, a date on or after it")
+	ErrContractItemsRequired = errors.New("a price contract needs at least one item")
+	ErrDuplicateContractItem = errors.New("a product can only be priced once in a price contract")
+)
+
+// PriceContractService manages the prices negotiated with wholesale
+// customers, applied at checkout by TransactionService
+type PriceContractService struct {
+	repo *repositories.PriceContractRepository
+}
+
+func NewPriceContractService(repo *repositories.PriceContractRepository) *PriceContractService {
+	return &PriceContractService{repo: repo}
+}
+
+func (s *PriceContractService) GetAll(customerID int) ([]models.PriceContract, error) {
+	return s.repo.GetAll(customerID)
+}
+
+func (s *PriceContractService) GetByID(id int) (models.PriceContract, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *PriceContractService) Create(c models.PriceContract) (models.PriceContract, error) {
+	if err := validatePriceContract(&c); err != nil {
+		return models.PriceContract{}, err
+	}
+	return s.repo.Create(c)
+}
+
+func (s *PriceContractService) Update(c models.PriceContract) (models.PriceContract, error) {
+	if err := validatePriceContract(&c); err != nil {
+		return models.PriceContract{}, err
+	}
+	return s.repo.Update(c)
+}
+
+func (s *PriceContractService) Delete(id int) error {
+	return s.repo.Delete(id)
+}
+
+func validatePriceContract(c *models.PriceContract) error {
+	c.Name = strings.TrimSpace(c.Name)
+	from, err := time.Parse("2006-01-02", c.ValidFrom)
+	if err != nil {
+		return ErrInvalidContractDates
+	}
+	if c.ValidUntil != "" {
+		until, err := time.Parse("2006-01-02", c.ValidUntil)
+		if err != nil || until.Before(from) {
+			return ErrInvalidContractDates
+		}
+	}
+
+	if len(c.Items) == 0 {
+		return ErrContractItemsRequired
+	}
+	priced := make(map[int]bool, len(c.Items))
+	for _, item := range c.Items {
+		if item.Price < 0 {
+			return ErrNegativeAmount
+		}
+		if priced[item.ProductID] {
+			return ErrDuplicateContractItem
+		}
+		priced[item.ProductID] = true
+	}
+	return nil
+}
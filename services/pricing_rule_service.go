@@ -0,0 +1,120 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/repositories"
+)
+
+var ErrInvalidMinQuantity = errors.New("min_quantity must be at least 2")
+
+type PricingRuleService struct {
+	repo repositories.PricingRuleStore
+}
+
+func NewPricingRuleService(repo repositories.PricingRuleStore) *PricingRuleService {
+	return &PricingRuleService{repo: repo}
+}
+
+func (s *PricingRuleService) GetAll(productID int) ([]models.PricingRule, error) {
+	return s.repo.GetAll(productID)
+}
+
+func (s *PricingRuleService) GetByID(id int) (models.PricingRule, error) {
+	return s.repo.GetByID(id)
+}
+
+// Create adds a rule; new rules are active straight away
+func (s *PricingRuleService) Create(rule models.PricingRule) (models.PricingRule, error) {
+	if err := validatePricingRule(rule); err != nil {
+		return models.PricingRule{}, err
+	}
+	rule.Active = true
+	return s.repo.Create(rule)
+}
+
+func (s *PricingRuleService) Update(rule models.PricingRule) (models.PricingRule, error) {
+	if err := validatePricingRule(rule); err != nil {
+		return models.PricingRule{}, err
+	}
+	return s.repo.Update(rule)
+}
+
+func (s *PricingRuleService) Delete(id int) error {
+	return s.repo.Delete(id)
+}
+
+func validatePricingRule(rule models.PricingRule) error {
+	if rule.MinQuantity < 2 {
+		return ErrInvalidMinQuantity
+	}
+	if rule.Price <= 0 {
+		return ErrInvalidAmount
+	}
+	return nil
+}
+
+// guardrails a checkout line may be priced past only with approval
+const (
+	GuardrailMaxDiscount = "max_discount"
+	GuardrailBelowCost   = "below_cost"
+)
+
+// PriceGuardrails bound how low checkout lines may be priced, by pricing
+// rules, contracts or overrides alike, without a manager's approval: at most
+// MaxDiscountPercent off the price of the line, unlimited when 0, and with
+// BlockBelowCost not under the cost of its units
+type PriceGuardrails struct {
+	MaxDiscountPercent int
+	BlockBelowCost     bool
+}
+
+// check returns the guardrail a line of quantity units at unitPrice, costing
+// costPrice each, is priced past at subtotal, nil when none
+func (g PriceGuardrails) check(unitPrice, costPrice money.Amount, quantity int, subtotal money.Amount) *models.PriceViolation {
+	regular := unitPrice.Times(quantity)
+	if g.MaxDiscountPercent > 0 && (regular-subtotal)*100 > regular.Times(g.MaxDiscountPercent) {
+		return &models.PriceViolation{
+			Guardrail: GuardrailMaxDiscount,
+			Message:   fmt.Sprintf("discount of %d on %d is more than %d%%", regular-subtotal, regular, g.MaxDiscountPercent),
+		}
+	}
+	if cost := costPrice.Times(quantity); g.BlockBelowCost && subtotal < cost {
+		return &models.PriceViolation{
+			Guardrail: GuardrailBelowCost,
+			Message:   fmt.Sprintf("sold at %d, under its cost of %d", subtotal, cost),
+		}
+	}
+	return nil
+}
+
+// priceLine returns the subtotal of quantity units at unitPrice with the
+// cheapest of rules applied. Full groups of the rule's quantity sell at its
+// price and the remaining units at the unit price. The rule is nil when none
+// beats the unit price.
+func priceLine(unitPrice money.Amount, quantity int, rules []models.PricingRule) (money.Amount, *models.AppliedPricingRule) {
+	regular := unitPrice.Times(quantity)
+	subtotal := regular
+	var applied *models.AppliedPricingRule
+	for _, rule := range rules {
+		if quantity < rule.MinQuantity {
+			continue
+		}
+
+		total := rule.Price.Times(quantity/rule.MinQuantity) + unitPrice.Times(quantity%rule.MinQuantity)
+		if total < subtotal {
+			subtotal = total
+			applied = &models.AppliedPricingRule{
+				ID:          rule.ID,
+				Name:        rule.Name,
+				MinQuantity: rule.MinQuantity,
+				Price:       rule.Price,
+				Discount:    regular - total,
+			}
+		}
+	}
+	return subtotal, applied
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/repositories"
+)
+
+// pricingRules is a PricingRuleStore holding fixed active rules
+type pricingRules struct {
+	repositories.PricingRuleStore
+	active map[int][]models.PricingRule
+}
+
+func (p pricingRules) GetActive(productIDs []int) (map[int][]models.PricingRule, error) {
+	return p.active, nil
+}
+
+// benchRules returns n quantity tiers of a product priced 5000 a unit, each
+// cheaper a unit than the one before
+func benchRules(n int) []models.PricingRule {
+	rules := make([]models.PricingRule, n)
+	for i := range rules {
+		minQuantity := 2 * (i + 1)
+		rules[i] = models.PricingRule{ID: i + 1, Name: fmt.Sprintf("tier %d", i+1), MinQuantity: minQuantity, Price: money.Amount(4900 - 100*i).Times(minQuantity)}
+	}
+	return rules
+}
+
+func BenchmarkPriceLine(b *testing.B) {
+	for _, n := range []int{0, 1, 5, 20} {
+		rules := benchRules(n)
+		b.Run(fmt.Sprintf("rules=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				priceLine(5000, 13, rules)
+			}
+		})
+	}
+}
+
+// BenchmarkPricer prices a line as checkout does: store prices, contracts,
+// pricing rules, an override and the guardrails
+func BenchmarkPricer(b *testing.B) {
+	service := NewTransactionService(nil, nil, pricingRules{active: map[int][]models.PricingRule{1: benchRules(5)}}, nil, nil, nil, nil, nil, OpenItemPolicy{}, money.Rounding{}).
+		WithGuardrails(PriceGuardrails{MaxDiscountPercent: 30, BlockBelowCost: true})
+	override := &models.PriceOverride{Price: 4500, Reason: "bench"}
+
+	for _, bench := range []struct {
+		name     string
+		override *models.PriceOverride
+		approver *models.AdminUser
+	}{
+		{name: "rules"},
+		{name: "override", override: override, approver: &models.AdminUser{ID: 1, Username: "bench"}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			price, err := service.pricer(0, 0, []int{1}, bench.approver)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			for b.Loop() {
+				price(1, 5000, 3500, 13, bench.override)
+			}
+		})
+	}
+}
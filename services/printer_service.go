@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"kasir-api/jobs"
+	"kasir-api/models"
+	"kasir-api/printer"
+	"kasir-api/repositories"
+)
+
+var (
+	ErrInvalidPrinterName    = errors.New("name is required, at most 100 characters")
+	ErrInvalidPrinterAddress = errors.New("address must be host:port, e.g. 192.168.1.50:9100")
+	ErrInvalidCategoryIDs    = errors.New("category_ids must be category IDs")
+	ErrInvalidPrintStatus    = errors.New("status must be pending, printed or failed")
+	ErrPrintJobPending       = errors.New("print job is still waiting to be printed")
+)
+
+const JobKitchenPrint = "kitchen.print"
+
+const (
+	PrintJobPending = "pending"
+	PrintJobPrinted = "printed"
+	PrintJobFailed  = "failed"
+)
+
+// ticketWidth is the characters a line of a 58mm kitchen printer holds
+const ticketWidth = 32
+
+type printJob struct {
+	PrintJobID int `json:"print_job_id"`
+}
+
+// PrinterService routes the lines of restaurant orders to the kitchen
+// printers printing their categories, as print jobs sent by the job runner
+type PrinterService struct {
+	repo   *repositories.PrinterRepository
+	runner *jobs.Runner
+}
+
+func NewPrinterService(repo *repositories.PrinterRepository, runner *jobs.Runner) *PrinterService {
+	s := &PrinterService{repo: repo, runner: runner}
+	runner.Register(JobKitchenPrint, s.print)
+	return s
+}
+
+func (s *PrinterService) GetAll() ([]models.Printer, error) {
+	return s.repo.GetAll()
+}
+
+func (s *PrinterService) GetByID(id int) (models.Printer, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *PrinterService) Create(p models.Printer) (models.Printer, error) {
+	if err := validatePrinter(&p); err != nil {
+		return models.Printer{}, err
+	}
+	p.Active = true
+	return s.repo.Create(p)
+}
+
+func (s *PrinterService) Update(p models.Printer) (models.Printer, error) {
+	if err := validatePrinter(&p); err != nil {
+		return models.Printer{}, err
+	}
+	return s.repo.Update(p)
+}
+
+func (s *PrinterService) Delete(id int) error {
+	return s.repo.Delete(id)
+}
+
+func (s *PrinterService) GetJobs(status string, limit int) ([]models.PrintJob, error) {
+	if status != "" && status != PrintJobPending && status != PrintJobPrinted && status != PrintJobFailed {
+		return nil, ErrInvalidPrintStatus
+	}
+	return s.repo.GetJobs(status, limit)
+}
+
+// Retry sends a failed print job again, or reprints a printed one
+func (s *PrinterService) Retry(id int) (models.PrintJob, error) {
+	job, err := s.repo.GetJob(id)
+	if err != nil {
+		return models.PrintJob{}, err
+	}
+	if job.Status == PrintJobPending {
+		return models.PrintJob{}, ErrPrintJobPending
+	}
+
+	if err := s.repo.UpdateJob(job.ID, PrintJobPending, job.Attempts, ""); err != nil {
+		return models.PrintJob{}, err
+	}
+	s.enqueue(job.ID)
+	return s.repo.GetJob(id)
+}
+
+// Route sends the lines order took since it was last routed to the active
+// printers: each line to every printer of one of its categories, or to the
+// default printer when none prints them. Lines no printer takes, e.g. while
+// none is set up, are never printed.
+func (s *PrinterService) Route(order *models.Transaction) error {
+	printers, err := s.repo.GetActive()
+	if err != nil {
+		return err
+	}
+
+	ids, err := s.repo.RouteLines(order.ID, func(lines []models.PrintLine) ([]models.PrintJob, error) {
+		byPrinter := map[int][]models.PrintLine{}
+		for _, line := range lines {
+			for _, p := range linePrinters(printers, line) {
+				byPrinter[p.ID] = append(byPrinter[p.ID], line)
+			}
+		}
+
+		tickets := []models.PrintJob{}
+		for _, p := range printers {
+			if len(byPrinter[p.ID]) > 0 {
+				tickets = append(tickets, models.PrintJob{PrinterID: p.ID, Content: kitchenTicket(p.Name, order, byPrinter[p.ID])})
+			}
+		}
+		return tickets, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		s.enqueue(id)
+	}
+	return nil
+}
+
+func (s *PrinterService) enqueue(printJobID int) {
+	if _, err := s.runner.Enqueue(JobKitchenPrint, printJob{PrintJobID: printJobID}); err != nil {
+		s.recordResult(printJobID, PrintJobFailed, 0, err)
+	}
+}
+
+// print is the job handler of JobKitchenPrint; failed attempts are retried by
+// the job runner with exponential backoff
+func (s *PrinterService) print(ctx context.Context, job models.Job) error {
+	var pj printJob
+	if err := json.Unmarshal(job.Payload, &pj); err != nil {
+		return err
+	}
+
+	ticket, err := s.repo.GetJob(pj.PrintJobID)
+	if err != nil {
+		return err
+	}
+
+	p, err := s.repo.GetByID(ticket.PrinterID)
+	if err == sql.ErrNoRows {
+		// deleted since the order was routed, nowhere left to print
+		s.recordResult(ticket.ID, PrintJobFailed, job.Attempts, errors.New("printer was deleted"))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = printer.Send(ctx, p.Address, ticket.Content)
+	switch {
+	case err == nil:
+		s.recordResult(ticket.ID, PrintJobPrinted, job.Attempts, nil)
+	case job.Attempts >= job.MaxAttempts:
+		s.recordResult(ticket.ID, PrintJobFailed, job.Attempts, err)
+	default:
+		s.recordResult(ticket.ID, PrintJobPending, job.Attempts, err)
+	}
+	return err
+}
+
+func (s *PrinterService) recordResult(printJobID int, status string, attempts int, err error) {
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+
+	if updateErr := s.repo.UpdateJob(printJobID, status, attempts, errMsg); updateErr != nil {
+		log.Println("Error updating print job status:", updateErr)
+	}
+}
+
+// linePrinters returns the printers of the categories of line, or the
+// default printer when there are none
+func linePrinters(printers []models.Printer, line models.PrintLine) []models.Printer {
+	matched := []models.Printer{}
+	var fallback []models.Printer
+	for _, p := range printers {
+		if p.IsDefault {
+			fallback = []models.Printer{p}
+		}
+		for _, id := range p.CategoryIDs {
+			if containsInt(line.CategoryIDs, id) {
+				matched = append(matched, p)
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return fallback
+	}
+	return matched
+}
+
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// kitchenTicket is what a station prints for lines of order: the station,
+// who the order is for and the quantity of each line, without prices
+func kitchenTicket(station string, order *models.Transaction, lines []models.PrintLine) string {
+	var b strings.Builder
+	rule := strings.Repeat("-", ticketWidth) + "\n"
+
+	b.WriteString(strings.ToUpper(station) + "\n")
+	if order.TableNumber != "" {
+		fmt.Fprintf(&b, "Meja %s\n", order.TableNumber)
+	}
+	if order.QueueNumber > 0 {
+		fmt.Fprintf(&b, "Antrian %d\n", order.QueueNumber)
+	}
+	if order.ReceiptNumber != "" {
+		b.WriteString(order.ReceiptNumber + "\n")
+	}
+	b.WriteString(time.Now().Format("02/01/2006 15:04") + "\n")
+	b.WriteString(rule)
+	for _, line := range lines {
+		fmt.Fprintf(&b, "%d x %s\n", line.Quantity, line.Name)
+	}
+	b.WriteString(rule)
+	return b.String()
+}
+
+func validatePrinter(p *models.Printer) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" || len(p.Name) > 100 {
+		return ErrInvalidPrinterName
+	}
+
+	p.Address = strings.TrimSpace(p.Address)
+	host, port, err := net.SplitHostPort(p.Address)
+	if err != nil || host == "" || port == "" || len(p.Address) > 255 {
+		return ErrInvalidPrinterAddress
+	}
+
+	if p.CategoryIDs == nil {
+		p.CategoryIDs = []int{}
+	}
+	for _, id := range p.CategoryIDs {
+		if id <= 0 {
+			return ErrInvalidCategoryIDs
+		}
+	}
+	return nil
+}
@@ -1,34 +1,291 @@
 package services
 
 import (
+	"database/sql"
+	"errors"
+	"time"
+
 	"kasir-api/models"
 	"kasir-api/repositories"
 )
 
+var (
+	ErrEmptyBundle    = errors.New("bundle must have at least one component")
+	ErrInvalidSort    = errors.New("sort must be name, created_at or updated_at, with a leading - for descending order")
+	ErrInvalidExpand  = errors.New("expand must be category")
+	ErrInvalidBatch   = errors.New("a batch holds 1 to 500 products")
+	ErrMissingBatchID = errors.New("id is required")
+	ErrZeroAdjustment = errors.New("quantity must not be zero")
+)
+
+// maxSKUAttempts bounds how many generated SKUs are tried when they collide
+// with SKUs entered by hand
+const maxSKUAttempts = 5
+
+// maxBatchProducts bounds the products of one batch, so one request doesn't
+// hold the catalog locked for long
+const maxBatchProducts = 500
+
+// outcomes of the products of a batch
+const (
+	BatchCreated = "created"
+	BatchUpdated = "updated"
+	BatchFailed  = "failed"
+	BatchSkipped = "skipped"
+)
+
 type ProductService struct {
-	Repo *repositories.ProductRepository
+	Repo repositories.ProductStore
+	SKUs *SKUNumbering
 }
 
-func NewProductService(repo *repositories.ProductRepository) *ProductService {
-	return &ProductService{Repo: repo}
+func NewProductService(repo repositories.ProductStore, skus *SKUNumbering) *ProductService {
+	return &ProductService{Repo: repo, SKUs: skus}
 }
 
-func (s *ProductService) GetAll(name string) ([]models.Product, error) {
-	return s.Repo.GetAll(name)
+// GetAll retrieves the active products, optionally filtered by name and ABC
+// class, sorted by sort (see repositories.CatalogOrderBy); with updatedSince
+// set, the ones updated from then on, deleted ones included. expand names the
+// relations embedded in each, separated by commas (see
+// repositories.ProductRelations).
+func (s *ProductService) GetAll(name, abcClass, sort, expand string, updatedSince time.Time) ([]models.Product, error) {
+	if !repositories.ValidCatalogSort(sort) {
+		return nil, ErrInvalidSort
+	}
+	expansion, ok := repositories.ParseExpansion(expand, repositories.ProductRelations)
+	if !ok {
+		return nil, ErrInvalidExpand
+	}
+	return s.Repo.GetAll(name, abcClass, sort, updatedSince, expansion)
 }
 
 func (s *ProductService) GetByID(id int) (models.Product, error) {
 	return s.Repo.GetByID(id)
 }
 
+// GetBySKU retrieves the active product with the given SKU
+func (s *ProductService) GetBySKU(sku string) (models.Product, error) {
+	return s.Repo.GetBySKU(sku)
+}
+
+// Create saves a new product, generating its SKU when none is given
 func (s *ProductService) Create(product models.Product) (models.Product, error) {
-	return s.Repo.Create(product)
+	if err := prepareProduct(&product); err != nil {
+		return models.Product{}, err
+	}
+	if product.SKU != "" {
+		return s.Repo.Create(product)
+	}
+
+	// a generated SKU may already have been entered by hand; draw the next one
+	for attempt := 1; ; attempt++ {
+		var err error
+		product.SKU, err = s.SKUs.Next()
+		if err != nil {
+			return models.Product{}, err
+		}
+		created, err := s.Repo.Create(product)
+		if err != repositories.ErrDuplicateSKU || attempt == maxSKUAttempts {
+			return created, err
+		}
+	}
 }
 
 func (s *ProductService) Update(product models.Product) (models.Product, error) {
+	if err := prepareProduct(&product); err != nil {
+		return models.Product{}, err
+	}
 	return s.Repo.Update(product)
 }
 
+// prepareProduct checks product before it is saved, dropping repeated
+// categories and trimming its SKU
+func prepareProduct(product *models.Product) error {
+	if product.Price < 0 || product.CostPrice < 0 {
+		return ErrNegativeAmount
+	}
+	product.CategoryIDs = uniqueIDs(product.CategoryIDs)
+
+	var err error
+	product.SKU, err = normalizeSKU(product.SKU)
+	return err
+}
+
+// MergeProduct returns existing with the fields set in changes; category_ids
+// replaces its categories when present, [] clears them
+func MergeProduct(existing, changes models.Product) models.Product {
+	if changes.Name != "" {
+		existing.Name = changes.Name
+	}
+	if changes.Barcode != "" {
+		existing.Barcode = changes.Barcode
+	}
+	if changes.SKU != "" {
+		existing.SKU = changes.SKU
+	}
+	if changes.Price != 0 {
+		existing.Price = changes.Price
+	}
+	if changes.CostPrice != 0 {
+		existing.CostPrice = changes.CostPrice
+	}
+	if changes.Stock != 0 {
+		existing.Stock = changes.Stock
+	}
+	if changes.ReorderPoint != 0 {
+		existing.ReorderPoint = changes.ReorderPoint
+	}
+	if changes.ReorderQty != 0 {
+		existing.ReorderQty = changes.ReorderQty
+	}
+	if changes.CategoryIDs != nil {
+		existing.CategoryIDs = changes.CategoryIDs
+	}
+	return existing
+}
+
+// CreateBatch creates products in one database transaction, as Create would
+// one by one, and reports on each. A product that fails is left out and the
+// others are saved; when atomic, none is saved unless all can be.
+func (s *ProductService) CreateBatch(products []models.Product, atomic bool) ([]models.ProductBatchResult, error) {
+	if len(products) == 0 || len(products) > maxBatchProducts {
+		return nil, ErrInvalidBatch
+	}
+
+	prepared := make([]models.Product, len(products))
+	errs := make([]error, len(products))
+	for i, product := range products {
+		errs[i] = prepareProduct(&product)
+		// SKUs are drawn up front; one entered by hand already fails the
+		// product, it draws the next SKU when sent again
+		if errs[i] == nil && product.SKU == "" {
+			if product.SKU, errs[i] = s.SKUs.Next(); errs[i] != nil {
+				return nil, errs[i]
+			}
+		}
+		prepared[i] = product
+	}
+	return s.saveBatch(prepared, errs, atomic, BatchCreated, s.Repo.CreateBatch)
+}
+
+// UpdateBatch applies changes, each naming the product it changes by ID as
+// an update of it would, in one database transaction and reports on each.
+// A product that fails is left out and the others are saved; when atomic,
+// none is saved unless all can be.
+func (s *ProductService) UpdateBatch(changes []models.Product, atomic bool) ([]models.ProductBatchResult, error) {
+	if len(changes) == 0 || len(changes) > maxBatchProducts {
+		return nil, ErrInvalidBatch
+	}
+
+	prepared := make([]models.Product, len(changes))
+	errs := make([]error, len(changes))
+	for i, change := range changes {
+		if change.ID <= 0 {
+			errs[i] = ErrMissingBatchID
+			continue
+		}
+		existing, err := s.Repo.GetByID(change.ID)
+		if err == sql.ErrNoRows {
+			errs[i] = repositories.ErrProductNotFound
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		prepared[i] = MergeProduct(existing, change)
+		errs[i] = prepareProduct(&prepared[i])
+	}
+	return s.saveBatch(prepared, errs, atomic, BatchUpdated, s.Repo.UpdateBatch)
+}
+
+// saveBatch saves the products that passed the checks, errs holding why the
+// others didn't, and reports on all of them
+func (s *ProductService) saveBatch(products []models.Product, errs []error, atomic bool, outcome string, save func([]models.Product, bool) ([]models.Product, []error, error)) ([]models.ProductBatchResult, error) {
+	results := make([]models.ProductBatchResult, len(products))
+	valid := []models.Product{}
+	validIndex := []int{}
+	for i := range products {
+		results[i] = models.ProductBatchResult{Index: i, Status: BatchSkipped}
+		if errs[i] != nil {
+			results[i].Status, results[i].Error = BatchFailed, errs[i].Error()
+			continue
+		}
+		valid = append(valid, products[i])
+		validIndex = append(validIndex, i)
+	}
+	if len(valid) == 0 || (atomic && len(valid) < len(products)) {
+		return results, nil
+	}
+
+	saved, saveErrs, err := save(valid, atomic)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range validIndex {
+		switch {
+		case saveErrs[j] != nil:
+			results[i].Status, results[i].Error = BatchFailed, saveErrs[j].Error()
+		case saved != nil:
+			product := saved[j]
+			results[i].Status, results[i].Product = outcome, &product
+		}
+	}
+	return results, nil
+}
+
+// uniqueIDs drops repeated IDs, keeping the first occurrence
+func uniqueIDs(ids []int) []int {
+	unique := []int{}
+	seen := map[int]bool{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
 func (s *ProductService) Delete(id int) error {
 	return s.Repo.Delete(id)
 }
+
+// AdjustStock adds quantity, negative to take stock, to the stock of product id
+func (s *ProductService) AdjustStock(id, quantity int) (models.Product, error) {
+	if quantity == 0 {
+		return models.Product{}, ErrZeroAdjustment
+	}
+	return s.Repo.AdjustStock(id, quantity)
+}
+
+// SetComponents makes the product a bundle of the given components
+func (s *ProductService) SetComponents(bundleID int, req models.BundleRequest) (models.Product, error) {
+	if len(req.Components) == 0 {
+		return models.Product{}, ErrEmptyBundle
+	}
+
+	seen := map[int]bool{}
+	for _, c := range req.Components {
+		if c.Quantity <= 0 {
+			return models.Product{}, ErrInvalidAmount
+		}
+		if seen[c.ProductID] {
+			return models.Product{}, repositories.ErrInvalidBundleComponent
+		}
+		seen[c.ProductID] = true
+	}
+
+	if err := s.Repo.SetComponents(bundleID, req.Components); err != nil {
+		return models.Product{}, err
+	}
+	return s.Repo.GetByID(bundleID)
+}
+
+// RemoveComponents turns a bundle back into a regular product with no stock
+func (s *ProductService) RemoveComponents(bundleID int) (models.Product, error) {
+	if err := s.Repo.SetComponents(bundleID, nil); err != nil {
+		return models.Product{}, err
+	}
+	return s.Repo.GetByID(bundleID)
+}
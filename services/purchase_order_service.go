@@ -0,0 +1,99 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var (
+	ErrEmptyPurchaseOrder  = errors.New("purchase order must have at least one item")
+	ErrReceivingInProgress = errors.New("purchase order has an open receiving session")
+)
+
+// isOpenPurchaseOrder reports whether a purchase order still expects deliveries
+func isOpenPurchaseOrder(status string) bool {
+	return status == "open" || status == "partially_received"
+}
+
+type PurchaseOrderService struct {
+	repo *repositories.PurchaseOrderRepository
+}
+
+func NewPurchaseOrderService(repo *repositories.PurchaseOrderRepository) *PurchaseOrderService {
+	return &PurchaseOrderService{repo: repo}
+}
+
+func (s *PurchaseOrderService) Create(req models.CreatePurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	if len(req.Items) == 0 {
+		return nil, ErrEmptyPurchaseOrder
+	}
+
+	po := models.PurchaseOrder{SupplierID: req.SupplierID}
+	for _, item := range req.Items {
+		if item.Quantity <= 0 {
+			return nil, ErrInvalidAmount
+		}
+		if item.UnitCost < 0 {
+			return nil, ErrNegativeAmount
+		}
+		po.Items = append(po.Items, models.PurchaseOrderItem{
+			ProductID:       item.ProductID,
+			QuantityOrdered: item.Quantity,
+			UnitCost:        item.UnitCost,
+		})
+	}
+
+	return s.repo.Create(po)
+}
+
+func (s *PurchaseOrderService) GetAll(status string) ([]models.PurchaseOrder, error) {
+	return s.repo.GetAll(status)
+}
+
+// GetByID returns a purchase order with the quantity still back-ordered per item
+func (s *PurchaseOrderService) GetByID(id int) (*models.PurchaseOrder, error) {
+	po, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if isOpenPurchaseOrder(po.Status) {
+		for i := range po.Items {
+			item := &po.Items[i]
+			if item.QuantityReceived < item.QuantityOrdered {
+				item.QuantityRemaining = item.QuantityOrdered - item.QuantityReceived
+			}
+		}
+	}
+	return po, nil
+}
+
+// ShortClose closes a partially delivered purchase order so the remaining
+// quantities are no longer expected, e.g. when the supplier can't deliver them
+func (s *PurchaseOrderService) ShortClose(id int, reason string) (*models.PurchaseOrder, error) {
+	po, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !isOpenPurchaseOrder(po.Status) {
+		return nil, ErrPurchaseOrderNotOpen
+	}
+
+	if err := s.repo.ShortClose(id, reason); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrReceivingInProgress
+		}
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+func (s *PurchaseOrderService) GetReceipts(id int) ([]models.GoodsReceipt, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetReceipts(id)
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"kasir-api/jobs"
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+const JobRecycleBinPurge = "maintenance.recycle_bin_purge"
+
+type PurgeService struct {
+	repo          *repositories.PurgeRepository
+	retentionDays int
+	dryRun        bool
+}
+
+// NewPurgeService purges rows soft deleted more than retentionDays ago; with
+// dryRun the scheduled purge only logs what it would remove
+func NewPurgeService(repo *repositories.PurgeRepository, runner *jobs.Runner, retentionDays int, dryRun bool) *PurgeService {
+	s := &PurgeService{repo: repo, retentionDays: retentionDays, dryRun: dryRun}
+	runner.Register(JobRecycleBinPurge, s.purgeJob)
+	return s
+}
+
+// DryRun reports whether purges are dry runs unless asked otherwise
+func (s *PurgeService) DryRun() bool {
+	return s.dryRun
+}
+
+func (s *PurgeService) purgeJob(ctx context.Context, job models.Job) error {
+	result, err := s.Purge(s.dryRun)
+	if err == nil {
+		log.Printf("Recycle bin purge (dry run %t): %d purged, %d kept", result.DryRun, result.Purged, result.Kept)
+	}
+	return err
+}
+
+// Purge hard deletes the rows soft deleted before the retention; a dry run
+// only reports and logs what it would purge
+func (s *PurgeService) Purge(dryRun bool) (models.PurgeResult, error) {
+	cutoff := time.Now().AddDate(0, 0, -s.retentionDays)
+	entries, err := s.repo.Purge(cutoff, dryRun)
+	if err != nil {
+		return models.PurgeResult{}, err
+	}
+
+	result := models.PurgeResult{
+		DryRun:        dryRun,
+		RetentionDays: s.retentionDays,
+		Cutoff:        cutoff.Format("2006-01-02 15:04:05"),
+		Entries:       entries,
+	}
+	for _, e := range entries {
+		if e.Outcome == repositories.PurgePurged {
+			result.Purged++
+		} else {
+			result.Kept++
+		}
+	}
+	return result, nil
+}
+
+// GetLog returns the most recent purge log entries
+func (s *PurgeService) GetLog(limit int) ([]models.PurgeEntry, error) {
+	return s.repo.GetLog(limit)
+}
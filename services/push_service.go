@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"kasir-api/jobs"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/webpush"
+)
+
+const JobPushNotification = "push.notification"
+
+// Critical alerts pushed to the owners' browsers
+const (
+	AlertPaymentGatewayDown = "payment_gateway_down"
+	AlertBackupFailed       = "backup_failed"
+	AlertLargeRefund        = "large_refund"
+	AlertJobFailed          = "job_failed"
+	AlertPaymentReceived    = "payment_received"
+	AlertTest               = "test"
+)
+
+const (
+	// pushTTL is how long push services keep an alert for an offline browser
+	pushTTL = 24 * time.Hour
+	// maxAlertBody keeps the encrypted payload well inside a single record
+	maxAlertBody = 1000
+)
+
+var (
+	ErrPushNotConfigured   = errors.New("web push is not configured, set VAPID_PRIVATE_KEY")
+	ErrInvalidSubscription = errors.New("invalid push subscription")
+)
+
+type pushJob struct {
+	SubscriptionID int              `json:"subscription_id"`
+	Alert          models.PushAlert `json:"alert"`
+}
+
+type PushService struct {
+	repo   *repositories.PushRepository
+	client *webpush.Client
+	runner *jobs.Runner
+}
+
+// NewPushService sends through client; a nil client only logs alerts
+func NewPushService(repo *repositories.PushRepository, client *webpush.Client, runner *jobs.Runner) *PushService {
+	s := &PushService{repo: repo, client: client, runner: runner}
+	runner.Register(JobPushNotification, s.send)
+	runner.OnFailure(s.jobFailed)
+	return s
+}
+
+// VAPIDPublicKey returns the applicationServerKey browsers subscribe with
+func (s *PushService) VAPIDPublicKey() (string, error) {
+	if s.client == nil {
+		return "", ErrPushNotConfigured
+	}
+	return s.client.PublicKey, nil
+}
+
+func (s *PushService) Subscribe(sub models.PushSubscription) (models.PushSubscription, error) {
+	err := webpush.Subscription{Endpoint: sub.Endpoint, P256dh: sub.Keys.P256dh, Auth: sub.Keys.Auth}.Validate()
+	if err != nil {
+		return models.PushSubscription{}, fmt.Errorf("%w: %v", ErrInvalidSubscription, err)
+	}
+	return s.repo.Save(sub)
+}
+
+func (s *PushService) Unsubscribe(endpoint string) error {
+	return s.repo.DeleteByEndpoint(endpoint)
+}
+
+func (s *PushService) GetSubscriptions() ([]models.PushSubscription, error) {
+	return s.repo.GetAll()
+}
+
+// Alert pushes a critical alert to every subscribed browser, each delivery
+// retried on its own by the job runner
+func (s *PushService) Alert(kind, title, body string) error {
+	if len(body) > maxAlertBody {
+		body = body[:maxAlertBody-3] + "..."
+	}
+	alert := models.PushAlert{Kind: kind, Title: title, Body: body, At: time.Now().Format("2006-01-02 15:04:05")}
+	if s.client == nil {
+		log.Printf("[push] %s: %s", title, body)
+		return nil
+	}
+
+	subscriptions, err := s.repo.GetAll()
+	if err != nil {
+		return err
+	}
+	for _, sub := range subscriptions {
+		if _, err := s.runner.Enqueue(JobPushNotification, pushJob{SubscriptionID: sub.ID, Alert: alert}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send is the job handler of JobPushNotification
+func (s *PushService) send(ctx context.Context, job models.Job) error {
+	var pj pushJob
+	if err := json.Unmarshal(job.Payload, &pj); err != nil {
+		return err
+	}
+
+	sub, err := s.repo.GetByID(pj.SubscriptionID)
+	if err == sql.ErrNoRows {
+		// unsubscribed since the alert was raised
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(pj.Alert)
+	if err != nil {
+		return err
+	}
+
+	statusCode, err := s.client.Send(webpush.Subscription{Endpoint: sub.Endpoint, P256dh: sub.Keys.P256dh, Auth: sub.Keys.Auth}, payload, pushTTL)
+	if statusCode == http.StatusNotFound || statusCode == http.StatusGone {
+		// the browser unsubscribed or the subscription expired
+		if err := s.repo.DeleteByEndpoint(sub.Endpoint); err != nil && err != sql.ErrNoRows {
+			log.Println("Error deleting expired push subscription:", err)
+		}
+		return nil
+	}
+	return err
+}
+
+// jobFailed alerts about background jobs that ran out of attempts
+func (s *PushService) jobFailed(job models.Job, err error) {
+	// a failing push can't report itself
+	if job.Type == JobPushNotification {
+		return
+	}
+
+	body := fmt.Sprintf("%s failed after %d attempts: %v", job.Type, job.Attempts, err)
+	if alertErr := s.Alert(AlertJobFailed, "Background job failed", body); alertErr != nil {
+		log.Println("Error raising job failure alert:", alertErr)
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"kasir-api/repositories"
+	"kasir-api/sequence"
+)
+
+// ReceiptNumbering hands out human-readable receipt numbers such as INV/2024/06/000123
+type ReceiptNumbering struct {
+	repo   repositories.SequenceStore
+	format *sequence.Format
+	store  string
+}
+
+func NewReceiptNumbering(repo repositories.SequenceStore, format *sequence.Format, store string) (*ReceiptNumbering, error) {
+	if store != "" && !format.HasStore() {
+		return nil, fmt.Errorf("receipt number format must contain {STORE} when a store code is set")
+	}
+	return &ReceiptNumbering{repo: repo, format: format, store: store}, nil
+}
+
+// Next allocates the next number. It's taken outside the checkout transaction
+// so concurrent checkouts don't queue on the counter; a checkout that fails
+// afterwards leaves a gap instead of reusing the number.
+func (n *ReceiptNumbering) Next() (string, error) {
+	now := time.Now()
+	seq, err := n.repo.Next(n.scope(now))
+	if err != nil {
+		return "", err
+	}
+	return n.format.Render(n.store, now, seq), nil
+}
+
+// scope is the counter the numbers of the reset period around t are drawn from
+func (n *ReceiptNumbering) scope(t time.Time) string {
+	return n.format.Scope(n.store, t)
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+
+	"kasir-api/jobs"
+	"kasir-api/mailer"
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var ErrInvalidEmail = errors.New("email is not a valid address")
+
+const JobEmailReceipt = "email.receipt"
+
+type receiptJob struct {
+	DeliveryID int            `json:"delivery_id"`
+	Message    mailer.Message `json:"message"`
+}
+
+type ReceiptService struct {
+	transactionRepo *repositories.TransactionRepository
+	emailRepo       *repositories.EmailRepository
+	mailer          mailer.Mailer
+	runner          *jobs.Runner
+	templates       *ReceiptTemplateService
+}
+
+func NewReceiptService(transactionRepo *repositories.TransactionRepository, emailRepo *repositories.EmailRepository, m mailer.Mailer, runner *jobs.Runner, templates *ReceiptTemplateService) *ReceiptService {
+	s := &ReceiptService{transactionRepo: transactionRepo, emailRepo: emailRepo, mailer: m, runner: runner, templates: templates}
+	runner.Register(JobEmailReceipt, s.sendReceipt)
+	return s
+}
+
+// EmailReceipt renders the receipt of a transaction with the store's branding
+// and queues it for delivery.
+// The returned delivery is in "queued" status; its final status is tracked in
+// email_delivery once a worker has sent it.
+func (s *ReceiptService) EmailReceipt(transactionID int, email string) (models.EmailDelivery, error) {
+	if _, err := mail.ParseAddress(email); err != nil {
+		return models.EmailDelivery{}, ErrInvalidEmail
+	}
+
+	transaction, err := s.transactionRepo.GetByID(transactionID)
+	if err != nil {
+		return models.EmailDelivery{}, err
+	}
+
+	body, err := s.templates.Render(transaction)
+	if err != nil {
+		return models.EmailDelivery{}, err
+	}
+
+	delivery, err := s.emailRepo.CreateDelivery(models.EmailDelivery{
+		TransactionID: transactionID,
+		Recipient:     email,
+		Subject:       receiptSubject(transaction),
+	})
+	if err != nil {
+		return models.EmailDelivery{}, err
+	}
+
+	_, err = s.runner.Enqueue(JobEmailReceipt, receiptJob{
+		DeliveryID: delivery.ID,
+		Message: mailer.Message{
+			To:      delivery.Recipient,
+			Subject: delivery.Subject,
+			HTML:    body,
+		},
+	})
+	if err != nil {
+		s.recordResult(delivery.ID, "failed", 0, err)
+		return models.EmailDelivery{}, err
+	}
+
+	return delivery, nil
+}
+
+func (s *ReceiptService) GetDeliveries(transactionID int) ([]models.EmailDelivery, error) {
+	return s.emailRepo.GetByTransactionID(transactionID)
+}
+
+// sendReceipt is the job handler of JobEmailReceipt
+func (s *ReceiptService) sendReceipt(ctx context.Context, job models.Job) error {
+	var rj receiptJob
+	if err := json.Unmarshal(job.Payload, &rj); err != nil {
+		return err
+	}
+
+	err := s.mailer.Send(rj.Message)
+	switch {
+	case err == nil:
+		s.recordResult(rj.DeliveryID, "sent", job.Attempts, nil)
+	case job.Attempts >= job.MaxAttempts:
+		s.recordResult(rj.DeliveryID, "failed", job.Attempts, err)
+	default:
+		s.recordResult(rj.DeliveryID, "queued", job.Attempts, err)
+	}
+	return err
+}
+
+func (s *ReceiptService) recordResult(deliveryID int, status string, attempts int, err error) {
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+
+	if updateErr := s.emailRepo.UpdateStatus(deliveryID, status, attempts, errMsg); updateErr != nil {
+		log.Println("Error updating email delivery status:", updateErr)
+	}
+}
+
+func receiptSubject(transaction *models.Transaction) string {
+	if transaction.ReceiptNumber != "" {
+		return "Struk Pembelian " + transaction.ReceiptNumber
+	}
+	return fmt.Sprintf("Struk Pembelian #%d", transaction.ID)
+}
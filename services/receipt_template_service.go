@@ -0,0 +1,236 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+	"time"
+
+	"kasir-api/locale"
+	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/repositories"
+)
+
+var ErrInvalidLogoURL = errors.New("logo_url must be an http or https URL")
+
+// receiptTemplate is the built-in layout, executed on a clone carrying the
+// store's locale; the functions here only let it parse
+var receiptTemplate = template.Must(template.New("receipt").Funcs(receiptFuncs(locale.Locale{})).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif;">
+	{{with .Branding.LogoURL}}<p><img src="{{.}}" alt="" style="max-height: 80px;"></p>
+	{{end}}{{with .Branding.StoreName}}<p style="margin: 0;"><strong>{{.}}</strong></p>
+	{{end}}{{range lines .Branding.StoreAddress}}<p style="margin: 0;">{{.}}</p>
+	{{end}}{{with .Branding.NPWP}}<p style="margin: 0;">NPWP {{.}}</p>
+	{{end}}{{range lines .Branding.Header}}<p style="margin: 0;">{{.}}</p>
+	{{end}}<h2>Struk Pembelian {{if .ReceiptNumber}}{{.ReceiptNumber}}{{else}}#{{.ID}}{{end}}</h2>
+	<p>{{datetime .CreatedAt}}</p>
+	{{with .TableNumber}}<p>Meja {{.}}</p>
+	{{end}}{{with .QueueNumber}}<p>Antrian {{.}}</p>
+	{{end}}	<table cellpadding="4" style="border-collapse: collapse;">
+		<tr><th align="left">Produk</th><th align="right">Qty</th><th align="right">Subtotal</th></tr>
+		{{range .Details}}<tr><td>{{.ProductName}}</td><td align="right">{{.Quantity}}</td><td align="right">{{money .Subtotal}}</td></tr>
+		{{with .PricingRule}}<tr><td colspan="2">&nbsp;&nbsp;{{.Name}}</td><td align="right">{{money (neg .Discount)}}</td></tr>
+		{{end}}{{end}}{{with .RoundingAdjustment}}<tr><td colspan="2">Pembulatan</td><td align="right">{{money .}}</td></tr>
+		{{end}}<tr><td colspan="2"><strong>Total</strong></td><td align="right"><strong>{{money .TotalAmount}}</strong></td></tr>
+		{{with .Payment}}{{with .Voucher}}<tr><td colspan="2">Voucher {{.Code}}</td><td align="right">{{money .Amount}}</td></tr>
+		<tr><td colspan="2">&nbsp;&nbsp;Sisa saldo</td><td align="right">{{money .Balance}}</td></tr>
+		{{end}}{{if .Currency}}<tr><td colspan="2">Bayar</td><td align="right">{{moneyIn .Currency .Amount}}</td></tr>
+		<tr><td colspan="2">Kembali</td><td align="right">{{money .Change}}</td></tr>
+		{{end}}{{end}}
+	</table>
+	{{range lines .Branding.Footer}}<p style="margin: 0;">{{.}}</p>
+	{{else}}<p>Terima kasih telah berbelanja.</p>
+	{{end}}{{with .Branding.Promo}}<p><strong>{{.}}</strong></p>
+	{{end}}
+</body>
+</html>`))
+
+// localeFuncs exposes the amount and date formatting of l to templates
+func localeFuncs(l locale.Locale) template.FuncMap {
+	return template.FuncMap{
+		"money":    l.Money,
+		"moneyIn":  l.MoneyIn,
+		"number":   l.Number,
+		"date":     l.Date,
+		"datetime": l.DateTime,
+		"neg":      func(n money.Amount) money.Amount { return -n },
+	}
+}
+
+// receiptFuncs are the functions of receipt templates: those of the locale,
+// and lines to split header and footer text into its non-empty lines
+func receiptFuncs(l locale.Locale) template.FuncMap {
+	funcs := localeFuncs(l)
+	funcs["lines"] = func(s string) []string {
+		lines := []string{}
+		for _, line := range strings.Split(s, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		return lines
+	}
+	return funcs
+}
+
+// ReceiptBranding is what a receipt shows of the store: the version of the
+// receipt template, and the store's name, address and NPWP from its settings.
+// The footer of the settings stands in for a version without one.
+type ReceiptBranding struct {
+	LogoURL      string
+	Header       string
+	Footer       string
+	Promo        string
+	StoreName    string
+	StoreAddress string
+	NPWP         string
+}
+
+// receiptView is what receipt templates are executed with: the fields of the
+// transaction, and Branding
+type receiptView struct {
+	*models.Transaction
+	Branding ReceiptBranding
+}
+
+// ReceiptTemplateService renders receipts with the active version of the
+// store's branding. Saving a version makes it active; an older version can be
+// made active again, and a version can be previewed before it is saved.
+type ReceiptTemplateService struct {
+	repo         *repositories.ReceiptTemplateRepository
+	transactions *repositories.TransactionRepository
+	settings     repositories.SettingsReader
+	loc          locale.Locale
+}
+
+func NewReceiptTemplateService(repo *repositories.ReceiptTemplateRepository, transactions *repositories.TransactionRepository, settings repositories.SettingsReader, loc locale.Locale) *ReceiptTemplateService {
+	return &ReceiptTemplateService{repo: repo, transactions: transactions, settings: settings, loc: loc}
+}
+
+func (s *ReceiptTemplateService) GetAll() ([]models.ReceiptTemplate, error) {
+	return s.repo.GetAll()
+}
+
+// Save checks req renders and saves it as the active version
+func (s *ReceiptTemplateService) Save(req models.ReceiptTemplateRequest) (models.ReceiptTemplate, error) {
+	req, err := s.check(req)
+	if err != nil {
+		return models.ReceiptTemplate{}, err
+	}
+	return s.repo.Create(req)
+}
+
+// Activate makes version id the active one again
+func (s *ReceiptTemplateService) Activate(id int) (models.ReceiptTemplate, error) {
+	return s.repo.Activate(id)
+}
+
+// Preview renders the receipt of transactionID, or of a sample sale when it
+// is 0, with req as if it had been saved
+func (s *ReceiptTemplateService) Preview(req models.ReceiptPreviewRequest) (string, error) {
+	draft, err := s.check(req.Template)
+	if err != nil {
+		return "", err
+	}
+
+	transaction := s.sampleReceipt()
+	if req.TransactionID != 0 {
+		transaction, err = s.transactions.GetByID(req.TransactionID)
+		if err != nil {
+			return "", err
+		}
+	}
+	return s.render(draft.Body, s.brandingOf(draft), transaction)
+}
+
+// Render renders the receipt of transaction with the active version, the
+// built-in layout without branding before any is saved
+func (s *ReceiptTemplateService) Render(transaction *models.Transaction) (string, error) {
+	active, err := s.repo.GetActive()
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	branding := s.brandingOf(models.ReceiptTemplateRequest{LogoURL: active.LogoURL, Header: active.Header, Footer: active.Footer, Promo: active.Promo})
+	return s.render(active.Body, branding, transaction)
+}
+
+// check trims req and makes sure its logo is a web address and its body
+// renders a sample sale
+func (s *ReceiptTemplateService) check(req models.ReceiptTemplateRequest) (models.ReceiptTemplateRequest, error) {
+	req.LogoURL = strings.TrimSpace(req.LogoURL)
+	req.Header = strings.TrimSpace(req.Header)
+	req.Footer = strings.TrimSpace(req.Footer)
+	req.Promo = strings.TrimSpace(req.Promo)
+	req.Note = strings.TrimSpace(req.Note)
+
+	if req.LogoURL != "" {
+		u, err := url.Parse(req.LogoURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return req, ErrInvalidLogoURL
+		}
+	}
+	if _, err := s.render(req.Body, s.brandingOf(req), s.sampleReceipt()); err != nil {
+		return req, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return req, nil
+}
+
+// render executes body, or the built-in layout when it is empty
+func (s *ReceiptTemplateService) render(body string, branding ReceiptBranding, transaction *models.Transaction) (string, error) {
+	tmpl := template.Must(receiptTemplate.Clone()).Funcs(receiptFuncs(s.loc))
+	if body != "" {
+		var err error
+		tmpl, err = template.New("receipt").Funcs(receiptFuncs(s.loc)).Parse(body)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, receiptView{Transaction: transaction, Branding: branding}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// brandingOf is the branding of receipts with req, and the settings in force
+func (s *ReceiptTemplateService) brandingOf(req models.ReceiptTemplateRequest) ReceiptBranding {
+	settings := s.settings.Settings()
+	branding := ReceiptBranding{
+		LogoURL:      req.LogoURL,
+		Header:       req.Header,
+		Footer:       req.Footer,
+		Promo:        req.Promo,
+		StoreName:    settings.Name,
+		StoreAddress: settings.Address,
+	}
+	if settings.NPWP != "" {
+		branding.NPWP = FormatNPWP(settings.NPWP)
+	}
+	if branding.Footer == "" {
+		branding.Footer = settings.ReceiptFooter
+	}
+	return branding
+}
+
+// sampleReceipt is the sale previews and checks render when no real one is given
+func (s *ReceiptTemplateService) sampleReceipt() *models.Transaction {
+	return &models.Transaction{
+		ID:            1,
+		ReceiptNumber: "INV/2026/01/000001",
+		Status:        repositories.TransactionCompleted,
+		TotalAmount:   27000,
+		CreatedAt:     time.Now().Format("2006-01-02 15:04:05"),
+		Details: []models.TransactionDetail{
+			{ID: 1, TransactionID: 1, ProductID: 1, ProductName: "Kopi Susu", Quantity: 2, Subtotal: 18000},
+			{ID: 2, TransactionID: 1, ProductID: 2, ProductName: "Roti Bakar", Quantity: 1, Subtotal: 9000},
+		},
+		Payment: &models.TransactionPayment{Currency: s.loc.Currency.Code, Amount: 30000, Rate: 1, BaseAmount: 30000, Change: 3000},
+	}
+}
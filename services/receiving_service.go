@@ -0,0 +1,102 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var (
+	ErrPurchaseOrderNotOpen = errors.New("purchase order is already closed")
+	ErrSessionAlreadyOpen   = errors.New("purchase order already has an open receiving session")
+	ErrInvalidExpiryDate    = errors.New("expires_on must be in YYYY-MM-DD format")
+)
+
+type ReceivingService struct {
+	repo   *repositories.ReceivingRepository
+	poRepo *repositories.PurchaseOrderRepository
+}
+
+func NewReceivingService(repo *repositories.ReceivingRepository, poRepo *repositories.PurchaseOrderRepository) *ReceivingService {
+	return &ReceivingService{repo: repo, poRepo: poRepo}
+}
+
+// Open starts a receiving session against an open or partially received purchase order
+func (s *ReceivingService) Open(purchaseOrderID int) (*models.ReceivingSession, error) {
+	po, err := s.poRepo.GetByID(purchaseOrderID)
+	if err != nil {
+		return nil, err
+	}
+	if !isOpenPurchaseOrder(po.Status) {
+		return nil, ErrPurchaseOrderNotOpen
+	}
+
+	if _, err := s.repo.GetOpenSessionID(purchaseOrderID); err != sql.ErrNoRows {
+		if err == nil {
+			return nil, ErrSessionAlreadyOpen
+		}
+		return nil, err
+	}
+
+	session, err := s.repo.Open(purchaseOrderID)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetByID(session.ID)
+}
+
+// Scan records one scan (or quantity units) of a barcode and returns the updated session
+func (s *ReceivingService) Scan(sessionID int, req models.ScanRequest) (*models.ReceivingSession, error) {
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity < 0 {
+		return nil, ErrInvalidAmount
+	}
+	if req.ExpiresOn != "" {
+		if _, err := time.Parse("2006-01-02", req.ExpiresOn); err != nil {
+			return nil, ErrInvalidExpiryDate
+		}
+	}
+
+	if err := s.repo.Scan(sessionID, req.Barcode, req.Quantity, req.ExpiresOn); err != nil {
+		return nil, err
+	}
+	return s.GetByID(sessionID)
+}
+
+// GetByID returns a session with overage/shortage flags per line
+func (s *ReceivingService) GetByID(id int) (*models.ReceivingSession, error) {
+	session, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range session.Lines {
+		line := &session.Lines[i]
+		line.Variance = line.Scanned - line.Expected
+		switch {
+		case line.Variance > 0:
+			line.Flag = "overage"
+		case line.Variance < 0:
+			line.Flag = "shortage"
+		default:
+			line.Flag = "match"
+		}
+		if line.Variance != 0 {
+			session.HasDiscrepancy = true
+		}
+	}
+	return session, nil
+}
+
+// Commit books the scanned quantities into stock and the purchase order
+func (s *ReceivingService) Commit(id int) (*models.ReceivingSession, error) {
+	if err := s.repo.Commit(id); err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var ErrInvalidReliabilityDays = errors.New("days must be between 1 and 90")
+
+// maxReliabilityDays is the longest trend the reliability report covers
+const maxReliabilityDays = 90
+
+// httpCounts are the responses of one day not yet written to the database
+type httpCounts struct {
+	requests     int64
+	serverErrors int64
+}
+
+// ReliabilityService gathers the install's failures into one error budget
+// report. API responses are counted in memory and written out every flush
+// interval, so counting doesn't cost a query per request.
+type ReliabilityService struct {
+	repo        *repositories.ReliabilityRepository
+	errorBudget float64
+
+	mu      sync.Mutex
+	pending map[string]*httpCounts
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewReliabilityService reports against errorBudget, the share of API
+// requests allowed to fail with a 5xx, e.g. 0.01 for 1%
+func NewReliabilityService(repo *repositories.ReliabilityRepository, errorBudget float64) *ReliabilityService {
+	return &ReliabilityService{
+		repo:        repo,
+		errorBudget: errorBudget,
+		pending:     map[string]*httpCounts{},
+		stop:        make(chan struct{}),
+	}
+}
+
+// CountResponse records an API response with the given status code
+func (s *ReliabilityService) CountResponse(status int) {
+	day := time.Now().Format("2006-01-02")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts, ok := s.pending[day]
+	if !ok {
+		counts = &httpCounts{}
+		s.pending[day] = counts
+	}
+	counts.requests++
+	if status >= 500 {
+		counts.serverErrors++
+	}
+}
+
+// Start writes the counted responses out every interval in the background
+func (s *ReliabilityService) Start(interval time.Duration) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-time.After(interval):
+			}
+			if err := s.Flush(); err != nil {
+				log.Println("Error saving HTTP stats, retrying later:", err)
+			}
+		}
+	}()
+}
+
+// Stop ends the background loop and writes out what is still pending
+func (s *ReliabilityService) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+	if err := s.Flush(); err != nil {
+		log.Println("Error saving HTTP stats:", err)
+	}
+}
+
+// Flush writes the counted responses to the database. Counts that fail to
+// save are kept for the next flush.
+func (s *ReliabilityService) Flush() error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = map[string]*httpCounts{}
+	s.mu.Unlock()
+
+	var firstErr error
+	for day, counts := range pending {
+		err := s.repo.AddHTTPStats(day, counts.requests, counts.serverErrors)
+		if err == nil {
+			continue
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+
+		s.mu.Lock()
+		kept, ok := s.pending[day]
+		if !ok {
+			kept = &httpCounts{}
+			s.pending[day] = kept
+		}
+		kept.requests += counts.requests
+		kept.serverErrors += counts.serverErrors
+		s.mu.Unlock()
+	}
+	return firstErr
+}
+
+// Report returns the failures of the last days days with the totals over the
+// period. The install is healthy while server errors stay within the budget
+// and nothing failed today.
+func (s *ReliabilityService) Report(days int) (models.ReliabilityReport, error) {
+	if days < 1 || days > maxReliabilityDays {
+		return models.ReliabilityReport{}, ErrInvalidReliabilityDays
+	}
+
+	// today's responses are part of the report even before the next flush
+	if err := s.Flush(); err != nil {
+		return models.ReliabilityReport{}, err
+	}
+
+	trend, err := s.repo.GetDaily(days, JobWebhookDelivery)
+	if err != nil {
+		return models.ReliabilityReport{}, err
+	}
+
+	report := models.ReliabilityReport{Days: days, ErrorBudget: s.errorBudget, Trend: trend}
+	for i := range trend {
+		d := &trend[i]
+		d.ServerErrorRate = errorRate(d.ServerErrors, d.Requests)
+
+		report.Totals.WebhookFailures += d.WebhookFailures
+		report.Totals.JobFailures += d.JobFailures
+		report.Totals.GatewayErrors += d.GatewayErrors
+		report.Totals.Requests += d.Requests
+		report.Totals.ServerErrors += d.ServerErrors
+	}
+	report.Totals.ServerErrorRate = errorRate(report.Totals.ServerErrors, report.Totals.Requests)
+
+	if s.errorBudget > 0 {
+		report.BudgetUsed = report.Totals.ServerErrorRate / s.errorBudget
+	}
+
+	report.Healthy = report.BudgetUsed <= 1
+	if len(trend) > 0 {
+		today := trend[len(trend)-1]
+		if today.WebhookFailures > 0 || today.JobFailures > 0 || today.GatewayErrors > 0 {
+			report.Healthy = false
+		}
+	}
+	return report, nil
+}
+
+func errorRate(failed, requests int64) float64 {
+	if requests == 0 {
+		return 0
+	}
+	return float64(failed) / float64(requests)
+}
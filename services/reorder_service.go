@@ -0,0 +1,34 @@
+package services
+
+import (
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+type ReorderService struct {
+	repo *repositories.ReorderRepository
+}
+
+func NewReorderService(repo *repositories.ReorderRepository) *ReorderService {
+	return &ReorderService{repo: repo}
+}
+
+// GetSuggestions lists products to reorder. When a product has no reorder_qty,
+// the suggestion refills stock plus quantity on order up to twice its reorder point.
+func (s *ReorderService) GetSuggestions() ([]models.ReorderSuggestion, error) {
+	suggestions, err := s.repo.GetSuggestions()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range suggestions {
+		sg := &suggestions[i]
+		if sg.SuggestedQty <= 0 {
+			sg.SuggestedQty = sg.ReorderPoint*2 - sg.Stock - sg.OnOrder
+		}
+		if sg.CheapestSupplier != nil {
+			sg.EstimatedCost = sg.CheapestSupplier.Price.Times(sg.SuggestedQty)
+		}
+	}
+	return suggestions, nil
+}
@@ -1,22 +1,329 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"time"
+
 	"kasir-api/models"
+	"kasir-api/money"
 	"kasir-api/repositories"
 )
 
+const JobReportAggregation = "report.aggregate_daily"
+
+var (
+	ErrInvalidAggregationDate = errors.New("date must be a past day in YYYY-MM-DD format")
+	ErrInvalidTimezone        = errors.New("tz must be an IANA time zone, e.g. Asia/Jakarta")
+	ErrInvalidDateRange       = errors.New("start_date and end_date must be dates in YYYY-MM-DD format, start_date not after end_date")
+	ErrInvalidComparePeriod   = errors.New("period must be month or year")
+	ErrInvalidComparison      = errors.New("current and previous must be months in YYYY-MM or years in YYYY format, as period")
+)
+
+// comparisonTopProducts is the number of best sellers a comparison report
+// sets side by side
+const comparisonTopProducts = 10
+
+// comparePeriods are the layouts of the periods a comparison report takes,
+// with the length of one
+var comparePeriods = map[string]struct {
+	layout        string
+	years, months int
+}{
+	"month": {"2006-01", 0, 1},
+	"year":  {"2006", 1, 0},
+}
+
 type ReportService struct {
-	repo *repositories.ReportRepository
+	repo     *repositories.ReportRepository
+	settings repositories.SettingsReader
+}
+
+// NewReportService reports the PPN prices include at the tax rate of settings
+func NewReportService(repo *repositories.ReportRepository, settings repositories.SettingsReader) *ReportService {
+	return &ReportService{repo: repo, settings: settings}
+}
+
+// location returns the time zone tz, the store time zone when empty, or
+// ErrInvalidTimezone unless it is an IANA time zone
+func (s *ReportService) location(tz string) (*time.Location, error) {
+	if tz == "" {
+		tz = s.repo.Timezone()
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil || tz == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
+
+// dateRange returns the range [start, end) from the start of startDate to
+// the end of endDate, days of time zone tz, and the time zone
+func (s *ReportService) dateRange(startDate, endDate, tz string) (time.Time, time.Time, *time.Location, error) {
+	loc, err := s.location(tz)
+	if err != nil {
+		return time.Time{}, time.Time{}, nil, err
+	}
+	start, err := time.ParseInLocation("2006-01-02", startDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, nil, ErrInvalidDateRange
+	}
+	end, err := time.ParseInLocation("2006-01-02", endDate, loc)
+	if err != nil || end.Before(start) {
+		return time.Time{}, time.Time{}, nil, ErrInvalidDateRange
+	}
+	return start, end.AddDate(0, 0, 1), loc, nil
+}
+
+// The reports below are of the days from startDate to endDate, inclusive, in
+// time zone tz, the store time zone when empty
+
+func (s *ReportService) GetDailySalesReport(tz string) (*models.SalesReport, error) {
+	loc, err := s.location(tz)
+	if err != nil {
+		return nil, err
+	}
+	today := time.Now().In(loc).Format("2006-01-02")
+	return s.GetSalesReportByDateRange(today, today, tz)
+}
+
+func (s *ReportService) GetSalesReportByDateRange(startDate, endDate, tz string) (*models.SalesReport, error) {
+	start, end, loc, err := s.dateRange(startDate, endDate, tz)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetSalesReportByDateRange(start, end, loc.String())
+}
+
+func (s *ReportService) GetPriceContractSalesReport(startDate, endDate, tz string) (*models.PriceContractSalesReport, error) {
+	start, end, _, err := s.dateRange(startDate, endDate, tz)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetPriceContractSalesReport(start, end)
+}
+
+// GetProfitReport computes gross profit and margin per product, per day and for the whole period
+func (s *ReportService) GetProfitReport(startDate, endDate, tz string) (*models.ProfitReport, error) {
+	start, end, loc, err := s.dateRange(startDate, endDate, tz)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.repo.GetProfitReport(start, end, loc.String())
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range report.Products {
+		p := &report.Products[i]
+		p.GrossProfit = p.Revenue - p.Cost
+		p.Margin = margin(p.GrossProfit, p.Revenue)
+	}
+
+	for i := range report.Periods {
+		p := &report.Periods[i]
+		p.GrossProfit = p.Revenue - p.Cost
+		p.Margin = margin(p.GrossProfit, p.Revenue)
+
+		report.TotalRevenue += p.Revenue
+		report.TotalCost += p.Cost
+	}
+
+	report.GrossProfit = report.TotalRevenue - report.TotalCost
+	report.Margin = margin(report.GrossProfit, report.TotalRevenue)
+
+	return report, nil
+}
+
+// GetComparisonReport sets the revenue, transactions and best sellers of the
+// month or year current against previous, the one before current when empty
+func (s *ReportService) GetComparisonReport(period, current, previous, tz string) (*models.ComparisonReport, error) {
+	p, ok := comparePeriods[period]
+	if !ok {
+		return nil, ErrInvalidComparePeriod
+	}
+	loc, err := s.location(tz)
+	if err != nil {
+		return nil, err
+	}
+	currentStart, err := time.ParseInLocation(p.layout, current, loc)
+	if err != nil {
+		return nil, ErrInvalidComparison
+	}
+	previousStart := currentStart.AddDate(-p.years, -p.months, 0)
+	if previous != "" {
+		if previousStart, err = time.ParseInLocation(p.layout, previous, loc); err != nil {
+			return nil, ErrInvalidComparison
+		}
+	}
+
+	report := &models.ComparisonReport{
+		Period:   period,
+		Current:  models.PeriodSales{Period: currentStart.Format(p.layout)},
+		Previous: models.PeriodSales{Period: previousStart.Format(p.layout)},
+	}
+	var previousProducts []models.ComparedProduct
+	for _, side := range []struct {
+		start    time.Time
+		sales    *models.PeriodSales
+		products *[]models.ComparedProduct
+	}{
+		{currentStart, &report.Current, &report.TopProducts},
+		{previousStart, &report.Previous, &previousProducts},
+	} {
+		end := side.start.AddDate(p.years, p.months, 0)
+		sales, err := s.repo.GetSalesReportByDateRange(side.start, end, loc.String())
+		if err != nil {
+			return nil, err
+		}
+		side.sales.Revenue = sales.TotalRevenue
+		side.sales.Transactions = sales.TotalTransaksi
+
+		if *side.products, err = s.repo.GetProductSales(side.start, end, loc.String()); err != nil {
+			return nil, err
+		}
+	}
+	report.RevenueGrowth = growth(report.Current.Revenue-report.Previous.Revenue, report.Previous.Revenue)
+	report.TransactionGrowth = growth(report.Current.Transactions-report.Previous.Transactions, report.Previous.Transactions)
+
+	previousSales := make(map[int]models.ComparedProduct, len(previousProducts))
+	for _, pp := range previousProducts {
+		previousSales[pp.ProductID] = pp
+	}
+	if len(report.TopProducts) > comparisonTopProducts {
+		report.TopProducts = report.TopProducts[:comparisonTopProducts]
+	}
+	for i := range report.TopProducts {
+		cp := &report.TopProducts[i]
+		cp.PreviousQty = previousSales[cp.ProductID].QtyTerjual
+		cp.PreviousRevenue = previousSales[cp.ProductID].Revenue
+		cp.RevenueGrowth = growth(cp.Revenue-cp.PreviousRevenue, cp.PreviousRevenue)
+	}
+
+	return report, nil
+}
+
+// GetProfitLossReport sums up the sales of the period down to net profit;
+// the PPN collected is taken out of the net sales at the tax rate
+func (s *ReportService) GetProfitLossReport(startDate, endDate, tz string) (*models.ProfitLossReport, error) {
+	start, end, loc, err := s.dateRange(startDate, endDate, tz)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.repo.GetProfitLossReport(start, end, loc.String())
+	if err != nil {
+		return nil, err
+	}
+
+	report.NetSales = report.GrossSales - report.Discounts + report.RoundingAdjustment - report.Refunds
+	report.Revenue = taxBase(report.NetSales, s.settings.Settings().TaxRate)
+	report.TaxCollected = report.NetSales - report.Revenue
+	report.GrossProfit = report.Revenue - report.COGS
+	report.GrossMargin = margin(report.GrossProfit, report.Revenue)
+	report.NetProfit = report.GrossProfit - report.Expenses
+	report.NetMargin = margin(report.NetProfit, report.Revenue)
+
+	return report, nil
+}
+
+// shrinkageReasons are the stock ledger reasons counted as stock lost
+var shrinkageReasons = []string{
+	repositories.MovementDamaged,
+	repositories.MovementExpired,
+	repositories.MovementSupplierReturn,
+	repositories.MovementCycleCount,
+	repositories.MovementOpname,
+}
+
+// GetShrinkageReport totals stock lost per reason and product; the shrinkage
+// rate is the lost value as a percentage of the cost of goods sold
+func (s *ReportService) GetShrinkageReport(startDate, endDate, tz string) (*models.ShrinkageReport, error) {
+	start, end, loc, err := s.dateRange(startDate, endDate, tz)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.repo.GetShrinkageReport(start, end, loc.String(), shrinkageReasons)
+	if err != nil {
+		return nil, err
+	}
+
+	report.SupplierReturns = models.ShrinkageByReason{Reason: repositories.MovementSupplierReturn}
+	reasons := report.Reasons[:0]
+	for _, r := range report.Reasons {
+		if r.Reason == repositories.MovementSupplierReturn {
+			report.SupplierReturns = r
+			continue
+		}
+		report.TotalQuantity += r.Quantity
+		report.TotalValue += r.Value
+		reasons = append(reasons, r)
+	}
+	report.Reasons = reasons
+	report.ShrinkageRate = margin(report.TotalValue, report.SalesCost)
+
+	return report, nil
+}
+
+func (s *ReportService) GetReceivablesAging() (*models.ReceivablesAging, error) {
+	return s.repo.GetReceivablesAging()
+}
+
+// AggregatePending rolls up every past day that has no daily summary yet and
+// returns the number of days aggregated
+func (s *ReportService) AggregatePending() (int, error) {
+	days, err := s.repo.GetPendingAggregationDays()
+	if err != nil {
+		return 0, err
+	}
+
+	for i, day := range days {
+		if err := s.repo.AggregateDay(day); err != nil {
+			return i, err
+		}
+	}
+	return len(days), nil
+}
+
+// AggregateDay rebuilds the summary of a past day, e.g. after a transaction of
+// that day was deleted
+func (s *ReportService) AggregateDay(date string) error {
+	if _, err := time.Parse("2006-01-02", date); err != nil || date >= today() {
+		return ErrInvalidAggregationDate
+	}
+	return s.repo.AggregateDay(date)
+}
+
+// AggregateDailyJob is the job handler of JobReportAggregation, run nightly
+func (s *ReportService) AggregateDailyJob(ctx context.Context, job models.Job) error {
+	aggregated, err := s.AggregatePending()
+	if aggregated > 0 {
+		log.Printf("Aggregated daily reports for %d days", aggregated)
+	}
+	return err
 }
 
-func NewReportService(repo *repositories.ReportRepository) *ReportService {
-	return &ReportService{repo: repo}
+// quantity is what shares and growth are worked out on: counts, and sums of
+// money
+type quantity interface {
+	int | money.Amount
 }
 
-func (s *ReportService) GetDailySalesReport() (*models.SalesReport, error) {
-	return s.repo.GetDailySalesReport()
+// margin returns profit as a percentage of revenue, rounded to two decimals
+func margin[N quantity](profit, revenue N) float64 {
+	if revenue == 0 {
+		return 0
+	}
+	return math.Round(float64(profit)/float64(revenue)*10000) / 100
 }
 
-func (s *ReportService) GetSalesReportByDateRange(startDate, endDate string) (*models.SalesReport, error) {
-	return s.repo.GetSalesReportByDateRange(startDate, endDate)
+// growth returns change as a percentage of previous, rounded to two
+// decimals; nil when previous is 0, which nothing grows from
+func growth[N quantity](change, previous N) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	g := math.Round(float64(change)/float64(previous)*10000) / 100
+	return &g
 }
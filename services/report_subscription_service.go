@@ -0,0 +1,269 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/mail"
+	"time"
+
+	"kasir-api/jobs"
+	"kasir-api/locale"
+	"kasir-api/mailer"
+	"kasir-api/models"
+	"kasir-api/pdf"
+	"kasir-api/repositories"
+)
+
+const (
+	// JobReportEmailDigest queues the summaries due to every subscription
+	JobReportEmailDigest = "report.email_digest"
+	JobReportEmail       = "email.report"
+)
+
+// Report subscription frequencies
+const (
+	ReportDaily  = "daily"
+	ReportWeekly = "weekly"
+)
+
+var (
+	ErrInvalidReportFrequency = errors.New("frequency must be daily or weekly")
+	ErrInvalidReportFormat    = errors.New("format must be html or pdf")
+)
+
+type reportEmailJob struct {
+	Message mailer.Message `json:"message"`
+}
+
+// reportEmailTemplate is the summary sent to subscribers, executed on a clone
+// carrying the store's locale; the functions here only let it parse
+var reportEmailTemplate = template.Must(template.New("report").Funcs(localeFuncs(locale.Locale{})).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif;">
+	<h2>Ringkasan Penjualan</h2>
+	<p>{{.Period}}</p>
+	<table cellpadding="4" style="border-collapse: collapse;">
+		<tr><td>Pendapatan</td><td align="right"><strong>{{money .Sales.TotalRevenue}}</strong></td></tr>
+		<tr><td>Transaksi</td><td align="right">{{number .Sales.TotalTransaksi}}</td></tr>
+		<tr><td>Laba kotor</td><td align="right">{{money .Profit.GrossProfit}} ({{.Profit.Margin}}%)</td></tr>
+		{{with .Sales.RoundingAdjustment}}<tr><td>Pembulatan</td><td align="right">{{money .}}</td></tr>
+		{{end}}{{with .Sales.ProdukTerlaris}}<tr><td>Produk terlaris</td><td align="right">{{.Nama}} ({{number .QtyTerjual}})</td></tr>
+		{{end}}
+	</table>
+	{{with .Sales.Payments}}<h3>Pembayaran</h3>
+	<table cellpadding="4" style="border-collapse: collapse;">
+		{{range .}}<tr><td>{{.Currency}}</td><td align="right">{{number .Transactions}} transaksi</td><td align="right">{{money .BaseAmount}}</td></tr>
+		{{end}}
+	</table>
+	{{end}}
+</body>
+</html>`))
+
+type reportEmailView struct {
+	Period string
+	Sales  *models.SalesReport
+	Profit *models.ProfitReport
+}
+
+// ReportSubscriptionService emails the sales summary to the managers
+// subscribed to it: daily of yesterday, weekly of last Monday to Sunday
+type ReportSubscriptionService struct {
+	repo    *repositories.ReportSubscriptionRepository
+	reports *ReportService
+	mailer  mailer.Mailer
+	runner  *jobs.Runner
+	locale  locale.Locale
+}
+
+func NewReportSubscriptionService(repo *repositories.ReportSubscriptionRepository, reports *ReportService, m mailer.Mailer, runner *jobs.Runner, loc locale.Locale) *ReportSubscriptionService {
+	s := &ReportSubscriptionService{repo: repo, reports: reports, mailer: m, runner: runner, locale: loc}
+	runner.Register(JobReportEmailDigest, s.queueDue)
+	runner.Register(JobReportEmail, s.send)
+	return s
+}
+
+func (s *ReportSubscriptionService) Subscribe(sub models.ReportSubscription) (models.ReportSubscription, error) {
+	if _, err := mail.ParseAddress(sub.Email); err != nil {
+		return models.ReportSubscription{}, ErrInvalidEmail
+	}
+	if sub.Frequency != ReportDaily && sub.Frequency != ReportWeekly {
+		return models.ReportSubscription{}, ErrInvalidReportFrequency
+	}
+	if sub.Format == "" {
+		sub.Format = "html"
+	}
+	if sub.Format != "html" && sub.Format != "pdf" {
+		return models.ReportSubscription{}, ErrInvalidReportFormat
+	}
+	return s.repo.Save(sub)
+}
+
+func (s *ReportSubscriptionService) Unsubscribe(id int) error {
+	return s.repo.Delete(id)
+}
+
+func (s *ReportSubscriptionService) GetAll() ([]models.ReportSubscription, error) {
+	return s.repo.GetAll()
+}
+
+// reportPeriod returns the first and last day of the last full period of
+// frequency before today
+func reportPeriod(frequency string, today time.Time) (time.Time, time.Time) {
+	if frequency == ReportWeekly {
+		// days since Monday, Sunday being the 6th
+		sinceMonday := (int(today.Weekday()) + 6) % 7
+		end := today.AddDate(0, 0, -sinceMonday-1)
+		return end.AddDate(0, 0, -6), end
+	}
+	yesterday := today.AddDate(0, 0, -1)
+	return yesterday, yesterday
+}
+
+// queueDue is the job handler of JobReportEmailDigest: it queues an email to
+// every subscription whose last period, in the store time zone, wasn't sent
+// yet, so a digest missed while the server was down goes out on the next run
+func (s *ReportSubscriptionService) queueDue(ctx context.Context, job models.Job) error {
+	loc, err := s.reports.location("")
+	if err != nil {
+		return err
+	}
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	subscriptions, err := s.repo.GetAll()
+	if err != nil {
+		return err
+	}
+
+	// every subscriber of a frequency gets the same summary, rendered once
+	messages := map[string]mailer.Message{}
+	for _, sub := range subscriptions {
+		start, end := reportPeriod(sub.Frequency, today)
+		periodEnd := end.Format("2006-01-02")
+		if sub.LastSentOn >= periodEnd {
+			continue
+		}
+
+		key := sub.Frequency + "/" + sub.Format
+		msg, ok := messages[key]
+		if !ok {
+			if msg, err = s.render(start, end, sub.Format == "pdf"); err != nil {
+				return err
+			}
+			messages[key] = msg
+		}
+
+		due, err := s.repo.MarkSent(sub.ID, periodEnd)
+		if err != nil {
+			return err
+		}
+		if !due {
+			// another run sent it meanwhile
+			continue
+		}
+		msg.To = sub.Email
+		if _, err := s.runner.Enqueue(JobReportEmail, reportEmailJob{Message: msg}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// render writes the summary of the days from start to end as an email,
+// with it attached as PDF too when withPDF
+func (s *ReportSubscriptionService) render(start, end time.Time, withPDF bool) (mailer.Message, error) {
+	startDate, endDate := start.Format("2006-01-02"), end.Format("2006-01-02")
+	sales, err := s.reports.GetSalesReportByDateRange(startDate, endDate, "")
+	if err != nil {
+		return mailer.Message{}, err
+	}
+	profit, err := s.reports.GetProfitReport(startDate, endDate, "")
+	if err != nil {
+		return mailer.Message{}, err
+	}
+
+	view := reportEmailView{Period: s.locale.Date(startDate), Sales: sales, Profit: profit}
+	if endDate != startDate {
+		view.Period += " - " + s.locale.Date(endDate)
+	}
+
+	var buf bytes.Buffer
+	tmpl := template.Must(reportEmailTemplate.Clone()).Funcs(localeFuncs(s.locale))
+	if err := tmpl.Execute(&buf, view); err != nil {
+		return mailer.Message{}, err
+	}
+	msg := mailer.Message{Subject: "Ringkasan Penjualan " + view.Period, HTML: buf.String()}
+
+	if withPDF {
+		var doc bytes.Buffer
+		if _, err := s.reportPDF(view).WriteTo(&doc); err != nil {
+			return mailer.Message{}, err
+		}
+		msg.Attachments = []mailer.Attachment{{
+			Name:        fmt.Sprintf("ringkasan-penjualan-%s-%s.pdf", startDate, endDate),
+			ContentType: "application/pdf",
+			Content:     doc.Bytes(),
+		}}
+	}
+	return msg, nil
+}
+
+func (s *ReportSubscriptionService) reportPDF(view reportEmailView) *pdf.Document {
+	const (
+		left = 40.0
+		size = 10.0
+		lead = 15.0
+	)
+	right := pdf.PageWidth - left
+	doc := pdf.New()
+	y := 50.0
+	row := func(label string, bold bool, value string) {
+		doc.Text(left, y, size, bold, label)
+		doc.TextRight(right, y, size, bold, value)
+		y += lead
+	}
+	rule := func() {
+		doc.Line(left, y-lead/2, right, y-lead/2)
+		y += lead / 2
+	}
+
+	doc.Text(left, y, 14, true, "RINGKASAN PENJUALAN")
+	y += lead
+	doc.Text(left, y, size, false, view.Period)
+	y += lead
+	rule()
+
+	row("Pendapatan", true, s.locale.Money(view.Sales.TotalRevenue))
+	row("Transaksi", false, s.locale.Number(view.Sales.TotalTransaksi))
+	row("Laba kotor", false, fmt.Sprintf("%s (%.2f%%)", s.locale.Money(view.Profit.GrossProfit), view.Profit.Margin))
+	if view.Sales.RoundingAdjustment != 0 {
+		row("Pembulatan", false, s.locale.Money(view.Sales.RoundingAdjustment))
+	}
+	if p := view.Sales.ProdukTerlaris; p != nil {
+		row("Produk terlaris", false, fmt.Sprintf("%s (%s)", p.Nama, s.locale.Number(p.QtyTerjual)))
+	}
+
+	if len(view.Sales.Payments) > 0 {
+		rule()
+		doc.Text(left, y, size, true, "Pembayaran")
+		y += lead
+		for _, p := range view.Sales.Payments {
+			row(fmt.Sprintf("%s - %s transaksi", p.Currency, s.locale.Number(p.Transactions)), false, s.locale.Money(p.BaseAmount))
+		}
+	}
+	return doc
+}
+
+// send is the job handler of JobReportEmail; failed sends are retried by
+// the job runner
+func (s *ReportSubscriptionService) send(ctx context.Context, job models.Job) error {
+	var rj reportEmailJob
+	if err := json.Unmarshal(job.Payload, &rj); err != nil {
+		return err
+	}
+	return s.mailer.Send(rj.Message)
+}
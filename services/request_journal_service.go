@@ -0,0 +1,181 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var ErrReplayNotConfigured = errors.New("replay is not configured, set REQUEST_JOURNAL_REPLAY_URL to a staging instance")
+
+// journalHeaders are the only request headers kept, and sent again on replay
+var journalHeaders = []string{"Content-Type", "Accept", "User-Agent", "X-Terminal-Id", "X-Request-Id"}
+
+// redactedFields are JSON fields holding personal data or credentials
+var redactedFields = map[string]bool{
+	"name": true, "nama": true, "customer_name": true, "email": true, "phone": true,
+	"address": true, "alamat": true, "note": true, "password": true, "token": true,
+	"secret": true, "endpoint": true, "p256dh": true, "auth": true,
+}
+
+var emailPattern = regexp.MustCompile(`[^\s@"]+@[^\s@"]+\.[^\s@"]+`)
+
+const redacted = "[redacted]"
+
+type RequestJournalService struct {
+	repo      *repositories.RequestJournalRepository
+	limit     int
+	replayURL string
+	client    *http.Client
+}
+
+// NewRequestJournalService keeps the last limit entries; replayURL is the
+// base URL of the staging instance requests are replayed against, if any
+func NewRequestJournalService(repo *repositories.RequestJournalRepository, limit int, replayURL string) *RequestJournalService {
+	return &RequestJournalService{
+		repo:      repo,
+		limit:     limit,
+		replayURL: strings.TrimSuffix(replayURL, "/"),
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Record anonymizes and stores a captured request
+func (s *RequestJournalService) Record(entry models.JournalEntry) error {
+	headers := map[string]string{}
+	for _, name := range journalHeaders {
+		if value, ok := entry.RequestHeaders[name]; ok {
+			headers[name] = value
+		}
+	}
+	entry.RequestHeaders = headers
+
+	entry.TerminalID = headers["X-Terminal-Id"]
+	if entry.TerminalID == "" {
+		entry.TerminalID = terminalIDFromBody(entry.RequestBody)
+	}
+
+	entry.RequestBody = anonymize(entry.RequestBody)
+	entry.ResponseBody = anonymize(entry.ResponseBody)
+	return s.repo.Create(entry, s.limit)
+}
+
+func (s *RequestJournalService) GetAll(terminalID, path string) ([]models.JournalEntry, error) {
+	return s.repo.GetAll(terminalID, path, 100)
+}
+
+func (s *RequestJournalService) GetByID(id int64) (models.JournalEntry, error) {
+	return s.repo.GetByID(id)
+}
+
+// Replay sends a captured request to the staging instance and returns both responses
+func (s *RequestJournalService) Replay(id int64) (*models.ReplayResult, error) {
+	if s.replayURL == "" {
+		return nil, ErrReplayNotConfigured
+	}
+
+	entry, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	target := s.replayURL + entry.Path
+	if entry.Query != "" {
+		target += "?" + entry.Query
+	}
+
+	req, err := http.NewRequest(entry.Method, target, strings.NewReader(entry.RequestBody))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range entry.RequestHeaders {
+		req.Header.Set(name, value)
+	}
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ReplayResult{
+		EntryID:        entry.ID,
+		TargetURL:      target,
+		OriginalStatus: entry.ResponseStatus,
+		OriginalBody:   entry.ResponseBody,
+		ReplayStatus:   resp.StatusCode,
+		ReplayBody:     anonymize(string(body)),
+		DurationMs:     int(time.Since(start).Milliseconds()),
+		StatusMatches:  resp.StatusCode == entry.ResponseStatus,
+	}, nil
+}
+
+func terminalIDFromBody(body string) string {
+	var payload struct {
+		TerminalID string `json:"terminal_id"`
+	}
+	if json.Unmarshal([]byte(body), &payload) != nil {
+		return ""
+	}
+	return payload.TerminalID
+}
+
+// anonymize redacts personal fields and e-mail addresses from a JSON body;
+// anything that isn't JSON is dropped
+func anonymize(body string) string {
+	if strings.TrimSpace(body) == "" {
+		return ""
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return "[non-JSON body omitted]"
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(redact(value)); err != nil {
+		return "[non-JSON body omitted]"
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			if redactedFields[strings.ToLower(key)] {
+				v[key] = redacted
+				continue
+			}
+			v[key] = redact(val)
+		}
+		return v
+	case []interface{}:
+		for i, val := range v {
+			v[i] = redact(val)
+		}
+		return v
+	case string:
+		return emailPattern.ReplaceAllString(v, redacted)
+	default:
+		return v
+	}
+}
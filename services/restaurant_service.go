@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var (
+	ErrRestaurantModeOff  = errors.New("restaurant mode is off, set RESTAURANT_MODE")
+	ErrInvalidTableNumber = errors.New("table_number must be at most 16 characters")
+)
+
+// queueScope counts the queue numbers of a day; it holds no "|", so it is
+// never taken for a receipt scope
+func queueScope(day time.Time) string {
+	return "queue:" + day.Format("2006-01-02")
+}
+
+// RestaurantService takes restaurant orders: draft sales served at a table
+// or called out by a queue number, which take more items until they are
+// billed through the transaction status, and shown to the kitchen while open.
+// What an order takes is printed at the stations making it.
+type RestaurantService struct {
+	enabled      bool
+	transactions *TransactionService
+	sequences    repositories.SequenceStore
+	repo         *repositories.RestaurantRepository
+	printers     *PrinterService
+}
+
+// NewRestaurantService takes orders only when enabled, i.e. in restaurant mode
+func NewRestaurantService(enabled bool, transactions *TransactionService, sequences repositories.SequenceStore, repo *repositories.RestaurantRepository, printers *PrinterService) *RestaurantService {
+	return &RestaurantService{enabled: enabled, transactions: transactions, sequences: sequences, repo: repo, printers: printers}
+}
+
+// OpenOrder checks out req as a draft at its table, which mustn't have
+// another open order, or under the next queue number of the day
+func (s *RestaurantService) OpenOrder(ctx context.Context, req models.OrderRequest) (*models.Transaction, error) {
+	if !s.enabled {
+		return nil, ErrRestaurantModeOff
+	}
+	if len(req.Items) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	order := repositories.OrderNumber{Table: strings.TrimSpace(req.TableNumber)}
+	if len(order.Table) > 16 {
+		return nil, ErrInvalidTableNumber
+	}
+	if order.Table == "" {
+		queue, err := s.sequences.Next(queueScope(time.Now()))
+		if err != nil {
+			return nil, err
+		}
+		order.Queue = int(queue)
+	}
+
+	transaction, err := s.transactions.CheckoutOrder(ctx, models.CheckoutRequest{
+		Items:      req.Items,
+		CustomerID: req.CustomerID,
+		StoreID:    req.StoreID,
+		Status:     repositories.TransactionDraft,
+		Approval:   req.Approval,
+	}, order)
+	if err != nil {
+		return nil, err
+	}
+	s.print(transaction)
+	return transaction, nil
+}
+
+// AddItems adds items to the open order id, with price overrides and lines
+// priced past the guardrails approved by approval
+func (s *RestaurantService) AddItems(ctx context.Context, id int, items []models.CheckoutItem, approval *models.OverrideApproval) (*models.Transaction, error) {
+	if !s.enabled {
+		return nil, ErrRestaurantModeOff
+	}
+	transaction, err := s.transactions.AddItems(ctx, id, items, approval)
+	if err != nil {
+		return nil, err
+	}
+	s.print(transaction)
+	return transaction, nil
+}
+
+// print sends the new lines of order to the kitchen printers; the order is
+// taken even when they can't be routed, the kitchen display still shows it
+func (s *RestaurantService) print(order *models.Transaction) {
+	if err := s.printers.Route(order); err != nil {
+		log.Println("Error routing order to printers:", err)
+	}
+}
+
+// KitchenOrders returns the open orders, oldest first
+func (s *RestaurantService) KitchenOrders() ([]models.KitchenOrder, error) {
+	if !s.enabled {
+		return nil, ErrRestaurantModeOff
+	}
+	return s.repo.GetOpenOrders()
+}
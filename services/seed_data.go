@@ -0,0 +1,69 @@
+package services
+
+import (
+	"kasir-api/models"
+	"kasir-api/money"
+)
+
+// sampleProduct is a demo product filed under the category named Category
+type sampleProduct struct {
+	Category  string
+	Name      string
+	Price     money.Amount
+	CostPrice money.Amount
+}
+
+var sampleCategories = []models.Category{
+	{Name: "Makanan", Description: "Makanan ringan dan instan"},
+	{Name: "Minuman", Description: "Minuman kemasan"},
+	{Name: "Sembako", Description: "Beras, minyak, gula dan bahan pokok"},
+	{Name: "Perawatan Diri", Description: "Sabun, sampo dan pasta gigi"},
+	{Name: "Kebutuhan Rumah", Description: "Deterjen dan perlengkapan rumah"},
+}
+
+// sampleProducts are ordered from best to slowest seller; demo sales favour
+// the first ones
+var sampleProducts = []sampleProduct{
+	{"Makanan", "Indomie Goreng", 3500, 2800},
+	{"Minuman", "Aqua 600ml", 4000, 2900},
+	{"Minuman", "Kopi Kapal Api Sachet", 2000, 1500},
+	{"Minuman", "Teh Botol Sosro 450ml", 6000, 4500},
+	{"Makanan", "Indomie Soto", 3300, 2700},
+	{"Sembako", "Gula Pasir Gulaku 1kg", 18500, 16500},
+	{"Sembako", "Minyak Goreng Bimoli 1L", 21000, 18800},
+	{"Makanan", "Chitato 68g", 11000, 8900},
+	{"Minuman", "Le Minerale 1.5L", 7000, 5300},
+	{"Perawatan Diri", "Sabun Lifebuoy 110g", 5000, 3800},
+	{"Makanan", "Roti Tawar Sari Roti", 15000, 12000},
+	{"Sembako", "Telur Ayam 1kg", 29000, 26000},
+	{"Minuman", "Susu Ultra Milk 250ml", 6500, 5200},
+	{"Perawatan Diri", "Pepsodent 190g", 14500, 11800},
+	{"Makanan", "Tango Wafer 130g", 9500, 7600},
+	{"Sembako", "Beras Pandan Wangi 5kg", 78000, 71000},
+	{"Minuman", "Pocari Sweat 500ml", 8000, 6300},
+	{"Perawatan Diri", "Sampo Sunsilk 170ml", 22500, 18900},
+	{"Kebutuhan Rumah", "Rinso Cair 750ml", 22000, 18500},
+	{"Makanan", "Silverqueen 62g", 16500, 13500},
+	{"Sembako", "Kecap Bango 520ml", 24000, 20500},
+	{"Kebutuhan Rumah", "Sunlight 755ml", 16000, 13200},
+	{"Minuman", "Good Day Cappuccino Sachet", 2500, 1900},
+	{"Perawatan Diri", "Sabun Cair Dettol 410ml", 31000, 26000},
+	{"Makanan", "Biskuit Roma Kelapa 300g", 11500, 9200},
+	{"Sembako", "Tepung Segitiga Biru 1kg", 13500, 11600},
+	{"Kebutuhan Rumah", "Baygon Aerosol 600ml", 42000, 36000},
+	{"Kebutuhan Rumah", "Tisu Paseo 250 lembar", 17500, 14200},
+	{"Perawatan Diri", "Deodoran Rexona 45ml", 19500, 15800},
+	{"Kebutuhan Rumah", "Stella Pengharum Ruangan", 23000, 18700},
+}
+
+// samplePacks are the multipacks that stretch the catalog past sampleProducts
+var samplePacks = []int{1, 6, 12, 24}
+
+var sampleFirstNames = []string{
+	"Budi", "Siti", "Agus", "Dewi", "Joko", "Rina", "Andi", "Sri", "Hendra", "Yuni",
+	"Bambang", "Wati", "Eko", "Lestari", "Rudi", "Ani", "Dedi", "Fitri", "Imam", "Nur",
+}
+
+var sampleLastNames = []string{
+	"Santoso", "Wijaya", "Saputra", "Lestari", "Hidayat", "Kusuma", "Pratama", "Rahayu", "Setiawan", "Nugroho",
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/repositories"
+)
+
+// seedReceiptPrefix marks demo sales, so reruns can tell which days are seeded
+const seedReceiptPrefix = "DEMO/"
+
+var ErrInvalidSeedVolume = fmt.Errorf("products must be 1 to %d, customers 0 to 1000, days 0 to 365 and transactions per day 0 to 1000",
+	len(sampleProducts)*len(samplePacks))
+
+// SeedOptions sets how much demo data to create
+type SeedOptions struct {
+	Products           int
+	Customers          int
+	Days               int
+	TransactionsPerDay int
+}
+
+// SeedResult counts what a seed run created; SeededDays are the days that
+// got new sales, for the caller to re-aggregate
+type SeedResult struct {
+	Categories   int
+	Products     int
+	Customers    int
+	Transactions int
+	Skipped      int
+	SeededDays   []string
+}
+
+// SeedService fills an install with realistic demo data for demos and load
+// tests: a grocery catalog, customers and a history of sales. It's
+// idempotent; a rerun only adds what the requested volume still lacks, and
+// products or customers already there are kept as they are.
+type SeedService struct {
+	categories   *CategoryService
+	products     *ProductService
+	customers    *CustomerService
+	transactions repositories.TransactionStore
+	pricing      repositories.PricingRuleStore
+	repo         *repositories.SeedRepository
+}
+
+// NewSeedService seeds no customers when customers is nil, as on kiosk installs
+func NewSeedService(categories *CategoryService, products *ProductService, customers *CustomerService, transactions repositories.TransactionStore, pricing repositories.PricingRuleStore, repo *repositories.SeedRepository) *SeedService {
+	return &SeedService{categories: categories, products: products, customers: customers, transactions: transactions, pricing: pricing, repo: repo}
+}
+
+func (s *SeedService) Seed(opts SeedOptions) (SeedResult, error) {
+	if opts.Products < 1 || opts.Products > len(sampleProducts)*len(samplePacks) ||
+		opts.Customers < 0 || opts.Customers > 1000 ||
+		opts.Days < 0 || opts.Days > 365 ||
+		opts.TransactionsPerDay < 0 || opts.TransactionsPerDay > 1000 {
+		return SeedResult{}, ErrInvalidSeedVolume
+	}
+
+	var result SeedResult
+	// enough stock for the best seller to last the whole history
+	stock := max(1000, 2*opts.Days*opts.TransactionsPerDay)
+	products, err := s.seedCatalog(opts.Products, stock, &result)
+	if err != nil {
+		return result, err
+	}
+	if s.customers != nil {
+		if err := s.seedCustomers(opts.Customers, &result); err != nil {
+			return result, err
+		}
+	}
+	if err := s.seedSales(products, opts, &result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// seedCatalog makes sure the first n sample products exist, new ones with
+// stock units, and returns them in sample order
+func (s *SeedService) seedCatalog(n, stock int, result *SeedResult) ([]models.Product, error) {
+	categories, err := s.categories.GetAll("", time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	categoryIDs := map[string]int{}
+	for _, c := range categories {
+		categoryIDs[c.Name] = c.ID
+	}
+	for _, c := range sampleCategories {
+		if _, ok := categoryIDs[c.Name]; ok {
+			continue
+		}
+		created, err := s.categories.Create(c)
+		if err != nil {
+			return nil, fmt.Errorf("error creating category %s: %v", c.Name, err)
+		}
+		categoryIDs[c.Name] = created.ID
+		result.Categories++
+	}
+
+	existing, err := s.products.GetAll("", "", "", "", time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]models.Product{}
+	for _, p := range existing {
+		byName[p.Name] = p
+	}
+
+	products := make([]models.Product, 0, n)
+	for i := 0; i < n; i++ {
+		p := sampleCatalogProduct(i, stock)
+		if found, ok := byName[p.Name]; ok {
+			products = append(products, found)
+			continue
+		}
+
+		sample := sampleProducts[i%len(sampleProducts)]
+		p.CategoryIDs = []int{categoryIDs[sample.Category]}
+		created, err := s.products.Create(p)
+		if err != nil {
+			return nil, fmt.Errorf("error creating product %s: %v", p.Name, err)
+		}
+		products = append(products, created)
+		result.Products++
+	}
+	return products, nil
+}
+
+// sampleCatalogProduct returns the i-th demo product; past the sample list
+// come multipacks, priced a little below their single units
+func sampleCatalogProduct(i, stock int) models.Product {
+	sample := sampleProducts[i%len(sampleProducts)]
+	pack := samplePacks[i/len(sampleProducts)]
+
+	p := models.Product{Name: sample.Name, Price: sample.Price, CostPrice: sample.CostPrice, Stock: stock}
+	if pack > 1 {
+		p.Name = fmt.Sprintf("%s (isi %d)", sample.Name, pack)
+		p.Price = roundUp(sample.Price.Times(pack)*95/100, 500)
+		p.CostPrice = sample.CostPrice.Times(pack)
+		p.Stock = stock / 4
+	}
+	return p
+}
+
+func roundUp(amount, step money.Amount) money.Amount {
+	return (amount + step - 1) / step * step
+}
+
+// seedCustomers makes sure the first n sample customers exist, telling them
+// apart by phone number
+func (s *SeedService) seedCustomers(n int, result *SeedResult) error {
+	existing, err := s.customers.GetAll()
+	if err != nil {
+		return err
+	}
+	phones := map[string]bool{}
+	for _, c := range existing {
+		phones[c.Phone] = true
+	}
+
+	for i := 0; i < n; i++ {
+		first := sampleFirstNames[i%len(sampleFirstNames)]
+		last := sampleLastNames[(i/len(sampleFirstNames))%len(sampleLastNames)]
+		customer := models.Customer{
+			Name:  first + " " + last,
+			Phone: fmt.Sprintf("0812%08d", 55500000+i),
+		}
+		if i%4 == 0 {
+			customer.Email = strings.ToLower(first+"."+last) + fmt.Sprintf("%d@example.com", i)
+		}
+
+		phone, err := NormalizePhone(customer.Phone, s.customers.PhoneCountryCode)
+		if err != nil {
+			return err
+		}
+		if phones[phone] {
+			continue
+		}
+		if _, err := s.customers.Create(customer); err != nil {
+			return fmt.Errorf("error creating customer %s: %v", customer.Name, err)
+		}
+		phones[phone] = true
+		result.Customers++
+	}
+	return nil
+}
+
+// seedSales tops up each of the past opts.Days days, today excluded, to its
+// number of demo sales. The number and content of each day's sales are drawn
+// from a generator seeded with the date, so reruns produce the same history.
+func (s *SeedService) seedSales(products []models.Product, opts SeedOptions, result *SeedResult) error {
+	if opts.TransactionsPerDay == 0 || len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	rules, err := s.pricing.GetActive(ids)
+	if err != nil {
+		return err
+	}
+	price := func(productID int, unitPrice, _ money.Amount, quantity int, _ *models.PriceOverride) repositories.LinePrice {
+		subtotal, rule := priceLine(unitPrice, quantity, rules[productID])
+		return repositories.LinePrice{Subtotal: subtotal, Rule: rule}
+	}
+
+	today := time.Now()
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+	for d := opts.Days; d >= 1; d-- {
+		day := today.AddDate(0, 0, -d)
+		prefix := seedReceiptPrefix + day.Format("20060102") + "/"
+
+		target := dailySales(day, opts.TransactionsPerDay)
+		count, err := s.repo.CountTransactions(prefix)
+		if err != nil {
+			return err
+		}
+		if count >= target {
+			continue
+		}
+
+		for n := count + 1; n <= target; n++ {
+			rng := rand.New(rand.NewPCG(uint64(day.Unix()), uint64(n)))
+			items := sampleBasket(rng, products)
+			at := day.Add(8*time.Hour + time.Duration(rng.Int64N(int64(13*time.Hour))))
+
+			transaction, err := s.transactions.CreateTransaction(context.Background(), items, 0, 0, fmt.Sprintf("%s%04d", prefix, n), repositories.TransactionCompleted, repositories.OrderNumber{}, price, nil, nil)
+			var stockErr *repositories.InsufficientStockError
+			if errors.As(err, &stockErr) {
+				result.Skipped++
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := s.repo.Backdate(transaction.ID, at.Format("2006-01-02 15:04:05")); err != nil {
+				return err
+			}
+			result.Transactions++
+		}
+		result.SeededDays = append(result.SeededDays, day.Format("2006-01-02"))
+	}
+	return nil
+}
+
+// dailySales is the number of demo sales of day: perDay give or take 30%,
+// with a busier weekend
+func dailySales(day time.Time, perDay int) int {
+	rng := rand.New(rand.NewPCG(uint64(day.Unix()), 0))
+	n := float64(perDay) * (0.7 + 0.6*rng.Float64())
+	if wd := day.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		n *= 1.3
+	}
+	return max(1, int(n))
+}
+
+// sampleBasket picks one to four products, favouring the best sellers
+func sampleBasket(rng *rand.Rand, products []models.Product) []models.CheckoutItem {
+	lines := 1 + rng.IntN(4)
+	quantities := map[int]int{}
+	order := []int{}
+	for i := 0; i < lines; i++ {
+		// an exponential pick makes the first fifth of the catalog sell the most
+		idx := int(rng.ExpFloat64()*float64(len(products))/5) % len(products)
+		id := products[idx].ID
+		if _, ok := quantities[id]; !ok {
+			order = append(order, id)
+		}
+		quantities[id] += 1 + rng.IntN(3)
+	}
+
+	items := make([]models.CheckoutItem, len(order))
+	for i, id := range order {
+		items[i] = models.CheckoutItem{ProductID: id, Quantity: quantities[id]}
+	}
+	return items
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/storage"
+)
+
+// selfTestTimeout bounds each check, so an unreachable printer doesn't hold up the boot
+const selfTestTimeout = 5 * time.Second
+
+// SelfTestCheck is one startup check. While a critical check fails the API
+// refuses to change data.
+type SelfTestCheck struct {
+	Name     string
+	Critical bool
+	Run      func(ctx context.Context) error
+}
+
+// SelfTestService runs the startup checks and keeps the last report
+type SelfTestService struct {
+	checks []SelfTestCheck
+
+	mu     sync.RWMutex
+	report models.SelfTestReport
+}
+
+func NewSelfTestService(checks []SelfTestCheck) *SelfTestService {
+	return &SelfTestService{checks: checks}
+}
+
+// Run runs every check and stores the report
+func (s *SelfTestService) Run(ctx context.Context) models.SelfTestReport {
+	report := models.SelfTestReport{OK: true, Checks: []models.SelfTestResult{}}
+	for _, check := range s.checks {
+		checkCtx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+		start := time.Now()
+		err := check.Run(checkCtx)
+		cancel()
+
+		result := models.SelfTestResult{
+			Name:       check.Name,
+			Critical:   check.Critical,
+			OK:         err == nil,
+			DurationMs: int(time.Since(start).Milliseconds()),
+		}
+		if err != nil {
+			result.Error = err.Error()
+			if check.Critical {
+				report.OK = false
+			}
+			log.Printf("Self-test %s failed: %v", check.Name, err)
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	report.CheckedAt = time.Now().Format("2006-01-02 15:04:05")
+
+	s.mu.Lock()
+	s.report = report
+	s.mu.Unlock()
+	return report
+}
+
+// Report returns the report of the last run
+func (s *SelfTestService) Report() models.SelfTestReport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.report
+}
+
+// Healthy reports whether every critical check passed on the last run
+func (s *SelfTestService) Healthy() bool {
+	return s.Report().OK
+}
+
+// StorageCheck writes, reads back and deletes a probe file
+func StorageCheck(files storage.Storage) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		const name = "selftest/probe"
+		probe := []byte(time.Now().Format(time.RFC3339Nano))
+
+		w, err := files.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(probe); err != nil {
+			w.Close()
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+
+		r, err := files.Open(name)
+		if err != nil {
+			return err
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(content, probe) {
+			return fmt.Errorf("probe file read back differently")
+		}
+		return files.Delete(name)
+	}
+}
+
+// ReachableCheck connects to a TCP address, such as a network receipt printer
+func ReachableCheck(addr string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
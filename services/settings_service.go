@@ -0,0 +1,146 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/repositories"
+)
+
+var (
+	ErrInvalidTaxRate       = errors.New("tax_rate must be a percentage from 0 to 100")
+	ErrInvalidStoreTimezone = errors.New("timezone must be an IANA time zone, e.g. Asia/Jakarta")
+	ErrCurrencyInUse        = errors.New("currency can't change once sales are recorded, their amounts are in it")
+)
+
+// settingsTTL is how long the settings are served from memory; settings
+// saved through another instance are in force here within it
+const settingsTTL = time.Minute
+
+// SettingsService keeps the store settings, those saved or, before any are,
+// the defaults taken from the environment. Receipts, reports, exports and
+// tax invoices read them as they are produced, so a change is in force
+// straight away, but for the currency, which the server takes on at start.
+type SettingsService struct {
+	repo     *repositories.SettingsRepository
+	defaults models.StoreSettings
+
+	mu       sync.Mutex
+	current  models.StoreSettings
+	loadedAt time.Time
+}
+
+func NewSettingsService(repo *repositories.SettingsRepository, defaults models.StoreSettings) *SettingsService {
+	return &SettingsService{repo: repo, defaults: defaults}
+}
+
+// Load reads the settings in force, as the server starts
+func (s *SettingsService) Load() (models.StoreSettings, error) {
+	settings, err := s.read()
+	if err != nil {
+		return models.StoreSettings{}, err
+	}
+	s.mu.Lock()
+	s.current, s.loadedAt = settings, time.Now()
+	s.mu.Unlock()
+	return settings, nil
+}
+
+// Settings returns the settings in force, read again once they are older
+// than settingsTTL; the last ones read stay in force while that fails
+func (s *SettingsService) Settings() models.StoreSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.loadedAt) < settingsTTL {
+		return s.current
+	}
+
+	settings, err := s.read()
+	if err != nil {
+		log.Println("Error reading store settings, keeping the last ones:", err)
+		if s.loadedAt.IsZero() {
+			return s.defaults
+		}
+		return s.current
+	}
+	s.current, s.loadedAt = settings, time.Now()
+	return settings
+}
+
+func (s *SettingsService) read() (models.StoreSettings, error) {
+	settings, err := s.repo.Get()
+	if err == sql.ErrNoRows {
+		return s.defaults, nil
+	}
+	return settings, err
+}
+
+// Update replaces the settings. A new currency is refused once sales are
+// recorded; a new time zone drops the daily summaries, which were of the
+// days of the old one.
+func (s *SettingsService) Update(req models.StoreSettings) (models.StoreSettings, error) {
+	req, err := checkStoreSettings(req)
+	if err != nil {
+		return models.StoreSettings{}, err
+	}
+
+	old, err := s.read()
+	if err != nil {
+		return models.StoreSettings{}, err
+	}
+	if req.Currency != old.Currency {
+		hasSales, err := s.repo.HasSales()
+		if err != nil {
+			return models.StoreSettings{}, err
+		}
+		if hasSales {
+			return models.StoreSettings{}, ErrCurrencyInUse
+		}
+	}
+
+	saved, err := s.repo.Save(req, req.Timezone != old.Timezone)
+	if err != nil {
+		return models.StoreSettings{}, err
+	}
+	s.mu.Lock()
+	s.current, s.loadedAt = saved, time.Now()
+	s.mu.Unlock()
+	return saved, nil
+}
+
+// checkStoreSettings trims req and checks every setting is usable
+func checkStoreSettings(req models.StoreSettings) (models.StoreSettings, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Address = strings.TrimSpace(req.Address)
+	req.ReceiptFooter = strings.TrimSpace(req.ReceiptFooter)
+	req.Currency = strings.ToUpper(strings.TrimSpace(req.Currency))
+	req.Timezone = strings.TrimSpace(req.Timezone)
+	req.UpdatedAt = ""
+
+	if req.Name == "" {
+		return req, ErrStoreNameRequired
+	}
+	if req.NPWP = strings.TrimSpace(req.NPWP); req.NPWP != "" {
+		npwp, err := NormalizeNPWP(req.NPWP)
+		if err != nil {
+			return req, err
+		}
+		req.NPWP = npwp
+	}
+	if _, err := money.Lookup(req.Currency); err != nil {
+		return req, ErrUnknownCurrency
+	}
+	if req.TaxRate < 0 || req.TaxRate > 100 {
+		return req, ErrInvalidTaxRate
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil || req.Timezone == "" || req.Timezone == "Local" {
+		return req, ErrInvalidStoreTimezone
+	}
+	return req, nil
+}
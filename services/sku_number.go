@@ -0,0 +1,59 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"kasir-api/repositories"
+	"kasir-api/sequence"
+)
+
+var ErrInvalidSKU = errors.New("sku may only contain letters, digits, '-', '_' and '.', up to 64 characters")
+
+// skuScope is the counter SKUs are drawn from; receipt scopes always hold a "|"
+const skuScope = "sku"
+
+// SKUNumbering hands out SKUs such as SKU-000123 for products created without one
+type SKUNumbering struct {
+	repo   repositories.SequenceStore
+	format *sequence.Format
+	store  string
+}
+
+func NewSKUNumbering(repo repositories.SequenceStore, format *sequence.Format, store string) (*SKUNumbering, error) {
+	if store == "" && format.HasStore() {
+		return nil, fmt.Errorf("sku format contains {STORE} but no store code is set")
+	}
+	return &SKUNumbering{repo: repo, format: format, store: store}, nil
+}
+
+// Next allocates the next SKU. The counter never resets, whatever date parts
+// the format holds.
+func (n *SKUNumbering) Next() (string, error) {
+	seq, err := n.repo.Next(skuScope)
+	if err != nil {
+		return "", err
+	}
+	return n.format.Render(n.store, time.Now(), seq), nil
+}
+
+// scope is the counter SKUs are drawn from, the same at any t
+func (n *SKUNumbering) scope(time.Time) string {
+	return skuScope
+}
+
+// normalizeSKU trims sku and checks it can be used in a URL path as is
+func normalizeSKU(sku string) (string, error) {
+	sku = strings.TrimSpace(sku)
+	if len(sku) > 64 {
+		return "", ErrInvalidSKU
+	}
+	for _, c := range sku {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return "", ErrInvalidSKU
+		}
+	}
+	return sku, nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"kasir-api/events"
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+const (
+	// statsTTL is how long computed stats are served before they're recomputed
+	statsTTL = time.Minute
+	// activeProductDays is how recently a product must have sold to count as active
+	activeProductDays = 30
+)
+
+type StatsService struct {
+	repo *repositories.StatsRepository
+
+	mu        sync.Mutex
+	cached    *models.StoreStats
+	expiresAt time.Time
+}
+
+// NewStatsService recomputes the stats after the sales, refunds and new
+// products announced on bus, rather than serving them stale until statsTTL
+func NewStatsService(repo *repositories.StatsRepository, bus *events.Bus) *StatsService {
+	s := &StatsService{repo: repo}
+	bus.Subscribe("stats cache", s.invalidate, events.TransactionCompleted, events.RefundIssued, events.ProductCreated)
+	return s
+}
+
+// invalidate is the bus Handler dropping the cached stats
+func (s *StatsService) invalidate(events.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cached = nil
+	return nil
+}
+
+// GetStats returns the store's key numbers; every dashboard polling within
+// statsTTL shares one computation
+func (s *StatsService) GetStats() (*models.StoreStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != nil && time.Now().Before(s.expiresAt) {
+		return s.cached, nil
+	}
+
+	stats, err := s.repo.GetCounts(activeProductDays)
+	if err != nil {
+		return nil, err
+	}
+
+	// the 30 days before the last 30 are what its trend is measured against
+	daily, err := s.repo.GetDailyRevenue(60)
+	if err != nil {
+		return nil, err
+	}
+	stats.Revenue7Days = revenueTrend(daily, 7)
+	stats.Revenue30Days = revenueTrend(daily, 30)
+
+	now := time.Now()
+	stats.GeneratedAt = now.Format("2006-01-02 15:04:05")
+	s.cached = stats
+	s.expiresAt = now.Add(statsTTL)
+	return stats, nil
+}
+
+// revenueTrend sums the last days of daily, oldest first, against the days
+// before them
+func revenueTrend(daily []models.DailyRevenue, days int) models.RevenueTrend {
+	trend := models.RevenueTrend{Days: days, Daily: []models.DailyRevenue{}}
+	for i, d := range daily {
+		switch {
+		case i >= len(daily)-days:
+			trend.Revenue += d.Revenue
+			trend.Transactions += d.Transactions
+			trend.Daily = append(trend.Daily, d)
+		case i >= len(daily)-2*days:
+			trend.PreviousRevenue += d.Revenue
+		}
+	}
+	trend.ChangePercent = margin(trend.Revenue-trend.PreviousRevenue, trend.PreviousRevenue)
+	return trend
+}
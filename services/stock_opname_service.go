@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var (
+	ErrOpnameInProgress = errors.New("another stock opname is still open or awaiting approval")
+	ErrEmptyOpnameCount = errors.New("items must have at least one counted product")
+)
+
+type StockOpnameService struct {
+	repo *repositories.StockOpnameRepository
+}
+
+func NewStockOpnameService(repo *repositories.StockOpnameRepository) *StockOpnameService {
+	return &StockOpnameService{repo: repo}
+}
+
+// Start opens a count session; only one session can be in progress at a time
+// so the same shelf isn't booked twice
+func (s *StockOpnameService) Start(req models.StartOpnameRequest) (*models.StockOpname, error) {
+	if req.CategoryID < 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	inProgress, err := s.repo.HasInProgress()
+	if err != nil {
+		return nil, err
+	}
+	if inProgress {
+		return nil, ErrOpnameInProgress
+	}
+
+	id, err := s.repo.Create(req.CategoryID, req.Note)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+func (s *StockOpnameService) GetAll(status string) ([]models.StockOpname, error) {
+	return s.repo.GetAll(status)
+}
+
+// GetByID returns a session with overage/shortage flags per line
+func (s *StockOpnameService) GetByID(id int) (*models.StockOpname, error) {
+	opname, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range opname.Lines {
+		line := &opname.Lines[i]
+		switch {
+		case line.Variance > 0:
+			line.Flag = "overage"
+		case line.Variance < 0:
+			line.Flag = "shortage"
+		default:
+			line.Flag = "match"
+		}
+	}
+	return opname, nil
+}
+
+// RecordCounts stores counted quantities and returns the updated session
+func (s *StockOpnameService) RecordCounts(id int, req models.OpnameCountRequest) (*models.StockOpname, error) {
+	if len(req.Items) == 0 {
+		return nil, ErrEmptyOpnameCount
+	}
+	for _, item := range req.Items {
+		if item.ProductID <= 0 || item.CountedQty < 0 {
+			return nil, ErrInvalidAmount
+		}
+	}
+
+	if err := s.repo.RecordCounts(id, req.Items); err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// Submit sends the counted session for approval
+func (s *StockOpnameService) Submit(id int) (*models.StockOpname, error) {
+	if err := s.repo.Submit(id); err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// Approve applies the variances to stock as stock opname movements
+func (s *StockOpnameService) Approve(id int, req models.ReviewOpnameRequest) (*models.StockOpname, error) {
+	if err := s.repo.Approve(id, req.Note); err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// Reject discards a submitted session, stock stays as recorded
+func (s *StockOpnameService) Reject(id int, req models.ReviewOpnameRequest) (*models.StockOpname, error) {
+	if err := s.repo.Reject(id, req.Note); err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
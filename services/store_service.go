@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var (
+	ErrMultiStoreOff     = errors.New("multi-store is off, set MULTI_STORE")
+	ErrStoreNameRequired = errors.New("store name is required")
+	ErrInvalidStorePrice = errors.New("a store price needs a product_id and a price of at least 0")
+)
+
+// StoreService keeps the stores of a multi-store install and the prices
+// they sell products at instead of the products' own
+type StoreService struct {
+	enabled bool
+	repo    *repositories.StoreRepository
+}
+
+// NewStoreService keeps stores only when enabled, i.e. with multi-store on
+func NewStoreService(enabled bool, repo *repositories.StoreRepository) *StoreService {
+	return &StoreService{enabled: enabled, repo: repo}
+}
+
+func (s *StoreService) GetAll() ([]models.Store, error) {
+	if !s.enabled {
+		return nil, ErrMultiStoreOff
+	}
+	return s.repo.GetAll()
+}
+
+func (s *StoreService) Create(store models.Store) (models.Store, error) {
+	if !s.enabled {
+		return models.Store{}, ErrMultiStoreOff
+	}
+	store, err := normalizeStore(store)
+	if err != nil {
+		return models.Store{}, err
+	}
+	return s.repo.Create(store)
+}
+
+func (s *StoreService) Update(store models.Store) (models.Store, error) {
+	if !s.enabled {
+		return models.Store{}, ErrMultiStoreOff
+	}
+	store, err := normalizeStore(store)
+	if err != nil {
+		return models.Store{}, err
+	}
+	return s.repo.Update(store)
+}
+
+func (s *StoreService) Delete(id int) error {
+	if !s.enabled {
+		return ErrMultiStoreOff
+	}
+	return s.repo.Delete(id)
+}
+
+func normalizeStore(store models.Store) (models.Store, error) {
+	store.Name = strings.TrimSpace(store.Name)
+	store.Address = strings.TrimSpace(store.Address)
+	if store.Name == "" {
+		return store, ErrStoreNameRequired
+	}
+	return store, nil
+}
+
+// GetPrices returns the prices store id overrides
+func (s *StoreService) GetPrices(id int) ([]models.StorePrice, error) {
+	if !s.enabled {
+		return nil, ErrMultiStoreOff
+	}
+	return s.repo.GetPrices(id)
+}
+
+// SetPrice makes store id sell a product at price.Price, which checkouts at
+// the store then start from instead of the product's own price
+func (s *StoreService) SetPrice(id int, price models.StorePrice) (models.StorePrice, error) {
+	if !s.enabled {
+		return models.StorePrice{}, ErrMultiStoreOff
+	}
+	if price.ProductID <= 0 || price.Price < 0 {
+		return models.StorePrice{}, ErrInvalidStorePrice
+	}
+	return s.repo.SetPrice(id, price)
+}
+
+// DeletePrice makes store id sell productID at the product's own price again
+func (s *StoreService) DeletePrice(id, productID int) error {
+	if !s.enabled {
+		return ErrMultiStoreOff
+	}
+	return s.repo.DeletePrice(id, productID)
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"math"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+type SupplierService struct {
+	Repo *repositories.SupplierRepository
+}
+
+func NewSupplierService(repo *repositories.SupplierRepository) *SupplierService {
+	return &SupplierService{Repo: repo}
+}
+
+func (s *SupplierService) GetAll() ([]models.Supplier, error) {
+	return s.Repo.GetAll()
+}
+
+func (s *SupplierService) GetByID(id int) (models.Supplier, error) {
+	return s.Repo.GetByID(id)
+}
+
+func (s *SupplierService) Create(supplier models.Supplier) (models.Supplier, error) {
+	return s.Repo.Create(supplier)
+}
+
+func (s *SupplierService) Update(supplier models.Supplier) (models.Supplier, error) {
+	return s.Repo.Update(supplier)
+}
+
+func (s *SupplierService) Delete(id int) error {
+	return s.Repo.Delete(id)
+}
+
+func (s *SupplierService) SavePrice(price models.SupplierPrice) (models.SupplierPrice, error) {
+	if price.Price <= 0 {
+		return models.SupplierPrice{}, ErrInvalidAmount
+	}
+	return s.Repo.SavePrice(price)
+}
+
+func (s *SupplierService) GetPricesBySupplier(supplierID int) ([]models.SupplierPrice, error) {
+	return s.Repo.GetPricesBySupplier(supplierID)
+}
+
+func (s *SupplierService) GetPricesByProduct(productID int) ([]models.SupplierPrice, error) {
+	return s.Repo.GetPricesByProduct(productID)
+}
+
+// GetPriceTrend summarizes how the price of a product at a supplier moved over time
+func (s *SupplierService) GetPriceTrend(supplierID, productID int) (*models.PriceTrend, error) {
+	history, err := s.Repo.GetPriceHistory(supplierID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	trend := &models.PriceTrend{
+		SupplierID: supplierID,
+		ProductID:  productID,
+		History:    history,
+	}
+	if len(history) == 0 {
+		return trend, nil
+	}
+
+	trend.FirstPrice = history[0].Price
+	trend.LatestPrice = history[len(history)-1].Price
+	trend.LowestPrice = history[0].Price
+	trend.HighestPrice = history[0].Price
+	for _, point := range history {
+		if point.Price < trend.LowestPrice {
+			trend.LowestPrice = point.Price
+		}
+		if point.Price > trend.HighestPrice {
+			trend.HighestPrice = point.Price
+		}
+	}
+
+	if trend.FirstPrice != 0 {
+		change := float64(trend.LatestPrice-trend.FirstPrice) / float64(trend.FirstPrice) * 100
+		trend.ChangePercent = math.Round(change*100) / 100
+	}
+	return trend, nil
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/repositories/sqlite"
+)
+
+// syncBatchSize is how many offline sales are read per round trip
+const syncBatchSize = 100
+
+// SyncService keeps an offline terminal in step with the central server:
+// sales made locally are pushed, then the catalog is pulled. While the
+// central server is unreachable the terminal keeps selling and every interval
+// tries again.
+type SyncService struct {
+	local    *sqlite.SyncRepository
+	central  *repositories.SyncRepository
+	interval time.Duration
+
+	run    sync.Mutex
+	mu     sync.Mutex
+	status models.SyncStatus
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewSyncService(local *sqlite.SyncRepository, central *repositories.SyncRepository, interval time.Duration) *SyncService {
+	return &SyncService{
+		local:    local,
+		central:  central,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start syncs right away and then every interval in the background
+func (s *SyncService) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			if err := s.RunOnce(context.Background()); err != nil {
+				log.Println("Sync with central server failed, retrying later:", err)
+			}
+			select {
+			case <-s.stop:
+				return
+			case <-time.After(s.interval):
+			}
+		}
+	}()
+}
+
+// Stop waits for a sync in progress to finish and stops the background loop
+func (s *SyncService) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// RunOnce pushes every pending sale and then pulls the catalog. Sales are
+// pushed oldest first and a failure stops the run, so none is skipped.
+func (s *SyncService) RunOnce(ctx context.Context) error {
+	s.run.Lock()
+	defer s.run.Unlock()
+
+	err := s.sync(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().Format("2006-01-02 15:04:05")
+	if err != nil {
+		s.status.LastError = err.Error()
+		s.status.LastErrorAt = now
+		return err
+	}
+	s.status.LastError = ""
+	s.status.LastErrorAt = ""
+	s.status.LastSyncAt = now
+	return nil
+}
+
+func (s *SyncService) sync(ctx context.Context) error {
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err := s.central.Ping(pingCtx)
+	s.mu.Lock()
+	s.status.Online = err == nil
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	pushed := 0
+	for {
+		transactions, err := s.local.GetPendingTransactions(syncBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, t := range transactions {
+			// a sale already on the central server was pushed before being marked
+			// locally; one clashing with a different sale stays pending to be looked at
+			if _, _, err := s.central.PushTransaction(t); err != nil {
+				return err
+			}
+			if err := s.local.MarkSynced(t.ID); err != nil {
+				return err
+			}
+			pushed++
+		}
+		if len(transactions) < syncBatchSize {
+			break
+		}
+	}
+	if pushed > 0 {
+		log.Printf("Pushed %d offline sales to the central server", pushed)
+	}
+
+	snapshot, err := s.central.GetCatalog()
+	if err != nil {
+		return err
+	}
+	return s.local.ApplyCatalog(snapshot)
+}
+
+// Status reports whether the central server was reachable on the last run and
+// how many sales are still waiting to be pushed
+func (s *SyncService) Status() (models.SyncStatus, error) {
+	pending, err := s.local.PendingCount()
+	if err != nil {
+		return models.SyncStatus{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	status.PendingTransactions = pending
+	return status, nil
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"kasir-api/locale"
+	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/pdf"
+	"kasir-api/repositories"
+	"kasir-api/sequence"
+)
+
+var (
+	ErrInvalidNPWP      = errors.New("npwp must have 15 or 16 digits")
+	ErrBuyerRequired    = errors.New("buyer_name and buyer_address are required")
+	ErrTaxNotConfigured = errors.New("tax invoices are not configured, set the store name and NPWP in /api/settings")
+)
+
+// taxInvoiceScope prefixes the counters of tax invoice numbers; receipt scopes
+// always hold a "|" after the store code
+const taxInvoiceScope = "tax_invoice:"
+
+// TaxInvoiceNumbering hands out tax invoice numbers such as 010.000-24.00000001
+// from the counter the tax invoice repository draws on as it issues them
+type TaxInvoiceNumbering struct {
+	format *sequence.Format
+	store  string
+}
+
+func NewTaxInvoiceNumbering(format *sequence.Format, store string) (*TaxInvoiceNumbering, error) {
+	if store == "" && format.HasStore() {
+		return nil, fmt.Errorf("tax invoice number format contains {STORE} but no store code is set")
+	}
+	return &TaxInvoiceNumbering{format: format, store: store}, nil
+}
+
+// scope is the counter the numbers of the reset period around t are drawn from
+func (n *TaxInvoiceNumbering) scope(t time.Time) string {
+	return taxInvoiceScope + n.format.Scope(n.store, t)
+}
+
+// render formats counter value seq of the reset period around t
+func (n *TaxInvoiceNumbering) render(t time.Time, seq int64) string {
+	return n.format.Render(n.store, t, seq)
+}
+
+// TaxInvoiceService issues faktur pajak for sales to business buyers, the
+// store of settings being the taxable entrepreneur (PKP). Prices include
+// PPN, so the tax base is taken out of the sale's total at the rate in force
+// when the invoice is issued.
+type TaxInvoiceService struct {
+	repo         *repositories.TaxInvoiceRepository
+	transactions *repositories.TransactionRepository
+	numbering    *TaxInvoiceNumbering
+	settings     repositories.SettingsReader
+	locale       locale.Locale
+}
+
+func NewTaxInvoiceService(repo *repositories.TaxInvoiceRepository, transactions *repositories.TransactionRepository, numbering *TaxInvoiceNumbering, settings repositories.SettingsReader, loc locale.Locale) *TaxInvoiceService {
+	return &TaxInvoiceService{repo: repo, transactions: transactions, numbering: numbering, settings: settings, locale: loc}
+}
+
+func (s *TaxInvoiceService) GetAll(dateFrom, dateTo string) ([]models.TaxInvoice, error) {
+	return s.repo.GetAll(dateFrom, dateTo)
+}
+
+func (s *TaxInvoiceService) GetByID(id int) (models.TaxInvoice, error) {
+	return s.repo.GetByID(id)
+}
+
+// Issue gives a transaction its tax invoice, numbered next in the sequence
+func (s *TaxInvoiceService) Issue(req models.TaxInvoiceRequest) (models.TaxInvoice, error) {
+	seller := s.settings.Settings()
+	if seller.Name == "" || seller.NPWP == "" {
+		return models.TaxInvoice{}, ErrTaxNotConfigured
+	}
+	npwp, err := NormalizeNPWP(req.BuyerNPWP)
+	if err != nil {
+		return models.TaxInvoice{}, err
+	}
+	name := strings.TrimSpace(req.BuyerName)
+	address := strings.TrimSpace(req.BuyerAddress)
+	if name == "" || address == "" {
+		return models.TaxInvoice{}, ErrBuyerRequired
+	}
+
+	transaction, err := s.transactions.GetByID(req.TransactionID)
+	if err == sql.ErrNoRows {
+		return models.TaxInvoice{}, repositories.ErrTransactionNotFound
+	}
+	if err != nil {
+		return models.TaxInvoice{}, err
+	}
+	base := taxBase(transaction.TotalAmount, seller.TaxRate)
+
+	now := time.Now()
+	return s.repo.Create(models.TaxInvoice{
+		TransactionID: req.TransactionID,
+		BuyerNPWP:     npwp,
+		BuyerName:     name,
+		BuyerAddress:  address,
+		TaxRate:       seller.TaxRate,
+		TaxBase:       base,
+		TaxAmount:     transaction.TotalAmount - base,
+	}, s.numbering.scope(now), func(seq int64) string {
+		return s.numbering.render(now, seq)
+	})
+}
+
+// RenderPDF returns the tax invoice as a printable PDF
+func (s *TaxInvoiceService) RenderPDF(id int) (models.TaxInvoice, []byte, error) {
+	inv, err := s.repo.GetByID(id)
+	if err != nil {
+		return models.TaxInvoice{}, nil, err
+	}
+	transaction, err := s.transactions.GetByID(inv.TransactionID)
+	if err != nil {
+		return models.TaxInvoice{}, nil, err
+	}
+
+	var buf bytes.Buffer
+	if _, err := s.taxInvoicePDF(inv, transaction).WriteTo(&buf); err != nil {
+		return models.TaxInvoice{}, nil, err
+	}
+	return inv, buf.Bytes(), nil
+}
+
+// taxInvoiceWidth is how many characters fit between the page margins
+const taxInvoiceWidth = 95
+
+func (s *TaxInvoiceService) taxInvoicePDF(inv models.TaxInvoice, transaction *models.Transaction) *pdf.Document {
+	const (
+		left = 40.0
+		size = 9.0
+		lead = 13.0
+	)
+	seller := s.settings.Settings()
+	right := pdf.PageWidth - left
+	doc := pdf.New()
+	y := 50.0
+	advance := func(lines float64) {
+		y += lines * lead
+		if y > pdf.PageHeight-50 {
+			doc.AddPage()
+			y = 50
+		}
+	}
+	text := func(bold bool, s string) {
+		doc.Text(left, y, size, bold, s)
+		advance(1)
+	}
+	amount := func(label string, bold bool, n money.Amount) {
+		doc.Text(left, y, size, bold, label)
+		doc.TextRight(right, y, size, bold, s.locale.Money(n))
+		advance(1)
+	}
+	rule := func() {
+		doc.Line(left, y-lead/2, right, y-lead/2)
+		advance(0.5)
+	}
+	party := func(title, name, address, npwp string) {
+		text(true, title)
+		text(false, "Nama   : "+name)
+		for i, line := range wrapText(address, taxInvoiceWidth-9) {
+			if i == 0 {
+				text(false, "Alamat : "+line)
+			} else {
+				text(false, "         "+line)
+			}
+		}
+		text(false, "NPWP   : "+FormatNPWP(npwp))
+		rule()
+	}
+
+	doc.Text(pdf.PageWidth/2-pdf.TextWidth("FAKTUR PAJAK", 14)/2, y, 14, true, "FAKTUR PAJAK")
+	advance(2)
+	text(false, "Kode dan Nomor Seri Faktur Pajak : "+inv.Number)
+	if inv.ReceiptNumber != "" {
+		text(false, "Nomor Struk                      : "+inv.ReceiptNumber)
+	}
+	rule()
+	party("Pengusaha Kena Pajak", seller.Name, seller.Address, seller.NPWP)
+	party("Pembeli Barang Kena Pajak", inv.BuyerName, inv.BuyerAddress, inv.BuyerNPWP)
+
+	doc.Text(left, y, size, true, fmt.Sprintf("%-4s %-60s %8s", "No.", "Nama Barang", "Qty"))
+	doc.TextRight(right, y, size, true, "Harga Jual")
+	advance(1)
+	rule()
+
+	// lines are shown without PPN; the last takes the rounding so they add up to the tax base
+	var sold money.Amount
+	for i, d := range transaction.Details {
+		price := taxBase(d.Subtotal, inv.TaxRate)
+		if i == len(transaction.Details)-1 {
+			price = inv.TaxBase - sold
+		}
+		sold += price
+
+		for j, line := range wrapText(d.ProductName, 60) {
+			if j == 0 {
+				doc.Text(left, y, size, false, fmt.Sprintf("%-4d %-60s %8s", i+1, line, s.locale.Number(d.Quantity)))
+				doc.TextRight(right, y, size, false, s.locale.Money(price))
+			} else {
+				doc.Text(left, y, size, false, "     "+line)
+			}
+			advance(1)
+		}
+	}
+	rule()
+
+	amount("Harga Jual / Penggantian", false, inv.TaxBase)
+	amount("Dasar Pengenaan Pajak", false, inv.TaxBase)
+	amount(fmt.Sprintf("Total PPN (%d%%)", inv.TaxRate), false, inv.TaxAmount)
+	amount("Jumlah", true, inv.TotalAmount)
+	rule()
+
+	advance(1)
+	doc.TextRight(right, y, size, false, s.locale.Date(strings.SplitN(inv.IssuedAt, " ", 2)[0]))
+	advance(4)
+	doc.TextRight(right, y, size, true, seller.Name)
+	return doc
+}
+
+// taxBase is the part of total, which includes PPN at rate percent, before
+// tax, rounded to the nearest minor unit
+func taxBase[N quantity](total N, rate int) N {
+	return (total*200 + 100 + N(rate)) / (2 * (100 + N(rate)))
+}
+
+// NormalizeNPWP returns the digits of npwp, written with or without its dots
+// and dash: the 15 digits of an NPWP or the 16 of an NIK used as one
+func NormalizeNPWP(npwp string) (string, error) {
+	var digits strings.Builder
+	for _, c := range strings.TrimSpace(npwp) {
+		switch {
+		case c >= '0' && c <= '9':
+			digits.WriteRune(c)
+		case c == '.' || c == '-' || c == ' ':
+		default:
+			return "", ErrInvalidNPWP
+		}
+	}
+	if digits.Len() != 15 && digits.Len() != 16 {
+		return "", ErrInvalidNPWP
+	}
+	return digits.String(), nil
+}
+
+// FormatNPWP writes a 15 digit NPWP in its usual form, 01.234.567.8-901.000;
+// a 16 digit one is written as is
+func FormatNPWP(npwp string) string {
+	if len(npwp) != 15 {
+		return npwp
+	}
+	return npwp[0:2] + "." + npwp[2:5] + "." + npwp[5:8] + "." + npwp[8:9] + "-" + npwp[9:12] + "." + npwp[12:15]
+}
+
+// wrapText breaks s into lines of at most width characters, at spaces where it can
+func wrapText(s string, width int) []string {
+	lines := []string{}
+	line := ""
+	for _, word := range strings.Fields(s) {
+		for len([]rune(word)) > width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			lines = append(lines, string([]rune(word)[:width]))
+			word = string([]rune(word)[width:])
+		}
+		switch {
+		case line == "":
+			line = word
+		case len([]rune(line))+1+len([]rune(word)) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/session"
+)
+
+var (
+	ErrInvalidPIN              = errors.New("pin must be 4 to 6 digits")
+	ErrWrongPIN                = errors.New("username or PIN is incorrect")
+	ErrWrongPassword           = errors.New("username or password is incorrect")
+	ErrEmailNotVerified        = errors.New("confirm the account's email address before signing in with its password")
+	ErrNoTerminalSession       = errors.New("no cashier is signed in with this session, switch in with a PIN")
+	ErrTerminalSessionRequired = errors.New("X-Terminal-Session header is required")
+)
+
+var pinPattern = regexp.MustCompile(`^[0-9]{4,6}$`)
+
+// session store key prefixes of terminal sessions, of the session each
+// terminal is in, of the count of wrong PINs and passwords of each account
+// and of the accounts locked out
+const (
+	terminalSessionPrefix = "terminal_session:"
+	terminalActivePrefix  = "terminal_active:"
+	pinFailurePrefix      = "pin_failures:"
+	pinLockPrefix         = "pin_locked:"
+)
+
+// PINLockedError refuses a PIN or password of an account locked out after too
+// many wrong ones
+type PINLockedError struct {
+	Until time.Time
+}
+
+func (e *PINLockedError) Error() string {
+	return fmt.Sprintf("too many failed sign-ins, the account is locked until %s", e.Until.Format("15:04:05"))
+}
+
+type pinLock struct {
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// TerminalSessionService switches the cashier of a terminal with a PIN, or
+// signs an admin in with a password. A terminal is in one session at a time,
+// kept in the session store for ttl; maxAttempts wrong PINs or passwords in a
+// row lock the account out of every terminal for lockout.
+type TerminalSessionService struct {
+	users       *repositories.AdminUserRepository
+	store       session.Store
+	ttl         time.Duration
+	maxAttempts int
+	lockout     time.Duration
+}
+
+func NewTerminalSessionService(users *repositories.AdminUserRepository, store session.Store, ttl time.Duration, maxAttempts int, lockout time.Duration) *TerminalSessionService {
+	return &TerminalSessionService{users: users, store: store, ttl: ttl, maxAttempts: maxAttempts, lockout: lockout}
+}
+
+// SetPIN gives an account the PIN it switches into terminals with
+func (s *TerminalSessionService) SetPIN(userID int, pin string) error {
+	if !pinPattern.MatchString(pin) {
+		return ErrInvalidPIN
+	}
+	hash, err := hashPassword(pin)
+	if err != nil {
+		return err
+	}
+	return s.users.SetPIN(userID, hash)
+}
+
+// Start switches a terminal to the cashier whose PIN it is, ending the
+// session the terminal was in
+func (s *TerminalSessionService) Start(ctx context.Context, req models.StartTerminalSessionRequest) (*models.TerminalSession, error) {
+	req.TerminalID = strings.TrimSpace(req.TerminalID)
+	if req.TerminalID == "" {
+		return nil, ErrTerminalRequired
+	}
+	admin, err := s.VerifyPIN(ctx, req.Username, req.PIN)
+	if err != nil {
+		return nil, err
+	}
+	return s.open(ctx, req.TerminalID, admin)
+}
+
+// SignIn starts a session on a terminal with the password of an account,
+// the one set at registration or through a reset link. An account with an
+// email must have confirmed it first, as that is where a reset link goes.
+func (s *TerminalSessionService) SignIn(ctx context.Context, req models.PasswordSignInRequest) (*models.TerminalSession, error) {
+	req.TerminalID = strings.TrimSpace(req.TerminalID)
+	if req.TerminalID == "" {
+		return nil, ErrTerminalRequired
+	}
+
+	admin, passwordHash, err := s.users.GetPassword(strings.ToLower(strings.TrimSpace(req.Username)))
+	if err == sql.ErrNoRows {
+		return nil, ErrWrongPassword
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	lock, err := s.lock(ctx, admin.ID)
+	if err != nil {
+		return nil, err
+	}
+	if now.Before(lock.LockedUntil) {
+		return nil, &PINLockedError{Until: lock.LockedUntil}
+	}
+	if !checkPassword(passwordHash, req.Password) {
+		return nil, s.recordFailure(ctx, admin.ID, now, ErrWrongPassword)
+	}
+	if err := s.store.Delete(ctx, pinKey(pinFailurePrefix, admin.ID)); err != nil && err != session.ErrNotFound {
+		return nil, err
+	}
+	if admin.Email != "" && !admin.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+	return s.open(ctx, req.TerminalID, &admin)
+}
+
+// open starts a session of admin on a terminal, ending the one it was in
+func (s *TerminalSessionService) open(ctx context.Context, terminalID string, admin *models.AdminUser) (*models.TerminalSession, error) {
+	now := time.Now()
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	ts := &models.TerminalSession{
+		Token:      hex.EncodeToString(token),
+		TerminalID: terminalID,
+		UserID:     admin.ID,
+		Username:   admin.Username,
+		Role:       admin.Role,
+		StartedAt:  now.Format("2006-01-02 15:04:05"),
+		ExpiresAt:  now.Add(s.ttl).Format("2006-01-02 15:04:05"),
+	}
+
+	if previous, err := s.store.Get(ctx, terminalActivePrefix+ts.TerminalID); err == nil {
+		if err := s.store.Delete(ctx, terminalSessionPrefix+string(previous)); err != nil && err != session.ErrNotFound {
+			return nil, err
+		}
+	} else if err != session.ErrNotFound {
+		return nil, err
+	}
+
+	value, err := json.Marshal(ts)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Set(ctx, terminalSessionPrefix+ts.Token, value, s.ttl); err != nil {
+		return nil, err
+	}
+	if err := s.store.Set(ctx, terminalActivePrefix+ts.TerminalID, []byte(ts.Token), s.ttl); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// VerifyPIN returns the account whose PIN it is, e.g. of a manager approving
+// something at a terminal. Wrong PINs count towards the account's lockout as
+// they do when switching in.
+func (s *TerminalSessionService) VerifyPIN(ctx context.Context, username, pin string) (*models.AdminUser, error) {
+	if !pinPattern.MatchString(pin) {
+		return nil, ErrInvalidPIN
+	}
+
+	admin, pinHash, err := s.users.GetPIN(strings.ToLower(strings.TrimSpace(username)))
+	if err == sql.ErrNoRows || (err == nil && pinHash == "") {
+		return nil, ErrWrongPIN
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	lock, err := s.lock(ctx, admin.ID)
+	if err != nil {
+		return nil, err
+	}
+	if now.Before(lock.LockedUntil) {
+		return nil, &PINLockedError{Until: lock.LockedUntil}
+	}
+	if !checkPassword(pinHash, pin) {
+		return nil, s.recordFailure(ctx, admin.ID, now, ErrWrongPIN)
+	}
+	if err := s.store.Delete(ctx, pinKey(pinFailurePrefix, admin.ID)); err != nil && err != session.ErrNotFound {
+		return nil, err
+	}
+	return &admin, nil
+}
+
+// Current returns the session a token is for, without the token
+func (s *TerminalSessionService) Current(ctx context.Context, token string) (*models.TerminalSession, error) {
+	ts, err := s.get(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	ts.Token = ""
+	return ts, nil
+}
+
+// End signs the cashier of a session out of its terminal
+func (s *TerminalSessionService) End(ctx context.Context, token string) error {
+	ts, err := s.get(ctx, token)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Delete(ctx, terminalSessionPrefix+token); err != nil && err != session.ErrNotFound {
+		return err
+	}
+
+	// the terminal stays with a newer session started meanwhile
+	active, err := s.store.Get(ctx, terminalActivePrefix+ts.TerminalID)
+	if err == session.ErrNotFound || (err == nil && string(active) != token) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.store.Delete(ctx, terminalActivePrefix+ts.TerminalID); err != nil && err != session.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+func (s *TerminalSessionService) get(ctx context.Context, token string) (*models.TerminalSession, error) {
+	if token == "" {
+		return nil, ErrTerminalSessionRequired
+	}
+	value, err := s.store.Get(ctx, terminalSessionPrefix+token)
+	if err == session.ErrNotFound {
+		return nil, ErrNoTerminalSession
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ts models.TerminalSession
+	if err := json.Unmarshal(value, &ts); err != nil {
+		return nil, err
+	}
+	return &ts, nil
+}
+
+func (s *TerminalSessionService) lock(ctx context.Context, userID int) (pinLock, error) {
+	var lock pinLock
+	value, err := s.store.Get(ctx, pinKey(pinLockPrefix, userID))
+	if err == session.ErrNotFound {
+		return lock, nil
+	}
+	if err != nil {
+		return lock, err
+	}
+	if err := json.Unmarshal(value, &lock); err != nil {
+		return pinLock{}, err
+	}
+	return lock, nil
+}
+
+// recordFailure counts a wrong PIN or password and returns the error to
+// answer it with, wrong until the account is locked. The count is
+// incremented in the store, so wrong ones tried at once on several terminals
+// all count; it's forgotten after lockout without another one.
+func (s *TerminalSessionService) recordFailure(ctx context.Context, userID int, now time.Time, wrong error) error {
+	attempts, err := s.store.Incr(ctx, pinKey(pinFailurePrefix, userID), s.lockout)
+	if err != nil {
+		return err
+	}
+	if attempts < int64(s.maxAttempts) {
+		return wrong
+	}
+
+	lock := pinLock{LockedUntil: now.Add(s.lockout)}
+	value, err := json.Marshal(lock)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Set(ctx, pinKey(pinLockPrefix, userID), value, s.lockout); err != nil {
+		return err
+	}
+	if err := s.store.Delete(ctx, pinKey(pinFailurePrefix, userID)); err != nil && err != session.ErrNotFound {
+		return err
+	}
+	return &PINLockedError{Until: lock.LockedUntil}
+}
+
+func pinKey(prefix string, userID int) string {
+	return fmt.Sprintf("%s%d", prefix, userID)
+}
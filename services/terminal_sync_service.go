@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/repositories"
+)
+
+// maxSyncPush is how many sales a terminal may push in one batch
+const maxSyncPush = 500
+
+const (
+	SyncCreated   = "created"
+	SyncDuplicate = "duplicate"
+	SyncConflict  = "conflict"
+	SyncDeferred  = "deferred"
+	SyncRejected  = "rejected"
+)
+
+var (
+	ErrInvalidSyncCursor = errors.New("since must be a cursor returned by an earlier pull")
+	ErrSyncBatchSize     = errors.New("a batch holds 1 to 500 sales")
+)
+
+// TerminalSyncService is the central side of the sync API for POS terminals
+// that sell offline: they pull what changed in the catalog since their
+// cursor and push the sales they rang up meanwhile.
+type TerminalSyncService struct {
+	repo *repositories.SyncRepository
+}
+
+func NewTerminalSyncService(repo *repositories.SyncRepository) *TerminalSyncService {
+	return &TerminalSyncService{repo: repo}
+}
+
+// GetChanges returns the products and categories changed from since on, the
+// whole active catalog for 0
+func (s *TerminalSyncService) GetChanges(since int64) (models.CatalogChanges, error) {
+	if since < 0 {
+		return models.CatalogChanges{}, ErrInvalidSyncCursor
+	}
+	return s.repo.GetChanges(since)
+}
+
+// Push records a batch of offline sales, each on its own, and reports what
+// became of every one in order. A sale pushed again, because the terminal
+// never saw the result, is reported a duplicate and stored once. Sales before
+// an error are kept, so the terminal can push the whole batch again.
+func (s *TerminalSyncService) Push(req models.SyncPushRequest) ([]models.SyncPushResult, error) {
+	if len(req.Transactions) == 0 || len(req.Transactions) > maxSyncPush {
+		return nil, ErrSyncBatchSize
+	}
+
+	results := make([]models.SyncPushResult, 0, len(req.Transactions))
+	for _, st := range req.Transactions {
+		result := models.SyncPushResult{ReceiptNumber: strings.TrimSpace(st.ReceiptNumber)}
+
+		t, reason := syncTransaction(st)
+		if reason == "" {
+			err := s.repo.CostTransaction(&t)
+			if errors.Is(err, repositories.ErrProductNotFound) {
+				reason = err.Error()
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		if reason != "" {
+			result.Result = SyncRejected
+			result.Message = reason
+			results = append(results, result)
+			continue
+		}
+
+		id, pushed, err := s.repo.PushTransaction(t)
+		switch {
+		case err == repositories.ErrSyncConflict:
+			result.Result = SyncConflict
+			result.TransactionID = id
+			result.Message = err.Error()
+		case errors.Is(err, repositories.ErrPeriodClosed):
+			result.Result = SyncDeferred
+			result.Message = err.Error()
+		case err != nil:
+			return nil, err
+		case pushed:
+			result.Result = SyncCreated
+			result.TransactionID = id
+		default:
+			result.Result = SyncDuplicate
+			result.TransactionID = id
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// syncTransaction turns a pushed sale into one to record, or says why it
+// can't be; its lines and rounding must add up to its total
+func syncTransaction(st models.SyncTransaction) (t models.Transaction, reason string) {
+	t = models.Transaction{
+		ReceiptNumber:      strings.TrimSpace(st.ReceiptNumber),
+		TotalAmount:        st.TotalAmount,
+		RoundingAdjustment: st.RoundingAdjustment,
+	}
+	if t.ReceiptNumber == "" {
+		return t, "receipt_number is required"
+	}
+	status, err := checkoutStatus(models.CheckoutRequest{Status: st.Status})
+	if err != nil {
+		return t, err.Error()
+	}
+	t.Status = status
+	createdAt, err := time.ParseInLocation("2006-01-02 15:04:05", st.CreatedAt, time.Local)
+	if err != nil {
+		return t, "created_at must be in YYYY-MM-DD HH:MM:SS format"
+	}
+	t.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+	if len(st.Items) == 0 {
+		return t, "a sale needs at least one item"
+	}
+
+	var total money.Amount
+	for _, item := range st.Items {
+		if item.Quantity <= 0 || item.Subtotal < 0 || item.Discount < 0 {
+			return t, "items need a positive quantity and no negative amounts"
+		}
+		d := models.TransactionDetail{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			Subtotal:  item.Subtotal,
+		}
+		if item.PricingRuleID > 0 {
+			d.PricingRule = &models.AppliedPricingRule{ID: item.PricingRuleID, Discount: item.Discount}
+		}
+		t.Details = append(t.Details, d)
+		total += item.Subtotal
+	}
+	if total+t.RoundingAdjustment != t.TotalAmount {
+		return t, "total_amount does not match the items and rounding_adjustment"
+	}
+	return t, ""
+}
@@ -1,18 +1,397 @@
 package services
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
 	"kasir-api/models"
+	"kasir-api/money"
 	"kasir-api/repositories"
+	"kasir-api/tracing"
+)
+
+// maxReceiptAttempts bounds how many receipt numbers a checkout tries when
+// they are already taken, e.g. after the reset period or format changed
+const maxReceiptAttempts = 5
+
+var (
+	ErrOpenItemNotAllowed = errors.New("open items aren't allowed, set OPEN_ITEMS")
+	ErrOpenItemOverLimit  = errors.New("open item is priced above OPEN_ITEM_MAX_PRICE")
+	ErrInvalidOpenItem    = errors.New("a line needs either a product_id or a description and a positive price")
+	ErrInvalidOverride    = errors.New("a price override is on a product line and needs a reason and a positive price or discount")
+	ErrOverrideApproval   = errors.New("a price override needs the approval of a manager with their PIN")
 )
 
+// OpenItemPolicy is whether cashiers may ring up open items, lines with no
+// product sold at a price they enter, and the highest unit price they may
+// enter, unlimited when 0
+type OpenItemPolicy struct {
+	Allowed  bool
+	MaxPrice money.Amount
+}
+
 type TransactionService struct {
-	repo *repositories.TransactionRepository
+	repo         repositories.TransactionStore
+	productRepo  repositories.ProductStore
+	pricingRepo  repositories.PricingRuleStore
+	numbering    *ReceiptNumbering
+	currencies   *CurrencyService
+	contractRepo *repositories.PriceContractRepository
+	vouchers     *repositories.VoucherRepository
+	stores       *repositories.StoreRepository
+	openItems    OpenItemPolicy
+	rounding     money.Rounding
+	approvals    *PermissionService
+	guardrails   PriceGuardrails
 }
 
-func NewTransactionService(repo *repositories.TransactionRepository) *TransactionService {
-	return &TransactionService{repo: repo}
+// NewTransactionService takes payments through currencies and vouchers,
+// prices sales to customers with contractRepo and sales at a store with
+// stores; any may be nil, as in kiosk installs, where checkouts record
+// neither what they were paid with nor a customer, and stores is nil unless
+// multi-store is on. Open items are sold as openItems allows, and totals are rounded
+// by rounding.
+func NewTransactionService(repo repositories.TransactionStore, productRepo repositories.ProductStore, pricingRepo repositories.PricingRuleStore, numbering *ReceiptNumbering, currencies *CurrencyService, contractRepo *repositories.PriceContractRepository, vouchers *repositories.VoucherRepository, stores *repositories.StoreRepository, openItems OpenItemPolicy, rounding money.Rounding) *TransactionService {
+	return &TransactionService{repo: repo, productRepo: productRepo, pricingRepo: pricingRepo, numbering: numbering, currencies: currencies, contractRepo: contractRepo, vouchers: vouchers, stores: stores, openItems: openItems, rounding: rounding}
 }
 
-func (s *TransactionService) Checkout(items []models.CheckoutItem, useLock bool) (*models.Transaction, error) {
-	return s.repo.CreateTransaction(items)
+// WithApprovals lets checkouts and orders sell lines at a price override, or
+// priced past the guardrails, approved by the PIN of an account holding
+// PermPriceOverride; without it they are refused
+func (s *TransactionService) WithApprovals(approvals *PermissionService) *TransactionService {
+	s.approvals = approvals
+	return s
+}
+
+// WithGuardrails refuses checkout lines priced past guardrails, by pricing
+// rules, contracts or overrides alike, unless a manager approves them
+func (s *TransactionService) WithGuardrails(guardrails PriceGuardrails) *TransactionService {
+	s.guardrails = guardrails
+	return s
+}
+
+// Checkout sells items at their current prices, or those of the store they
+// are sold at, with the pricing rules applied, or at the contract prices of the customer attached, which replace both.
+// Quantities must be positive, so no line comes to less than nothing. Open
+// items are sold at their own price when the open item policy allows it, and
+// lines with a price override at it when req.Approval approves it, as it must
+// lines priced past the guardrails. A voucher, when given, pays what its balance allows; the payment, when given,
+// must cover the rest in the store currency or one it accepts. The sale starts
+// in the status requested, completed by default, its total rounded for cash
+// unless it is a draft, whose tab stays open.
+func (s *TransactionService) Checkout(ctx context.Context, req models.CheckoutRequest, useLock bool) (*models.Transaction, error) {
+	return s.CheckoutOrder(ctx, req, repositories.OrderNumber{})
+}
+
+// CheckoutOrder is Checkout for a restaurant order, called out by order
+func (s *TransactionService) CheckoutOrder(ctx context.Context, req models.CheckoutRequest, order repositories.OrderNumber) (transaction *models.Transaction, err error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.Checkout")
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	status, err := checkoutStatus(req)
+	if err != nil {
+		return nil, err
+	}
+
+	items, productIDs, err := s.checkItems(req.Items)
+	if err != nil {
+		return nil, err
+	}
+	approver, err := s.approve(ctx, items, req.Approval)
+	if err != nil {
+		return nil, err
+	}
+	price, err := s.pricer(req.CustomerID, req.StoreID, productIDs, approver)
+	if err != nil {
+		return nil, err
+	}
+
+	var tender repositories.Tender
+	if req.Payment != nil {
+		if s.currencies == nil {
+			return nil, ErrCurrencyNotAccepted
+		}
+		tender, err = s.currencies.Tender(*req.Payment)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if req.VoucherCode != "" {
+		if s.vouchers == nil {
+			return nil, repositories.ErrVoucherNotFound
+		}
+		tender = s.voucherTender(req.VoucherCode, tender)
+	}
+
+	// a number issued before the numbering changed is skipped, not reused
+	for attempt := 1; ; attempt++ {
+		receiptNumber, err := s.numbering.Next()
+		if err != nil {
+			return nil, err
+		}
+		transaction, err = s.repo.CreateTransaction(ctx, items, req.CustomerID, req.StoreID, receiptNumber, status, order, price, s.rounder(status), tender)
+		var approved bool
+		if approver, approved, err = s.approveRefused(ctx, err, approver, req.Approval); approved {
+			if price, err = s.pricer(req.CustomerID, req.StoreID, productIDs, approver); err != nil {
+				return nil, err
+			}
+			transaction, err = s.repo.CreateTransaction(ctx, items, req.CustomerID, req.StoreID, receiptNumber, status, order, price, s.rounder(status), tender)
+		}
+		if err == repositories.ErrDuplicateReceiptNumber && attempt < maxReceiptAttempts {
+			log.Println("Receipt number", receiptNumber, "is already used, drawing the next one")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	return transaction, nil
+}
+
+// AddItems adds items to the draft sale id, priced as a checkout of them
+// would be now for the sale's customer at the store it is made at, and
+// returns the sale with them. Price overrides and lines priced past the
+// guardrails need approval, as they do at checkout.
+func (s *TransactionService) AddItems(ctx context.Context, id int, items []models.CheckoutItem, approval *models.OverrideApproval) (*models.Transaction, error) {
+	transaction, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	items, productIDs, err := s.checkItems(items)
+	if err != nil {
+		return nil, err
+	}
+	approver, err := s.approve(ctx, items, approval)
+	if err != nil {
+		return nil, err
+	}
+	price, err := s.pricer(transaction.CustomerID, transaction.StoreID, productIDs, approver)
+	if err != nil {
+		return nil, err
+	}
+	err = s.repo.AppendItems(id, items, price)
+	approver, approved, err := s.approveRefused(ctx, err, approver, approval)
+	if approved {
+		if price, err = s.pricer(transaction.CustomerID, transaction.StoreID, productIDs, approver); err != nil {
+			return nil, err
+		}
+		err = s.repo.AppendItems(id, items, price)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+// pricer prices the lines of productIDs sold to customerID at storeID, at
+// the prices of the customer's contracts or with the pricing rules applied to
+// the prices of the store, where it overrides them; storeID 0 sells at the
+// products' own prices. Lines with a price override are sold at it, and lines
+// priced past the guardrails refused, unless approver approves them.
+func (s *TransactionService) pricer(customerID, storeID int, productIDs []int, approver *models.AdminUser) (repositories.LinePricer, error) {
+	rules, err := s.pricingRepo.GetActive(productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	storePrices := map[int]money.Amount{}
+	if storeID != 0 {
+		if s.stores == nil {
+			return nil, ErrMultiStoreOff
+		}
+		storePrices, err = s.stores.GetActive(storeID, productIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	contracts := map[int]models.AppliedPriceContract{}
+	if customerID != 0 {
+		if s.contractRepo == nil {
+			return nil, repositories.ErrCustomerNotFound
+		}
+		contracts, err = s.contractRepo.GetActive(customerID, productIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return func(productID int, unitPrice, costPrice money.Amount, quantity int, override *models.PriceOverride) repositories.LinePrice {
+		if price, ok := storePrices[productID]; ok {
+			unitPrice = price
+		}
+		var line repositories.LinePrice
+		if contract, ok := contracts[productID]; ok {
+			subtotal := contract.Price.Times(quantity)
+			// a contract may also price above the product price; that is no discount
+			if regular := unitPrice.Times(quantity); regular > subtotal {
+				contract.Discount = regular - subtotal
+			}
+			line = repositories.LinePrice{Subtotal: subtotal, Contract: &contract}
+		} else {
+			subtotal, rule := priceLine(unitPrice, quantity, rules[productID])
+			line = repositories.LinePrice{Subtotal: subtotal, Rule: rule}
+		}
+		if override != nil {
+			line = overrideLine(line.Subtotal, unitPrice, quantity, *override, approver)
+		}
+		if approver == nil {
+			line.Violation = s.guardrails.check(unitPrice, costPrice, quantity, line.Subtotal)
+		}
+		return line
+	}, nil
+}
+
+// overrideLine prices a line that came to subtotal at override instead, a
+// discount taking it down to nothing at most
+func overrideLine(subtotal, unitPrice money.Amount, quantity int, override models.PriceOverride, approver *models.AdminUser) repositories.LinePrice {
+	applied := &models.AppliedPriceOverride{OriginalSubtotal: subtotal, Price: override.Price, Reason: override.Reason}
+	if approver != nil {
+		applied.ApprovedByID, applied.ApprovedBy = approver.ID, approver.Username
+	}
+	if override.Price > 0 {
+		subtotal = applied.Price.Times(quantity)
+	}
+	applied.ExtraDiscount = override.Discount
+	if applied.ExtraDiscount > subtotal {
+		applied.ExtraDiscount = subtotal
+	}
+	subtotal -= applied.ExtraDiscount
+	if regular := unitPrice.Times(quantity); regular > subtotal {
+		applied.Discount = regular - subtotal
+	}
+	return repositories.LinePrice{Subtotal: subtotal, Override: applied}
+}
+
+// approve returns the account approving the price overrides of items with
+// approval, which they need, and nil when items have none: the PIN of an
+// approval sent along with lines needing none isn't checked, so it never
+// counts towards a lockout.
+func (s *TransactionService) approve(ctx context.Context, items []models.CheckoutItem, approval *models.OverrideApproval) (*models.AdminUser, error) {
+	for _, item := range items {
+		if item.Override == nil {
+			continue
+		}
+		if approval == nil || s.approvals == nil {
+			return nil, ErrOverrideApproval
+		}
+		return s.approvals.Approve(ctx, approval.Username, approval.PIN, PermPriceOverride)
+	}
+	return nil, nil
+}
+
+// approveRefused returns the account approving with approval the lines err
+// refuses as priced past the guardrails, when approver didn't approve the
+// sale already, and whether the sale is to be priced again with it. Those
+// lines are only known once priced, so the PIN is checked only then; any
+// other err is returned as is.
+func (s *TransactionService) approveRefused(ctx context.Context, err error, approver *models.AdminUser, approval *models.OverrideApproval) (*models.AdminUser, bool, error) {
+	var refused *repositories.PriceGuardrailError
+	if approver != nil || approval == nil || s.approvals == nil || !errors.As(err, &refused) {
+		return approver, false, err
+	}
+	approver, err = s.approvals.Approve(ctx, approval.Username, approval.PIN, PermPriceOverride)
+	if err != nil {
+		return nil, false, err
+	}
+	return approver, true, nil
+}
+
+// rounder returns the rounding of totals of sales in status, nil for none.
+// Drafts are left unrounded while items are still added; they are rounded
+// when billed, see TransactionStatusService.UpdateStatus.
+func (s *TransactionService) rounder(status string) repositories.Rounder {
+	if status == repositories.TransactionDraft {
+		return nil
+	}
+	return rounderOf(s.rounding)
+}
+
+// rounderOf returns the rounder of rounding, nil when it rounds nothing
+func rounderOf(rounding money.Rounding) repositories.Rounder {
+	if rounding.Mode == "" {
+		return nil
+	}
+	return func(total money.Amount) money.Amount {
+		return money.Amount(rounding.Round(int64(total)))
+	}
+}
+
+// voucherTender returns a tender paying with the voucher of code first and
+// with rest, which may be nil, for what its balance doesn't cover
+func (s *TransactionService) voucherTender(code string, rest repositories.Tender) repositories.Tender {
+	return func(tx *sql.Tx, total money.Amount) (*models.TransactionPayment, error) {
+		voucher, err := s.vouchers.Redeem(tx, code, total)
+		if err != nil {
+			return nil, err
+		}
+
+		due := total - voucher.Amount
+		payment := &models.TransactionPayment{}
+		if rest != nil {
+			payment, err = rest(tx, due)
+			if err != nil {
+				return nil, err
+			}
+		} else if due > 0 {
+			return nil, fmt.Errorf("%w: voucher %s pays %d of %d", ErrInsufficientPayment, voucher.Code, voucher.Amount, total)
+		}
+		payment.Voucher = &voucher
+		return payment, nil
+	}
+}
+
+// checkItems returns items with the descriptions of open items and the
+// reasons of price overrides trimmed, and the products sold. Every line needs
+// a positive quantity and either a product or a description and price, which
+// the open item policy must allow; only product lines may have an override.
+func (s *TransactionService) checkItems(items []models.CheckoutItem) ([]models.CheckoutItem, []int, error) {
+	checked := make([]models.CheckoutItem, len(items))
+	productIDs := make([]int, 0, len(items))
+	for i, item := range items {
+		if item.Quantity <= 0 {
+			return nil, nil, ErrInvalidAmount
+		}
+		item.Description = strings.TrimSpace(item.Description)
+		if item.Override != nil {
+			override := *item.Override
+			override.Reason = strings.TrimSpace(override.Reason)
+			if item.ProductID == 0 || override.Reason == "" || override.Price < 0 || override.Discount < 0 || override.Price+override.Discount == 0 {
+				return nil, nil, ErrInvalidOverride
+			}
+			item.Override = &override
+		}
+		checked[i] = item
+
+		if item.ProductID != 0 {
+			if item.Description != "" || item.Price != 0 {
+				return nil, nil, ErrInvalidOpenItem
+			}
+			productIDs = append(productIDs, item.ProductID)
+			continue
+		}
+		if item.Description == "" || item.Price <= 0 {
+			return nil, nil, ErrInvalidOpenItem
+		}
+		if !s.openItems.Allowed {
+			return nil, nil, ErrOpenItemNotAllowed
+		}
+		if s.openItems.MaxPrice > 0 && item.Price > s.openItems.MaxPrice {
+			return nil, nil, fmt.Errorf("%w: %q at %d, at most %d", ErrOpenItemOverLimit, item.Description, item.Price, s.openItems.MaxPrice)
+		}
+	}
+	return checked, productIDs, nil
 }
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/money"
+	"kasir-api/repositories"
+)
+
+var (
+	ErrInvalidTransactionStatus = errors.New("status must be one of draft, pending_payment, paid, completed")
+	ErrTransactionTransition    = errors.New("transaction can't move to that status from its current one")
+	ErrStatusReasonRequired     = errors.New("reason is required to void or refund a transaction")
+	ErrPaymentNotDue            = errors.New("a draft or pending_payment sale is paid later, leave out payment and voucher_code")
+)
+
+// transactionTransitions are the statuses a sale can be moved to from each
+// status. Voided and refunded are final; a sale paid for is refunded, not voided.
+var transactionTransitions = map[string][]string{
+	repositories.TransactionDraft:          {repositories.TransactionPendingPayment, repositories.TransactionPaid, repositories.TransactionVoided},
+	repositories.TransactionPendingPayment: {repositories.TransactionPaid, repositories.TransactionVoided},
+	repositories.TransactionPaid:           {repositories.TransactionCompleted, repositories.TransactionRefunded},
+	repositories.TransactionCompleted:      {repositories.TransactionRefunded},
+}
+
+// checkoutStatus returns the status a checkout starts in, completed unless
+// the request says otherwise
+func checkoutStatus(req models.CheckoutRequest) (string, error) {
+	switch req.Status {
+	case "":
+		return repositories.TransactionCompleted, nil
+	case repositories.TransactionDraft, repositories.TransactionPendingPayment:
+		if req.Payment != nil || req.VoucherCode != "" {
+			return "", ErrPaymentNotDue
+		}
+		return req.Status, nil
+	case repositories.TransactionPaid, repositories.TransactionCompleted:
+		return req.Status, nil
+	}
+	return "", ErrInvalidTransactionStatus
+}
+
+// TransactionStatusService moves sales through their statuses, keeping the
+// history of every move. Voiding or refunding a sale puts its stock back and
+// takes it out of the reports; billing a draft rounds its total by rounding.
+type TransactionStatusService struct {
+	repo     *repositories.TransactionRepository
+	rounding money.Rounding
+}
+
+func NewTransactionStatusService(repo *repositories.TransactionRepository, rounding money.Rounding) *TransactionStatusService {
+	return &TransactionStatusService{repo: repo, rounding: rounding}
+}
+
+// UpdateStatus moves a sale on to req.Status. A draft moved to
+// pending_payment or paid is billed: its total is rounded for cash then.
+func (s *TransactionStatusService) UpdateStatus(id int, req models.TransactionStatusRequest) (models.TransactionStatusEvent, error) {
+	status, err := s.repo.GetStatus(id)
+	if err != nil {
+		return models.TransactionStatusEvent{}, err
+	}
+	if !containsStatus(transactionTransitions[status], req.Status) {
+		return models.TransactionStatusEvent{}, ErrTransactionTransition
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" && (req.Status == repositories.TransactionVoided || req.Status == repositories.TransactionRefunded) {
+		return models.TransactionStatusEvent{}, ErrStatusReasonRequired
+	}
+
+	if err := s.repo.UpdateStatus(id, status, req.Status, reason, rounderOf(s.rounding)); err != nil {
+		return models.TransactionStatusEvent{}, err
+	}
+
+	event := models.TransactionStatusEvent{
+		TransactionID: id,
+		FromStatus:    status,
+		Status:        req.Status,
+		Reason:        reason,
+		CreatedAt:     time.Now().Format("2006-01-02 15:04:05"),
+	}
+	return event, nil
+}
+
+// GetHistory returns the statuses a sale went through, oldest first
+func (s *TransactionStatusService) GetHistory(id int) ([]models.TransactionStatusEvent, error) {
+	return s.repo.GetStatusHistory(id)
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+func TestTransactionTransitions(t *testing.T) {
+	statuses := []string{
+		repositories.TransactionDraft,
+		repositories.TransactionPendingPayment,
+		repositories.TransactionPaid,
+		repositories.TransactionCompleted,
+		repositories.TransactionVoided,
+		repositories.TransactionRefunded,
+	}
+	allowed := map[[2]string]bool{
+		{repositories.TransactionDraft, repositories.TransactionPendingPayment}:  true,
+		{repositories.TransactionDraft, repositories.TransactionPaid}:            true,
+		{repositories.TransactionDraft, repositories.TransactionVoided}:          true,
+		{repositories.TransactionPendingPayment, repositories.TransactionPaid}:   true,
+		{repositories.TransactionPendingPayment, repositories.TransactionVoided}: true,
+		{repositories.TransactionPaid, repositories.TransactionCompleted}:        true,
+		{repositories.TransactionPaid, repositories.TransactionRefunded}:         true,
+		{repositories.TransactionCompleted, repositories.TransactionRefunded}:    true,
+	}
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := allowed[[2]string{from, to}]
+			if got := containsStatus(transactionTransitions[from], to); got != want {
+				t.Errorf("%s to %s allowed = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
+
+func TestCheckoutStatus(t *testing.T) {
+	payment := &models.CheckoutPayment{Currency: "IDR", Amount: 10000}
+	tests := []struct {
+		req     models.CheckoutRequest
+		want    string
+		wantErr error
+	}{
+		{models.CheckoutRequest{}, repositories.TransactionCompleted, nil},
+		{models.CheckoutRequest{Status: repositories.TransactionPaid}, repositories.TransactionPaid, nil},
+		{models.CheckoutRequest{Status: repositories.TransactionCompleted, Payment: payment}, repositories.TransactionCompleted, nil},
+		{models.CheckoutRequest{Status: repositories.TransactionDraft}, repositories.TransactionDraft, nil},
+		{models.CheckoutRequest{Status: repositories.TransactionPendingPayment}, repositories.TransactionPendingPayment, nil},
+		{models.CheckoutRequest{Status: repositories.TransactionDraft, Payment: payment}, "", ErrPaymentNotDue},
+		{models.CheckoutRequest{Status: repositories.TransactionPendingPayment, VoucherCode: "GIFT"}, "", ErrPaymentNotDue},
+		{models.CheckoutRequest{Status: repositories.TransactionVoided}, "", ErrInvalidTransactionStatus},
+		{models.CheckoutRequest{Status: "bogus"}, "", ErrInvalidTransactionStatus},
+	}
+	for _, tt := range tests {
+		got, err := checkoutStatus(tt.req)
+		if got != tt.want || err != tt.wantErr {
+			t.Errorf("checkoutStatus(%q) = %q, %v, want %q, %v", tt.req.Status, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var (
+	ErrInvalidVoucherAmount = errors.New("voucher amount must be positive")
+	ErrInvalidVoucherCode   = errors.New("voucher code must be 4 to 32 letters, digits or dashes")
+	ErrInvalidVoucherExpiry = errors.New("expires_on must be a date as YYYY-MM-DD, not in the past")
+)
+
+var voucherCodePattern = regexp.MustCompile(`^[A-Z0-9-]{4,32}$`)
+
+// voucherCodeAlphabet leaves out characters read alike, such as 0 and O
+const voucherCodeAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// VoucherService issues gift cards and vouchers, which pay for sales at
+// checkout in one go or in part until their balance runs out
+type VoucherService struct {
+	repo *repositories.VoucherRepository
+}
+
+func NewVoucherService(repo *repositories.VoucherRepository) *VoucherService {
+	return &VoucherService{repo: repo}
+}
+
+func (s *VoucherService) GetAll() ([]models.Voucher, error) {
+	return s.repo.GetAll()
+}
+
+func (s *VoucherService) GetByCode(code string) (models.Voucher, error) {
+	return s.repo.GetByCode(strings.TrimSpace(code))
+}
+
+// Issue creates a voucher worth req.Amount with the code given, or a
+// generated one such as GV-7K3M-Q9XP
+func (s *VoucherService) Issue(req models.VoucherRequest) (models.Voucher, error) {
+	if req.Amount <= 0 {
+		return models.Voucher{}, ErrInvalidVoucherAmount
+	}
+
+	var expiresOn time.Time
+	if req.ExpiresOn != "" {
+		var err error
+		expiresOn, err = time.ParseInLocation("2006-01-02", req.ExpiresOn, time.Local)
+		y, m, d := time.Now().Date()
+		if err != nil || expiresOn.Before(time.Date(y, m, d, 0, 0, 0, 0, time.Local)) {
+			return models.Voucher{}, ErrInvalidVoucherExpiry
+		}
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if code == "" {
+		var err error
+		if code, err = newVoucherCode(); err != nil {
+			return models.Voucher{}, err
+		}
+	}
+	if !voucherCodePattern.MatchString(code) {
+		return models.Voucher{}, ErrInvalidVoucherCode
+	}
+
+	return s.repo.Create(code, req.Amount, expiresOn, strings.TrimSpace(req.Note))
+}
+
+// newVoucherCode returns a random code of two groups of four after GV-
+func newVoucherCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := []byte("GV-")
+	for i, c := range b {
+		if i == 4 {
+			code = append(code, '-')
+		}
+		code = append(code, voucherCodeAlphabet[int(c)%len(voucherCodeAlphabet)])
+	}
+	return string(code), nil
+}
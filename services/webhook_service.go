@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"kasir-api/events"
+	"kasir-api/jobs"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/webhook"
+)
+
+var (
+	ErrInvalidWebhookURL = errors.New("url must be an absolute http or https URL")
+	ErrInvalidEvent      = errors.New("events must be one or more of transaction.created, product.low_stock, refund.issued, payment_link.paid")
+)
+
+const JobWebhookDelivery = "webhook.delivery"
+
+type webhookJob struct {
+	DeliveryID int `json:"delivery_id"`
+}
+
+type WebhookService struct {
+	repo   *repositories.WebhookRepository
+	client *webhook.Client
+	runner *jobs.Runner
+}
+
+// webhookEvents are the webhook events the domain events of the bus are
+// delivered to integrators as
+var webhookEvents = map[string]string{
+	events.TransactionCompleted: webhook.EventTransactionCreated,
+	events.ProductLowStock:      webhook.EventProductLowStock,
+	events.RefundIssued:         webhook.EventRefundIssued,
+	events.PaymentLinkPaid:      webhook.EventPaymentLinkPaid,
+}
+
+// NewWebhookService delivers the events of bus to the webhooks subscribed to them
+func NewWebhookService(repo *repositories.WebhookRepository, runner *jobs.Runner, bus *events.Bus) *WebhookService {
+	s := &WebhookService{repo: repo, client: webhook.NewClient(), runner: runner}
+	runner.Register(JobWebhookDelivery, s.deliver)
+	bus.Subscribe("webhooks", s.handle, events.TransactionCompleted, events.ProductLowStock, events.RefundIssued, events.PaymentLinkPaid)
+	return s
+}
+
+func (s *WebhookService) GetAll() ([]models.Webhook, error) {
+	return s.repo.GetAll()
+}
+
+func (s *WebhookService) GetByID(id int) (models.Webhook, error) {
+	return s.repo.GetByID(id)
+}
+
+// Create registers a webhook, generating a signing secret when none is given
+func (s *WebhookService) Create(wh models.Webhook) (models.Webhook, error) {
+	if err := validateWebhook(wh); err != nil {
+		return models.Webhook{}, err
+	}
+
+	if wh.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return models.Webhook{}, err
+		}
+		wh.Secret = hex.EncodeToString(secret)
+	}
+	wh.Active = true
+
+	return s.repo.Create(wh)
+}
+
+func (s *WebhookService) Update(wh models.Webhook) (models.Webhook, error) {
+	if err := validateWebhook(wh); err != nil {
+		return models.Webhook{}, err
+	}
+	return s.repo.Update(wh)
+}
+
+func (s *WebhookService) Delete(id int) error {
+	return s.repo.Delete(id)
+}
+
+func (s *WebhookService) GetDeliveries(webhookID, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := s.repo.GetByID(webhookID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDeliveries(webhookID, limit)
+}
+
+// Publish queues event for every active webhook subscribed to it. Each
+// delivery is logged in webhook_delivery before it is sent.
+func (s *WebhookService) Publish(event string, data interface{}) error {
+	webhooks, err := s.repo.GetActiveByEvent(event)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	payload, err := json.Marshal(models.WebhookEvent{
+		Event:      event,
+		OccurredAt: time.Now().Format(time.RFC3339),
+		Data:       data,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, wh := range webhooks {
+		deliveryID, err := s.repo.CreateDelivery(wh.ID, event, payload)
+		if err != nil {
+			return err
+		}
+
+		if _, err := s.runner.Enqueue(JobWebhookDelivery, webhookJob{DeliveryID: deliveryID}); err != nil {
+			s.recordResult(deliveryID, "failed", 0, 0, err)
+		}
+	}
+	return nil
+}
+
+// handle is the bus Handler of the webhooks; a low stock event is delivered
+// as one product.low_stock per product
+func (s *WebhookService) handle(e events.Event) error {
+	if products, ok := e.Payload.([]models.Product); ok {
+		for _, p := range products {
+			if err := s.Publish(webhookEvents[e.Name], p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return s.Publish(webhookEvents[e.Name], e.Payload)
+}
+
+// deliver is the job handler of JobWebhookDelivery; failed attempts are
+// retried by the job runner with exponential backoff
+func (s *WebhookService) deliver(ctx context.Context, job models.Job) error {
+	var wj webhookJob
+	if err := json.Unmarshal(job.Payload, &wj); err != nil {
+		return err
+	}
+
+	delivery, err := s.repo.GetDelivery(wj.DeliveryID)
+	if err != nil {
+		return err
+	}
+
+	wh, err := s.repo.GetByID(delivery.WebhookID)
+	if err == sql.ErrNoRows {
+		// deleted since the event was published, nothing left to deliver to
+		s.recordResult(delivery.ID, "failed", job.Attempts, 0, errors.New("webhook was deleted"))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	statusCode, err := s.client.Post(webhook.Delivery{
+		ID:      delivery.ID,
+		URL:     wh.URL,
+		Secret:  wh.Secret,
+		Event:   delivery.Event,
+		Payload: delivery.Payload,
+	})
+	switch {
+	case err == nil:
+		s.recordResult(delivery.ID, "delivered", job.Attempts, statusCode, nil)
+	case job.Attempts >= job.MaxAttempts:
+		s.recordResult(delivery.ID, "failed", job.Attempts, statusCode, err)
+	default:
+		s.recordResult(delivery.ID, "pending", job.Attempts, statusCode, err)
+	}
+	return err
+}
+
+func (s *WebhookService) recordResult(deliveryID int, status string, attempts, statusCode int, err error) {
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+
+	if updateErr := s.repo.UpdateDelivery(deliveryID, status, attempts, statusCode, errMsg); updateErr != nil {
+		log.Println("Error updating webhook delivery status:", updateErr)
+	}
+}
+
+func validateWebhook(wh models.Webhook) error {
+	u, err := url.Parse(wh.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+
+	if len(wh.Events) == 0 {
+		return ErrInvalidEvent
+	}
+	for _, event := range wh.Events {
+		if !isWebhookEvent(event) {
+			return ErrInvalidEvent
+		}
+	}
+	return nil
+}
+
+func isWebhookEvent(event string) bool {
+	for _, e := range webhook.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
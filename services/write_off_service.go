@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var (
+	ErrInvalidWriteOffReason = errors.New("reason must be one of damaged, expired, supplier_return")
+	ErrSupplierRequired      = errors.New("supplier_id is required for supplier_return")
+)
+
+var writeOffReasons = map[string]bool{
+	repositories.MovementDamaged:        true,
+	repositories.MovementExpired:        true,
+	repositories.MovementSupplierReturn: true,
+}
+
+type WriteOffService struct {
+	repo *repositories.WriteOffRepository
+}
+
+func NewWriteOffService(repo *repositories.WriteOffRepository) *WriteOffService {
+	return &WriteOffService{repo: repo}
+}
+
+// Create records a write-off, taking the quantity out of stock
+func (s *WriteOffService) Create(req models.WriteOffRequest) (models.WriteOff, error) {
+	if !writeOffReasons[req.Reason] {
+		return models.WriteOff{}, ErrInvalidWriteOffReason
+	}
+	if req.Quantity <= 0 || req.SupplierID < 0 {
+		return models.WriteOff{}, ErrInvalidAmount
+	}
+	if req.Reason == repositories.MovementSupplierReturn && req.SupplierID == 0 {
+		return models.WriteOff{}, ErrSupplierRequired
+	}
+
+	id, err := s.repo.Create(req)
+	if err != nil {
+		return models.WriteOff{}, err
+	}
+	return s.repo.GetByID(id)
+}
+
+func (s *WriteOffService) GetAll(reason, dateFrom, dateTo string) ([]models.WriteOff, error) {
+	if reason != "" && !writeOffReasons[reason] {
+		return nil, ErrInvalidWriteOffReason
+	}
+	return s.repo.GetAll(reason, dateFrom, dateTo)
+}
+
+func (s *WriteOffService) GetByID(id int) (models.WriteOff, error) {
+	return s.repo.GetByID(id)
+}
@@ -0,0 +1,225 @@
+package session
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// probeInterval is how often an unreachable primary store is checked again
+const probeInterval = 5 * time.Second
+
+// pinger is a store that can tell whether its backend is reachable
+type pinger interface {
+	Store
+	Ping(ctx context.Context) error
+}
+
+// FallbackStore serves from a shared primary store and switches to an
+// in-process MemoryStore while the primary is unreachable, so a Redis outage
+// only stops held carts from being shared between instances. Entries written
+// during the outage are copied back to the primary once it recovers.
+type FallbackStore struct {
+	primary  pinger
+	fallback *MemoryStore
+
+	mu       sync.RWMutex
+	degraded bool
+	since    time.Time
+	lastErr  error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewFallbackStore starts in degraded mode when primary can't be reached
+func NewFallbackStore(primary pinger) *FallbackStore {
+	s := &FallbackStore{
+		primary:  primary,
+		fallback: NewMemoryStore(),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := primary.Ping(ctx); err != nil {
+		s.degrade(err)
+	}
+
+	go s.probe()
+	return s
+}
+
+// current returns the store to use and whether it is the primary
+func (s *FallbackStore) current() (Store, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.degraded {
+		return s.fallback, false
+	}
+	return s.primary, true
+}
+
+func (s *FallbackStore) degrade(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err
+	if s.degraded {
+		return
+	}
+	s.degraded = true
+	s.since = time.Now()
+	log.Printf("Session store unreachable, falling back to in-process store: %v", err)
+}
+
+// failed reports whether err means the primary is unreachable, switching to
+// the fallback if so
+func (s *FallbackStore) failed(err error) bool {
+	if err == nil || err == ErrNotFound {
+		return false
+	}
+	s.degrade(err)
+	return true
+}
+
+// probe pings the primary while degraded and switches back when it answers
+func (s *FallbackStore) probe() {
+	defer close(s.done)
+	ticker := time.NewTicker(probeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		if _, primary := s.current(); primary {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := s.primary.Ping(ctx)
+		if err == nil {
+			err = s.restore(ctx)
+		}
+		cancel()
+		if err != nil {
+			s.degrade(err)
+		}
+	}
+}
+
+// restore copies what was written during the outage to the primary and
+// switches back to it
+func (s *FallbackStore) restore(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fallback.mu.Lock()
+	defer s.fallback.mu.Unlock()
+
+	now := time.Now()
+	for key := range s.fallback.entries {
+		entry, ok := s.fallback.lookup(key, now)
+		if !ok {
+			continue
+		}
+		var ttl time.Duration
+		if !entry.expiresAt.IsZero() {
+			ttl = entry.expiresAt.Sub(now)
+		}
+		if err := s.primary.Set(ctx, key, entry.value, ttl); err != nil {
+			return err
+		}
+		delete(s.fallback.entries, key)
+	}
+
+	log.Printf("Session store reachable again after %s, leaving degraded mode", now.Sub(s.since).Round(time.Second))
+	s.degraded = false
+	s.since = time.Time{}
+	s.lastErr = nil
+	return nil
+}
+
+func (s *FallbackStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if store, primary := s.current(); primary {
+		value, err := store.Get(ctx, key)
+		if !s.failed(err) {
+			return value, err
+		}
+	}
+	return s.fallback.Get(ctx, key)
+}
+
+func (s *FallbackStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if store, primary := s.current(); primary {
+		err := store.Set(ctx, key, value, ttl)
+		if !s.failed(err) {
+			return err
+		}
+	}
+	return s.fallback.Set(ctx, key, value, ttl)
+}
+
+func (s *FallbackStore) Take(ctx context.Context, key string) ([]byte, error) {
+	if store, primary := s.current(); primary {
+		value, err := store.Take(ctx, key)
+		if !s.failed(err) {
+			return value, err
+		}
+	}
+	return s.fallback.Take(ctx, key)
+}
+
+func (s *FallbackStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if store, primary := s.current(); primary {
+		count, err := store.Incr(ctx, key, ttl)
+		if !s.failed(err) {
+			return count, err
+		}
+	}
+	return s.fallback.Incr(ctx, key, ttl)
+}
+
+func (s *FallbackStore) Delete(ctx context.Context, key string) error {
+	if store, primary := s.current(); primary {
+		err := store.Delete(ctx, key)
+		if !s.failed(err) {
+			return err
+		}
+	}
+	return s.fallback.Delete(ctx, key)
+}
+
+func (s *FallbackStore) Keys(ctx context.Context, prefix string) ([]string, error) {
+	if store, primary := s.current(); primary {
+		keys, err := store.Keys(ctx, prefix)
+		if !s.failed(err) {
+			return keys, err
+		}
+	}
+	return s.fallback.Keys(ctx, prefix)
+}
+
+func (s *FallbackStore) Status() Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status := s.primary.Status()
+	if s.degraded {
+		status.Degraded = true
+		status.Since = s.since.Format("2006-01-02 15:04:05")
+		status.Error = s.lastErr.Error()
+	}
+	return status
+}
+
+func (s *FallbackStore) Close() error {
+	close(s.stop)
+	<-s.done
+	return s.primary.Close()
+}
@@ -0,0 +1,100 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces our keys in a Redis server shared with other apps
+const keyPrefix = "kasir:"
+
+// RedisStore shares state between API instances behind a load balancer
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore creates a client for a redis:// or rediss:// URL; it does not
+// wait for the server to be reachable
+func NewRedisStore(url string) (*RedisStore, error) {
+	if url == "" {
+		return nil, fmt.Errorf("REDIS_URL is required for the redis session driver")
+	}
+
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing REDIS_URL: %v", err)
+	}
+
+	return &RedisStore{client: redis.NewClient(opts)}, nil
+}
+
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := s.client.Get(ctx, keyPrefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, keyPrefix+key, value, ttl).Err()
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string) ([]byte, error) {
+	value, err := s.client.GetDel(ctx, keyPrefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+func (s *RedisStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, keyPrefix+key)
+		if ttl > 0 {
+			pipe.Expire(ctx, keyPrefix+key, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	deleted, err := s.client.Del(ctx, keyPrefix+key).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *RedisStore) Keys(ctx context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	iter := s.client.Scan(ctx, 0, keyPrefix+prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, strings.TrimPrefix(iter.Val(), keyPrefix))
+	}
+	return keys, iter.Err()
+}
+
+func (s *RedisStore) Status() Status {
+	return Status{Driver: "redis"}
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,55 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is returned when a key does not exist or has expired
+var ErrNotFound = errors.New("session key not found")
+
+// Store keeps short-lived terminal state, such as held carts, that has to be
+// shared by every API instance serving the same terminals
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Take returns the value and deletes the key atomically, so only one caller gets it
+	Take(ctx context.Context, key string) ([]byte, error)
+	// Incr adds one to the counter at key atomically and returns the new
+	// count; the counter expires ttl after its last increment
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	Delete(ctx context.Context, key string) error
+	Keys(ctx context.Context, prefix string) ([]string, error)
+	Status() Status
+	Close() error
+}
+
+// Status describes the backend a store is serving from, for readiness checks.
+// Degraded is set while a shared store is unreachable and state is kept in
+// the process instead.
+type Status struct {
+	Driver   string `json:"driver"`
+	Degraded bool   `json:"degraded"`
+	Since    string `json:"since,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Open creates the store for driver: "memory" (default) keeps state in the
+// process, "redis" shares it through the Redis server at redisURL and falls
+// back to the process while Redis is unreachable
+func Open(driver, redisURL string) (Store, error) {
+	switch driver {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "redis":
+		store, err := NewRedisStore(redisURL)
+		if err != nil {
+			return nil, err
+		}
+		return NewFallbackStore(store), nil
+	default:
+		return nil, fmt.Errorf("unknown session driver %q", driver)
+	}
+}
@@ -0,0 +1,250 @@
+package tracing
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+)
+
+// WrapConnector traces the statements run on the connections of c as
+// client spans of the span of their context; system names the database, as
+// OpenTelemetry's db.system, e.g. "postgresql". Statements run without a
+// context, as most repositories run them, are traced inside a database
+// transaction begun with one, and left out otherwise.
+func WrapConnector(c driver.Connector, system string) driver.Connector {
+	return &connector{Connector: c, system: system}
+}
+
+type connector struct {
+	driver.Connector
+	system string
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: dc, system: c.system}, nil
+}
+
+// conn is a traced connection; txCtx is the context of the database
+// transaction open on it, which statements run without one are traced in
+type conn struct {
+	driver.Conn
+	system string
+	txCtx  context.Context
+}
+
+// startStatement starts the span of query, nil when neither ctx nor the
+// open transaction is traced
+func (c *conn) startStatement(ctx context.Context, query string) *Span {
+	if SpanFromContext(ctx) == nil {
+		if c.txCtx == nil || SpanFromContext(c.txCtx) == nil {
+			return nil
+		}
+		ctx = c.txCtx
+	}
+
+	operation, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	_, span := start(ctx, strings.ToUpper(operation), KindClient)
+	span.SetAttribute("db.system", c.system)
+	span.SetAttribute("db.statement", query)
+	return span
+}
+
+// endStatement ends the span of a statement with its error; driver.ErrSkip
+// only means the statement is retried another way
+func endStatement(span *Span, err error) {
+	if err != nil && !errors.Is(err, driver.ErrSkip) {
+		span.SetError(err)
+	}
+	span.End()
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var (
+		ds  driver.Stmt
+		err error
+	)
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		ds, err = p.PrepareContext(ctx, query)
+	} else {
+		ds, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: ds, conn: c, query: query}, nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	b, ok := c.Conn.(driver.ConnBeginTx)
+	if !ok {
+		return nil, errors.New("tracing: driver doesn't support BeginTx")
+	}
+	dtx, err := b.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.txCtx = ctx
+	return &tx{Tx: dtx, conn: c}, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	span := c.startStatement(ctx, query)
+	rows, err := q.QueryContext(ctx, query, args)
+	endStatement(span, err)
+	return rows, err
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	span := c.startStatement(ctx, query)
+	result, err := e.ExecContext(ctx, query, args)
+	endStatement(span, err)
+	return result, err
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	c.txCtx = nil
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// tx stops tracing statements in the transaction's context once it ends
+type tx struct {
+	driver.Tx
+	conn *conn
+}
+
+func (t *tx) Commit() error {
+	span := t.conn.startStatement(context.Background(), "COMMIT")
+	err := t.Tx.Commit()
+	endStatement(span, err)
+	t.conn.txCtx = nil
+	return err
+}
+
+func (t *tx) Rollback() error {
+	span := t.conn.startStatement(context.Background(), "ROLLBACK")
+	err := t.Tx.Rollback()
+	endStatement(span, err)
+	t.conn.txCtx = nil
+	return err
+}
+
+type stmt struct {
+	driver.Stmt
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	span := s.conn.startStatement(ctx, s.query)
+	var (
+		result driver.Result
+		err    error
+	)
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = e.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = plainValues(args); err == nil {
+			result, err = s.Stmt.Exec(values)
+		}
+	}
+	endStatement(span, err)
+	return result, err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	span := s.conn.startStatement(ctx, s.query)
+	var (
+		rows driver.Rows
+		err  error
+	)
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = plainValues(args); err == nil {
+			rows, err = s.Stmt.Query(values)
+		}
+	}
+	endStatement(span, err)
+	return rows, err
+}
+
+func (s *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return s.conn.CheckNamedValue(nv)
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+func plainValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, errors.New("tracing: driver doesn't support named arguments")
+		}
+		values[i] = a.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,238 @@
+// Package tracing records OpenTelemetry traces of requests: spans of the
+// handlers, services and SQL statements serving them, continued from the
+// W3C traceparent header of the caller and exported over OTLP/HTTP, e.g. to
+// Jaeger. Without a tracer set, starting a span costs nothing.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SpanKind is what a span's operation is to the trace, as in OTLP
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// spanContext identifies a span across services
+type spanContext struct {
+	traceID [16]byte
+	spanID  [8]byte
+	sampled bool
+}
+
+// Tracer samples the traces started here and hands their finished spans to
+// an exporter
+type Tracer struct {
+	exporter    *OTLPExporter
+	sampleRatio float64
+}
+
+// NewTracer samples sampleRatio of the traces started here, between 0 and 1;
+// those continued from a caller are sampled when the caller's were
+func NewTracer(exporter *OTLPExporter, sampleRatio float64) *Tracer {
+	return &Tracer{exporter: exporter, sampleRatio: sampleRatio}
+}
+
+var current atomic.Pointer[Tracer]
+
+// SetTracer has spans started from then on recorded by t; nil stops tracing
+func SetTracer(t *Tracer) {
+	current.Store(t)
+}
+
+// Enabled reports whether spans are being recorded
+func Enabled() bool {
+	return current.Load() != nil
+}
+
+// Span is one timed operation of a trace. A nil Span, what Start returns
+// without a tracer, ignores everything done to it.
+type Span struct {
+	tracer   *Tracer
+	ctx      spanContext
+	parentID [8]byte
+	name     string
+	kind     SpanKind
+	start    time.Time
+
+	mu         sync.Mutex
+	end        time.Time
+	attributes []attribute
+	err        string
+	ended      bool
+}
+
+type attribute struct {
+	key   string
+	value interface{}
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// Start starts an internal span, a child of the span of ctx, and returns the
+// context carrying it
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return start(ctx, name, KindInternal)
+}
+
+// StartServer starts the span of a request served, continuing the trace of
+// the caller when ctx was extracted from its headers
+func StartServer(ctx context.Context, name string) (context.Context, *Span) {
+	return start(ctx, name, KindServer)
+}
+
+func start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	t := current.Load()
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := &Span{tracer: t, name: name, kind: kind, start: time.Now()}
+	if parent, ok := parentContext(ctx); ok {
+		span.ctx.traceID = parent.traceID
+		span.ctx.sampled = parent.sampled
+		span.parentID = parent.spanID
+	} else {
+		rand.Read(span.ctx.traceID[:])
+		span.ctx.sampled = sampled(span.ctx.traceID, t.sampleRatio)
+	}
+	rand.Read(span.ctx.spanID[:])
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// sampled samples ratio of the trace IDs, deciding on the ID alone so every
+// server deciding sees the same
+func sampled(traceID [16]byte, ratio float64) bool {
+	if ratio >= 1 {
+		return true
+	}
+	if ratio <= 0 {
+		return false
+	}
+	return float64(binary.BigEndian.Uint64(traceID[8:])>>11)/(1<<53) < ratio
+}
+
+func parentContext(ctx context.Context) (spanContext, bool) {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.ctx, true
+	}
+	remote, ok := ctx.Value(remoteKey{}).(spanContext)
+	return remote, ok
+}
+
+// SpanFromContext returns the span ctx carries, nil when none
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// SetAttribute records key, a string, integer, float or bool, on the span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil || !s.ctx.sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes = append(s.attributes, attribute{key: key, value: value})
+}
+
+// SetError marks the span failed with err, unless err is nil
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil || !s.ctx.sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err.Error()
+}
+
+// End finishes the span and hands it to the exporter when its trace is
+// sampled; only the first End counts
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if s.ctx.sampled && s.tracer.exporter != nil {
+		s.tracer.exporter.export(s)
+	}
+}
+
+// TraceID returns the hex trace ID of the span, "" for a nil span
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.ctx.traceID[:])
+}
+
+// Extract returns ctx carrying the span of the caller named by the
+// traceparent header, so spans started from it continue the caller's trace;
+// ctx itself when there is none or it is malformed
+func Extract(ctx context.Context, header http.Header) context.Context {
+	remote, ok := parseTraceparent(header.Get("traceparent"))
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, remote)
+}
+
+// Inject sets the traceparent header of a call made on behalf of the span
+// of ctx, so the callee continues its trace
+func Inject(ctx context.Context, header http.Header) {
+	span := SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	flags := "00"
+	if span.ctx.sampled {
+		flags = "01"
+	}
+	header.Set("traceparent", fmt.Sprintf("00-%x-%x-%s", span.ctx.traceID, span.ctx.spanID, flags))
+}
+
+// parseTraceparent parses a version 00 traceparent: 00-<trace ID>-<span ID>-<flags>
+func parseTraceparent(value string) (spanContext, bool) {
+	var sc spanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.traceID[:], []byte(parts[1])); err != nil || sc.traceID == [16]byte{} {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.spanID[:], []byte(parts[2])); err != nil || sc.spanID == [8]byte{} {
+		return sc, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, false
+	}
+	sc.sampled = flags[0]&1 == 1
+	return sc, true
+}
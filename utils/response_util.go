@@ -3,18 +3,52 @@ package utils
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 )
 
-// Response represents the standardized API response format
+// RequestIDHeader carries the ID a request is logged and answered under
+const RequestIDHeader = "X-Request-Id"
+
+// Response represents the standardized API response format. Failed responses
+// carry a machine-readable ErrorCode, and Errors when several fields of a
+// request are wrong.
 type Response struct {
-	Status  string      `json:"status"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+	Status    string       `json:"status"`
+	Message   string       `json:"message"`
+	ErrorCode string       `json:"error_code,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Data      interface{}  `json:"data,omitempty"`
+}
+
+// FieldError describes what is wrong with one field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
-// WriteJSON is a helper to write JSON responses
+// WriteJSON is a helper to write JSON responses. The request ID is taken from
+// the response header set by the request ID middleware, and failed responses
+// without an error code get one derived from status, e.g. "not_found".
 func WriteJSON(w http.ResponseWriter, status int, res Response) {
+	if res.RequestID == "" {
+		res.RequestID = w.Header().Get(RequestIDHeader)
+	}
+	if status >= http.StatusBadRequest && res.ErrorCode == "" {
+		res.ErrorCode = ErrorCode(status)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(res)
 }
+
+// ErrorCode returns the default error code for an HTTP status
+func ErrorCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	text = strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text)
+	return strings.ToLower(text)
+}
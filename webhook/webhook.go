@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	EventTransactionCreated = "transaction.created"
+	EventProductLowStock    = "product.low_stock"
+	EventRefundIssued       = "refund.issued"
+	EventPaymentLinkPaid    = "payment_link.paid"
+)
+
+// Events lists every event integrators can subscribe to
+var Events = []string{EventTransactionCreated, EventProductLowStock, EventRefundIssued, EventPaymentLinkPaid}
+
+// Sign returns the hex encoded HMAC-SHA256 of body, sent as
+// "X-Webhook-Signature: sha256=<signature>" so receivers can verify the payload
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Delivery is one event payload sent to a webhook URL
+type Delivery struct {
+	ID      int
+	URL     string
+	Secret  string
+	Event   string
+	Payload []byte
+}
+
+// Client posts signed payloads to webhook URLs
+type Client struct {
+	http *http.Client
+}
+
+func NewClient() *Client {
+	return &Client{http: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Post sends a delivery and returns the response status code. Any non-2xx
+// response is returned as an error so the delivery is retried.
+func (c *Client) Post(d Delivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", d.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(d.ID))
+	req.Header.Set("X-Webhook-Signature", "sha256="+Sign(d.Secret, d.Payload))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,258 @@
+package webpush
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recordSize is the aes128gcm record size; payloads must fit in one record
+const recordSize = 4096
+
+// MaxPayloadSize is the largest payload that fits in a single record
+const MaxPayloadSize = recordSize - 16 - 1 - 86
+
+var b64 = base64.RawURLEncoding
+
+// Subscription is what the browser's PushManager.subscribe() returns
+type Subscription struct {
+	Endpoint string
+	P256dh   string
+	Auth     string
+}
+
+// GenerateVAPIDKeys creates a key pair for VAPID, both base64url encoded;
+// the public key is the applicationServerKey browsers subscribe with
+func GenerateVAPIDKeys() (privateKey, publicKey string, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	priv, err := key.Bytes()
+	if err != nil {
+		return "", "", err
+	}
+	pub, err := key.PublicKey.Bytes()
+	if err != nil {
+		return "", "", err
+	}
+	return b64.EncodeToString(priv), b64.EncodeToString(pub), nil
+}
+
+// Client sends encrypted push messages (RFC 8291) authenticated with VAPID (RFC 8292)
+type Client struct {
+	PublicKey string
+	key       *ecdsa.PrivateKey
+	subject   string
+	http      *http.Client
+}
+
+// NewClient takes the base64url VAPID private key and a contact subject (mailto: or https: URL)
+func NewClient(privateKey, subject string) (*Client, error) {
+	raw, err := decode(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding VAPID private key: %v", err)
+	}
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), raw)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing VAPID private key: %v", err)
+	}
+	pub, err := key.PublicKey.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		PublicKey: b64.EncodeToString(pub),
+		key:       key,
+		subject:   subject,
+		http:      &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Send posts payload to the subscription's push service and returns the
+// response status. 404 and 410 mean the subscription is gone for good.
+func (c *Client) Send(sub Subscription, payload []byte, ttl time.Duration) (int, error) {
+	body, err := encrypt(sub, payload)
+	if err != nil {
+		return 0, err
+	}
+
+	token, err := c.vapidToken(sub.Endpoint)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+	req.Header.Set("Urgency", "high")
+	req.Header.Set("Authorization", "vapid t="+token+", k="+c.PublicKey)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("push service responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// vapidToken signs an ES256 JWT for the origin of endpoint
+func (c *Client) vapidToken(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("invalid push endpoint %q", endpoint)
+	}
+
+	header := b64.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": c.subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := header + "." + b64.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, c.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return signingInput + "." + b64.EncodeToString(signature), nil
+}
+
+// encrypt produces a single aes128gcm record (RFC 8188) keyed as RFC 8291 describes
+func encrypt(sub Subscription, payload []byte) ([]byte, error) {
+	if len(payload) > MaxPayloadSize {
+		return nil, fmt.Errorf("payload of %d bytes exceeds %d", len(payload), MaxPayloadSize)
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return encryptWith(sub, payload, asPrivate, salt)
+}
+
+// encryptWith encrypts with the given application server key and salt
+func encryptWith(sub Subscription, payload []byte, asPrivate *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	uaPublicRaw, err := decode(sub.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding p256dh key: %v", err)
+	}
+	authSecret, err := decode(sub.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding auth secret: %v", err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicRaw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %v", err)
+	}
+
+	asPublic := asPrivate.PublicKey().Bytes()
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	prkKey, err := hkdf.Extract(sha256.New, sharedSecret, authSecret)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo := "WebPush: info\x00" + string(uaPublicRaw) + string(asPublic)
+	ikm, err := hkdf.Expand(sha256.New, prkKey, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// 0x02 marks the last (and only) record
+	plaintext := append(append([]byte{}, payload...), 0x02)
+
+	header := make([]byte, 0, 16+4+1+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// decode accepts base64url with or without padding, browsers differ
+func decode(s string) ([]byte, error) {
+	return b64.DecodeString(strings.TrimRight(s, "="))
+}
+
+// Validate checks that the subscription keys can be used for encryption
+func (sub Subscription) Validate() error {
+	u, err := url.Parse(sub.Endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("endpoint must be an https URL")
+	}
+	raw, err := decode(sub.P256dh)
+	if err != nil {
+		return fmt.Errorf("p256dh must be base64url")
+	}
+	if _, err := ecdh.P256().NewPublicKey(raw); err != nil {
+		return fmt.Errorf("p256dh is not a P-256 public key")
+	}
+	auth, err := decode(sub.Auth)
+	if err != nil || len(auth) != 16 {
+		return fmt.Errorf("auth must be a base64url 16 byte secret")
+	}
+	return nil
+}